@@ -0,0 +1,226 @@
+// This program prints the FLAP frames recorded in traffic capture files
+// written by the server when CAPTURE_FILE is set. Files are read in the order
+// given, so pass rotated files oldest first. Data frames of connections that
+// negotiated FLAP compression are inflated before they're printed.
+// Usage: go run ./cmd/capture_reader [-hex] [-conn id] [-screen-name name] file...
+// Example: go run ./cmd/capture_reader -hex capture.bin.20240101T120000.000000000 capture.bin
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// flapHeaderLen is the length of the FLAP frame header: start marker, frame
+// type, sequence number, and payload length.
+const flapHeaderLen = 6
+
+// streamKey identifies one direction of a client connection.
+type streamKey struct {
+	connID    uint64
+	direction uint8
+}
+
+// negotiation tracks the FLAP compression negotiated in the signon frames of
+// a client connection. The server offers compression in its signon frame and
+// the client accepts it in its own. Data frames that follow are compressed
+// once both have happened.
+type negotiation struct {
+	offered  bool
+	accepted bool
+}
+
+// compression returns the compression method that applies to the
+// connection's data frames.
+func (n negotiation) compression() uint16 {
+	if n.offered && n.accepted {
+		return wire.FLAPCompressionDeflate
+	}
+	return 0
+}
+
+// filter selects the frames that are printed.
+type filter struct {
+	connID     uint64
+	screenName state.IdentScreenName
+}
+
+func (f filter) matches(rec wire.CaptureRecord) bool {
+	if f.connID != 0 && rec.ConnID != f.connID {
+		return false
+	}
+	if f.screenName.String() != "" && state.NewIdentScreenName(rec.ScreenName) != f.screenName {
+		return false
+	}
+	return true
+}
+
+func main() {
+	showHex := flag.Bool("hex", false, "Print a hex dump of each frame payload")
+	connID := flag.Uint64("conn", 0, "Only print frames of the connection with this ID")
+	screenName := flag.String("screen-name", "", "Only print frames of connections signed on with this screen name")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: go run ./cmd/capture_reader [-hex] [-conn id] [-screen-name name] file...")
+		os.Exit(1)
+	}
+
+	f := filter{
+		connID:     *connID,
+		screenName: state.NewIdentScreenName(*screenName),
+	}
+	streams := make(map[streamKey][]byte)
+	negotiations := make(map[uint64]*negotiation)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	for _, path := range flag.Args() {
+		if err := readFile(out, path, streams, negotiations, f, *showHex); err != nil {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "error reading %s: %s\n", path, err.Error())
+			os.Exit(1)
+		}
+	}
+}
+
+// readFile prints the frames recorded in the capture file at path. streams
+// holds the bytes of partially captured frames, which may be completed by a
+// later file. negotiations holds the compression state of each connection.
+func readFile(w io.Writer, path string, streams map[streamKey][]byte, negotiations map[uint64]*negotiation, f filter, showHex bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for {
+		rec, err := wire.ReadCaptureRecord(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		neg, ok := negotiations[rec.ConnID]
+		if !ok {
+			neg = &negotiation{}
+			negotiations[rec.ConnID] = neg
+		}
+
+		key := streamKey{connID: rec.ConnID, direction: rec.Direction}
+		buf := append(streams[key], rec.Data...)
+		for len(buf) >= flapHeaderLen {
+			if buf[0] != 0x2A {
+				if f.matches(rec) {
+					fmt.Fprintf(w, "%s conn=%d lost track of FLAP frames, skipping %d bytes\n",
+						rec.CapturedAt().UTC().Format(time.RFC3339Nano), rec.ConnID, len(buf))
+				}
+				buf = nil
+				break
+			}
+			frameLen := flapHeaderLen + int(binary.BigEndian.Uint16(buf[4:6]))
+			if len(buf) < frameLen {
+				break
+			}
+			// signon frames are tracked even when filtered out, since they
+			// come before the connection is tagged with a screen name
+			if buf[1] == wire.FLAPFrameSignon {
+				updateNegotiation(neg, rec.Direction, buf[flapHeaderLen:frameLen])
+			}
+			if f.matches(rec) {
+				printFrame(w, rec, buf[:frameLen], neg.compression(), showHex)
+			}
+			buf = buf[frameLen:]
+		}
+		streams[key] = bytes.Clone(buf)
+	}
+}
+
+// printFrame prints a summary of a FLAP frame and, for data frames, the SNAC
+// it carries. Data frames are inflated if compression is set.
+func printFrame(w io.Writer, rec wire.CaptureRecord, frame []byte, compression uint16, showHex bool) {
+	direction := "in "
+	if rec.Direction == wire.CaptureDirectionOut {
+		direction = "out"
+	}
+	screenName := rec.ScreenName
+	if screenName == "" {
+		screenName = "-"
+	}
+	fmt.Fprintf(w, "%s conn=%d %s %s %s seq=%d",
+		rec.CapturedAt().UTC().Format(time.RFC3339Nano), rec.ConnID, screenName, direction,
+		frameTypeName(frame[1]), binary.BigEndian.Uint16(frame[2:4]))
+
+	payload := frame[flapHeaderLen:]
+	if frame[1] == wire.FLAPFrameData {
+		if compression != 0 {
+			flapc := wire.NewFlapClient(0, nil, nil)
+			flapc.SetCompression(compression)
+			inflated, err := flapc.DataPayload(wire.FLAPFrame{Payload: payload})
+			if err != nil {
+				fmt.Fprintf(w, " %s len=%d\n", err.Error(), len(payload))
+				return
+			}
+			payload = inflated
+		}
+		snac := wire.SNACFrame{}
+		if err := wire.UnmarshalBE(&snac, bytes.NewReader(payload)); err == nil {
+			fmt.Fprintf(w, " %s/%s req=%d",
+				wire.FoodGroupName(snac.FoodGroup), wire.SubGroupName(snac.FoodGroup, snac.SubGroup), snac.RequestID)
+		}
+	}
+	fmt.Fprintf(w, " len=%d\n", len(payload))
+
+	if showHex && len(payload) > 0 {
+		fmt.Fprint(w, hex.Dump(payload))
+	}
+}
+
+// updateNegotiation records the compression offer in the server's signon
+// frame or the acceptance in the client's.
+func updateNegotiation(neg *negotiation, direction uint8, payload []byte) {
+	signon := wire.FLAPSignonFrame{}
+	if err := wire.UnmarshalBE(&signon, bytes.NewReader(payload)); err != nil {
+		return
+	}
+	method, ok := signon.Uint16BE(wire.FLAPSignonTLVCompression)
+	if !ok || method != wire.FLAPCompressionDeflate {
+		return
+	}
+	if direction == wire.CaptureDirectionOut {
+		neg.offered = true
+	} else {
+		neg.accepted = true
+	}
+}
+
+// frameTypeName returns the name of a FLAP frame type.
+func frameTypeName(frameType uint8) string {
+	switch frameType {
+	case wire.FLAPFrameSignon:
+		return "signon"
+	case wire.FLAPFrameData:
+		return "data"
+	case wire.FLAPFrameError:
+		return "error"
+	case wire.FLAPFrameSignoff:
+		return "signoff"
+	case wire.FLAPFrameKeepAlive:
+		return "keepalive"
+	default:
+		return fmt.Sprintf("type=%#02x", frameType)
+	}
+}
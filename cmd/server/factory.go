@@ -1,10 +1,15 @@
 package main
 
 import (
+	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	nethttp "net/http"
+	"os"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
@@ -15,21 +20,51 @@ import (
 	"github.com/mk6i/retro-aim-server/server/oscar/handler"
 	"github.com/mk6i/retro-aim-server/server/oscar/middleware"
 	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
 )
 
 // Container groups together common dependencies.
 type Container struct {
-	cfg                    config.Config
-	chatSessionManager     *state.InMemoryChatSessionManager
-	hmacCookieBaker        state.HMACCookieBaker
-	inMemorySessionManager *state.InMemorySessionManager
-	logger                 *slog.Logger
-	sqLiteUserStore        *state.SQLiteUserStore
+	autoSuspender            *state.AutoSuspender
+	banList                  *state.BanList
+	build                    config.Build
+	captureFile              *middleware.RotatingFile
+	cfg                      config.Config
+	chatSessionManager       *state.InMemoryChatSessionManager
+	chatSlowMode             *state.ChatSlowMode
+	fileTransferLimiter      *state.FileTransferLimiter
+	frameCapture             *oscar.FrameCapture
+	hmacCookieBaker          state.HMACCookieBaker
+	ignoredSNACs             []wire.SNACFrame
+	inMemorySessionManager   *state.InMemorySessionManager
+	inviteDisabledExchanges  []uint16
+	logFile                  *middleware.RotatingFile
+	logger                   *slog.Logger
+	messageFilter            *state.MessageFilter
+	omitCapabilities         [][16]byte
+	quietHours               *state.QuietHours
+	registrationLimiter      *state.RegistrationLimiter
+	rendezvousCapabilities   [][16]byte
+	screenNamePolicy         state.ScreenNamePolicy
+	sqLiteUserStore          *state.SQLiteUserStore
+	tlsCertificate           tls.Certificate
+	tlsConfig                *tls.Config
+	traffic                  *state.TrafficCounter
+	webhookClient            *nethttp.Client
+	whisperDisabledExchanges []uint16
+	bartIconFormats          []string
+	icqXMLKeys               map[string]string
 }
 
 // MakeCommonDeps creates common dependencies used by the food group services.
 func MakeCommonDeps() (Container, error) {
-	c := Container{}
+	c := Container{
+		build: config.Build{
+			Version: version,
+			Commit:  commit,
+			Date:    date,
+		},
+	}
 
 	err := envconfig.Process("", &c.cfg)
 	if err != nil {
@@ -42,35 +77,336 @@ func MakeCommonDeps() (Container, error) {
 			"address or hostname reachable by AIM/ICQ clients")
 	}
 
+	switch c.cfg.ICBMSelfMessages {
+	case config.SelfMessagesDeliver, config.SelfMessagesDrop, config.SelfMessagesError:
+	default:
+		return c, fmt.Errorf("invalid config: ICBM_SELF_MESSAGES must be one of '%s', '%s', or '%s'",
+			config.SelfMessagesDeliver, config.SelfMessagesDrop, config.SelfMessagesError)
+	}
+
+	switch c.cfg.ConcurrentLoginPolicy {
+	case config.ConcurrentLoginKickOld, config.ConcurrentLoginAllowMultiple:
+	case config.ConcurrentLoginRejectNew:
+		if c.cfg.ServerKeepaliveSec <= 0 {
+			// without keepalives, a dead connection holds the session until
+			// TCP gives up on it, locking the user out in the meantime
+			return c, fmt.Errorf("invalid config: CONCURRENT_LOGIN_POLICY '%s' requires SERVER_KEEPALIVE_SEC to be set",
+				config.ConcurrentLoginRejectNew)
+		}
+	default:
+		return c, fmt.Errorf("invalid config: CONCURRENT_LOGIN_POLICY must be one of '%s', '%s', or '%s'",
+			config.ConcurrentLoginKickOld, config.ConcurrentLoginRejectNew, config.ConcurrentLoginAllowMultiple)
+	}
+
+	switch c.cfg.ChatRoomCreation {
+	case config.ChatRoomCreationEveryone, config.ChatRoomCreationConfirmed, config.ChatRoomCreationFlagged:
+	default:
+		return c, fmt.Errorf("invalid config: CHAT_ROOM_CREATION must be one of '%s', '%s', or '%s'",
+			config.ChatRoomCreationEveryone, config.ChatRoomCreationConfirmed, config.ChatRoomCreationFlagged)
+	}
+
+	if c.cfg.RateLimitWindowSize == 0 ||
+		c.cfg.RateLimitDisconnectLevel >= c.cfg.RateLimitLimitLevel ||
+		c.cfg.RateLimitLimitLevel >= c.cfg.RateLimitAlertLevel ||
+		c.cfg.RateLimitAlertLevel >= c.cfg.RateLimitClearLevel ||
+		c.cfg.RateLimitClearLevel > c.cfg.RateLimitMaxLevel {
+		return c, errors.New("invalid config: RATE_LIMIT_WINDOW_SIZE must be greater than 0 and rate limit levels " +
+			"must satisfy RATE_LIMIT_DISCONNECT_LEVEL < RATE_LIMIT_LIMIT_LEVEL < RATE_LIMIT_ALERT_LEVEL < " +
+			"RATE_LIMIT_CLEAR_LEVEL <= RATE_LIMIT_MAX_LEVEL")
+	}
+
+	if c.cfg.RegistrationRateLimit > 0 && c.cfg.RegistrationRateWindowMin <= 0 {
+		return c, errors.New("invalid config: REGISTRATION_RATE_WINDOW_MIN must be greater than 0 when REGISTRATION_RATE_LIMIT is set")
+	}
+
+	if c.cfg.FileTransferProxyPort != "" {
+		if ip := net.ParseIP(c.cfg.FileTransferProxyIP); ip == nil || ip.To4() == nil {
+			return c, errors.New("invalid config: FILE_TRANSFER_PROXY_IP must be an IPv4 address when FILE_TRANSFER_PROXY_PORT is set")
+		}
+		if c.cfg.FileTransferProxyTimeoutSec <= 0 {
+			return c, errors.New("invalid config: FILE_TRANSFER_PROXY_TIMEOUT_SEC must be greater than 0 when FILE_TRANSFER_PROXY_PORT is set")
+		}
+	}
+
+	if c.cfg.TLSCertFile != "" {
+		if c.cfg.AuthTLSPort == "" || c.cfg.BOSTLSPort == "" || c.cfg.ChatTLSPort == "" ||
+			c.cfg.ChatNavTLSPort == "" || c.cfg.AlertTLSPort == "" || c.cfg.BARTTLSPort == "" ||
+			c.cfg.AdminTLSPort == "" || c.cfg.ODirTLSPort == "" {
+			return c, errors.New("invalid config: every *_TLS_PORT must be set when TLS_CERT_FILE is set")
+		}
+		c.tlsCertificate, err = tls.LoadX509KeyPair(c.cfg.TLSCertFile, c.cfg.TLSKeyFile)
+		if err != nil {
+			return c, fmt.Errorf("invalid config: unable to load TLS_CERT_FILE and TLS_KEY_FILE: %s\n", err.Error())
+		}
+	}
+
 	c.sqLiteUserStore, err = state.NewSQLiteUserStore(c.cfg.DBPath)
 	if err != nil {
 		return c, fmt.Errorf("unable to create feedbag store: %s\n", err.Error())
 	}
 
-	c.hmacCookieBaker, err = state.NewHMACCookieBaker()
+	if c.cfg.ContentEncryptionKey != "" {
+		key, err := hex.DecodeString(c.cfg.ContentEncryptionKey)
+		if err != nil {
+			return c, fmt.Errorf("invalid config: CONTENT_ENCRYPTION_KEY must be hex-encoded: %s\n", err.Error())
+		}
+		contentCipher, err := state.NewContentCipher(key)
+		if err != nil {
+			return c, fmt.Errorf("invalid config: CONTENT_ENCRYPTION_KEY: %s\n", err.Error())
+		}
+		c.sqLiteUserStore.SetContentCipher(contentCipher)
+	}
+
+	if c.cfg.SearchCacheTTLSec > 0 {
+		c.sqLiteUserStore.SetSearchCache(state.NewSearchCache(time.Duration(c.cfg.SearchCacheTTLSec) * time.Second))
+	}
+
+	c.sqLiteUserStore.SetDefaultStorageQuota(c.cfg.StorageQuotaBytes)
+	c.sqLiteUserStore.SetOfflineMessageLimit(c.cfg.OfflineMessageLimit)
+
+	if c.cfg.DisableWeakMD5Auth {
+		if err := c.sqLiteUserStore.DisableWeakMD5Pass(); err != nil {
+			return c, fmt.Errorf("unable to delete weak MD5 password hashes: %s\n", err.Error())
+		}
+	}
+
+	if c.cfg.DefaultBuddyIconFile != "" {
+		icon, err := os.ReadFile(c.cfg.DefaultBuddyIconFile)
+		if err != nil {
+			return c, fmt.Errorf("unable to read DEFAULT_BUDDY_ICON_FILE: %s\n", err.Error())
+		}
+		if err := c.sqLiteUserStore.SetDefaultBuddyIcon(icon); err != nil {
+			return c, fmt.Errorf("unable to set default buddy icon: %s\n", err.Error())
+		}
+	}
+
+	c.hmacCookieBaker, err = state.NewHMACCookieBaker(time.Duration(c.cfg.ServiceCookieTTLSec)*time.Second, c.cfg.ServiceCookieSingleUse)
 	if err != nil {
 		return c, fmt.Errorf("unable to create HMAC cookie baker: %s\n", err.Error())
 	}
 
-	c.logger = middleware.NewLogger(c.cfg)
+	if c.cfg.TraceLogFile != "" {
+		c.logFile, err = middleware.NewRotatingFile(c.cfg.TraceLogFile, int64(c.cfg.TraceMaxSizeMB)*1024*1024,
+			time.Duration(c.cfg.TraceRetentionDays)*24*time.Hour)
+		if err != nil {
+			return c, fmt.Errorf("unable to open TRACE_LOG_FILE: %s\n", err.Error())
+		}
+		c.logger, err = middleware.NewLogger(c.cfg, c.logFile)
+	} else {
+		c.logger, err = middleware.NewLogger(c.cfg, os.Stdout)
+	}
+	if err != nil {
+		return c, fmt.Errorf("invalid config: FOOD_GROUP_LOG_LEVELS: %s\n", err.Error())
+	}
+	if c.cfg.CaptureFile != "" {
+		c.captureFile, err = middleware.NewRotatingFile(c.cfg.CaptureFile, int64(c.cfg.CaptureMaxSizeMB)*1024*1024,
+			time.Duration(c.cfg.CaptureRetentionDays)*24*time.Hour)
+		if err != nil {
+			return c, fmt.Errorf("unable to open CAPTURE_FILE: %s\n", err.Error())
+		}
+		c.frameCapture = oscar.NewFrameCapture(c.captureFile)
+	}
 	c.inMemorySessionManager = state.NewInMemorySessionManager(c.logger)
 	c.chatSessionManager = state.NewInMemoryChatSessionManager(c.logger)
+	c.chatSlowMode = state.NewChatSlowMode()
+	c.fileTransferLimiter = state.NewFileTransferLimiter(c.cfg.MaxFileTransfersPerUser, c.cfg.MaxFileTransfers)
+	c.registrationLimiter = state.NewRegistrationLimiter(c.cfg.RegistrationRateLimit,
+		time.Duration(c.cfg.RegistrationRateWindowMin)*time.Minute)
+	c.traffic = &state.TrafficCounter{}
+
+	c.banList = state.NewBanList(c.cfg.BanListFile)
+	if err := c.banList.Load(); err != nil {
+		return c, err
+	}
+	c.autoSuspender = state.NewAutoSuspender(c.banList, c.cfg.AutoSuspendWarnThreshold,
+		time.Duration(c.cfg.AutoSuspendWarnWindowMin)*time.Minute,
+		time.Duration(c.cfg.AutoSuspendCooldownMin)*time.Minute)
+	c.messageFilter = state.NewMessageFilter(c.cfg.FilterListFile)
+	if err := c.messageFilter.Load(); err != nil {
+		return c, err
+	}
+
+	c.quietHours, err = state.NewQuietHours(c.cfg.QuietHoursStart, c.cfg.QuietHoursEnd)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: %s\n", err.Error())
+	}
+
+	c.ignoredSNACs, err = oscar.ParseSNACList(c.cfg.IgnoredSNACs)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: IGNORED_SNACS: %s\n", err.Error())
+	}
+
+	c.icqXMLKeys, err = foodgroup.ParseICQXMLKeys(c.cfg.ICQXMLKeys)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: ICQ_XML_KEYS: %s\n", err.Error())
+	}
+
+	c.whisperDisabledExchanges, err = foodgroup.ParseExchangeList(c.cfg.ChatWhisperDisabledExchanges)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: CHAT_WHISPER_DISABLED_EXCHANGES: %s\n", err.Error())
+	}
+	c.inviteDisabledExchanges, err = foodgroup.ParseExchangeList(c.cfg.ChatInviteDisabledExchanges)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: CHAT_INVITE_DISABLED_EXCHANGES: %s\n", err.Error())
+	}
+	c.omitCapabilities, err = foodgroup.ParseCapabilityList(c.cfg.OmitCapabilities)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: OMIT_CAPABILITIES: %s\n", err.Error())
+	}
+	c.rendezvousCapabilities, err = foodgroup.ParseCapabilityList(c.cfg.RendezvousCapabilities)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: RENDEZVOUS_CAPABILITIES: %s\n", err.Error())
+	}
+	c.bartIconFormats, err = foodgroup.ParseIconFormatList(c.cfg.BARTIconFormats)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: BART_ICON_FORMATS: %s\n", err.Error())
+	}
+	c.webhookClient, err = foodgroup.NewWebhookClient(c.cfg.OutboundHTTPProxy)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: OUTBOUND_HTTP_PROXY: %s\n", err.Error())
+	}
+	c.screenNamePolicy, err = state.NewScreenNamePolicy(c.cfg.ScreenNameAllowedSymbols, c.cfg.ScreenNameASCIIOnly,
+		c.cfg.ScreenNameMinLetters, c.cfg.ScreenNameMaxLength)
+	if err != nil {
+		return c, fmt.Errorf("invalid config: screen name policy: %s\n", err.Error())
+	}
 
 	return c, nil
 }
 
+// TLSDeps returns a copy of deps for the servers that accept TLS connections.
+// The service ports are replaced by their TLS counterparts, so that the TLS
+// servers listen on the TLS ports and send clients that sign on through them
+// to the TLS ports of the other services.
+func TLSDeps(deps Container) Container {
+	deps.cfg.AdminPort = deps.cfg.AdminTLSPort
+	deps.cfg.AlertPort = deps.cfg.AlertTLSPort
+	deps.cfg.AuthPort = deps.cfg.AuthTLSPort
+	deps.cfg.BARTPort = deps.cfg.BARTTLSPort
+	deps.cfg.BOSPort = deps.cfg.BOSTLSPort
+	deps.cfg.ChatNavPort = deps.cfg.ChatNavTLSPort
+	deps.cfg.ChatPort = deps.cfg.ChatTLSPort
+	deps.cfg.ODirPort = deps.cfg.ODirTLSPort
+	deps.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{deps.tlsCertificate},
+	}
+	return deps
+}
+
+// ReloadLists rereads the ban list and message filter files. A list that
+// fails to load keeps its current entries.
+func (c Container) ReloadLists() {
+	if err := c.banList.Load(); err != nil {
+		c.logger.Error("unable to reload ban list", "err", err.Error())
+	} else {
+		c.logger.Info("reloaded ban list", "path", c.cfg.BanListFile)
+	}
+	if err := c.messageFilter.Load(); err != nil {
+		c.logger.Error("unable to reload message filter list", "err", err.Error())
+	} else {
+		c.logger.Info("reloaded message filter list", "path", c.cfg.FilterListFile)
+	}
+}
+
+// PruneChatTranscripts deletes chat transcript messages that are older than
+// the configured retention period. It does nothing if transcripts are kept
+// forever.
+func (c Container) PruneChatTranscripts() {
+	if c.cfg.ChatTranscriptTTLHours <= 0 {
+		return
+	}
+	before := time.Now().Add(-time.Duration(c.cfg.ChatTranscriptTTLHours) * time.Hour)
+	if err := c.sqLiteUserStore.DeleteChatTranscriptsBefore(before); err != nil {
+		c.logger.Error("unable to prune chat transcripts", "err", err.Error())
+	}
+}
+
+// PruneLogFiles deletes rotated log and capture files that are older than
+// the configured retention periods. It does nothing for logs written to
+// standard output or when capturing is disabled.
+func (c Container) PruneLogFiles() {
+	if c.logFile != nil {
+		if err := c.logFile.Prune(); err != nil {
+			c.logger.Error("unable to prune rotated log files", "err", err.Error())
+		}
+	}
+	if c.captureFile != nil {
+		if err := c.captureFile.Prune(); err != nil {
+			c.logger.Error("unable to prune rotated capture files", "err", err.Error())
+		}
+	}
+}
+
+// ExportBARTItems writes every BART item, such as buddy icons, to the file at
+// path so that the items can be imported into another server's database with
+// ImportBARTItems.
+func (c Container) ExportBARTItems(path string) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	count, err := c.sqLiteUserStore.ExportBARTItems(f)
+	if err != nil {
+		f.Close()
+		return count, err
+	}
+	return count, f.Close()
+}
+
+// ImportBARTItems stores the BART items in a file created by
+// ExportBARTItems.
+func (c Container) ImportBARTItems(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return c.sqLiteUserStore.ImportBARTItems(f)
+}
+
+// PresenceReconciler creates a reconciler that corrects stale buddy presence.
+func PresenceReconciler(deps Container) foodgroup.PresenceReconciler {
+	return foodgroup.NewPresenceReconciler(
+		deps.logger.With("svc", "PRESENCE"),
+		deps.sqLiteUserStore,
+		deps.inMemorySessionManager,
+		deps.inMemorySessionManager,
+		deps.inMemorySessionManager,
+	)
+}
+
+// WarningDecayer creates a job that lowers users' warning levels over time.
+func WarningDecayer(deps Container) foodgroup.WarningDecayer {
+	return foodgroup.NewWarningDecayer(
+		deps.cfg,
+		deps.logger.With("svc", "ICBM"),
+		deps.sqLiteUserStore,
+		deps.inMemorySessionManager,
+		deps.inMemorySessionManager,
+		deps.inMemorySessionManager,
+	)
+}
+
 // Admin creates an OSCAR server for the Admin food group.
 func Admin(deps Container) oscar.AdminServer {
 	logger := deps.logger.With("svc", "ADMIN")
 
+	var confirmationSender foodgroup.ConfirmationSender
+	if deps.cfg.SMTPHost != "" {
+		confirmationSender = foodgroup.NewConfirmationMailer(logger, deps.cfg.SMTPHost, deps.cfg.SMTPPort,
+			deps.cfg.SMTPUsername, deps.cfg.SMTPPassword, deps.cfg.SMTPFrom, deps.cfg.AccountConfirmURL)
+	}
 	adminService := foodgroup.NewAdminService(
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
 		deps.inMemorySessionManager,
+		deps.screenNamePolicy,
+		confirmationSender,
 	)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		deps.inMemorySessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -78,6 +414,11 @@ func Admin(deps Container) oscar.AdminServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
 	oServiceService := foodgroup.NewOServiceServiceForAdmin(
 		deps.cfg,
@@ -90,13 +431,16 @@ func Admin(deps Container) oscar.AdminServer {
 	return oscar.AdminServer{
 		AuthService: authService,
 		Config:      deps.cfg,
-		Handler: handler.NewAdminRouter(handler.Handlers{
+		Handler: oscar.IgnoreSNACs(handler.NewAdminRouter(handler.Handlers{
 			AdminHandler:    handler.NewAdminHandler(logger, adminService),
 			OServiceHandler: handler.NewOServiceHandler(logger, oServiceService),
-		}),
+		}), deps.ignoredSNACs, logger),
 		Logger:         logger,
 		OnlineNotifier: oServiceService,
+		Traffic:        deps.traffic,
 		ListenAddr:     net.JoinHostPort("", deps.cfg.AdminPort),
+		Capture:        deps.frameCapture,
+		TLSConfig:      deps.tlsConfig,
 	}
 }
 
@@ -107,6 +451,7 @@ func Alert(deps Container) oscar.BOSServer {
 	sessionManager := state.NewInMemorySessionManager(logger)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		sessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -114,6 +459,11 @@ func Alert(deps Container) oscar.BOSServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
 	oServiceService := foodgroup.NewOServiceServiceForAlert(
 		deps.cfg,
@@ -126,13 +476,16 @@ func Alert(deps Container) oscar.BOSServer {
 	return oscar.BOSServer{
 		AuthService: authService,
 		Config:      deps.cfg,
-		Handler: handler.NewAlertRouter(handler.Handlers{
+		Handler: oscar.IgnoreSNACs(handler.NewAlertRouter(handler.Handlers{
 			AlertHandler:    handler.NewAlertHandler(logger),
 			OServiceHandler: handler.NewOServiceHandler(logger, oServiceService),
-		}),
+		}), deps.ignoredSNACs, logger),
 		Logger:         logger,
 		OnlineNotifier: oServiceService,
+		Traffic:        deps.traffic,
 		ListenAddr:     net.JoinHostPort("", deps.cfg.AlertPort),
+		Capture:        deps.frameCapture,
+		TLSConfig:      deps.tlsConfig,
 	}
 }
 
@@ -140,8 +493,14 @@ func Alert(deps Container) oscar.BOSServer {
 func Auth(deps Container) oscar.AuthServer {
 	logger := deps.logger.With("svc", "AUTH")
 
+	var registrationVerifier foodgroup.RegistrationVerifier
+	if deps.cfg.RegistrationWebhookURL != "" {
+		registrationVerifier = foodgroup.NewRegistrationWebhook(logger, deps.cfg.RegistrationWebhookURL, deps.webhookClient)
+	}
+
 	authHandler := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		deps.inMemorySessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -149,12 +508,18 @@ func Auth(deps Container) oscar.AuthServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		foodgroup.NewWatchAuditor(logger, deps.cfg.WatchedAccountWebhookURL, deps.webhookClient),
+		deps.screenNamePolicy,
+		deps.registrationLimiter,
+		registrationVerifier,
 	)
 
 	return oscar.AuthServer{
 		AuthService: authHandler,
 		Config:      deps.cfg,
 		Logger:      logger,
+		TLSConfig:   deps.tlsConfig,
 	}
 }
 
@@ -164,14 +529,17 @@ func BART(deps Container) oscar.BOSServer {
 
 	sessionManager := state.NewInMemorySessionManager(logger)
 	bartService := foodgroup.NewBARTService(
+		deps.cfg,
 		logger,
 		deps.sqLiteUserStore,
 		sessionManager,
 		deps.sqLiteUserStore,
 		sessionManager,
+		deps.bartIconFormats,
 	)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		sessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -179,6 +547,11 @@ func BART(deps Container) oscar.BOSServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
 	oServiceService := foodgroup.NewOServiceServiceForBART(
 		deps.cfg,
@@ -191,13 +564,16 @@ func BART(deps Container) oscar.BOSServer {
 	return oscar.BOSServer{
 		AuthService: authService,
 		Config:      deps.cfg,
-		Handler: handler.NewBARTRouter(handler.Handlers{
+		Handler: oscar.IgnoreSNACs(handler.NewBARTRouter(handler.Handlers{
 			BARTHandler:     handler.NewBARTHandler(logger, bartService),
 			OServiceHandler: handler.NewOServiceHandler(logger, oServiceService),
-		}),
+		}), deps.ignoredSNACs, logger),
 		ListenAddr:     net.JoinHostPort("", deps.cfg.BARTPort),
 		Logger:         logger,
 		OnlineNotifier: oServiceService,
+		Traffic:        deps.traffic,
+		Capture:        deps.frameCapture,
+		TLSConfig:      deps.tlsConfig,
 	}
 }
 
@@ -207,6 +583,7 @@ func BOS(deps Container) oscar.BOSServer {
 
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		deps.inMemorySessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -214,21 +591,31 @@ func BOS(deps Container) oscar.BOSServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
 	bartService := foodgroup.NewBARTService(
+		deps.cfg,
 		logger,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.bartIconFormats,
 	)
 	buddyService := foodgroup.NewBuddyService(
+		deps.cfg,
 		deps.inMemorySessionManager,
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.sqLiteUserStore,
+		deps.sqLiteUserStore,
 	)
-	chatNavService := foodgroup.NewChatNavService(logger, deps.sqLiteUserStore)
+	chatNavService := foodgroup.NewChatNavService(deps.cfg, logger, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.chatSessionManager)
 	feedbagService := foodgroup.NewFeedbagService(
 		logger,
 		deps.inMemorySessionManager,
@@ -236,6 +623,9 @@ func BOS(deps Container) oscar.BOSServer {
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.sqLiteUserStore,
+		deps.cfg,
+		deps.sqLiteUserStore,
 	)
 	permitDenyService := foodgroup.NewPermitDenyService(
 		deps.sqLiteUserStore,
@@ -244,36 +634,54 @@ func BOS(deps Container) oscar.BOSServer {
 		deps.inMemorySessionManager,
 	)
 	icbmService := foodgroup.NewICBMService(
+		deps.cfg,
 		deps.inMemorySessionManager,
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.messageFilter,
+		deps.inviteDisabledExchanges,
+		deps.rendezvousCapabilities,
+		deps.fileTransferLimiter,
+		deps.autoSuspender,
+		deps.sqLiteUserStore,
+		deps.sqLiteUserStore,
 	)
 	icqService := foodgroup.NewICQService(deps.inMemorySessionManager, deps.sqLiteUserStore, deps.sqLiteUserStore,
-		logger, deps.inMemorySessionManager, deps.sqLiteUserStore)
+		logger, deps.inMemorySessionManager, deps.sqLiteUserStore, deps.icqXMLKeys)
 	locateService := foodgroup.NewLocateService(
+		deps.cfg,
 		deps.inMemorySessionManager,
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.sqLiteUserStore,
+		deps.omitCapabilities,
 	)
 	oServiceService := foodgroup.NewOServiceServiceForBOS(
 		deps.cfg,
+		deps.build,
 		deps.inMemorySessionManager,
 		logger,
 		deps.hmacCookieBaker,
 		deps.sqLiteUserStore,
 		deps.sqLiteUserStore,
 		deps.inMemorySessionManager,
+		deps.sqLiteUserStore,
+		deps.sqLiteUserStore,
+		deps.omitCapabilities,
 	)
-	userLookupService := foodgroup.NewUserLookupService(deps.sqLiteUserStore)
+	userLookupService := foodgroup.NewUserLookupService(deps.sqLiteUserStore, deps.cfg)
 
 	return oscar.BOSServer{
-		AuthService:       authService,
-		BuddyListRegistry: deps.sqLiteUserStore,
-		Config:            deps.cfg,
-		DepartureNotifier: buddyService,
-		Handler: handler.NewBOSRouter(handler.Handlers{
+		AddrBanList:          deps.banList,
+		AuthService:          authService,
+		BuddyListRegistry:    deps.sqLiteUserStore,
+		ChatSessionCloser:    deps.chatSessionManager,
+		Config:               deps.cfg,
+		DepartureNotifier:    buddyService,
+		FileTransferReleaser: deps.fileTransferLimiter,
+		Handler: oscar.IgnoreSNACs(handler.NewBOSRouter(handler.Handlers{
 			AlertHandler:      handler.NewAlertHandler(logger),
 			BARTHandler:       handler.NewBARTHandler(logger, bartService),
 			BuddyHandler:      handler.NewBuddyHandler(logger, buddyService),
@@ -285,10 +693,15 @@ func BOS(deps Container) oscar.BOSServer {
 			OServiceHandler:   handler.NewOServiceHandler(logger, oServiceService),
 			PermitDenyHandler: handler.NewPermitDenyHandler(logger, permitDenyService),
 			UserLookupHandler: handler.NewUserLookupHandler(logger, userLookupService),
-		}),
-		Logger:         logger,
-		OnlineNotifier: oServiceService,
-		ListenAddr:     net.JoinHostPort("", deps.cfg.BOSPort),
+		}), deps.ignoredSNACs, logger),
+		Logger:          logger,
+		OnlineNotifier:  oServiceService,
+		SessionDetacher: deps.inMemorySessionManager,
+		SignonTimeout:   time.Duration(deps.cfg.SignonTimeoutSec) * time.Second,
+		Traffic:         deps.traffic,
+		ListenAddr:      net.JoinHostPort("", deps.cfg.BOSPort),
+		Capture:         deps.frameCapture,
+		TLSConfig:       deps.tlsConfig,
 	}
 }
 
@@ -299,6 +712,7 @@ func Chat(deps Container) oscar.ChatServer {
 	sessionManager := state.NewInMemorySessionManager(logger)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		sessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -306,8 +720,17 @@ func Chat(deps Container) oscar.ChatServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
-	chatService := foodgroup.NewChatService(deps.chatSessionManager)
+	var chatTranscriptRecorder foodgroup.ChatTranscriptRecorder
+	if deps.cfg.ChatTranscripts {
+		chatTranscriptRecorder = deps.sqLiteUserStore
+	}
+	chatService := foodgroup.NewChatService(deps.cfg, logger, deps.chatSessionManager, deps.chatSlowMode, deps.sqLiteUserStore, deps.sqLiteUserStore, chatTranscriptRecorder, deps.whisperDisabledExchanges)
 	oServiceService := foodgroup.NewOServiceServiceForChat(
 		deps.cfg,
 		logger,
@@ -316,17 +739,21 @@ func Chat(deps Container) oscar.ChatServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		sessionManager,
+		deps.omitCapabilities,
 	)
 
 	return oscar.ChatServer{
 		AuthService: authService,
 		Config:      deps.cfg,
-		Handler: handler.NewChatRouter(handler.Handlers{
+		Handler: oscar.IgnoreSNACs(handler.NewChatRouter(handler.Handlers{
 			ChatHandler:     handler.NewChatHandler(logger, chatService),
 			OServiceHandler: handler.NewOServiceHandler(logger, oServiceService),
-		}),
+		}), deps.ignoredSNACs, logger),
 		Logger:         logger,
 		OnlineNotifier: oServiceService,
+		Traffic:        deps.traffic,
+		Capture:        deps.frameCapture,
+		TLSConfig:      deps.tlsConfig,
 	}
 }
 
@@ -337,6 +764,7 @@ func ChatNav(deps Container) oscar.BOSServer {
 	sessionManager := state.NewInMemorySessionManager(logger)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		sessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -344,8 +772,13 @@ func ChatNav(deps Container) oscar.BOSServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
-	chatNavService := foodgroup.NewChatNavService(logger, deps.sqLiteUserStore)
+	chatNavService := foodgroup.NewChatNavService(deps.cfg, logger, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.chatSessionManager)
 	oServiceService := foodgroup.NewOServiceServiceForChatNav(
 		deps.cfg,
 		logger,
@@ -357,26 +790,50 @@ func ChatNav(deps Container) oscar.BOSServer {
 	return oscar.BOSServer{
 		AuthService: authService,
 		Config:      deps.cfg,
-		Handler: handler.NewChatNavRouter(handler.Handlers{
+		Handler: oscar.IgnoreSNACs(handler.NewChatNavRouter(handler.Handlers{
 			ChatNavHandler:  handler.NewChatNavHandler(chatNavService, logger),
 			OServiceHandler: handler.NewOServiceHandler(logger, oServiceService),
-		}),
+		}), deps.ignoredSNACs, logger),
 		Logger:         logger,
 		OnlineNotifier: oServiceService,
+		Traffic:        deps.traffic,
 		ListenAddr:     net.JoinHostPort("", deps.cfg.ChatNavPort),
+		Capture:        deps.frameCapture,
+		TLSConfig:      deps.tlsConfig,
 	}
 }
 
 // MgmtAPI creates an HTTP server for the management API.
 func MgmtAPI(deps Container) *http.Server {
-	bld := config.Build{
-		Version: version,
-		Commit:  commit,
-		Date:    date,
-	}
-	return http.NewManagementAPI(bld, deps.cfg, deps.sqLiteUserStore, deps.inMemorySessionManager, deps.sqLiteUserStore,
-		deps.sqLiteUserStore, deps.chatSessionManager, deps.sqLiteUserStore, deps.inMemorySessionManager,
-		deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.logger)
+	buddyService := foodgroup.NewBuddyService(deps.cfg, deps.inMemorySessionManager, deps.sqLiteUserStore,
+		deps.sqLiteUserStore, deps.inMemorySessionManager, deps.sqLiteUserStore, deps.sqLiteUserStore)
+	virtualUserService := foodgroup.NewVirtualUserService(deps.logger, deps.sqLiteUserStore, deps.inMemorySessionManager,
+		deps.inMemorySessionManager, deps.sqLiteUserStore, deps.inMemorySessionManager, deps.cfg.VirtualUserWebhookURL, deps.webhookClient)
+	return http.NewManagementAPI(deps.build, deps.cfg, deps.sqLiteUserStore, deps.inMemorySessionManager, deps.sqLiteUserStore,
+		deps.sqLiteUserStore, deps.sqLiteUserStore, deps.chatSessionManager, deps.sqLiteUserStore, deps.inMemorySessionManager,
+		deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore,
+		buddyService, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.chatSlowMode, deps.chatSessionManager, deps.traffic, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.quietHours, deps.sqLiteUserStore, deps.screenNamePolicy, virtualUserService, deps.sqLiteUserStore, deps.logger)
+}
+
+// PublicAPI creates an HTTP server for the endpoints that users reach, such as
+// account confirmation links and password resets.
+func PublicAPI(deps Container) *http.Server {
+	buddyService := foodgroup.NewBuddyService(deps.cfg, deps.inMemorySessionManager, deps.sqLiteUserStore,
+		deps.sqLiteUserStore, deps.inMemorySessionManager, deps.sqLiteUserStore, deps.sqLiteUserStore)
+	return http.NewPublicAPI(deps.cfg, deps.sqLiteUserStore, deps.sqLiteUserStore, deps.inMemorySessionManager, buddyService, deps.logger)
+}
+
+// FileTransferProxy creates a server that relays file transfers between
+// users who can't connect to each other directly.
+func FileTransferProxy(deps Container) oscar.FileTransferProxyServer {
+	return oscar.FileTransferProxyServer{
+		FileTransferEnder: deps.fileTransferLimiter,
+		IP:                net.ParseIP(deps.cfg.FileTransferProxyIP),
+		ListenAddr:        net.JoinHostPort("", deps.cfg.FileTransferProxyPort),
+		Logger:            deps.logger.With("svc", "FT_PROXY"),
+		PairTimeout:       time.Duration(deps.cfg.FileTransferProxyTimeoutSec) * time.Second,
+		SessionRetriever:  deps.inMemorySessionManager,
+	}
 }
 
 // ODir creates an OSCAR server for the ODir food group.
@@ -386,6 +843,7 @@ func ODir(deps Container) oscar.BOSServer {
 	sessionManager := state.NewInMemorySessionManager(logger)
 	authService := foodgroup.NewAuthService(
 		deps.cfg,
+		logger,
 		sessionManager,
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
@@ -393,6 +851,11 @@ func ODir(deps Container) oscar.BOSServer {
 		deps.chatSessionManager,
 		deps.sqLiteUserStore,
 		nil,
+		deps.banList,
+		nil,
+		deps.screenNamePolicy,
+		nil,
+		nil,
 	)
 	oServiceService := foodgroup.NewOServiceServiceForODir(deps.cfg, logger)
 	oDirService := foodgroup.NewODirService(logger, deps.sqLiteUserStore)
@@ -400,12 +863,15 @@ func ODir(deps Container) oscar.BOSServer {
 	return oscar.BOSServer{
 		AuthService: authService,
 		Config:      deps.cfg,
-		Handler: handler.NewODirRouter(handler.Handlers{
+		Handler: oscar.IgnoreSNACs(handler.NewODirRouter(handler.Handlers{
 			OServiceHandler: handler.NewOServiceHandler(logger, oServiceService),
 			ODirHandler:     handler.NewODirHandler(logger, oDirService),
-		}),
+		}), deps.ignoredSNACs, logger),
 		Logger:         logger,
 		OnlineNotifier: oServiceService,
+		Traffic:        deps.traffic,
 		ListenAddr:     net.JoinHostPort("", deps.cfg.ODirPort),
+		Capture:        deps.frameCapture,
+		TLSConfig:      deps.tlsConfig,
 	}
 }
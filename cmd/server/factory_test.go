@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mk6i/retro-aim-server/config"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfig_BaselineSettings verifies that a settings file that predates the
+// optional settings still loads, and that the optional settings fall back to
+// their defaults.
+func TestConfig_BaselineSettings(t *testing.T) {
+	baseline := map[string]string{
+		"API_HOST":      "127.0.0.1",
+		"API_PORT":      "8080",
+		"ALERT_PORT":    "5194",
+		"AUTH_PORT":     "5190",
+		"BART_PORT":     "5195",
+		"BOS_PORT":      "5191",
+		"CHAT_NAV_PORT": "5193",
+		"CHAT_PORT":     "5192",
+		"ADMIN_PORT":    "5196",
+		"ODIR_PORT":     "5197",
+		"DB_PATH":       "oscar.sqlite",
+		"DISABLE_AUTH":  "true",
+		"LOG_LEVEL":     "info",
+		"OSCAR_HOST":    "127.0.0.1",
+	}
+	for k, v := range baseline {
+		t.Setenv(k, v)
+	}
+
+	cfg := config.Config{}
+	assert.NoError(t, envconfig.Process("", &cfg))
+
+	assert.Equal(t, "8081", cfg.PublicApiPort)
+	assert.Equal(t, uint32(0), cfg.MaxRendezvousFileSize)
+	assert.Equal(t, config.ConcurrentLoginKickOld, cfg.ConcurrentLoginPolicy)
+	assert.Equal(t, 60, cfg.FileTransferProxyTimeoutSec)
+	assert.Equal(t, uint32(999999999), cfg.ICQUINEnd)
+	assert.True(t, cfg.ServiceCookieSingleUse)
+}
+
+func TestTLSDeps(t *testing.T) {
+	deps := Container{
+		cfg: config.Config{
+			AdminPort:      "5196",
+			AdminTLSPort:   "5296",
+			AlertPort:      "5194",
+			AlertTLSPort:   "5294",
+			AuthPort:       "5190",
+			AuthTLSPort:    "5290",
+			BARTPort:       "5195",
+			BARTTLSPort:    "5295",
+			BOSPort:        "5191",
+			BOSTLSPort:     "5291",
+			ChatNavPort:    "5193",
+			ChatNavTLSPort: "5293",
+			ChatPort:       "5192",
+			ChatTLSPort:    "5292",
+			ODirPort:       "5197",
+			ODirTLSPort:    "5297",
+		},
+	}
+
+	tlsDeps := TLSDeps(deps)
+
+	assert.Equal(t, "5296", tlsDeps.cfg.AdminPort)
+	assert.Equal(t, "5294", tlsDeps.cfg.AlertPort)
+	assert.Equal(t, "5290", tlsDeps.cfg.AuthPort)
+	assert.Equal(t, "5295", tlsDeps.cfg.BARTPort)
+	assert.Equal(t, "5291", tlsDeps.cfg.BOSPort)
+	assert.Equal(t, "5293", tlsDeps.cfg.ChatNavPort)
+	assert.Equal(t, "5292", tlsDeps.cfg.ChatPort)
+	assert.Equal(t, "5297", tlsDeps.cfg.ODirPort)
+	assert.NotNil(t, tlsDeps.tlsConfig)
+
+	// the plaintext deps are unchanged
+	assert.Equal(t, "5191", deps.cfg.BOSPort)
+	assert.Nil(t, deps.tlsConfig)
+}
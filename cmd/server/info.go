@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mk6i/retro-aim-server/config"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// listener describes a server that the process starts and the food groups it
+// serves.
+type listener struct {
+	name       string
+	port       func(cfg config.Config) string
+	tlsPort    func(cfg config.Config) string
+	foodGroups []uint16
+}
+
+// listeners are the OSCAR servers started by main, in start order. The
+// management API is reported separately because it speaks HTTP. Listeners
+// without a port are disabled and not reported. Listeners with a TLS port
+// are also reported on that port when TLS is enabled.
+var listeners = []listener{
+	{
+		name:       "Admin",
+		port:       func(cfg config.Config) string { return cfg.AdminPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.AdminTLSPort },
+		foodGroups: []uint16{wire.Admin, wire.OService},
+	},
+	{
+		name:       "Alert",
+		port:       func(cfg config.Config) string { return cfg.AlertPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.AlertTLSPort },
+		foodGroups: []uint16{wire.Alert, wire.OService},
+	},
+	{
+		name:       "Auth",
+		port:       func(cfg config.Config) string { return cfg.AuthPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.AuthTLSPort },
+		foodGroups: []uint16{wire.BUCP},
+	},
+	{
+		name:       "BART",
+		port:       func(cfg config.Config) string { return cfg.BARTPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.BARTTLSPort },
+		foodGroups: []uint16{wire.BART, wire.OService},
+	},
+	{
+		name:    "BOS",
+		port:    func(cfg config.Config) string { return cfg.BOSPort },
+		tlsPort: func(cfg config.Config) string { return cfg.BOSTLSPort },
+		foodGroups: []uint16{wire.Alert, wire.BART, wire.Buddy, wire.ChatNav, wire.Feedbag, wire.ICBM,
+			wire.ICQ, wire.Locate, wire.OService, wire.PermitDeny, wire.UserLookup},
+	},
+	{
+		name:       "Chat",
+		port:       func(cfg config.Config) string { return cfg.ChatPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.ChatTLSPort },
+		foodGroups: []uint16{wire.Chat, wire.OService},
+	},
+	{
+		name:       "ChatNav",
+		port:       func(cfg config.Config) string { return cfg.ChatNavPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.ChatNavTLSPort },
+		foodGroups: []uint16{wire.ChatNav, wire.OService},
+	},
+	{
+		name: "File Transfer Proxy",
+		port: func(cfg config.Config) string { return cfg.FileTransferProxyPort },
+	},
+	{
+		name:       "ODir",
+		port:       func(cfg config.Config) string { return cfg.ODirPort },
+		tlsPort:    func(cfg config.Config) string { return cfg.ODirTLSPort },
+		foodGroups: []uint16{wire.ODir, wire.OService},
+	},
+}
+
+// writeInfo writes a deployment summary for operators: the build, the
+// storage backend, the listener addresses and the food groups they serve,
+// and the active config with secrets redacted.
+func writeInfo(w io.Writer, build config.Build, cfg config.Config) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Build:")
+	fmt.Fprintf(tw, "  version:\t%s\n", build.Version)
+	fmt.Fprintf(tw, "  commit:\t%s\n", build.Commit)
+	fmt.Fprintf(tw, "  date:\t%s\n", build.Date)
+	fmt.Fprintf(tw, "  go:\t%s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	fmt.Fprintln(tw, "\nStorage:")
+	fmt.Fprintf(tw, "  backend:\tSQLite\n")
+	fmt.Fprintf(tw, "  path:\t%s\n", cfg.DBPath)
+
+	fmt.Fprintf(tw, "\nListeners (clients connect to %s):\n", cfg.OSCARHost)
+	for _, l := range listeners {
+		if l.port(cfg) == "" {
+			// optional listener that's disabled
+			continue
+		}
+		names := make([]string, 0, len(l.foodGroups))
+		for _, foodGroup := range l.foodGroups {
+			names = append(names, wire.FoodGroupName(foodGroup))
+		}
+		fmt.Fprintf(tw, "  %s:\t%s\t%s\n", l.name, net.JoinHostPort("", l.port(cfg)), strings.Join(names, ", "))
+		if cfg.TLSCertFile != "" && l.tlsPort != nil {
+			fmt.Fprintf(tw, "  %s (TLS):\t%s\t%s\n", l.name, net.JoinHostPort("", l.tlsPort(cfg)), strings.Join(names, ", "))
+		}
+	}
+	fmt.Fprintf(tw, "  Management API:\t%s\tHTTP\n", net.JoinHostPort(cfg.ApiHost, cfg.ApiPort))
+	fmt.Fprintf(tw, "  Public API:\t%s\tHTTP\n", net.JoinHostPort(cfg.PublicApiHost, cfg.PublicApiPort))
+
+	fmt.Fprintln(tw, "\nConfig:")
+	for _, setting := range cfg.Settings() {
+		fmt.Fprintf(tw, "  %s\t%s\n", setting.Name, setting.Value)
+	}
+
+	return tw.Flush()
+}
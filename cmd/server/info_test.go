@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mk6i/retro-aim-server/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteInfo(t *testing.T) {
+	build := config.Build{
+		Version: "v1.2.3",
+		Commit:  "abc123",
+		Date:    "2024-01-01",
+	}
+	cfg := config.Config{
+		ApiHost:                  "127.0.0.1",
+		ApiPort:                  "8080",
+		BOSPort:                  "5191",
+		BOSTLSPort:               "5291",
+		DBPath:                   "/var/lib/ras/oscar.sqlite",
+		FileTransferProxyPort:    "5198",
+		OSCARHost:                "aim.example.com",
+		SMTPUsername:             "mailer",
+		SMTPPassword:             "hunter2",
+		TLSCertFile:              "/etc/ras/cert.pem",
+		ContentEncryptionKey:     "00112233445566778899aabbccddeeff",
+		WatchedAccountWebhookURL: "https://hooks.example.com/T0KEN",
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeInfo(buf, build, cfg))
+	out := buf.String()
+
+	// build, storage, and listeners
+	assert.Contains(t, out, "v1.2.3")
+	assert.Contains(t, out, "abc123")
+	assert.Contains(t, out, "SQLite")
+	assert.Contains(t, out, "/var/lib/ras/oscar.sqlite")
+	assert.Contains(t, out, "aim.example.com")
+	assert.Regexp(t, `BOS:\s+:5191\s+.*ICBM`, out)
+	assert.Regexp(t, `BOS \(TLS\):\s+:5291\s+.*ICBM`, out)
+	assert.Regexp(t, `Management API:\s+127\.0\.0\.1:8080`, out)
+	assert.Regexp(t, `File Transfer Proxy:\s+:5198`, out)
+
+	// non-secret settings are shown as-is
+	assert.Regexp(t, `SMTP_USERNAME\s+mailer`, out)
+
+	// secrets are redacted
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "00112233445566778899aabbccddeeff")
+	assert.NotContains(t, out, "T0KEN")
+	assert.Regexp(t, `SMTP_PASSWORD\s+\[redacted\]`, out)
+	assert.Regexp(t, `CONTENT_ENCRYPTION_KEY\s+\[redacted\]`, out)
+
+	// empty secrets show that they're unset
+	assert.Regexp(t, `VIRTUAL_USER_WEBHOOK_URL\s*\n`, out)
+}
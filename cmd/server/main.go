@@ -7,8 +7,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/config"
 
 	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -19,10 +23,24 @@ var (
 	date    = "unknown"
 )
 
-func init() {
+var (
+	// exportBARTFile is the path of the file to export BART items to.
+	exportBARTFile string
+	// importBARTFile is the path of the file to import BART items from.
+	importBARTFile string
+	// showInfo indicates whether to print a deployment summary and exit.
+	showInfo bool
+)
+
+// parseFlags parses the command line flags and loads the config file into
+// the environment.
+func parseFlags() {
 	cfgFile := flag.String("config", "settings.env", "Path to config file")
 	showHelp := flag.Bool("help", false, "Display help")
 	showVersion := flag.Bool("version", false, "Display build information")
+	flag.StringVar(&exportBARTFile, "export-bart", "", "Export BART items, such as buddy icons, to a file and exit")
+	flag.BoolVar(&showInfo, "info", false, "Display build information, listener addresses, and the active config with secrets redacted, then exit")
+	flag.StringVar(&importBARTFile, "import-bart", "", "Import BART items from a file created by -export-bart and exit")
 
 	flag.Parse()
 
@@ -46,6 +64,8 @@ func init() {
 }
 
 func main() {
+	parseFlags()
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -58,12 +78,109 @@ func main() {
 		g.Go(func() error { return fn.Start(ctx) })
 	}
 
+	if showInfo {
+		cfg := config.Config{}
+		if err := envconfig.Process("", &cfg); err != nil {
+			fmt.Printf("unable to process app config: %v\n", err)
+			os.Exit(1)
+		}
+		build := config.Build{
+			Version: version,
+			Commit:  commit,
+			Date:    date,
+		}
+		if err := writeInfo(os.Stdout, build, cfg); err != nil {
+			fmt.Printf("error writing info: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	deps, err := MakeCommonDeps()
 	if err != nil {
 		fmt.Printf("error initializing common deps: %v\n", err)
 		os.Exit(1)
 	}
 
+	switch {
+	case exportBARTFile != "":
+		count, err := deps.ExportBARTItems(exportBARTFile)
+		if err != nil {
+			fmt.Printf("error exporting BART items: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d BART item(s) to %s\n", count, exportBARTFile)
+		os.Exit(0)
+	case importBARTFile != "":
+		count, err := deps.ImportBARTItems(importBARTFile)
+		if err != nil {
+			fmt.Printf("error importing BART items: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported %d BART item(s) from %s\n", count, importBARTFile)
+		os.Exit(0)
+	}
+
+	// reload file-based lists on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			deps.ReloadLists()
+		}
+	}()
+
+	// delete expired chat transcript messages and rotated log files hourly
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			deps.PruneChatTranscripts()
+			deps.PruneLogFiles()
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// periodically correct stale buddy presence
+	if deps.cfg.PresenceReconcileIntervalSec > 0 {
+		go func() {
+			reconciler := PresenceReconciler(deps)
+			ticker := time.NewTicker(time.Duration(deps.cfg.PresenceReconcileIntervalSec) * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := reconciler.Reconcile(ctx); err != nil {
+						deps.logger.Error("unable to reconcile buddy presence", "err", err.Error())
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// periodically lower users' warning levels
+	if deps.cfg.ICBMWarnDecayIntervalSec > 0 {
+		go func() {
+			decayer := WarningDecayer(deps)
+			ticker := time.NewTicker(time.Duration(deps.cfg.ICBMWarnDecayIntervalSec) * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					decayer.Decay(ctx)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
 	start(Admin(deps))
 	start(Alert(deps))
 	start(Auth(deps))
@@ -71,8 +188,24 @@ func main() {
 	start(BOS(deps))
 	start(Chat(deps))
 	start(ChatNav(deps))
+	if deps.cfg.FileTransferProxyPort != "" {
+		start(FileTransferProxy(deps))
+	}
 	start(MgmtAPI(deps))
 	start(ODir(deps))
+	start(PublicAPI(deps))
+
+	if deps.cfg.TLSCertFile != "" {
+		tlsDeps := TLSDeps(deps)
+		start(Admin(tlsDeps))
+		start(Alert(tlsDeps))
+		start(Auth(tlsDeps))
+		start(BART(tlsDeps))
+		start(BOS(tlsDeps))
+		start(Chat(tlsDeps))
+		start(ChatNav(tlsDeps))
+		start(ODir(tlsDeps))
+	}
 
 	if err := g.Wait(); err != nil {
 		fmt.Println(err.Error())
@@ -1,21 +1,191 @@
 package config
 
+import (
+	"fmt"
+	"reflect"
+)
+
 //go:generate go run github.com/mk6i/retro-aim-server/cmd/config_generator unix settings.env
 type Config struct {
-	ApiHost     string `envconfig:"API_HOST" require:"true" val:"127.0.0.1" description:"The hostname or address at which the management API listens."`
-	ApiPort     string `envconfig:"API_PORT" required:"true" val:"8080" description:"The port that the management API service binds to."`
-	AlertPort   string `envconfig:"ALERT_PORT" required:"true" val:"5194" description:"The port that the Alert service binds to."`
-	AuthPort    string `envconfig:"AUTH_PORT" required:"true" val:"5190" description:"The port that the auth service binds to."`
-	BARTPort    string `envconfig:"BART_PORT" required:"true" val:"5195" description:"The port that the BART service binds to."`
-	BOSPort     string `envconfig:"BOS_PORT" required:"true" val:"5191" description:"The port that the BOS service binds to."`
-	ChatNavPort string `envconfig:"CHAT_NAV_PORT" required:"true" val:"5193" description:"The port that the chat nav service binds to."`
-	ChatPort    string `envconfig:"CHAT_PORT" required:"true" val:"5192" description:"The port that the chat service binds to."`
-	AdminPort   string `envconfig:"ADMIN_PORT" required:"true" val:"5196" description:"The port that the admin service binds to."`
-	ODirPort    string `envconfig:"ODIR_PORT" required:"true" val:"5197" description:"The port that the ODir service binds to."`
-	DBPath      string `envconfig:"DB_PATH" required:"true" val:"oscar.sqlite" description:"The path to the SQLite database file. The file and DB schema are auto-created if they doesn't exist."`
-	DisableAuth bool   `envconfig:"DISABLE_AUTH" required:"true" val:"true" description:"Disable password check and auto-create new users at login time. Useful for quickly creating new accounts during development without having to register new users via the management API."`
-	LogLevel    string `envconfig:"LOG_LEVEL" required:"true" val:"info" description:"Set logging granularity. Possible values: 'trace', 'debug', 'info', 'warn', 'error'."`
-	OSCARHost   string `envconfig:"OSCAR_HOST" required:"true" val:"127.0.0.1" description:"The hostname that AIM clients connect to in order to reach OSCAR services (auth, BOS, BUCP, etc). Make sure the hostname is reachable by all clients. For local development, the default loopback address should work provided the server and AIM client(s) are running on the same machine. For LAN-only clients, a private IP address (e.g. 192.168..) or hostname should suffice. For clients connecting over the Internet, specify your public IP address and ensure that TCP ports 5190-5197 are open on your firewall."`
+	ApiHost                       string `envconfig:"API_HOST" require:"true" val:"127.0.0.1" description:"The hostname or address at which the management API listens."`
+	ApiPort                       string `envconfig:"API_PORT" required:"true" val:"8080" description:"The port that the management API service binds to."`
+	PublicApiHost                 string `envconfig:"PUBLIC_API_HOST" required:"false" default:"127.0.0.1" val:"127.0.0.1" description:"The hostname or address at which the public API listens. The public API serves the pages that users reach from account confirmation emails and the endpoint that completes password resets. Unlike the management API, it can be exposed to users."`
+	PublicApiPort                 string `envconfig:"PUBLIC_API_PORT" required:"false" default:"8081" val:"8081" description:"The port that the public API service binds to."`
+	AlertPort                     string `envconfig:"ALERT_PORT" required:"true" val:"5194" description:"The port that the Alert service binds to."`
+	AuthPort                      string `envconfig:"AUTH_PORT" required:"true" val:"5190" description:"The port that the auth service binds to."`
+	BARTPort                      string `envconfig:"BART_PORT" required:"true" val:"5195" description:"The port that the BART service binds to."`
+	BOSPort                       string `envconfig:"BOS_PORT" required:"true" val:"5191" description:"The port that the BOS service binds to."`
+	ChatNavPort                   string `envconfig:"CHAT_NAV_PORT" required:"true" val:"5193" description:"The port that the chat nav service binds to."`
+	ChatPort                      string `envconfig:"CHAT_PORT" required:"true" val:"5192" description:"The port that the chat service binds to."`
+	AdminPort                     string `envconfig:"ADMIN_PORT" required:"true" val:"5196" description:"The port that the admin service binds to."`
+	ODirPort                      string `envconfig:"ODIR_PORT" required:"true" val:"5197" description:"The port that the ODir service binds to."`
+	FileTransferProxyPort         string `envconfig:"FILE_TRANSFER_PROXY_PORT" required:"false" val:"" description:"The port that the file transfer proxy binds to. The proxy relays file transfers between users who can't connect to each other directly, such as when both are behind NAT. AIM clients reach the proxy at ars.oscar.aol.com on port 5190, which clashes with AUTH_PORT, so point ars.oscar.aol.com at FILE_TRANSFER_PROXY_IP and forward port 5190 of that address to this port. Leave empty to disable."`
+	FileTransferProxyIP           string `envconfig:"FILE_TRANSFER_PROXY_IP" required:"false" val:"" description:"The IPv4 address at which clients reach the file transfer proxy. The sender's client passes it along to the recipient, who connects to it on port 5190. Required if FILE_TRANSFER_PROXY_PORT is set."`
+	FileTransferProxyTimeoutSec   int    `envconfig:"FILE_TRANSFER_PROXY_TIMEOUT_SEC" required:"false" default:"60" val:"60" description:"The number of seconds a sender waits on the file transfer proxy for the recipient to join before the transfer is abandoned."`
+	TLSCertFile                   string `envconfig:"TLS_CERT_FILE" required:"false" val:"" description:"The path to a PEM-encoded certificate file for the TLS listeners. When set, each OSCAR service also listens for TLS connections on its *_TLS_PORT, so that clients and proxies that speak TLS can connect without a separate TLS terminator such as stunnel. Clients that sign on through the TLS auth port are sent to the TLS ports of the other services. Leave empty to disable the TLS listeners."`
+	TLSKeyFile                    string `envconfig:"TLS_KEY_FILE" required:"false" val:"" description:"The path to the PEM-encoded private key file that matches TLS_CERT_FILE."`
+	AuthTLSPort                   string `envconfig:"AUTH_TLS_PORT" required:"false" default:"5290" val:"5290" description:"The port that the auth service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	BOSTLSPort                    string `envconfig:"BOS_TLS_PORT" required:"false" default:"5291" val:"5291" description:"The port that the BOS service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	ChatTLSPort                   string `envconfig:"CHAT_TLS_PORT" required:"false" default:"5292" val:"5292" description:"The port that the chat service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	ChatNavTLSPort                string `envconfig:"CHAT_NAV_TLS_PORT" required:"false" default:"5293" val:"5293" description:"The port that the chat nav service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	AlertTLSPort                  string `envconfig:"ALERT_TLS_PORT" required:"false" default:"5294" val:"5294" description:"The port that the Alert service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	BARTTLSPort                   string `envconfig:"BART_TLS_PORT" required:"false" default:"5295" val:"5295" description:"The port that the BART service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	AdminTLSPort                  string `envconfig:"ADMIN_TLS_PORT" required:"false" default:"5296" val:"5296" description:"The port that the admin service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	ODirTLSPort                   string `envconfig:"ODIR_TLS_PORT" required:"false" default:"5297" val:"5297" description:"The port that the ODir service binds to for TLS connections. Only used when TLS_CERT_FILE is set."`
+	DBPath                        string `envconfig:"DB_PATH" required:"true" val:"oscar.sqlite" description:"The path to the SQLite database file. The file and DB schema are auto-created if they doesn't exist."`
+	DisableAuth                   bool   `envconfig:"DISABLE_AUTH" required:"true" val:"true" description:"Disable password check and auto-create new users at login time. Useful for quickly creating new accounts during development without having to register new users via the management API."`
+	DisableWeakMD5Auth            bool   `envconfig:"DISABLE_WEAK_MD5_AUTH" required:"false" val:"false" description:"Reject sign-ons that authenticate with the weak MD5 password hash sent by AIM v3.5-v4.7, and stop storing MD5 password hashes for accounts that have an argon2id password hash. Existing weak and strong MD5 hashes of those accounts are deleted at startup, so they can no longer sign on with MD5 password hashes (AIM v3.5-v5.9). Enable this if your users run clients that send roasted passwords (AIM v1.0-v3.0, ICQ), which are checked against the argon2id hash."`
+	RegistrationRateLimit         int    `envconfig:"REGISTRATION_RATE_LIMIT" required:"false" val:"0" description:"The maximum number of accounts that can be registered from a single IP address within REGISTRATION_RATE_WINDOW_MIN minutes. Accounts are registered at sign-on when DISABLE_AUTH is true. Sign-ons that would exceed the limit are told to try again later. Set to 0 to disable the limit."`
+	RegistrationRateWindowMin     int    `envconfig:"REGISTRATION_RATE_WINDOW_MIN" required:"false" default:"60" val:"60" description:"The length, in minutes, of the sliding window that REGISTRATION_RATE_LIMIT applies to."`
+	RegistrationWebhookURL        string `envconfig:"REGISTRATION_WEBHOOK_URL" required:"false" val:"" secret:"true" description:"A URL that new accounts are posted to as JSON before they're created, such as a CAPTCHA or approval service. A 2xx response approves the registration and a 4xx response rejects it. Any other response or a delivery failure tells the client to try again later. Leave empty to register accounts without verification."`
+	LogLevel                      string `envconfig:"LOG_LEVEL" required:"true" val:"info" description:"Set logging granularity. Possible values: 'trace', 'debug', 'info', 'warn', 'error'."`
+	FoodGroupLogLevels            string `envconfig:"FOOD_GROUP_LOG_LEVELS" required:"false" val:"" description:"A comma-separated list of food group:level pairs, such as ICBM:debug,ChatNav:trace, that set the logging granularity of client requests for individual food groups, overriding LOG_LEVEL. Useful for tracing one food group without flooding the log with the others. Food groups are named as they appear in the log, such as ICBM, Feedbag, or ChatNav. Possible levels: 'trace', 'debug', 'info', 'warn', 'error'. Leave empty to log all food groups at LOG_LEVEL."`
+	OSCARHost                     string `envconfig:"OSCAR_HOST" required:"true" val:"127.0.0.1" description:"The hostname that AIM clients connect to in order to reach OSCAR services (auth, BOS, BUCP, etc). Make sure the hostname is reachable by all clients. For local development, the default loopback address should work provided the server and AIM client(s) are running on the same machine. For LAN-only clients, a private IP address (e.g. 192.168..) or hostname should suffice. For clients connecting over the Internet, specify your public IP address and ensure that TCP ports 5190-5197 are open on your firewall."`
+	MaxRendezvousFileSize         uint32 `envconfig:"MAX_RENDEZVOUS_FILE_SIZE" required:"false" val:"0" description:"The maximum file size in bytes that a user may offer in a file transfer proposal. The server cancels proposals that advertise a larger size. Set to 0 to allow file transfers of any size."`
+	MaxFileTransfersPerUser       int    `envconfig:"MAX_FILE_TRANSFERS_PER_USER" required:"false" val:"0" description:"The maximum number of file transfers that a user may take part in at once, as sender or recipient. The server cancels proposals past the cap. Transfers in progress are unaffected. Set to 0 to disable."`
+	MaxFileTransfers              int    `envconfig:"MAX_FILE_TRANSFERS" required:"false" val:"0" description:"The maximum number of file transfers that may run at once server-wide. The server cancels proposals past the cap. Since the server can't see when a direct transfer finishes, it counts toward the caps until it's accepted or cancelled. A transfer relayed through the file transfer proxy counts until its proxy session ends. No transfer counts for more than an hour. Set to 0 to disable."`
+	MaxSessions                   int    `envconfig:"MAX_SESSIONS" required:"false" val:"0" description:"The maximum number of users who may be signed on at once. Once the server is full, sign-on attempts are refused with an error that tells clients to wait a few minutes before reconnecting, which spreads out reconnects after a restart. Users who are already signed on are unaffected. Set to 0 to disable."`
+	ConcurrentLoginPolicy         string `envconfig:"CONCURRENT_LOGIN_POLICY" required:"false" default:"kick-old" val:"kick-old" description:"What happens when a user signs on while they're already signed on elsewhere. Possible values: 'kick-old' (sign off the existing session in favor of the new one), 'reject-new' (refuse the new sign-on until the existing session ends), 'allow-multiple' (keep both sessions signed on, deliver messages to both, and show buddies a single presence until the last session signs off). Since 'reject-new' relies on dead connections being noticed, it requires SERVER_KEEPALIVE_SEC to be set. Operators can override the policy for individual accounts via the management API."`
+	OutboundBatchMs               int    `envconfig:"OUTBOUND_BATCH_MS" required:"false" val:"0" description:"The number of milliseconds to buffer outbound BOS and chat messages before writing them to the client connection. Batching coalesces bursts of messages, such as chat room fan-out, into fewer network writes at the cost of up to this much added latency. Set to 0 to disable batching."`
+	ServiceCookieTTLSec           int    `envconfig:"SERVICE_COOKIE_TTL_SEC" required:"false" default:"60" val:"60" description:"The number of seconds that a login cookie issued for a service redirect (BOS, chat, etc) remains valid. Clients that connect to the redirected service after the cookie expires are refused and must sign on again."`
+	ServiceCookieSingleUse        bool   `envconfig:"SERVICE_COOKIE_SINGLE_USE" required:"false" default:"true" val:"true" description:"Allow each login cookie issued for a service redirect to be redeemed only once, so that an intercepted cookie can't be replayed to hijack a session. Clients that reconnect with a cookie they already used are refused and must sign on again."`
+	ICQUINStart                   uint32 `envconfig:"ICQ_UIN_START" required:"false" default:"100000" val:"100000" description:"The first UIN that the server allocates to new ICQ accounts registered via the management API. UINs are allocated sequentially and the next UIN is persisted, so set this above any historical UIN range you want to avoid."`
+	ICQUINEnd                     uint32 `envconfig:"ICQ_UIN_END" required:"false" default:"999999999" val:"999999999" description:"The last UIN that the server may allocate to new ICQ accounts. Registration fails once the range between ICQ_UIN_START and ICQ_UIN_END is used up."`
+	ICBMMaxMessageLen             uint16 `envconfig:"ICBM_MAX_MESSAGE_LEN" required:"false" val:"0" description:"The maximum length in bytes of instant message text. Longer messages are rejected. This value is advertised to clients so they can enforce it before sending. Set to 0 to disable, in which case a limit of 512 bytes is advertised."`
+	ICBMMaxSenderWarnLevel        uint16 `envconfig:"ICBM_MAX_SENDER_WARN_LEVEL" required:"false" default:"999" val:"999" description:"The maximum warning level, in tenths of a percent (0-999), at which a user may send instant messages. This value is advertised to clients."`
+	ICBMMaxRecipientWarnLevel     uint16 `envconfig:"ICBM_MAX_RECIPIENT_WARN_LEVEL" required:"false" default:"999" val:"999" description:"The maximum warning level, in tenths of a percent (0-999), at which a user may receive instant messages. This value is advertised to clients."`
+	ICBMWarnDecayIntervalSec      int    `envconfig:"ICBM_WARN_DECAY_INTERVAL_SEC" required:"false" default:"60" val:"60" description:"The number of seconds between decreases of signed-on users' warning levels. Each decrease lowers the level by ICBM_WARN_DECAY_AMOUNT and informs the user and their buddies of the new level. Set to 0 to disable, in which case warnings last until the user signs off."`
+	ICBMWarnDecayAmount           uint16 `envconfig:"ICBM_WARN_DECAY_AMOUNT" required:"false" default:"10" val:"10" description:"The amount, in tenths of a percent, that a warned user's warning level decreases every ICBM_WARN_DECAY_INTERVAL_SEC seconds."`
+	ICBMMinMessageIntervalMs      uint32 `envconfig:"ICBM_MIN_MESSAGE_INTERVAL_MS" required:"false" val:"0" description:"The minimum number of milliseconds between instant messages sent by a user. Messages sent faster are rejected. This value is advertised to clients. Set to 0 to disable."`
+	ICBMSelfMessages              string `envconfig:"ICBM_SELF_MESSAGES" required:"false" default:"deliver" val:"deliver" description:"How to handle instant messages that users send to their own screen name. Possible values: 'deliver' (echo the message back to the sender, useful for testing), 'drop' (silently discard the message), 'error' (reject the message with an error)."`
+	UserLookupMinIntervalMs       uint32 `envconfig:"USER_LOOKUP_MIN_INTERVAL_MS" required:"false" val:"0" description:"The minimum number of milliseconds between user lookups by email address made by a user. Lookups made faster are rejected with a rate limit error. Set to 0 to disable."`
+	UserLookupMaxPerSession       int    `envconfig:"USER_LOOKUP_MAX_PER_SESSION" required:"false" val:"0" description:"The maximum number of user lookups by email address that a user may make per session. Once reached, lookups return no results until the user signs on again. This limits harvesting of screen names by email address. Set to 0 to disable."`
+	TraceLogFile                  string `envconfig:"TRACE_LOG_FILE" required:"false" val:"" description:"Path to a file that receives log output, including TRACE level client request logs, instead of standard output. The file is rotated once it reaches TRACE_MAX_SIZE_MB, and rotated files older than TRACE_RETENTION_DAYS are deleted. Leave empty to log to standard output."`
+	TraceMaxSizeMB                int    `envconfig:"TRACE_MAX_SIZE_MB" required:"false" default:"100" val:"100" description:"The size in megabytes at which TRACE_LOG_FILE is rotated. The rotated file is renamed with a timestamp suffix. Set to 0 to disable rotation."`
+	TraceRetentionDays            int    `envconfig:"TRACE_RETENTION_DAYS" required:"false" default:"7" val:"7" description:"The number of days to keep log files rotated from TRACE_LOG_FILE. Older files are deleted hourly. Set to 0 to keep rotated files forever."`
+	CaptureFile                   string `envconfig:"CAPTURE_FILE" required:"false" val:"" description:"Path to a binary file that records every FLAP frame exchanged with clients on the BOS, chat, and admin services, tagged with the connection and screen name. Useful for replaying protocol bugs offline with cmd/capture_reader. Auth service traffic isn't captured so that passwords stay out of the file. The file grows quickly and contains private messages, so enable it only while debugging. The file is rotated once it reaches CAPTURE_MAX_SIZE_MB. Leave empty to disable capturing."`
+	CaptureMaxSizeMB              int    `envconfig:"CAPTURE_MAX_SIZE_MB" required:"false" default:"100" val:"100" description:"The size in megabytes at which CAPTURE_FILE is rotated. The rotated file is renamed with a timestamp suffix. Set to 0 to disable rotation."`
+	CaptureRetentionDays          int    `envconfig:"CAPTURE_RETENTION_DAYS" required:"false" default:"7" val:"7" description:"The number of days to keep capture files rotated from CAPTURE_FILE. Older files are deleted hourly. Set to 0 to keep rotated files forever."`
+	ServerName                    string `envconfig:"SERVER_NAME" required:"false" val:"" description:"The name of this server. When set, clients are sent a message of the day after signing on that identifies the server name and the server's version and commit. Some clients show the message in their connection info. Leave empty to disable."`
+	FeedbagClusterTimeoutSec      int    `envconfig:"FEEDBAG_CLUSTER_TIMEOUT_SEC" required:"false" val:"0" description:"The number of seconds that a client may leave a group of server-side buddy list edits open before the server treats the group as abandoned and closes it. While a group is open, requests to open another group are rejected. Some clients open groups inside other groups, so only enable this for clients that don't. Set to 0 to disable tracking of edit groups."`
+	FeedbagLargeListWarnItems     int    `envconfig:"FEEDBAG_LARGE_LIST_WARN_ITEMS" required:"false" default:"1000" val:"1000" description:"Log a warning when a user signs on with a server-side buddy list that has more than this many items, since older clients may struggle to load very large lists. Set to 0 to disable the warning."`
+	FeedbagReplyMaxItems          int    `envconfig:"FEEDBAG_REPLY_MAX_ITEMS" required:"false" val:"0" description:"The maximum number of buddy list items sent in a single reply when a client fetches its server-side buddy list. Larger lists are split across multiple replies. Set to 0 to always send the whole list in one reply."`
+	VirtualUserWebhookURL         string `envconfig:"VIRTUAL_USER_WEBHOOK_URL" secret:"true" required:"false" val:"" description:"A URL that receives a JSON POST request for each instant message sent to a virtual user. Virtual users are contacts bridged from an external system, such as an XMPP gateway, whose presence is set using the management API. Leave empty to drop messages sent to virtual users."`
+	OutboundHTTPProxy             string `envconfig:"OUTBOUND_HTTP_PROXY" required:"false" val:"" description:"The URL of an HTTP proxy, such as http://proxy:3128, that webhooks are sent through. Hosts listed in NO_PROXY are reached directly. Leave empty to use the proxy set by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, if any."`
+	SearchCacheTTLSec             int    `envconfig:"SEARCH_CACHE_TTL_SEC" required:"false" val:"0" description:"The number of seconds to cache the results of user directory searches, such as AIM interest searches and ICQ white pages searches. Identical searches within this window are served from memory instead of the database. The cache is cleared whenever a user changes their directory info, an account is created or deleted, or the interest keywords change. Set to 0 to disable."`
+	DirInfoOfflineUsers           bool   `envconfig:"DIR_INFO_OFFLINE_USERS" required:"false" default:"true" val:"true" description:"Return a user's stored directory info when another user looks them up while they're signed off. When disabled, directory info is only returned for users who are signed on. Users whose registration status is set to no disclosure never have their directory info returned to others."`
+	EnableDebugAPI                bool   `envconfig:"ENABLE_DEBUG_API" required:"false" val:"false" description:"Enable the management API debug endpoints, such as POST /debug/snac, which sends raw SNACs to connected clients. Intended for protocol development only. Do not enable in production."`
+	ServerKeepaliveSec            int    `envconfig:"SERVER_KEEPALIVE_SEC" required:"false" val:"0" description:"The number of seconds a BOS or chat connection may go without receiving anything from the client before the server sends a FLAP keepalive probe. If the client sends nothing for another interval after the probe, the connection is closed. This detects dead connections faster than TCP timeouts. Set it well above the client keepalive interval so that quiet clients aren't dropped. Set to 0 to disable."`
+	AutoSuspendWarnThreshold      int    `envconfig:"AUTO_SUSPEND_WARN_THRESHOLD" required:"false" val:"0" description:"The number of warnings a user may receive within AUTO_SUSPEND_WARN_WINDOW_MIN minutes before their account is automatically suspended. A suspended user is disconnected and can't sign on again until AUTO_SUSPEND_COOLDOWN_MIN minutes have passed. Set to 0 to disable."`
+	AutoSuspendWarnWindowMin      int    `envconfig:"AUTO_SUSPEND_WARN_WINDOW_MIN" required:"false" default:"60" val:"60" description:"The number of minutes over which warnings count toward AUTO_SUSPEND_WARN_THRESHOLD."`
+	AutoSuspendCooldownMin        int    `envconfig:"AUTO_SUSPEND_COOLDOWN_MIN" required:"false" default:"60" val:"60" description:"The number of minutes that an account stays suspended after receiving too many warnings."`
+	BanListFile                   string `envconfig:"BAN_LIST_FILE" required:"false" val:"" description:"Path to a file of screen names and IP addresses that are barred from signing on, one per line. Entries that are an IP address or CIDR, such as 203.0.113.7 or 198.51.100.0/24, bar clients from logging in or connecting to BOS from those addresses. Blank lines and lines starting with # are ignored. The file is reloaded when the server receives SIGHUP. Leave empty to disable."`
+	BanLoginErrorCode             uint16 `envconfig:"BAN_LOGIN_ERROR_CODE" required:"false" default:"17" val:"17" description:"The login error code, in decimal, that accounts banned via the management API get when they try to sign on. The default of 17 tells the client that the account is suspended. Other useful codes are 5 (incorrect password) and 24 (rate limited, try again later)."`
+	FilterListFile                string `envconfig:"FILTER_LIST_FILE" required:"false" val:"" description:"Path to a file of words and phrases that are prohibited in instant messages, one per line. Matching is case-insensitive. Blank lines and lines starting with # are ignored. The file is reloaded when the server receives SIGHUP. Leave empty to disable."`
+	AutoReciprocateBuddies        bool   `envconfig:"AUTO_RECIPROCATE_BUDDIES" required:"false" val:"false" description:"When a user adds someone to their client-side buddy list, also add the user to the other party's server-side buddy list, so that buddy relationships are mutual. The entry is not added if either party blocks the other."`
+	DefaultGroupName              string `envconfig:"DEFAULT_GROUP_NAME" required:"false" default:"Buddies" val:"Buddies" description:"The name of the server-side buddy list group that the server places buddies in when it adds them on a user's behalf without a group, such as through auto-reciprocation or the management API. The group is created if the user doesn't have it. Leave empty to use Buddies."`
+	ContentEncryptionKey          string `envconfig:"CONTENT_ENCRYPTION_KEY" secret:"true" required:"false" val:"" description:"A hex-encoded 16, 24, or 32 byte AES key used to encrypt profiles, offline messages, and chat transcripts stored in the database. Content stored before the key was set remains readable. Keep the key safe: encrypted content can't be recovered without it. Leave empty to store content unencrypted."`
+	QuietHoursStart               string `envconfig:"QUIET_HOURS_START" required:"false" val:"" description:"The time of day, in 24-hour HH:MM format and server time, when quiet hours begin. During quiet hours, non-critical server alerts sent via the management API are held and delivered when quiet hours end. Held alerts are lost if the server restarts. Leave empty along with QUIET_HOURS_END to disable."`
+	QuietHoursEnd                 string `envconfig:"QUIET_HOURS_END" required:"false" val:"" description:"The time of day, in 24-hour HH:MM format and server time, when quiet hours end. Quiet hours may span midnight, for example 22:00 to 07:00."`
+	ICQDefaultRequireAuth         bool   `envconfig:"ICQ_DEFAULT_REQUIRE_AUTH" required:"false" val:"false" description:"Require other users to ask permission before adding new ICQ accounts to their contact lists. Users can change this setting from their ICQ client."`
+	ICQDefaultWebAware            bool   `envconfig:"ICQ_DEFAULT_WEB_AWARE" required:"false" val:"false" description:"Allow the online status of new ICQ accounts to be shown outside of ICQ, such as on the web. Users can change this setting from their ICQ client."`
+	ICQOccupiedSuppressesDelivery bool   `envconfig:"ICQ_OCCUPIED_SUPPRESSES_DELIVERY" required:"false" val:"false" description:"Hold back instant messages sent to ICQ users in 'occupied' status, as is always done for users in 'do not disturb' status. The sender gets an automatic reply saying that the user is occupied. Users in 'away', 'N/A', and 'free for chat' status always receive messages."`
+	ChatDeliveryFailureNotices    bool   `envconfig:"CHAT_DELIVERY_FAILURE_NOTICES" required:"false" val:"false" description:"When a chat room participant is disconnected because their connection can't keep up with the room's messages, tell the rest of the room how many messages they missed. Useful for diagnosing dropped connections."`
+	IgnoredSNACs                  string `envconfig:"IGNORED_SNACS" required:"false" val:"" description:"A comma-separated list of food group:subgroup pairs, such as 0x0001:0x0022, for SNACs that the server doesn't support but should accept without replying. By default, unsupported SNACs get an error reply, which makes some clients with vendor-specific extensions disconnect. Numbers may be decimal or 0x-prefixed hex. Leave empty to disable."`
+	ChatTranscripts               bool   `envconfig:"CHAT_TRANSCRIPTS" required:"false" val:"false" description:"Record chat room messages in the database so that room transcripts can be reviewed via the management API. Each entry records the sender, time, and message text."`
+	ChatTranscriptTTLHours        int    `envconfig:"CHAT_TRANSCRIPT_TTL_HOURS" required:"false" default:"720" val:"720" description:"The number of hours that chat transcript messages are kept before they are deleted. Set to 0 to keep transcripts forever."`
+	MaxChatRooms                  int    `envconfig:"MAX_CHAT_ROOMS" required:"false" val:"0" description:"The maximum number of chat rooms that may be in use server-wide. Only rooms that have at least one participant count toward the limit. Once the limit is reached, users can't create new rooms, but they can still join existing ones. Set to 0 to disable."`
+	ChatRoomCreation              string `envconfig:"CHAT_ROOM_CREATION" required:"false" default:"everyone" val:"everyone" description:"Who may create private chat rooms. Users who aren't allowed to create rooms can still join existing rooms. Possible values: 'everyone' (any signed-on user), 'confirmed' (only users whose accounts are confirmed), 'flagged' (only users granted permission via the management API PUT /user/{screenname}/chat-room-creator endpoint)."`
+	StorageQuotaBytes             int64  `envconfig:"STORAGE_QUOTA_BYTES" required:"false" val:"0" description:"The maximum number of bytes that the server stores on behalf of each user, counting offline messages waiting for the user, the user's profile, and the user's server-side buddy list. Offline messages that would take the recipient past the quota are bounced back to the sender. Operators can override the quota for individual users via the management API PUT /user/{screenname}/storage endpoint. Set to 0 to disable."`
+	ICQBroadcastOffline           bool   `envconfig:"ICQ_BROADCAST_OFFLINE" required:"false" val:"false" description:"Store ICQ system broadcasts sent via the management API for ICQ users who are offline. Stored broadcasts are delivered with the user's offline messages at next sign-on. When disabled, only online ICQ users receive broadcasts."`
+	BroadcastScreenName           string `envconfig:"BROADCAST_SCREEN_NAME" required:"false" val:"" description:"The screen name that server-wide broadcasts sent via the management API POST /broadcast endpoint appear to come from. Broadcasts are delivered to every online user as an instant message from this screen name. Leave empty to deliver broadcasts as a server message of the day instead."`
+	ICQXMLKeys                    string `envconfig:"ICQ_XML_KEYS" required:"false" val:"" description:"A comma-separated list of key:value pairs, such as DataFilesIP:127.0.0.1, that answer ICQ clients requesting server settings by key over the XML request channel. ICQ 2002 and 2003 clients request keys such as DataFilesIP, BannersIP, and ChannelsIP. Requests for keys that aren't listed, and other XML requests, get an empty reply. Leave empty to answer all XML requests with an empty reply."`
+	AutoResponseLoopPrevention    bool   `envconfig:"AUTO_RESPONSE_LOOP_PREVENTION" required:"false" default:"true" val:"true" description:"Prevent automatic replies, such as away messages and do-not-disturb notices, from triggering each other endlessly. An auto-response is only delivered if it answers an instant message typed by the other user, and auto-responses never trigger a do-not-disturb notice."`
+	DefaultBuddyIconFile          string `envconfig:"DEFAULT_BUDDY_ICON_FILE" required:"false" val:"" description:"Path to an image file, such as a GIF, that is shown as the buddy icon of users who haven't uploaded their own. Users can replace it by setting a buddy icon in their client. Leave empty to disable."`
+	BuddyTransientWatches         bool   `envconfig:"BUDDY_TRANSIENT_WATCHES" required:"false" default:"true" val:"true" description:"Let users with server-side buddy lists watch the presence of users who aren't on their list, such as when an IM window is open with a non-buddy. Watches last until the client removes them or the user signs off."`
+	ChatWhisperDisabledExchanges  string `envconfig:"CHAT_WHISPER_DISABLED_EXCHANGES" required:"false" val:"" description:"A comma-separated list of chat exchange IDs, such as 4,5, in which users can't whisper to other participants. Exchange 4 hosts rooms created by users and exchange 5 hosts public rooms. Whispers sent in these exchanges are refused. Leave empty to allow whispering everywhere."`
+	ChatInviteDisabledExchanges   string `envconfig:"CHAT_INVITE_DISABLED_EXCHANGES" required:"false" val:"" description:"A comma-separated list of chat exchange IDs, such as 4,5, whose rooms users can't invite others to. Invitations to rooms in these exchanges are refused. Users can still join the rooms directly. Leave empty to allow invitations everywhere."`
+	DropEmptyMessages             bool   `envconfig:"DROP_EMPTY_MESSAGES" required:"false" val:"false" description:"Drop instant messages and chat messages that are empty or contain only whitespace instead of delivering them. Some clients send blank messages by accident."`
+	PresenceReconcileIntervalSec  int    `envconfig:"PRESENCE_RECONCILE_INTERVAL_SEC" required:"false" val:"0" description:"The number of seconds between checks that correct stale buddy presence, such as a buddy who still appears online after their connection dropped. Each check resends arrival and departure notifications for buddies whose presence doesn't match who is actually signed on. Set to 0 to disable."`
+	OmitCapabilities              string `envconfig:"OMIT_CAPABILITIES" required:"false" val:"" description:"A comma-separated list of capability UUIDs to strip from the capability lists that clients advertise before they are relayed to buddies, such as 0946134A-4C7F-11D1-8222-444553540000 for games. All other capabilities, including ones the server doesn't recognize, are relayed verbatim. Leave empty to relay every capability."`
+	RendezvousCapabilities        string `envconfig:"RENDEZVOUS_CAPABILITIES" required:"false" val:"" description:"A comma-separated list of capability UUIDs, such as 09461343-4C7F-11D1-8222-444553540000 for file transfer, that users may propose rendezvous sessions for. The server cancels proposals for other capabilities, such as games or direct IM. Include 748F2420-6287-11D1-8222-444553540000 to keep chat invitations working. Leave empty to allow all capabilities."`
+	WatchedAccountWebhookURL      string `envconfig:"WATCHED_ACCOUNT_WEBHOOK_URL" secret:"true" required:"false" val:"" description:"A URL that receives a JSON POST request whenever a watched account signs on. Accounts are watched using the management API. Sign-ons of watched accounts are always written to the server log. Leave empty to disable the webhook."`
+	ScreenNameAllowedSymbols      string `envconfig:"SCREEN_NAME_ALLOWED_SYMBOLS" required:"false" val:"" description:"Punctuation characters, such as _-., that new AIM screen names may contain in addition to letters, digits, and spaces. Screen names must still start with a letter. Applies to registration and screen name formatting changes. Leave empty to allow only letters, digits, and spaces."`
+	ScreenNameASCIIOnly           bool   `envconfig:"SCREEN_NAME_ASCII_ONLY" required:"false" val:"false" description:"Only allow ASCII letters and digits in new AIM screen names, rejecting accented and other non-English letters."`
+	ScreenNameMinLetters          int    `envconfig:"SCREEN_NAME_MIN_LETTERS" required:"false" default:"3" val:"3" description:"The minimum number of letters that new AIM screen names must contain."`
+	ScreenNameMaxLength           int    `envconfig:"SCREEN_NAME_MAX_LENGTH" required:"false" default:"16" val:"16" description:"The maximum length in bytes of new AIM screen names, including spaces. Must be no greater than 255. Many older clients can't display screen names longer than 16 characters."`
+	FLAPCompression               bool   `envconfig:"FLAP_COMPRESSION" required:"false" val:"false" description:"Offer to compress BOS and chat connection traffic with DEFLATE, which saves bandwidth on slow links. Compression is a server extension that is only used if the client accepts the offer at sign-on. Clients that don't support it stay uncompressed, but some clients may reject the offer and fail to connect, so leave this off unless your clients support it."`
+	SMTPHost                      string `envconfig:"SMTP_HOST" required:"false" val:"" description:"The hostname of the SMTP server used to email account confirmation links. When a user asks to confirm their account, they are emailed a link to the public API /confirm endpoint, which confirms the account. The link expires after 24 hours. Leave empty to confirm accounts immediately without sending email."`
+	SMTPPort                      string `envconfig:"SMTP_PORT" required:"false" default:"587" val:"587" description:"The port of the SMTP server. The connection is upgraded to TLS if the server supports it."`
+	SMTPUsername                  string `envconfig:"SMTP_USERNAME" required:"false" val:"" description:"The username used to sign in to the SMTP server. Leave empty if the server doesn't require authentication."`
+	SMTPPassword                  string `envconfig:"SMTP_PASSWORD" secret:"true" required:"false" val:"" description:"The password used to sign in to the SMTP server."`
+	SMTPFrom                      string `envconfig:"SMTP_FROM" required:"false" val:"" description:"The email address that account confirmation emails are sent from."`
+	AccountConfirmURL             string `envconfig:"ACCOUNT_CONFIRM_URL" required:"false" default:"http://127.0.0.1:8081/confirm" val:"http://127.0.0.1:8081/confirm" description:"The address of the public API /confirm endpoint as reached by users. The confirmation token is appended to this URL in confirmation emails. The public API must be reachable at this address for users to confirm their accounts."`
+	RestrictUnconfirmedAccounts   bool   `envconfig:"RESTRICT_UNCONFIRMED_ACCOUNTS" required:"false" val:"false" description:"Stop users whose accounts aren't confirmed from sending instant messages. Users confirm their accounts from their client, which requires an email address to be set on the account."`
+	NewbieRestrictionMin          int    `envconfig:"NEWBIE_RESTRICTION_MIN" required:"false" val:"0" description:"The number of minutes after an account is created during which it can't send chat messages or send instant messages to users who aren't on its buddy list. This limits spam from freshly created accounts. Accounts created before this setting existed are never restricted. Set to 0 to disable."`
+	BARTMaxItemSize               uint32 `envconfig:"BART_MAX_ITEM_SIZE" required:"false" val:"0" description:"The maximum size in bytes of buddy icons and other items that users upload to the BART service. Larger uploads are rejected. Set to 0 to allow uploads of any size."`
+	BARTIconFormats               string `envconfig:"BART_ICON_FORMATS" required:"false" default:"gif,jpeg,png,bmp" val:"gif,jpeg,png,bmp" description:"A comma-separated list of image formats that users may upload as buddy icons. Possible values: 'gif', 'jpeg', 'png', 'bmp'. Uploads whose content isn't an image in one of these formats are rejected. Leave empty to accept buddy icons without checking their content."`
+	PasswordResetBlocksLogin      bool   `envconfig:"PASSWORD_RESET_BLOCKS_LOGIN" required:"false" val:"false" description:"Refuse sign-on for accounts with a pending password reset, which operators start via the management API, until the user sets a new password with the reset token. Set to false to let the old password keep working until the reset is completed."`
+	SignonTimeoutSec              int    `envconfig:"SIGNON_TIMEOUT_SEC" required:"false" default:"120" val:"120" description:"The number of seconds a client has to finish signing on to BOS after connecting. Buddies don't see the user online until sign-on completes, so connections that never finish signing on are closed once this time passes. Set to 0 to disable."`
+	AIMOfflineMessages            bool   `envconfig:"AIM_OFFLINE_MESSAGES" required:"false" val:"false" description:"Store instant messages sent to AIM screen names that are offline and deliver them, stamped with the time they were sent, when the recipient next signs on. ICQ offline messages are stored regardless of this setting."`
+	OfflineMessageLimit           int    `envconfig:"OFFLINE_MESSAGE_LIMIT" required:"false" val:"0" description:"The maximum number of offline messages stored for each AIM user. Messages sent to a user who already has this many messages waiting are bounced back to the sender. The limit applies to AIM users only; ICQ offline messages are not limited. Set to 0 to disable."`
+	RateLimitEnforce              bool   `envconfig:"RATE_LIMIT_ENFORCE" required:"false" val:"false" description:"Enforce the SNAC rate limits that the server advertises to clients. Clients that send too fast are warned, then have their requests dropped with a rate limit error until they slow down, and are disconnected if they keep going. Set to false to only advertise the limits."`
+	RateLimitWindowSize           uint32 `envconfig:"RATE_LIMIT_WINDOW_SIZE" required:"false" default:"80" val:"80" description:"The number of recent SNACs that the rolling average time between a client's SNACs is computed over."`
+	RateLimitClearLevel           uint32 `envconfig:"RATE_LIMIT_CLEAR_LEVEL" required:"false" default:"2500" val:"2500" description:"The rolling average, in milliseconds between SNACs, that a rate-limited or warned client must climb back to before its limit is cleared."`
+	RateLimitAlertLevel           uint32 `envconfig:"RATE_LIMIT_ALERT_LEVEL" required:"false" default:"2000" val:"2000" description:"The rolling average, in milliseconds between SNACs, below which a client is warned that it's approaching the rate limit."`
+	RateLimitLimitLevel           uint32 `envconfig:"RATE_LIMIT_LIMIT_LEVEL" required:"false" default:"1500" val:"1500" description:"The rolling average, in milliseconds between SNACs, below which a client's SNACs are dropped until its average climbs back to the clear level."`
+	RateLimitDisconnectLevel      uint32 `envconfig:"RATE_LIMIT_DISCONNECT_LEVEL" required:"false" default:"800" val:"800" description:"The rolling average, in milliseconds between SNACs, below which a client is disconnected."`
+	RateLimitMaxLevel             uint32 `envconfig:"RATE_LIMIT_MAX_LEVEL" required:"false" default:"6000" val:"6000" description:"The highest value the rolling average can reach. Clients start at this level when they connect. Levels must satisfy DISCONNECT < LIMIT < ALERT < CLEAR <= MAX."`
+}
+
+// Possible values of ICBMSelfMessages.
+const (
+	SelfMessagesDeliver = "deliver"
+	SelfMessagesDrop    = "drop"
+	SelfMessagesError   = "error"
+)
+
+// Possible values of ConcurrentLoginPolicy.
+const (
+	ConcurrentLoginKickOld       = "kick-old"
+	ConcurrentLoginRejectNew     = "reject-new"
+	ConcurrentLoginAllowMultiple = "allow-multiple"
+)
+
+// Possible values of ChatRoomCreation.
+const (
+	ChatRoomCreationEveryone  = "everyone"
+	ChatRoomCreationConfirmed = "confirmed"
+	ChatRoomCreationFlagged   = "flagged"
+)
+
+// redactedValue replaces the value of a secret setting in Settings.
+const redactedValue = "[redacted]"
+
+// Setting is a config variable and its value.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Settings returns the config variables and their values in declaration
+// order. The values of variables tagged as secret, such as passwords and
+// keys, are redacted unless they are empty.
+func (c Config) Settings() []Setting {
+	v := reflect.ValueOf(c)
+	settings := make([]Setting, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := fmt.Sprint(v.Field(i).Interface())
+		if field.Tag.Get("secret") == "true" && value != "" {
+			value = redactedValue
+		}
+		settings = append(settings, Setting{
+			Name:  field.Tag.Get("envconfig"),
+			Value: value,
+		})
+	}
+	return settings
 }
 
 type Build struct {
@@ -3,7 +3,9 @@ package foodgroup
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/mail"
+	"time"
 
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
@@ -15,11 +17,15 @@ func NewAdminService(
 	buddyListRetriever BuddyListRetriever,
 	messageRelayer MessageRelayer,
 	sessionRetriever SessionRetriever,
+	screenNamePolicy state.ScreenNamePolicy,
+	confirmationSender ConfirmationSender,
 ) *AdminService {
 	return &AdminService{
-		accountManager:   accountManager,
-		buddyBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
-		messageRelayer:   messageRelayer,
+		accountManager:     accountManager,
+		buddyBroadcaster:   newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		confirmationSender: confirmationSender,
+		messageRelayer:     messageRelayer,
+		screenNamePolicy:   screenNamePolicy,
 	}
 }
 
@@ -29,10 +35,17 @@ func NewAdminService(
 type AdminService struct {
 	accountManager   AccountManager
 	buddyBroadcaster buddyBroadcaster
-	messageRelayer   MessageRelayer
+	// confirmationSender emails account confirmation links. Accounts are
+	// confirmed immediately if nil.
+	confirmationSender ConfirmationSender
+	messageRelayer     MessageRelayer
+	screenNamePolicy   state.ScreenNamePolicy
 }
 
-// ConfirmRequest will mark the user account as confirmed if the user has an email address set
+// ConfirmRequest handles a request to confirm the user's account, which must
+// have an email address set. If a confirmation sender is configured, the user
+// is emailed a link that confirms the account. Otherwise, the account is
+// confirmed immediately.
 func (s AdminService) ConfirmRequest(ctx context.Context, sess *state.Session, frame wire.SNACFrame) (wire.SNACMessage, error) {
 	// getAdminInfoReply returns an AdminAcctConfirmReply SNAC
 	var getAdminConfirmReply = func(status uint16) wire.SNACMessage {
@@ -48,7 +61,7 @@ func (s AdminService) ConfirmRequest(ctx context.Context, sess *state.Session, f
 		}
 	}
 
-	_, err := s.accountManager.EmailAddressByName(sess.IdentScreenName())
+	emailAddress, err := s.accountManager.EmailAddressByName(sess.IdentScreenName())
 	if errors.Is(err, state.ErrNoEmailAddress) {
 		return getAdminConfirmReply(wire.AdminAcctConfirmStatusServerError), nil
 	} else if err != nil {
@@ -62,6 +75,17 @@ func (s AdminService) ConfirmRequest(ctx context.Context, sess *state.Session, f
 	if accountConfirmed {
 		return getAdminConfirmReply(wire.AdminAcctConfirmStatusAlreadyConfirmed), nil
 	}
+	if s.confirmationSender != nil {
+		token, err := newConfirmToken()
+		if err != nil {
+			return wire.SNACMessage{}, fmt.Errorf("unable to create confirmation token: %w", err)
+		}
+		if err := s.accountManager.SetConfirmToken(token, time.Now().Add(confirmTokenTTL), sess.IdentScreenName()); err != nil {
+			return wire.SNACMessage{}, err
+		}
+		s.confirmationSender.SendConfirmation(sess.DisplayScreenName(), emailAddress, token)
+		return getAdminConfirmReply(wire.AdminAcctConfirmStatusEmailSent), nil
+	}
 	if err := s.accountManager.UpdateConfirmStatus(true, sess.IdentScreenName()); err != nil {
 		return wire.SNACMessage{}, err
 	}
@@ -152,7 +176,7 @@ func (s AdminService) InfoChangeRequest(ctx context.Context, sess *state.Session
 
 	// validateProposedName ensures that the name is valid
 	var validateProposedName = func(name state.DisplayScreenName) (ok bool, errorCode uint16) {
-		err := name.ValidateAIMHandle()
+		err := state.ValidateScreenName(s.screenNamePolicy, name)
 		switch {
 		case errors.Is(err, state.ErrAIMHandleLength):
 			// proposed name is too long
@@ -3,6 +3,7 @@ package foodgroup
 import (
 	"net/mail"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -207,6 +208,62 @@ func TestAdminService_ConfirmRequest(t *testing.T) {
 	}
 }
 
+func TestAdminService_ConfirmRequest_EmailConfirmation(t *testing.T) {
+	sess := newTestSession("ChattingChuck")
+	sess.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+	emailAddress := &mail.Address{Address: "chattingchuck@aol.com"}
+
+	accountManager := newMockAccountManager(t)
+	accountManager.EXPECT().
+		EmailAddressByName(state.NewIdentScreenName("ChattingChuck")).
+		Return(emailAddress, nil)
+	accountManager.EXPECT().
+		ConfirmStatusByName(state.NewIdentScreenName("ChattingChuck")).
+		Return(false, nil)
+
+	var storedToken string
+	accountManager.EXPECT().
+		SetConfirmToken(mock.Anything, mock.Anything, state.NewIdentScreenName("ChattingChuck")).
+		Run(func(token string, expiresAt time.Time, screenName state.IdentScreenName) {
+			storedToken = token
+		}).
+		Return(nil)
+
+	var sentToken string
+	confirmationSender := newMockConfirmationSender(t)
+	confirmationSender.EXPECT().
+		SendConfirmation(state.DisplayScreenName("ChattingChuck"), emailAddress, mock.Anything).
+		Run(func(screenName state.DisplayScreenName, emailAddress *mail.Address, token string) {
+			sentToken = token
+		})
+
+	svc := AdminService{
+		accountManager:     accountManager,
+		buddyBroadcaster:   newMockbuddyBroadcaster(t),
+		confirmationSender: confirmationSender,
+		messageRelayer:     newMockMessageRelayer(t),
+	}
+	outputSNAC, err := svc.ConfirmRequest(nil, sess, wire.SNACFrame{RequestID: 1234})
+	assert.NoError(t, err)
+
+	assert.Equal(t, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Admin,
+			SubGroup:  wire.AdminAcctConfirmReply,
+			RequestID: 1234,
+		},
+		Body: wire.SNAC_0x07_0x07_AdminConfirmReply{
+			Status: wire.AdminAcctConfirmStatusEmailSent,
+		},
+	}, outputSNAC)
+
+	// the emailed token is the one stored on the account
+	assert.Len(t, storedToken, 32)
+	assert.Equal(t, storedToken, sentToken)
+	// the account stays unconfirmed until the link is followed
+	assert.NotZero(t, sess.UserInfoBitmask()&wire.OServiceUserFlagUnconfirmed)
+}
+
 func TestAdminService_InfoQuery(t *testing.T) {
 	cases := []struct {
 		// name is the unit test name
@@ -461,6 +518,8 @@ func TestAdminService_InfoChangeRequest_ScreenName(t *testing.T) {
 		// mockParams is the list of params sent to mocks that satisfy this
 		// method's dependencies
 		mockParams mockParams
+		// screenNamePolicy is the screen name policy enforced by the service
+		screenNamePolicy state.ScreenNamePolicy
 		// userSession is the session of the user
 		userSession *state.Session
 		// expectOutput is the SNAC sent from the server to client
@@ -632,6 +691,40 @@ func TestAdminService_InfoChangeRequest_ScreenName(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:             "proposed screen name contains non-ASCII letter disallowed by policy",
+			userSession:      newTestSession("chattingchuck"),
+			screenNamePolicy: state.ScreenNamePolicy{ASCIIOnly: true},
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Admin,
+					SubGroup:  wire.AdminInfoChangeRequest,
+					RequestID: 1337,
+				},
+				Body: wire.SNAC_0x07_0x04_AdminInfoChangeRequest{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.AdminTLVScreenNameFormatted, "ChättingChuck")},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Admin,
+					SubGroup:  wire.AdminInfoChangeReply,
+					RequestID: 1337,
+				},
+				Body: wire.SNAC_0x07_0x05_AdminChangeReply{
+					Permissions: wire.AdminInfoPermissionsReadWrite,
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.AdminTLVErrorCode, wire.AdminInfoErrorInvalidNickName),
+							wire.NewTLVBE(wire.AdminTLVUrl, ""),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -662,6 +755,7 @@ func TestAdminService_InfoChangeRequest_ScreenName(t *testing.T) {
 				accountManager:   accountManager,
 				buddyBroadcaster: mockBuddyBroadcaster,
 				messageRelayer:   messageRelayer,
+				screenNamePolicy: tc.screenNamePolicy,
 			}
 			outputSNAC, err := svc.InfoChangeRequest(nil, tc.userSession, tc.inputSNAC.Frame, tc.inputSNAC.Body.(wire.SNAC_0x07_0x04_AdminInfoChangeRequest))
 			assert.ErrorIs(t, err, tc.expectErr)
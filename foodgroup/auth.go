@@ -5,7 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/netip"
 	"strconv"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 // NewAuthService creates a new instance of AuthService.
 func NewAuthService(
 	cfg config.Config,
+	logger *slog.Logger,
 	sessionManager SessionRegistry,
 	chatSessionRegistry ChatSessionRegistry,
 	userManager UserManager,
@@ -26,17 +29,28 @@ func NewAuthService(
 	chatMessageRelayer ChatMessageRelayer,
 	accountManager AccountManager,
 	adminServerSessionRetriever SessionRetriever,
+	banList BanList,
+	watchedAccountNotifier WatchedAccountNotifier,
+	screenNamePolicy state.ScreenNamePolicy,
+	registrationLimiter RegistrationLimiter,
+	registrationVerifier RegistrationVerifier,
 ) *AuthService {
 	return &AuthService{
+		banList:             banList,
 		chatSessionRegistry: chatSessionRegistry,
 		config:              cfg,
 		cookieBaker:         cookieBaker,
+		logger:              logger,
 		sessionManager:      sessionManager,
 		userManager:         userManager,
 		chatMessageRelayer:  chatMessageRelayer,
 		accountManager:      accountManager,
 		// hack - adminServerSessionRetriever is just used for admin server
 		adminServerSessionRetriever: adminServerSessionRetriever,
+		registrationLimiter:         registrationLimiter,
+		registrationVerifier:        registrationVerifier,
+		screenNamePolicy:            screenNamePolicy,
+		watchedAccountNotifier:      watchedAccountNotifier,
 	}
 }
 
@@ -44,14 +58,20 @@ func NewAuthService(
 // supports both FLAP (AIM v1.0-v3.0) and BUCP (AIM v3.5-v5.9) authentication
 // modes.
 type AuthService struct {
+	banList                     BanList
 	chatMessageRelayer          ChatMessageRelayer
 	chatSessionRegistry         ChatSessionRegistry
 	config                      config.Config
 	cookieBaker                 CookieBaker
+	logger                      *slog.Logger
 	sessionManager              SessionRegistry
 	userManager                 UserManager
 	accountManager              AccountManager
 	adminServerSessionRetriever SessionRetriever
+	registrationLimiter         RegistrationLimiter
+	registrationVerifier        RegistrationVerifier
+	screenNamePolicy            state.ScreenNamePolicy
+	watchedAccountNotifier      WatchedAccountNotifier
 }
 
 // RegisterChatSession adds a user to a chat room. The authCookie param is an
@@ -74,6 +94,9 @@ func (s AuthService) RegisterChatSession(authCookie []byte) (*state.Session, err
 	if err != nil {
 		return nil, fmt.Errorf("AddSession: %w", err)
 	}
+	if c.AccountCreatedAt > 0 {
+		sess.SetAccountCreatedAt(time.Unix(int64(c.AccountCreatedAt), 0))
+	}
 	return sess, err
 }
 
@@ -107,7 +130,17 @@ func (s AuthService) RegisterBOSSession(ctx context.Context, authCookie []byte)
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
-	sess, err := s.sessionManager.AddSession(ctx, u.DisplayScreenName)
+	var sess *state.Session
+	switch u.ConcurrentLogin(s.config.ConcurrentLoginPolicy) {
+	case config.ConcurrentLoginRejectNew:
+		// keep the active session, if any, and refuse this one
+		sess, err = s.sessionManager.AddSessionIfAbsent(u.DisplayScreenName)
+	case config.ConcurrentLoginAllowMultiple:
+		// sign on alongside the active session, if any
+		sess, err = s.sessionManager.AddSessionInstance(ctx, u.DisplayScreenName)
+	default:
+		sess, err = s.sessionManager.AddSession(ctx, u.DisplayScreenName)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("AddSession: %w", err)
 	}
@@ -119,9 +152,16 @@ func (s AuthService) RegisterBOSSession(ctx context.Context, authCookie []byte)
 		sess.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
 	}
 
+	// Badge staff and bot accounts
+	if u.IsOfficial {
+		sess.SetUserInfoFlag(wire.OServiceUserFlagOfficial)
+	}
+
 	// set string containing OSCAR client name and version
 	sess.SetClientID(c.ClientID)
 
+	sess.SetAccountCreatedAt(u.CreatedAt)
+
 	if u.DisplayScreenName.IsUIN() {
 		sess.SetUserInfoFlag(wire.OServiceUserFlagICQ)
 
@@ -166,9 +206,14 @@ func (s AuthService) Signout(_ context.Context, sess *state.Session) {
 }
 
 // SignoutChat removes user from chat room and notifies remaining participants
-// of their departure.
+// of their departure. If chat delivery-failure notices are enabled and the
+// user was dropped because they couldn't keep up, the participants are also
+// told how many messages the user missed.
 func (s AuthService) SignoutChat(ctx context.Context, sess *state.Session) {
 	alertUserLeft(ctx, sess, s.chatMessageRelayer)
+	if s.config.ChatDeliveryFailureNotices {
+		alertUserMissedMessages(ctx, sess, s.chatMessageRelayer)
+	}
 	s.chatSessionRegistry.RemoveSession(sess)
 }
 
@@ -238,13 +283,14 @@ func (s AuthService) BUCPChallenge(
 // If login is successful, the SNAC TLV list contains the BOS server address
 // (wire.LoginTLVTagsReconnectHere) and an authorization cookie
 // (wire.LoginTLVTagsAuthorizationCookie). Else, an error code is set
-// (wire.LoginTLVTagsErrorSubcode).
+// (wire.LoginTLVTagsErrorSubcode). remoteAddr is the IP address of the client.
 func (s AuthService) BUCPLogin(
 	bodyIn wire.SNAC_0x17_0x02_BUCPLoginRequest,
 	newUserFn func(screenName state.DisplayScreenName) (state.User, error),
+	remoteAddr netip.Addr,
 ) (wire.SNACMessage, error) {
 
-	block, err := s.login(bodyIn.TLVList, newUserFn)
+	block, err := s.login(bodyIn.TLVList, newUserFn, remoteAddr)
 	if err != nil {
 		return wire.SNACMessage{}, err
 	}
@@ -269,12 +315,13 @@ func (s AuthService) BUCPLogin(
 // If login is successful, the SNAC TLV list contains the BOS server address
 // (wire.LoginTLVTagsReconnectHere) and an authorization cookie
 // (wire.LoginTLVTagsAuthorizationCookie). Else, an error code is set
-// (wire.LoginTLVTagsErrorSubcode).
+// (wire.LoginTLVTagsErrorSubcode). remoteAddr is the IP address of the client.
 func (s AuthService) FLAPLogin(
 	frame wire.FLAPSignonFrame,
 	newUserFn func(screenName state.DisplayScreenName) (state.User, error),
+	remoteAddr netip.Addr,
 ) (wire.TLVRestBlock, error) {
-	return s.login(frame.TLVList, newUserFn)
+	return s.login(frame.TLVList, newUserFn, remoteAddr)
 }
 
 // loginProperties represents the properties sent by the client at login.
@@ -319,10 +366,21 @@ func (l *loginProperties) fromTLV(list wire.TLVList) error {
 }
 
 // login validates a user's credentials and creates their session. it returns
-// metadata used in both BUCP and FLAP authentication responses.
+// metadata used in both BUCP and FLAP authentication responses. Users on the
+// ban list or connecting from a banned address are refused, as is everyone
+// once config.Config.MaxSessions users
+// are signed on. Users restricted to certain networks are refused when logging
+// in from elsewhere, and the attempt is written to the audit log. Operators
+// are notified when a watched user logs in. Users who log in with a screen
+// name alias are signed on to the account that the alias belongs to. Accounts
+// with a pending password reset may be refused, depending on configuration.
+// Users who are already signed on are refused if their concurrent login
+// policy is config.ConcurrentLoginRejectNew. Accounts banned via the
+// management API are refused with config.Config.BanLoginErrorCode.
 func (s AuthService) login(
 	tlv wire.TLVList,
 	newUserFn func(screenName state.DisplayScreenName) (state.User, error),
+	remoteAddr netip.Addr,
 ) (wire.TLVRestBlock, error) {
 
 	props := loginProperties{}
@@ -330,6 +388,23 @@ func (s AuthService) login(
 		return wire.TLVRestBlock{}, err
 	}
 
+	if s.banList.Banned(props.screenName.IdentScreenName()) {
+		return loginFailureResponse(props, wire.LoginErrSuspendedAccount), nil
+	}
+
+	if s.banList.BannedAddr(remoteAddr) {
+		s.logger.Info("login refused from banned address",
+			"screen_name", props.screenName.String(), "client_id", props.clientID, "remote_addr", remoteAddr.String())
+		return loginFailureResponse(props, wire.LoginErrSuspendedAccount), nil
+	}
+
+	if s.config.MaxSessions > 0 && s.sessionManager.SessionCount() >= s.config.MaxSessions {
+		// the server is full. OSCAR has no field for a reconnect delay, but
+		// clients tell the user to wait a few minutes before reconnecting
+		// when they get this error.
+		return loginFailureResponse(props, wire.LoginErrRateLimitExceeded), nil
+	}
+
 	user, err := s.userManager.User(props.screenName.IdentScreenName())
 	if err != nil {
 		return wire.TLVRestBlock{}, err
@@ -339,7 +414,7 @@ func (s AuthService) login(
 		// user not found
 		if s.config.DisableAuth {
 			// auth disabled, create the user
-			return s.createUser(props, newUserFn)
+			return s.createUser(props, newUserFn, remoteAddr)
 		}
 		// auth enabled, return separate login errors for ICQ and AIM
 		loginErr := wire.LoginErrInvalidUsernameOrPassword
@@ -349,34 +424,116 @@ func (s AuthService) login(
 		return loginFailureResponse(props, loginErr), nil
 	}
 
+	if user.IdentScreenName != props.screenName.IdentScreenName() {
+		// the user signed on with an alias, which signs them on to the
+		// account that the alias belongs to
+		props.screenName = user.DisplayScreenName
+		if s.banList.Banned(user.IdentScreenName) {
+			return loginFailureResponse(props, wire.LoginErrSuspendedAccount), nil
+		}
+	}
+
+	if user.IsBanned {
+		s.logger.Info("login refused for banned account",
+			"screen_name", props.screenName.String(), "client_id", props.clientID)
+		return loginFailureResponse(props, s.config.BanLoginErrorCode), nil
+	}
+
+	if !user.LoginAllowedFrom(remoteAddr) {
+		// refuse before checking the password so that the password can't be
+		// guessed from outside the allowed networks. the error doesn't reveal
+		// the restriction.
+		s.logger.Warn("audit: login refused from disallowed network",
+			"screen_name", props.screenName.String(), "client_id", props.clientID, "remote_addr", remoteAddr.String())
+		return loginFailureResponse(props, wire.LoginErrInvalidUsernameOrPassword), nil
+	}
+
 	if s.config.DisableAuth {
 		// user exists, but don't validate
+		if s.rejectsConcurrentLogin(*user) {
+			return loginFailureResponse(props, wire.LoginErrRateLimitExceeded), nil
+		}
+		s.notifyIfWatched(*user, props)
 		return s.loginSuccessResponse(props)
 	}
 
+	if s.config.PasswordResetBlocksLogin && user.PasswordResetPending {
+		// the old password stops working once a reset is requested
+		s.logger.Warn("audit: login refused for account with pending password reset",
+			"screen_name", props.screenName.String(), "client_id", props.clientID)
+		return loginFailureResponse(props, wire.LoginErrInvalidPassword), nil
+	}
+
 	var loginOK bool
-	if props.isBUCPAuth {
+	switch {
+	case props.isBUCPAuth && s.config.DisableWeakMD5Auth:
+		loginOK = user.ValidateStrongHash(props.passwordHash)
+	case props.isBUCPAuth:
 		loginOK = user.ValidateHash(props.passwordHash)
-	} else {
+	default:
 		loginOK = user.ValidateRoastedPass(props.roastedPass)
 	}
 	if !loginOK {
 		return loginFailureResponse(props, wire.LoginErrInvalidPassword), nil
 	}
 
+	if !props.isBUCPAuth && user.PasswordHash == "" {
+		s.upgradePasswordHash(*user, string(wire.RoastPassword(props.roastedPass)))
+	}
+
+	if s.rejectsConcurrentLogin(*user) {
+		// like a full server, this tells the client to try again later,
+		// by which time the active session may have signed off.
+		return loginFailureResponse(props, wire.LoginErrRateLimitExceeded), nil
+	}
+
+	s.notifyIfWatched(*user, props)
 	return s.loginSuccessResponse(props)
 }
 
+// upgradePasswordHash stores the argon2id hash of the password of a user
+// whose password was set before argon2id hashes were stored. Failures are
+// logged and don't affect the login.
+func (s AuthService) upgradePasswordHash(user state.User, passwd string) {
+	if err := user.SetPasswordHash(passwd); err != nil {
+		s.logger.Error("unable to hash password", "screen_name", user.IdentScreenName.String(), "err", err.Error())
+		return
+	}
+	if err := s.userManager.SetPasswordHash(user.IdentScreenName, user.PasswordHash); err != nil {
+		s.logger.Error("unable to store password hash", "screen_name", user.IdentScreenName.String(), "err", err.Error())
+		return
+	}
+	s.logger.Debug("upgraded password hash", "screen_name", user.IdentScreenName.String())
+}
+
+// rejectsConcurrentLogin indicates whether the user's concurrent login policy
+// refuses the sign-on because the user is already signed on.
+func (s AuthService) rejectsConcurrentLogin(user state.User) bool {
+	return user.ConcurrentLogin(s.config.ConcurrentLoginPolicy) == config.ConcurrentLoginRejectNew &&
+		s.sessionManager.RetrieveSession(user.IdentScreenName) != nil
+}
+
+// notifyIfWatched tells operators that a watched account is signing on.
+func (s AuthService) notifyIfWatched(user state.User, props loginProperties) {
+	if user.IsWatched {
+		s.watchedAccountNotifier.WatchedSignOn(props.screenName, props.clientID)
+	}
+}
+
+// createUser registers a new account for a user signing on with auth
+// disabled. Registrations from remoteAddr are subject to the registration
+// rate limit and verification hook, if configured.
 func (s AuthService) createUser(
 	props loginProperties,
 	newUserFn func(screenName state.DisplayScreenName) (state.User, error),
+	remoteAddr netip.Addr,
 ) (wire.TLVRestBlock, error) {
 
 	var err error
 	if props.screenName.IsUIN() {
 		err = props.screenName.ValidateUIN()
 	} else {
-		err = props.screenName.ValidateAIMHandle()
+		err = state.ValidateScreenName(s.screenNamePolicy, props.screenName)
 	}
 
 	if err != nil {
@@ -390,11 +547,43 @@ func (s AuthService) createUser(
 		}
 	}
 
+	if s.registrationLimiter != nil && !s.registrationLimiter.Allow(remoteAddr) {
+		s.logger.Warn("audit: registration refused, too many registrations from address",
+			"screen_name", props.screenName.String(), "client_id", props.clientID, "remote_addr", remoteAddr.String())
+		return loginFailureResponse(props, wire.LoginErrRateLimitExceeded), nil
+	}
+
+	if s.registrationVerifier != nil {
+		approved, err := s.registrationVerifier.VerifyRegistration(props.screenName, props.clientID, remoteAddr)
+		if err != nil {
+			// fail closed so that an unreachable verification service doesn't
+			// let registrations through. the client is told to try again
+			// later.
+			s.logger.Error("unable to verify registration",
+				"screen_name", props.screenName.String(), "err", err.Error())
+			return loginFailureResponse(props, wire.LoginErrRateLimitExceeded), nil
+		}
+		if !approved {
+			loginErr := wire.LoginErrInvalidUsernameOrPassword
+			if props.screenName.IsUIN() {
+				loginErr = wire.LoginErrICQUserErr
+			}
+			return loginFailureResponse(props, loginErr), nil
+		}
+	}
+
 	newUser, err := newUserFn(props.screenName)
 	if err != nil {
 		return wire.TLVRestBlock{}, err
 	}
 
+	if newUser.IsICQ {
+		newUser.ICQPermissions = state.ICQPermissions{
+			AuthRequired: s.config.ICQDefaultRequireAuth,
+			WebAware:     s.config.ICQDefaultWebAware,
+		}
+	}
+
 	err = s.userManager.InsertUser(newUser)
 	if err != nil {
 		return wire.TLVRestBlock{}, err
@@ -5,6 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/mk6i/retro-aim-server/config"
@@ -56,6 +60,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -112,6 +124,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -168,6 +188,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -192,6 +220,45 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "AIM account is on the ban list, login fails",
+			cfg: config.Config{
+				OSCARHost: "127.0.0.1",
+				BOSPort:   "1234",
+			},
+			inputSNAC: wire.SNAC_0x17_0x02_BUCPLoginRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+						wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+					},
+				},
+			},
+			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     true,
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BUCP,
+					SubGroup:  wire.BUCPLoginResponse,
+				},
+				Body: wire.SNAC_0x17_0x03_BUCPLoginResponse{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: []wire.TLV{
+							wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+							wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, wire.LoginErrSuspendedAccount),
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "AIM account doesn't exist, login fails",
 			cfg: config.Config{
@@ -207,6 +274,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("non_existent_screen_name"),
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -246,6 +321,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -286,6 +369,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -334,6 +425,92 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ICQ account doesn't exist, authentication is disabled, account is created with default permissions, login succeeds",
+			cfg: config.Config{
+				OSCARHost:             "127.0.0.1",
+				BOSPort:               "1234",
+				DisableAuth:           true,
+				ICQDefaultRequireAuth: true,
+				ICQDefaultWebAware:    true,
+			},
+			inputSNAC: wire.SNAC_0x17_0x02_BUCPLoginRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, []byte("100003")),
+						wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, []byte("password")),
+					},
+				},
+			},
+			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     false,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     nil,
+						},
+					},
+					insertUserParams: insertUserParams{
+						{
+							user: state.User{
+								IdentScreenName:   state.NewIdentScreenName("100003"),
+								DisplayScreenName: "100003",
+								IsICQ:             true,
+								ICQPermissions: state.ICQPermissions{
+									AuthRequired: true,
+									WebAware:     true,
+								},
+							},
+						},
+					},
+				},
+				cookieBakerParams: cookieBakerParams{
+					cookieIssueParams: cookieIssueParams{
+						{
+							dataIn: func() []byte {
+								loginCookie := bosCookie{
+									ScreenName: "100003",
+								}
+								buf := &bytes.Buffer{}
+								assert.NoError(t, wire.MarshalBE(loginCookie, buf))
+								return buf.Bytes()
+							}(),
+							cookieOut: []byte("the-cookie"),
+						},
+					},
+				},
+			},
+			newUserFn: func(screenName state.DisplayScreenName) (state.User, error) {
+				return state.User{
+					IdentScreenName:   screenName.IdentScreenName(),
+					DisplayScreenName: screenName,
+					IsICQ:             true,
+				}, nil
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BUCP,
+					SubGroup:  wire.BUCPLoginResponse,
+				},
+				Body: wire.SNAC_0x17_0x03_BUCPLoginResponse{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.LoginTLVTagsScreenName, state.DisplayScreenName("100003")),
+							wire.NewTLVBE(wire.LoginTLVTagsReconnectHere, "127.0.0.1:1234"),
+							wire.NewTLVBE(wire.LoginTLVTagsAuthorizationCookie, []byte("the-cookie")),
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "AIM account doesn't exist, authentication is disabled, screen name has bad format, login fails",
 			cfg: config.Config{
@@ -350,6 +527,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("2coolforschool"),
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -390,6 +575,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("99"),
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -430,6 +623,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -484,6 +685,14 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -516,13 +725,25 @@ func TestAuthService_BUCPLoginRequest(t *testing.T) {
 					Issue(params.dataIn).
 					Return(params.cookieOut, params.err)
 			}
+			banList := newMockBanList(t)
+			for _, params := range tc.mockParams.bannedParams {
+				banList.EXPECT().
+					Banned(params.screenName).
+					Return(params.result)
+			}
+			// the tests log in without a remote address, which isn't banned
+			banList.EXPECT().
+				BannedAddr(netip.Addr{}).
+				Return(false).
+				Maybe()
 
 			svc := AuthService{
+				banList:     banList,
 				config:      tc.cfg,
 				cookieBaker: cookieBaker,
 				userManager: userManager,
 			}
-			outputSNAC, err := svc.BUCPLogin(tc.inputSNAC, tc.newUserFn)
+			outputSNAC, err := svc.BUCPLogin(tc.inputSNAC, tc.newUserFn, netip.Addr{})
 			assert.ErrorIs(t, err, tc.wantErr)
 			assert.Equal(t, tc.expectOutput, outputSNAC)
 		})
@@ -572,6 +793,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -620,6 +849,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -668,6 +905,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -684,6 +929,37 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "AIM account is on the ban list, login fails",
+			cfg: config.Config{
+				OSCARHost: "127.0.0.1",
+				BOSPort:   "1234",
+			},
+			inputSNAC: wire.FLAPSignonFrame{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsRoastedPassword, []byte("roasted_password")),
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					},
+				},
+			},
+			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     true,
+						},
+					},
+				},
+			},
+			expectOutput: wire.TLVRestBlock{
+				TLVList: []wire.TLV{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					wire.NewTLVBE(wire.LoginTLVTagsErrorSubcode, wire.LoginErrSuspendedAccount),
+				},
+			},
+		},
 		{
 			name: "AIM account doesn't exist, login fails",
 			cfg: config.Config{
@@ -699,6 +975,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("non_existent_screen_name"),
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -731,6 +1015,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -763,6 +1055,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -819,6 +1119,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -865,6 +1173,14 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				banListParams: banListParams{
+					bannedParams: bannedParams{
+						{
+							screenName: user.IdentScreenName,
+							result:     false,
+						},
+					},
+				},
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
@@ -897,34 +1213,828 @@ func TestAuthService_FLAPLoginResponse(t *testing.T) {
 					Issue(params.dataIn).
 					Return(params.cookieOut, params.err)
 			}
+			banList := newMockBanList(t)
+			for _, params := range tc.mockParams.bannedParams {
+				banList.EXPECT().
+					Banned(params.screenName).
+					Return(params.result)
+			}
+			// the tests log in without a remote address, which isn't banned
+			banList.EXPECT().
+				BannedAddr(netip.Addr{}).
+				Return(false).
+				Maybe()
 			svc := AuthService{
+				banList:     banList,
 				config:      tc.cfg,
 				cookieBaker: cookieBaker,
 				userManager: userManager,
 			}
-			outputSNAC, err := svc.FLAPLogin(tc.inputSNAC, tc.newUserFn)
+			outputSNAC, err := svc.FLAPLogin(tc.inputSNAC, tc.newUserFn, netip.Addr{})
 			assert.ErrorIs(t, err, tc.wantErr)
 			assert.Equal(t, tc.expectOutput, outputSNAC)
 		})
 	}
 }
 
-func TestAuthService_BUCPChallengeRequest(t *testing.T) {
-	sessUUID := uuid.UUID{1, 2, 3}
-	cases := []struct {
+func TestAuthService_BUCPLoginRequest_ServerFull(t *testing.T) {
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("screenName"),
+		DisplayScreenName: "screenName",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	otherSess, err := sessionManager.AddSession(context.Background(), "otherUser")
+	assert.NoError(t, err)
+
+	banList := newMockBanList(t)
+	banList.EXPECT().
+		Banned(user.IdentScreenName).
+		Return(false)
+	banList.EXPECT().
+		BannedAddr(netip.Addr{}).
+		Return(false)
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(user.IdentScreenName).
+		Return(&user, nil).
+		Once()
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil).
+		Once()
+
+	svc := AuthService{
+		banList: banList,
+		config: config.Config{
+			MaxSessions: 1,
+		},
+		cookieBaker:    cookieBaker,
+		sessionManager: sessionManager,
+		userManager:    userManager,
+	}
+
+	inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+				wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+			},
+		},
+	}
+
+	// the server is full, the user is told to reconnect later
+	outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+	assert.NoError(t, err)
+	body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	errCode, ok := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.True(t, ok)
+	assert.Equal(t, wire.LoginErrRateLimitExceeded, errCode)
+
+	// a user signs off, the user is now let in
+	sessionManager.RemoveSession(otherSess)
+
+	outputSNAC, err = svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+	assert.NoError(t, err)
+	body = outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	_, ok = body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.False(t, ok)
+}
+
+func TestAuthService_BUCPLoginRequest_ConcurrentLogin(t *testing.T) {
+	tests := []struct {
 		// name is the unit test name
 		name string
-		// cfg is the app configuration
-		cfg config.Config
-		// inputSNAC is the SNAC sent from the client to the server
-		inputSNAC wire.SNAC_0x17_0x06_BUCPChallengeRequest
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-		// expectOutput is the SNAC sent from the server to client
-		expectOutput wire.SNACMessage
-		// wantErr is the error we expect from the method
-		wantErr error
+		// policy is the server's concurrent login policy
+		policy string
+		// userPolicy is the user's concurrent login policy
+		userPolicy string
+		// wantErrCode is the login error code, or 0 if login succeeds
+		wantErrCode uint16
+	}{
+		{
+			name:   "kick-old server policy lets the user in",
+			policy: config.ConcurrentLoginKickOld,
+		},
+		{
+			name:        "reject-new server policy refuses the user",
+			policy:      config.ConcurrentLoginRejectNew,
+			wantErrCode: wire.LoginErrRateLimitExceeded,
+		},
+		{
+			name:        "reject-new user policy overrides the server policy",
+			policy:      config.ConcurrentLoginKickOld,
+			userPolicy:  config.ConcurrentLoginRejectNew,
+			wantErrCode: wire.LoginErrRateLimitExceeded,
+		},
+		{
+			name:       "kick-old user policy overrides the server policy",
+			policy:     config.ConcurrentLoginRejectNew,
+			userPolicy: config.ConcurrentLoginKickOld,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := state.User{
+				IdentScreenName:       state.NewIdentScreenName("screenName"),
+				DisplayScreenName:     "screenName",
+				AuthKey:               "auth_key",
+				ConcurrentLoginPolicy: tt.userPolicy,
+			}
+			assert.NoError(t, user.HashPassword("the_password"))
+
+			// the user is already signed on
+			sessionManager := state.NewInMemorySessionManager(slog.Default())
+			_, err := sessionManager.AddSession(context.Background(), user.DisplayScreenName)
+			assert.NoError(t, err)
+
+			userManager := newMockUserManager(t)
+			userManager.EXPECT().
+				User(user.IdentScreenName).
+				Return(&user, nil)
+			cookieBaker := newMockCookieBaker(t)
+			if tt.wantErrCode == 0 {
+				cookieBaker.EXPECT().
+					Issue(mock.Anything).
+					Return([]byte("the-cookie"), nil)
+			}
+
+			svc := AuthService{
+				banList:        state.NewBanList(""),
+				config:         config.Config{ConcurrentLoginPolicy: tt.policy},
+				cookieBaker:    cookieBaker,
+				sessionManager: sessionManager,
+				userManager:    userManager,
+			}
+
+			inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+						wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+					},
+				},
+			}
+			outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+			assert.NoError(t, err)
+
+			body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+			errCode, hasErr := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+			if tt.wantErrCode == 0 {
+				assert.False(t, hasErr)
+			} else {
+				assert.True(t, hasErr)
+				assert.Equal(t, tt.wantErrCode, errCode)
+			}
+		})
+	}
+}
+
+func TestAuthService_BUCPLoginRequest_BannedAccount(t *testing.T) {
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("screenName"),
+		DisplayScreenName: "screenName",
+		AuthKey:           "auth_key",
+		IsBanned:          true,
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(user.IdentScreenName).
+		Return(&user, nil)
+
+	svc := AuthService{
+		banList: state.NewBanList(""),
+		config: config.Config{
+			BanLoginErrorCode: wire.LoginErrInvalidPassword,
+		},
+		logger:      slog.Default(),
+		userManager: userManager,
+	}
+
+	inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+				wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+			},
+		},
+	}
+
+	// the banned user gets the configured error code
+	outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+	assert.NoError(t, err)
+	body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	errCode, ok := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.True(t, ok)
+	assert.Equal(t, wire.LoginErrInvalidPassword, errCode)
+}
+
+func TestAuthService_BUCPLoginRequest_DisableWeakMD5Auth(t *testing.T) {
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("screenName"),
+		DisplayScreenName: "screenName",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(user.IdentScreenName).
+		Return(&user, nil)
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil)
+
+	svc := AuthService{
+		banList: state.NewBanList(""),
+		config: config.Config{
+			DisableWeakMD5Auth: true,
+		},
+		cookieBaker: cookieBaker,
+		userManager: userManager,
+	}
+
+	login := func(passwordHash []byte) (uint16, bool) {
+		inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, passwordHash),
+				},
+			},
+		}
+		outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+		assert.NoError(t, err)
+		body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+		return body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	}
+
+	// the weak MD5 hash sent by AIM v3.5-v4.7 is rejected
+	errCode, ok := login(user.WeakMD5Pass)
+	assert.True(t, ok)
+	assert.Equal(t, wire.LoginErrInvalidPassword, errCode)
+
+	// the strong MD5 hash sent by AIM v4.8+ is accepted
+	_, ok = login(user.StrongMD5Pass)
+	assert.False(t, ok)
+}
+
+func TestAuthService_FLAPLogin_UpgradePasswordHash(t *testing.T) {
+	// legacyUser's password was set before argon2id hashes were stored
+	legacyUser := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Legacy User"),
+		DisplayScreenName: "Legacy User",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, legacyUser.HashPassword("the_password"))
+	legacyUser.PasswordHash = ""
+
+	strongUser := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Strong User"),
+		DisplayScreenName: "Strong User",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, strongUser.HashPassword("the_password"))
+
+	// roastedPassword the roasted form of "the_password"
+	roastedPassword := []byte{0x87, 0x4E, 0xE4, 0x9B, 0x49, 0xE7, 0xA8, 0xE1, 0x06, 0xCC, 0xCB, 0x82}
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(legacyUser.IdentScreenName).
+		Return(&legacyUser, nil)
+	userManager.EXPECT().
+		User(strongUser.IdentScreenName).
+		Return(&strongUser, nil)
+	// only the legacy account gets an argon2id hash
+	userManager.EXPECT().
+		SetPasswordHash(legacyUser.IdentScreenName, mock.MatchedBy(func(passwordHash string) bool {
+			u := state.User{PasswordHash: passwordHash}
+			return u.ValidatePassword("the_password")
+		})).
+		Return(nil).
+		Once()
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil)
+
+	svc := AuthService{
+		banList:     state.NewBanList(""),
+		cookieBaker: cookieBaker,
+		logger:      slog.Default(),
+		userManager: userManager,
+	}
+
+	for _, user := range []state.User{legacyUser, strongUser} {
+		inputSNAC := wire.FLAPSignonFrame{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					wire.NewTLVBE(wire.LoginTLVTagsRoastedPassword, roastedPassword),
+				},
+			},
+		}
+		outputTLV, err := svc.FLAPLogin(inputSNAC, nil, netip.Addr{})
+		assert.NoError(t, err)
+		_, ok := outputTLV.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+		assert.False(t, ok)
+	}
+}
+
+func TestAuthService_BUCPLoginRequest_FileBanList(t *testing.T) {
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Banned User"),
+		DisplayScreenName: "Banned User",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	banFile := filepath.Join(t.TempDir(), "bans.txt")
+	assert.NoError(t, os.WriteFile(banFile, []byte("# banned users\nBanned User\n"), 0644))
+
+	banList := state.NewBanList(banFile)
+	assert.NoError(t, banList.Load())
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(user.IdentScreenName).
+		Return(&user, nil).
+		Once()
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil).
+		Once()
+
+	svc := AuthService{
+		banList:     banList,
+		cookieBaker: cookieBaker,
+		userManager: userManager,
+	}
+
+	inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+				wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+			},
+		},
+	}
+
+	// the listed user is refused
+	outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+	assert.NoError(t, err)
+	body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	errCode, ok := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.True(t, ok)
+	assert.Equal(t, wire.LoginErrSuspendedAccount, errCode)
+
+	// remove the user from the file and reload, the user is now let in
+	assert.NoError(t, os.WriteFile(banFile, []byte("# banned users\n"), 0644))
+	assert.NoError(t, banList.Load())
+
+	outputSNAC, err = svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+	assert.NoError(t, err)
+	body = outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	_, ok = body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.False(t, ok)
+}
+
+func TestAuthService_BUCPLoginRequest_FileBanListAddr(t *testing.T) {
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Some User"),
+		DisplayScreenName: "Some User",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	banFile := filepath.Join(t.TempDir(), "bans.txt")
+	assert.NoError(t, os.WriteFile(banFile, []byte("# banned networks\n198.51.100.0/24\n"), 0644))
+
+	banList := state.NewBanList(banFile)
+	assert.NoError(t, banList.Load())
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(user.IdentScreenName).
+		Return(&user, nil).
+		Once()
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil).
+		Once()
+
+	svc := AuthService{
+		banList:     banList,
+		cookieBaker: cookieBaker,
+		logger:      slog.Default(),
+		userManager: userManager,
+	}
+
+	inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+				wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+			},
+		},
+	}
+
+	// the user is refused from the banned network
+	outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.MustParseAddr("198.51.100.7"))
+	assert.NoError(t, err)
+	body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	errCode, ok := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.True(t, ok)
+	assert.Equal(t, wire.LoginErrSuspendedAccount, errCode)
+
+	// the user is let in from elsewhere
+	outputSNAC, err = svc.BUCPLogin(inputSNAC, nil, netip.MustParseAddr("203.0.113.7"))
+	assert.NoError(t, err)
+	body = outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	_, ok = body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.False(t, ok)
+}
+
+// TestAuthService_BUCPLoginRequest_WatchedAccount verifies that operators are
+// notified when a watched account logs in, but not an unwatched one.
+func TestAuthService_BUCPLoginRequest_WatchedAccount(t *testing.T) {
+	watched := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Watched User"),
+		DisplayScreenName: "Watched User",
+		AuthKey:           "auth_key",
+		IsWatched:         true,
+	}
+	assert.NoError(t, watched.HashPassword("the_password"))
+
+	unwatched := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Unwatched User"),
+		DisplayScreenName: "Unwatched User",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, unwatched.HashPassword("the_password"))
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(watched.IdentScreenName).
+		Return(&watched, nil)
+	userManager.EXPECT().
+		User(unwatched.IdentScreenName).
+		Return(&unwatched, nil)
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil)
+	watchedAccountNotifier := newMockWatchedAccountNotifier(t)
+	watchedAccountNotifier.EXPECT().
+		WatchedSignOn(watched.DisplayScreenName, "AIM Client").
+		Once()
+
+	svc := AuthService{
+		banList:                state.NewBanList(""),
+		cookieBaker:            cookieBaker,
+		userManager:            userManager,
+		watchedAccountNotifier: watchedAccountNotifier,
+	}
+
+	for _, user := range []state.User{watched, unwatched} {
+		inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+					wire.NewTLVBE(wire.LoginTLVTagsClientIdentity, "AIM Client"),
+					wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+				},
+			},
+		}
+		outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+		assert.NoError(t, err)
+		body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+		_, ok := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+		assert.False(t, ok)
+	}
+}
+
+// TestAuthService_BUCPLoginRequest_AllowedNetworks verifies that a user
+// restricted to certain networks can log in from those networks only, and
+// that refused attempts are written to the audit log.
+func TestAuthService_BUCPLoginRequest_Alias(t *testing.T) {
+	// the user store returns the canonical account when looking up an alias
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Canonical User"),
+		DisplayScreenName: "Canonical User",
+		AuthKey:           "auth_key",
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	userManager := newMockUserManager(t)
+	userManager.EXPECT().
+		User(state.NewIdentScreenName("The Alias")).
+		Return(&user, nil)
+
+	wantCookie := bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(bosCookie{ScreenName: "Canonical User"}, &wantCookie))
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(wantCookie.Bytes()).
+		Return([]byte("the-cookie"), nil)
+
+	svc := AuthService{
+		banList:     state.NewBanList(""),
+		cookieBaker: cookieBaker,
+		userManager: userManager,
+	}
+
+	inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LoginTLVTagsScreenName, "The Alias"),
+				wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+			},
+		},
+	}
+	outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+	assert.NoError(t, err)
+
+	// the user is signed on to the canonical account
+	body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	_, hasErr := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.False(t, hasErr)
+	screenName, _ := body.String(wire.LoginTLVTagsScreenName)
+	assert.Equal(t, "Canonical User", screenName)
+	cookie, _ := body.Bytes(wire.LoginTLVTagsAuthorizationCookie)
+	assert.Equal(t, []byte("the-cookie"), cookie)
+}
+
+func TestAuthService_BUCPLoginRequest_AllowedNetworks(t *testing.T) {
+	user := state.User{
+		IdentScreenName:   state.NewIdentScreenName("Admin Bot"),
+		DisplayScreenName: "Admin Bot",
+		AuthKey:           "auth_key",
+		AllowedNetworks: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+		},
+	}
+	assert.NoError(t, user.HashPassword("the_password"))
+
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// remoteAddr is the IP address the client connects from
+		remoteAddr netip.Addr
+		// wantErrCode is the login error code, or 0 if login succeeds
+		wantErrCode uint16
+		// wantAudit indicates whether the attempt is written to the audit log
+		wantAudit bool
+	}{
+		{
+			name:       "login from allowed network succeeds",
+			remoteAddr: netip.MustParseAddr("10.1.2.3"),
+		},
+		{
+			name:        "login from other network is refused",
+			remoteAddr:  netip.MustParseAddr("203.0.113.7"),
+			wantErrCode: wire.LoginErrInvalidUsernameOrPassword,
+			wantAudit:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userManager := newMockUserManager(t)
+			userManager.EXPECT().
+				User(user.IdentScreenName).
+				Return(&user, nil)
+			cookieBaker := newMockCookieBaker(t)
+			if tt.wantErrCode == 0 {
+				cookieBaker.EXPECT().
+					Issue(mock.Anything).
+					Return([]byte("the-cookie"), nil)
+			}
+
+			logs := &bytes.Buffer{}
+			svc := AuthService{
+				banList:     state.NewBanList(""),
+				cookieBaker: cookieBaker,
+				logger:      slog.New(slog.NewTextHandler(logs, nil)),
+				userManager: userManager,
+			}
+
+			inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+						wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+					},
+				},
+			}
+			outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, tt.remoteAddr)
+			assert.NoError(t, err)
+
+			body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+			errCode, hasErr := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+			if tt.wantErrCode == 0 {
+				assert.False(t, hasErr)
+			} else {
+				assert.True(t, hasErr)
+				assert.Equal(t, tt.wantErrCode, errCode)
+			}
+
+			if tt.wantAudit {
+				assert.Contains(t, logs.String(), "audit: login refused from disallowed network")
+				assert.Contains(t, logs.String(), "remote_addr=203.0.113.7")
+			} else {
+				assert.Empty(t, logs.String())
+			}
+		})
+	}
+}
+
+// TestAuthService_BUCPLoginRequest_ICQDefaultPermissions verifies that an ICQ
+// account created at sign-on starts with the configured privacy settings.
+func TestAuthService_BUCPLoginRequest_ICQDefaultPermissions(t *testing.T) {
+	userStore, err := state.NewSQLiteUserStore(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Issue(mock.Anything).
+		Return([]byte("the-cookie"), nil).
+		Once()
+
+	svc := AuthService{
+		banList: state.NewBanList(""),
+		config: config.Config{
+			DisableAuth:           true,
+			ICQDefaultRequireAuth: true,
+			ICQDefaultWebAware:    true,
+		},
+		cookieBaker: cookieBaker,
+		userManager: userStore,
+	}
+
+	inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.LoginTLVTagsScreenName, []byte("100003")),
+				wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, []byte("password")),
+			},
+		},
+	}
+
+	outputSNAC, err := svc.BUCPLogin(inputSNAC, state.NewStubUser, netip.Addr{})
+	assert.NoError(t, err)
+	body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+	_, ok := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+	assert.False(t, ok)
+
+	user, err := userStore.User(state.NewIdentScreenName("100003"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.True(t, user.IsICQ)
+		assert.Equal(t, state.ICQPermissions{AuthRequired: true, WebAware: true}, user.ICQPermissions)
+	}
+}
+
+// TestAuthService_BUCPLoginRequest_Registration verifies that accounts
+// created at sign-on are subject to the registration rate limit and
+// verification hook.
+func TestAuthService_BUCPLoginRequest_Registration(t *testing.T) {
+	remoteAddr := netip.MustParseAddr("203.0.113.1")
+
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// screenName is the screen name the client signs on with
+		screenName state.DisplayScreenName
+		// allowed indicates whether the rate limiter allows the registration
+		allowed bool
+		// verifyApproved is the verification hook's decision
+		verifyApproved bool
+		// verifyErr is the verification hook's error
+		verifyErr error
+		// wantErrCode is the login error code, or 0 if login succeeds
+		wantErrCode uint16
+	}{
+		{
+			name:           "registration is approved, account is created",
+			screenName:     "New User",
+			allowed:        true,
+			verifyApproved: true,
+		},
+		{
+			name:        "too many registrations from address, account isn't created",
+			screenName:  "New User",
+			allowed:     false,
+			wantErrCode: wire.LoginErrRateLimitExceeded,
+		},
+		{
+			name:           "verification hook rejects AIM registration, account isn't created",
+			screenName:     "New User",
+			allowed:        true,
+			verifyApproved: false,
+			wantErrCode:    wire.LoginErrInvalidUsernameOrPassword,
+		},
+		{
+			name:           "verification hook rejects ICQ registration, account isn't created",
+			screenName:     "100003",
+			allowed:        true,
+			verifyApproved: false,
+			wantErrCode:    wire.LoginErrICQUserErr,
+		},
+		{
+			name:        "verification hook fails, account isn't created",
+			screenName:  "New User",
+			allowed:     true,
+			verifyErr:   io.EOF,
+			wantErrCode: wire.LoginErrRateLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userManager := newMockUserManager(t)
+			userManager.EXPECT().
+				User(tt.screenName.IdentScreenName()).
+				Return(nil, nil)
+			registrationLimiter := newMockRegistrationLimiter(t)
+			registrationLimiter.EXPECT().
+				Allow(remoteAddr).
+				Return(tt.allowed)
+			registrationVerifier := newMockRegistrationVerifier(t)
+			if tt.allowed {
+				registrationVerifier.EXPECT().
+					VerifyRegistration(tt.screenName, mock.Anything, remoteAddr).
+					Return(tt.verifyApproved, tt.verifyErr)
+			}
+			cookieBaker := newMockCookieBaker(t)
+			if tt.wantErrCode == 0 {
+				userManager.EXPECT().
+					InsertUser(mock.Anything).
+					Return(nil)
+				cookieBaker.EXPECT().
+					Issue(mock.Anything).
+					Return([]byte("the-cookie"), nil)
+			}
+
+			svc := AuthService{
+				banList:              state.NewBanList(""),
+				config:               config.Config{DisableAuth: true},
+				cookieBaker:          cookieBaker,
+				logger:               slog.Default(),
+				registrationLimiter:  registrationLimiter,
+				registrationVerifier: registrationVerifier,
+				userManager:          userManager,
+			}
+
+			inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, tt.screenName),
+						wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, []byte("password")),
+					},
+				},
+			}
+			outputSNAC, err := svc.BUCPLogin(inputSNAC, state.NewStubUser, remoteAddr)
+			assert.NoError(t, err)
+
+			body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+			errCode, hasErr := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+			if tt.wantErrCode == 0 {
+				assert.False(t, hasErr)
+			} else {
+				assert.True(t, hasErr)
+				assert.Equal(t, tt.wantErrCode, errCode)
+			}
+		})
+	}
+}
+
+func TestAuthService_BUCPChallengeRequest(t *testing.T) {
+	sessUUID := uuid.UUID{1, 2, 3}
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// inputSNAC is the SNAC sent from the client to the server
+		inputSNAC wire.SNAC_0x17_0x06_BUCPChallengeRequest
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+		// expectOutput is the SNAC sent from the server to client
+		expectOutput wire.SNACMessage
+		// wantErr is the error we expect from the method
+		wantErr error
 	}{
 		{
 			name: "login with valid username, expect OK login response",
@@ -1034,111 +2144,294 @@ func TestAuthService_BUCPChallengeRequest(t *testing.T) {
 			},
 		},
 		{
-			name: "login fails on user manager lookup",
-			inputSNAC: wire.SNAC_0x17_0x06_BUCPChallengeRequest{
-				TLVRestBlock: wire.TLVRestBlock{
-					TLVList: wire.TLVList{
-						wire.NewTLVBE(wire.LoginTLVTagsScreenName, "sn_user_b"),
+			name: "login fails on user manager lookup",
+			inputSNAC: wire.SNAC_0x17_0x06_BUCPChallengeRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, "sn_user_b"),
+					},
+				},
+			},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("sn_user_b"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+			wantErr: io.EOF,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			svc := AuthService{
+				config:      tc.cfg,
+				userManager: userManager,
+			}
+			fnNewUUID := func() uuid.UUID {
+				return sessUUID
+			}
+			outputSNAC, err := svc.BUCPChallenge(tc.inputSNAC, fnNewUUID)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.expectOutput, outputSNAC)
+		})
+	}
+}
+
+func TestAuthService_RegisterChatSession_HappyPath(t *testing.T) {
+	sess := newTestSession("ScreenName")
+
+	chatCookie := "the-chat-cookie"
+	chatSessionRegistry := newMockChatSessionRegistry(t)
+	chatSessionRegistry.EXPECT().
+		AddSession(mock.Anything, chatCookie, sess.DisplayScreenName()).
+		Return(sess, nil)
+
+	c := chatLoginCookie{
+		ChatCookie: chatCookie,
+		ScreenName: sess.DisplayScreenName(),
+	}
+	chatCookieBuf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(c, chatCookieBuf))
+
+	authCookie := []byte("the-auth-cookie")
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Crack(authCookie).
+		Return(chatCookieBuf.Bytes(), nil)
+
+	svc := NewAuthService(config.Config{}, slog.Default(), nil, chatSessionRegistry, nil, cookieBaker, nil, nil, nil, nil, nil, state.ScreenNamePolicy{}, nil, nil)
+
+	have, err := svc.RegisterChatSession(authCookie)
+	assert.NoError(t, err)
+	assert.Equal(t, sess, have)
+}
+
+func TestAuthService_RegisterChatSession_ExpiredCookie(t *testing.T) {
+	authCookie := []byte("the-auth-cookie")
+	cookieBaker := newMockCookieBaker(t)
+	cookieBaker.EXPECT().
+		Crack(authCookie).
+		Return(nil, state.ErrCookieExpired)
+
+	svc := NewAuthService(config.Config{}, slog.Default(), nil, nil, nil, cookieBaker, nil, nil, nil, nil, nil, state.ScreenNamePolicy{}, nil, nil)
+
+	have, err := svc.RegisterChatSession(authCookie)
+	assert.ErrorIs(t, err, state.ErrCookieExpired)
+	assert.Nil(t, have)
+}
+
+func TestAuthService_RegisterBOSSession(t *testing.T) {
+	screenName := state.DisplayScreenName("UserScreenName")
+	aimAuthCookie := bosCookie{
+		ScreenName: screenName,
+	}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(aimAuthCookie, buf))
+	aimCookie := buf.Bytes()
+
+	uin := state.DisplayScreenName("100003")
+	icqAuthCookie := bosCookie{
+		ScreenName: uin,
+	}
+	buf = &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(icqAuthCookie, buf))
+	icqCookie := buf.Bytes()
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cookieOut is the auth cookieOut that contains session information
+		cookie []byte
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+		// wantSess asserts the values of one or more session properties
+		wantSess func(*state.Session) bool
+		// wantErr is the error we expect from the method
+		wantErr error
+	}{
+		{
+			name:   "successfully register an AIM session",
+			cookie: aimCookie,
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					cookieCrackParams: cookieCrackParams{
+						{
+							dataOut:  aimCookie,
+							cookieIn: aimCookie,
+						},
+					},
+				},
+				sessionRegistryParams: sessionRegistryParams{
+					addSessionParams: addSessionParams{
+						{
+							screenName: screenName,
+							result:     newTestSession(screenName),
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: screenName.IdentScreenName(),
+							result: &state.User{
+								IdentScreenName:   screenName.IdentScreenName(),
+								DisplayScreenName: screenName,
+							},
+						},
+					},
+				},
+				accountManagerParams: accountManagerParams{
+					accountManagerConfirmStatusByNameParams: accountManagerConfirmStatusByNameParams{
+						{
+							screenName:    screenName.IdentScreenName(),
+							confirmStatus: true,
+						},
+					},
+				},
+			},
+			wantSess: func(session *state.Session) bool {
+				return true
+			},
+		},
+		{
+			name:   "register an AIM session with the reject-new concurrent login policy",
+			cookie: aimCookie,
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					cookieCrackParams: cookieCrackParams{
+						{
+							dataOut:  aimCookie,
+							cookieIn: aimCookie,
+						},
+					},
+				},
+				sessionRegistryParams: sessionRegistryParams{
+					addSessionIfAbsentParams: addSessionIfAbsentParams{
+						{
+							screenName: screenName,
+							result:     newTestSession(screenName),
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: screenName.IdentScreenName(),
+							result: &state.User{
+								IdentScreenName:       screenName.IdentScreenName(),
+								DisplayScreenName:     screenName,
+								ConcurrentLoginPolicy: config.ConcurrentLoginRejectNew,
+							},
+						},
+					},
+				},
+				accountManagerParams: accountManagerParams{
+					accountManagerConfirmStatusByNameParams: accountManagerConfirmStatusByNameParams{
+						{
+							screenName:    screenName.IdentScreenName(),
+							confirmStatus: true,
+						},
+					},
+				},
+			},
+			wantSess: func(session *state.Session) bool {
+				return session.IdentScreenName() == screenName.IdentScreenName()
+			},
+		},
+		{
+			name:   "refuse an AIM session with the reject-new concurrent login policy while signed on",
+			cookie: aimCookie,
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					cookieCrackParams: cookieCrackParams{
+						{
+							dataOut:  aimCookie,
+							cookieIn: aimCookie,
+						},
+					},
+				},
+				sessionRegistryParams: sessionRegistryParams{
+					addSessionIfAbsentParams: addSessionIfAbsentParams{
+						{
+							screenName: screenName,
+							err:        state.ErrSessionActive,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: screenName.IdentScreenName(),
+							result: &state.User{
+								IdentScreenName:       screenName.IdentScreenName(),
+								DisplayScreenName:     screenName,
+								ConcurrentLoginPolicy: config.ConcurrentLoginRejectNew,
+							},
+						},
+					},
+				},
+			},
+			wantErr: state.ErrSessionActive,
+		},
+		{
+			name:   "register an AIM session with the allow-multiple concurrent login policy",
+			cookie: aimCookie,
+			mockParams: mockParams{
+				cookieBakerParams: cookieBakerParams{
+					cookieCrackParams: cookieCrackParams{
+						{
+							dataOut:  aimCookie,
+							cookieIn: aimCookie,
+						},
+					},
+				},
+				sessionRegistryParams: sessionRegistryParams{
+					addSessionInstanceParams: addSessionInstanceParams{
+						{
+							screenName: screenName,
+							result:     newTestSession(screenName),
+						},
 					},
 				},
-			},
-			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
 						{
-							screenName: state.NewIdentScreenName("sn_user_b"),
-							err:        io.EOF,
+							screenName: screenName.IdentScreenName(),
+							result: &state.User{
+								IdentScreenName:       screenName.IdentScreenName(),
+								DisplayScreenName:     screenName,
+								ConcurrentLoginPolicy: config.ConcurrentLoginAllowMultiple,
+							},
+						},
+					},
+				},
+				accountManagerParams: accountManagerParams{
+					accountManagerConfirmStatusByNameParams: accountManagerConfirmStatusByNameParams{
+						{
+							screenName:    screenName.IdentScreenName(),
+							confirmStatus: true,
 						},
 					},
 				},
 			},
-			wantErr: io.EOF,
+			wantSess: func(session *state.Session) bool {
+				return session.IdentScreenName() == screenName.IdentScreenName()
+			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			userManager := newMockUserManager(t)
-			for _, params := range tc.mockParams.userManagerParams.getUserParams {
-				userManager.EXPECT().
-					User(params.screenName).
-					Return(params.result, params.err)
-			}
-			svc := AuthService{
-				config:      tc.cfg,
-				userManager: userManager,
-			}
-			fnNewUUID := func() uuid.UUID {
-				return sessUUID
-			}
-			outputSNAC, err := svc.BUCPChallenge(tc.inputSNAC, fnNewUUID)
-			assert.ErrorIs(t, err, tc.wantErr)
-			assert.Equal(t, tc.expectOutput, outputSNAC)
-		})
-	}
-}
-
-func TestAuthService_RegisterChatSession_HappyPath(t *testing.T) {
-	sess := newTestSession("ScreenName")
-
-	chatCookie := "the-chat-cookie"
-	chatSessionRegistry := newMockChatSessionRegistry(t)
-	chatSessionRegistry.EXPECT().
-		AddSession(mock.Anything, chatCookie, sess.DisplayScreenName()).
-		Return(sess, nil)
-
-	c := chatLoginCookie{
-		ChatCookie: chatCookie,
-		ScreenName: sess.DisplayScreenName(),
-	}
-	chatCookieBuf := &bytes.Buffer{}
-	assert.NoError(t, wire.MarshalBE(c, chatCookieBuf))
-
-	authCookie := []byte("the-auth-cookie")
-	cookieBaker := newMockCookieBaker(t)
-	cookieBaker.EXPECT().
-		Crack(authCookie).
-		Return(chatCookieBuf.Bytes(), nil)
-
-	svc := NewAuthService(config.Config{}, nil, chatSessionRegistry, nil, cookieBaker, nil, nil, nil)
-
-	have, err := svc.RegisterChatSession(authCookie)
-	assert.NoError(t, err)
-	assert.Equal(t, sess, have)
-}
-
-func TestAuthService_RegisterBOSSession(t *testing.T) {
-	screenName := state.DisplayScreenName("UserScreenName")
-	aimAuthCookie := bosCookie{
-		ScreenName: screenName,
-	}
-	buf := &bytes.Buffer{}
-	assert.NoError(t, wire.MarshalBE(aimAuthCookie, buf))
-	aimCookie := buf.Bytes()
-
-	uin := state.DisplayScreenName("100003")
-	icqAuthCookie := bosCookie{
-		ScreenName: uin,
-	}
-	buf = &bytes.Buffer{}
-	assert.NoError(t, wire.MarshalBE(icqAuthCookie, buf))
-	icqCookie := buf.Bytes()
-
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// cookieOut is the auth cookieOut that contains session information
-		cookie []byte
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-		// wantSess asserts the values of one or more session properties
-		wantSess func(*state.Session) bool
-		// wantErr is the error we expect from the method
-		wantErr error
-	}{
 		{
-			name:   "successfully register an AIM session",
+			name:   "successfully register an official AIM session",
 			cookie: aimCookie,
 			mockParams: mockParams{
 				cookieBakerParams: cookieBakerParams{
@@ -1164,6 +2457,7 @@ func TestAuthService_RegisterBOSSession(t *testing.T) {
 							result: &state.User{
 								IdentScreenName:   screenName.IdentScreenName(),
 								DisplayScreenName: screenName,
+								IsOfficial:        true,
 							},
 						},
 					},
@@ -1178,7 +2472,11 @@ func TestAuthService_RegisterBOSSession(t *testing.T) {
 				},
 			},
 			wantSess: func(session *state.Session) bool {
-				return true
+				userInfo := session.TLVUserInfo()
+				flags, _ := userInfo.Uint16BE(wire.OServiceUserInfoUserFlags)
+				caps, _ := userInfo.Bytes(wire.OServiceUserInfoOscarCaps)
+				return flags&wire.OServiceUserFlagOfficial == wire.OServiceUserFlagOfficial &&
+					bytes.Equal(caps, wire.CapOfficialBadge[:])
 			},
 		},
 		{
@@ -1237,6 +2535,16 @@ func TestAuthService_RegisterBOSSession(t *testing.T) {
 					AddSession(mock.Anything, params.screenName).
 					Return(params.result, params.err)
 			}
+			for _, params := range tc.mockParams.addSessionIfAbsentParams {
+				sessionRegistry.EXPECT().
+					AddSessionIfAbsent(params.screenName).
+					Return(params.result, params.err)
+			}
+			for _, params := range tc.mockParams.addSessionInstanceParams {
+				sessionRegistry.EXPECT().
+					AddSessionInstance(mock.Anything, params.screenName).
+					Return(params.result, params.err)
+			}
 			cookieBaker := newMockCookieBaker(t)
 			for _, params := range tc.mockParams.cookieCrackParams {
 				cookieBaker.EXPECT().
@@ -1256,9 +2564,13 @@ func TestAuthService_RegisterBOSSession(t *testing.T) {
 					Return(params.confirmStatus, nil)
 			}
 
-			svc := NewAuthService(config.Config{}, sessionRegistry, nil, userManager, cookieBaker, nil, accountManager, nil)
+			svc := NewAuthService(config.Config{}, slog.Default(), sessionRegistry, nil, userManager, cookieBaker, nil, accountManager, nil, nil, nil, state.ScreenNamePolicy{}, nil, nil)
 
 			have, err := svc.RegisterBOSSession(context.Background(), tc.cookie)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
 			assert.NoError(t, err)
 
 			if tc.wantSess != nil {
@@ -1294,7 +2606,7 @@ func TestAuthService_RetrieveBOSSession_HappyPath(t *testing.T) {
 		User(sess.IdentScreenName()).
 		Return(&state.User{IdentScreenName: sess.IdentScreenName()}, nil)
 
-	svc := NewAuthService(config.Config{}, nil, nil, userManager, cookieBaker, nil, nil, sessionRetriever)
+	svc := NewAuthService(config.Config{}, slog.Default(), nil, nil, userManager, cookieBaker, nil, nil, sessionRetriever, nil, nil, state.ScreenNamePolicy{}, nil, nil)
 
 	have, err := svc.RetrieveBOSSession(authCookie)
 	assert.NoError(t, err)
@@ -1327,7 +2639,7 @@ func TestAuthService_RetrieveBOSSession_SessionNotFound(t *testing.T) {
 		User(sess.IdentScreenName()).
 		Return(&state.User{IdentScreenName: sess.IdentScreenName()}, nil)
 
-	svc := NewAuthService(config.Config{}, nil, nil, userManager, cookieBaker, nil, nil, sessionRetriever)
+	svc := NewAuthService(config.Config{}, slog.Default(), nil, nil, userManager, cookieBaker, nil, nil, sessionRetriever, nil, nil, state.ScreenNamePolicy{}, nil, nil)
 
 	have, err := svc.RetrieveBOSSession(authCookie)
 	assert.NoError(t, err)
@@ -1338,6 +2650,8 @@ func TestAuthService_SignoutChat(t *testing.T) {
 	tests := []struct {
 		// name is the unit test name
 		name string
+		// cfg is the app configuration
+		cfg config.Config
 		// userSession is the session of the user signing out
 		userSession *state.Session
 		// mockParams is the list of params sent to mocks that satisfy this
@@ -1406,6 +2720,75 @@ func TestAuthService_SignoutChat(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "dropped user signs out of chat room, delivery-failure notices enabled, room is told about missed messages",
+			cfg: config.Config{
+				ChatDeliveryFailureNotices: true,
+			},
+			userSession: newTestSession("me", sessOptCannedSignonTime, sessOptChatRoomCookie("the-chat-cookie"), sessOptDropped(3)),
+			mockParams: mockParams{
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatUsersLeft,
+								},
+								Body: wire.SNAC_0x0E_0x04_ChatUsersLeft{
+									Users: []wire.TLVUserInfo{
+										newTestSession("me", sessOptCannedSignonTime, sessOptChatRoomCookie("the-chat-cookie")).TLVUserInfo(),
+									},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message:    onlineHostChatMsg(0, wire.ICBMChannelMIME, "me was disconnected after missing 3 message(s) because their connection couldn't keep up."),
+						},
+					},
+				},
+				sessionRegistryParams: sessionRegistryParams{
+					removeSessionParams: removeSessionParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "dropped user signs out of chat room, delivery-failure notices disabled, room is not told about missed messages",
+			userSession: newTestSession("me", sessOptCannedSignonTime, sessOptChatRoomCookie("the-chat-cookie"), sessOptDropped(3)),
+			mockParams: mockParams{
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatUsersLeft,
+								},
+								Body: wire.SNAC_0x0E_0x04_ChatUsersLeft{
+									Users: []wire.TLVUserInfo{
+										newTestSession("me", sessOptCannedSignonTime, sessOptChatRoomCookie("the-chat-cookie")).TLVUserInfo(),
+									},
+								},
+							},
+						},
+					},
+				},
+				sessionRegistryParams: sessionRegistryParams{
+					removeSessionParams: removeSessionParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1420,7 +2803,7 @@ func TestAuthService_SignoutChat(t *testing.T) {
 					RemoveSession(matchSession(params.screenName))
 			}
 
-			svc := NewAuthService(config.Config{}, nil, sessionManager, nil, nil, chatMessageRelayer, nil, nil)
+			svc := NewAuthService(tt.cfg, slog.Default(), nil, sessionManager, nil, nil, chatMessageRelayer, nil, nil, nil, nil, state.ScreenNamePolicy{}, nil, nil)
 			svc.SignoutChat(nil, tt.userSession)
 		})
 	}
@@ -1465,9 +2848,128 @@ func TestAuthService_Signout(t *testing.T) {
 			for _, params := range tt.mockParams.removeSessionParams {
 				sessionManager.EXPECT().RemoveSession(matchSession(params.screenName))
 			}
-			svc := NewAuthService(config.Config{}, sessionManager, nil, nil, nil, nil, nil, nil)
+			svc := NewAuthService(config.Config{}, slog.Default(), sessionManager, nil, nil, nil, nil, nil, nil, nil, nil, state.ScreenNamePolicy{}, nil, nil)
 
 			svc.Signout(nil, tt.userSession)
 		})
 	}
 }
+
+func TestAuthService_SignoutChat_DisconnectNotifiesOccupants(t *testing.T) {
+	chatSessionManager := state.NewInMemoryChatSessionManager(slog.Default())
+
+	leavingSess, err := chatSessionManager.AddSession(context.Background(), "the-chat-cookie", "leaving-user")
+	assert.NoError(t, err)
+	remainingSess, err := chatSessionManager.AddSession(context.Background(), "the-chat-cookie", "remaining-user")
+	assert.NoError(t, err)
+
+	svc := AuthService{
+		chatMessageRelayer:  chatSessionManager,
+		chatSessionRegistry: chatSessionManager,
+	}
+
+	// the leaving user's BOS connection drops, which closes their chat session
+	// and causes the chat connection to sign them out of the room
+	chatSessionManager.CloseUserSessions(leavingSess.IdentScreenName())
+	<-leavingSess.Closed()
+	svc.SignoutChat(context.Background(), leavingSess)
+
+	select {
+	case msg := <-remainingSess.ReceiveMessage():
+		assert.Equal(t, wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Chat,
+				SubGroup:  wire.ChatUsersLeft,
+			},
+			Body: wire.SNAC_0x0E_0x04_ChatUsersLeft{
+				Users: []wire.TLVUserInfo{
+					leavingSess.TLVUserInfo(),
+				},
+			},
+		}, msg)
+	default:
+		t.Fatal("expected remaining occupant to receive a users-left notification")
+	}
+
+	assert.Equal(t, []*state.Session{remainingSess}, chatSessionManager.AllSessions("the-chat-cookie"))
+}
+
+func TestAuthService_BUCPLoginRequest_PendingPasswordReset(t *testing.T) {
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// resetPending indicates whether the account has a pending password
+		// reset
+		resetPending bool
+		// wantErrCode is the login error code, or 0 if login succeeds
+		wantErrCode uint16
+	}{
+		{
+			name:         "pending reset blocks login when enabled",
+			cfg:          config.Config{PasswordResetBlocksLogin: true},
+			resetPending: true,
+			wantErrCode:  wire.LoginErrInvalidPassword,
+		},
+		{
+			name:         "pending reset doesn't block login when disabled",
+			resetPending: true,
+		},
+		{
+			name: "login without pending reset succeeds when enabled",
+			cfg:  config.Config{PasswordResetBlocksLogin: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := state.User{
+				IdentScreenName:      state.NewIdentScreenName("User A"),
+				DisplayScreenName:    "User A",
+				AuthKey:              "auth_key",
+				PasswordResetPending: tt.resetPending,
+			}
+			assert.NoError(t, user.HashPassword("the_password"))
+
+			userManager := newMockUserManager(t)
+			userManager.EXPECT().
+				User(user.IdentScreenName).
+				Return(&user, nil)
+			cookieBaker := newMockCookieBaker(t)
+			if tt.wantErrCode == 0 {
+				cookieBaker.EXPECT().
+					Issue(mock.Anything).
+					Return([]byte("the-cookie"), nil)
+			}
+
+			svc := AuthService{
+				banList:     state.NewBanList(""),
+				config:      tt.cfg,
+				cookieBaker: cookieBaker,
+				logger:      slog.Default(),
+				userManager: userManager,
+			}
+
+			inputSNAC := wire.SNAC_0x17_0x02_BUCPLoginRequest{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LoginTLVTagsScreenName, user.DisplayScreenName),
+						wire.NewTLVBE(wire.LoginTLVTagsPasswordHash, user.StrongMD5Pass),
+					},
+				},
+			}
+			outputSNAC, err := svc.BUCPLogin(inputSNAC, nil, netip.Addr{})
+			assert.NoError(t, err)
+
+			body := outputSNAC.Body.(wire.SNAC_0x17_0x03_BUCPLoginResponse)
+			errCode, hasErr := body.Uint16BE(wire.LoginTLVTagsErrorSubcode)
+			if tt.wantErrCode == 0 {
+				assert.False(t, hasErr)
+			} else {
+				assert.True(t, hasErr)
+				assert.Equal(t, tt.wantErrCode, errCode)
+			}
+		})
+	}
+}
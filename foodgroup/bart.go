@@ -1,11 +1,15 @@
 package foodgroup
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -18,16 +22,70 @@ var blankGIF = []byte{
 	0x32, 0x00, 0x32, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
 }
 
+// iconFormatSignatures maps each buddy icon format accepted by
+// ParseIconFormatList to the magic bytes that begin files of that format.
+var iconFormatSignatures = map[string][][]byte{
+	"gif":  {[]byte("GIF87a"), []byte("GIF89a")},
+	"jpeg": {{0xff, 0xd8, 0xff}},
+	"png":  {{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}},
+	"bmp":  {[]byte("BM")},
+}
+
+// ParseIconFormatList parses a comma-separated list of buddy icon image
+// formats, such as gif,png. An empty string yields an empty list.
+func ParseIconFormatList(s string) ([]string, error) {
+	var formats []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if _, ok := iconFormatSignatures[entry]; !ok {
+			return nil, fmt.Errorf("unsupported icon format %q", entry)
+		}
+		formats = append(formats, entry)
+	}
+	return formats, nil
+}
+
+// iconFormat identifies the image format of data by its magic bytes. It
+// returns an empty string if the format is not recognized.
+func iconFormat(data []byte) string {
+	for format, signatures := range iconFormatSignatures {
+		for _, sig := range signatures {
+			if bytes.HasPrefix(data, sig) {
+				return format
+			}
+		}
+	}
+	return ""
+}
+
+// isIconType reports whether BART items of type bartType hold buddy icon
+// images.
+func isIconType(bartType uint16) bool {
+	switch bartType {
+	case wire.BARTTypesBuddyIconSmall, wire.BARTTypesBuddyIcon, wire.BARTTypesBuddyIconBig:
+		return true
+	default:
+		return false
+	}
+}
+
 func NewBARTService(
+	cfg config.Config,
 	logger *slog.Logger,
 	bartManager BARTManager,
 	messageRelayer MessageRelayer,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	iconFormats []string,
 ) BARTService {
 	return BARTService{
 		bartManager:            bartManager,
 		buddyUpdateBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		cfg:                    cfg,
+		iconFormats:            iconFormats,
 		logger:                 logger,
 	}
 }
@@ -35,10 +93,57 @@ func NewBARTService(
 type BARTService struct {
 	bartManager            BARTManager
 	buddyUpdateBroadcaster buddyBroadcaster
+	cfg                    config.Config
+	iconFormats            []string
 	logger                 *slog.Logger
 }
 
+// validateItem checks an uploaded BART item against the configured size
+// limit and, for buddy icons, the allowed image formats. It returns
+// wire.BARTReplyCodesSuccess if the item is acceptable, otherwise the reply
+// code that explains why it was rejected.
+func (s BARTService) validateItem(bartType uint16, data []byte) uint8 {
+	if s.cfg.BARTMaxItemSize > 0 && len(data) > int(s.cfg.BARTMaxItemSize) {
+		return wire.BARTReplyCodesTooBig
+	}
+	if !isIconType(bartType) || len(s.iconFormats) == 0 {
+		return wire.BARTReplyCodesSuccess
+	}
+	if len(data) == 0 {
+		return wire.BARTReplyCodesTooSmall
+	}
+	format := iconFormat(data)
+	switch {
+	case format == "":
+		return wire.BARTReplyCodesInvalid
+	case !slices.Contains(s.iconFormats, format):
+		return wire.BARTReplyCodesInvalidType
+	default:
+		return wire.BARTReplyCodesSuccess
+	}
+}
+
+// UpsertItem stores an uploaded BART item and notifies the user's buddies
+// of the change. Items that fail validation are not stored and are refused
+// with a reply code that explains why.
 func (s BARTService) UpsertItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x10_0x02_BARTUploadQuery) (wire.SNACMessage, error) {
+	if code := s.validateItem(inBody.Type, inBody.Data); code != wire.BARTReplyCodesSuccess {
+		s.logger.DebugContext(ctx, "rejected BART upload", "type", inBody.Type, "size", len(inBody.Data), "code", code)
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.BART,
+				SubGroup:  wire.BARTUploadReply,
+				RequestID: inFrame.RequestID,
+			},
+			Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+				Code: code,
+				ID: wire.BARTID{
+					Type: inBody.Type,
+				},
+			},
+		}, nil
+	}
+
 	h := md5.New()
 	if _, err := h.Write(inBody.Data); err != nil {
 		return wire.SNACMessage{}, err
@@ -7,14 +7,21 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
 func TestBARTService_UpsertItem(t *testing.T) {
+	blankGIFHash := []byte{0x6f, 0x0e, 0xe2, 0x9d, 0x94, 0x6b, 0x85, 0x75, 0x9f, 0xd6, 0x56, 0x03, 0x40, 0x45, 0x9c, 0x03}
+
 	cases := []struct {
 		// name is the unit test name
 		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// iconFormats is the list of image formats allowed for buddy icons
+		iconFormats []string
 		// userSession is the session of the user adding to feedbag
 		userSession *state.Session
 		// inputSNAC is the SNAC sent from the client to the server
@@ -72,6 +79,190 @@ func TestBARTService_UpsertItem(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "upsert valid buddy icon with size and format limits",
+			cfg:         config.Config{BARTMaxItemSize: uint32(len(blankGIF))},
+			iconFormats: []string{"gif", "png"},
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+					Data: blankGIF,
+				},
+			},
+			mockParams: mockParams{
+				bartManagerParams: bartManagerParams{
+					bartManagerUpsertParams: bartManagerUpsertParams{
+						{
+							itemHash: blankGIFHash,
+							payload:  blankGIF,
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("user_screen_name"),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesSuccess,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+						BARTInfo: wire.BARTInfo{
+							Flags: wire.BARTFlagsKnown,
+							Hash:  blankGIFHash,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "reject oversized buddy icon",
+			cfg:         config.Config{BARTMaxItemSize: uint32(len(blankGIF) - 1)},
+			iconFormats: []string{"gif"},
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+					Data: blankGIF,
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesTooBig,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+					},
+				},
+			},
+		},
+		{
+			name:        "reject oversized item of any type",
+			cfg:         config.Config{BARTMaxItemSize: 4},
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesArriveSound,
+					Data: []byte{'i', 't', 'e', 'm', 'd', 'a', 't', 'a'},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesTooBig,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesArriveSound,
+					},
+				},
+			},
+		},
+		{
+			name:        "reject buddy icon that isn't an image",
+			iconFormats: []string{"gif"},
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+					Data: []byte{'i', 't', 'e', 'm', 'd', 'a', 't', 'a'},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesInvalid,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+					},
+				},
+			},
+		},
+		{
+			name:        "reject buddy icon in disallowed format",
+			iconFormats: []string{"png"},
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIconSmall,
+					Data: blankGIF,
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesInvalidType,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIconSmall,
+					},
+				},
+			},
+		},
+		{
+			name:        "reject empty buddy icon",
+			iconFormats: []string{"gif"},
+			userSession: newTestSession("user_screen_name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x02_BARTUploadQuery{
+					Type: wire.BARTTypesBuddyIcon,
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.BART,
+					SubGroup:  wire.BARTUploadReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x10_0x03_BARTUploadReply{
+					Code: wire.BARTReplyCodesTooSmall,
+					ID: wire.BARTID{
+						Type: wire.BARTTypesBuddyIcon,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -88,7 +279,7 @@ func TestBARTService_UpsertItem(t *testing.T) {
 					BroadcastBuddyArrived(mock.Anything, matchSession(params.screenName)).
 					Return(params.err)
 			}
-			svc := NewBARTService(slog.Default(), bartManager, nil, nil, nil)
+			svc := NewBARTService(tc.cfg, slog.Default(), bartManager, nil, nil, nil, tc.iconFormats)
 			svc.buddyUpdateBroadcaster = buddyUpdateBroadcaster
 
 			output, err := svc.UpsertItem(nil, tc.userSession, tc.inputSNAC.Frame,
@@ -213,7 +404,7 @@ func TestBARTService_RetrieveItem(t *testing.T) {
 					Return(params.result, nil)
 			}
 
-			svc := NewBARTService(slog.Default(), bartManager, nil, nil, nil)
+			svc := NewBARTService(config.Config{}, slog.Default(), bartManager, nil, nil, nil, nil)
 
 			output, err := svc.RetrieveItem(nil, tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x10_0x04_BARTDownloadQuery))
@@ -226,3 +417,40 @@ func TestBARTService_RetrieveItem(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIconFormatList(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  nil,
+		},
+		{
+			name:  "all formats",
+			given: "gif, JPEG,png,bmp,",
+			want:  []string{"gif", "jpeg", "png", "bmp"},
+		},
+		{
+			name:    "unsupported format",
+			given:   "gif,tiff",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseIconFormatList(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
@@ -2,29 +2,46 @@ package foodgroup
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
 // NewBuddyService creates a new instance of BuddyService.
 func NewBuddyService(
+	cfg config.Config,
 	messageRelayer MessageRelayer,
 	localBuddyListManager LocalBuddyListManager,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	feedbagManager FeedbagManager,
+	screenNameResolver ScreenNameResolver,
 ) *BuddyService {
 	return &BuddyService{
 		buddyBroadcaster:      newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		buddyListRetriever:    buddyListRetriever,
+		cfg:                   cfg,
+		feedbagManager:        feedbagManager,
 		localBuddyListManager: localBuddyListManager,
+		messageRelayer:        messageRelayer,
+		screenNameResolver:    screenNameResolver,
 	}
 }
 
 // BuddyService provides functionality for the Buddy food group.
 type BuddyService struct {
-	localBuddyListManager LocalBuddyListManager
 	buddyBroadcaster      buddyBroadcaster
+	buddyListRetriever    BuddyListRetriever
+	cfg                   config.Config
+	feedbagManager        FeedbagManager
+	localBuddyListManager LocalBuddyListManager
+	messageRelayer        MessageRelayer
+	// screenNameResolver resolves aliases on the buddy list to their accounts.
+	screenNameResolver ScreenNameResolver
 }
 
 // RightsQuery returns buddy list service parameters.
@@ -48,18 +65,39 @@ func (s BuddyService) RightsQuery(_ context.Context, frameIn wire.SNACFrame) wir
 	}
 }
 
-// AddBuddies adds buddies to my client-side buddy list.
+// AddBuddies adds buddies to my client-side buddy list. For users with a
+// server-side buddy list, the buddies are transient watches that deliver
+// presence updates for users who aren't on the server-side list. Transient
+// watches last until DelBuddies removes them or the user signs off, and are
+// ignored if they are disabled by config. Screen name aliases are watched as
+// the accounts they belong to.
 func (s BuddyService) AddBuddies(
 	ctx context.Context,
 	sess *state.Session,
 	inBody wire.SNAC_0x03_0x04_BuddyAddBuddies,
 ) error {
 
+	if sess.FeedbagInUse() && !s.cfg.BuddyTransientWatches {
+		return nil
+	}
+
+	var toNotify []state.IdentScreenName
 	for _, entry := range inBody.Buddies {
-		sn := state.NewIdentScreenName(entry.ScreenName)
+		sn, err := s.screenNameResolver.CanonicalScreenName(state.NewIdentScreenName(entry.ScreenName))
+		if err != nil {
+			return fmt.Errorf("CanonicalScreenName: %w", err)
+		}
 		if err := s.localBuddyListManager.AddBuddy(sess.IdentScreenName(), sn); err != nil {
 			return err
 		}
+		toNotify = append(toNotify, sn)
+		// transient watches are temporary, so they must not leave a
+		// permanent entry in the other user's buddy list
+		if s.cfg.AutoReciprocateBuddies && !sess.FeedbagInUse() {
+			if err := s.reciprocate(ctx, sess, sn); err != nil {
+				return fmt.Errorf("reciprocate: %w", err)
+			}
+		}
 	}
 
 	if !sess.SignonComplete() {
@@ -68,10 +106,6 @@ func (s BuddyService) AddBuddies(
 		return nil
 	}
 
-	var toNotify []state.IdentScreenName
-	for _, entry := range inBody.Buddies {
-		toNotify = append(toNotify, state.NewIdentScreenName(entry.ScreenName))
-	}
 	if err := s.buddyBroadcaster.BroadcastVisibility(ctx, sess, toNotify, true); err != nil {
 		return fmt.Errorf("buddyBroadcaster.BroadcastVisibility: %w", err)
 	}
@@ -79,6 +113,80 @@ func (s BuddyService) AddBuddies(
 	return nil
 }
 
+// reciprocate adds you to their server-side buddy list so that the buddy
+// relationship is mutual. The entry is placed in the configured default group,
+// which is created if they don't have it. Nothing is added if either of you
+// blocks the other, if you're already on their list, if they don't have a
+// server-side buddy list, or if their buddy list is full. The entry is written
+// straight to their feedbag rather than going through AddBuddies, so
+// reciprocation never triggers itself in a loop.
+func (s BuddyService) reciprocate(ctx context.Context, you *state.Session, them state.IdentScreenName) error {
+	if them == you.IdentScreenName() {
+		return nil
+	}
+
+	rel, err := s.buddyListRetriever.Relationship(you.IdentScreenName(), them)
+	if err != nil {
+		return fmt.Errorf("buddyListRetriever.Relationship: %w", err)
+	}
+	if rel.YouBlock || rel.BlocksYou || rel.IsOnTheirList {
+		return nil
+	}
+
+	items, err := s.feedbagManager.Feedbag(them)
+	if err != nil {
+		return fmt.Errorf("feedbagManager.Feedbag: %w", err)
+	}
+	if len(items) == 0 {
+		// they manage their buddy list client-side, which the server can't
+		// modify on their behalf
+		return nil
+	}
+	for _, item := range items {
+		// check their feedbag directly, since relationship info is only
+		// available for users who are online
+		if (item.ClassID == wire.FeedbagClassIdBuddy || item.ClassID == wire.FeedbagClassIDDeny) &&
+			state.NewIdentScreenName(item.Name) == you.IdentScreenName() {
+			return nil
+		}
+	}
+
+	inserted, updated, err := state.AddBuddyToFeedbag(items, you.DisplayScreenName(), s.cfg.DefaultGroupName)
+	if err != nil {
+		if errors.Is(err, state.ErrFeedbagFull) {
+			return nil
+		}
+		return err
+	}
+	if err := s.feedbagManager.FeedbagUpsert(them, append(inserted, updated...)); err != nil {
+		return fmt.Errorf("feedbagManager.FeedbagUpsert: %w", err)
+	}
+
+	// keep their client's buddy list in sync if they're online
+	s.messageRelayer.RelayToScreenName(ctx, them, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagInsertItem,
+		},
+		Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+			Items: inserted,
+		},
+	})
+	if len(updated) > 0 {
+		s.messageRelayer.RelayToScreenName(ctx, them, wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Feedbag,
+				SubGroup:  wire.FeedbagUpdateItem,
+			},
+			Body: wire.SNAC_0x13_0x09_FeedbagUpdateItem{
+				Items: updated,
+			},
+		})
+	}
+
+	return nil
+}
+
 // DelBuddies deletes buddies from my client-side buddy list.
 func (s BuddyService) DelBuddies(
 	ctx context.Context,
@@ -89,7 +197,10 @@ func (s BuddyService) DelBuddies(
 	var toNotify []state.IdentScreenName
 
 	for _, entry := range inBody.Buddies {
-		sn := state.NewIdentScreenName(entry.ScreenName)
+		sn, err := s.screenNameResolver.CanonicalScreenName(state.NewIdentScreenName(entry.ScreenName))
+		if err != nil {
+			return fmt.Errorf("CanonicalScreenName: %w", err)
+		}
 		if err := s.localBuddyListManager.RemoveBuddy(sess.IdentScreenName(), sn); err != nil {
 			return err
 		}
@@ -103,6 +214,12 @@ func (s BuddyService) DelBuddies(
 	return nil
 }
 
+// BroadcastBuddyArrived sends the latest user info to the user's adjacent
+// users.
+func (s BuddyService) BroadcastBuddyArrived(ctx context.Context, sess *state.Session) error {
+	return s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess)
+}
+
 func (s BuddyService) BroadcastBuddyDeparted(ctx context.Context, sess *state.Session) error {
 	return s.buddyBroadcaster.BroadcastBuddyDeparted(ctx, sess)
 }
@@ -142,6 +259,9 @@ func (s buddyNotifier) BroadcastBuddyArrived(ctx context.Context, sess *state.Se
 		if user.YouBlock || user.BlocksYou || !user.IsOnTheirList {
 			continue
 		}
+		if theirSess := s.sessionRetriever.RetrieveSession(user.User); theirSess != nil && !permitsEachOther(sess, theirSess) {
+			continue
+		}
 		recipients = append(recipients, user.User)
 	}
 
@@ -174,6 +294,9 @@ func (s buddyNotifier) BroadcastBuddyDeparted(ctx context.Context, sess *state.S
 		if user.YouBlock || user.BlocksYou || !user.IsOnTheirList {
 			continue
 		}
+		if theirSess := s.sessionRetriever.RetrieveSession(user.User); theirSess != nil && !permitsEachOther(sess, theirSess) {
+			continue
+		}
 		recipients = append(recipients, user.User)
 	}
 
@@ -206,16 +329,20 @@ func (s buddyNotifier) BroadcastBuddyDeparted(ctx context.Context, sess *state.S
 // notifications that reflect your buddy list and privacy preferences.
 //
 // Behavior:
-//   - Sends you arrival notifications for users on your buddy list that I do
-//     not block.
-//   - Sends arrival notifications to users that you block who have you on
-//     their buddy lists.
+//   - Sends you arrival notifications for users on your buddy list that you
+//     do not block.
+//   - Sends arrival notifications to users that you do not block who have you
+//     on their buddy lists.
 //   - Sends you departure notifications for users on your buddy list that you
-//     block  (if doSendDepartures is true).
+//     block (if doSendDepartures is true).
 //   - Sends departure notifications to users that you block who have you on
 //     their buddy lists (if doSendDepartures is true).
 //   - Don't send notifications for any user that blocks you.
 //
+// Users whose class is excluded by your group permit mask are treated as
+// users you block. Users whose group permit mask excludes your class are
+// treated as users who block you.
+//
 // This method is called when your visibility settings change, ensuring that
 // all relevant users are notified of your arrival or departure status.
 func (s buddyNotifier) BroadcastVisibility(
@@ -239,11 +366,19 @@ func (s buddyNotifier) BroadcastVisibility(
 		}
 
 		theirSess := s.sessionRetriever.RetrieveSession(relationship.User)
-		if theirSess == nil {
-			continue // they are offline
+		if theirSess == nil || !theirSess.SignonComplete() {
+			// they are offline or haven't finished signing on. users who are
+			// signing on learn about you when their own sign-on completes.
+			continue
 		}
 
-		if !relationship.YouBlock {
+		if !theirSess.PermitsClassOf(you) {
+			continue // their group permit mask blocks you
+		}
+		// your group permit mask blocks them just like your block list does
+		youBlock := relationship.YouBlock || !you.PermitsClassOf(theirSess)
+
+		if !youBlock {
 			if relationship.IsOnTheirList {
 				if !buddyIconSet {
 					// lazy load your buddy icon
@@ -263,14 +398,14 @@ func (s buddyNotifier) BroadcastVisibility(
 				// tell you they're online
 				s.unicastBuddyArrived(ctx, theirInfo, you.IdentScreenName())
 			}
-		} else if relationship.YouBlock && doSendDepartures {
+		} else if doSendDepartures {
 			if relationship.IsOnTheirList {
 				// tell them you're offline
-				s.unicastBuddyDeparted(ctx, you, theirSess.IdentScreenName())
+				s.unicastBuddyDeparted(ctx, you.IdentScreenName(), you.Warning(), theirSess.IdentScreenName())
 			}
 			if relationship.IsOnYourList {
 				// tell you they're offline
-				s.unicastBuddyDeparted(ctx, theirSess, you.IdentScreenName())
+				s.unicastBuddyDeparted(ctx, theirSess.IdentScreenName(), theirSess.Warning(), you.IdentScreenName())
 			}
 		}
 	}
@@ -278,6 +413,12 @@ func (s buddyNotifier) BroadcastVisibility(
 	return nil
 }
 
+// permitsEachOther indicates whether the group permit masks of both users
+// allow each other's user class.
+func permitsEachOther(you, them *state.Session) bool {
+	return you.PermitsClassOf(them) && them.PermitsClassOf(you)
+}
+
 // setBuddyIcon adds buddy icon metadata to TLV user info
 func (s buddyNotifier) setBuddyIcon(you state.IdentScreenName, myInfo *wire.TLVUserInfo) error {
 	icon, err := s.buddyListRetriever.BuddyIconRefByName(you)
@@ -290,7 +431,7 @@ func (s buddyNotifier) setBuddyIcon(you state.IdentScreenName, myInfo *wire.TLVU
 	return nil
 }
 
-func (s buddyNotifier) unicastBuddyDeparted(ctx context.Context, from *state.Session, to state.IdentScreenName) {
+func (s buddyNotifier) unicastBuddyDeparted(ctx context.Context, from state.IdentScreenName, warning uint16, to state.IdentScreenName) {
 	s.messageRelayer.RelayToScreenName(ctx, to, wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.Buddy,
@@ -300,8 +441,8 @@ func (s buddyNotifier) unicastBuddyDeparted(ctx context.Context, from *state.Ses
 			TLVUserInfo: wire.TLVUserInfo{
 				// don't include the TLV block, otherwise the AIM client fails
 				// to process the block event
-				ScreenName:   from.IdentScreenName().String(),
-				WarningLevel: from.Warning(),
+				ScreenName:   from.String(),
+				WarningLevel: warning,
 			},
 		},
 	})
@@ -322,3 +463,95 @@ func (s buddyNotifier) unicastBuddyArrived(ctx context.Context, userInfo wire.TL
 		},
 	})
 }
+
+// NewPresenceReconciler creates a new instance of PresenceReconciler.
+func NewPresenceReconciler(
+	logger *slog.Logger,
+	buddyListRetriever BuddyListRetriever,
+	messageRelayer MessageRelayer,
+	sessionRetriever SessionRetriever,
+	sessionLister SessionLister,
+) PresenceReconciler {
+	return PresenceReconciler{
+		buddyListRetriever: buddyListRetriever,
+		buddyNotifier:      newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		logger:             logger,
+		sessionLister:      sessionLister,
+		sessionRetriever:   sessionRetriever,
+	}
+}
+
+// PresenceReconciler corrects buddy presence that has drifted from the
+// session pool, such as a buddy still shown online after their connection
+// dropped without a departure notification.
+type PresenceReconciler struct {
+	buddyListRetriever BuddyListRetriever
+	buddyNotifier      buddyNotifier
+	logger             *slog.Logger
+	sessionLister      SessionLister
+	sessionRetriever   SessionRetriever
+}
+
+// Reconcile compares the online buddies that each signed-on user was last
+// told about with the buddies that are actually online and visible to them.
+// It sends a buddy arrival notification for each buddy the user is missing
+// and a buddy departure notification for each buddy the user wrongly sees
+// online.
+func (r PresenceReconciler) Reconcile(ctx context.Context) error {
+	for _, sess := range r.sessionLister.AllSessions() {
+		if !sess.SignonComplete() {
+			continue
+		}
+		if err := r.reconcile(ctx, sess); err != nil {
+			return fmt.Errorf("reconciling presence for %s: %w", sess.IdentScreenName(), err)
+		}
+	}
+	return nil
+}
+
+func (r PresenceReconciler) reconcile(ctx context.Context, you *state.Session) error {
+	relationships, err := r.buddyListRetriever.AllRelationships(you.IdentScreenName(), nil)
+	if err != nil {
+		return fmt.Errorf("retrieving relationships: %w", err)
+	}
+
+	onYourList := make(map[state.IdentScreenName]bool)
+	for _, relationship := range relationships {
+		if !relationship.IsOnYourList {
+			continue
+		}
+		onYourList[relationship.User] = true
+
+		theirSess := r.sessionRetriever.RetrieveSession(relationship.User)
+		isOnline := theirSess != nil && theirSess.SignonComplete() && !theirSess.Invisible() &&
+			!relationship.YouBlock && !relationship.BlocksYou
+		toldOnline := you.BuddyToldOnline(relationship.User)
+
+		switch {
+		case isOnline && !toldOnline:
+			theirInfo := theirSess.TLVUserInfo()
+			if err := r.buddyNotifier.setBuddyIcon(theirSess.IdentScreenName(), &theirInfo); err != nil {
+				return err
+			}
+			r.logger.DebugContext(ctx, "correcting stale buddy presence", "user", you.IdentScreenName(),
+				"buddy", relationship.User, "online", true)
+			r.buddyNotifier.unicastBuddyArrived(ctx, theirInfo, you.IdentScreenName())
+		case !isOnline && toldOnline:
+			r.logger.DebugContext(ctx, "correcting stale buddy presence", "user", you.IdentScreenName(),
+				"buddy", relationship.User, "online", false)
+			r.buddyNotifier.unicastBuddyDeparted(ctx, relationship.User, 0, you.IdentScreenName())
+		}
+	}
+
+	// clear users who are no longer on your buddy list and went offline
+	for _, buddy := range you.BuddiesToldOnline() {
+		if onYourList[buddy] || r.sessionRetriever.RetrieveSession(buddy) != nil {
+			continue
+		}
+		r.logger.DebugContext(ctx, "correcting stale buddy presence", "user", you.IdentScreenName(),
+			"buddy", buddy, "online", false)
+		r.buddyNotifier.unicastBuddyDeparted(ctx, buddy, 0, you.IdentScreenName())
+	}
+
+	return nil
+}
@@ -1,10 +1,13 @@
 package foodgroup
 
 import (
+	"context"
+	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
@@ -12,7 +15,7 @@ import (
 )
 
 func TestBuddyService_RightsQuery(t *testing.T) {
-	svc := NewBuddyService(nil, nil, nil, nil)
+	svc := NewBuddyService(config.Config{}, nil, nil, nil, nil, nil, nil)
 
 	want := wire.SNACMessage{
 		Frame: wire.SNACFrame{
@@ -40,6 +43,8 @@ func TestBuddyService_AddBuddies(t *testing.T) {
 	tests := []struct {
 		// name is the name of the test
 		name string
+		// cfg is the app configuration
+		cfg config.Config
 		// sess is the client session
 		sess *state.Session
 		// bodyIn is the input SNAC
@@ -121,6 +126,559 @@ func TestBuddyService_AddBuddies(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "add transient watch for server-side buddy list user",
+			cfg: config.Config{
+				BuddyTransientWatches: true,
+			},
+			sess: newTestSession("user_screen_name", sessOptSignonComplete, sessOptFeedbagInUse),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "not_a_buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("not_a_buddy"),
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from: state.NewIdentScreenName("user_screen_name"),
+							filter: []state.IdentScreenName{
+								state.NewIdentScreenName("not_a_buddy"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ignore transient watch for server-side buddy list user when disabled",
+			sess: newTestSession("user_screen_name", sessOptSignonComplete, sessOptFeedbagInUse),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "not_a_buddy",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localBuddyListManager := newMockLocalBuddyListManager(t)
+			for _, params := range tt.mockParams.addBuddyParams {
+				localBuddyListManager.EXPECT().
+					AddBuddy(params.me, params.them).
+					Return(params.err)
+			}
+			mockBuddyBroadcaster := newMockbuddyBroadcaster(t)
+			for _, params := range tt.mockParams.broadcastVisibilityParams {
+				mockBuddyBroadcaster.EXPECT().
+					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, true).
+					Return(params.err)
+			}
+
+			svc := BuddyService{
+				cfg:                   tt.cfg,
+				localBuddyListManager: localBuddyListManager,
+				buddyBroadcaster:      mockBuddyBroadcaster,
+				screenNameResolver:    newNoAliasScreenNameResolver(t),
+			}
+
+			haveErr := svc.AddBuddies(nil, tt.sess, tt.bodyIn)
+			assert.ErrorIs(t, tt.wantErr, haveErr)
+		})
+	}
+}
+
+func TestBuddyService_AddBuddies_AutoReciprocate(t *testing.T) {
+	buddiesGroup := wire.FeedbagItem{
+		Name:    "Buddies",
+		GroupID: 1,
+		ClassID: wire.FeedbagClassIdGroup,
+		TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}),
+			},
+		},
+	}
+	existingBuddy := wire.FeedbagItem{
+		Name:    "someone_else",
+		GroupID: 1,
+		ItemID:  1,
+		ClassID: wire.FeedbagClassIdBuddy,
+	}
+
+	tests := []struct {
+		// name is the name of the test
+		name string
+		// autoReciprocate indicates whether auto reciprocation is enabled
+		autoReciprocate bool
+		// defaultGroupName is the configured group for reciprocal entries
+		defaultGroupName string
+		// sess is the client session
+		sess *state.Session
+		// bodyIn is the input SNAC
+		bodyIn wire.SNAC_0x03_0x04_BuddyAddBuddies
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+		// wantErr is the expected error
+		wantErr error
+	}{
+		{
+			name:            "add buddy, reciprocal entry added to their feedbag",
+			autoReciprocate: true,
+			sess:            newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("buddy"),
+							},
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							results:    []wire.FeedbagItem{buddiesGroup, existingBuddy},
+						},
+					},
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							items: []wire.FeedbagItem{
+								{
+									Name:    "user_screen_name",
+									GroupID: 1,
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+								{
+									Name:    "Buddies",
+									GroupID: 1,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagInsertItem,
+								},
+								Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "user_screen_name",
+											GroupID: 1,
+											ItemID:  2,
+											ClassID: wire.FeedbagClassIdBuddy,
+										},
+									},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagUpdateItem,
+								},
+								Body: wire.SNAC_0x13_0x09_FeedbagUpdateItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "Buddies",
+											GroupID: 1,
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:             "add buddy, reciprocal entry added to new configured default group",
+			autoReciprocate:  true,
+			defaultGroupName: "Contacts",
+			sess:             newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("buddy"),
+							},
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							results:    []wire.FeedbagItem{buddiesGroup, existingBuddy},
+						},
+					},
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+										},
+									},
+								},
+								{
+									Name:    "Contacts",
+									GroupID: 2,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+										},
+									},
+								},
+								{
+									Name:    "user_screen_name",
+									GroupID: 2,
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagInsertItem,
+								},
+								Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+									Items: []wire.FeedbagItem{
+										{
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+												},
+											},
+										},
+										{
+											Name:    "Contacts",
+											GroupID: 2,
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+												},
+											},
+										},
+										{
+											Name:    "user_screen_name",
+											GroupID: 2,
+											ItemID:  2,
+											ClassID: wire.FeedbagClassIdBuddy,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:            "add buddy, auto reciprocation disabled",
+			autoReciprocate: false,
+			sess:            newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:            "add buddy who blocks you, no reciprocal entry",
+			autoReciprocate: true,
+			sess:            newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+							result: state.Relationship{
+								User:      state.NewIdentScreenName("buddy"),
+								BlocksYou: true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:            "add offline buddy who denies you in their feedbag, no reciprocal entry",
+			autoReciprocate: true,
+			sess:            newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("buddy"),
+							},
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+							results: []wire.FeedbagItem{
+								buddiesGroup,
+								{
+									Name:    "User_Screen_Name",
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIDDeny,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:            "add buddy who already has you, no reciprocal entry",
+			autoReciprocate: true,
+			sess:            newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+							result: state.Relationship{
+								User:          state.NewIdentScreenName("buddy"),
+								IsOnTheirList: true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:            "add buddy without server-side buddy list, no reciprocal entry",
+			autoReciprocate: true,
+			sess:            newTestSession("user_screen_name"),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("buddy"),
+							},
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:            "add transient watch, no reciprocal entry added to their feedbag",
+			autoReciprocate: true,
+			sess:            newTestSession("user_screen_name", sessOptFeedbagInUse),
+			bodyIn: wire.SNAC_0x03_0x04_BuddyAddBuddies{
+				Buddies: []struct {
+					ScreenName string `oscar:"len_prefix=uint8"`
+				}{
+					{
+						ScreenName: "buddy",
+					},
+				},
+			},
+			mockParams: mockParams{
+				localBuddyListManagerParams: localBuddyListManagerParams{
+					addBuddyParams: addBuddyParams{
+						{
+							me:   state.NewIdentScreenName("user_screen_name"),
+							them: state.NewIdentScreenName("buddy"),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -130,16 +688,40 @@ func TestBuddyService_AddBuddies(t *testing.T) {
 					AddBuddy(params.me, params.them).
 					Return(params.err)
 			}
-			mockBuddyBroadcaster := newMockbuddyBroadcaster(t)
-			for _, params := range tt.mockParams.broadcastVisibilityParams {
-				mockBuddyBroadcaster.EXPECT().
-					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, true).
-					Return(params.err)
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			for _, params := range tt.mockParams.relationshipParams {
+				buddyListRetriever.EXPECT().
+					Relationship(params.me, params.them).
+					Return(params.result, params.err)
+			}
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tt.mockParams.feedbagParams {
+				feedbagManager.EXPECT().
+					Feedbag(params.screenName).
+					Return(params.results, nil)
+			}
+			for _, params := range tt.mockParams.feedbagUpsertParams {
+				feedbagManager.EXPECT().
+					FeedbagUpsert(params.screenName, params.items).
+					Return(nil)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tt.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
 			}
 
 			svc := BuddyService{
+				buddyListRetriever: buddyListRetriever,
+				cfg: config.Config{
+					AutoReciprocateBuddies: tt.autoReciprocate,
+					BuddyTransientWatches:  true,
+					DefaultGroupName:       tt.defaultGroupName,
+				},
+				feedbagManager:        feedbagManager,
 				localBuddyListManager: localBuddyListManager,
-				buddyBroadcaster:      mockBuddyBroadcaster,
+				messageRelayer:        messageRelayer,
+				screenNameResolver:    newNoAliasScreenNameResolver(t),
 			}
 
 			haveErr := svc.AddBuddies(nil, tt.sess, tt.bodyIn)
@@ -148,6 +730,43 @@ func TestBuddyService_AddBuddies(t *testing.T) {
 	}
 }
 
+func TestBuddyService_AddBuddies_Alias(t *testing.T) {
+	sess := newTestSession("me", sessOptSignonComplete)
+
+	screenNameResolver := newMockScreenNameResolver(t)
+	screenNameResolver.EXPECT().
+		CanonicalScreenName(state.NewIdentScreenName("The Alias")).
+		Return(state.NewIdentScreenName("Canonical User"), nil)
+
+	// the buddy is watched as the account the alias belongs to
+	localBuddyListManager := newMockLocalBuddyListManager(t)
+	localBuddyListManager.EXPECT().
+		AddBuddy(sess.IdentScreenName(), state.NewIdentScreenName("Canonical User")).
+		Return(nil)
+	buddyBroadcaster := newMockbuddyBroadcaster(t)
+	buddyBroadcaster.EXPECT().
+		BroadcastVisibility(mock.Anything, matchSession(sess.IdentScreenName()),
+			[]state.IdentScreenName{state.NewIdentScreenName("Canonical User")}, true).
+		Return(nil)
+
+	svc := BuddyService{
+		buddyBroadcaster:      buddyBroadcaster,
+		localBuddyListManager: localBuddyListManager,
+		screenNameResolver:    screenNameResolver,
+	}
+
+	inBody := wire.SNAC_0x03_0x04_BuddyAddBuddies{
+		Buddies: []struct {
+			ScreenName string `oscar:"len_prefix=uint8"`
+		}{
+			{
+				ScreenName: "The Alias",
+			},
+		},
+	}
+	assert.NoError(t, svc.AddBuddies(nil, sess, inBody))
+}
+
 func TestBuddyService_DelBuddies(t *testing.T) {
 	tests := []struct {
 		// name is the name of the test
@@ -222,6 +841,7 @@ func TestBuddyService_DelBuddies(t *testing.T) {
 			svc := BuddyService{
 				buddyBroadcaster:      mockBuddyBroadcaster,
 				localBuddyListManager: localBuddyListManager,
+				screenNameResolver:    newNoAliasScreenNameResolver(t),
 			}
 
 			assert.ErrorIs(t, tt.wantErr, svc.DelBuddies(nil, tt.sess, tt.bodyIn))
@@ -284,6 +904,20 @@ func TestBuddyNotifier_BroadcastBuddyArrived(t *testing.T) {
 									IsOnYourList:  true,
 									IsOnTheirList: false,
 								},
+								{
+									User:          state.NewIdentScreenName("friend6-masks-you"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
+								{
+									User:          state.NewIdentScreenName("friend7-offline"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
 							},
 						},
 					},
@@ -300,12 +934,33 @@ func TestBuddyNotifier_BroadcastBuddyArrived(t *testing.T) {
 						},
 					},
 				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("friend1-visible"),
+							result:     newTestSession("friend1-visible"),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend2-visible"),
+							result:     newTestSession("friend2-visible"),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend6-masks-you"),
+							result:     newTestSession("friend6-masks-you", sessOptPermitMask(wire.OServiceUserFlagICQ)),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend7-offline"),
+							result:     nil,
+						},
+					},
+				},
 				messageRelayerParams: messageRelayerParams{
 					relayToScreenNamesParams: relayToScreenNamesParams{
 						{
 							screenNames: []state.IdentScreenName{
 								state.NewIdentScreenName("friend1-visible"),
 								state.NewIdentScreenName("friend2-visible"),
+								state.NewIdentScreenName("friend7-offline"),
 							},
 							message: newBuddyArrivedNotif(userInfoWithBARTIcon(
 								newTestSession("me"),
@@ -344,9 +999,17 @@ func TestBuddyNotifier_BroadcastBuddyArrived(t *testing.T) {
 					RelayToScreenNames(mock.Anything, params.screenNames, params.message)
 			}
 
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+
 			svc := buddyNotifier{
 				buddyListRetriever: buddyListRetriever,
 				messageRelayer:     messageRelayer,
+				sessionRetriever:   sessionRetriever,
 			}
 
 			err := svc.BroadcastBuddyArrived(nil, tc.userSession)
@@ -410,16 +1073,51 @@ func TestBuddyService_BroadcastDeparture(t *testing.T) {
 									IsOnYourList:  true,
 									IsOnTheirList: false,
 								},
+								{
+									User:          state.NewIdentScreenName("friend6-masks-you"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
+								{
+									User:          state.NewIdentScreenName("friend7-offline"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
 							},
 						},
 					},
 				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("friend1-visible"),
+							result:     newTestSession("friend1-visible"),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend2-visible"),
+							result:     newTestSession("friend2-visible"),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend6-masks-you"),
+							result:     newTestSession("friend6-masks-you", sessOptPermitMask(wire.OServiceUserFlagICQ)),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend7-offline"),
+							result:     nil,
+						},
+					},
+				},
 				messageRelayerParams: messageRelayerParams{
 					relayToScreenNamesParams: relayToScreenNamesParams{
 						{
 							screenNames: []state.IdentScreenName{
 								state.NewIdentScreenName("friend1-visible"),
 								state.NewIdentScreenName("friend2-visible"),
+								state.NewIdentScreenName("friend7-offline"),
 							},
 							message: wire.SNACMessage{
 								Frame: wire.SNACFrame{
@@ -465,9 +1163,17 @@ func TestBuddyService_BroadcastDeparture(t *testing.T) {
 					RelayToScreenNames(mock.Anything, params.screenNames, params.message)
 			}
 
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+
 			svc := buddyNotifier{
 				buddyListRetriever: buddyListRetriever,
 				messageRelayer:     messageRelayer,
+				sessionRetriever:   sessionRetriever,
 			}
 
 			err := svc.BroadcastBuddyDeparted(nil, tc.userSession)
@@ -614,27 +1320,27 @@ func Test_buddyNotifier_BroadcastVisibility(t *testing.T) {
 					retrieveSessionParams: retrieveSessionParams{
 						{
 							screenName: state.NewIdentScreenName("friend2-visible-on-their-list"),
-							result:     newTestSession("friend2-visible-on-their-list"),
+							result:     newTestSession("friend2-visible-on-their-list", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend3-visible-on-your-list"),
-							result:     newTestSession("friend3-visible-on-your-list"),
+							result:     newTestSession("friend3-visible-on-your-list", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend4-visible-on-both-lists"),
-							result:     newTestSession("friend4-visible-on-both-lists"),
+							result:     newTestSession("friend4-visible-on-both-lists", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend5-blocked-on-their-list"),
-							result:     newTestSession("friend5-blocked-on-their-list"),
+							result:     newTestSession("friend5-blocked-on-their-list", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend6-blocked-on-your-list"),
-							result:     newTestSession("friend6-blocked-on-your-list"),
+							result:     newTestSession("friend6-blocked-on-your-list", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend7-blocked-on-both-lists"),
-							result:     newTestSession("friend7-blocked-on-both-lists"),
+							result:     newTestSession("friend7-blocked-on-both-lists", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend7-visible-offline"),
@@ -645,6 +1351,85 @@ func Test_buddyNotifier_BroadcastVisibility(t *testing.T) {
 			},
 			doSendDepartures: true,
 		},
+		{
+			name:        "group permit masks block users like block lists",
+			userSession: newTestSession("me", sessOptPermitMask(wire.OServiceUserFlagOSCARFree)),
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					allRelationshipsParams: allRelationshipsParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							filter:     nil,
+							result: []state.Relationship{
+								{
+									User:          state.NewIdentScreenName("friend1-masks-you"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
+								{
+									User:          state.NewIdentScreenName("friend2-masked-by-you"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
+								{
+									User:          state.NewIdentScreenName("friend3-permitted"),
+									BlocksYou:     false,
+									YouBlock:      false,
+									IsOnYourList:  false,
+									IsOnTheirList: true,
+								},
+							},
+						},
+					},
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							result:     nil,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("friend2-masked-by-you"),
+							message:    newBuddyDepartedNotif(newTestSession("me")),
+						},
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message:    newBuddyDepartedNotif(newTestSession("friend2-masked-by-you")),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend3-permitted"),
+							message:    newBuddyArrivedNotif(newTestSession("me", sessOptPermitMask(wire.OServiceUserFlagOSCARFree)).TLVUserInfo()),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("friend1-masks-you"),
+							result:     newTestSession("friend1-masks-you", sessOptSignonComplete, sessOptPermitMask(wire.OServiceUserFlagAOL)),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend2-masked-by-you"),
+							result: newTestSession("friend2-masked-by-you", sessOptSignonComplete, func(session *state.Session) {
+								session.ClearUserInfoFlag(wire.OServiceUserFlagOSCARFree)
+								session.SetUserInfoFlag(wire.OServiceUserFlagAOL)
+							}),
+						},
+						{
+							screenName: state.NewIdentScreenName("friend3-permitted"),
+							result:     newTestSession("friend3-permitted", sessOptSignonComplete),
+						},
+					},
+				},
+			},
+			doSendDepartures: true,
+		},
 		{
 			name:        "don't send departure notifications",
 			userSession: newTestSession("me"),
@@ -725,15 +1510,15 @@ func Test_buddyNotifier_BroadcastVisibility(t *testing.T) {
 					retrieveSessionParams: retrieveSessionParams{
 						{
 							screenName: state.NewIdentScreenName("friend2-visible-on-their-list"),
-							result:     newTestSession("friend2-visible-on-their-list"),
+							result:     newTestSession("friend2-visible-on-their-list", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend3-visible-on-your-list"),
-							result:     newTestSession("friend3-visible-on-your-list"),
+							result:     newTestSession("friend3-visible-on-your-list", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend4-visible-on-both-lists"),
-							result:     newTestSession("friend4-visible-on-both-lists"),
+							result:     newTestSession("friend4-visible-on-both-lists", sessOptSignonComplete),
 						},
 						{
 							screenName: state.NewIdentScreenName("friend7-visible-offline"),
@@ -821,7 +1606,37 @@ func Test_buddyNotifier_BroadcastVisibility(t *testing.T) {
 					retrieveSessionParams: retrieveSessionParams{
 						{
 							screenName: state.NewIdentScreenName("friend-visible-on-both-lists"),
-							result:     newTestSession("friend-visible-on-both-lists"),
+							result:     newTestSession("friend-visible-on-both-lists", sessOptSignonComplete),
+						},
+					},
+				},
+			},
+			doSendDepartures: true,
+		},
+		{
+			name:        "users who haven't completed sign-on aren't notified or reported online",
+			userSession: newTestSession("me"),
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					allRelationshipsParams: allRelationshipsParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							filter:     nil,
+							result: []state.Relationship{
+								{
+									User:          state.NewIdentScreenName("friend-signing-on"),
+									IsOnYourList:  true,
+									IsOnTheirList: true,
+								},
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("friend-signing-on"),
+							result:     newTestSession("friend-signing-on"),
 						},
 					},
 				},
@@ -895,3 +1710,93 @@ func newBuddyArrivedNotif(userInfo wire.TLVUserInfo) wire.SNACMessage {
 		},
 	}
 }
+
+func TestPresenceReconciler_Reconcile(t *testing.T) {
+	you := newTestSession("you", sessOptSignonComplete)
+	// online buddy you were never told about
+	alice := newTestSession("alice", sessOptSignonComplete)
+	// online buddy you already know about
+	bob := newTestSession("bob", sessOptSignonComplete)
+	// online buddy who is invisible
+	carol := newTestSession("carol", sessOptSignonComplete, sessOptInvisible)
+	ghost := state.NewIdentScreenName("ghost")
+
+	// introduce drift: you were told that bob, carol, and ghost are online,
+	// but ghost's session dropped without a departure notification
+	for _, sn := range []string{"bob", "carol", "ghost"} {
+		you.RelayMessage(wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyArrived},
+			Body: wire.SNAC_0x03_0x0B_BuddyArrived{
+				TLVUserInfo: wire.TLVUserInfo{ScreenName: sn},
+			},
+		})
+	}
+	// drain the session queue
+	for range 3 {
+		<-you.ReceiveMessage()
+	}
+
+	sessionLister := newMockSessionLister(t)
+	sessionLister.EXPECT().
+		AllSessions().
+		Return([]*state.Session{you})
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		AllRelationships(you.IdentScreenName(), []state.IdentScreenName(nil)).
+		Return([]state.Relationship{
+			{User: alice.IdentScreenName(), IsOnYourList: true},
+			{User: bob.IdentScreenName(), IsOnYourList: true},
+			{User: carol.IdentScreenName(), IsOnYourList: true},
+			{User: ghost, IsOnYourList: true},
+		}, nil)
+	buddyListRetriever.EXPECT().
+		BuddyIconRefByName(alice.IdentScreenName()).
+		Return(nil, nil)
+
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().RetrieveSession(alice.IdentScreenName()).Return(alice)
+	sessionRetriever.EXPECT().RetrieveSession(bob.IdentScreenName()).Return(bob)
+	sessionRetriever.EXPECT().RetrieveSession(carol.IdentScreenName()).Return(carol)
+	sessionRetriever.EXPECT().RetrieveSession(ghost).Return(nil)
+
+	// deliver the corrections to your session so that the next pass sees
+	// them
+	var relayed []wire.SNACMessage
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, you.IdentScreenName(), mock.Anything).
+		Run(func(ctx context.Context, screenName state.IdentScreenName, msg wire.SNACMessage) {
+			relayed = append(relayed, msg)
+			you.RelayMessage(msg)
+		})
+
+	reconciler := NewPresenceReconciler(slog.Default(), buddyListRetriever, messageRelayer, sessionRetriever, sessionLister)
+
+	assert.NoError(t, reconciler.Reconcile(context.Background()))
+	want := []wire.SNACMessage{
+		{
+			Frame: wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyArrived},
+			Body:  wire.SNAC_0x03_0x0B_BuddyArrived{TLVUserInfo: alice.TLVUserInfo()},
+		},
+		{
+			Frame: wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyDeparted},
+			Body: wire.SNAC_0x03_0x0C_BuddyDeparted{
+				TLVUserInfo: wire.TLVUserInfo{ScreenName: "carol"},
+			},
+		},
+		{
+			Frame: wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyDeparted},
+			Body: wire.SNAC_0x03_0x0C_BuddyDeparted{
+				TLVUserInfo: wire.TLVUserInfo{ScreenName: "ghost"},
+			},
+		},
+	}
+	assert.Equal(t, want, relayed)
+	assert.ElementsMatch(t, []state.IdentScreenName{alice.IdentScreenName(), bob.IdentScreenName()}, you.BuddiesToldOnline())
+
+	// the next pass finds nothing to correct
+	relayed = nil
+	assert.NoError(t, reconciler.Reconcile(context.Background()))
+	assert.Empty(t, relayed)
+}
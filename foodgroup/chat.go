@@ -6,14 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"math/rand/v2"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"golang.org/x/net/html"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -32,31 +37,106 @@ var (
 	// rollDiceRgxp matches a roll dice chat command.
 	// ex: //roll //roll-sides3 //roll-dice2 //role-sides3-dice2
 	rollDiceRgxp = regexp.MustCompile(`^//roll(?:-(dice|sides)([0-9]{1,3}))?(?:-(dice|sides)([0-9]{1,3}))?\s*$`)
+
+	// moderatorCmdRgxp matches a chat command that only room moderators can
+	// use.
+	// ex: //kick some user //ban some user //unban some user //topic cats
+	moderatorCmdRgxp = regexp.MustCompile(`^//(kick|ban|unban|topic)\s+(.+?)\s*$`)
 )
 
-// NewChatService creates a new instance of ChatService.
-func NewChatService(chatMessageRelayer ChatMessageRelayer) *ChatService {
+// NewChatService creates a new instance of ChatService. Chat room messages
+// are recorded to chatTranscriptRecorder, unless it is nil. Whispers are
+// refused in rooms that belong to whisperDisabledExchanges.
+func NewChatService(cfg config.Config, logger *slog.Logger, chatMessageRelayer ChatMessageRelayer, chatSlowModeLimiter ChatSlowModeLimiter, chatModeratorRetriever ChatModeratorRetriever, chatRoomModerationManager ChatRoomModerationManager, chatTranscriptRecorder ChatTranscriptRecorder, whisperDisabledExchanges []uint16) *ChatService {
 	return &ChatService{
-		chatMessageRelayer: chatMessageRelayer,
+		cfg:                       cfg,
+		chatMessageRelayer:        chatMessageRelayer,
+		chatModeratorRetriever:    chatModeratorRetriever,
+		chatRoomModerationManager: chatRoomModerationManager,
+		chatSlowModeLimiter:       chatSlowModeLimiter,
+		chatTranscriptRecorder:    chatTranscriptRecorder,
+		logger:                    logger,
+		whisperDisabledExchanges:  whisperDisabledExchanges,
 		randRollDie: func(sides int) int {
 			// generate random number between 1 and sides
 			return rand.IntN(sides) + 1
 		},
+		timeNow: time.Now,
 	}
 }
 
 // ChatService provides functionality for the Chat food group, which is
 // responsible for sending and receiving chat messages.
 type ChatService struct {
-	chatMessageRelayer ChatMessageRelayer
-	randRollDie        func(sides int) int
+	cfg                       config.Config
+	chatMessageRelayer        ChatMessageRelayer
+	chatModeratorRetriever    ChatModeratorRetriever
+	chatRoomModerationManager ChatRoomModerationManager
+	chatSlowModeLimiter       ChatSlowModeLimiter
+	chatTranscriptRecorder    ChatTranscriptRecorder
+	logger                    *slog.Logger
+	randRollDie               func(sides int) int
+	timeNow                   func() time.Time
+	// whisperDisabledExchanges are the exchanges whose rooms don't allow
+	// whispers.
+	whisperDisabledExchanges []uint16
 }
 
 // ChannelMsgToHost relays wire.ChatChannelMsgToClient SNAC sent from a user
 // to the other chat room participants. It returns the same
 // wire.ChatChannelMsgToClient message back to the user if the chat reflection
-// TLV flag is set, otherwise return nil.
+// TLV flag is set, otherwise return nil. Messages from spectators, or from
+// users who post too soon in a room that is in slow mode or whose accounts are
+// too new to chat, are dropped and the user is sent a notice from OnlineHost.
+// Moderator commands (//kick, //ban, //unban, and //topic) are handled by the
+// server and not relayed to the room. Relayed messages are recorded in the
+// room's transcript if transcripts are enabled. A whisper is delivered only to
+// the participant it's addressed to and is never recorded. Whispers in rooms
+// whose exchange disallows them are refused with wire.ChatErr. Empty messages
+// are dropped if configured.
 func (s ChatService) ChannelMsgToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) (*wire.SNACMessage, error) {
+	if sess.Spectator() {
+		s.sendNotice(ctx, sess, inBody, "You are a spectator in this room and can't send messages.")
+		return nil, nil
+	}
+	if s.cfg.DropEmptyMessages && isEmptyChatMsg(inBody) {
+		return nil, nil
+	}
+	if newbieRestricted(s.cfg, sess, s.timeNow) {
+		s.sendNotice(ctx, sess, inBody,
+			fmt.Sprintf("New accounts can't send chat messages for the first %d minutes.", s.cfg.NewbieRestrictionMin))
+		return nil, nil
+	}
+	if cmd, arg, isCmd := parseModeratorCommand(inBody); isCmd {
+		return nil, s.moderate(ctx, sess, inBody, cmd, arg)
+	}
+	whisperTo, isWhisper := inBody.String(wire.ChatTLVWhisperToUser)
+	if isWhisper && s.whisperDisabled(sess.ChatRoomCookie()) {
+		return &wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Chat,
+				SubGroup:  wire.ChatErr,
+				RequestID: inFrame.RequestID,
+			},
+			Body: wire.SNACError{
+				Code: wire.ErrorCodeRequestDenied,
+			},
+		}, nil
+	}
+	var whisperRecip *state.Session
+	if isWhisper {
+		whisperRecip = s.findParticipant(sess.ChatRoomCookie(), state.NewIdentScreenName(whisperTo))
+		if whisperRecip == nil {
+			s.sendNotice(ctx, sess, inBody, fmt.Sprintf("%s is not in this room.", whisperTo))
+			return nil, nil
+		}
+	}
+	if allowed, interval := s.chatSlowModeLimiter.AllowMessage(sess.ChatRoomCookie(), sess.IdentScreenName()); !allowed {
+		s.sendNotice(ctx, sess, inBody,
+			fmt.Sprintf("This room is in slow mode. Please wait %d seconds between messages.", int(interval.Seconds())))
+		return nil, nil
+	}
+
 	frameOut := wire.SNACFrame{
 		FoodGroup: wire.Chat,
 		SubGroup:  wire.ChatChannelMsgToClient,
@@ -76,11 +156,19 @@ func (s ChatService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 		return nil, err
 	}
 
-	// send message to all the participants except sender
-	s.chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
-		Frame: frameOut,
-		Body:  bodyOut,
-	})
+	if whisperRecip != nil {
+		s.chatMessageRelayer.RelayToScreenName(ctx, sess.ChatRoomCookie(), whisperRecip.IdentScreenName(), wire.SNACMessage{
+			Frame: frameOut,
+			Body:  bodyOut,
+		})
+	} else {
+		// send message to all the participants except sender
+		s.chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
+			Frame: frameOut,
+			Body:  bodyOut,
+		})
+		s.recordTranscript(ctx, sess, bodyOut)
+	}
 
 	var ret *wire.SNACMessage
 	if _, ackMsg := inBody.Bytes(wire.ChatTLVEnableReflectionFlag); ackMsg {
@@ -95,6 +183,182 @@ func (s ChatService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 	return ret, nil
 }
 
+// whisperDisabled reports whether the exchange of the chat room identified by
+// cookie disallows whispers.
+func (s ChatService) whisperDisabled(cookie string) bool {
+	exchange, ok := exchangeFromCookie(cookie)
+	return ok && slices.Contains(s.whisperDisabledExchanges, exchange)
+}
+
+// findParticipant returns the session of the chat room participant with a
+// matching screen name, or nil if they're not in the room.
+func (s ChatService) findParticipant(cookie string, screenName state.IdentScreenName) *state.Session {
+	for _, participant := range s.chatMessageRelayer.AllSessions(cookie) {
+		if participant.IdentScreenName() == screenName {
+			return participant
+		}
+	}
+	return nil
+}
+
+// recordTranscript records the text of a message relayed to the chat room in
+// the room's transcript. The entry is attributed to the sender that the
+// participants see, which is OnlineHost for die rolls. Messages without text
+// are not recorded. The message has already been relayed by the time it's
+// recorded, so a failure to record it is logged rather than returned.
+func (s ChatService) recordTranscript(ctx context.Context, sess *state.Session, bodyOut wire.SNAC_0x0E_0x06_ChatChannelMsgToClient) {
+	if s.chatTranscriptRecorder == nil {
+		return
+	}
+	messageBlob, hasMessage := bodyOut.Bytes(wire.ChatTLVMessageInfo)
+	if !hasMessage {
+		return
+	}
+	text, err := textFromChatMsgBlob(messageBlob)
+	if err != nil || len(text) == 0 {
+		return
+	}
+	sender := sess.DisplayScreenName()
+	if senderInfo, hasSender := bodyOut.Bytes(wire.ChatTLVSenderInformation); hasSender {
+		userInfo := wire.TLVUserInfo{}
+		if err := wire.UnmarshalBE(&userInfo, bytes.NewBuffer(senderInfo)); err == nil {
+			sender = state.DisplayScreenName(userInfo.ScreenName)
+		}
+	}
+	entry := state.ChatTranscriptEntry{
+		ScreenName: sender,
+		Text:       string(text),
+		Sent:       s.timeNow(),
+	}
+	if err := s.chatTranscriptRecorder.AppendChatTranscript(sess.ChatRoomCookie(), entry); err != nil {
+		s.logger.ErrorContext(ctx, "unable to record chat transcript", "err", err.Error())
+	}
+}
+
+// moderate runs a moderator command in the sender's chat room:
+//   - kick removes a user from the room. The user's chat session is closed,
+//     which announces their departure to the remaining participants.
+//   - ban removes a user from the room and keeps them from rejoining.
+//   - unban lets a banned user rejoin the room.
+//   - topic sets the room topic and announces it to the participants.
+//
+// Users who aren't moderators of the room are sent a notice instead.
+func (s ChatService) moderate(ctx context.Context, sess *state.Session, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost, cmd string, arg string) error {
+	isModerator, err := s.chatModeratorRetriever.IsChatRoomModerator(sess.ChatRoomCookie(), sess.IdentScreenName())
+	if err != nil {
+		return fmt.Errorf("chatModeratorRetriever.IsChatRoomModerator: %w", err)
+	}
+
+	cookie := sess.ChatRoomCookie()
+	switch cmd {
+	case "kick":
+		if !isModerator {
+			s.sendNotice(ctx, sess, inBody, "Only room moderators can remove users.")
+			return nil
+		}
+		target := state.DisplayScreenName(arg)
+		targetSess := s.findParticipant(cookie, target.IdentScreenName())
+		if targetSess == nil {
+			s.sendNotice(ctx, sess, inBody, fmt.Sprintf("%s is not in this room.", target))
+			return nil
+		}
+		targetSess.Close()
+		s.sendNotice(ctx, sess, inBody, fmt.Sprintf("%s was removed from the room.", targetSess.DisplayScreenName()))
+	case "ban":
+		if !isModerator {
+			s.sendNotice(ctx, sess, inBody, "Only room moderators can remove users.")
+			return nil
+		}
+		target := state.DisplayScreenName(arg)
+		if err := s.chatRoomModerationManager.AddChatRoomBan(cookie, target.IdentScreenName()); err != nil {
+			return fmt.Errorf("chatRoomModerationManager.AddChatRoomBan: %w", err)
+		}
+		if targetSess := s.findParticipant(cookie, target.IdentScreenName()); targetSess != nil {
+			targetSess.Close()
+		}
+		s.sendNotice(ctx, sess, inBody, fmt.Sprintf("%s was banned from the room.", target))
+	case "unban":
+		if !isModerator {
+			s.sendNotice(ctx, sess, inBody, "Only room moderators can unban users.")
+			return nil
+		}
+		target := state.DisplayScreenName(arg)
+		if err := s.chatRoomModerationManager.RemoveChatRoomBan(cookie, target.IdentScreenName()); err != nil {
+			return fmt.Errorf("chatRoomModerationManager.RemoveChatRoomBan: %w", err)
+		}
+		s.sendNotice(ctx, sess, inBody, fmt.Sprintf("%s was unbanned from the room.", target))
+	case "topic":
+		if !isModerator {
+			s.sendNotice(ctx, sess, inBody, "Only room moderators can set the topic.")
+			return nil
+		}
+		if err := s.chatRoomModerationManager.SetChatRoomTopic(cookie, arg); err != nil {
+			return fmt.Errorf("chatRoomModerationManager.SetChatRoomTopic: %w", err)
+		}
+		// announce the topic to everyone in the room, including the sender
+		s.chatMessageRelayer.RelayToAllExcept(ctx, cookie, state.IdentScreenName{}, onlineHostChatMsg(inBody.Cookie, inBody.Channel,
+			fmt.Sprintf("%s set the topic: %s", sess.DisplayScreenName(), html.EscapeString(arg))))
+	}
+	return nil
+}
+
+// parseModeratorCommand gets the command name and argument from a moderator
+// command, such as //kick some user.
+func parseModeratorCommand(inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) (cmd string, arg string, ok bool) {
+	messageBlob, hasMessage := inBody.Bytes(wire.ChatTLVMessageInfo)
+	if !hasMessage {
+		return "", "", false
+	}
+	messageText, err := textFromChatMsgBlob(messageBlob)
+	if err != nil {
+		return "", "", false
+	}
+	matches := moderatorCmdRgxp.FindSubmatch(messageText)
+	if len(matches) == 0 {
+		return "", "", false
+	}
+	return string(matches[1]), string(matches[2]), true
+}
+
+// sendNotice sends the user a chat message from OnlineHost that only they
+// can see.
+func (s ChatService) sendNotice(ctx context.Context, sess *state.Session, inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost, text string) {
+	s.chatMessageRelayer.RelayToScreenName(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(),
+		onlineHostChatMsg(inBody.Cookie, inBody.Channel, text))
+}
+
+// onlineHostChatMsg creates a chat message from OnlineHost that is whispered
+// to its recipients.
+func onlineHostChatMsg(cookie uint64, channel uint16, text string) wire.SNACMessage {
+	msg := wire.TLVRestBlock{}
+	msg.Append(wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"))
+	msg.Append(wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"))
+	msg.Append(wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+		"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">"+text+"</FONT></BODY></HTML>"))
+
+	block := wire.TLVRestBlock{}
+	block.Append(wire.NewTLVBE(wire.ChatTLVSenderInformation, sessOnlineHost.TLVUserInfo()))
+	block.Append(wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}))
+	block.Append(wire.NewTLVBE(wire.ChatTLVMessageInfo, msg))
+
+	if channel == math.MaxUint16 {
+		// fix incorrect channel bug in macOS client v4.0.9.
+		channel = wire.ICBMChannelMIME
+	}
+
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Chat,
+			SubGroup:  wire.ChatChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+			Cookie:       cookie,
+			Channel:      channel,
+			TLVRestBlock: block,
+		},
+	}
+}
+
 // transformChatMessage inspects and modifies the incoming chat message payload.
 //   - If message contains a properly formatted //roll command, return a roll
 //     die response.
@@ -176,6 +440,41 @@ func textFromChatMsgBlob(msg []byte) ([]byte, error) {
 	}
 }
 
+// isEmptyChatMsg reports whether a chat message has a text TLV whose HTML
+// contains only whitespace.
+func isEmptyChatMsg(inBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost) bool {
+	messageBlob, hasMessage := inBody.Bytes(wire.ChatTLVMessageInfo)
+	if !hasMessage {
+		return false
+	}
+	block := wire.TLVRestBlock{}
+	if err := wire.UnmarshalBE(&block, bytes.NewBuffer(messageBlob)); err != nil {
+		return false
+	}
+	b, hasMsg := block.Bytes(wire.ChatTLVMessageInfoText)
+	return hasMsg && isBlankHTML(b)
+}
+
+// isBlankHTML reports whether an HTML-formatted message has nothing to show,
+// meaning that its text is empty or only whitespace and it has no images.
+func isBlankHTML(b []byte) bool {
+	tok := html.NewTokenizer(bytes.NewBuffer(b))
+	for {
+		switch tok.Next() {
+		case html.ErrorToken:
+			return true
+		case html.TextToken:
+			if len(bytes.TrimFunc(tok.Text(), unicode.IsSpace)) > 0 {
+				return false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if name, _ := tok.TagName(); string(name) == "img" {
+				return false
+			}
+		}
+	}
+}
+
 // parseDiceCommand gets the number of dice and sides from a die roll command.
 //
 // The roll command is activated with //roll followed by up to two arguments to
@@ -225,11 +524,16 @@ func parseDiceCommand(in []byte) (valid bool, dice int, sides int) {
 	return true, dice, sides
 }
 
+// setOnlineChatUsers sends the user the chat room occupant list. Spectators
+// other than the user are left off the list.
 func setOnlineChatUsers(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer) {
 	snacPayloadOut := wire.SNAC_0x0E_0x03_ChatUsersJoined{}
 	sessions := chatMessageRelayer.AllSessions(sess.ChatRoomCookie())
 
 	for _, uSess := range sessions {
+		if uSess.Spectator() && uSess != sess {
+			continue
+		}
 		snacPayloadOut.Users = append(snacPayloadOut.Users, uSess.TLVUserInfo())
 	}
 
@@ -242,7 +546,12 @@ func setOnlineChatUsers(ctx context.Context, sess *state.Session, chatMessageRel
 	})
 }
 
+// alertUserJoined announces the user's arrival to the other chat room
+// participants. Spectators are not announced.
 func alertUserJoined(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer) {
+	if sess.Spectator() {
+		return
+	}
 	chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.Chat,
@@ -256,7 +565,12 @@ func alertUserJoined(ctx context.Context, sess *state.Session, chatMessageRelaye
 	})
 }
 
+// alertUserLeft announces the user's departure to the other chat room
+// participants. Spectators are not announced.
 func alertUserLeft(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer) {
+	if sess.Spectator() {
+		return
+	}
 	chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.Chat,
@@ -270,6 +584,21 @@ func alertUserLeft(ctx context.Context, sess *state.Session, chatMessageRelayer
 	})
 }
 
+// alertUserMissedMessages tells the other chat room participants that the
+// user was disconnected after missing messages because their connection
+// couldn't keep up. Nothing is sent if the user missed no messages or is a
+// spectator.
+func alertUserMissedMessages(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer) {
+	missed := sess.UndeliveredCount()
+	if missed == 0 || sess.Spectator() {
+		return
+	}
+	text := fmt.Sprintf("%s was disconnected after missing %d message(s) because their connection couldn't keep up.",
+		sess.DisplayScreenName(), missed)
+	chatMessageRelayer.RelayToAllExcept(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(),
+		onlineHostChatMsg(0, wire.ICBMChannelMIME, text))
+}
+
 func sendChatRoomInfoUpdate(ctx context.Context, sess *state.Session, chatMessageRelayer ChatMessageRelayer, room state.ChatRoom) {
 	chatMessageRelayer.RelayToScreenName(ctx, sess.ChatRoomCookie(), sess.IdentScreenName(), wire.SNACMessage{
 		Frame: wire.SNACFrame{
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -32,18 +35,24 @@ var (
 )
 
 // NewChatNavService creates a new instance of NewChatNavService.
-func NewChatNavService(logger *slog.Logger, chatRoomManager ChatRoomRegistry) *ChatNavService {
+func NewChatNavService(cfg config.Config, logger *slog.Logger, chatRoomManager ChatRoomRegistry, userManager UserManager, liveChatRoomCounter LiveChatRoomCounter) *ChatNavService {
 	return &ChatNavService{
-		logger:          logger,
-		chatRoomManager: chatRoomManager,
+		cfg:                 cfg,
+		logger:              logger,
+		chatRoomManager:     chatRoomManager,
+		userManager:         userManager,
+		liveChatRoomCounter: liveChatRoomCounter,
 	}
 }
 
 // ChatNavService provides functionality for the ChatNav food group, which
 // handles chat room creation and serving chat room metadata.
 type ChatNavService struct {
-	logger          *slog.Logger
-	chatRoomManager ChatRoomRegistry
+	cfg                 config.Config
+	logger              *slog.Logger
+	chatRoomManager     ChatRoomRegistry
+	userManager         UserManager
+	liveChatRoomCounter LiveChatRoomCounter
 }
 
 // RequestChatRights returns SNAC wire.ChatNavNavInfo, which contains chat
@@ -100,6 +109,25 @@ func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFra
 			return sendChatNavErrorSNAC(inFrame, wire.ErrorCodeNoMatch)
 		}
 
+		allowed, err := s.canCreateRoom(sess)
+		if err != nil {
+			return wire.SNACMessage{}, fmt.Errorf("%w: %w", errChatNavRoomCreateFailed, err)
+		}
+		if !allowed {
+			s.logger.Info("cannot create room: user not permitted by chat room creation policy",
+				"screen_name", sess.IdentScreenName().String(), "policy", s.cfg.ChatRoomCreation)
+			return sendChatNavErrorSNAC(inFrame, wire.ErrorCodeInsufficientRights)
+		}
+
+		// rooms stay in the registry after everyone leaves, so count only
+		// the rooms that are in use
+		if s.cfg.MaxChatRooms > 0 {
+			if s.liveChatRoomCounter.LiveChatRoomCount() >= s.cfg.MaxChatRooms {
+				s.logger.Info("cannot create room: server chat room limit reached", "limit", s.cfg.MaxChatRooms)
+				return sendChatNavErrorSNAC(inFrame, wire.ErrorCodeRequestDenied)
+			}
+		}
+
 		room = state.NewChatRoom(name, sess.IdentScreenName(), inBody.Exchange)
 
 		if err := s.chatRoomManager.CreateChatRoom(&room); err != nil {
@@ -133,6 +161,23 @@ func (s ChatNavService) CreateRoom(_ context.Context, sess *state.Session, inFra
 	}, nil
 }
 
+// canCreateRoom reports whether the chat room creation policy allows the user
+// to create chat rooms.
+func (s ChatNavService) canCreateRoom(sess *state.Session) (bool, error) {
+	switch s.cfg.ChatRoomCreation {
+	case config.ChatRoomCreationConfirmed:
+		return sess.UserInfoBitmask()&wire.OServiceUserFlagUnconfirmed == 0, nil
+	case config.ChatRoomCreationFlagged:
+		user, err := s.userManager.User(sess.IdentScreenName())
+		if err != nil {
+			return false, fmt.Errorf("userManager.User: %w", err)
+		}
+		return user != nil && user.CanCreateChatRooms, nil
+	default:
+		return true, nil
+	}
+}
+
 // RequestRoomInfo returns wire.ChatNavNavInfo, which contains metadata for
 // the chat room specified in the inFrame.hmacCookie.
 func (s ChatNavService) RequestRoomInfo(_ context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0D_0x04_ChatNavRequestRoomInfo) (wire.SNACMessage, error) {
@@ -219,3 +264,39 @@ func validateExchange(exchange uint16) error {
 	}
 	return nil
 }
+
+// ParseExchangeList parses a comma-separated list of chat exchange IDs, such
+// as "4,5". Only the exchanges that the server hosts are accepted. An empty
+// string yields an empty list.
+func ParseExchangeList(s string) ([]uint16, error) {
+	var exchanges []uint16
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		exchange, err := strconv.ParseUint(entry, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange %q: %w", entry, err)
+		}
+		if err := validateExchange(uint16(exchange)); err != nil {
+			return nil, err
+		}
+		exchanges = append(exchanges, uint16(exchange))
+	}
+	return exchanges, nil
+}
+
+// exchangeFromCookie gets the exchange ID from the first segment of a chat
+// room cookie created by state.ChatRoom.Cookie.
+func exchangeFromCookie(cookie string) (uint16, bool) {
+	prefix, _, found := strings.Cut(cookie, "-")
+	if !found {
+		return 0, false
+	}
+	exchange, err := strconv.ParseUint(prefix, 10, 16)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(exchange), true
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"testing"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
@@ -18,6 +19,7 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 
 	tests := []struct {
 		name          string
+		cfg           config.Config
 		chatRoom      *state.ChatRoom
 		sess          *state.Session
 		inputSNAC     wire.SNACMessage
@@ -148,6 +150,433 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 				return basicChatRoom
 			},
 		},
+		{
+			name: "join private room that already exists at the chat room limit",
+			cfg: config.Config{
+				MaxChatRooms: 1,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavNavInfo,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(
+								wire.ChatNavRequestRoomInfo,
+								wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       basicChatRoom.Exchange(),
+									Cookie:         basicChatRoom.Cookie(),
+									InstanceNumber: basicChatRoom.InstanceNumber(),
+									DetailLevel:    basicChatRoom.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: basicChatRoom.TLVList(),
+									},
+								},
+							),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							room:     basicChatRoom,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "create private room under the chat room limit",
+			cfg: config.Config{
+				MaxChatRooms: 2,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavNavInfo,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(
+								wire.ChatNavRequestRoomInfo,
+								wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       basicChatRoom.Exchange(),
+									Cookie:         basicChatRoom.Cookie(),
+									InstanceNumber: basicChatRoom.InstanceNumber(),
+									DetailLevel:    basicChatRoom.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: basicChatRoom.TLVList(),
+									},
+								},
+							),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							err:      state.ErrChatRoomNotFound,
+						},
+					},
+					createChatRoomParams: createChatRoomParams{
+						{
+							room: &basicChatRoom,
+						},
+					},
+				},
+				liveChatRoomCounterParams: liveChatRoomCounterParams{
+					liveChatRoomCountParams: liveChatRoomCountParams{
+						{
+							count: 1,
+						},
+					},
+				},
+			},
+			fnNewChatRoom: func() state.ChatRoom {
+				return basicChatRoom
+			},
+		},
+		{
+			name: "create private room at the chat room limit",
+			cfg: config.Config{
+				MaxChatRooms: 1,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							err:      state.ErrChatRoomNotFound,
+						},
+					},
+				},
+				liveChatRoomCounterParams: liveChatRoomCounterParams{
+					liveChatRoomCountParams: liveChatRoomCountParams{
+						{
+							count: 1,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "join private room that already exists when user may not create rooms",
+			cfg: config.Config{
+				ChatRoomCreation: config.ChatRoomCreationFlagged,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavNavInfo,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(
+								wire.ChatNavRequestRoomInfo,
+								wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       basicChatRoom.Exchange(),
+									Cookie:         basicChatRoom.Cookie(),
+									InstanceNumber: basicChatRoom.InstanceNumber(),
+									DetailLevel:    basicChatRoom.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: basicChatRoom.TLVList(),
+									},
+								},
+							),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							room:     basicChatRoom,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "create private room refused to user without room creation flag",
+			cfg: config.Config{
+				ChatRoomCreation: config.ChatRoomCreationFlagged,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeInsufficientRights,
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							err:      state.ErrChatRoomNotFound,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("the-screen-name"),
+							result: &state.User{
+								IdentScreenName:    state.NewIdentScreenName("the-screen-name"),
+								CanCreateChatRooms: false,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "create private room as user with room creation flag",
+			cfg: config.Config{
+				ChatRoomCreation: config.ChatRoomCreationFlagged,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavNavInfo,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0D_0x09_ChatNavNavInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(
+								wire.ChatNavRequestRoomInfo,
+								wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+									Exchange:       basicChatRoom.Exchange(),
+									Cookie:         basicChatRoom.Cookie(),
+									InstanceNumber: basicChatRoom.InstanceNumber(),
+									DetailLevel:    basicChatRoom.DetailLevel(),
+									TLVBlock: wire.TLVBlock{
+										TLVList: basicChatRoom.TLVList(),
+									},
+								},
+							),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							err:      state.ErrChatRoomNotFound,
+						},
+					},
+					createChatRoomParams: createChatRoomParams{
+						{
+							room: &basicChatRoom,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("the-screen-name"),
+							result: &state.User{
+								IdentScreenName:    state.NewIdentScreenName("the-screen-name"),
+								CanCreateChatRooms: true,
+							},
+						},
+					},
+				},
+			},
+			fnNewChatRoom: func() state.ChatRoom {
+				return basicChatRoom
+			},
+		},
+		{
+			name: "create private room refused to unconfirmed user",
+			cfg: config.Config{
+				ChatRoomCreation: config.ChatRoomCreationConfirmed,
+			},
+			chatRoom: &basicChatRoom,
+			sess:     newTestSession("the-screen-name", sessOptUnconfirmed),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+					Exchange:       basicChatRoom.Exchange(),
+					Cookie:         "create", // actual canned value sent by AIM client
+					InstanceNumber: basicChatRoom.InstanceNumber(),
+					DetailLevel:    basicChatRoom.DetailLevel(),
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatRoomTLVRoomName, basicChatRoom.Name()),
+						},
+					},
+				},
+			},
+			want: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ChatNav,
+					SubGroup:  wire.ChatNavErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeInsufficientRights,
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByNameParams: chatRoomByNameParams{
+						{
+							exchange: basicChatRoom.Exchange(),
+							name:     basicChatRoom.Name(),
+							err:      state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
 		{
 			name:     "create public room that already exists",
 			chatRoom: &publicChatRoom,
@@ -397,8 +826,21 @@ func TestChatNavService_CreateRoom(t *testing.T) {
 					CreateChatRoom(params.room).
 					Return(params.err)
 			}
+			userManager := newMockUserManager(t)
+			for _, params := range tt.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
 
-			svc := NewChatNavService(slog.Default(), chatRoomRegistry)
+			liveChatRoomCounter := newMockLiveChatRoomCounter(t)
+			for _, params := range tt.mockParams.liveChatRoomCountParams {
+				liveChatRoomCounter.EXPECT().
+					LiveChatRoomCount().
+					Return(params.count)
+			}
+
+			svc := NewChatNavService(tt.cfg, slog.Default(), chatRoomRegistry, userManager, liveChatRoomCounter)
 			outputSNAC, err := svc.CreateRoom(context.Background(), tt.sess, tt.inputSNAC.Frame, tt.inputSNAC.Body.(wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate))
 			assert.ErrorIs(t, err, tt.wantErr)
 			assert.Equal(t, tt.want, outputSNAC)
@@ -585,7 +1027,7 @@ func TestChatNavService_RequestRoomInfo(t *testing.T) {
 					Return(params.room, params.err)
 			}
 
-			svc := NewChatNavService(slog.Default(), chatRoomRegistry)
+			svc := NewChatNavService(config.Config{}, slog.Default(), chatRoomRegistry, nil, nil)
 			got, err := svc.RequestRoomInfo(nil, tt.inputSNAC.Frame,
 				tt.inputSNAC.Body.(wire.SNAC_0x0D_0x04_ChatNavRequestRoomInfo))
 			assert.ErrorIs(t, err, tt.wantErr)
@@ -598,7 +1040,7 @@ func TestChatNavService_RequestRoomInfo(t *testing.T) {
 }
 
 func TestChatNavService_RequestChatRights(t *testing.T) {
-	svc := NewChatNavService(nil, nil)
+	svc := NewChatNavService(config.Config{}, nil, nil, nil, nil)
 
 	have := svc.RequestChatRights(nil, wire.SNACFrame{RequestID: 1234})
 
@@ -767,7 +1209,7 @@ func TestChatNavService_ExchangeInfo(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			svc := NewChatNavService(slog.Default(), nil)
+			svc := NewChatNavService(config.Config{}, slog.Default(), nil, nil, nil)
 			outputSNAC, err := svc.ExchangeInfo(context.Background(), tt.inputSNAC.Frame,
 				tt.inputSNAC.Body.(wire.SNAC_0x0D_0x03_ChatNavRequestExchangeInfo))
 			assert.ErrorIs(t, err, tt.wantErr)
@@ -778,3 +1220,45 @@ func TestChatNavService_ExchangeInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestParseExchangeList(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    []uint16
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  nil,
+		},
+		{
+			name:  "both exchanges",
+			given: "4, 5,",
+			want:  []uint16{state.PrivateExchange, state.PublicExchange},
+		},
+		{
+			name:    "invalid number",
+			given:   "abc",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported exchange",
+			given:   "6",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseExchangeList(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
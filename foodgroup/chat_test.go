@@ -2,9 +2,13 @@ package foodgroup
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"math"
 	"testing"
+	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
@@ -63,6 +67,15 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				chatSlowModeLimiterParams: chatSlowModeLimiterParams{
+					allowMessageParams: allowMessageParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							allowed:    true,
+						},
+					},
+				},
 				chatMessageRelayerParams: chatMessageRelayerParams{
 					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
 						{
@@ -152,6 +165,15 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				chatSlowModeLimiterParams: chatSlowModeLimiterParams{
+					allowMessageParams: allowMessageParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							allowed:    true,
+						},
+					},
+				},
 				chatMessageRelayerParams: chatMessageRelayerParams{
 					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
 						{
@@ -249,6 +271,15 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				chatSlowModeLimiterParams: chatSlowModeLimiterParams{
+					allowMessageParams: allowMessageParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							allowed:    true,
+						},
+					},
+				},
 				chatMessageRelayerParams: chatMessageRelayerParams{
 					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
 						{
@@ -311,6 +342,15 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				chatSlowModeLimiterParams: chatSlowModeLimiterParams{
+					allowMessageParams: allowMessageParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							allowed:    true,
+						},
+					},
+				},
 				chatMessageRelayerParams: chatMessageRelayerParams{
 					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
 						{
@@ -344,6 +384,142 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "send chat room message too soon in slow mode, expect message dropped and notice to sender",
+			userSession: newTestSession("user_sending_chat_msg", sessOptCannedSignonTime,
+				sessOptChatRoomCookie("the-chat-cookie")),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+					Cookie:  1234,
+					Channel: 14,
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							{
+								Tag:   wire.ChatTLVEnableReflectionFlag,
+								Value: []byte{},
+							},
+							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+										"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">Hello</FONT></BODY></HTML>"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatSlowModeLimiterParams: chatSlowModeLimiterParams{
+					allowMessageParams: allowMessageParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							allowed:    false,
+							interval:   30 * time.Second,
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							cookie:     "the-chat-cookie",
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+									Cookie:  1234,
+									Channel: 14,
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ChatTLVSenderInformation, sessOnlineHost.TLVUserInfo()),
+											wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+											wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+													wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+													wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+														"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">This room is in slow mode. Please wait 30 seconds between messages.</FONT></BODY></HTML>"),
+												},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectOutput: nil,
+		},
+		{
+			name: "send chat room message as spectator, expect message dropped and notice to sender",
+			userSession: newTestSession("user_sending_chat_msg", sessOptCannedSignonTime,
+				sessOptChatRoomCookie("the-chat-cookie"), sessOptSpectator),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+					Cookie:  1234,
+					Channel: 14,
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							{
+								Tag:   wire.ChatTLVEnableReflectionFlag,
+								Value: []byte{},
+							},
+							wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+										"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">Hello</FONT></BODY></HTML>"),
+								},
+							}),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("user_sending_chat_msg"),
+							cookie:     "the-chat-cookie",
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+									Cookie:  1234,
+									Channel: 14,
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ChatTLVSenderInformation, sessOnlineHost.TLVUserInfo()),
+											wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+											wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+													wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+													wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+														"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">You are a spectator in this room and can't send messages.</FONT></BODY></HTML>"),
+												},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectOutput: nil,
+		},
 	}
 
 	for _, tc := range cases {
@@ -353,8 +529,18 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 				chatMessageRelayer.EXPECT().
 					RelayToAllExcept(mock.Anything, params.cookie, params.screenName, params.message)
 			}
+			for _, params := range tc.mockParams.chatRelayToScreenNameParams {
+				chatMessageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.cookie, params.screenName, params.message)
+			}
+			chatSlowModeLimiter := newMockChatSlowModeLimiter(t)
+			for _, params := range tc.mockParams.allowMessageParams {
+				chatSlowModeLimiter.EXPECT().
+					AllowMessage(params.cookie, params.screenName).
+					Return(params.allowed, params.interval)
+			}
 
-			svc := NewChatService(chatMessageRelayer)
+			svc := NewChatService(config.Config{}, slog.Default(), chatMessageRelayer, chatSlowModeLimiter, newMockChatModeratorRetriever(t), nil, nil, nil)
 			svc.randRollDie = tc.randRollDie
 			outputSNAC, err := svc.ChannelMsgToHost(context.Background(), tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x0E_0x05_ChatChannelMsgToHost))
@@ -364,6 +550,730 @@ func TestChatService_ChannelMsgToHost(t *testing.T) {
 	}
 }
 
+func TestChatService_ChannelMsgToHost_ModeratorCommands(t *testing.T) {
+	cmdSNAC := func(text string) wire.SNAC_0x0E_0x05_ChatChannelMsgToHost {
+		return wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+			Cookie:  1234,
+			Channel: 14,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+								"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">"+text+"</FONT></BODY></HTML>"),
+						},
+					}),
+				},
+			},
+		}
+	}
+	notice := func(text string) wire.SNACMessage {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Chat,
+				SubGroup:  wire.ChatChannelMsgToClient,
+			},
+			Body: wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+				Cookie:  1234,
+				Channel: 14,
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVSenderInformation, sessOnlineHost.TLVUserInfo()),
+						wire.NewTLVBE(wire.ChatTLVPublicWhisperFlag, []byte{}),
+						wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+							TLVList: wire.TLVList{
+								wire.NewTLVBE(wire.ChatTLVMessageInfoEncoding, "us-ascii"),
+								wire.NewTLVBE(wire.ChatTLVMessageInfoLang, "en"),
+								wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+									"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">"+text+"</FONT></BODY></HTML>"),
+							},
+						}),
+					},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// userSession is the session of the user sending the command
+		userSession *state.Session
+		// targetSession is the session of the user being kicked or banned
+		targetSession *state.Session
+		// inputBody is the SNAC sent by the sender client
+		inputBody wire.SNAC_0x0E_0x05_ChatChannelMsgToHost
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+		// wantKicked indicates whether the target session should be closed
+		wantKicked bool
+	}{
+		{
+			name:          "moderator kicks user",
+			userSession:   newTestSession("moderator", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//kick target user"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							result:     true,
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatAllSessionsParams: chatAllSessionsParams{
+						{
+							cookie: "the-chat-cookie",
+							sessions: []*state.Session{
+								newTestSession("moderator"),
+								newTestSession("Target User"),
+							},
+						},
+					},
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							message:    notice("Target User was removed from the room."),
+						},
+					},
+				},
+			},
+			wantKicked: true,
+		},
+		{
+			name:          "non-moderator is denied",
+			userSession:   newTestSession("regular_user", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//kick Target User"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("regular_user"),
+							result:     false,
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("regular_user"),
+							message:    notice("Only room moderators can remove users."),
+						},
+					},
+				},
+			},
+			wantKicked: false,
+		},
+		{
+			name:          "moderator kicks user who isn't in the room",
+			userSession:   newTestSession("moderator", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//kick nobody"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							result:     true,
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatAllSessionsParams: chatAllSessionsParams{
+						{
+							cookie: "the-chat-cookie",
+							sessions: []*state.Session{
+								newTestSession("moderator"),
+								newTestSession("Target User"),
+							},
+						},
+					},
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							message:    notice("nobody is not in this room."),
+						},
+					},
+				},
+			},
+			wantKicked: false,
+		},
+		{
+			name:          "moderator bans user",
+			userSession:   newTestSession("moderator", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//ban Target User"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							result:     true,
+						},
+					},
+				},
+				chatRoomModerationManagerParams: chatRoomModerationManagerParams{
+					addChatRoomBanParams: addChatRoomBanParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("Target User"),
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatAllSessionsParams: chatAllSessionsParams{
+						{
+							cookie: "the-chat-cookie",
+							sessions: []*state.Session{
+								newTestSession("moderator"),
+								newTestSession("Target User"),
+							},
+						},
+					},
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							message:    notice("Target User was banned from the room."),
+						},
+					},
+				},
+			},
+			wantKicked: true,
+		},
+		{
+			name:          "moderator unbans user",
+			userSession:   newTestSession("moderator", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//unban Target User"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							result:     true,
+						},
+					},
+				},
+				chatRoomModerationManagerParams: chatRoomModerationManagerParams{
+					removeChatRoomBanParams: removeChatRoomBanParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("Target User"),
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							message:    notice("Target User was unbanned from the room."),
+						},
+					},
+				},
+			},
+			wantKicked: false,
+		},
+		{
+			name:          "moderator sets topic",
+			userSession:   newTestSession("moderator", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//topic cats &amp; dogs"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("moderator"),
+							result:     true,
+						},
+					},
+				},
+				chatRoomModerationManagerParams: chatRoomModerationManagerParams{
+					setChatRoomTopicParams: setChatRoomTopicParams{
+						{
+							cookie: "the-chat-cookie",
+							topic:  "cats & dogs",
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.IdentScreenName{},
+							message:    notice("moderator set the topic: cats &amp; dogs"),
+						},
+					},
+				},
+			},
+			wantKicked: false,
+		},
+		{
+			name:          "non-moderator can't set topic",
+			userSession:   newTestSession("regular_user", sessOptChatRoomCookie("the-chat-cookie")),
+			targetSession: newTestSession("Target User", sessOptChatRoomCookie("the-chat-cookie")),
+			inputBody:     cmdSNAC("//topic cats"),
+			mockParams: mockParams{
+				chatModeratorRetrieverParams: chatModeratorRetrieverParams{
+					isChatRoomModeratorParams: isChatRoomModeratorParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("regular_user"),
+							result:     false,
+						},
+					},
+				},
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToScreenNameParams: chatRelayToScreenNameParams{
+						{
+							cookie:     "the-chat-cookie",
+							screenName: state.NewIdentScreenName("regular_user"),
+							message:    notice("Only room moderators can set the topic."),
+						},
+					},
+				},
+			},
+			wantKicked: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chatMessageRelayer := newMockChatMessageRelayer(t)
+			for _, params := range tc.mockParams.chatAllSessionsParams {
+				// substitute the target session so that the test can check
+				// whether it was closed
+				sessions := make([]*state.Session, len(params.sessions))
+				for i, sess := range params.sessions {
+					if sess.IdentScreenName() == tc.targetSession.IdentScreenName() {
+						sess = tc.targetSession
+					}
+					sessions[i] = sess
+				}
+				chatMessageRelayer.EXPECT().
+					AllSessions(params.cookie).
+					Return(sessions)
+			}
+			for _, params := range tc.mockParams.chatRelayToScreenNameParams {
+				chatMessageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.cookie, params.screenName, params.message)
+			}
+			for _, params := range tc.mockParams.chatRelayToAllExceptParams {
+				chatMessageRelayer.EXPECT().
+					RelayToAllExcept(mock.Anything, params.cookie, params.screenName, params.message)
+			}
+			chatModeratorRetriever := newMockChatModeratorRetriever(t)
+			for _, params := range tc.mockParams.isChatRoomModeratorParams {
+				chatModeratorRetriever.EXPECT().
+					IsChatRoomModerator(params.cookie, params.screenName).
+					Return(params.result, params.err)
+			}
+			chatRoomModerationManager := newMockChatRoomModerationManager(t)
+			for _, params := range tc.mockParams.addChatRoomBanParams {
+				chatRoomModerationManager.EXPECT().
+					AddChatRoomBan(params.cookie, params.screenName).
+					Return(params.err)
+			}
+			for _, params := range tc.mockParams.removeChatRoomBanParams {
+				chatRoomModerationManager.EXPECT().
+					RemoveChatRoomBan(params.cookie, params.screenName).
+					Return(params.err)
+			}
+			for _, params := range tc.mockParams.setChatRoomTopicParams {
+				chatRoomModerationManager.EXPECT().
+					SetChatRoomTopic(params.cookie, params.topic).
+					Return(params.err)
+			}
+
+			svc := NewChatService(config.Config{}, slog.Default(), chatMessageRelayer, newMockChatSlowModeLimiter(t), chatModeratorRetriever, chatRoomModerationManager, nil, nil)
+			outputSNAC, err := svc.ChannelMsgToHost(context.Background(), tc.userSession, wire.SNACFrame{}, tc.inputBody)
+			assert.NoError(t, err)
+			assert.Nil(t, outputSNAC)
+
+			select {
+			case <-tc.targetSession.Closed():
+				assert.True(t, tc.wantKicked, "target session should not be closed")
+			default:
+				assert.False(t, tc.wantKicked, "target session should be closed")
+			}
+		})
+	}
+}
+
+func TestChatService_ChannelMsgToHost_Transcript(t *testing.T) {
+	chatMsg := func(text string) wire.SNAC_0x0E_0x05_ChatChannelMsgToHost {
+		return wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+			Cookie:  1234,
+			Channel: 14,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+								"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">"+text+"</FONT></BODY></HTML>"),
+						},
+					}),
+				},
+			},
+		}
+	}
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	alice := newTestSession("Alice", sessOptChatRoomCookie("the-cookie"))
+	bob := newTestSession("Bob", sessOptChatRoomCookie("the-cookie"))
+
+	chatMessageRelayer := newMockChatMessageRelayer(t)
+	chatMessageRelayer.EXPECT().
+		RelayToAllExcept(mock.Anything, "the-cookie", mock.Anything, mock.Anything)
+	chatSlowModeLimiter := newMockChatSlowModeLimiter(t)
+	chatSlowModeLimiter.EXPECT().
+		AllowMessage("the-cookie", mock.Anything).
+		Return(true, time.Duration(0))
+
+	chatTranscriptRecorder := newMockChatTranscriptRecorder(t)
+	var recorded []state.ChatTranscriptEntry
+	chatTranscriptRecorder.EXPECT().
+		AppendChatTranscript("the-cookie", mock.Anything).
+		RunAndReturn(func(cookie string, entry state.ChatTranscriptEntry) error {
+			recorded = append(recorded, entry)
+			return nil
+		})
+
+	svc := NewChatService(config.Config{}, slog.Default(), chatMessageRelayer, chatSlowModeLimiter, newMockChatModeratorRetriever(t), nil, chatTranscriptRecorder, nil)
+	svc.randRollDie = func(sides int) int { return 3 }
+	svc.timeNow = func() time.Time { return sent }
+
+	for _, msg := range []struct {
+		sess *state.Session
+		text string
+	}{
+		{sess: alice, text: "hello"},
+		{sess: bob, text: "hi alice"},
+		{sess: bob, text: "//roll"},
+	} {
+		_, err := svc.ChannelMsgToHost(context.Background(), msg.sess, wire.SNACFrame{}, chatMsg(msg.text))
+		assert.NoError(t, err)
+	}
+
+	want := []state.ChatTranscriptEntry{
+		{ScreenName: "Alice", Text: "hello", Sent: sent},
+		{ScreenName: "Bob", Text: "hi alice", Sent: sent},
+		{ScreenName: "OnlineHost", Text: "Bob rolled 2 6-sided dice: 3 3", Sent: sent},
+	}
+	assert.Equal(t, want, recorded)
+}
+
+func TestChatService_ChannelMsgToHost_TranscriptFailure(t *testing.T) {
+	alice := newTestSession("Alice", sessOptChatRoomCookie("the-cookie"))
+
+	// the message reaches the room even though it can't be recorded
+	chatMessageRelayer := newMockChatMessageRelayer(t)
+	chatMessageRelayer.EXPECT().
+		RelayToAllExcept(mock.Anything, "the-cookie", alice.IdentScreenName(), mock.Anything)
+	chatSlowModeLimiter := newMockChatSlowModeLimiter(t)
+	chatSlowModeLimiter.EXPECT().
+		AllowMessage("the-cookie", mock.Anything).
+		Return(true, time.Duration(0))
+	chatTranscriptRecorder := newMockChatTranscriptRecorder(t)
+	chatTranscriptRecorder.EXPECT().
+		AppendChatTranscript("the-cookie", mock.Anything).
+		Return(errors.New("database is locked"))
+
+	svc := NewChatService(config.Config{}, slog.Default(), chatMessageRelayer, chatSlowModeLimiter, newMockChatModeratorRetriever(t), nil, chatTranscriptRecorder, nil)
+
+	inBody := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+		Cookie:  1234,
+		Channel: 14,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+							"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">hello</FONT></BODY></HTML>"),
+					},
+				}),
+			},
+		},
+	}
+	_, err := svc.ChannelMsgToHost(context.Background(), alice, wire.SNACFrame{}, inBody)
+	assert.NoError(t, err)
+}
+
+func TestChatService_ChannelMsgToHost_Whisper(t *testing.T) {
+	whisper := func(to string) wire.SNAC_0x0E_0x05_ChatChannelMsgToHost {
+		return wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+			Channel: wire.ICBMChannelMIME,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ChatTLVWhisperToUser, to),
+					wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+								"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\">psst</FONT></BODY></HTML>"),
+						},
+					}),
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cookie is the cookie of the chat room the sender is in
+		cookie string
+		// whisperTo is the screen name the whisper is addressed to
+		whisperTo string
+		// wantRelayedTo is the screen name the whisper is relayed to, if any
+		wantRelayedTo state.IdentScreenName
+		// wantNotice indicates whether the sender gets a notice from
+		// OnlineHost
+		wantNotice bool
+		// wantOutput is the SNAC returned to the sender
+		wantOutput *wire.SNACMessage
+	}{
+		{
+			name:          "whisper in an exchange that allows whispers",
+			cookie:        "4-0-hangout",
+			whisperTo:     "Bob",
+			wantRelayedTo: state.NewIdentScreenName("Bob"),
+		},
+		{
+			name:      "whisper in an exchange that disallows whispers",
+			cookie:    "5-0-lecture",
+			whisperTo: "Bob",
+			wantOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Chat,
+					SubGroup:  wire.ChatErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			},
+		},
+		{
+			name:       "whisper to a user who isn't in the room",
+			cookie:     "4-0-hangout",
+			whisperTo:  "Carol",
+			wantNotice: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			alice := newTestSession("Alice", sessOptChatRoomCookie(tc.cookie))
+			bob := newTestSession("Bob", sessOptChatRoomCookie(tc.cookie))
+
+			chatMessageRelayer := newMockChatMessageRelayer(t)
+			chatSlowModeLimiter := newMockChatSlowModeLimiter(t)
+			if tc.wantOutput == nil {
+				chatMessageRelayer.EXPECT().
+					AllSessions(tc.cookie).
+					Return([]*state.Session{alice, bob})
+			}
+			if tc.wantRelayedTo != (state.IdentScreenName{}) {
+				chatSlowModeLimiter.EXPECT().
+					AllowMessage(tc.cookie, alice.IdentScreenName()).
+					Return(true, time.Duration(0))
+				chatMessageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, tc.cookie, tc.wantRelayedTo, mock.Anything)
+			}
+			if tc.wantNotice {
+				chatMessageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, tc.cookie, alice.IdentScreenName(), mock.Anything)
+			}
+
+			svc := NewChatService(config.Config{}, slog.Default(), chatMessageRelayer, chatSlowModeLimiter, newMockChatModeratorRetriever(t), nil, nil,
+				[]uint16{state.PublicExchange})
+
+			output, err := svc.ChannelMsgToHost(context.Background(), alice, wire.SNACFrame{RequestID: 1234}, whisper(tc.whisperTo))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantOutput, output)
+		})
+	}
+}
+
+func TestChatService_ChannelMsgToHost_EmptyMessage(t *testing.T) {
+	blankMsg := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+		Channel: wire.ICBMChannelMIME,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVMessageInfoText,
+							"<HTML><BODY BGCOLOR=\"#ffffff\"><FONT LANG=\"0\"> &nbsp;\t</FONT></BODY></HTML>"),
+					},
+				}),
+			},
+		},
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// dropEmptyMessages is the DROP_EMPTY_MESSAGES config value
+		dropEmptyMessages bool
+		// wantRelayed indicates whether the message is relayed to the room
+		wantRelayed bool
+	}{
+		{
+			name:              "whitespace-only message is dropped when enabled",
+			dropEmptyMessages: true,
+			wantRelayed:       false,
+		},
+		{
+			name:              "whitespace-only message is relayed when disabled",
+			dropEmptyMessages: false,
+			wantRelayed:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sess := newTestSession("Alice", sessOptChatRoomCookie("the-cookie"))
+
+			chatMessageRelayer := newMockChatMessageRelayer(t)
+			chatSlowModeLimiter := newMockChatSlowModeLimiter(t)
+			if tc.wantRelayed {
+				chatSlowModeLimiter.EXPECT().
+					AllowMessage("the-cookie", sess.IdentScreenName()).
+					Return(true, time.Duration(0))
+				chatMessageRelayer.EXPECT().
+					RelayToAllExcept(mock.Anything, "the-cookie", sess.IdentScreenName(), mock.Anything)
+			}
+
+			cfg := config.Config{DropEmptyMessages: tc.dropEmptyMessages}
+			svc := NewChatService(cfg, slog.Default(), chatMessageRelayer, chatSlowModeLimiter, newMockChatModeratorRetriever(t), nil, nil, nil)
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sess, wire.SNACFrame{}, blankMsg)
+			assert.NoError(t, err)
+			assert.Nil(t, output)
+		})
+	}
+}
+
+func TestChatService_ChannelMsgToHost_NewbieRestriction(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	msg := wire.SNAC_0x0E_0x05_ChatChannelMsgToHost{
+		Cookie:  1234,
+		Channel: wire.ICBMChannelMIME,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ChatTLVMessageInfo, wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ChatTLVMessageInfoText, "<HTML><BODY>hello</BODY></HTML>"),
+					},
+				}),
+			},
+		},
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// now is the time the message is sent
+		now time.Time
+		// wantRelayed indicates whether the message is relayed to the room
+		wantRelayed bool
+	}{
+		{
+			name:        "brand-new account is restricted, expect message dropped and notice to sender",
+			now:         createdAt.Add(time.Minute),
+			wantRelayed: false,
+		},
+		{
+			name:        "restriction lifts once the period passes, expect message relayed",
+			now:         createdAt.Add(10 * time.Minute),
+			wantRelayed: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sess := newTestSession("Alice", sessOptChatRoomCookie("the-cookie"))
+			sess.SetAccountCreatedAt(createdAt)
+
+			chatMessageRelayer := newMockChatMessageRelayer(t)
+			chatSlowModeLimiter := newMockChatSlowModeLimiter(t)
+			if tc.wantRelayed {
+				chatSlowModeLimiter.EXPECT().
+					AllowMessage("the-cookie", sess.IdentScreenName()).
+					Return(true, time.Duration(0))
+				chatMessageRelayer.EXPECT().
+					RelayToAllExcept(mock.Anything, "the-cookie", sess.IdentScreenName(), mock.Anything)
+			} else {
+				chatMessageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, "the-cookie", sess.IdentScreenName(),
+						onlineHostChatMsg(msg.Cookie, msg.Channel, "New accounts can't send chat messages for the first 10 minutes."))
+			}
+
+			cfg := config.Config{NewbieRestrictionMin: 10}
+			svc := NewChatService(cfg, slog.Default(), chatMessageRelayer, chatSlowModeLimiter, newMockChatModeratorRetriever(t), nil, nil, nil)
+			svc.timeNow = func() time.Time { return tc.now }
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sess, wire.SNACFrame{}, msg)
+			assert.NoError(t, err)
+			assert.Nil(t, output)
+		})
+	}
+}
+
+func TestIsBlankHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		given string
+		want  bool
+	}{
+		{
+			name:  "empty string",
+			given: "",
+			want:  true,
+		},
+		{
+			name:  "whitespace in formatting tags",
+			given: "<HTML><BODY><FONT> \r\n&nbsp;</FONT></BODY></HTML>",
+			want:  true,
+		},
+		{
+			name:  "text after whitespace",
+			given: "<HTML><BODY><FONT> </FONT><B>hi</B></BODY></HTML>",
+			want:  false,
+		},
+		{
+			name:  "image without text",
+			given: "<HTML><BODY><IMG SRC=\"smile.gif\"></BODY></HTML>",
+			want:  false,
+		},
+		{
+			name:  "plain text",
+			given: "hello",
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isBlankHTML([]byte(tt.given)))
+		})
+	}
+}
+
 func TestParseDiceCommand(t *testing.T) {
 	tests := []struct {
 		input         []byte
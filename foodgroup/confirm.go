@@ -0,0 +1,100 @@
+package foodgroup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// confirmTokenTTL is how long an account confirmation link stays valid.
+const confirmTokenTTL = 24 * time.Hour
+
+// NewConfirmationMailer creates a new instance of ConfirmationMailer that
+// sends mail through the SMTP server at host:port. The SMTP username and
+// password are optional. confirmURL is the address of the public API
+// GET /confirm endpoint as reached by users.
+func NewConfirmationMailer(logger *slog.Logger, host, port, username, password, from, confirmURL string) ConfirmationMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return ConfirmationMailer{
+		addr:       net.JoinHostPort(host, port),
+		auth:       auth,
+		confirmURL: confirmURL,
+		from:       from,
+		logger:     logger,
+		sendMail:   smtp.SendMail,
+		timeNow:    time.Now,
+	}
+}
+
+// ConfirmationMailer emails account confirmation links to users.
+type ConfirmationMailer struct {
+	addr       string
+	auth       smtp.Auth
+	confirmURL string
+	from       string
+	logger     *slog.Logger
+	sendMail   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	timeNow    func() time.Time
+}
+
+// SendConfirmation emails a link that confirms the account of screenName
+// using token. The email is sent in the background so that it doesn't hold up
+// the client. Delivery failures are logged.
+func (m ConfirmationMailer) SendConfirmation(screenName state.DisplayScreenName, emailAddress *mail.Address, token string) {
+	msg, err := m.message(screenName, emailAddress, token)
+	if err != nil {
+		m.logger.Error("unable to create confirmation email", "err", err.Error())
+		return
+	}
+	go func() {
+		if err := m.sendMail(m.addr, m.auth, m.from, []string{emailAddress.Address}, msg); err != nil {
+			m.logger.Error("unable to send confirmation email",
+				"screen_name", screenName.String(), "err", err.Error())
+			return
+		}
+		m.logger.Info("sent confirmation email", "screen_name", screenName.String())
+	}()
+}
+
+// message creates the confirmation email message.
+func (m ConfirmationMailer) message(screenName state.DisplayScreenName, emailAddress *mail.Address, token string) ([]byte, error) {
+	link, err := url.Parse(m.confirmURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid confirmation URL: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", m.from)
+	fmt.Fprintf(buf, "To: %s\r\n", emailAddress.String())
+	fmt.Fprintf(buf, "Subject: Confirm your account %s\r\n", screenName)
+	fmt.Fprintf(buf, "Date: %s\r\n", m.timeNow().Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(buf, "\r\n")
+	fmt.Fprintf(buf, "To confirm the account %s, open this link:\r\n\r\n%s\r\n\r\n", screenName, link.String())
+	fmt.Fprintf(buf, "The link expires in %d hours.\r\n", int(confirmTokenTTL.Hours()))
+	return buf.Bytes(), nil
+}
+
+// newConfirmToken creates a random account confirmation token.
+func newConfirmToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
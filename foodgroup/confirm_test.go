@@ -0,0 +1,62 @@
+package foodgroup
+
+import (
+	"log/slog"
+	"net/mail"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmationMailer_SendConfirmation(t *testing.T) {
+	type sentMail struct {
+		addr string
+		from string
+		to   []string
+		msg  string
+	}
+	sent := make(chan sentMail, 1)
+
+	mailer := NewConfirmationMailer(slog.Default(), "smtp.example.com", "587", "user", "pass",
+		"noreply@example.com", "https://aim.example.com/confirm")
+	mailer.timeNow = func() time.Time {
+		return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+	}
+	mailer.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		assert.NotNil(t, a)
+		sent <- sentMail{addr: addr, from: from, to: to, msg: string(msg)}
+		return nil
+	}
+
+	mailer.SendConfirmation("Chatting Chuck", &mail.Address{Address: "chuck@example.com"}, "the-token")
+
+	select {
+	case have := <-sent:
+		assert.Equal(t, "smtp.example.com:587", have.addr)
+		assert.Equal(t, "noreply@example.com", have.from)
+		assert.Equal(t, []string{"chuck@example.com"}, have.to)
+		assert.Equal(t, "From: noreply@example.com\r\n"+
+			"To: <chuck@example.com>\r\n"+
+			"Subject: Confirm your account Chatting Chuck\r\n"+
+			"Date: Sat, 01 Aug 2020 00:00:00 +0000\r\n"+
+			"Content-Type: text/plain; charset=UTF-8\r\n"+
+			"\r\n"+
+			"To confirm the account Chatting Chuck, open this link:\r\n\r\n"+
+			"https://aim.example.com/confirm?token=the-token\r\n\r\n"+
+			"The link expires in 24 hours.\r\n", have.msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("confirmation email was not sent")
+	}
+}
+
+func TestNewConfirmToken(t *testing.T) {
+	token1, err := newConfirmToken()
+	assert.NoError(t, err)
+	token2, err := newConfirmToken()
+	assert.NoError(t, err)
+
+	assert.Len(t, token1, 32)
+	assert.NotEqual(t, token1, token2)
+}
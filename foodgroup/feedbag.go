@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -20,24 +23,40 @@ func NewFeedbagService(
 	bartManager BARTManager,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	offlineMessageManager OfflineMessageManager,
+	cfg config.Config,
+	screenNameResolver ScreenNameResolver,
 ) FeedbagService {
 	return FeedbagService{
-		bartManager:      bartManager,
-		buddyBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
-		feedbagManager:   feedbagManager,
-		logger:           logger,
-		messageRelayer:   messageRelayer,
+		bartManager:           bartManager,
+		buddyBroadcaster:      newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		buddyListRetriever:    buddyListRetriever,
+		cfg:                   cfg,
+		feedbagManager:        feedbagManager,
+		logger:                logger,
+		messageRelayer:        messageRelayer,
+		offlineMessageManager: offlineMessageManager,
+		screenNameResolver:    screenNameResolver,
+		sessionRetriever:      sessionRetriever,
+		timeNow:               time.Now,
 	}
 }
 
 // FeedbagService provides functionality for the Feedbag food group, which
 // handles buddy list management.
 type FeedbagService struct {
-	bartManager      BARTManager
-	buddyBroadcaster buddyBroadcaster
-	feedbagManager   FeedbagManager
-	logger           *slog.Logger
-	messageRelayer   MessageRelayer
+	bartManager           BARTManager
+	buddyBroadcaster      buddyBroadcaster
+	buddyListRetriever    BuddyListRetriever
+	cfg                   config.Config
+	feedbagManager        FeedbagManager
+	logger                *slog.Logger
+	messageRelayer        MessageRelayer
+	offlineMessageManager OfflineMessageManager
+	// screenNameResolver resolves aliases on the buddy list to their accounts.
+	screenNameResolver ScreenNameResolver
+	sessionRetriever   SessionRetriever
+	timeNow            func() time.Time
 }
 
 // RightsQuery returns SNAC wire.FeedbagRightsReply, which contains Feedbag
@@ -90,80 +109,122 @@ func (s FeedbagService) RightsQuery(_ context.Context, inFrame wire.SNACFrame) w
 }
 
 // Query fetches the user's feedbag (aka buddy list). It returns
-// wire.FeedbagReply, which contains feedbag entries.
-func (s FeedbagService) Query(_ context.Context, sess *state.Session, inFrame wire.SNACFrame) (wire.SNACMessage, error) {
+// wire.FeedbagReply, which contains feedbag entries in the order arranged by
+// the user. Large feedbags are split across multiple wire.FeedbagReply SNACs
+// according to the configured item cap. The feedbag is activated for the
+// session if FeedbagUse hasn't been received yet.
+func (s FeedbagService) Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) ([]wire.SNACMessage, error) {
+	if err := s.activateFeedbag(ctx, sess); err != nil {
+		return nil, err
+	}
+	sess.SetFeedbagQueried()
+
 	fb, err := s.feedbagManager.Feedbag(sess.IdentScreenName())
 	if err != nil {
-		return wire.SNACMessage{}, err
+		return nil, err
 	}
+	fb = state.OrderFeedbag(fb)
 
 	lm := time.UnixMilli(0)
 
 	if len(fb) > 0 {
 		lm, err = s.feedbagManager.FeedbagLastModified(sess.IdentScreenName())
 		if err != nil {
-			return wire.SNACMessage{}, err
+			return nil, err
 		}
 	}
 
-	return wire.SNACMessage{
-		Frame: wire.SNACFrame{
-			FoodGroup: wire.Feedbag,
-			SubGroup:  wire.FeedbagReply,
-			RequestID: inFrame.RequestID,
-		},
-		Body: wire.SNAC_0x13_0x06_FeedbagReply{
-			Version:    0,
-			Items:      fb,
-			LastUpdate: uint32(lm.Unix()),
-		},
-	}, nil
+	return s.feedbagReplies(ctx, sess, inFrame, fb, lm), nil
 }
 
 // QueryIfModified fetches the user's feedbag (aka buddy list). It returns
 // wire.FeedbagReplyNotModified if the feedbag was last modified before
 // inBody.LastUpdate, else return wire.FeedbagReply, which contains feedbag
-// entries.
-func (s FeedbagService) QueryIfModified(_ context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x05_FeedbagQueryIfModified) (wire.SNACMessage, error) {
+// entries. Large feedbags are split across multiple wire.FeedbagReply SNACs
+// according to the configured item cap. The feedbag is activated for the
+// session if FeedbagUse hasn't been received yet.
+func (s FeedbagService) QueryIfModified(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x05_FeedbagQueryIfModified) ([]wire.SNACMessage, error) {
+	if err := s.activateFeedbag(ctx, sess); err != nil {
+		return nil, err
+	}
+	sess.SetFeedbagQueried()
+
 	fb, err := s.feedbagManager.Feedbag(sess.IdentScreenName())
 	if err != nil {
-		return wire.SNACMessage{}, err
+		return nil, err
 	}
+	fb = state.OrderFeedbag(fb)
 
 	lm := time.UnixMilli(0)
 
 	if len(fb) > 0 {
 		lm, err = s.feedbagManager.FeedbagLastModified(sess.IdentScreenName())
 		if err != nil {
-			return wire.SNACMessage{}, err
+			return nil, err
 		}
 		if lm.Before(time.Unix(int64(inBody.LastUpdate), 0)) {
-			return wire.SNACMessage{
-				Frame: wire.SNACFrame{
-					FoodGroup: wire.Feedbag,
-					SubGroup:  wire.FeedbagReplyNotModified,
-					RequestID: inFrame.RequestID,
-				},
-				Body: wire.SNAC_0x13_0x05_FeedbagQueryIfModified{
-					LastUpdate: uint32(lm.Unix()),
-					Count:      uint8(len(fb)),
+			return []wire.SNACMessage{
+				{
+					Frame: wire.SNACFrame{
+						FoodGroup: wire.Feedbag,
+						SubGroup:  wire.FeedbagReplyNotModified,
+						RequestID: inFrame.RequestID,
+					},
+					Body: wire.SNAC_0x13_0x05_FeedbagQueryIfModified{
+						LastUpdate: uint32(lm.Unix()),
+						Count:      uint8(len(fb)),
+					},
 				},
 			}, nil
 		}
 	}
 
-	return wire.SNACMessage{
-		Frame: wire.SNACFrame{
+	return s.feedbagReplies(ctx, sess, inFrame, fb, lm), nil
+}
+
+// feedbagReplies builds the wire.FeedbagReply SNACs that deliver fb to the
+// client. If config.Config.FeedbagReplyMaxItems is set, the items are split
+// across as many SNACs as needed to stay within the cap, and every SNAC but
+// the last is flagged with wire.SNACFlagMoreReplies. An empty feedbag is
+// always delivered in a single SNAC.
+func (s FeedbagService) feedbagReplies(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, fb []wire.FeedbagItem, lm time.Time) []wire.SNACMessage {
+	if s.cfg.FeedbagLargeListWarnItems > 0 && len(fb) > s.cfg.FeedbagLargeListWarnItems {
+		s.logger.WarnContext(ctx, "user has an oversized feedbag, some clients may fail to load it",
+			"screen_name", sess.IdentScreenName().String(), "items", len(fb))
+	}
+
+	chunkSize := len(fb)
+	if s.cfg.FeedbagReplyMaxItems > 0 && s.cfg.FeedbagReplyMaxItems < chunkSize {
+		chunkSize = s.cfg.FeedbagReplyMaxItems
+	}
+
+	var chunks [][]wire.FeedbagItem
+	if chunkSize == 0 {
+		chunks = [][]wire.FeedbagItem{fb}
+	} else {
+		chunks = slices.Collect(slices.Chunk(fb, chunkSize))
+	}
+
+	replies := make([]wire.SNACMessage, 0, len(chunks))
+	for i, chunk := range chunks {
+		frame := wire.SNACFrame{
 			FoodGroup: wire.Feedbag,
 			SubGroup:  wire.FeedbagReply,
 			RequestID: inFrame.RequestID,
-		},
-		Body: wire.SNAC_0x13_0x06_FeedbagReply{
-			Version:    0,
-			Items:      fb,
-			LastUpdate: uint32(lm.Unix()),
-		},
-	}, nil
+		}
+		if i < len(chunks)-1 {
+			frame.Flags |= wire.SNACFlagMoreReplies
+		}
+		replies = append(replies, wire.SNACMessage{
+			Frame: frame,
+			Body: wire.SNAC_0x13_0x06_FeedbagReply{
+				Version:    0,
+				Items:      chunk,
+				LastUpdate: uint32(lm.Unix()),
+			},
+		})
+	}
+	return replies
 }
 
 // UpsertItem updates items in the user's feedbag (aka buddy list). Sends user
@@ -173,8 +234,16 @@ func (s FeedbagService) QueryIfModified(_ context.Context, sess *state.Session,
 // confirmation.
 // UpdateItem updates items in the user's feedbag (aka buddy list). Sends user
 // buddy arrival notifications for each online & visible buddy added to the
-// feedbag. It returns wire.FeedbagStatus, which contains update confirmation.
+// feedbag. It returns wire.FeedbagStatus, which contains update confirmation,
+// or wire.FeedbagErr if the client tries to change a shared group. Screen
+// name aliases are stored as the accounts they belong to, so that presence
+// updates reach the buddy list.
 func (s FeedbagService) UpsertItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, items []wire.FeedbagItem) (wire.SNACMessage, error) {
+	items, err := s.canonicalItems(items)
+	if err != nil {
+		return wire.SNACMessage{}, err
+	}
+
 	for _, item := range items {
 		// don't let users block themselves, it causes the AIM client to go
 		// into a weird state.
@@ -192,7 +261,27 @@ func (s FeedbagService) UpsertItem(ctx context.Context, sess *state.Session, inF
 		}
 	}
 
+	// clients mishandle buddies that don't belong to a group, so reject them
+	// rather than storing them.
+	if ok, err := s.buddiesHaveGroups(sess, items); err != nil {
+		return wire.SNACMessage{}, err
+	} else if !ok {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Feedbag,
+				SubGroup:  wire.FeedbagErr,
+				RequestID: inFrame.RequestID,
+			},
+			Body: wire.SNACError{
+				Code: wire.ErrorCodeInvalidSnac,
+			},
+		}, nil
+	}
+
 	if err := s.feedbagManager.FeedbagUpsert(sess.IdentScreenName(), items); err != nil {
+		if errors.Is(err, state.ErrSharedGroupReadOnly) {
+			return sharedGroupReadOnlyErr(inFrame), nil
+		}
 		return wire.SNACMessage{}, err
 	}
 
@@ -211,7 +300,9 @@ func (s FeedbagService) UpsertItem(ctx context.Context, sess *state.Session, inF
 		}
 	}
 
-	if alertAll || len(filter) > 0 {
+	// buddies learn about the user's presence when sign-on completes, so don't
+	// reveal it early.
+	if (alertAll || len(filter) > 0) && sess.SignonComplete() {
 		if err := s.buddyBroadcaster.BroadcastVisibility(ctx, sess, filter, true); err != nil {
 			return wire.SNACMessage{}, err
 		}
@@ -248,6 +339,10 @@ func (s FeedbagService) broadcastIconUpdate(ctx context.Context, sess *state.Ses
 	if bytes.Equal(btlv.Hash, wire.GetClearIconHash()) {
 		s.logger.DebugContext(ctx, "user is clearing icon",
 			"hash", fmt.Sprintf("%x", btlv.Hash))
+		if !sess.SignonComplete() {
+			// buddies learn about the icon when sign-on completes
+			return nil
+		}
 		// tell buddies about the icon update
 		return s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess)
 	}
@@ -269,9 +364,12 @@ func (s FeedbagService) broadcastIconUpdate(ctx context.Context, sess *state.Ses
 	} else {
 		s.logger.DebugContext(ctx, "icon already exists in BART store, don't upload the icon file",
 			"hash", fmt.Sprintf("%x", btlv.Hash))
-		// tell buddies about the icon update
-		if err := s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess); err != nil {
-			return err
+		// tell buddies about the icon update. if the client hasn't completed
+		// sign-on, buddies learn about the icon when it does.
+		if sess.SignonComplete() {
+			if err := s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -291,23 +389,36 @@ func (s FeedbagService) broadcastIconUpdate(ctx context.Context, sess *state.Ses
 // DeleteItem removes items from feedbag (aka buddy list). Sends user buddy
 // arrival notifications for each online & visible buddy added to the feedbag.
 // Sends buddy arrival notifications to each unblocked buddy if current user is
-// visible. It returns wire.FeedbagStatus, which contains update confirmation.
+// visible. It returns wire.FeedbagStatus, which contains update confirmation,
+// or wire.FeedbagErr if the client tries to change a shared group.
 func (s FeedbagService) DeleteItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x0A_FeedbagDeleteItem) (wire.SNACMessage, error) {
-	if err := s.feedbagManager.FeedbagDelete(sess.IdentScreenName(), inBody.Items); err != nil {
+	items, err := s.canonicalItems(inBody.Items)
+	if err != nil {
+		return wire.SNACMessage{}, err
+	}
+
+	if err := s.feedbagManager.FeedbagDelete(sess.IdentScreenName(), items); err != nil {
+		if errors.Is(err, state.ErrSharedGroupReadOnly) {
+			return sharedGroupReadOnlyErr(inFrame), nil
+		}
 		return wire.SNACMessage{}, err
 	}
 
 	var filter []state.IdentScreenName
 
-	for _, item := range inBody.Items {
+	for _, item := range items {
 		switch item.ClassID {
 		case wire.FeedbagClassIdBuddy, wire.FeedbagClassIDDeny, wire.FeedbagClassIDPermit:
 			filter = append(filter, state.NewIdentScreenName(item.Name))
 		}
 	}
 
-	if err := s.buddyBroadcaster.BroadcastVisibility(ctx, sess, filter, true); err != nil {
-		return wire.SNACMessage{}, err
+	// buddies learn about the user's presence when sign-on completes, so don't
+	// reveal it early.
+	if sess.SignonComplete() {
+		if err := s.buddyBroadcaster.BroadcastVisibility(ctx, sess, filter, true); err != nil {
+			return wire.SNACMessage{}, err
+		}
 	}
 
 	snacPayloadOut := wire.SNAC_0x13_0x0E_FeedbagStatus{}
@@ -325,43 +436,259 @@ func (s FeedbagService) DeleteItem(ctx context.Context, sess *state.Session, inF
 	}, nil
 }
 
-// StartCluster exists to capture the SNAC input in unit tests to verify it's
-// correctly unmarshalled.
-func (s FeedbagService) StartCluster(context.Context, wire.SNACFrame, wire.SNAC_0x13_0x11_FeedbagStartCluster) {
+// canonicalItems returns a copy of items in which buddy, permit and deny
+// entries that name a screen name alias are renamed to the account that the
+// alias belongs to.
+func (s FeedbagService) canonicalItems(items []wire.FeedbagItem) ([]wire.FeedbagItem, error) {
+	items = slices.Clone(items)
+	for i, item := range items {
+		switch item.ClassID {
+		case wire.FeedbagClassIdBuddy, wire.FeedbagClassIDPermit, wire.FeedbagClassIDDeny:
+			sn := state.NewIdentScreenName(item.Name)
+			canonical, err := s.screenNameResolver.CanonicalScreenName(sn)
+			if err != nil {
+				return nil, fmt.Errorf("CanonicalScreenName: %w", err)
+			}
+			if canonical != sn {
+				items[i].Name = canonical.String()
+			}
+		}
+	}
+	return items, nil
+}
+
+// buddiesHaveGroups reports whether every buddy in items belongs to a group
+// other than the root group that is either in items or already in the user's
+// feedbag. The feedbag is only read if a buddy's group isn't in items.
+func (s FeedbagService) buddiesHaveGroups(sess *state.Session, items []wire.FeedbagItem) (bool, error) {
+	groups := make(map[uint16]bool)
+	for _, item := range items {
+		if item.ClassID == wire.FeedbagClassIdGroup {
+			groups[item.GroupID] = true
+		}
+	}
+
+	var fetched bool
+	for _, item := range items {
+		if item.ClassID != wire.FeedbagClassIdBuddy {
+			continue
+		}
+		// group 0 is the root group, which holds groups rather than buddies
+		if item.GroupID == 0 {
+			return false, nil
+		}
+		if groups[item.GroupID] {
+			continue
+		}
+		if fetched {
+			return false, nil
+		}
+		fb, err := s.feedbagManager.Feedbag(sess.IdentScreenName())
+		if err != nil {
+			return false, err
+		}
+		for _, fbItem := range fb {
+			if fbItem.ClassID == wire.FeedbagClassIdGroup {
+				groups[fbItem.GroupID] = true
+			}
+		}
+		fetched = true
+		if !groups[item.GroupID] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sharedGroupReadOnlyErr returns the error sent to clients that try to change
+// a shared group, which only the operator can change.
+func sharedGroupReadOnlyErr(inFrame wire.SNACFrame) wire.SNACMessage {
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagErr,
+			RequestID: inFrame.RequestID,
+		},
+		Body: wire.SNACError{
+			Code: wire.ErrorCodeNotSupportedByHost,
+		},
+	}
+}
+
+// StartCluster marks the start of a group of feedbag edits that the client
+// treats as a single transaction. Edits are applied as they arrive, so the
+// session only tracks whether a group is open. A group left open longer than
+// the configured timeout is considered abandoned and is finalized so that the
+// client can start a new one. It returns SNAC wire.FeedbagErr if the client
+// tries to open a group while another is still open, or nil if the group was
+// started.
+func (s FeedbagService) StartCluster(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, _ wire.SNAC_0x13_0x11_FeedbagStartCluster) *wire.SNACMessage {
+	if s.cfg.FeedbagClusterTimeoutSec <= 0 {
+		return nil
+	}
+
+	now := s.timeNow()
+	if started := sess.FeedbagClusterStart(); !started.IsZero() {
+		timeout := time.Duration(s.cfg.FeedbagClusterTimeoutSec) * time.Second
+		if now.Sub(started) < timeout {
+			s.logger.DebugContext(ctx, "rejected nested feedbag cluster")
+			return &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagErr,
+					RequestID: inFrame.RequestID,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			}
+		}
+		s.logger.DebugContext(ctx, "finalized abandoned feedbag cluster", "started", started)
+	}
+
+	sess.StartFeedbagCluster(now)
+	return nil
+}
+
+// EndCluster marks the end of the client's current group of feedbag edits.
+func (s FeedbagService) EndCluster(_ context.Context, sess *state.Session) {
+	sess.EndFeedbagCluster()
 }
 
 // Use sends a user the contents of their buddy list. It's invoked at sign-on
 // by AIM clients that use the feedbag food group for buddy list management (as
-// opposed to client-side management).
+// opposed to client-side management). Some clients send it before
+// FeedbagQuery, which is tolerated but logged.
 func (s FeedbagService) Use(ctx context.Context, sess *state.Session) error {
+	if !sess.FeedbagQueried() {
+		s.logger.DebugContext(ctx, "client sent FeedbagUse before FeedbagQuery")
+	}
+	return s.activateFeedbag(ctx, sess)
+}
+
+// activateFeedbag switches the session to the server-side buddy list if it
+// hasn't been switched already. Clients don't agree on whether FeedbagQuery or
+// FeedbagUse comes first, so the feedbag is activated by whichever arrives
+// first.
+func (s FeedbagService) activateFeedbag(ctx context.Context, sess *state.Session) error {
+	if sess.FeedbagInUse() {
+		return nil
+	}
 	if err := s.feedbagManager.UseFeedbag(sess.IdentScreenName()); err != nil {
 		return fmt.Errorf("could not use feedbag: %w", err)
 	}
+	sess.SetFeedbagInUse()
+	return nil
+}
+
+// RequestAuthorizeToHost forwards an authorization request to the user whose
+// authorization is required to add them to the requester's buddy list.
+// Clients that use the feedbag receive SNAC(0x0013,0x0019), while older ICQ
+// clients receive the request as an ICQ channel ICBM. If an ICQ recipient is
+// offline, the request is stored and delivered with their offline messages.
+// Requests between users who block each other are dropped.
+func (s FeedbagService) RequestAuthorizeToHost(ctx context.Context, sess *state.Session, _ wire.SNACFrame, inBody wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost) error {
+	recip := state.NewIdentScreenName(inBody.ScreenName)
+	if blocked, err := s.authMessageBlocked(ctx, sess, recip); err != nil || blocked {
+		return err
+	}
+
+	icqMsg := wire.ICBMCh4Message{
+		UIN:         sess.UIN(),
+		MessageType: wire.ICBMMsgTypeAuthReq,
+		Message:     icqAuthRequestText(inBody.Reason),
+	}
+
+	recipSess := s.sessionRetriever.RetrieveSession(recip)
+	switch {
+	case recipSess == nil:
+		return s.saveOfflineAuthMessage(ctx, sess, recip, icqMsg)
+	case recipSess.FeedbagInUse():
+		s.messageRelayer.RelayToScreenName(ctx, recip, wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Feedbag,
+				SubGroup:  wire.FeedbagRequestAuthorizeToClient,
+			},
+			Body: wire.SNAC_0x13_0x19_FeedbagRequestAuthorizeToClient{
+				ScreenName: sess.DisplayScreenName().String(),
+				Reason:     inBody.Reason,
+			},
+		})
+	default:
+		s.relayICQAuthMessage(ctx, sess, recip, icqMsg)
+	}
+
 	return nil
 }
 
 // RespondAuthorizeToHost forwards an authorization response from the user
 // whose authorization was requested to the user who made the authorization
-// request.
-// Right now we send an ICBM request so that responses can work for both ICQ
-// 2000b and ICQ 2001a. This function should eventually only send an ICBM
-// message to non-feedbag clients and SNAC(0x0013,0x001B) to feedbag clients.
-func (s FeedbagService) RespondAuthorizeToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost) error {
-	response := wire.ICBMCh4Message{
+// request. Clients that use the feedbag receive SNAC(0x0013,0x001B), while
+// older ICQ clients receive the response as an ICQ channel ICBM. If an ICQ
+// requester is offline, the response is stored and delivered with their
+// offline messages. Responses between users who block each other are dropped.
+func (s FeedbagService) RespondAuthorizeToHost(ctx context.Context, sess *state.Session, _ wire.SNACFrame, inBody wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost) error {
+	icqMsg := wire.ICBMCh4Message{
 		UIN:     sess.UIN(),
 		Message: inBody.Reason,
 	}
 
 	switch inBody.Accepted {
 	case 0:
-		response.MessageType = wire.ICBMMsgTypeAuthDeny
+		icqMsg.MessageType = wire.ICBMMsgTypeAuthDeny
 	case 1:
-		response.MessageType = wire.ICBMMsgTypeAuthOK
+		icqMsg.MessageType = wire.ICBMMsgTypeAuthOK
 	default:
 		return fmt.Errorf("invalid accepted flag %d", inBody.Accepted)
 	}
 
-	s.messageRelayer.RelayToScreenName(ctx, state.NewIdentScreenName(inBody.ScreenName), wire.SNACMessage{
+	recip := state.NewIdentScreenName(inBody.ScreenName)
+	if blocked, err := s.authMessageBlocked(ctx, sess, recip); err != nil || blocked {
+		return err
+	}
+
+	recipSess := s.sessionRetriever.RetrieveSession(recip)
+	switch {
+	case recipSess == nil:
+		return s.saveOfflineAuthMessage(ctx, sess, recip, icqMsg)
+	case recipSess.FeedbagInUse():
+		s.messageRelayer.RelayToScreenName(ctx, recip, wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.Feedbag,
+				SubGroup:  wire.FeedbagRespondAuthorizeToClient,
+			},
+			Body: wire.SNAC_0x13_0x1B_FeedbagRespondAuthorizeToClient{
+				ScreenName: sess.DisplayScreenName().String(),
+				Accepted:   inBody.Accepted,
+				Reason:     inBody.Reason,
+			},
+		})
+	default:
+		s.relayICQAuthMessage(ctx, sess, recip, icqMsg)
+	}
+
+	return nil
+}
+
+// authMessageBlocked indicates whether an authorization message from sess to
+// recip must be dropped because either user blocks the other.
+func (s FeedbagService) authMessageBlocked(ctx context.Context, sess *state.Session, recip state.IdentScreenName) (bool, error) {
+	rel, err := s.buddyListRetriever.Relationship(sess.IdentScreenName(), recip)
+	if err != nil {
+		return false, fmt.Errorf("buddyListRetriever.Relationship: %w", err)
+	}
+	if rel.BlocksYou || rel.YouBlock {
+		s.logger.DebugContext(ctx, "dropped authorization message between blocked users",
+			"recipient", recip.String())
+		return true, nil
+	}
+	return false, nil
+}
+
+// relayICQAuthMessage sends an authorization request or response to an
+// online client as an ICQ channel ICBM.
+func (s FeedbagService) relayICQAuthMessage(ctx context.Context, sess *state.Session, recip state.IdentScreenName, icqMsg wire.ICBMCh4Message) {
+	s.messageRelayer.RelayToScreenName(ctx, recip, wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.ICBM,
 			SubGroup:  wire.ICBMChannelMsgToClient,
@@ -371,12 +698,56 @@ func (s FeedbagService) RespondAuthorizeToHost(ctx context.Context, sess *state.
 			TLVUserInfo: sess.TLVUserInfo(),
 			TLVRestBlock: wire.TLVRestBlock{
 				TLVList: wire.TLVList{
-					wire.NewTLVLE(wire.ICBMTLVData, response),
+					wire.NewTLVLE(wire.ICBMTLVData, icqMsg),
 					wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
 				},
 			},
 		},
 	})
+}
 
+// saveOfflineAuthMessage stores an authorization request or response for an
+// offline ICQ user as an ICQ channel ICBM, the same way that ICQ clients store
+// their own authorization messages. The message is dropped for AIM users,
+// whose clients can't render it, and when the recipient's offline message
+// storage is full.
+func (s FeedbagService) saveOfflineAuthMessage(ctx context.Context, sess *state.Session, recip state.IdentScreenName, icqMsg wire.ICBMCh4Message) error {
+	if !state.DisplayScreenName(recip.String()).IsUIN() {
+		s.logger.DebugContext(ctx, "dropped authorization message for offline AIM user",
+			"recipient", recip.String())
+		return nil
+	}
+
+	offlineMsg := state.OfflineMessage{
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			ChannelID:  wire.ICBMChannelICQ,
+			ScreenName: recip.String(),
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVLE(wire.ICBMTLVData, icqMsg),
+					wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+				},
+			},
+		},
+		Recipient: recip,
+		Sender:    sess.IdentScreenName(),
+		Sent:      s.timeNow().UTC(),
+	}
+	if err := s.offlineMessageManager.SaveMessage(offlineMsg); err != nil {
+		if errors.Is(err, state.ErrStorageQuotaExceeded) || errors.Is(err, state.ErrOfflineMessageLimit) {
+			s.logger.InfoContext(ctx, "dropped authorization message for offline user",
+				"recipient", recip.String(), "err", err.Error())
+			return nil
+		}
+		return fmt.Errorf("save offline authorization message failed: %w", err)
+	}
 	return nil
 }
+
+// icqAuthRequestText formats the reason for an authorization request as the
+// 0xFE-delimited text that ICQ clients expect. The requester's nickname, first
+// name, last name and email address are left empty so that the client shows
+// their UIN instead.
+func icqAuthRequestText(reason string) string {
+	return strings.Join([]string{"", "", "", "", "1", reason}, "\xFE")
+}
@@ -1,10 +1,13 @@
 package foodgroup
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"testing"
 	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
@@ -36,6 +39,11 @@ func TestFeedbagService_Query(t *testing.T) {
 			},
 			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
 					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
@@ -66,6 +74,11 @@ func TestFeedbagService_Query(t *testing.T) {
 			},
 			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
 					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
@@ -107,11 +120,86 @@ func TestFeedbagService_Query(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "retrieve feedbag in the order arranged by the user",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							results: []wire.FeedbagItem{
+								{Name: "buddy10", GroupID: 1, ItemID: 10, ClassID: wire.FeedbagClassIdBuddy},
+								{Name: "Family", GroupID: 2, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+									TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{20})},
+								}},
+								{Name: "buddy20", GroupID: 2, ItemID: 20, ClassID: wire.FeedbagClassIdBuddy},
+								{Name: "buddy11", GroupID: 1, ItemID: 11, ClassID: wire.FeedbagClassIdBuddy},
+								{Name: "Friends", GroupID: 1, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+									TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{11, 10})},
+								}},
+								{GroupID: 0, ItemID: 5, ClassID: wire.FeedbagClassIdPdinfo},
+								{GroupID: 0, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+									TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2, 1})},
+								}},
+							},
+						},
+					},
+					feedbagLastModifiedParams: feedbagLastModifiedParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							result:     time.UnixMilli(1696472198082),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x06_FeedbagReply{
+					Version: 0,
+					Items: []wire.FeedbagItem{
+						{GroupID: 0, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+							TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2, 1})},
+						}},
+						{GroupID: 0, ItemID: 5, ClassID: wire.FeedbagClassIdPdinfo},
+						{Name: "Family", GroupID: 2, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+							TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{20})},
+						}},
+						{Name: "buddy20", GroupID: 2, ItemID: 20, ClassID: wire.FeedbagClassIdBuddy},
+						{Name: "Friends", GroupID: 1, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+							TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{11, 10})},
+						}},
+						{Name: "buddy11", GroupID: 1, ItemID: 11, ClassID: wire.FeedbagClassIdBuddy},
+						{Name: "buddy10", GroupID: 1, ItemID: 10, ClassID: wire.FeedbagClassIdBuddy},
+					},
+					LastUpdate: uint32(time.UnixMilli(1696472198082).Unix()),
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.useFeedbagParams {
+				feedbagManager.EXPECT().
+					UseFeedbag(params.screenName).
+					Return(params.err)
+			}
 			for _, params := range tc.mockParams.feedbagParams {
 				feedbagManager.EXPECT().
 					Feedbag(params.screenName).
@@ -128,7 +216,7 @@ func TestFeedbagService_Query(t *testing.T) {
 			}
 			outputSNAC, err := svc.Query(nil, tc.userSession, tc.inputSNAC.Frame)
 			assert.NoError(t, err)
-			assert.Equal(t, tc.expectOutput, outputSNAC)
+			assert.Equal(t, []wire.SNACMessage{tc.expectOutput}, outputSNAC)
 		})
 	}
 }
@@ -160,6 +248,11 @@ func TestFeedbagService_QueryIfModified(t *testing.T) {
 			},
 			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
 					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
@@ -192,6 +285,11 @@ func TestFeedbagService_QueryIfModified(t *testing.T) {
 			},
 			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
 					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
@@ -246,6 +344,11 @@ func TestFeedbagService_QueryIfModified(t *testing.T) {
 			},
 			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
 					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
@@ -287,6 +390,11 @@ func TestFeedbagService_QueryIfModified(t *testing.T) {
 			// initialize dependencies
 			//
 			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.useFeedbagParams {
+				feedbagManager.EXPECT().
+					UseFeedbag(params.screenName).
+					Return(params.err)
+			}
 			for _, params := range tc.mockParams.feedbagParams {
 				feedbagManager.EXPECT().
 					Feedbag(params.screenName).
@@ -309,13 +417,73 @@ func TestFeedbagService_QueryIfModified(t *testing.T) {
 			//
 			// verify output
 			//
-			assert.Equal(t, tc.expectOutput, outputSNAC)
+			assert.Equal(t, []wire.SNACMessage{tc.expectOutput}, outputSNAC)
+		})
+	}
+}
+
+func TestFeedbagService_Query_LargeFeedbag(t *testing.T) {
+	var items []wire.FeedbagItem
+	var order []uint16
+	for i := uint16(1); i <= 7; i++ {
+		order = append(order, i)
+	}
+	items = append(items, wire.FeedbagItem{
+		Name:    "Friends",
+		GroupID: 1,
+		ClassID: wire.FeedbagClassIdGroup,
+		TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, order)},
+		},
+	})
+	for _, itemID := range order {
+		items = append(items, wire.FeedbagItem{
+			Name:    fmt.Sprintf("buddy%d", itemID),
+			GroupID: 1,
+			ItemID:  itemID,
+			ClassID: wire.FeedbagClassIdBuddy,
 		})
 	}
+	lastModified := time.UnixMilli(1696472198082)
+
+	feedbagManager := newMockFeedbagManager(t)
+	feedbagManager.EXPECT().
+		UseFeedbag(state.NewIdentScreenName("me")).
+		Return(nil)
+	feedbagManager.EXPECT().
+		Feedbag(state.NewIdentScreenName("me")).
+		Return(items, nil)
+	feedbagManager.EXPECT().
+		FeedbagLastModified(state.NewIdentScreenName("me")).
+		Return(lastModified, nil)
+
+	svc := NewFeedbagService(slog.Default(), nil, feedbagManager, nil, nil, nil, nil, config.Config{
+		FeedbagReplyMaxItems: 3,
+	}, nil)
+
+	replies, err := svc.Query(nil, newTestSession("me"), wire.SNACFrame{RequestID: 1234})
+	assert.NoError(t, err)
+
+	// 8 items are delivered in 3 SNACs, all but the last flagged as
+	// having more replies to follow
+	if assert.Len(t, replies, 3) {
+		var haveItems []wire.FeedbagItem
+		for i, reply := range replies {
+			assert.Equal(t, wire.FeedbagReply, reply.Frame.SubGroup)
+			assert.Equal(t, uint32(1234), reply.Frame.RequestID)
+			assert.Equal(t, i < len(replies)-1, reply.Frame.Flags&wire.SNACFlagMoreReplies != 0)
+
+			body := reply.Body.(wire.SNAC_0x13_0x06_FeedbagReply)
+			assert.LessOrEqual(t, len(body.Items), 3)
+			assert.Equal(t, uint32(lastModified.Unix()), body.LastUpdate)
+			haveItems = append(haveItems, body.Items...)
+		}
+		assert.Equal(t, items, haveItems)
+	}
 }
 
 func TestFeedbagService_RightsQuery(t *testing.T) {
-	svc := NewFeedbagService(nil, nil, nil, nil, nil, nil)
+	svc := NewFeedbagService(nil, nil, nil, nil, nil, nil, nil, config.Config{}, nil)
 
 	outputSNAC := svc.RightsQuery(nil, wire.SNACFrame{RequestID: 1234})
 	expectSNAC := wire.SNACMessage{
@@ -384,7 +552,7 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 	}{
 		{
 			name:        "add buddies",
-			userSession: newTestSession("me"),
+			userSession: newTestSession("me", sessOptSignonComplete),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
@@ -444,7 +612,7 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 			},
 		},
 		{
-			name:        "block buddies",
+			name:        "add buddies before sign-on completes, don't notify buddies",
 			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -453,11 +621,11 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIDDeny,
+							ClassID: wire.FeedbagClassIDPermit,
 							Name:    "buddy1",
 						},
 						{
-							ClassID: wire.FeedbagClassIDDeny,
+							ClassID: wire.FeedbagClassIDPermit,
 							Name:    "buddy2",
 						},
 					},
@@ -470,28 +638,17 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 							screenName: state.NewIdentScreenName("me"),
 							items: []wire.FeedbagItem{
 								{
-									ClassID: wire.FeedbagClassIDDeny,
+									ClassID: wire.FeedbagClassIDPermit,
 									Name:    "buddy1",
 								},
 								{
-									ClassID: wire.FeedbagClassIDDeny,
+									ClassID: wire.FeedbagClassIDPermit,
 									Name:    "buddy2",
 								},
 							},
 						},
 					},
 				},
-				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastVisibilityParams: broadcastVisibilityParams{
-						{
-							from: state.NewIdentScreenName("me"),
-							filter: []state.IdentScreenName{
-								state.NewIdentScreenName("buddy1"),
-								state.NewIdentScreenName("buddy2"),
-							},
-						},
-					},
-				},
 			},
 			expectOutput: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -505,7 +662,7 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 			},
 		},
 		{
-			name:        "permit buddies",
+			name:        "add buddy to shared group",
 			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -514,42 +671,40 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIDPermit,
+							ClassID: wire.FeedbagClassIdBuddy,
+							GroupID: 2,
+							ItemID:  5,
 							Name:    "buddy1",
 						},
-						{
-							ClassID: wire.FeedbagClassIDPermit,
-							Name:    "buddy2",
-						},
 					},
 				},
 			},
 			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
-					feedbagUpsertParams: feedbagUpsertParams{
+					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
-							items: []wire.FeedbagItem{
-								{
-									ClassID: wire.FeedbagClassIDPermit,
-									Name:    "buddy1",
-								},
+							results: []wire.FeedbagItem{
 								{
-									ClassID: wire.FeedbagClassIDPermit,
-									Name:    "buddy2",
+									ClassID: wire.FeedbagClassIdGroup,
+									GroupID: 2,
+									Name:    "Shared",
 								},
 							},
 						},
 					},
-				},
-				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastVisibilityParams: broadcastVisibilityParams{
+					feedbagUpsertParams: feedbagUpsertParams{
 						{
-							from: state.NewIdentScreenName("me"),
-							filter: []state.IdentScreenName{
-								state.NewIdentScreenName("buddy1"),
-								state.NewIdentScreenName("buddy2"),
+							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdBuddy,
+									GroupID: 2,
+									ItemID:  5,
+									Name:    "buddy1",
+								},
 							},
+							err: state.ErrSharedGroupReadOnly,
 						},
 					},
 				},
@@ -557,16 +712,16 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 			expectOutput: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					FoodGroup: wire.Feedbag,
-					SubGroup:  wire.FeedbagStatus,
+					SubGroup:  wire.FeedbagErr,
 					RequestID: 1234,
 				},
-				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
-					Results: []uint16{0x0000, 0x0000},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeNotSupportedByHost,
 				},
 			},
 		},
 		{
-			name:        "set privacy mode",
+			name:        "add buddy with its new group",
 			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -575,7 +730,15 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIdPdinfo,
+							ClassID: wire.FeedbagClassIdGroup,
+							GroupID: 3,
+							Name:    "Friends",
+						},
+						{
+							ClassID: wire.FeedbagClassIdBuddy,
+							GroupID: 3,
+							ItemID:  5,
+							Name:    "buddy1",
 						},
 					},
 				},
@@ -587,20 +750,20 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 							screenName: state.NewIdentScreenName("me"),
 							items: []wire.FeedbagItem{
 								{
-									ClassID: wire.FeedbagClassIdPdinfo,
+									ClassID: wire.FeedbagClassIdGroup,
+									GroupID: 3,
+									Name:    "Friends",
+								},
+								{
+									ClassID: wire.FeedbagClassIdBuddy,
+									GroupID: 3,
+									ItemID:  5,
+									Name:    "buddy1",
 								},
 							},
 						},
 					},
 				},
-				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastVisibilityParams: broadcastVisibilityParams{
-						{
-							from:   state.NewIdentScreenName("me"),
-							filter: nil,
-						},
-					},
-				},
 			},
 			expectOutput: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -609,12 +772,12 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 					RequestID: 1234,
 				},
 				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
-					Results: []uint16{0x0000},
+					Results: []uint16{0x0000, 0x0000},
 				},
 			},
 		},
 		{
-			name:        "user blocks themselves, receives error",
+			name:        "add buddy without a group, receives error",
 			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -623,8 +786,9 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIDDeny,
-							Name:    "me",
+							ClassID: wire.FeedbagClassIdBuddy,
+							ItemID:  5,
+							Name:    "buddy1",
 						},
 					},
 				},
@@ -636,12 +800,12 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 					RequestID: 1234,
 				},
 				Body: wire.SNACError{
-					Code: wire.ErrorCodeNotSupportedByHost,
+					Code: wire.ErrorCodeInvalidSnac,
 				},
 			},
 		},
 		{
-			name:        "add icon hash to feedbag, icon doesn't exist in BART store, instruct client to upload icon",
+			name:        "add buddy to nonexistent group, receives error",
 			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -650,63 +814,24 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIdBart,
-							TLVLBlock: wire.TLVLBlock{
-								TLVList: wire.TLVList{
-									wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
-										Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-									}),
-								},
-							},
+							ClassID: wire.FeedbagClassIdBuddy,
+							GroupID: 7,
+							ItemID:  5,
+							Name:    "buddy1",
 						},
 					},
 				},
 			},
 			mockParams: mockParams{
-				bartManagerParams: bartManagerParams{
-					bartManagerRetrieveParams: bartManagerRetrieveParams{
-						{
-							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-							result:   []byte{}, // icon doesn't exist
-						},
-					},
-				},
 				feedbagManagerParams: feedbagManagerParams{
-					feedbagUpsertParams: feedbagUpsertParams{
+					feedbagParams: feedbagParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
-							items: []wire.FeedbagItem{
+							results: []wire.FeedbagItem{
 								{
-									ClassID: wire.FeedbagClassIdBart,
-									TLVLBlock: wire.TLVLBlock{
-										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
-												Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-											}),
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				messageRelayerParams: messageRelayerParams{
-					relayToScreenNameParams: relayToScreenNameParams{
-						{
-							screenName: state.NewIdentScreenName("me"),
-							message: wire.SNACMessage{
-								Frame: wire.SNACFrame{
-									FoodGroup: wire.OService,
-									SubGroup:  wire.OServiceBartReply,
-								},
-								Body: wire.SNAC_0x01_0x21_OServiceBARTReply{
-									BARTID: wire.BARTID{
-										Type: wire.BARTTypesBuddyIcon,
-										BARTInfo: wire.BARTInfo{
-											Flags: wire.BARTFlagsCustom | wire.BARTFlagsUnknown,
-											Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-										},
-									},
+									ClassID: wire.FeedbagClassIdGroup,
+									GroupID: 2,
+									Name:    "Buddies",
 								},
 							},
 						},
@@ -716,17 +841,17 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 			expectOutput: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					FoodGroup: wire.Feedbag,
-					SubGroup:  wire.FeedbagStatus,
+					SubGroup:  wire.FeedbagErr,
 					RequestID: 1234,
 				},
-				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
-					Results: []uint16{0x0000},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeInvalidSnac,
 				},
 			},
 		},
 		{
-			name:        "add icon hash to feedbag, icon already exists in BART store, notify buddies about icon change",
-			userSession: newTestSession("me"),
+			name:        "block buddies",
+			userSession: newTestSession("me", sessOptSignonComplete),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
@@ -734,74 +859,151 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIdBart,
-							TLVLBlock: wire.TLVLBlock{
-								TLVList: wire.TLVList{
-									wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
-										Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-									}),
-								},
-							},
+							ClassID: wire.FeedbagClassIDDeny,
+							Name:    "buddy1",
 						},
-					},
-				},
-			},
-			mockParams: mockParams{
-				bartManagerParams: bartManagerParams{
-					bartManagerRetrieveParams: bartManagerRetrieveParams{
 						{
-							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-							result:   []byte{'i', 'c', 'o', 'n', 'd', 'a', 't', 'a'},
+							ClassID: wire.FeedbagClassIDDeny,
+							Name:    "buddy2",
 						},
 					},
 				},
+			},
+			mockParams: mockParams{
 				feedbagManagerParams: feedbagManagerParams{
 					feedbagUpsertParams: feedbagUpsertParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
 							items: []wire.FeedbagItem{
 								{
-									ClassID: wire.FeedbagClassIdBart,
-									TLVLBlock: wire.TLVLBlock{
-										TLVList: wire.TLVList{
-											wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
-												Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-											}),
-										},
-									},
+									ClassID: wire.FeedbagClassIDDeny,
+									Name:    "buddy1",
+								},
+								{
+									ClassID: wire.FeedbagClassIDDeny,
+									Name:    "buddy2",
 								},
 							},
 						},
 					},
-					adjacentUsersParams: adjacentUsersParams{},
-					feedbagParams:       feedbagParams{},
 				},
-				messageRelayerParams: messageRelayerParams{
-					relayToScreenNameParams: relayToScreenNameParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from: state.NewIdentScreenName("me"),
+							filter: []state.IdentScreenName{
+								state.NewIdentScreenName("buddy1"),
+								state.NewIdentScreenName("buddy2"),
+							},
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000, 0x0000},
+				},
+			},
+		},
+		{
+			name:        "permit buddies",
+			userSession: newTestSession("me", sessOptSignonComplete),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIDPermit,
+							Name:    "buddy1",
+						},
+						{
+							ClassID: wire.FeedbagClassIDPermit,
+							Name:    "buddy2",
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagUpsertParams: feedbagUpsertParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
-							message: wire.SNACMessage{
-								Frame: wire.SNACFrame{
-									FoodGroup: wire.OService,
-									SubGroup:  wire.OServiceBartReply,
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIDPermit,
+									Name:    "buddy1",
 								},
-								Body: wire.SNAC_0x01_0x21_OServiceBARTReply{
-									BARTID: wire.BARTID{
-										Type: wire.BARTTypesBuddyIcon,
-										BARTInfo: wire.BARTInfo{
-											Flags: wire.BARTFlagsCustom,
-											Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-										},
-									},
+								{
+									ClassID: wire.FeedbagClassIDPermit,
+									Name:    "buddy2",
 								},
 							},
 						},
 					},
 				},
 				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from: state.NewIdentScreenName("me"),
+							filter: []state.IdentScreenName{
+								state.NewIdentScreenName("buddy1"),
+								state.NewIdentScreenName("buddy2"),
+							},
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000, 0x0000},
+				},
+			},
+		},
+		{
+			name:        "set privacy mode",
+			userSession: newTestSession("me", sessOptSignonComplete),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIdPdinfo,
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagUpsertParams: feedbagUpsertParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdPdinfo,
+								},
+							},
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:   state.NewIdentScreenName("me"),
+							filter: nil,
 						},
 					},
 				},
@@ -818,7 +1020,34 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 			},
 		},
 		{
-			name:        "clear icon, notify buddies about icon change",
+			name:        "user blocks themselves, receives error",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIDDeny,
+							Name:    "me",
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeNotSupportedByHost,
+				},
+			},
+		},
+		{
+			name:        "add icon hash to feedbag, icon doesn't exist in BART store, instruct client to upload icon",
 			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -831,7 +1060,7 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 							TLVLBlock: wire.TLVLBlock{
 								TLVList: wire.TLVList{
 									wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
-										Hash: wire.GetClearIconHash(),
+										Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
 									}),
 								},
 							},
@@ -840,6 +1069,14 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				},
 			},
 			mockParams: mockParams{
+				bartManagerParams: bartManagerParams{
+					bartManagerRetrieveParams: bartManagerRetrieveParams{
+						{
+							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+							result:   []byte{}, // icon doesn't exist
+						},
+					},
+				},
 				feedbagManagerParams: feedbagManagerParams{
 					feedbagUpsertParams: feedbagUpsertParams{
 						{
@@ -850,7 +1087,7 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 									TLVLBlock: wire.TLVLBlock{
 										TLVList: wire.TLVList{
 											wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
-												Hash: wire.GetClearIconHash(),
+												Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
 											}),
 										},
 									},
@@ -859,10 +1096,25 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 						},
 					},
 				},
-				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
 						{
 							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.OService,
+									SubGroup:  wire.OServiceBartReply,
+								},
+								Body: wire.SNAC_0x01_0x21_OServiceBARTReply{
+									BARTID: wire.BARTID{
+										Type: wire.BARTTypesBuddyIcon,
+										BARTInfo: wire.BARTInfo{
+											Flags: wire.BARTFlagsCustom | wire.BARTFlagsUnknown,
+											Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
@@ -878,82 +1130,474 @@ func TestFeedbagService_UpsertItem(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			feedbagManager := newMockFeedbagManager(t)
-			for _, params := range tc.mockParams.feedbagManagerParams.feedbagUpsertParams {
-				feedbagManager.EXPECT().
-					FeedbagUpsert(params.screenName, params.items).
-					Return(nil)
-			}
-			messageRelayer := newMockMessageRelayer(t)
-			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
-				messageRelayer.EXPECT().
-					RelayToScreenName(mock.Anything, params.screenName, params.message)
-			}
-			bartManager := newMockBARTManager(t)
-			for _, params := range tc.mockParams.bartManagerParams.bartManagerRetrieveParams {
-				bartManager.EXPECT().
-					BARTRetrieve(params.itemHash).
-					Return(params.result, nil)
-			}
-			buddyUpdateBroadcaster := newMockbuddyBroadcaster(t)
-			for _, params := range tc.mockParams.broadcastBuddyArrivedParams {
-				buddyUpdateBroadcaster.EXPECT().
-					BroadcastBuddyArrived(mock.Anything, matchSession(params.screenName)).
-					Return(params.err)
-			}
-			for _, params := range tc.mockParams.broadcastVisibilityParams {
-				buddyUpdateBroadcaster.EXPECT().
-					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, true).
-					Return(params.err)
-			}
-			svc := NewFeedbagService(slog.Default(), messageRelayer, feedbagManager, bartManager, nil, nil)
-			svc.buddyBroadcaster = buddyUpdateBroadcaster
-			output, err := svc.UpsertItem(nil, tc.userSession, tc.inputSNAC.Frame,
-				tc.inputSNAC.Body.(wire.SNAC_0x13_0x08_FeedbagInsertItem).Items)
-			assert.NoError(t, err)
-			assert.Equal(t, output, tc.expectOutput)
-		})
-	}
-}
-
-func TestFeedbagService_DeleteItem(t *testing.T) {
-	cases := []struct {
-		// name is the unit test name
-		name string
-		// userSession is the session of the user adding to feedbag
-		userSession *state.Session
-		// inputSNAC is the SNAC sent from the client to the server
-		inputSNAC wire.SNACMessage
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-		// expectOutput is the SNAC sent from the server to client
-		expectOutput wire.SNACMessage
-	}{
 		{
-			name:        "delete buddies",
-			userSession: newTestSession("me"),
+			name:        "add icon hash to feedbag, icon already exists in BART store, notify buddies about icon change",
+			userSession: newTestSession("me", sessOptSignonComplete),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
 				},
-				Body: wire.SNAC_0x13_0x0A_FeedbagDeleteItem{
+				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
 					Items: []wire.FeedbagItem{
 						{
-							ClassID: wire.FeedbagClassIdBuddy,
-							Name:    "buddy1",
-						},
-						{
+							ClassID: wire.FeedbagClassIdBart,
+							TLVLBlock: wire.TLVLBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+										Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+									}),
+								},
+							},
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				bartManagerParams: bartManagerParams{
+					bartManagerRetrieveParams: bartManagerRetrieveParams{
+						{
+							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+							result:   []byte{'i', 'c', 'o', 'n', 'd', 'a', 't', 'a'},
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdBart,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+												Hash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+					adjacentUsersParams: adjacentUsersParams{},
+					feedbagParams:       feedbagParams{},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.OService,
+									SubGroup:  wire.OServiceBartReply,
+								},
+								Body: wire.SNAC_0x01_0x21_OServiceBARTReply{
+									BARTID: wire.BARTID{
+										Type: wire.BARTTypesBuddyIcon,
+										BARTInfo: wire.BARTInfo{
+											Flags: wire.BARTFlagsCustom,
+											Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000},
+				},
+			},
+		},
+		{
+			name:        "clear icon, notify buddies about icon change",
+			userSession: newTestSession("me", sessOptSignonComplete),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIdBart,
+							TLVLBlock: wire.TLVLBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+										Hash: wire.GetClearIconHash(),
+									}),
+								},
+							},
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdBart,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+												Hash: wire.GetClearIconHash(),
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000},
+				},
+			},
+		},
+		{
+			name:        "clear icon before sign-on completes, don't notify buddies",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIdBart,
+							TLVLBlock: wire.TLVLBlock{
+								TLVList: wire.TLVList{
+									wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+										Hash: wire.GetClearIconHash(),
+									}),
+								},
+							},
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdBart,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+												Hash: wire.GetClearIconHash(),
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagParams {
+				feedbagManager.EXPECT().
+					Feedbag(params.screenName).
+					Return(params.results, nil)
+			}
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagUpsertParams {
+				feedbagManager.EXPECT().
+					FeedbagUpsert(params.screenName, params.items).
+					Return(params.err)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+			bartManager := newMockBARTManager(t)
+			for _, params := range tc.mockParams.bartManagerParams.bartManagerRetrieveParams {
+				bartManager.EXPECT().
+					BARTRetrieve(params.itemHash).
+					Return(params.result, nil)
+			}
+			buddyUpdateBroadcaster := newMockbuddyBroadcaster(t)
+			for _, params := range tc.mockParams.broadcastBuddyArrivedParams {
+				buddyUpdateBroadcaster.EXPECT().
+					BroadcastBuddyArrived(mock.Anything, matchSession(params.screenName)).
+					Return(params.err)
+			}
+			for _, params := range tc.mockParams.broadcastVisibilityParams {
+				buddyUpdateBroadcaster.EXPECT().
+					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, true).
+					Return(params.err)
+			}
+			svc := NewFeedbagService(slog.Default(), messageRelayer, feedbagManager, bartManager, nil, nil, nil, config.Config{}, newNoAliasScreenNameResolver(t))
+			svc.buddyBroadcaster = buddyUpdateBroadcaster
+			output, err := svc.UpsertItem(nil, tc.userSession, tc.inputSNAC.Frame,
+				tc.inputSNAC.Body.(wire.SNAC_0x13_0x08_FeedbagInsertItem).Items)
+			assert.NoError(t, err)
+			assert.Equal(t, output, tc.expectOutput)
+		})
+	}
+}
+
+func TestFeedbagService_UpsertItem_Alias(t *testing.T) {
+	sess := newTestSession("me", sessOptSignonComplete)
+
+	screenNameResolver := newMockScreenNameResolver(t)
+	screenNameResolver.EXPECT().
+		CanonicalScreenName(state.NewIdentScreenName("The Alias")).
+		Return(state.NewIdentScreenName("Canonical User"), nil)
+
+	// the buddy is stored and watched as the account the alias belongs to
+	feedbagManager := newMockFeedbagManager(t)
+	feedbagManager.EXPECT().
+		Feedbag(sess.IdentScreenName()).
+		Return([]wire.FeedbagItem{
+			{
+				ClassID: wire.FeedbagClassIdGroup,
+				GroupID: 1,
+				Name:    "Buddies",
+			},
+		}, nil)
+	feedbagManager.EXPECT().
+		FeedbagUpsert(sess.IdentScreenName(), []wire.FeedbagItem{
+			{
+				ClassID: wire.FeedbagClassIdBuddy,
+				GroupID: 1,
+				Name:    "canonicaluser",
+			},
+		}).
+		Return(nil)
+	buddyBroadcaster := newMockbuddyBroadcaster(t)
+	buddyBroadcaster.EXPECT().
+		BroadcastVisibility(mock.Anything, matchSession(sess.IdentScreenName()),
+			[]state.IdentScreenName{state.NewIdentScreenName("Canonical User")}, true).
+		Return(nil)
+
+	svc := NewFeedbagService(slog.Default(), nil, feedbagManager, nil, nil, nil, nil, config.Config{}, screenNameResolver)
+	svc.buddyBroadcaster = buddyBroadcaster
+
+	items := []wire.FeedbagItem{
+		{
+			ClassID: wire.FeedbagClassIdBuddy,
+			GroupID: 1,
+			Name:    "The Alias",
+		},
+	}
+	output, err := svc.UpsertItem(nil, sess, wire.SNACFrame{RequestID: 1234}, items)
+	assert.NoError(t, err)
+	assert.Equal(t, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagStatus,
+			RequestID: 1234,
+		},
+		Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+			Results: []uint16{0x0000},
+		},
+	}, output)
+	// the client's items are left alone
+	assert.Equal(t, "The Alias", items[0].Name)
+}
+
+func TestFeedbagService_DeleteItem(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// userSession is the session of the user adding to feedbag
+		userSession *state.Session
+		// inputSNAC is the SNAC sent from the client to the server
+		inputSNAC wire.SNACMessage
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+		// expectOutput is the SNAC sent from the server to client
+		expectOutput wire.SNACMessage
+	}{
+		{
+			name:        "delete buddies",
+			userSession: newTestSession("me", sessOptSignonComplete),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0A_FeedbagDeleteItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIdBuddy,
+							Name:    "buddy1",
+						},
+						{
 							ClassID: wire.FeedbagClassIdBuddy,
 							Name:    "buddy2",
 						},
 						{
-							ClassID: wire.FeedbagClassIdGroup,
-							Name:    "group",
+							ClassID: wire.FeedbagClassIdGroup,
+							Name:    "group",
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagDeleteParams: feedbagDeleteParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdBuddy,
+									Name:    "buddy1",
+								},
+								{
+									ClassID: wire.FeedbagClassIdBuddy,
+									Name:    "buddy2",
+								},
+								{
+									ClassID: wire.FeedbagClassIdGroup,
+									Name:    "group",
+								},
+							},
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from: state.NewIdentScreenName("me"),
+							filter: []state.IdentScreenName{
+								state.NewIdentScreenName("buddy1"),
+								state.NewIdentScreenName("buddy2"),
+							},
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000, 0x0000, 0x0000},
+				},
+			},
+		},
+		{
+			name:        "delete buddies before sign-on completes, don't notify buddies",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0A_FeedbagDeleteItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIdBuddy,
+							Name:    "buddy1",
+						},
+						{
+							ClassID: wire.FeedbagClassIdBuddy,
+							Name:    "buddy2",
+						},
+						{
+							ClassID: wire.FeedbagClassIdGroup,
+							Name:    "group",
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagDeleteParams: feedbagDeleteParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdBuddy,
+									Name:    "buddy1",
+								},
+								{
+									ClassID: wire.FeedbagClassIdBuddy,
+									Name:    "buddy2",
+								},
+								{
+									ClassID: wire.FeedbagClassIdGroup,
+									Name:    "group",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagStatus,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
+					Results: []uint16{0x0000, 0x0000, 0x0000},
+				},
+			},
+		},
+		{
+			name:        "delete buddy from shared group",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x13_0x0A_FeedbagDeleteItem{
+					Items: []wire.FeedbagItem{
+						{
+							ClassID: wire.FeedbagClassIdBuddy,
+							Name:    "buddy1",
 						},
 					},
 				},
@@ -968,98 +1612,432 @@ func TestFeedbagService_DeleteItem(t *testing.T) {
 									ClassID: wire.FeedbagClassIdBuddy,
 									Name:    "buddy1",
 								},
-								{
-									ClassID: wire.FeedbagClassIdBuddy,
-									Name:    "buddy2",
+							},
+							err: state.ErrSharedGroupReadOnly,
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Feedbag,
+					SubGroup:  wire.FeedbagErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeNotSupportedByHost,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagDeleteParams {
+				feedbagManager.EXPECT().
+					FeedbagDelete(params.screenName, params.items).
+					Return(params.err)
+			}
+			buddyUpdateBroadcast := newMockbuddyBroadcaster(t)
+			for _, params := range tc.mockParams.broadcastVisibilityParams {
+				buddyUpdateBroadcast.EXPECT().
+					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, true).
+					Return(params.err)
+			}
+
+			svc := FeedbagService{
+				buddyBroadcaster:   buddyUpdateBroadcast,
+				feedbagManager:     feedbagManager,
+				messageRelayer:     nil,
+				screenNameResolver: newNoAliasScreenNameResolver(t),
+			}
+			output, err := svc.DeleteItem(nil, tc.userSession, tc.inputSNAC.Frame,
+				tc.inputSNAC.Body.(wire.SNAC_0x13_0x0A_FeedbagDeleteItem))
+			assert.NoError(t, err)
+			assert.Equal(t, output, tc.expectOutput)
+		})
+	}
+}
+
+func TestFeedbagService_Use(t *testing.T) {
+	tests := []struct {
+		// name is the name of the test
+		name string
+		// joiningChatter is the session of the arriving user
+		sess *state.Session
+		// bodyIn is the SNAC body sent from the arriving user's client to the
+		// server
+		bodyIn wire.SNAC_0x01_0x02_OServiceClientOnline
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+		wantErr    error
+	}{
+		{
+			name:   "notify arriving user's buddies of its arrival and populate the arriving user's buddy list",
+			sess:   newTestSession("me"),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				feedbagManagerParams: feedbagManagerParams{
+					useFeedbagParams: useFeedbagParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "feedbag was already activated by FeedbagQuery",
+			sess:   newTestSession("me", sessOptFeedbagInUse),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tt.mockParams.useFeedbagParams {
+				feedbagManager.EXPECT().
+					UseFeedbag(params.screenName).
+					Return(params.err)
+			}
+
+			svc := NewFeedbagService(slog.Default(), nil, feedbagManager, nil, nil, nil, nil, config.Config{}, nil)
+
+			haveErr := svc.Use(nil, tt.sess)
+			assert.ErrorIs(t, tt.wantErr, haveErr)
+		})
+	}
+}
+func TestFeedbagService_UseAndQueryOrdering(t *testing.T) {
+	items := []wire.FeedbagItem{
+		{
+			Name: "buddy1",
+		},
+	}
+	lastModified := time.UnixMilli(1696472198082)
+
+	wantReply := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagReply,
+			RequestID: 1234,
+		},
+		Body: wire.SNAC_0x13_0x06_FeedbagReply{
+			Items:      items,
+			LastUpdate: uint32(lastModified.Unix()),
+		},
+	}
+
+	tests := []struct {
+		name string
+		// useFirst indicates whether FeedbagUse is sent before FeedbagQuery
+		useFirst bool
+	}{
+		{
+			name:     "FeedbagQuery then FeedbagUse",
+			useFirst: false,
+		},
+		{
+			name:     "FeedbagUse then FeedbagQuery",
+			useFirst: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feedbagManager := newMockFeedbagManager(t)
+			feedbagManager.EXPECT().
+				UseFeedbag(state.NewIdentScreenName("me")).
+				Return(nil).
+				Once()
+			feedbagManager.EXPECT().
+				Feedbag(state.NewIdentScreenName("me")).
+				Return(items, nil)
+			feedbagManager.EXPECT().
+				FeedbagLastModified(state.NewIdentScreenName("me")).
+				Return(lastModified, nil)
+
+			svc := NewFeedbagService(slog.Default(), nil, feedbagManager, nil, nil, nil, nil, config.Config{}, nil)
+			sess := newTestSession("me")
+
+			if tt.useFirst {
+				assert.NoError(t, svc.Use(nil, sess))
+			}
+			haveReply, err := svc.Query(nil, sess, wire.SNACFrame{RequestID: 1234})
+			assert.NoError(t, err)
+			if !tt.useFirst {
+				assert.NoError(t, svc.Use(nil, sess))
+			}
+
+			assert.Equal(t, []wire.SNACMessage{wantReply}, haveReply)
+			assert.True(t, sess.FeedbagInUse())
+		})
+	}
+}
+
+func TestFeedbagService_RequestAuthorizeToHost(t *testing.T) {
+	sentAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	authReq := wire.ICBMCh4Message{
+		UIN:         100001,
+		MessageType: wire.ICBMMsgTypeAuthReq,
+		Message:     "\xFE\xFE\xFE\xFE1\xFEplease add me",
+	}
+
+	tests := []struct {
+		name       string
+		sess       *state.Session
+		bodyIn     wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost
+		mockParams mockParams
+		wantErr    error
+	}{
+		{
+			name: "recipient uses feedbag",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "100003",
+				Reason:     "please add me",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     newTestSession("100003", sessOptFeedbagInUse),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagRequestAuthorizeToClient,
 								},
-								{
-									ClassID: wire.FeedbagClassIdGroup,
-									Name:    "group",
+								Body: wire.SNAC_0x13_0x19_FeedbagRequestAuthorizeToClient{
+									ScreenName: "100001",
+									Reason:     "please add me",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "recipient doesn't use feedbag",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "100003",
+				Reason:     "please add me",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     newTestSession("100003"),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									ChannelID:   wire.ICBMChannelICQ,
+									TLVUserInfo: newTestSession("100001").TLVUserInfo(),
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVLE(wire.ICBMTLVData, authReq),
+											wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "recipient is offline",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "100003",
+				Reason:     "please add me",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     nil,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessageIn: state.OfflineMessage{
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:  wire.ICBMChannelICQ,
+									ScreenName: "100003",
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVLE(wire.ICBMTLVData, authReq),
+											wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+										},
+									},
+								},
+								Recipient: state.NewIdentScreenName("100003"),
+								Sender:    state.NewIdentScreenName("100001"),
+								Sent:      sentAt,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "recipient is offline with full offline message storage",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "100003",
+				Reason:     "please add me",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     nil,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessageIn: state.OfflineMessage{
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:  wire.ICBMChannelICQ,
+									ScreenName: "100003",
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVLE(wire.ICBMTLVData, authReq),
+											wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+										},
+									},
 								},
+								Recipient: state.NewIdentScreenName("100003"),
+								Sender:    state.NewIdentScreenName("100001"),
+								Sent:      sentAt,
 							},
+							err: state.ErrOfflineMessageLimit,
 						},
 					},
 				},
-				buddyBroadcasterParams: buddyBroadcasterParams{
-					broadcastVisibilityParams: broadcastVisibilityParams{
+			},
+		},
+		{
+			name: "recipient blocks requester, drop request",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "100003",
+				Reason:     "please add me",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
 						{
-							from: state.NewIdentScreenName("me"),
-							filter: []state.IdentScreenName{
-								state.NewIdentScreenName("buddy1"),
-								state.NewIdentScreenName("buddy2"),
-							},
+							me:     state.NewIdentScreenName("100001"),
+							them:   state.NewIdentScreenName("100003"),
+							result: state.Relationship{User: state.NewIdentScreenName("100003"), BlocksYou: true},
 						},
 					},
 				},
 			},
-			expectOutput: wire.SNACMessage{
-				Frame: wire.SNACFrame{
-					FoodGroup: wire.Feedbag,
-					SubGroup:  wire.FeedbagStatus,
-					RequestID: 1234,
-				},
-				Body: wire.SNAC_0x13_0x0E_FeedbagStatus{
-					Results: []uint16{0x0000, 0x0000, 0x0000},
+		},
+		{
+			name: "requester blocks recipient, drop request",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "100003",
+				Reason:     "please add me",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:     state.NewIdentScreenName("100001"),
+							them:   state.NewIdentScreenName("100003"),
+							result: state.Relationship{User: state.NewIdentScreenName("100003"), YouBlock: true},
+						},
+					},
 				},
 			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			feedbagManager := newMockFeedbagManager(t)
-			for _, params := range tc.mockParams.feedbagManagerParams.feedbagDeleteParams {
-				feedbagManager.EXPECT().
-					FeedbagDelete(params.screenName, params.items).
-					Return(nil)
-			}
-			buddyUpdateBroadcast := newMockbuddyBroadcaster(t)
-			for _, params := range tc.mockParams.broadcastVisibilityParams {
-				buddyUpdateBroadcast.EXPECT().
-					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, true).
-					Return(params.err)
-			}
-
-			svc := FeedbagService{
-				buddyBroadcaster: buddyUpdateBroadcast,
-				feedbagManager:   feedbagManager,
-				messageRelayer:   nil,
-			}
-			output, err := svc.DeleteItem(nil, tc.userSession, tc.inputSNAC.Frame,
-				tc.inputSNAC.Body.(wire.SNAC_0x13_0x0A_FeedbagDeleteItem))
-			assert.NoError(t, err)
-			assert.Equal(t, output, tc.expectOutput)
-		})
-	}
-}
-
-func TestFeedbagService_Use(t *testing.T) {
-	tests := []struct {
-		// name is the name of the test
-		name string
-		// joiningChatter is the session of the arriving user
-		sess *state.Session
-		// bodyIn is the SNAC body sent from the arriving user's client to the
-		// server
-		bodyIn wire.SNAC_0x01_0x02_OServiceClientOnline
-		// mockParams is the list of params sent to mocks that satisfy this
-		// method's dependencies
-		mockParams mockParams
-		wantErr    error
-	}{
 		{
-			name:   "notify arriving user's buddies of its arrival and populate the arriving user's buddy list",
-			sess:   newTestSession("me"),
-			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			name: "AIM recipient is offline, drop request",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+				ScreenName: "aimuser",
+				Reason:     "please add me",
+			},
 			mockParams: mockParams{
-				feedbagManagerParams: feedbagManagerParams{
-					buddiesParams: buddiesParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
 						{
-							screenName: state.NewIdentScreenName("me"),
-							results: []state.IdentScreenName{
-								state.NewIdentScreenName("buddy1"),
-								state.NewIdentScreenName("buddy3"),
-							},
+							me:     state.NewIdentScreenName("100001"),
+							them:   state.NewIdentScreenName("aimuser"),
+							result: state.Relationship{User: state.NewIdentScreenName("aimuser")},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("aimuser"),
+							result:     nil,
 						},
 					},
 				},
@@ -1068,21 +2046,41 @@ func TestFeedbagService_Use(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			feedbagManager := newMockFeedbagManager(t)
-			for _, params := range tt.mockParams.buddiesParams {
-				feedbagManager.EXPECT().
-					UseFeedbag(params.screenName).
-					Return(nil)
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			for _, params := range tt.mockParams.relationshipParams {
+				buddyListRetriever.EXPECT().
+					Relationship(params.me, params.them).
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tt.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tt.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(nil, params.screenName, params.message)
+			}
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tt.mockParams.saveMessageParams {
+				offlineMessageManager.EXPECT().
+					SaveMessage(params.offlineMessageIn).
+					Return(params.err)
 			}
 
-			svc := NewFeedbagService(slog.Default(), nil, feedbagManager, nil, nil, nil)
-
-			haveErr := svc.Use(nil, tt.sess)
+			svc := NewFeedbagService(slog.Default(), messageRelayer, nil, nil, buddyListRetriever, sessionRetriever, offlineMessageManager, config.Config{}, nil)
+			svc.timeNow = func() time.Time { return sentAt }
+			haveErr := svc.RequestAuthorizeToHost(nil, tt.sess, wire.SNACFrame{}, tt.bodyIn)
 			assert.ErrorIs(t, tt.wantErr, haveErr)
 		})
 	}
 }
+
 func TestFeedbagService_RespondAuthorizeToHost(t *testing.T) {
+	sentAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name       string
 		sess       *state.Session
@@ -1098,6 +2096,22 @@ func TestFeedbagService_RespondAuthorizeToHost(t *testing.T) {
 				Accepted:   1,
 			},
 			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     newTestSession("100003"),
+						},
+					},
+				},
 				messageRelayerParams: messageRelayerParams{
 					relayToScreenNameParams: relayToScreenNameParams{
 						{
@@ -1135,6 +2149,22 @@ func TestFeedbagService_RespondAuthorizeToHost(t *testing.T) {
 				Reason:     "I don't know you!",
 			},
 			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     newTestSession("100003"),
+						},
+					},
+				},
 				messageRelayerParams: messageRelayerParams{
 					relayToScreenNameParams: relayToScreenNameParams{
 						{
@@ -1164,18 +2194,281 @@ func TestFeedbagService_RespondAuthorizeToHost(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "authorization accepted, requester uses feedbag",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{
+				ScreenName: "100003",
+				Accepted:   1,
+				Reason:     "welcome",
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     newTestSession("100003", sessOptFeedbagInUse),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagRespondAuthorizeToClient,
+								},
+								Body: wire.SNAC_0x13_0x1B_FeedbagRespondAuthorizeToClient{
+									ScreenName: "100001",
+									Accepted:   1,
+									Reason:     "welcome",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "authorization accepted, requester is offline",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{
+				ScreenName: "100003",
+				Accepted:   1,
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("100001"),
+							them: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     nil,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessageIn: state.OfflineMessage{
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:  wire.ICBMChannelICQ,
+									ScreenName: "100003",
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVLE(wire.ICBMTLVData, wire.ICBMCh4Message{
+												UIN:         100001,
+												MessageType: wire.ICBMMsgTypeAuthOK,
+											}),
+											wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+										},
+									},
+								},
+								Recipient: state.NewIdentScreenName("100003"),
+								Sender:    state.NewIdentScreenName("100001"),
+								Sent:      sentAt,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid accepted flag",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{
+				ScreenName: "100003",
+				Accepted:   2,
+			},
+			wantErr: errors.New("invalid accepted flag 2"),
+		},
+		{
+			name: "requester blocks responder, drop response",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{
+				ScreenName: "100003",
+				Accepted:   1,
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:     state.NewIdentScreenName("100001"),
+							them:   state.NewIdentScreenName("100003"),
+							result: state.Relationship{User: state.NewIdentScreenName("100003"), BlocksYou: true},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "AIM requester is offline, drop response",
+			sess: newTestSession("100001", sessOptUIN(100001)),
+			bodyIn: wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{
+				ScreenName: "aimuser",
+				Accepted:   1,
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:     state.NewIdentScreenName("100001"),
+							them:   state.NewIdentScreenName("aimuser"),
+							result: state.Relationship{User: state.NewIdentScreenName("aimuser")},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("aimuser"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			for _, params := range tt.mockParams.relationshipParams {
+				buddyListRetriever.EXPECT().
+					Relationship(params.me, params.them).
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tt.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
 			messageRelayer := newMockMessageRelayer(t)
 			for _, params := range tt.mockParams.relayToScreenNameParams {
 				messageRelayer.EXPECT().
 					RelayToScreenName(nil, params.screenName, params.message)
 			}
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tt.mockParams.saveMessageParams {
+				offlineMessageManager.EXPECT().
+					SaveMessage(params.offlineMessageIn).
+					Return(params.err)
+			}
 
-			svc := NewFeedbagService(slog.Default(), messageRelayer, nil, nil, nil, nil)
+			svc := NewFeedbagService(slog.Default(), messageRelayer, nil, nil, buddyListRetriever, sessionRetriever, offlineMessageManager, config.Config{}, nil)
+			svc.timeNow = func() time.Time { return sentAt }
 			haveErr := svc.RespondAuthorizeToHost(nil, tt.sess, wire.SNACFrame{}, tt.bodyIn)
-			assert.ErrorIs(t, tt.wantErr, haveErr)
+			if tt.wantErr != nil {
+				assert.EqualError(t, haveErr, tt.wantErr.Error())
+				return
+			}
+			assert.NoError(t, haveErr)
+		})
+	}
+}
+
+func TestFeedbagService_StartCluster(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rejected := &wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagErr,
+			RequestID: 1234,
+		},
+		Body: wire.SNACError{
+			Code: wire.ErrorCodeRequestDenied,
+		},
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// openedAt is when the session's current cluster was opened, or the
+		// zero time if no cluster is open
+		openedAt time.Time
+		// now is the time at which the new cluster is started
+		now time.Time
+		// expectOutput is the SNAC sent from the server to the client
+		expectOutput *wire.SNACMessage
+		// expectClusterStart is when the session's cluster was opened after
+		// the call
+		expectClusterStart time.Time
+	}{
+		{
+			name:               "start a cluster",
+			cfg:                config.Config{FeedbagClusterTimeoutSec: 30},
+			now:                start,
+			expectClusterStart: start,
+		},
+		{
+			name:               "reject a nested cluster",
+			cfg:                config.Config{FeedbagClusterTimeoutSec: 30},
+			openedAt:           start,
+			now:                start.Add(10 * time.Second),
+			expectOutput:       rejected,
+			expectClusterStart: start,
+		},
+		{
+			name:               "finalize an unterminated cluster after the timeout",
+			cfg:                config.Config{FeedbagClusterTimeoutSec: 30},
+			openedAt:           start,
+			now:                start.Add(30 * time.Second),
+			expectClusterStart: start.Add(30 * time.Second),
+		},
+		{
+			name:     "cluster tracking disabled",
+			cfg:      config.Config{},
+			openedAt: start,
+			now:      start.Add(10 * time.Second),
+			// the open cluster is left alone
+			expectClusterStart: start,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sess := newTestSession("me")
+			if !tc.openedAt.IsZero() {
+				sess.StartFeedbagCluster(tc.openedAt)
+			}
+
+			svc := NewFeedbagService(slog.Default(), nil, nil, nil, nil, nil, nil, tc.cfg, nil)
+			svc.timeNow = func() time.Time { return tc.now }
+
+			inFrame := wire.SNACFrame{RequestID: 1234}
+			actual := svc.StartCluster(nil, sess, inFrame, wire.SNAC_0x13_0x11_FeedbagStartCluster{})
+			assert.Equal(t, tc.expectOutput, actual)
+			assert.Equal(t, tc.expectClusterStart, sess.FeedbagClusterStart())
 		})
 	}
 }
+
+func TestFeedbagService_EndCluster(t *testing.T) {
+	sess := newTestSession("me")
+	svc := NewFeedbagService(slog.Default(), nil, nil, nil, nil, nil, nil, config.Config{FeedbagClusterTimeoutSec: 30}, nil)
+
+	assert.Nil(t, svc.StartCluster(nil, sess, wire.SNACFrame{}, wire.SNAC_0x13_0x11_FeedbagStartCluster{}))
+	svc.EndCluster(nil, sess)
+	assert.True(t, sess.FeedbagClusterStart().IsZero())
+
+	// a new cluster may be started once the previous one ends
+	assert.Nil(t, svc.StartCluster(nil, sess, wire.SNACFrame{}, wire.SNAC_0x13_0x11_FeedbagStartCluster{}))
+}
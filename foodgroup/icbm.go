@@ -1,12 +1,20 @@
 package foodgroup
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -16,18 +24,34 @@ const (
 
 // NewICBMService returns a new instance of ICBMService.
 func NewICBMService(
+	cfg config.Config,
 	messageRelayer MessageRelayer,
 	offlineMessageSaver OfflineMessageManager,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	messageFilter MessageFilter,
+	inviteDisabledExchanges []uint16,
+	rendezvousCapabilities [][16]byte,
+	fileTransferLimiter FileTransferLimiter,
+	autoSuspender AutoSuspender,
+	screenNameResolver ScreenNameResolver,
+	userManager UserManager,
 ) *ICBMService {
 	return &ICBMService{
-		buddyListRetriever:  buddyListRetriever,
-		buddyBroadcaster:    newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
-		messageRelayer:      messageRelayer,
-		offlineMessageSaver: offlineMessageSaver,
-		timeNow:             time.Now,
-		sessionRetriever:    sessionRetriever,
+		autoSuspender:           autoSuspender,
+		buddyListRetriever:      buddyListRetriever,
+		buddyBroadcaster:        newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		cfg:                     cfg,
+		fileTransferLimiter:     fileTransferLimiter,
+		inviteDisabledExchanges: inviteDisabledExchanges,
+		messageFilter:           messageFilter,
+		messageRelayer:          messageRelayer,
+		offlineMessageSaver:     offlineMessageSaver,
+		rendezvousCapabilities:  rendezvousCapabilities,
+		screenNameResolver:      screenNameResolver,
+		timeNow:                 time.Now,
+		sessionRetriever:        sessionRetriever,
+		userManager:             userManager,
 	}
 }
 
@@ -35,16 +59,44 @@ func NewICBMService(
 // responsible for sending and receiving instant messages and associated
 // functionality such as warning, typing events, etc.
 type ICBMService struct {
-	buddyListRetriever  BuddyListRetriever
-	buddyBroadcaster    buddyBroadcaster
-	messageRelayer      MessageRelayer
-	offlineMessageSaver OfflineMessageManager
-	timeNow             func() time.Time
-	sessionRetriever    SessionRetriever
+	// autoSuspender suspends users who receive too many warnings.
+	autoSuspender      AutoSuspender
+	buddyListRetriever BuddyListRetriever
+	buddyBroadcaster   buddyBroadcaster
+	cfg                config.Config
+	// fileTransferLimiter caps the number of file transfers that may run at
+	// once.
+	fileTransferLimiter FileTransferLimiter
+	// inviteDisabledExchanges are the exchanges whose rooms users can't
+	// invite others to.
+	inviteDisabledExchanges []uint16
+	messageFilter           MessageFilter
+	messageRelayer          MessageRelayer
+	offlineMessageSaver     OfflineMessageManager
+	// rendezvousCapabilities are the capabilities that users may propose
+	// rendezvous sessions for. All capabilities are allowed if empty.
+	rendezvousCapabilities [][16]byte
+	// screenNameResolver resolves recipient aliases to their accounts.
+	screenNameResolver ScreenNameResolver
+	timeNow            func() time.Time
+	sessionRetriever   SessionRetriever
+	// userManager looks up offline recipients before their messages are
+	// stored.
+	userManager UserManager
 }
 
-// ParameterQuery returns ICBM service parameters.
+// defaultMaxICBMLen is the instant message length in bytes that is advertised
+// to clients if the message length isn't limited.
+const defaultMaxICBMLen uint16 = 512
+
+// ParameterQuery returns ICBM service parameters. The advertised limits are
+// the same ones that ChannelMsgToHost enforces. If the message length isn't
+// limited, defaultMaxICBMLen is advertised.
 func (s ICBMService) ParameterQuery(_ context.Context, inFrame wire.SNACFrame) wire.SNACMessage {
+	maxLen := s.cfg.ICBMMaxMessageLen
+	if maxLen == 0 {
+		maxLen = defaultMaxICBMLen
+	}
 	return wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.ICBM,
@@ -54,10 +106,10 @@ func (s ICBMService) ParameterQuery(_ context.Context, inFrame wire.SNACFrame) w
 		Body: wire.SNAC_0x04_0x05_ICBMParameterReply{
 			MaxSlots:             100,
 			ICBMFlags:            3,
-			MaxIncomingICBMLen:   512,
-			MaxSourceEvil:        999,
-			MaxDestinationEvil:   999,
-			MinInterICBMInterval: 0,
+			MaxIncomingICBMLen:   maxLen,
+			MaxSourceEvil:        s.cfg.ICBMMaxSenderWarnLevel,
+			MaxDestinationEvil:   s.cfg.ICBMMaxRecipientWarnLevel,
+			MinInterICBMInterval: s.cfg.ICBMMinMessageIntervalMs,
 		},
 	}
 }
@@ -76,11 +128,45 @@ func newICBMErr(requestID uint32, errCode uint16) *wire.SNACMessage {
 }
 
 // ChannelMsgToHost relays the instant message SNAC wire.ICBMChannelMsgToHost
-// from the sender to the intended recipient. It returns wire.ICBMHostAck if
-// the wire.ICBMChannelMsgToHost message contains a request acknowledgement
-// flag.
+// from the sender to the intended recipient, or to the account that a screen
+// name alias belongs to. A recipient signed on from several places receives
+// the message on each session. It returns a single wire.ICBMHostAck if the
+// wire.ICBMChannelMsgToHost message contains a request acknowledgement flag.
+// Messages that fail the server's checks are dropped or rejected with
+// wire.ICBMErr, and rendezvous proposals that fail them are cancelled on
+// behalf of the recipient.
 func (s ICBMService) ChannelMsgToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) (*wire.SNACMessage, error) {
-	recip := state.NewIdentScreenName(inBody.ScreenName)
+	recip, err := s.screenNameResolver.CanonicalScreenName(state.NewIdentScreenName(inBody.ScreenName))
+	if err != nil {
+		return nil, err
+	}
+
+	if inBody.ChannelID == wire.ICBMChannelIM && s.cfg.DropEmptyMessages && isEmptyIM(inBody) {
+		// quietly discard the message so that the sender doesn't see an error
+		return s.hostAck(inFrame, inBody), nil
+	}
+
+	if s.cfg.RestrictUnconfirmedAccounts && sess.UserInfoBitmask()&wire.OServiceUserFlagUnconfirmed != 0 {
+		// users must confirm their accounts before they can send messages
+		return newICBMErr(inFrame.RequestID, wire.ErrorCodeInsufficientRights), nil
+	}
+
+	if recip == sess.IdentScreenName() {
+		// messages to yourself are delivered unless configured otherwise
+		switch s.cfg.ICBMSelfMessages {
+		case config.SelfMessagesDrop:
+			return s.hostAck(inFrame, inBody), nil
+		case config.SelfMessagesError:
+			return newICBMErr(inFrame.RequestID, wire.ErrorCodeRequestDenied), nil
+		}
+	}
+
+	isIM := inBody.ChannelID == wire.ICBMChannelIM || inBody.ChannelID == wire.ICBMChannelICQ
+	if isIM {
+		if errCode := s.checkSenderLimits(sess, inBody); errCode != 0 {
+			return newICBMErr(inFrame.RequestID, errCode), nil
+		}
+	}
 
 	rel, err := s.buddyListRetriever.Relationship(sess.IdentScreenName(), recip)
 	if err != nil {
@@ -92,12 +178,23 @@ func (s ICBMService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 		return newICBMErr(inFrame.RequestID, wire.ErrorCodeNotLoggedOn), nil
 	case rel.YouBlock:
 		return newICBMErr(inFrame.RequestID, wire.ErrorCodeInLocalPermitDeny), nil
+	case isIM && !rel.IsOnYourList && newbieRestricted(s.cfg, sess, s.timeNow):
+		return newICBMErr(inFrame.RequestID, wire.ErrorCodeInsufficientRights), nil
 	}
 
 	recipSess := s.sessionRetriever.RetrieveSession(recip)
 	if recipSess == nil {
-		// todo: verify user exists, otherwise this could save a bunch of garbage records
-		if _, saveOffline := inBody.Bytes(wire.ICBMTLVStore); saveOffline {
+		_, saveOffline := inBody.Bytes(wire.ICBMTLVStore)
+		storeForAIM := !saveOffline && s.storesAIMOfflineMessage(recip, inBody)
+		if saveOffline || storeForAIM {
+			// don't store messages for screen names that don't exist
+			user, err := s.userManager.User(recip)
+			if err != nil {
+				return nil, fmt.Errorf("userManager.User: %w", err)
+			}
+			if user == nil {
+				return newICBMErr(inFrame.RequestID, wire.ErrorCodeNotLoggedOn), nil
+			}
 			offlineMsg := state.OfflineMessage{
 				Message:   inBody,
 				Recipient: recip,
@@ -105,9 +202,17 @@ func (s ICBMService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 				Sent:      s.timeNow().UTC(),
 			}
 			if err := s.offlineMessageSaver.SaveMessage(offlineMsg); err != nil {
+				if errors.Is(err, state.ErrStorageQuotaExceeded) || errors.Is(err, state.ErrOfflineMessageLimit) {
+					// bounce the message back to the sender
+					return newICBMErr(inFrame.RequestID, wire.ErrorCodeQueueFull), nil
+				}
 				return nil, fmt.Errorf("save ICBM offline message failed: %w", err)
 			}
 		}
+		if storeForAIM {
+			// the message will be delivered when the recipient signs on
+			return s.hostAck(inFrame, inBody), nil
+		}
 		return &wire.SNACMessage{
 			Frame: wire.SNACFrame{
 				FoodGroup: wire.ICBM,
@@ -120,6 +225,56 @@ func (s ICBMService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 		}, nil
 	}
 
+	switch {
+	case !recipSess.PermitsClassOf(sess):
+		// their group permit mask blocks you
+		return newICBMErr(inFrame.RequestID, wire.ErrorCodeNotLoggedOn), nil
+	case !sess.PermitsClassOf(recipSess):
+		// your group permit mask blocks them
+		return newICBMErr(inFrame.RequestID, wire.ErrorCodeInLocalPermitDeny), nil
+	}
+
+	if isIM && recipSess.Warning() > s.cfg.ICBMMaxRecipientWarnLevel {
+		return newICBMErr(inFrame.RequestID, wire.ErrorCodeTooEvilReceiver), nil
+	}
+
+	if inBody.ChannelID == wire.ICBMChannelRendezvous {
+		// rendezvous messages that can't be parsed are relayed as-is
+		if frag, ok := rendezvousFragment(inBody); ok {
+			if s.chatInviteDenied(frag) {
+				return newICBMErr(inFrame.RequestID, wire.ErrorCodeRequestDenied), nil
+			}
+			if !s.rendezvousCapabilityAllowed(frag) {
+				return rendezvousCancel(inBody, frag, recipSess, wire.ICBMRdvCancelReasonsNotAllowed), nil
+			}
+			if s.rendezvousFileTooLarge(frag) {
+				return rendezvousCancel(inBody, frag, recipSess, wire.ICBMRdvCancelReasonsSizeExceeded), nil
+			}
+			if !s.fileTransferAllowed(sess, frag, recipSess) {
+				return rendezvousCancel(inBody, frag, recipSess, wire.ICBMRdvCancelReasonsTooManyTransfers), nil
+			}
+		}
+	}
+
+	autoGenerated := isIM && s.isAutoGenerated(inBody)
+	if autoGenerated && !sess.TakeAutoResponse(recip) {
+		// the auto-response doesn't answer a message typed by the recipient,
+		// so it's likely a reply to another auto-response. drop it to stop
+		// the two clients from replying to each other endlessly.
+		return s.hostAck(inFrame, inBody), nil
+	}
+
+	if notice := s.suppressedDeliveryNotice(recipSess); notice != "" && isIM {
+		// suppress delivery and let the sender know the recipient doesn't
+		// want to be disturbed
+		if !autoGenerated {
+			if err := s.sendDNDNotice(ctx, sess, recipSess, inBody.Cookie, notice); err != nil {
+				return nil, err
+			}
+		}
+		return s.hostAck(inFrame, inBody), nil
+	}
+
 	clientIM := wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
 		Cookie:      inBody.Cookie,
 		ChannelID:   inBody.ChannelID,
@@ -152,9 +307,92 @@ func (s ICBMService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 		Body: clientIM,
 	})
 
+	if isIM && !autoGenerated && s.cfg.AutoResponseLoopPrevention {
+		recipSess.ExpectAutoResponse(sess.IdentScreenName())
+	}
+
+	return s.hostAck(inFrame, inBody), nil
+}
+
+// isAutoGenerated reports whether an instant message was generated
+// automatically, such as an away message, rather than typed by the sender.
+// Auto-generated messages never trigger another auto-response. It always
+// returns false if auto-response loop prevention is disabled.
+func (s ICBMService) isAutoGenerated(inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) bool {
+	if !s.cfg.AutoResponseLoopPrevention {
+		return false
+	}
+	_, isAutoResponse := inBody.Bytes(wire.ICBMTLVAutoResponse)
+	return isAutoResponse
+}
+
+// isEmptyIM reports whether a channel 1 instant message has text that
+// contains only whitespace. Messages that can't be parsed are not considered
+// empty.
+func isEmptyIM(inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) bool {
+	b, ok := inBody.Bytes(wire.ICBMTLVAOLIMData)
+	if !ok {
+		return false
+	}
+	text, err := wire.UnmarshalICBMMessageText(b)
+	return err == nil && isBlankHTML([]byte(text))
+}
+
+// newbieRestricted reports whether sess belongs to an account that is still
+// within the config.Config.NewbieRestrictionMin period after its creation.
+func newbieRestricted(cfg config.Config, sess *state.Session, timeNow func() time.Time) bool {
+	if cfg.NewbieRestrictionMin <= 0 {
+		return false
+	}
+	createdAt := sess.AccountCreatedAt()
+	if createdAt.IsZero() {
+		return false
+	}
+	return timeNow().Before(createdAt.Add(time.Duration(cfg.NewbieRestrictionMin) * time.Minute))
+}
+
+// checkSenderLimits checks an instant message against the sender warning
+// level, message length, message filter, and message rate limits. It returns
+// the error code of the first limit exceeded, or 0 if the message is within
+// limits. If the message is within limits, the send time is recorded for rate
+// limiting.
+func (s ICBMService) checkSenderLimits(sess *state.Session, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) uint16 {
+	if sess.Warning() > s.cfg.ICBMMaxSenderWarnLevel {
+		return wire.ErrorCodeTooEvilSender
+	}
+
+	if inBody.ChannelID == wire.ICBMChannelIM {
+		if b, ok := inBody.Bytes(wire.ICBMTLVAOLIMData); ok {
+			// messages that can't be parsed are passed through as-is
+			if text, err := wire.UnmarshalICBMMessageText(b); err == nil {
+				if s.cfg.ICBMMaxMessageLen > 0 && len(text) > int(s.cfg.ICBMMaxMessageLen) {
+					return wire.ErrorCodeRequestDenied
+				}
+				if s.messageFilter.Match(text) {
+					return wire.ErrorCodeRequestDenied
+				}
+			}
+		}
+	}
+
+	if s.cfg.ICBMMinMessageIntervalMs > 0 {
+		now := s.timeNow()
+		minInterval := time.Duration(s.cfg.ICBMMinMessageIntervalMs) * time.Millisecond
+		if now.Sub(sess.LastIMTime()) < minInterval {
+			return wire.ErrorCodeRateToHost
+		}
+		sess.SetLastIMTime(now)
+	}
+
+	return 0
+}
+
+// hostAck returns wire.ICBMHostAck if the sender requested acknowledgement of
+// the message, otherwise nil.
+func (s ICBMService) hostAck(inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) *wire.SNACMessage {
 	if _, requestedConfirmation := inBody.TLVRestBlock.Bytes(wire.ICBMTLVRequestHostAck); !requestedConfirmation {
 		// don't ack message
-		return nil, nil
+		return nil
 	}
 
 	// ack message back to sender
@@ -169,13 +407,212 @@ func (s ICBMService) ChannelMsgToHost(ctx context.Context, sess *state.Session,
 			ChannelID:  inBody.ChannelID,
 			ScreenName: inBody.ScreenName,
 		},
-	}, nil
+	}
+}
+
+// storesAIMOfflineMessage indicates whether an instant message sent to an
+// offline AIM recipient should be stored for delivery at next sign-on. AIM
+// clients don't ask for their messages to be stored, so the server decides on
+// their behalf. Auto-responses are never stored.
+func (s ICBMService) storesAIMOfflineMessage(recip state.IdentScreenName, inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) bool {
+	if !s.cfg.AIMOfflineMessages || inBody.ChannelID != wire.ICBMChannelIM {
+		return false
+	}
+	if state.DisplayScreenName(recip.String()).IsUIN() {
+		return false
+	}
+	_, isAutoResponse := inBody.Bytes(wire.ICBMTLVAutoResponse)
+	return !isAutoResponse
+}
+
+// suppressedDeliveryNotice returns the auto-response text sent on behalf of a
+// recipient whose status holds back instant messages. It returns an empty
+// string if messages are delivered to the recipient.
+func (s ICBMService) suppressedDeliveryNotice(recipSess *state.Session) string {
+	switch {
+	case recipSess.DND():
+		return "User is in do not disturb mode."
+	case s.cfg.ICQOccupiedSuppressesDelivery && recipSess.Occupied():
+		return "User is occupied."
+	}
+	return ""
+}
+
+// sendDNDNotice sends an auto-response to the sender on behalf of a recipient
+// who is in "do not disturb" or "occupied" mode.
+func (s ICBMService) sendDNDNotice(ctx context.Context, sess *state.Session, recipSess *state.Session, cookie uint64, notice string) error {
+	frags, err := wire.ICBMFragmentList(notice)
+	if err != nil {
+		return fmt.Errorf("unable to create DND notice: %w", err)
+	}
+	s.messageRelayer.RelayToScreenName(ctx, sess.IdentScreenName(), wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:      cookie,
+			ChannelID:   wire.ICBMChannelIM,
+			TLVUserInfo: recipSess.TLVUserInfo(),
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+					wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}),
+				},
+			},
+		},
+	})
+	return nil
+}
+
+// rendezvousFragment parses the rendezvous fragment carried by a channel 2
+// message. It returns false if the message has no fragment or the fragment
+// can't be parsed.
+func rendezvousFragment(inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost) (wire.ICBMCh2Fragment, bool) {
+	frag := wire.ICBMCh2Fragment{}
+	b, hasData := inBody.Bytes(wire.ICBMTLVData)
+	if !hasData {
+		return frag, false
+	}
+	if err := wire.UnmarshalBE(&frag, bytes.NewBuffer(b)); err != nil {
+		return frag, false
+	}
+	return frag, true
+}
+
+// chatInviteDenied reports whether a rendezvous fragment is a chat room
+// invitation to a room whose exchange disallows invitations. Invitations
+// whose room info can't be parsed are not denied.
+func (s ICBMService) chatInviteDenied(frag wire.ICBMCh2Fragment) bool {
+	if len(s.inviteDisabledExchanges) == 0 || frag.Type != wire.ICBMRdvMessagePropose || frag.Capability != wire.CapChat {
+		return false
+	}
+
+	svcBytes, hasSvcData := frag.Bytes(wire.ICBMRdvTLVTagsSvcData)
+	if !hasSvcData {
+		return false
+	}
+	roomInfo := wire.ICBMRoomInfo{}
+	if err := wire.UnmarshalBE(&roomInfo, bytes.NewBuffer(svcBytes)); err != nil {
+		return false
+	}
+	return slices.Contains(s.inviteDisabledExchanges, roomInfo.Exchange)
+}
+
+// rendezvousCapabilityAllowed reports whether a rendezvous fragment proposes
+// a session for a capability in the configured allowlist. Fragments other
+// than proposals, and all proposals if no allowlist is configured, are
+// allowed.
+func (s ICBMService) rendezvousCapabilityAllowed(frag wire.ICBMCh2Fragment) bool {
+	if len(s.rendezvousCapabilities) == 0 || frag.Type != wire.ICBMRdvMessagePropose {
+		return true
+	}
+	return slices.Contains(s.rendezvousCapabilities, frag.Capability)
+}
+
+// rendezvousFileTooLarge reports whether a rendezvous fragment is a file
+// transfer proposal whose advertised file size exceeds
+// config.Config.MaxRendezvousFileSize. Proposals whose service data can't be
+// parsed are not considered too large.
+func (s ICBMService) rendezvousFileTooLarge(frag wire.ICBMCh2Fragment) bool {
+	if s.cfg.MaxRendezvousFileSize == 0 || frag.Type != wire.ICBMRdvMessagePropose || frag.Capability != wire.CapFileTransfer {
+		return false
+	}
+
+	svcBytes, hasSvcData := frag.Bytes(wire.ICBMRdvTLVTagsSvcData)
+	if !hasSvcData {
+		return false
+	}
+	svcData := wire.ICBMRdvFileTransferSvcData{}
+	if err := wire.UnmarshalBE(&svcData, bytes.NewBuffer(svcBytes)); err != nil {
+		return false
+	}
+	return svcData.TotalBytes > s.cfg.MaxRendezvousFileSize
+}
+
+// fileTransferAllowed tracks the file transfers that a rendezvous fragment
+// starts or ends when file transfer concurrency caps are configured. A
+// transfer stops counting toward the caps when it's cancelled, or when it's
+// accepted unless it's relayed through the file transfer proxy. It reports
+// false if the fragment proposes a new file transfer past the caps.
+func (s ICBMService) fileTransferAllowed(sess *state.Session, frag wire.ICBMCh2Fragment, recipSess *state.Session) bool {
+	if (s.cfg.MaxFileTransfersPerUser <= 0 && s.cfg.MaxFileTransfers <= 0) || frag.Capability != wire.CapFileTransfer {
+		return true
+	}
+
+	switch frag.Type {
+	case wire.ICBMRdvMessagePropose:
+		_, proxied := frag.Bytes(wire.ICBMRdvTLVTagsRequestUseARS)
+		return s.fileTransferLimiter.StartTransfer(frag.Cookie, sess.IdentScreenName(), recipSess.IdentScreenName(), proxied)
+	case wire.ICBMRdvMessageAccept:
+		s.fileTransferLimiter.AcceptTransfer(frag.Cookie)
+	case wire.ICBMRdvMessageCancel:
+		s.fileTransferLimiter.EndTransfer(frag.Cookie)
+	}
+	return true
+}
+
+// rendezvousCancel creates a message that cancels a rendezvous proposal on
+// behalf of the recipient for the given reason.
+func rendezvousCancel(inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost, frag wire.ICBMCh2Fragment, recipSess *state.Session, reason uint16) *wire.SNACMessage {
+	cancel := wire.ICBMCh2Fragment{
+		Type:       wire.ICBMRdvMessageCancel,
+		Cookie:     frag.Cookie,
+		Capability: frag.Capability,
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, reason),
+			},
+		},
+	}
+
+	return &wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:      inBody.Cookie,
+			ChannelID:   wire.ICBMChannelRendezvous,
+			TLVUserInfo: recipSess.TLVUserInfo(),
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVData, cancel),
+				},
+			},
+		},
+	}
+}
+
+// ParseCapabilityList parses a comma-separated list of capability UUIDs, such
+// as "09461343-4C7F-11D1-8222-444553540000". An empty string yields an empty
+// list.
+func ParseCapabilityList(s string) ([][16]byte, error) {
+	var caps [][16]byte
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		capability, err := uuid.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capability %q: %w", entry, err)
+		}
+		caps = append(caps, capability)
+	}
+	return caps, nil
 }
 
 // ClientEvent relays SNAC wire.ICBMClientEvent typing events from the
-// sender to the recipient.
+// sender to the recipient. Events addressed to a screen name alias are
+// relayed to the account that the alias belongs to.
 func (s ICBMService) ClientEvent(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x14_ICBMClientEvent) error {
-	blocked, err := s.buddyListRetriever.Relationship(sess.IdentScreenName(), state.NewIdentScreenName(inBody.ScreenName))
+	recip, err := s.screenNameResolver.CanonicalScreenName(state.NewIdentScreenName(inBody.ScreenName))
+	if err != nil {
+		return err
+	}
+
+	blocked, err := s.buddyListRetriever.Relationship(sess.IdentScreenName(), recip)
 
 	switch {
 	case err != nil:
@@ -183,7 +620,7 @@ func (s ICBMService) ClientEvent(ctx context.Context, sess *state.Session, inFra
 	case blocked.BlocksYou || blocked.YouBlock:
 		return nil
 	default:
-		s.messageRelayer.RelayToScreenName(ctx, state.NewIdentScreenName(inBody.ScreenName), wire.SNACMessage{
+		s.messageRelayer.RelayToScreenName(ctx, recip, wire.SNACMessage{
 			Frame: wire.SNACFrame{
 				FoodGroup: wire.ICBM,
 				SubGroup:  wire.ICBMClientEvent,
@@ -224,9 +661,15 @@ func (s ICBMService) ClientErr(ctx context.Context, sess *state.Session, inFrame
 // have been warned. The user may choose to warn anonymously or
 // non-anonymously. It returns SNAC wire.ICBMEvilReply to confirm that the
 // warning was sent. Users may not warn themselves or warn users they have
-// blocked or are blocked by.
+// blocked or are blocked by. Users who receive too many warnings are
+// suspended and disconnected when config.Config.AutoSuspendWarnThreshold is
+// set. Warnings addressed to a screen name alias apply to the account that
+// the alias belongs to.
 func (s ICBMService) EvilRequest(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x04_0x08_ICBMEvilRequest) (wire.SNACMessage, error) {
-	identScreenName := state.NewIdentScreenName(inBody.ScreenName)
+	identScreenName, err := s.screenNameResolver.CanonicalScreenName(state.NewIdentScreenName(inBody.ScreenName))
+	if err != nil {
+		return wire.SNACMessage{}, err
+	}
 
 	// don't let users warn themselves, it causes the AIM client to go into a
 	// weird state.
@@ -304,9 +747,16 @@ func (s ICBMService) EvilRequest(ctx context.Context, sess *state.Session, inFra
 		Body: notif,
 	})
 
-	// inform the warned user's buddies that their warning level has increased
-	if err := s.buddyBroadcaster.BroadcastBuddyArrived(ctx, recipSess); err != nil {
-		return wire.SNACMessage{}, err
+	if s.cfg.AutoSuspendWarnThreshold > 0 && s.autoSuspender.RecordWarning(recipSess.IdentScreenName()) {
+		// the user has been warned too often. kick them off; the suspension
+		// keeps them from signing back on until the cooldown passes.
+		recipSess.Close()
+	} else {
+		// inform the warned user's buddies that their warning level has
+		// increased
+		if err := s.buddyBroadcaster.BroadcastBuddyArrived(ctx, recipSess); err != nil {
+			return wire.SNACMessage{}, err
+		}
 	}
 
 	return wire.SNACMessage{
@@ -321,3 +771,67 @@ func (s ICBMService) EvilRequest(ctx context.Context, sess *state.Session, inFra
 		},
 	}, nil
 }
+
+// NewWarningDecayer creates a new instance of WarningDecayer.
+func NewWarningDecayer(
+	cfg config.Config,
+	logger *slog.Logger,
+	buddyListRetriever BuddyListRetriever,
+	messageRelayer MessageRelayer,
+	sessionRetriever SessionRetriever,
+	sessionLister SessionLister,
+) WarningDecayer {
+	return WarningDecayer{
+		buddyBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		cfg:              cfg,
+		logger:           logger,
+		messageRelayer:   messageRelayer,
+		sessionLister:    sessionLister,
+	}
+}
+
+// WarningDecayer lowers the warning levels of signed-on users over time, so
+// that warnings received via ICBMService.EvilRequest wear off as they did on
+// the original AIM service.
+type WarningDecayer struct {
+	buddyBroadcaster buddyBroadcaster
+	cfg              config.Config
+	logger           *slog.Logger
+	messageRelayer   MessageRelayer
+	sessionLister    SessionLister
+}
+
+// Decay decreases the warning level of each warned, signed-on user by
+// config.Config.ICBMWarnDecayAmount. Each user is sent their updated user
+// info, and the buddies of visible users are informed of the new warning
+// level. A failed broadcast is logged so that it doesn't hold up the
+// remaining users.
+func (d WarningDecayer) Decay(ctx context.Context) {
+	for _, sess := range d.sessionLister.AllSessions() {
+		if !sess.SignonComplete() || sess.Warning() == 0 {
+			continue
+		}
+
+		newLevel := sess.DecayWarning(d.cfg.ICBMWarnDecayAmount)
+		d.logger.DebugContext(ctx, "decayed warning level", "user", sess.IdentScreenName(), "warning", newLevel)
+
+		d.messageRelayer.RelayToScreenName(ctx, sess.IdentScreenName(), wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.OService,
+				SubGroup:  wire.OServiceUserInfoUpdate,
+			},
+			Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+				TLVUserInfo: sess.TLVUserInfo(),
+			},
+		})
+
+		// don't reveal invisible users to their buddies
+		if sess.Invisible() {
+			continue
+		}
+		if err := d.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess); err != nil {
+			d.logger.ErrorContext(ctx, "unable to broadcast decayed warning level",
+				"user", sess.IdentScreenName(), "err", err.Error())
+		}
+	}
+}
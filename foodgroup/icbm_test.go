@@ -1,14 +1,20 @@
 package foodgroup
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"testing"
 	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestICBMService_ChannelMsgToHost(t *testing.T) {
@@ -27,6 +33,8 @@ func TestICBMService_ChannelMsgToHost(t *testing.T) {
 		mockParams mockParams
 		// timeNow returns the current time
 		timeNow func() time.Time
+		// cfg is the application config
+		cfg config.Config
 	}{
 		{
 			name:          "transmit message from sender to recipient, ack message back to sender",
@@ -410,6 +418,14 @@ func TestICBMService_ChannelMsgToHost(t *testing.T) {
 				messageRelayerParams: messageRelayerParams{
 					relayToScreenNameParams: relayToScreenNameParams{},
 				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("22222222"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("22222222")},
+						},
+					},
+				},
 				offlineMessageManagerParams: offlineMessageManagerParams{
 					saveMessageParams: saveMessageParams{
 						{
@@ -432,46 +448,1729 @@ func TestICBMService_ChannelMsgToHost(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			buddyListRetriever := newMockBuddyListRetriever(t)
-			for _, item := range tc.mockParams.buddyListRetrieverParams.relationshipParams {
-				buddyListRetriever.EXPECT().
-					Relationship(item.me, item.them).
-					Return(item.result, item.err)
+		{
+			name:          "bounce offline message to recipient over storage quota",
+			senderSession: newTestSession("11111111"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					ScreenName: "22222222",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+							wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeQueueFull,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("11111111"),
+							them: state.NewIdentScreenName("22222222"),
+							result: state.Relationship{
+								User:          state.NewIdentScreenName("22222222"),
+								BlocksYou:     false,
+								YouBlock:      false,
+								IsOnTheirList: false,
+								IsOnYourList:  false,
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("22222222"),
+							result:     nil,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("22222222"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("22222222")},
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessageIn: state.OfflineMessage{
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ScreenName: "22222222",
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+											wire.NewTLVBE(wire.ICBMTLVStore, []byte{}),
+										},
+									},
+								},
+								Recipient: state.NewIdentScreenName("22222222"),
+								Sender:    state.NewIdentScreenName("11111111"),
+								Sent:      time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC),
+							},
+							err: state.ErrStorageQuotaExceeded,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "don't store offline message for AIM recipient that doesn't exist",
+			senderSession: newTestSession("sender-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeNotLoggedOn,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     nil,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     nil,
+						},
+					},
+				},
+			},
+			cfg: config.Config{
+				AIMOfflineMessages:        true,
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+		},
+		{
+			name:          "store offline message for AIM recipient, ack message back to sender",
+			senderSession: newTestSession("sender-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMHostAck,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x0C_ICBMHostAck{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     nil,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("recipient-screen-name")},
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessageIn: state.OfflineMessage{
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									Cookie:     1234,
+									ChannelID:  wire.ICBMChannelIM,
+									ScreenName: "recipient-screen-name",
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+										},
+									},
+								},
+								Recipient: state.NewIdentScreenName("recipient-screen-name"),
+								Sender:    state.NewIdentScreenName("sender-screen-name"),
+								Sent:      time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC),
+							},
+						},
+					},
+				},
+			},
+			cfg: config.Config{
+				AIMOfflineMessages:        true,
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+		},
+		{
+			name:          "bounce AIM offline message to recipient at offline message limit",
+			senderSession: newTestSession("sender-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeQueueFull,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     nil,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("recipient-screen-name")},
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessageIn: state.OfflineMessage{
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									Cookie:     1234,
+									ChannelID:  wire.ICBMChannelIM,
+									ScreenName: "recipient-screen-name",
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+										},
+									},
+								},
+								Recipient: state.NewIdentScreenName("recipient-screen-name"),
+								Sender:    state.NewIdentScreenName("sender-screen-name"),
+								Sent:      time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC),
+							},
+							err: state.ErrOfflineMessageLimit,
+						},
+					},
+				},
+			},
+			cfg: config.Config{
+				AIMOfflineMessages:        true,
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+		},
+		{
+			name:          "don't transmit message to recipient in DND mode, send DND notice to sender",
+			senderSession: newTestSession("sender-screen-name"),
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     newTestSession("recipient-screen-name", sessOptDND),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("sender-screen-name"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									Cookie:      1234,
+									ChannelID:   wire.ICBMChannelIM,
+									TLVUserInfo: newTestSession("recipient-screen-name", sessOptDND).TLVUserInfo(),
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVAOLIMData, func() []wire.ICBMCh1Fragment {
+												frags, err := wire.ICBMFragmentList("User is in do not disturb mode.")
+												assert.NoError(t, err)
+												return frags
+											}()),
+											wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{1, 2, 3, 4}),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMHostAck,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x0C_ICBMHostAck{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+				},
+			},
+		},
+		{
+			name:          "cancel file transfer proposal that exceeds the max rendezvous file size",
+			senderSession: newTestSession("sender-screen-name"),
+			cfg: config.Config{
+				MaxRendezvousFileSize: 1000,
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     newTestSession("recipient-screen-name"),
+						},
+					},
+				},
+			},
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelRendezvous,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Type:       wire.ICBMRdvMessagePropose,
+								Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+								Capability: wire.CapFileTransfer,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, wire.ICBMRdvFileTransferSvcData{
+											MultipleFilesFlag: 1,
+											FileCount:         1,
+											TotalBytes:        1001,
+										}),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMChannelMsgToClient,
+				},
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					Cookie:      1234,
+					ChannelID:   wire.ICBMChannelRendezvous,
+					TLVUserInfo: newTestSession("recipient-screen-name").TLVUserInfo(),
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Type:       wire.ICBMRdvMessageCancel,
+								Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+								Capability: wire.CapFileTransfer,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsSizeExceeded),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "relay file transfer proposal that is within the max rendezvous file size",
+			senderSession: newTestSession("sender-screen-name"),
+			cfg: config.Config{
+				MaxRendezvousFileSize: 1000,
+			},
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     newTestSession("recipient-screen-name"),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									Cookie:      1234,
+									ChannelID:   wire.ICBMChannelRendezvous,
+									TLVUserInfo: newTestSession("sender-screen-name").TLVUserInfo(),
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVWantEvents, []byte{}),
+											wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+												Type:       wire.ICBMRdvMessagePropose,
+												Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+												Capability: wire.CapFileTransfer,
+												TLVRestBlock: wire.TLVRestBlock{
+													TLVList: wire.TLVList{
+														wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, wire.ICBMRdvFileTransferSvcData{
+															MultipleFilesFlag: 1,
+															FileCount:         1,
+															TotalBytes:        1000,
+														}),
+													},
+												},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					Cookie:     1234,
+					ChannelID:  wire.ICBMChannelRendezvous,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Type:       wire.ICBMRdvMessagePropose,
+								Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+								Capability: wire.CapFileTransfer,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, wire.ICBMRdvFileTransferSvcData{
+											MultipleFilesFlag: 1,
+											FileCount:         1,
+											TotalBytes:        1000,
+										}),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+			expectOutput: nil,
+		},
+		{
+			name:          "reject instant message from sender whose warning level exceeds the limit",
+			senderSession: newTestSession("sender-screen-name", sessOptWarning(500)),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeTooEvilSender,
+				},
+			},
+			cfg: config.Config{
+				ICBMMaxSenderWarnLevel:    499,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+		},
+		{
+			name:          "reject instant message to recipient whose warning level exceeds the limit",
+			senderSession: newTestSession("sender-screen-name"),
+			mockParams: mockParams{
+				buddyListRetrieverParams: buddyListRetrieverParams{
+					relationshipParams: relationshipParams{
+						{
+							me:   state.NewIdentScreenName("sender-screen-name"),
+							them: state.NewIdentScreenName("recipient-screen-name"),
+							result: state.Relationship{
+								User: state.NewIdentScreenName("recipient-screen-name"),
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("recipient-screen-name"),
+							result:     newTestSession("recipient-screen-name", sessOptWarning(500)),
+						},
+					},
+				},
+			},
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeTooEvilReceiver,
+				},
+			},
+			cfg: config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 499,
+			},
+		},
+		{
+			name:          "reject instant message that exceeds the max message length",
+			senderSession: newTestSession("sender-screen-name"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, func() []wire.ICBMCh1Fragment {
+								frags, err := wire.ICBMFragmentList("hello world")
+								assert.NoError(t, err)
+								return frags
+							}()),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			},
+			cfg: config.Config{
+				ICBMMaxMessageLen:         5,
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+		},
+		{
+			name:          "reject instant message that matches the message filter",
+			senderSession: newTestSession("sender-screen-name"),
+			mockParams: mockParams{
+				messageFilterParams: messageFilterParams{
+					matchParams: matchParams{
+						{
+							text:   "buy cheap stuff",
+							result: true,
+						},
+					},
+				},
+			},
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVAOLIMData, func() []wire.ICBMCh1Fragment {
+								frags, err := wire.ICBMFragmentList("buy cheap stuff")
+								assert.NoError(t, err)
+								return frags
+							}()),
+						},
+					},
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			},
+			cfg: config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+		},
+		{
+			name: "reject instant message sent sooner than the min message interval",
+			senderSession: func() *state.Session {
+				sess := newTestSession("sender-screen-name")
+				sess.SetLastIMTime(time.Unix(1000, 0))
+				return sess
+			}(),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+					ChannelID:  wire.ICBMChannelIM,
+					ScreenName: "recipient-screen-name",
+				},
+			},
+			expectOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRateToHost,
+				},
+			},
+			timeNow: func() time.Time {
+				return time.Unix(1000, 0).Add(500 * time.Millisecond)
+			},
+			cfg: config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+				ICBMMinMessageIntervalMs:  1000,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			for _, item := range tc.mockParams.buddyListRetrieverParams.relationshipParams {
+				buddyListRetriever.EXPECT().
+					Relationship(item.me, item.them).
+					Return(item.result, item.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, item := range tc.mockParams.sessionRetrieverParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(item.screenName).
+					Return(item.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, item := range tc.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, item.screenName, item.message)
+			}
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tc.mockParams.saveMessageParams {
+				offlineMessageManager.EXPECT().
+					SaveMessage(params.offlineMessageIn).
+					Return(params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			messageFilter := newMockMessageFilter(t)
+			for _, params := range tc.mockParams.matchParams {
+				messageFilter.EXPECT().
+					Match(params.text).
+					Return(params.result)
+			}
+
+			svc := ICBMService{
+				buddyListRetriever:  buddyListRetriever,
+				cfg:                 tc.cfg,
+				messageFilter:       messageFilter,
+				messageRelayer:      messageRelayer,
+				offlineMessageSaver: offlineMessageManager,
+				screenNameResolver:  newNoAliasScreenNameResolver(t),
+				sessionRetriever:    sessionRetriever,
+				timeNow:             tc.timeNow,
+				userManager:         userManager,
+			}
+
+			outputSNAC, err := svc.ChannelMsgToHost(nil, tc.senderSession, tc.inputSNAC.Frame,
+				tc.inputSNAC.Body.(wire.SNAC_0x04_0x06_ICBMChannelMsgToHost))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectOutput, outputSNAC)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_AutoResponseLoop(t *testing.T) {
+	newIM := func(recipient string, autoResponse bool) wire.SNAC_0x04_0x06_ICBMChannelMsgToHost {
+		im := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			ChannelID:  wire.ICBMChannelIM,
+			ScreenName: recipient,
+		}
+		if autoResponse {
+			im.Append(wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}))
+		}
+		return im
+	}
+
+	cases := []struct {
+		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// wantRelayed is the number of messages expected to reach a client
+		wantRelayed int
+	}{
+		{
+			name: "two away users exchange one auto-response with loop prevention",
+			cfg: config.Config{
+				ICBMMaxSenderWarnLevel:     999,
+				ICBMMaxRecipientWarnLevel:  999,
+				AutoResponseLoopPrevention: true,
+			},
+			wantRelayed: 2,
+		},
+		{
+			name: "two away users reply to each other endlessly without loop prevention",
+			cfg: config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			},
+			wantRelayed: 100,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions := map[state.IdentScreenName]*state.Session{
+				state.NewIdentScreenName("userA"): newTestSession("userA"),
+				state.NewIdentScreenName("userB"): newTestSession("userB"),
+			}
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(mock.Anything, mock.Anything).
+				Return(state.Relationship{}, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(mock.Anything).
+				RunAndReturn(func(screenName state.IdentScreenName) *state.Session {
+					return sessions[screenName]
+				})
+
+			// both users are away, and their clients answer every instant
+			// message they receive with an auto-response, including other
+			// auto-responses
+			type delivery struct {
+				from *state.Session
+				to   state.IdentScreenName
+			}
+			var pending []delivery
+			relayed := 0
+			messageRelayer := newMockMessageRelayer(t)
+			messageRelayer.EXPECT().
+				RelayToScreenName(mock.Anything, mock.Anything, mock.Anything).
+				Run(func(ctx context.Context, screenName state.IdentScreenName, msg wire.SNACMessage) {
+					relayed++
+					body := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+					pending = append(pending, delivery{
+						from: sessions[screenName],
+						to:   state.NewIdentScreenName(body.ScreenName),
+					})
+				})
+
+			svc := NewICBMService(tc.cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			// userA types a message to userB
+			_, err := svc.ChannelMsgToHost(context.Background(), sessions[state.NewIdentScreenName("userA")],
+				wire.SNACFrame{}, newIM("userB", false))
+			assert.NoError(t, err)
+
+			for len(pending) > 0 && relayed < 100 {
+				next := pending[0]
+				pending = pending[1:]
+				_, err := svc.ChannelMsgToHost(context.Background(), next.from, wire.SNACFrame{}, newIM(next.to.String(), true))
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantRelayed, relayed)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_AutoResponseToDNDUser(t *testing.T) {
+	sender := newTestSession("userA")
+	recipient := newTestSession("userB", sessOptDND)
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+		Return(state.Relationship{}, nil)
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(recipient.IdentScreenName()).
+		Return(recipient)
+	// the relayer must not be called: the auto-response isn't delivered
+	// and no DND notice is sent back
+	messageRelayer := newMockMessageRelayer(t)
+
+	cfg := config.Config{
+		ICBMMaxSenderWarnLevel:     999,
+		ICBMMaxRecipientWarnLevel:  999,
+		AutoResponseLoopPrevention: true,
+	}
+	svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+	// userB sent userA a message while away, so userA's auto-response is
+	// allowed, but it must not trigger a DND notice
+	sender.ExpectAutoResponse(recipient.IdentScreenName())
+
+	inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		ChannelID:  wire.ICBMChannelIM,
+		ScreenName: recipient.IdentScreenName().String(),
+	}
+	inBody.Append(wire.NewTLVBE(wire.ICBMTLVAutoResponse, []byte{}))
+
+	outputSNAC, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{}, inBody)
+	assert.NoError(t, err)
+	assert.Nil(t, outputSNAC)
+}
+
+func TestICBMService_ChannelMsgToHost_ChatInvite(t *testing.T) {
+	invite := func(exchange uint16) wire.SNAC_0x04_0x06_ICBMChannelMsgToHost {
+		inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			ChannelID:  wire.ICBMChannelRendezvous,
+			ScreenName: "userB",
+		}
+		inBody.Append(wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+			Type:       wire.ICBMRdvMessagePropose,
+			Capability: wire.CapChat,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMRdvTLVTagsSvcData, wire.ICBMRoomInfo{
+						Exchange: exchange,
+						Cookie:   fmt.Sprintf("%d-0-the-room", exchange),
+					}),
+				},
+			},
+		}))
+		return inBody
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// exchange is the exchange of the room the recipient is invited to
+		exchange uint16
+		// wantRelayed indicates whether the invitation is relayed to the
+		// recipient
+		wantRelayed bool
+		// wantOutput is the SNAC returned to the sender
+		wantOutput *wire.SNACMessage
+	}{
+		{
+			name:        "invite to a room in an exchange that allows invitations",
+			exchange:    state.PrivateExchange,
+			wantRelayed: true,
+		},
+		{
+			name:     "invite to a room in an exchange that disallows invitations",
+			exchange: state.PublicExchange,
+			wantOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+			recipient := newTestSession("userB")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+				Return(state.Relationship{}, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(recipient.IdentScreenName()).
+				Return(recipient)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantRelayed {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+			}
+
+			svc := NewICBMService(config.Config{}, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil,
+				[]uint16{state.PublicExchange}, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1234}, invite(tc.exchange))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantOutput, output)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_EmptyMessage(t *testing.T) {
+	frags, err := wire.ICBMFragmentList("<HTML><BODY BGCOLOR=\"#ffffff\"><FONT>   </FONT></BODY></HTML>")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// dropEmptyMessages is the DROP_EMPTY_MESSAGES config value
+		dropEmptyMessages bool
+		// wantRelayed indicates whether the message is relayed to the
+		// recipient
+		wantRelayed bool
+	}{
+		{
+			name:              "whitespace-only message is dropped when enabled",
+			dropEmptyMessages: true,
+			wantRelayed:       false,
+		},
+		{
+			name:              "whitespace-only message is relayed when disabled",
+			dropEmptyMessages: false,
+			wantRelayed:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+			recipient := newTestSession("userB")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			sessionRetriever := newMockSessionRetriever(t)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantRelayed {
+				buddyListRetriever.EXPECT().
+					Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+					Return(state.Relationship{}, nil)
+				sessionRetriever.EXPECT().
+					RetrieveSession(recipient.IdentScreenName()).
+					Return(recipient)
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+			}
+
+			cfg := config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+				DropEmptyMessages:         tc.dropEmptyMessages,
+			}
+			messageFilter := state.NewMessageFilter("")
+			svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, messageFilter, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     1234,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: recipient.IdentScreenName().String(),
+			}
+			inBody.Append(wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags))
+			inBody.Append(wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}))
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+			assert.NoError(t, err)
+			// the sender gets an ack whether or not the message is delivered
+			assert.Equal(t, wire.ICBMHostAck, output.Frame.SubGroup)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_ICQStatusMode(t *testing.T) {
+	frags, err := wire.ICBMFragmentList("hello")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// status is the recipient's ICQ status mode
+		status uint32
+		// occupiedSuppressesDelivery is the ICQ_OCCUPIED_SUPPRESSES_DELIVERY
+		// config value
+		occupiedSuppressesDelivery bool
+		// wantNotice is the auto-response sent to the sender instead of
+		// delivering the message. The message is delivered if empty.
+		wantNotice string
+	}{
+		{
+			name:   "away user receives message",
+			status: wire.ICQStatusModeAway,
+		},
+		{
+			name:   "N/A user receives message",
+			status: wire.ICQStatusModeNA,
+		},
+		{
+			name:   "free for chat user receives message",
+			status: wire.ICQStatusModeFreeForChat,
+		},
+		{
+			name:   "occupied user receives message when suppression is disabled",
+			status: wire.ICQStatusModeOccupied,
+		},
+		{
+			name:                       "occupied user doesn't receive message when suppression is enabled",
+			status:                     wire.ICQStatusModeOccupied,
+			occupiedSuppressesDelivery: true,
+			wantNotice:                 "User is occupied.",
+		},
+		{
+			name:       "DND user doesn't receive message",
+			status:     wire.ICQStatusModeDND,
+			wantNotice: "User is in do not disturb mode.",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("100001", sessOptUIN(100001))
+			recipient := newTestSession("100002", sessOptUIN(100002))
+			recipient.SetUserStatusBitmask(tc.status)
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+				Return(state.Relationship{}, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(recipient.IdentScreenName()).
+				Return(recipient)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantNotice == "" {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+			} else {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, sender.IdentScreenName(), mock.MatchedBy(func(msg wire.SNACMessage) bool {
+						body := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+						b, _ := body.Bytes(wire.ICBMTLVAOLIMData)
+						text, err := wire.UnmarshalICBMMessageText(b)
+						return err == nil && text == tc.wantNotice
+					}))
+			}
+
+			cfg := config.Config{
+				ICBMMaxSenderWarnLevel:        999,
+				ICBMMaxRecipientWarnLevel:     999,
+				ICQOccupiedSuppressesDelivery: tc.occupiedSuppressesDelivery,
+			}
+			messageFilter := state.NewMessageFilter("")
+			svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, messageFilter, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     1234,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: recipient.IdentScreenName().String(),
+			}
+			inBody.Append(wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags))
+
+			_, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_SelfMessage(t *testing.T) {
+	frags, err := wire.ICBMFragmentList("hello me")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// selfMessages is the ICBM_SELF_MESSAGES config value
+		selfMessages string
+		// wantRelayed indicates whether the message is relayed back to the
+		// sender
+		wantRelayed bool
+		// wantSubGroup is the subgroup of the SNAC returned to the sender
+		wantSubGroup uint16
+	}{
+		{
+			name:         "message to self is delivered",
+			selfMessages: config.SelfMessagesDeliver,
+			wantRelayed:  true,
+			wantSubGroup: wire.ICBMHostAck,
+		},
+		{
+			name:         "message to self is dropped",
+			selfMessages: config.SelfMessagesDrop,
+			wantRelayed:  false,
+			wantSubGroup: wire.ICBMHostAck,
+		},
+		{
+			name:         "message to self is rejected",
+			selfMessages: config.SelfMessagesError,
+			wantRelayed:  false,
+			wantSubGroup: wire.ICBMErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			sessionRetriever := newMockSessionRetriever(t)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantRelayed {
+				buddyListRetriever.EXPECT().
+					Relationship(sender.IdentScreenName(), sender.IdentScreenName()).
+					Return(state.Relationship{}, nil)
+				sessionRetriever.EXPECT().
+					RetrieveSession(sender.IdentScreenName()).
+					Return(sender)
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, sender.IdentScreenName(), mock.Anything)
+			}
+
+			cfg := config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+				ICBMSelfMessages:          tc.selfMessages,
+			}
+			messageFilter := state.NewMessageFilter("")
+			svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, messageFilter, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     1234,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: "UserA",
+			}
+			inBody.Append(wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags))
+			inBody.Append(wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}))
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantSubGroup, output.Frame.SubGroup)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_RendezvousCapability(t *testing.T) {
+	// a capability UUID for a game that isn't on the allowlist
+	capGame := [16]byte{0x09, 0x46, 0x13, 0x4A, 0x4C, 0x7F, 0x11, 0xD1, 0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00}
+
+	rendezvous := func(rdvType uint16, capability [16]byte) wire.SNAC_0x04_0x06_ICBMChannelMsgToHost {
+		inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     1234,
+			ChannelID:  wire.ICBMChannelRendezvous,
+			ScreenName: "userB",
+		}
+		inBody.Append(wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+			Type:       rdvType,
+			Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Capability: capability,
+		}))
+		return inBody
+	}
+
+	recipient := newTestSession("userB")
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// inBody is the rendezvous message sent by the sender
+		inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost
+		// wantRelayed indicates whether the message is relayed to the
+		// recipient
+		wantRelayed bool
+		// wantOutput is the SNAC returned to the sender
+		wantOutput *wire.SNACMessage
+	}{
+		{
+			name:        "file transfer proposal is allowed",
+			inBody:      rendezvous(wire.ICBMRdvMessagePropose, wire.CapFileTransfer),
+			wantRelayed: true,
+		},
+		{
+			name:   "game proposal is cancelled",
+			inBody: rendezvous(wire.ICBMRdvMessagePropose, capGame),
+			wantOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMChannelMsgToClient,
+				},
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					Cookie:      1234,
+					ChannelID:   wire.ICBMChannelRendezvous,
+					TLVUserInfo: recipient.TLVUserInfo(),
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Type:       wire.ICBMRdvMessageCancel,
+								Cookie:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+								Capability: capGame,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsNotAllowed),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "game cancellation is relayed",
+			inBody:      rendezvous(wire.ICBMRdvMessageCancel, capGame),
+			wantRelayed: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+				Return(state.Relationship{}, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(recipient.IdentScreenName()).
+				Return(recipient)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantRelayed {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+			}
+
+			svc := NewICBMService(config.Config{}, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil, nil,
+				[][16]byte{wire.CapFileTransfer, wire.CapChat}, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{}, tc.inBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantOutput, output)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_FileTransferConcurrency(t *testing.T) {
+	rendezvous := func(rdvType uint16, cookie [8]byte, tlvs ...wire.TLV) wire.SNAC_0x04_0x06_ICBMChannelMsgToHost {
+		inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     1234,
+			ChannelID:  wire.ICBMChannelRendezvous,
+			ScreenName: "userB",
+		}
+		inBody.Append(wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+			Type:       rdvType,
+			Cookie:     cookie,
+			Capability: wire.CapFileTransfer,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: tlvs,
+			},
+		}))
+		return inBody
+	}
+
+	activeCookie := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	newCookie := [8]byte{8, 7, 6, 5, 4, 3, 2, 1}
+	recipient := newTestSession("userB")
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// inBody is the rendezvous message sent by the sender
+		inBody wire.SNAC_0x04_0x06_ICBMChannelMsgToHost
+		// allowTransfer is the limiter's verdict on a proposal
+		allowTransfer bool
+		// wantStart indicates whether the transfer is checked against the caps
+		wantStart bool
+		// wantProxied indicates whether the transfer is started as relayed
+		// through the file transfer proxy
+		wantProxied bool
+		// wantAccept indicates whether the transfer is marked accepted
+		wantAccept bool
+		// wantEnd indicates whether the transfer stops being tracked
+		wantEnd bool
+		// wantRelayed indicates whether the message is relayed to the
+		// recipient
+		wantRelayed bool
+		// wantOutput is the SNAC returned to the sender
+		wantOutput *wire.SNACMessage
+	}{
+		{
+			name:          "proposal within the caps is relayed",
+			inBody:        rendezvous(wire.ICBMRdvMessagePropose, newCookie),
+			allowTransfer: true,
+			wantStart:     true,
+			wantRelayed:   true,
+		},
+		{
+			name:          "proposal past the caps is cancelled",
+			inBody:        rendezvous(wire.ICBMRdvMessagePropose, newCookie),
+			allowTransfer: false,
+			wantStart:     true,
+			wantOutput: &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMChannelMsgToClient,
+				},
+				Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+					Cookie:      1234,
+					ChannelID:   wire.ICBMChannelRendezvous,
+					TLVUserInfo: recipient.TLVUserInfo(),
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+								Type:       wire.ICBMRdvMessageCancel,
+								Cookie:     newCookie,
+								Capability: wire.CapFileTransfer,
+								TLVRestBlock: wire.TLVRestBlock{
+									TLVList: wire.TLVList{
+										wire.NewTLVBE(wire.ICBMRdvTLVTagsCancelReason, wire.ICBMRdvCancelReasonsTooManyTransfers),
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "proxied proposal within the caps is relayed",
+			inBody:        rendezvous(wire.ICBMRdvMessagePropose, newCookie, wire.NewTLVBE(wire.ICBMRdvTLVTagsRequestUseARS, []byte{})),
+			allowTransfer: true,
+			wantStart:     true,
+			wantProxied:   true,
+			wantRelayed:   true,
+		},
+		{
+			name:        "acceptance of an active transfer is relayed",
+			inBody:      rendezvous(wire.ICBMRdvMessageAccept, activeCookie),
+			wantAccept:  true,
+			wantRelayed: true,
+		},
+		{
+			name:        "cancellation of an active transfer ends it",
+			inBody:      rendezvous(wire.ICBMRdvMessageCancel, activeCookie),
+			wantEnd:     true,
+			wantRelayed: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+				Return(state.Relationship{}, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(recipient.IdentScreenName()).
+				Return(recipient)
+			fileTransferLimiter := newMockFileTransferLimiter(t)
+			if tc.wantStart {
+				fileTransferLimiter.EXPECT().
+					StartTransfer(newCookie, sender.IdentScreenName(), recipient.IdentScreenName(), tc.wantProxied).
+					Return(tc.allowTransfer)
+			}
+			if tc.wantAccept {
+				fileTransferLimiter.EXPECT().
+					AcceptTransfer(activeCookie)
 			}
-			sessionRetriever := newMockSessionRetriever(t)
-			for _, item := range tc.mockParams.sessionRetrieverParams.retrieveSessionParams {
-				sessionRetriever.EXPECT().
-					RetrieveSession(item.screenName).
-					Return(item.result)
+			if tc.wantEnd {
+				fileTransferLimiter.EXPECT().
+					EndTransfer(activeCookie)
 			}
 			messageRelayer := newMockMessageRelayer(t)
-			for _, item := range tc.mockParams.relayToScreenNameParams {
+			if tc.wantRelayed {
 				messageRelayer.EXPECT().
-					RelayToScreenName(mock.Anything, item.screenName, item.message)
-			}
-			offlineMessageManager := newMockOfflineMessageManager(t)
-			for _, params := range tc.mockParams.saveMessageParams {
-				offlineMessageManager.EXPECT().
-					SaveMessage(params.offlineMessageIn).
-					Return(params.err)
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
 			}
 
-			svc := ICBMService{
-				buddyListRetriever:  buddyListRetriever,
-				messageRelayer:      messageRelayer,
-				offlineMessageSaver: offlineMessageManager,
-				sessionRetriever:    sessionRetriever,
-				timeNow:             tc.timeNow,
+			cfg := config.Config{MaxFileTransfersPerUser: 1}
+			svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil, nil, nil,
+				fileTransferLimiter, nil, newNoAliasScreenNameResolver(t), nil)
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{}, tc.inBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantOutput, output)
+		})
+	}
+}
+
+// TestICBMService_ChannelMsgToHost_MalformedRendezvous verifies that
+// rendezvous messages that can't be parsed are relayed as-is, even when the
+// rendezvous checks are enabled.
+func TestICBMService_ChannelMsgToHost_MalformedRendezvous(t *testing.T) {
+	sender := newTestSession("userA")
+	recipient := newTestSession("userB")
+
+	inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		Cookie:     1234,
+		ChannelID:  wire.ICBMChannelRendezvous,
+		ScreenName: "userB",
+	}
+	inBody.Append(wire.NewTLVBE(wire.ICBMTLVData, []byte{0x00, 0x01, 0x02}))
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+		Return(state.Relationship{}, nil)
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(recipient.IdentScreenName()).
+		Return(recipient)
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+
+	cfg := config.Config{
+		MaxRendezvousFileSize:   1,
+		MaxFileTransfersPerUser: 1,
+	}
+	svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil, []uint16{4},
+		[][16]byte{wire.CapChat}, newMockFileTransferLimiter(t), nil, newNoAliasScreenNameResolver(t), nil)
+
+	output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{}, inBody)
+	assert.NoError(t, err)
+	assert.Nil(t, output)
+}
+
+// TestICBMService_ChannelMsgToHost_FileTransferCompleted verifies that a
+// direct file transfer that was accepted, and so ran to completion outside of
+// the server, doesn't stop the sender from proposing another one.
+func TestICBMService_ChannelMsgToHost_FileTransferCompleted(t *testing.T) {
+	rendezvous := func(to string, rdvType uint16, cookie [8]byte) wire.SNAC_0x04_0x06_ICBMChannelMsgToHost {
+		inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     1234,
+			ChannelID:  wire.ICBMChannelRendezvous,
+			ScreenName: to,
+		}
+		inBody.Append(wire.NewTLVBE(wire.ICBMTLVData, wire.ICBMCh2Fragment{
+			Type:       rdvType,
+			Cookie:     cookie,
+			Capability: wire.CapFileTransfer,
+		}))
+		return inBody
+	}
+
+	sender := newTestSession("userA")
+	recipient := newTestSession("userB")
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(mock.Anything, mock.Anything).
+		Return(state.Relationship{}, nil)
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(sender.IdentScreenName()).
+		Return(sender)
+	sessionRetriever.EXPECT().
+		RetrieveSession(recipient.IdentScreenName()).
+		Return(recipient)
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, mock.Anything, mock.Anything)
+
+	cfg := config.Config{MaxFileTransfersPerUser: 1}
+	svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, nil, nil, nil,
+		state.NewFileTransferLimiter(cfg.MaxFileTransfersPerUser, cfg.MaxFileTransfers), nil,
+		newNoAliasScreenNameResolver(t), nil)
+
+	firstCookie := [8]byte{1}
+	secondCookie := [8]byte{2}
+
+	// the sender proposes a transfer, which the recipient accepts
+	output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{}, rendezvous("userB", wire.ICBMRdvMessagePropose, firstCookie))
+	assert.NoError(t, err)
+	assert.Nil(t, output)
+	output, err = svc.ChannelMsgToHost(context.Background(), recipient, wire.SNACFrame{}, rendezvous("userA", wire.ICBMRdvMessageAccept, firstCookie))
+	assert.NoError(t, err)
+	assert.Nil(t, output)
+
+	// once the transfer is done, the sender proposes another one
+	output, err = svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{}, rendezvous("userB", wire.ICBMRdvMessagePropose, secondCookie))
+	assert.NoError(t, err)
+	assert.Nil(t, output)
+}
+
+func TestICBMService_ChannelMsgToHost_MultipleSessions(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	sender, err := sessionManager.AddSession(context.Background(), "userA")
+	require.NoError(t, err)
+	// the recipient is signed on from two places
+	firstRecipSess, err := sessionManager.AddSessionInstance(context.Background(), "userB")
+	require.NoError(t, err)
+	secondRecipSess, err := sessionManager.AddSessionInstance(context.Background(), "userB")
+	require.NoError(t, err)
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(sender.IdentScreenName(), state.NewIdentScreenName("userB")).
+		Return(state.Relationship{}, nil)
+
+	svc := NewICBMService(config.Config{}, sessionManager, nil, buddyListRetriever, sessionManager, nil, nil, nil, nil, nil,
+		newNoAliasScreenNameResolver(t), nil)
+
+	inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		Cookie:     1234,
+		ChannelID:  wire.ICBMChannelIM,
+		ScreenName: "userB",
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{1, 2, 3, 4}),
+				wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+			},
+		},
+	}
+	output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+	assert.NoError(t, err)
+
+	// the sender gets a single ack for both deliveries
+	if assert.NotNil(t, output) {
+		assert.Equal(t, wire.ICBMHostAck, output.Frame.SubGroup)
+	}
+
+	for _, recipSess := range []*state.Session{firstRecipSess, secondRecipSess} {
+		select {
+		case msg := <-recipSess.ReceiveMessage():
+			assert.Equal(t, wire.ICBMChannelMsgToClient, msg.Frame.SubGroup)
+			body, ok := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+			if assert.True(t, ok) {
+				assert.Equal(t, inBody.Cookie, body.Cookie)
+				assert.Equal(t, "userA", body.ScreenName)
 			}
+		default:
+			assert.Fail(t, "each of the recipient's sessions should receive the IM")
+		}
+	}
+}
 
-			outputSNAC, err := svc.ChannelMsgToHost(nil, tc.senderSession, tc.inputSNAC.Frame,
-				tc.inputSNAC.Body.(wire.SNAC_0x04_0x06_ICBMChannelMsgToHost))
+func TestParseCapabilityList(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    [][16]byte
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  nil,
+		},
+		{
+			name:  "upper and lower case UUIDs",
+			given: "09461343-4C7F-11D1-8222-444553540000, 748f2420-6287-11d1-8222-444553540000,",
+			want:  [][16]byte{wire.CapFileTransfer, wire.CapChat},
+		},
+		{
+			name:    "invalid UUID",
+			given:   "09461343-4C7F",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseCapabilityList(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
 			assert.NoError(t, err)
-			assert.Equal(t, tc.expectOutput, outputSNAC)
+			assert.Equal(t, tt.want, have)
 		})
 	}
 }
@@ -592,6 +2291,7 @@ func TestICBMService_ClientEvent(t *testing.T) {
 			svc := ICBMService{
 				buddyListRetriever: buddyListRetriever,
 				messageRelayer:     messageRelayer,
+				screenNameResolver: newNoAliasScreenNameResolver(t),
 			}
 			assert.NoError(t, svc.ClientEvent(nil, senderSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x04_0x14_ICBMClientEvent)))
@@ -1003,6 +2703,7 @@ func TestICBMService_EvilRequest(t *testing.T) {
 				buddyListRetriever:  buddyListRetriever,
 				messageRelayer:      messageRelayer,
 				offlineMessageSaver: offlineMessageManager,
+				screenNameResolver:  newNoAliasScreenNameResolver(t),
 				sessionRetriever:    sessionRetriever,
 			}
 
@@ -1014,8 +2715,135 @@ func TestICBMService_EvilRequest(t *testing.T) {
 	}
 }
 
+func TestICBMService_EvilRequest_AutoSuspend(t *testing.T) {
+	ctx := context.Background()
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	banList := state.NewBanList("")
+	autoSuspender := state.NewAutoSuspender(banList, 2, time.Hour, time.Hour)
+
+	sender, err := sessionManager.AddSession(ctx, "sender")
+	require.NoError(t, err)
+	recipient, err := sessionManager.AddSession(ctx, "recipient")
+	require.NoError(t, err)
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(state.NewIdentScreenName("sender"), state.NewIdentScreenName("recipient")).
+		Return(state.Relationship{User: state.NewIdentScreenName("recipient")}, nil)
+	buddyBroadcaster := newMockbuddyBroadcaster(t)
+	buddyBroadcaster.EXPECT().
+		BroadcastBuddyArrived(mock.Anything, matchSession(state.NewIdentScreenName("recipient"))).
+		Return(nil).
+		Once()
+
+	svc := ICBMService{
+		autoSuspender:      autoSuspender,
+		buddyBroadcaster:   buddyBroadcaster,
+		buddyListRetriever: buddyListRetriever,
+		cfg:                config.Config{AutoSuspendWarnThreshold: 2},
+		messageRelayer:     sessionManager,
+		screenNameResolver: newNoAliasScreenNameResolver(t),
+		sessionRetriever:   sessionManager,
+	}
+	inBody := wire.SNAC_0x04_0x08_ICBMEvilRequest{
+		SendAs:     1,
+		ScreenName: "recipient",
+	}
+
+	// the first warning is under the threshold
+	_, err = svc.EvilRequest(ctx, sender, wire.SNACFrame{}, inBody)
+	require.NoError(t, err)
+	select {
+	case <-recipient.Closed():
+		t.Fatal("recipient should still be signed on")
+	default:
+	}
+	assert.False(t, banList.Banned(state.NewIdentScreenName("recipient")))
+
+	// the second warning suspends and disconnects the recipient
+	outputSNAC, err := svc.EvilRequest(ctx, sender, wire.SNACFrame{}, inBody)
+	require.NoError(t, err)
+	assert.Equal(t, wire.ICBMEvilReply, outputSNAC.Frame.SubGroup)
+	select {
+	case <-recipient.Closed():
+	case <-time.After(time.Second):
+		t.Fatal("recipient should have been disconnected")
+	}
+	assert.True(t, banList.Banned(state.NewIdentScreenName("recipient")))
+}
+
+func TestWarningDecayer_Decay(t *testing.T) {
+	// warned user whose level decays by the full amount
+	warned := newTestSession("warned", sessOptSignonComplete, sessOptWarning(100))
+	// warned user whose level decays to zero
+	almostClear := newTestSession("almost-clear", sessOptSignonComplete, sessOptWarning(5))
+	// user who was never warned
+	notWarned := newTestSession("not-warned", sessOptSignonComplete)
+	// warned user who hasn't finished signing on
+	signingOn := newTestSession("signing-on", sessOptWarning(100))
+	// warned user who is invisible to their buddies
+	invisible := newTestSession("invisible", sessOptSignonComplete, sessOptWarning(100), sessOptInvisible)
+	// warned user whose buddies can't be notified
+	broadcastFails := newTestSession("broadcast-fails", sessOptSignonComplete, sessOptWarning(100))
+
+	sessionLister := newMockSessionLister(t)
+	sessionLister.EXPECT().
+		AllSessions().
+		Return([]*state.Session{broadcastFails, warned, almostClear, notWarned, signingOn, invisible})
+
+	messageRelayer := newMockMessageRelayer(t)
+	for _, sess := range []*state.Session{broadcastFails, warned, almostClear, invisible} {
+		messageRelayer.EXPECT().
+			RelayToScreenName(mock.Anything, sess.IdentScreenName(), mock.Anything).
+			Run(func(ctx context.Context, screenName state.IdentScreenName, msg wire.SNACMessage) {
+				assert.Equal(t, wire.SNACMessage{
+					Frame: wire.SNACFrame{
+						FoodGroup: wire.OService,
+						SubGroup:  wire.OServiceUserInfoUpdate,
+					},
+					Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+						TLVUserInfo: sess.TLVUserInfo(),
+					},
+				}, msg)
+			})
+	}
+
+	buddyBroadcaster := newMockbuddyBroadcaster(t)
+	buddyBroadcaster.EXPECT().
+		BroadcastBuddyArrived(mock.Anything, broadcastFails).
+		Return(errors.New("broadcast failed"))
+	buddyBroadcaster.EXPECT().
+		BroadcastBuddyArrived(mock.Anything, warned).
+		Return(nil)
+	buddyBroadcaster.EXPECT().
+		BroadcastBuddyArrived(mock.Anything, almostClear).
+		Return(nil)
+
+	decayer := WarningDecayer{
+		buddyBroadcaster: buddyBroadcaster,
+		cfg:              config.Config{ICBMWarnDecayAmount: 10},
+		logger:           slog.Default(),
+		messageRelayer:   messageRelayer,
+		sessionLister:    sessionLister,
+	}
+
+	decayer.Decay(context.Background())
+	assert.Equal(t, uint16(90), broadcastFails.Warning())
+	assert.Equal(t, uint16(90), warned.Warning())
+	assert.Equal(t, uint16(0), almostClear.Warning())
+	assert.Equal(t, uint16(0), notWarned.Warning())
+	assert.Equal(t, uint16(100), signingOn.Warning())
+	assert.Equal(t, uint16(90), invisible.Warning())
+}
+
 func TestICBMService_ParameterQuery(t *testing.T) {
-	svc := NewICBMService(nil, nil, nil, nil)
+	cfg := config.Config{
+		ICBMMaxMessageLen:         1024,
+		ICBMMaxSenderWarnLevel:    500,
+		ICBMMaxRecipientWarnLevel: 800,
+		ICBMMinMessageIntervalMs:  2000,
+	}
+	svc := NewICBMService(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	have := svc.ParameterQuery(nil, wire.SNACFrame{RequestID: 1234})
 	want := wire.SNACMessage{
@@ -1027,16 +2855,24 @@ func TestICBMService_ParameterQuery(t *testing.T) {
 		Body: wire.SNAC_0x04_0x05_ICBMParameterReply{
 			MaxSlots:             100,
 			ICBMFlags:            3,
-			MaxIncomingICBMLen:   512,
-			MaxSourceEvil:        999,
-			MaxDestinationEvil:   999,
-			MinInterICBMInterval: 0,
+			MaxIncomingICBMLen:   cfg.ICBMMaxMessageLen,
+			MaxSourceEvil:        cfg.ICBMMaxSenderWarnLevel,
+			MaxDestinationEvil:   cfg.ICBMMaxRecipientWarnLevel,
+			MinInterICBMInterval: cfg.ICBMMinMessageIntervalMs,
 		},
 	}
 
 	assert.Equal(t, want, have)
 }
 
+func TestICBMService_ParameterQuery_NoMessageLenLimit(t *testing.T) {
+	svc := NewICBMService(config.Config{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	have := svc.ParameterQuery(nil, wire.SNACFrame{})
+	body := have.Body.(wire.SNAC_0x04_0x05_ICBMParameterReply)
+	assert.Equal(t, uint16(512), body.MaxIncomingICBMLen)
+}
+
 func TestICBMService_ClientErr(t *testing.T) {
 	sess := newTestSession("theScreenName")
 
@@ -1067,8 +2903,424 @@ func TestICBMService_ClientErr(t *testing.T) {
 	messageRelayer.EXPECT().
 		RelayToScreenName(mock.Anything, state.NewIdentScreenName("recipientScreenName"), expect)
 
-	svc := NewICBMService(messageRelayer, nil, nil, nil)
+	svc := NewICBMService(config.Config{}, messageRelayer, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	err := svc.ClientErr(nil, sess, wire.SNACFrame{RequestID: 1234}, inBody)
 	assert.NoError(t, err)
 }
+
+func TestICBMService_ChannelMsgToHost_UnconfirmedAccount(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// restrict is the RESTRICT_UNCONFIRMED_ACCOUNTS config value
+		restrict bool
+		// unconfirmed indicates whether the sender's account is unconfirmed
+		unconfirmed bool
+		// wantRelayed indicates whether the message is relayed to the
+		// recipient
+		wantRelayed bool
+	}{
+		{
+			name:        "unconfirmed sender is refused when restricted",
+			restrict:    true,
+			unconfirmed: true,
+			wantRelayed: false,
+		},
+		{
+			name:        "confirmed sender is relayed when restricted",
+			restrict:    true,
+			unconfirmed: false,
+			wantRelayed: true,
+		},
+		{
+			name:        "unconfirmed sender is relayed when not restricted",
+			restrict:    false,
+			unconfirmed: true,
+			wantRelayed: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+			if tc.unconfirmed {
+				sender.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+			}
+			recipient := newTestSession("userB")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			sessionRetriever := newMockSessionRetriever(t)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantRelayed {
+				buddyListRetriever.EXPECT().
+					Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+					Return(state.Relationship{}, nil)
+				sessionRetriever.EXPECT().
+					RetrieveSession(recipient.IdentScreenName()).
+					Return(recipient)
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+			}
+
+			cfg := config.Config{
+				ICBMMaxSenderWarnLevel:      999,
+				ICBMMaxRecipientWarnLevel:   999,
+				RestrictUnconfirmedAccounts: tc.restrict,
+			}
+			messageFilter := state.NewMessageFilter("")
+			svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, messageFilter, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     1234,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: recipient.IdentScreenName().String(),
+			}
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+			assert.NoError(t, err)
+			if tc.wantRelayed {
+				assert.Nil(t, output)
+			} else {
+				assert.Equal(t, &wire.SNACMessage{
+					Frame: wire.SNACFrame{
+						FoodGroup: wire.ICBM,
+						SubGroup:  wire.ICBMErr,
+						RequestID: 1,
+					},
+					Body: wire.SNACError{
+						Code: wire.ErrorCodeInsufficientRights,
+					},
+				}, output)
+			}
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_GroupPermitMask(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// sender is the session of the user sending the message
+		sender *state.Session
+		// recipient is the session of the user receiving the message
+		recipient *state.Session
+		// wantErrCode is the error code returned to the sender, or 0 if the
+		// message is relayed
+		wantErrCode uint16
+	}{
+		{
+			name:      "default masks permit all users",
+			sender:    newTestSession("userA"),
+			recipient: newTestSession("userB"),
+		},
+		{
+			name:      "recipient permits AIM users",
+			sender:    newTestSession("userA"),
+			recipient: newTestSession("userB", sessOptPermitMask(wire.OServiceUserFlagOSCARFree)),
+		},
+		{
+			name:        "recipient permits ICQ users only",
+			sender:      newTestSession("userA"),
+			recipient:   newTestSession("userB", sessOptPermitMask(wire.OServiceUserFlagICQ)),
+			wantErrCode: wire.ErrorCodeNotLoggedOn,
+		},
+		{
+			name:      "recipient permits ICQ users only, sender is ICQ user",
+			sender:    newTestSession("100001", sessOptUIN(100001), func(session *state.Session) { session.SetUserInfoFlag(wire.OServiceUserFlagICQ) }),
+			recipient: newTestSession("userB", sessOptPermitMask(wire.OServiceUserFlagICQ)),
+		},
+		{
+			name:        "recipient permits AOL users only",
+			sender:      newTestSession("userA"),
+			recipient:   newTestSession("userB", sessOptPermitMask(wire.OServiceUserFlagAOL)),
+			wantErrCode: wire.ErrorCodeNotLoggedOn,
+		},
+		{
+			name:      "recipient permits unconfirmed users, sender is unconfirmed",
+			sender:    newTestSession("userA", sessOptUnconfirmed),
+			recipient: newTestSession("userB", sessOptPermitMask(wire.OServiceUserFlagUnconfirmed)),
+		},
+		{
+			name:        "recipient permits no users",
+			sender:      newTestSession("userA"),
+			recipient:   newTestSession("userB", sessOptPermitMask(0)),
+			wantErrCode: wire.ErrorCodeNotLoggedOn,
+		},
+		{
+			name:        "sender doesn't permit recipient's class",
+			sender:      newTestSession("userA", sessOptPermitMask(wire.OServiceUserFlagAOL)),
+			recipient:   newTestSession("userB"),
+			wantErrCode: wire.ErrorCodeInLocalPermitDeny,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(tc.sender.IdentScreenName(), tc.recipient.IdentScreenName()).
+				Return(state.Relationship{}, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(tc.recipient.IdentScreenName()).
+				Return(tc.recipient)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantErrCode == 0 {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, tc.recipient.IdentScreenName(), mock.Anything)
+			}
+
+			cfg := config.Config{
+				ICBMMaxSenderWarnLevel:    999,
+				ICBMMaxRecipientWarnLevel: 999,
+			}
+			messageFilter := state.NewMessageFilter("")
+			svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, messageFilter, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+
+			inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     1234,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: tc.recipient.IdentScreenName().String(),
+			}
+
+			output, err := svc.ChannelMsgToHost(context.Background(), tc.sender, wire.SNACFrame{RequestID: 1}, inBody)
+			assert.NoError(t, err)
+			if tc.wantErrCode == 0 {
+				assert.Nil(t, output)
+				return
+			}
+			assert.Equal(t, &wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.ICBM,
+					SubGroup:  wire.ICBMErr,
+					RequestID: 1,
+				},
+				Body: wire.SNACError{
+					Code: tc.wantErrCode,
+				},
+			}, output)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_NewbieRestriction(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// accountCreatedAt is when the sender's account was created
+		accountCreatedAt time.Time
+		// now is the time the message is sent
+		now time.Time
+		// relationship is the sender's relationship with the recipient
+		relationship state.Relationship
+		// wantErrCode is the error code returned to the sender, or 0 if the
+		// message is relayed
+		wantErrCode uint16
+	}{
+		{
+			name:             "brand-new account can't message a non-buddy",
+			cfg:              config.Config{NewbieRestrictionMin: 10},
+			accountCreatedAt: createdAt,
+			now:              createdAt.Add(time.Minute),
+			wantErrCode:      wire.ErrorCodeInsufficientRights,
+		},
+		{
+			name:             "brand-new account can message a buddy",
+			cfg:              config.Config{NewbieRestrictionMin: 10},
+			accountCreatedAt: createdAt,
+			now:              createdAt.Add(time.Minute),
+			relationship:     state.Relationship{IsOnYourList: true},
+		},
+		{
+			name:             "restriction lifts once the period passes",
+			cfg:              config.Config{NewbieRestrictionMin: 10},
+			accountCreatedAt: createdAt,
+			now:              createdAt.Add(10 * time.Minute),
+		},
+		{
+			name: "account with unknown creation time isn't restricted",
+			cfg:  config.Config{NewbieRestrictionMin: 10},
+			now:  createdAt.Add(time.Minute),
+		},
+		{
+			name:             "restriction disabled",
+			accountCreatedAt: createdAt,
+			now:              createdAt.Add(time.Minute),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := newTestSession("userA")
+			sender.SetAccountCreatedAt(tc.accountCreatedAt)
+			recipient := newTestSession("userB")
+
+			buddyListRetriever := newMockBuddyListRetriever(t)
+			buddyListRetriever.EXPECT().
+				Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+				Return(tc.relationship, nil)
+			sessionRetriever := newMockSessionRetriever(t)
+			messageRelayer := newMockMessageRelayer(t)
+			if tc.wantErrCode == 0 {
+				sessionRetriever.EXPECT().
+					RetrieveSession(recipient.IdentScreenName()).
+					Return(recipient)
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+			}
+
+			tc.cfg.ICBMMaxSenderWarnLevel = 999
+			tc.cfg.ICBMMaxRecipientWarnLevel = 999
+			messageFilter := state.NewMessageFilter("")
+			svc := NewICBMService(tc.cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, messageFilter, nil, nil, nil, nil, newNoAliasScreenNameResolver(t), nil)
+			svc.timeNow = func() time.Time { return tc.now }
+
+			inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+				Cookie:     1234,
+				ChannelID:  wire.ICBMChannelIM,
+				ScreenName: recipient.IdentScreenName().String(),
+			}
+
+			output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+			assert.NoError(t, err)
+			if tc.wantErrCode == 0 {
+				assert.Nil(t, output)
+				return
+			}
+			assert.Equal(t, newICBMErr(1, tc.wantErrCode), output)
+		})
+	}
+}
+
+func TestICBMService_ChannelMsgToHost_Alias(t *testing.T) {
+	sender := newTestSession("sender")
+	recipient := newTestSession("canonical")
+
+	screenNameResolver := newMockScreenNameResolver(t)
+	screenNameResolver.EXPECT().
+		CanonicalScreenName(state.NewIdentScreenName("the alias")).
+		Return(recipient.IdentScreenName(), nil)
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+		Return(state.Relationship{}, nil)
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(recipient.IdentScreenName()).
+		Return(recipient)
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+
+	cfg := config.Config{
+		ICBMMaxSenderWarnLevel:    999,
+		ICBMMaxRecipientWarnLevel: 999,
+	}
+	svc := NewICBMService(cfg, messageRelayer, nil, buddyListRetriever, sessionRetriever, state.NewMessageFilter(""), nil, nil, nil, nil, screenNameResolver, nil)
+
+	// the message is addressed to the alias, but the canonical account's
+	// session receives it
+	inBody := wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+		Cookie:     1234,
+		ChannelID:  wire.ICBMChannelIM,
+		ScreenName: "The Alias",
+	}
+	output, err := svc.ChannelMsgToHost(context.Background(), sender, wire.SNACFrame{RequestID: 1}, inBody)
+	assert.NoError(t, err)
+	assert.Nil(t, output)
+}
+
+func TestICBMService_ClientEvent_Alias(t *testing.T) {
+	sender := newTestSession("sender")
+	recipient := newTestSession("canonical")
+
+	screenNameResolver := newMockScreenNameResolver(t)
+	screenNameResolver.EXPECT().
+		CanonicalScreenName(state.NewIdentScreenName("the alias")).
+		Return(recipient.IdentScreenName(), nil)
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+		Return(state.Relationship{}, nil)
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+
+	svc := ICBMService{
+		buddyListRetriever: buddyListRetriever,
+		messageRelayer:     messageRelayer,
+		screenNameResolver: screenNameResolver,
+	}
+
+	// the typing event is addressed to the alias, but the canonical
+	// account's session receives it
+	inBody := wire.SNAC_0x04_0x14_ICBMClientEvent{
+		Cookie:     1234,
+		ChannelID:  wire.ICBMChannelIM,
+		ScreenName: "The Alias",
+		Event:      2,
+	}
+	assert.NoError(t, svc.ClientEvent(context.Background(), sender, wire.SNACFrame{}, inBody))
+}
+
+func TestICBMService_EvilRequest_Alias(t *testing.T) {
+	sender := newTestSession("sender")
+	recipient := newTestSession("canonical")
+
+	screenNameResolver := newMockScreenNameResolver(t)
+	screenNameResolver.EXPECT().
+		CanonicalScreenName(state.NewIdentScreenName("the alias")).
+		Return(recipient.IdentScreenName(), nil)
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		Relationship(sender.IdentScreenName(), recipient.IdentScreenName()).
+		Return(state.Relationship{}, nil)
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(recipient.IdentScreenName()).
+		Return(recipient)
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().
+		RelayToScreenName(mock.Anything, recipient.IdentScreenName(), mock.Anything)
+	buddyBroadcaster := newMockbuddyBroadcaster(t)
+	buddyBroadcaster.EXPECT().
+		BroadcastBuddyArrived(mock.Anything, recipient).
+		Return(nil)
+
+	svc := ICBMService{
+		buddyBroadcaster:   buddyBroadcaster,
+		buddyListRetriever: buddyListRetriever,
+		messageRelayer:     messageRelayer,
+		screenNameResolver: screenNameResolver,
+		sessionRetriever:   sessionRetriever,
+	}
+
+	// the warning is addressed to the alias, but the canonical account is
+	// warned
+	inBody := wire.SNAC_0x04_0x08_ICBMEvilRequest{
+		SendAs:     1,
+		ScreenName: "The Alias",
+	}
+	output, err := svc.EvilRequest(context.Background(), sender, wire.SNACFrame{}, inBody)
+	assert.NoError(t, err)
+	assert.Equal(t, wire.ICBMEvilReply, output.Frame.SubGroup)
+	assert.Equal(t, evilDeltaAnon, recipient.Warning())
+}
+
+// newNoAliasScreenNameResolver returns a ScreenNameResolver for tests that
+// don't involve aliases. Every screen name resolves to itself.
+func newNoAliasScreenNameResolver(t *testing.T) *mockScreenNameResolver {
+	resolver := newMockScreenNameResolver(t)
+	resolver.EXPECT().
+		CanonicalScreenName(mock.Anything).
+		RunAndReturn(func(screenName state.IdentScreenName) (state.IdentScreenName, error) {
+			return screenName, nil
+		}).
+		Maybe()
+	return resolver
+}
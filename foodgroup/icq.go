@@ -3,6 +3,7 @@ package foodgroup
 import (
 	"bytes"
 	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -16,6 +17,11 @@ import (
 
 var errICQBadRequest = errors.New("bad ICQ request")
 
+// icqSearchResultLimit is the maximum number of users returned by a
+// white-pages search. The number of matches left out is reported to the
+// client in the last search result.
+const icqSearchResultLimit = 40
+
 // NewICQService creates an instance of ICQService.
 func NewICQService(
 	messageRelayer MessageRelayer,
@@ -24,6 +30,7 @@ func NewICQService(
 	logger *slog.Logger,
 	sessionRetriever SessionRetriever,
 	offlineMessageManager OfflineMessageManager,
+	xmlKeys map[string]string,
 ) ICQService {
 	return ICQService{
 		messageRelayer:        messageRelayer,
@@ -33,6 +40,7 @@ func NewICQService(
 		sessionRetriever:      sessionRetriever,
 		offlineMessageManager: offlineMessageManager,
 		timeNow:               time.Now,
+		xmlKeys:               xmlKeys,
 	}
 }
 
@@ -45,6 +53,9 @@ type ICQService struct {
 	userUpdater           ICQUserUpdater
 	timeNow               func() time.Time
 	offlineMessageManager OfflineMessageManager
+	// xmlKeys holds the values of server settings that clients request by
+	// key over the XML request channel
+	xmlKeys map[string]string
 }
 
 func (s ICQService) DeleteMsgReq(ctx context.Context, sess *state.Session, seq uint16) error {
@@ -65,7 +76,7 @@ func (s ICQService) FindByICQName(ctx context.Context, sess *state.Session, req
 		ReqSubType: wire.ICQDBQueryMetaReplyLastUserFound,
 	}
 
-	res, err := s.userFinder.FindByICQName(req.FirstName, req.LastName, req.NickName)
+	res, total, err := s.userFinder.FindByICQName(req.FirstName, req.LastName, req.NickName, icqSearchResultLimit)
 
 	if err != nil {
 		s.logger.Error("FindByICQName failed", "err", err.Error())
@@ -84,6 +95,7 @@ func (s ICQService) FindByICQName(ctx context.Context, sess *state.Session, req
 	for i := 0; i < len(res); i++ {
 		if i == len(res)-1 {
 			resp.LastResult()
+			resp.LastMessageFooter.FoundUsersLeft = uint32(total - len(res))
 		} else {
 			resp.ReqSubType = wire.ICQDBQueryMetaReplyUserFound
 		}
@@ -180,7 +192,7 @@ func (s ICQService) FindByICQInterests(ctx context.Context, sess *state.Session,
 	}
 
 	interests := strings.Split(req.InterestsKeyword, ",")
-	res, err := s.userFinder.FindByICQInterests(req.InterestsCode, interests)
+	res, total, err := s.userFinder.FindByICQInterests(req.InterestsCode, interests, icqSearchResultLimit)
 
 	if err != nil {
 		s.logger.Error("FindByICQInterests failed", "err", err.Error())
@@ -199,6 +211,7 @@ func (s ICQService) FindByICQInterests(ctx context.Context, sess *state.Session,
 	for i := 0; i < len(res); i++ {
 		if i == len(res)-1 {
 			resp.LastResult()
+			resp.LastMessageFooter.FoundUsersLeft = uint32(total - len(res))
 		} else {
 			resp.ReqSubType = wire.ICQDBQueryMetaReplyUserFound
 		}
@@ -215,13 +228,13 @@ func (s ICQService) FindByICQInterests(ctx context.Context, sess *state.Session,
 
 func (s ICQService) FindByWhitePages2(ctx context.Context, sess *state.Session, req wire.ICQ_0x07D0_0x055F_DBQueryMetaReqSearchWhitePages2, seq uint16) error {
 
-	users, err := func() ([]state.User, error) {
+	users, total, err := func() ([]state.User, int, error) {
 		if keyword, hasKeyword := req.ICQString(wire.ICQTLVTagsWhitepagesSearchKeywords); hasKeyword {
-			res, err := s.userFinder.FindByICQKeyword(keyword)
+			res, total, err := s.userFinder.FindByICQKeyword(keyword, icqSearchResultLimit)
 			if err != nil {
-				return nil, fmt.Errorf("FindByICQKeyword failed: %w", err)
+				return nil, 0, fmt.Errorf("FindByICQKeyword failed: %w", err)
 			}
-			return res, nil
+			return res, total, nil
 		}
 
 		bNick, hasNick := req.ICQString(wire.ICQTLVTagsNickname)
@@ -229,14 +242,14 @@ func (s ICQService) FindByWhitePages2(ctx context.Context, sess *state.Session,
 		bLast, hastLast := req.ICQString(wire.ICQTLVTagsLastName)
 
 		if hasNick || hasFirst || hastLast {
-			res, err := s.userFinder.FindByICQName(bFirst, bLast, bNick)
+			res, total, err := s.userFinder.FindByICQName(bFirst, bLast, bNick, icqSearchResultLimit)
 			if err != nil {
-				return nil, fmt.Errorf("FindByICQName failed: %w", err)
+				return nil, 0, fmt.Errorf("FindByICQName failed: %w", err)
 			}
-			return res, nil
+			return res, total, nil
 		}
 
-		return nil, nil
+		return nil, 0, nil
 	}()
 
 	resp := wire.ICQ_0x07DA_0x01AE_DBQueryMetaReplyLastUserFound{
@@ -267,6 +280,7 @@ func (s ICQService) FindByWhitePages2(ctx context.Context, sess *state.Session,
 	for i := 0; i < len(users); i++ {
 		if i == len(users)-1 {
 			resp.LastResult()
+			resp.LastMessageFooter.FoundUsersLeft = uint32(total - len(users))
 		} else {
 			resp.ReqSubType = wire.ICQDBQueryMetaReplyUserFound
 		}
@@ -560,7 +574,15 @@ func (s ICQService) SetMoreInfo(ctx context.Context, sess *state.Session, req wi
 }
 
 func (s ICQService) SetPermissions(ctx context.Context, sess *state.Session, req wire.ICQ_0x07D0_0x0424_DBQueryMetaReqSetPermissions, seq uint16) error {
-	s.logger.Debug("setting permissions is not yet supported")
+	u := state.ICQPermissions{
+		AuthRequired: req.Authorization == 1,
+		WebAware:     req.WebAware == 1,
+	}
+
+	if err := s.userUpdater.SetPermissions(sess.IdentScreenName(), u); err != nil {
+		return err
+	}
+
 	return s.reqAck(ctx, sess, seq, wire.ICQDBQueryMetaReplySetPermissions)
 }
 
@@ -630,21 +652,99 @@ func (s ICQService) ShortUserInfo(ctx context.Context, sess *state.Session, req
 	return s.reply(ctx, sess, msg)
 }
 
+// icqXMLRequest is a request sent over the ICQ XML request channel, such as
+// <key>DataFilesIP</key>. The root element names the request type.
+type icqXMLRequest struct {
+	XMLName xml.Name
+	Body    string `xml:",chardata"`
+}
+
+// icqXMLHandlers answers XML requests, keyed by request type. Each handler
+// receives the request body and returns the XML response, or false if it has
+// no answer for the request. Support for new request types is added here.
+var icqXMLHandlers = map[string]func(s ICQService, body string) (string, bool){
+	"key": ICQService.xmlKey,
+}
+
+// XMLReqData answers a request sent over the ICQ XML request channel. Requests
+// that are malformed, of an unsupported type, or that the server has no answer
+// for get an empty failure reply, which clients treat as no data.
 func (s ICQService) XMLReqData(ctx context.Context, sess *state.Session, req wire.ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq, seq uint16) error {
-	msg := wire.ICQMessageReplyEnvelope{
-		Message: wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{
-			ICQMetadata: wire.ICQMetadata{
-				UIN:     sess.UIN(),
-				ReqType: wire.ICQDBQueryMetaReply,
-				Seq:     seq,
-			},
-			ReqSubType: wire.ICQDBQueryMetaReplyXMLData,
-			Success:    wire.ICQStatusCodeFail,
+	reply := wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{
+		ICQMetadata: wire.ICQMetadata{
+			UIN:     sess.UIN(),
+			ReqType: wire.ICQDBQueryMetaReply,
+			Seq:     seq,
 		},
+		ReqSubType: wire.ICQDBQueryMetaReplyXMLData,
+		Success:    wire.ICQStatusCodeFail,
+	}
+	if resp, ok := s.xmlResponse(ctx, req.XMLRequest); ok {
+		reply.Success = wire.ICQStatusCodeOK
+		reply.XML = resp
+	}
+
+	msg := wire.ICQMessageReplyEnvelope{
+		Message: reply,
 	}
 	return s.reply(ctx, sess, msg)
 }
 
+// xmlResponse returns the XML response to an XML request, or false if the
+// server has no answer for it.
+func (s ICQService) xmlResponse(ctx context.Context, xmlReq string) (string, bool) {
+	req := icqXMLRequest{}
+	if err := xml.Unmarshal([]byte(xmlReq), &req); err != nil {
+		s.logger.DebugContext(ctx, "unable to parse ICQ XML request", "xml", xmlReq, "err", err.Error())
+		return "", false
+	}
+	handler, ok := icqXMLHandlers[req.XMLName.Local]
+	if !ok {
+		s.logger.DebugContext(ctx, "unsupported ICQ XML request", "xml", xmlReq)
+		return "", false
+	}
+	return handler(s, strings.TrimSpace(req.Body))
+}
+
+// xmlKey answers a request for the server setting named by key, such as
+// DataFilesIP, with <value>setting</value>.
+func (s ICQService) xmlKey(key string) (string, bool) {
+	value, ok := s.xmlKeys[key]
+	if !ok {
+		return "", false
+	}
+	b := &strings.Builder{}
+	b.WriteString("<value>")
+	if err := xml.EscapeText(b, []byte(value)); err != nil {
+		return "", false
+	}
+	b.WriteString("</value>")
+	return b.String(), true
+}
+
+// ParseICQXMLKeys parses a comma-separated list of key:value pairs, such as
+// "DataFilesIP:127.0.0.1,BannersIP:127.0.0.1". Values may contain colons. An
+// empty string yields an empty map.
+func ParseICQXMLKeys(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("XML key %q must be in key:value format", entry)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("XML key %q is missing a key name", entry)
+		}
+		keys[key] = strings.TrimSpace(value)
+	}
+	return keys, nil
+}
+
 func (s ICQService) affiliations(ctx context.Context, sess *state.Session, user state.User, seq uint16) error {
 	msg := wire.ICQMessageReplyEnvelope{
 		Message: wire.ICQ_0x07DA_0x00FA_DBQueryMetaReplyAffiliations{
@@ -45,7 +45,7 @@ func TestICQService_DeleteMsgReq(t *testing.T) {
 					Return(params.err)
 			}
 
-			s := NewICQService(nil, nil, nil, slog.Default(), nil, offlineMessageManager)
+			s := NewICQService(nil, nil, nil, slog.Default(), nil, offlineMessageManager, nil)
 			err := s.DeleteMsgReq(nil, tt.sess, tt.seq)
 			assert.NoError(t, err)
 		})
@@ -119,6 +119,7 @@ func TestICQService_FindByICQName(t *testing.T) {
 									},
 								},
 							},
+							total: 2,
 						},
 					},
 				},
@@ -225,8 +226,8 @@ func TestICQService_FindByICQName(t *testing.T) {
 			userFinder := newMockICQUserFinder(t)
 			for _, params := range tt.mockParams.findByDetailsParams {
 				userFinder.EXPECT().
-					FindByICQName(params.firstName, params.lastName, params.nickName).
-					Return(params.result, params.err)
+					FindByICQName(params.firstName, params.lastName, params.nickName, icqSearchResultLimit).
+					Return(params.result, params.total, params.err)
 			}
 
 			messageRelayer := newMockMessageRelayer(t)
@@ -253,6 +254,75 @@ func TestICQService_FindByICQName(t *testing.T) {
 	}
 }
 
+func TestICQService_FindByICQName_FoundUsersLeft(t *testing.T) {
+	sess := newTestSession("11111111", sessOptUIN(11111111))
+
+	userFinder := newMockICQUserFinder(t)
+	userFinder.EXPECT().
+		FindByICQName("John", "", "", icqSearchResultLimit).
+		Return([]state.User{
+			{
+				IdentScreenName: state.NewIdentScreenName("123456789"),
+				ICQBasicInfo: state.ICQBasicInfo{
+					FirstName: "John",
+				},
+			},
+		}, 41, nil)
+
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		RetrieveSession(state.NewIdentScreenName("123456789")).
+		Return(nil)
+
+	// the last result reports the 40 matches that were left out
+	messageRelayer := newMockMessageRelayer(t)
+	messageRelayer.EXPECT().RelayToScreenName(mock.Anything, sess.IdentScreenName(), wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICQ,
+			SubGroup:  wire.ICQDBReply,
+		},
+		Body: wire.SNAC_0x15_0x02_DBReply{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICQTLVTagsMetadata, wire.ICQMessageReplyEnvelope{
+						Message: wire.ICQ_0x07DA_0x01AE_DBQueryMetaReplyLastUserFound{
+							ICQMetadata: wire.ICQMetadata{
+								UIN:     11111111,
+								ReqType: wire.ICQDBQueryMetaReply,
+								Seq:     1,
+							},
+							Success:    wire.ICQStatusCodeOK,
+							ReqSubType: wire.ICQDBQueryMetaReplyLastUserFound,
+							Details: wire.ICQUserSearchRecord{
+								UIN:       123456789,
+								FirstName: "John",
+							},
+							LastMessageFooter: &struct {
+								FoundUsersLeft uint32
+							}{
+								FoundUsersLeft: 40,
+							},
+						},
+					}),
+				},
+			},
+		},
+	})
+
+	s := ICQService{
+		messageRelayer:   messageRelayer,
+		sessionRetriever: sessionRetriever,
+		timeNow: func() time.Time {
+			return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+		},
+		userFinder: userFinder,
+	}
+	req := wire.ICQ_0x07D0_0x0515_DBQueryMetaReqSearchByDetails{
+		FirstName: "John",
+	}
+	assert.NoError(t, s.FindByICQName(nil, sess, req, 1))
+}
+
 func TestICQService_FindByICQEmail(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -872,6 +942,7 @@ func TestICQService_FindByWhitePages(t *testing.T) {
 									},
 								},
 							},
+							total: 2,
 						},
 					},
 				},
@@ -978,8 +1049,8 @@ func TestICQService_FindByWhitePages(t *testing.T) {
 			userFinder := newMockICQUserFinder(t)
 			for _, params := range tt.mockParams.findByInterestsParams {
 				userFinder.EXPECT().
-					FindByICQInterests(params.code, params.keywords).
-					Return(params.result, params.err)
+					FindByICQInterests(params.code, params.keywords, icqSearchResultLimit).
+					Return(params.result, params.total, params.err)
 			}
 
 			messageRelayer := newMockMessageRelayer(t)
@@ -1077,6 +1148,7 @@ func TestICQService_FindByWhitePages2(t *testing.T) {
 									},
 								},
 							},
+							total: 2,
 						},
 					},
 				},
@@ -1232,6 +1304,7 @@ func TestICQService_FindByWhitePages2(t *testing.T) {
 									},
 								},
 							},
+							total: 1,
 						},
 					},
 				},
@@ -1297,13 +1370,13 @@ func TestICQService_FindByWhitePages2(t *testing.T) {
 			userFinder := newMockICQUserFinder(t)
 			for _, params := range tt.mockParams.findByKeywordParams {
 				userFinder.EXPECT().
-					FindByICQKeyword(params.keyword).
-					Return(params.result, params.err)
+					FindByICQKeyword(params.keyword, icqSearchResultLimit).
+					Return(params.result, params.total, params.err)
 			}
 			for _, params := range tt.mockParams.findByDetailsParams {
 				userFinder.EXPECT().
-					FindByICQName(params.firstName, params.lastName, params.nickName).
-					Return(params.result, params.err)
+					FindByICQName(params.firstName, params.lastName, params.nickName, icqSearchResultLimit).
+					Return(params.result, params.total, params.err)
 			}
 
 			messageRelayer := newMockMessageRelayer(t)
@@ -1957,7 +2030,7 @@ func TestICQService_OfflineMsgReq(t *testing.T) {
 				messageRelayer.EXPECT().RelayToScreenName(mock.Anything, params.screenName, params.message)
 			}
 
-			s := NewICQService(messageRelayer, nil, nil, slog.Default(), nil, offlineMessageManager)
+			s := NewICQService(messageRelayer, nil, nil, slog.Default(), nil, offlineMessageManager, nil)
 			err := s.OfflineMsgReq(nil, tt.sess, tt.seq)
 			assert.NoError(t, err)
 		})
@@ -2547,8 +2620,22 @@ func TestICQService_SetPermissions(t *testing.T) {
 			name: "happy path",
 			seq:  1,
 			sess: newTestSession("100003", sessOptUIN(100003)),
-			req:  wire.ICQ_0x07D0_0x0424_DBQueryMetaReqSetPermissions{},
+			req: wire.ICQ_0x07D0_0x0424_DBQueryMetaReqSetPermissions{
+				Authorization: 1,
+				WebAware:      1,
+			},
 			mockParams: mockParams{
+				icqUserUpdaterParams: icqUserUpdaterParams{
+					setPermissionsParams: setPermissionsParams{
+						{
+							name: state.NewIdentScreenName("100003"),
+							data: state.ICQPermissions{
+								AuthRequired: true,
+								WebAware:     true,
+							},
+						},
+					},
+				},
 				messageRelayerParams: messageRelayerParams{
 					relayToScreenNameParams: relayToScreenNameParams{
 						{
@@ -2584,13 +2671,20 @@ func TestICQService_SetPermissions(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			userUpdater := newMockICQUserUpdater(t)
+			for _, params := range tt.mockParams.icqUserUpdaterParams.setPermissionsParams {
+				userUpdater.EXPECT().
+					SetPermissions(params.name, params.data).
+					Return(params.err)
+			}
+
 			messageRelayer := newMockMessageRelayer(t)
 			for _, params := range tt.mockParams.relayToScreenNameParams {
 				messageRelayer.EXPECT().RelayToScreenName(mock.Anything, params.screenName, params.message)
 			}
 
 			s := ICQService{
-				logger:         slog.Default(),
+				userUpdater:    userUpdater,
 				messageRelayer: messageRelayer,
 			}
 			err := s.SetPermissions(nil, tt.sess, tt.req, tt.seq)
@@ -2910,17 +3004,114 @@ func TestICQService_XMLReqData(t *testing.T) {
 		timeNow    func() time.Time
 		seq        uint16
 		sess       *state.Session
+		xmlKeys    map[string]string
 		req        wire.ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq
 		mockParams mockParams
 		wantErr    error
 	}{
 		{
-			name: "happy path",
+			name: "known key gets its value",
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			seq:  1,
+			sess: newTestSession("11111111", sessOptUIN(11111111)),
+			xmlKeys: map[string]string{
+				"DataFilesIP": "127.0.0.1",
+			},
+			req: wire.ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq{
+				XMLRequest: "<key>DataFilesIP</key>",
+			},
+			mockParams: mockParams{
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("11111111"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICQ,
+									SubGroup:  wire.ICQDBReply,
+								},
+								Body: wire.SNAC_0x15_0x02_DBReply{
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICQTLVTagsMetadata, wire.ICQMessageReplyEnvelope{
+												Message: wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{
+													ICQMetadata: wire.ICQMetadata{
+														UIN:     11111111,
+														ReqType: wire.ICQDBQueryMetaReply,
+														Seq:     1,
+													},
+													ReqSubType: wire.ICQDBQueryMetaReplyXMLData,
+													Success:    wire.ICQStatusCodeOK,
+													XML:        "<value>127.0.0.1</value>",
+												},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown key gets empty reply",
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			seq:  1,
+			sess: newTestSession("11111111", sessOptUIN(11111111)),
+			xmlKeys: map[string]string{
+				"DataFilesIP": "127.0.0.1",
+			},
+			req: wire.ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq{
+				XMLRequest: "<key>BannersIP</key>",
+			},
+			mockParams: mockParams{
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("11111111"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICQ,
+									SubGroup:  wire.ICQDBReply,
+								},
+								Body: wire.SNAC_0x15_0x02_DBReply{
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICQTLVTagsMetadata, wire.ICQMessageReplyEnvelope{
+												Message: wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{
+													ICQMetadata: wire.ICQMetadata{
+														UIN:     11111111,
+														ReqType: wire.ICQDBQueryMetaReply,
+														Seq:     1,
+													},
+													ReqSubType: wire.ICQDBQueryMetaReplyXMLData,
+													Success:    wire.ICQStatusCodeFail,
+												},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unsupported request type gets empty reply",
 			timeNow: func() time.Time {
 				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
 			},
 			seq:  1,
 			sess: newTestSession("11111111", sessOptUIN(11111111)),
+			xmlKeys: map[string]string{
+				"DataFilesIP": "127.0.0.1",
+			},
 			req: wire.ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq{
 				XMLRequest: "<xml></xml>",
 			},
@@ -2957,6 +3148,52 @@ func TestICQService_XMLReqData(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "malformed request gets empty reply",
+			timeNow: func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			},
+			seq:  1,
+			sess: newTestSession("11111111", sessOptUIN(11111111)),
+			xmlKeys: map[string]string{
+				"DataFilesIP": "127.0.0.1",
+			},
+			req: wire.ICQ_0x07D0_0x0898_DBQueryMetaReqXMLReq{
+				XMLRequest: "<key>DataFilesIP",
+			},
+			mockParams: mockParams{
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("11111111"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICQ,
+									SubGroup:  wire.ICQDBReply,
+								},
+								Body: wire.SNAC_0x15_0x02_DBReply{
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICQTLVTagsMetadata, wire.ICQMessageReplyEnvelope{
+												Message: wire.ICQ_0x07DA_0x08A2_DBQueryMetaReplyXMLData{
+													ICQMetadata: wire.ICQMetadata{
+														UIN:     11111111,
+														ReqType: wire.ICQDBQueryMetaReply,
+														Seq:     1,
+													},
+													ReqSubType: wire.ICQDBQueryMetaReplyXMLData,
+													Success:    wire.ICQStatusCodeFail,
+												},
+											}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -2965,11 +3202,57 @@ func TestICQService_XMLReqData(t *testing.T) {
 				messageRelayer.EXPECT().RelayToScreenName(mock.Anything, params.screenName, params.message)
 			}
 			s := ICQService{
+				logger:         slog.Default(),
 				messageRelayer: messageRelayer,
 				timeNow:        tt.timeNow,
+				xmlKeys:        tt.xmlKeys,
 			}
 			err := s.XMLReqData(nil, tt.sess, tt.req, tt.seq)
 			assert.NoError(t, err)
 		})
 	}
 }
+
+func TestParseICQXMLKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  map[string]string{},
+		},
+		{
+			name:  "values with colons",
+			given: "DataFilesIP:127.0.0.1, ChannelsIP:[::1],",
+			want: map[string]string{
+				"DataFilesIP": "127.0.0.1",
+				"ChannelsIP":  "[::1]",
+			},
+		},
+		{
+			name:    "missing value",
+			given:   "DataFilesIP",
+			wantErr: true,
+		},
+		{
+			name:    "missing key",
+			given:   ":127.0.0.1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseICQXMLKeys(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
@@ -4,33 +4,50 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
-// omitCaps is the map of to filter out of the client's capability list
-// because they are not currently supported by the server.
-var omitCaps = map[[16]byte]bool{
-	// 0946134a-4c7f-11d1-8222-444553540000 (games)
-	{9, 70, 19, 74, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0}: true,
-	// 0946134d-4c7f-11d1-8222-444553540000 (ICQ inter-op)
-	{9, 70, 19, 77, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0}: true,
-	// 09461341-4c7f-11d1-8222-444553540000 (voice chat)
-	{9, 70, 19, 65, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0}: true,
+// capabilityList unpacks a capability TLV value into a list of 16-byte
+// capability UUIDs. The list is kept verbatim, including capabilities the
+// server doesn't recognize, except for the UUIDs listed in omit.
+func capabilityList(b []byte, omit [][16]byte) ([][16]byte, error) {
+	if len(b)%16 != 0 {
+		return nil, errors.New("capability list must be array of 16-byte values")
+	}
+	caps := make([][16]byte, 0, len(b)/16)
+	for i := 0; i < len(b); i += 16 {
+		var c [16]byte
+		copy(c[:], b[i:i+16])
+		if slices.Contains(omit, c) {
+			continue
+		}
+		caps = append(caps, c)
+	}
+	return caps, nil
 }
 
-// NewLocateService creates a new instance of LocateService.
+// NewLocateService creates a new instance of LocateService. The capabilities
+// in omitCapabilities are stripped from the capability lists that users set.
 func NewLocateService(
+	cfg config.Config,
 	messageRelayer MessageRelayer,
 	profileManager ProfileManager,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	screenNameResolver ScreenNameResolver,
+	omitCapabilities [][16]byte,
 ) LocateService {
 	return LocateService{
 		buddyBroadcaster:   newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
 		buddyListRetriever: buddyListRetriever,
+		cfg:                cfg,
+		omitCapabilities:   omitCapabilities,
 		profileManager:     profileManager,
+		screenNameResolver: screenNameResolver,
 		sessionRetriever:   sessionRetriever,
 	}
 }
@@ -41,7 +58,10 @@ func NewLocateService(
 type LocateService struct {
 	buddyBroadcaster   buddyBroadcaster
 	buddyListRetriever BuddyListRetriever
+	cfg                config.Config
+	omitCapabilities   [][16]byte
 	profileManager     ProfileManager
+	screenNameResolver ScreenNameResolver
 	sessionRetriever   SessionRetriever
 }
 
@@ -74,14 +94,14 @@ func (s LocateService) RightsQuery(_ context.Context, inFrame wire.SNACFrame) wi
 func (s LocateService) SetInfo(ctx context.Context, sess *state.Session, inBody wire.SNAC_0x02_0x04_LocateSetInfo) error {
 	// update profile
 	if profile, hasProfile := inBody.String(wire.LocateTLVTagsInfoSigData); hasProfile {
-		if err := s.profileManager.SetProfile(sess.IdentScreenName(), profile); err != nil {
+		if err := s.profileManager.SetProfile(sess.IdentScreenName(), state.SanitizeUserInfo(profile)); err != nil {
 			return err
 		}
 	}
 
 	// broadcast away message change to buddies
 	if awayMsg, hasAwayMsg := inBody.String(wire.LocateTLVTagsInfoUnavailableData); hasAwayMsg {
-		sess.SetAwayMessage(awayMsg)
+		sess.SetAwayMessage(state.SanitizeUserInfo(awayMsg))
 		if sess.SignonComplete() {
 			if err := s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess); err != nil {
 				return err
@@ -91,17 +111,9 @@ func (s LocateService) SetInfo(ctx context.Context, sess *state.Session, inBody
 
 	// update client capabilities (buddy icon, chat, etc...)
 	if b, hasCaps := inBody.Bytes(wire.LocateTLVTagsInfoCapabilities); hasCaps {
-		if len(b)%16 != 0 {
-			return errors.New("capability list must be array of 16-byte values")
-		}
-		var caps [][16]byte
-		for i := 0; i < len(b); i += 16 {
-			var c [16]byte
-			copy(c[:], b[i:i+16])
-			if _, found := omitCaps[c]; found {
-				continue
-			}
-			caps = append(caps, c)
+		caps, err := capabilityList(b, s.omitCapabilities)
+		if err != nil {
+			return err
 		}
 		sess.SetCaps(caps)
 	}
@@ -125,9 +137,13 @@ func newLocateErr(requestID uint32, errCode uint16) wire.SNACMessage {
 // UserInfoQuery fetches display information about an arbitrary user (not the
 // current user). It returns wire.LocateUserInfoReply, which contains the
 // profile, if requested, and/or the away message, if requested. This is a v2
-// of UserInfoQuery.
+// of UserInfoQuery. Looking up a screen name alias returns information about
+// the account that the alias belongs to.
 func (s LocateService) UserInfoQuery(_ context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x02_0x05_LocateUserInfoQuery) (wire.SNACMessage, error) {
-	identScreenName := state.NewIdentScreenName(inBody.ScreenName)
+	identScreenName, err := s.screenNameResolver.CanonicalScreenName(state.NewIdentScreenName(inBody.ScreenName))
+	if err != nil {
+		return wire.SNACMessage{}, err
+	}
 
 	rel, err := s.buddyListRetriever.Relationship(sess.IdentScreenName(), identScreenName)
 	if err != nil {
@@ -200,9 +216,11 @@ func (s LocateService) SetDirInfo(ctx context.Context, sess *state.Session, inFr
 	}, nil
 }
 
-// SetKeywordInfo sets profile keywords and interests. This method does nothing
-// and exists to placate the AIM client. It returns wire.LocateSetKeywordReply
-// with a canned success message.
+// SetKeywordInfo sets profile keywords and interests, which makes the user
+// immediately searchable by keyword in the directory. Each keyword must be
+// one of the keywords offered in the directory interests list, otherwise
+// wire.LocateErr is returned and no keywords are changed. It returns
+// wire.LocateSetKeywordReply on success.
 func (s LocateService) SetKeywordInfo(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, body wire.SNAC_0x02_0x0F_LocateSetKeywordInfo) (wire.SNACMessage, error) {
 	var keywords [5]string
 
@@ -218,6 +236,22 @@ func (s LocateService) SetKeywordInfo(ctx context.Context, sess *state.Session,
 		}
 	}
 
+	interests, err := s.profileManager.InterestList()
+	if err != nil {
+		return wire.SNACMessage{}, fmt.Errorf("InterestList: %w", err)
+	}
+	validKeywords := make(map[string]bool)
+	for _, item := range interests {
+		if item.Type == wire.ODirKeyword {
+			validKeywords[item.Name] = true
+		}
+	}
+	for _, keyword := range keywords[:i] {
+		if keyword != "" && !validKeywords[keyword] {
+			return newLocateErr(inFrame.RequestID, wire.ErrorCodeNoMatch), nil
+		}
+	}
+
 	if err := s.profileManager.SetKeywords(sess.IdentScreenName(), keywords); err != nil {
 		return wire.SNACMessage{}, fmt.Errorf("SetKeywords: %w", err)
 	}
@@ -234,8 +268,12 @@ func (s LocateService) SetKeywordInfo(ctx context.Context, sess *state.Session,
 	}, nil
 }
 
-// DirInfo returns directory information for a user.
-func (s LocateService) DirInfo(ctx context.Context, inFrame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error) {
+// DirInfo returns directory information for a user. The info is read from
+// the user's stored directory info, so it's available whether or not the user
+// is signed on, unless returning info for offline users is disabled by
+// config. Users who chose not to disclose their info get an unavailable
+// reply, except when looking up themselves.
+func (s LocateService) DirInfo(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error) {
 	reply := wire.SNAC_0x02_0x0C_LocateGetDirReply{
 		Status: wire.LocateGetDirReplyOK,
 		TLVBlock: wire.TLVBlock{
@@ -248,6 +286,17 @@ func (s LocateService) DirInfo(ctx context.Context, inFrame wire.SNACFrame, body
 		return wire.SNACMessage{}, fmt.Errorf("User: %w", err)
 	}
 
+	if user != nil && user.IdentScreenName != sess.IdentScreenName() {
+		switch {
+		case user.RegStatus == int(wire.AdminInfoRegStatusNoDisclosure):
+			reply.Status = wire.LocateGetDirReplyUnavailable
+			user = nil
+		case !s.cfg.DirInfoOfflineUsers && s.sessionRetriever.RetrieveSession(user.IdentScreenName) == nil:
+			reply.Status = wire.LocateGetDirReplyUnavailable
+			user = nil
+		}
+	}
+
 	if user != nil {
 		reply.Append(wire.NewTLVBE(wire.ODirTLVFirstName, user.AIMDirectoryInfo.FirstName))
 		reply.Append(wire.NewTLVBE(wire.ODirTLVLastName, user.AIMDirectoryInfo.LastName))
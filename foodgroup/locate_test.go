@@ -2,11 +2,14 @@ package foodgroup
 
 import (
 	"context"
+	"log/slog"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -387,6 +390,7 @@ func TestLocateService_UserInfoQuery(t *testing.T) {
 				buddyListRetriever: buddyListRetriever,
 				sessionRetriever:   sessionRetriever,
 				profileManager:     profileManager,
+				screenNameResolver: newNoAliasScreenNameResolver(t),
 			}
 			outputSNAC, err := svc.UserInfoQuery(context.Background(), tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x02_0x05_LocateUserInfoQuery))
@@ -397,6 +401,15 @@ func TestLocateService_UserInfoQuery(t *testing.T) {
 }
 
 func TestLocateService_SetKeywordInfo(t *testing.T) {
+	interestList := []wire.ODirKeywordListItem{
+		{Type: wire.ODirKeywordCategory, ID: 1, Name: "category1"},
+		{Type: wire.ODirKeyword, ID: 1, Name: "interest1"},
+		{Type: wire.ODirKeyword, ID: 1, Name: "interest2"},
+		{Type: wire.ODirKeyword, ID: 1, Name: "interest3"},
+		{Type: wire.ODirKeyword, ID: 0, Name: "interest4"},
+		{Type: wire.ODirKeyword, ID: 0, Name: "interest5"},
+	}
+
 	tests := []struct {
 		// name is the unit test name
 		name string
@@ -445,6 +458,11 @@ func TestLocateService_SetKeywordInfo(t *testing.T) {
 			},
 			mockParams: mockParams{
 				profileManagerParams: profileManagerParams{
+					interestListParams: interestListParams{
+						{
+							result: interestList,
+						},
+					},
 					setKeywordsParams: setKeywordsParams{
 						{
 							screenName: state.NewIdentScreenName("test-user"),
@@ -492,6 +510,11 @@ func TestLocateService_SetKeywordInfo(t *testing.T) {
 			},
 			mockParams: mockParams{
 				profileManagerParams: profileManagerParams{
+					interestListParams: interestListParams{
+						{
+							result: interestList,
+						},
+					},
 					setKeywordsParams: setKeywordsParams{
 						{
 							screenName: state.NewIdentScreenName("test-user"),
@@ -540,6 +563,11 @@ func TestLocateService_SetKeywordInfo(t *testing.T) {
 			},
 			mockParams: mockParams{
 				profileManagerParams: profileManagerParams{
+					interestListParams: interestListParams{
+						{
+							result: interestList,
+						},
+					},
 					setKeywordsParams: setKeywordsParams{
 						{
 							screenName: state.NewIdentScreenName("test-user"),
@@ -555,16 +583,92 @@ func TestLocateService_SetKeywordInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "set unknown interest",
+			userSession: newTestSession("test-user"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ODirTLVInterest, "interest1"),
+							wire.NewTLVBE(wire.ODirTLVInterest, "not-an-interest"),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Locate,
+					SubGroup:  wire.LocateErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeNoMatch,
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					interestListParams: interestListParams{
+						{
+							result: interestList,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "category name is not a valid interest",
+			userSession: newTestSession("test-user"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ODirTLVInterest, "category1"),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Locate,
+					SubGroup:  wire.LocateErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeNoMatch,
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					interestListParams: interestListParams{
+						{
+							result: interestList,
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			profileManager := newMockProfileManager(t)
+			for _, params := range tt.mockParams.interestListParams {
+				profileManager.EXPECT().
+					InterestList().
+					Return(params.result, params.err)
+			}
 			for _, params := range tt.mockParams.setKeywordsParams {
 				profileManager.EXPECT().
 					SetKeywords(params.screenName, params.keywords).
 					Return(params.err)
 			}
-			svc := NewLocateService(nil, profileManager, nil, nil)
+			svc := NewLocateService(config.Config{}, nil, profileManager, nil, nil, nil, nil)
 			outputSNAC, err := svc.SetKeywordInfo(nil, tt.userSession, tt.inputSNAC.Frame, tt.inputSNAC.Body.(wire.SNAC_0x02_0x0F_LocateSetKeywordInfo))
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expectOutput, outputSNAC)
@@ -572,6 +676,49 @@ func TestLocateService_SetKeywordInfo(t *testing.T) {
 	}
 }
 
+// TestLocateService_SetKeywordInfo_SearchableInODir verifies that keywords
+// set via the Locate food group are immediately searchable in ODir.
+func TestLocateService_SetKeywordInfo_SearchableInODir(t *testing.T) {
+	userStore, err := state.NewSQLiteUserStore(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+
+	category, err := userStore.CreateCategory("Music")
+	assert.NoError(t, err)
+	_, err = userStore.CreateKeyword("Jazz", category.ID)
+	assert.NoError(t, err)
+
+	user, err := state.NewStubUser("me")
+	assert.NoError(t, err)
+	assert.NoError(t, userStore.InsertUser(user))
+
+	locateSvc := NewLocateService(config.Config{}, nil, userStore, nil, nil, nil, nil)
+	reply, err := locateSvc.SetKeywordInfo(context.Background(), newTestSession("me"), wire.SNACFrame{}, wire.SNAC_0x02_0x0F_LocateSetKeywordInfo{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ODirTLVInterest, "Jazz"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, wire.LocateSetKeywordReply, reply.Frame.SubGroup)
+
+	odirSvc := NewODirService(slog.Default(), userStore)
+	result, err := odirSvc.InfoQuery(context.Background(), wire.SNACFrame{}, wire.SNAC_0x0F_0x02_InfoQuery{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ODirTLVInterest, "Jazz"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	body := result.Body.(wire.SNAC_0x0F_0x03_InfoReply)
+	if assert.Len(t, body.Results.List, 1) {
+		screenName, _ := body.Results.List[0].String(wire.ODirTLVScreenName)
+		assert.Equal(t, "me", screenName)
+	}
+}
+
 func TestLocateService_SetDirInfo(t *testing.T) {
 	tests := []struct {
 		// name is the unit test name
@@ -653,7 +800,7 @@ func TestLocateService_SetDirInfo(t *testing.T) {
 					SetDirectoryInfo(params.screenName, params.info).
 					Return(nil)
 			}
-			svc := NewLocateService(nil, profileManager, nil, nil)
+			svc := NewLocateService(config.Config{}, nil, profileManager, nil, nil, nil, nil)
 			outputSNAC, err := svc.SetDirInfo(nil, tt.userSession, tt.inputSNAC.Frame, tt.inputSNAC.Body.(wire.SNAC_0x02_0x09_LocateSetDirInfo))
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expectOutput, outputSNAC)
@@ -695,6 +842,27 @@ func TestLocateService_SetInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "set profile, remove unsafe markup",
+			userSession: newTestSession("test-user"),
+			inBody: wire.SNAC_0x02_0x04_LocateSetInfo{
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.LocateTLVTagsInfoSigData, `<B>profile-result</B><SCRIPT>alert(1)</SCRIPT>`),
+					},
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					setProfileParams: setProfileParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							body:       "<B>profile-result</B>",
+						},
+					},
+				},
+			},
+		},
 		{
 			name:        "set away message during sign on flow",
 			userSession: newTestSession("user_screen_name"),
@@ -746,7 +914,7 @@ func TestLocateService_SetInfo(t *testing.T) {
 					BroadcastBuddyArrived(mock.Anything, matchSession(params.screenName)).
 					Return(params.err)
 			}
-			svc := NewLocateService(nil, profileManager, nil, nil)
+			svc := NewLocateService(config.Config{}, nil, profileManager, nil, nil, nil, nil)
 			svc.buddyBroadcaster = buddyUpdateBroadcaster
 			assert.Equal(t, tt.wantErr, svc.SetInfo(nil, tt.userSession, tt.inBody))
 		})
@@ -754,9 +922,6 @@ func TestLocateService_SetInfo(t *testing.T) {
 }
 
 func TestLocateService_SetInfo_SetCaps(t *testing.T) {
-	svc := NewLocateService(nil, nil, nil, nil)
-
-	sess := newTestSession("screen-name")
 	inBody := wire.SNAC_0x02_0x04_LocateSetInfo{
 		TLVRestBlock: wire.TLVRestBlock{
 			TLVList: wire.TLVList{
@@ -769,25 +934,53 @@ func TestLocateService_SetInfo_SetCaps(t *testing.T) {
 					9, 70, 19, 74, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0,
 					// 0946134d-4c7f-11d1-8222-444553540000 (ICQ inter-op)
 					9, 70, 19, 77, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0,
-					// 09461341-4c7f-11d1-8222-444553540000 (voice chat)
-					9, 70, 19, 65, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0,
+					// 01020304-0506-0708-090a-0b0c0d0e0f10 (unknown)
+					1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
 				}),
 			},
 		},
 	}
-	assert.NoError(t, svc.SetInfo(nil, sess, inBody))
 
-	expect := [][16]byte{
-		// 748F2420-6287-11D1-8222-444553540000 (chat)
-		{0x74, 0x8f, 0x24, 0x20, 0x62, 0x87, 0x11, 0xd1, 0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00},
-		// 09461346-4C7F-11D1-8222-444553540000 (avatar)
-		{9, 70, 19, 70, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+	tests := []struct {
+		name   string
+		omit   [][16]byte
+		expect [][16]byte
+	}{
+		{
+			name: "keep all capabilities verbatim",
+			expect: [][16]byte{
+				{0x74, 0x8f, 0x24, 0x20, 0x62, 0x87, 0x11, 0xd1, 0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00},
+				{9, 70, 19, 70, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+				{9, 70, 19, 74, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+				{9, 70, 19, 77, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+				{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			},
+		},
+		{
+			name: "omit configured capabilities",
+			omit: [][16]byte{
+				{9, 70, 19, 74, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+				{9, 70, 19, 77, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+			},
+			expect: [][16]byte{
+				{0x74, 0x8f, 0x24, 0x20, 0x62, 0x87, 0x11, 0xd1, 0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00},
+				{9, 70, 19, 70, 76, 127, 17, 209, 130, 34, 68, 69, 83, 84, 0, 0},
+				{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewLocateService(config.Config{}, nil, nil, nil, nil, nil, tt.omit)
+			sess := newTestSession("screen-name")
+			assert.NoError(t, svc.SetInfo(nil, sess, inBody))
+			assert.Equal(t, tt.expect, sess.Caps())
+		})
 	}
-	assert.Equal(t, expect, sess.Caps())
 }
 
 func TestLocateService_RightsQuery(t *testing.T) {
-	svc := NewLocateService(nil, nil, nil, nil)
+	svc := NewLocateService(config.Config{}, nil, nil, nil, nil, nil, nil)
 
 	outputSNAC := svc.RightsQuery(nil, wire.SNACFrame{RequestID: 1234})
 	expectSNAC := wire.SNACMessage{
@@ -816,7 +1009,9 @@ func TestLocateService_DirInfo(t *testing.T) {
 	tests := []struct {
 		// name is the unit test name
 		name string
-		// userSession is the session of the user setting info
+		// cfg is the app configuration
+		cfg config.Config
+		// userSession is the session of the user requesting info
 		userSession *state.Session
 		// inputSNAC is the SNAC sent from client to server
 		inputSNAC wire.SNACMessage
@@ -829,7 +1024,251 @@ func TestLocateService_DirInfo(t *testing.T) {
 		wantErr error
 	}{
 		{
-			name:        "happy path",
+			name: "offline user, stored directory info returned",
+			cfg: config.Config{
+				DirInfoOfflineUsers: true,
+			},
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0B_LocateGetDirInfo{
+					WatcherScreenNames: "test-user",
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Locate,
+					SubGroup:  wire.LocateGetDirReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0C_LocateGetDirReply{
+					Status: wire.LocateGetDirReplyOK,
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ODirTLVFirstName, "John"),
+							wire.NewTLVBE(wire.ODirTLVLastName, "Doe"),
+							wire.NewTLVBE(wire.ODirTLVMiddleName, "A"),
+							wire.NewTLVBE(wire.ODirTLVMaidenName, "Smith"),
+							wire.NewTLVBE(wire.ODirTLVCountry, "USA"),
+							wire.NewTLVBE(wire.ODirTLVState, "CA"),
+							wire.NewTLVBE(wire.ODirTLVCity, "San Francisco"),
+							wire.NewTLVBE(wire.ODirTLVNickName, "Johnny"),
+							wire.NewTLVBE(wire.ODirTLVZIP, "94107"),
+							wire.NewTLVBE(wire.ODirTLVAddress, "123 Main St"),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							result: &state.User{
+								IdentScreenName: state.NewIdentScreenName("test-user"),
+								RegStatus:       int(wire.AdminInfoRegStatusFullDisclosure),
+								AIMDirectoryInfo: state.AIMNameAndAddr{
+									FirstName:  "John",
+									LastName:   "Doe",
+									MiddleName: "A",
+									MaidenName: "Smith",
+									Country:    "USA",
+									State:      "CA",
+									City:       "San Francisco",
+									NickName:   "Johnny",
+									ZIPCode:    "94107",
+									Address:    "123 Main St",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "offline user, offline lookups disabled",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0B_LocateGetDirInfo{
+					WatcherScreenNames: "test-user",
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Locate,
+					SubGroup:  wire.LocateGetDirReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0C_LocateGetDirReply{
+					Status: wire.LocateGetDirReplyUnavailable,
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{},
+					},
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							result: &state.User{
+								IdentScreenName: state.NewIdentScreenName("test-user"),
+								RegStatus:       int(wire.AdminInfoRegStatusFullDisclosure),
+								AIMDirectoryInfo: state.AIMNameAndAddr{
+									FirstName:  "John",
+									LastName:   "Doe",
+									MiddleName: "A",
+									MaidenName: "Smith",
+									Country:    "USA",
+									State:      "CA",
+									City:       "San Francisco",
+									NickName:   "Johnny",
+									ZIPCode:    "94107",
+									Address:    "123 Main St",
+								},
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "online user, offline lookups disabled",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0B_LocateGetDirInfo{
+					WatcherScreenNames: "test-user",
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Locate,
+					SubGroup:  wire.LocateGetDirReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0C_LocateGetDirReply{
+					Status: wire.LocateGetDirReplyOK,
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.ODirTLVFirstName, "John"),
+							wire.NewTLVBE(wire.ODirTLVLastName, "Doe"),
+							wire.NewTLVBE(wire.ODirTLVMiddleName, "A"),
+							wire.NewTLVBE(wire.ODirTLVMaidenName, "Smith"),
+							wire.NewTLVBE(wire.ODirTLVCountry, "USA"),
+							wire.NewTLVBE(wire.ODirTLVState, "CA"),
+							wire.NewTLVBE(wire.ODirTLVCity, "San Francisco"),
+							wire.NewTLVBE(wire.ODirTLVNickName, "Johnny"),
+							wire.NewTLVBE(wire.ODirTLVZIP, "94107"),
+							wire.NewTLVBE(wire.ODirTLVAddress, "123 Main St"),
+						},
+					},
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							result: &state.User{
+								IdentScreenName: state.NewIdentScreenName("test-user"),
+								RegStatus:       int(wire.AdminInfoRegStatusFullDisclosure),
+								AIMDirectoryInfo: state.AIMNameAndAddr{
+									FirstName:  "John",
+									LastName:   "Doe",
+									MiddleName: "A",
+									MaidenName: "Smith",
+									Country:    "USA",
+									State:      "CA",
+									City:       "San Francisco",
+									NickName:   "Johnny",
+									ZIPCode:    "94107",
+									Address:    "123 Main St",
+								},
+							},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							result:     newTestSession("test-user"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "user doesn't disclose directory info",
+			cfg: config.Config{
+				DirInfoOfflineUsers: true,
+			},
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0B_LocateGetDirInfo{
+					WatcherScreenNames: "test-user",
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.Locate,
+					SubGroup:  wire.LocateGetDirReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x02_0x0C_LocateGetDirReply{
+					Status: wire.LocateGetDirReplyUnavailable,
+					TLVBlock: wire.TLVBlock{
+						TLVList: wire.TLVList{},
+					},
+				},
+			},
+			mockParams: mockParams{
+				profileManagerParams: profileManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("test-user"),
+							result: &state.User{
+								IdentScreenName: state.NewIdentScreenName("test-user"),
+								RegStatus:       int(wire.AdminInfoRegStatusNoDisclosure),
+								AIMDirectoryInfo: state.AIMNameAndAddr{
+									FirstName:  "John",
+									LastName:   "Doe",
+									MiddleName: "A",
+									MaidenName: "Smith",
+									Country:    "USA",
+									State:      "CA",
+									City:       "San Francisco",
+									NickName:   "Johnny",
+									ZIPCode:    "94107",
+									Address:    "123 Main St",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "user doesn't disclose directory info, looks up self",
 			userSession: newTestSession("test-user"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
@@ -869,6 +1308,8 @@ func TestLocateService_DirInfo(t *testing.T) {
 						{
 							screenName: state.NewIdentScreenName("test-user"),
 							result: &state.User{
+								IdentScreenName: state.NewIdentScreenName("test-user"),
+								RegStatus:       int(wire.AdminInfoRegStatusNoDisclosure),
 								AIMDirectoryInfo: state.AIMNameAndAddr{
 									FirstName:  "John",
 									LastName:   "Doe",
@@ -889,7 +1330,7 @@ func TestLocateService_DirInfo(t *testing.T) {
 		},
 		{
 			name:        "user not found",
-			userSession: newTestSession("test-user"),
+			userSession: newTestSession("me"),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
@@ -931,8 +1372,14 @@ func TestLocateService_DirInfo(t *testing.T) {
 					User(params.screenName).
 					Return(params.result, params.err)
 			}
-			svc := NewLocateService(nil, profileManager, nil, nil)
-			outputSNAC, err := svc.DirInfo(nil, tt.inputSNAC.Frame, tt.inputSNAC.Body.(wire.SNAC_0x02_0x0B_LocateGetDirInfo))
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tt.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			svc := NewLocateService(tt.cfg, nil, profileManager, nil, sessionRetriever, nil, nil)
+			outputSNAC, err := svc.DirInfo(nil, tt.userSession, tt.inputSNAC.Frame, tt.inputSNAC.Body.(wire.SNAC_0x02_0x0B_LocateGetDirInfo))
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expectOutput, outputSNAC)
 		})
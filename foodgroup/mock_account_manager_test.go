@@ -7,6 +7,8 @@ import (
 
 	state "github.com/mk6i/retro-aim-server/state"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // mockAccountManager is an autogenerated mock type for the AccountManager type
@@ -192,6 +194,54 @@ func (_c *mockAccountManager_RegStatusByName_Call) RunAndReturn(run func(state.I
 	return _c
 }
 
+// SetConfirmToken provides a mock function with given fields: token, expiresAt, screenName
+func (_m *mockAccountManager) SetConfirmToken(token string, expiresAt time.Time, screenName state.IdentScreenName) error {
+	ret := _m.Called(token, expiresAt, screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetConfirmToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, state.IdentScreenName) error); ok {
+		r0 = rf(token, expiresAt, screenName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockAccountManager_SetConfirmToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetConfirmToken'
+type mockAccountManager_SetConfirmToken_Call struct {
+	*mock.Call
+}
+
+// SetConfirmToken is a helper method to define mock.On call
+//   - token string
+//   - expiresAt time.Time
+//   - screenName state.IdentScreenName
+func (_e *mockAccountManager_Expecter) SetConfirmToken(token interface{}, expiresAt interface{}, screenName interface{}) *mockAccountManager_SetConfirmToken_Call {
+	return &mockAccountManager_SetConfirmToken_Call{Call: _e.mock.On("SetConfirmToken", token, expiresAt, screenName)}
+}
+
+func (_c *mockAccountManager_SetConfirmToken_Call) Run(run func(token string, expiresAt time.Time, screenName state.IdentScreenName)) *mockAccountManager_SetConfirmToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(time.Time), args[2].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockAccountManager_SetConfirmToken_Call) Return(_a0 error) *mockAccountManager_SetConfirmToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockAccountManager_SetConfirmToken_Call) RunAndReturn(run func(string, time.Time, state.IdentScreenName) error) *mockAccountManager_SetConfirmToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateConfirmStatus provides a mock function with given fields: confirmStatus, screenName
 func (_m *mockAccountManager) UpdateConfirmStatus(confirmStatus bool, screenName state.IdentScreenName) error {
 	ret := _m.Called(confirmStatus, screenName)
@@ -76,34 +76,41 @@ func (_c *mockICQUserFinder_FindByICQEmail_Call) RunAndReturn(run func(string) (
 	return _c
 }
 
-// FindByICQInterests provides a mock function with given fields: code, keywords
-func (_m *mockICQUserFinder) FindByICQInterests(code uint16, keywords []string) ([]state.User, error) {
-	ret := _m.Called(code, keywords)
+// FindByICQInterests provides a mock function with given fields: code, keywords, limit
+func (_m *mockICQUserFinder) FindByICQInterests(code uint16, keywords []string, limit int) ([]state.User, int, error) {
+	ret := _m.Called(code, keywords, limit)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FindByICQInterests")
 	}
 
 	var r0 []state.User
-	var r1 error
-	if rf, ok := ret.Get(0).(func(uint16, []string) ([]state.User, error)); ok {
-		return rf(code, keywords)
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(uint16, []string, int) ([]state.User, int, error)); ok {
+		return rf(code, keywords, limit)
 	}
-	if rf, ok := ret.Get(0).(func(uint16, []string) []state.User); ok {
-		r0 = rf(code, keywords)
+	if rf, ok := ret.Get(0).(func(uint16, []string, int) []state.User); ok {
+		r0 = rf(code, keywords, limit)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]state.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uint16, []string) error); ok {
-		r1 = rf(code, keywords)
+	if rf, ok := ret.Get(1).(func(uint16, []string, int) int); ok {
+		r1 = rf(code, keywords, limit)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(int)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(uint16, []string, int) error); ok {
+		r2 = rf(code, keywords, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // mockICQUserFinder_FindByICQInterests_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindByICQInterests'
@@ -114,55 +121,63 @@ type mockICQUserFinder_FindByICQInterests_Call struct {
 // FindByICQInterests is a helper method to define mock.On call
 //   - code uint16
 //   - keywords []string
-func (_e *mockICQUserFinder_Expecter) FindByICQInterests(code interface{}, keywords interface{}) *mockICQUserFinder_FindByICQInterests_Call {
-	return &mockICQUserFinder_FindByICQInterests_Call{Call: _e.mock.On("FindByICQInterests", code, keywords)}
+//   - limit int
+func (_e *mockICQUserFinder_Expecter) FindByICQInterests(code interface{}, keywords interface{}, limit interface{}) *mockICQUserFinder_FindByICQInterests_Call {
+	return &mockICQUserFinder_FindByICQInterests_Call{Call: _e.mock.On("FindByICQInterests", code, keywords, limit)}
 }
 
-func (_c *mockICQUserFinder_FindByICQInterests_Call) Run(run func(code uint16, keywords []string)) *mockICQUserFinder_FindByICQInterests_Call {
+func (_c *mockICQUserFinder_FindByICQInterests_Call) Run(run func(code uint16, keywords []string, limit int)) *mockICQUserFinder_FindByICQInterests_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint16), args[1].([]string))
+		run(args[0].(uint16), args[1].([]string), args[2].(int))
 	})
 	return _c
 }
 
-func (_c *mockICQUserFinder_FindByICQInterests_Call) Return(_a0 []state.User, _a1 error) *mockICQUserFinder_FindByICQInterests_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *mockICQUserFinder_FindByICQInterests_Call) Return(_a0 []state.User, _a1 int, _a2 error) *mockICQUserFinder_FindByICQInterests_Call {
+	_c.Call.Return(_a0, _a1, _a2)
 	return _c
 }
 
-func (_c *mockICQUserFinder_FindByICQInterests_Call) RunAndReturn(run func(uint16, []string) ([]state.User, error)) *mockICQUserFinder_FindByICQInterests_Call {
+func (_c *mockICQUserFinder_FindByICQInterests_Call) RunAndReturn(run func(uint16, []string, int) ([]state.User, int, error)) *mockICQUserFinder_FindByICQInterests_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// FindByICQKeyword provides a mock function with given fields: keyword
-func (_m *mockICQUserFinder) FindByICQKeyword(keyword string) ([]state.User, error) {
-	ret := _m.Called(keyword)
+// FindByICQKeyword provides a mock function with given fields: keyword, limit
+func (_m *mockICQUserFinder) FindByICQKeyword(keyword string, limit int) ([]state.User, int, error) {
+	ret := _m.Called(keyword, limit)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FindByICQKeyword")
 	}
 
 	var r0 []state.User
-	var r1 error
-	if rf, ok := ret.Get(0).(func(string) ([]state.User, error)); ok {
-		return rf(keyword)
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, int) ([]state.User, int, error)); ok {
+		return rf(keyword, limit)
 	}
-	if rf, ok := ret.Get(0).(func(string) []state.User); ok {
-		r0 = rf(keyword)
+	if rf, ok := ret.Get(0).(func(string, int) []state.User); ok {
+		r0 = rf(keyword, limit)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]state.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(keyword)
+	if rf, ok := ret.Get(1).(func(string, int) int); ok {
+		r1 = rf(keyword, limit)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(int)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(string, int) error); ok {
+		r2 = rf(keyword, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // mockICQUserFinder_FindByICQKeyword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindByICQKeyword'
@@ -172,55 +187,63 @@ type mockICQUserFinder_FindByICQKeyword_Call struct {
 
 // FindByICQKeyword is a helper method to define mock.On call
 //   - keyword string
-func (_e *mockICQUserFinder_Expecter) FindByICQKeyword(keyword interface{}) *mockICQUserFinder_FindByICQKeyword_Call {
-	return &mockICQUserFinder_FindByICQKeyword_Call{Call: _e.mock.On("FindByICQKeyword", keyword)}
+//   - limit int
+func (_e *mockICQUserFinder_Expecter) FindByICQKeyword(keyword interface{}, limit interface{}) *mockICQUserFinder_FindByICQKeyword_Call {
+	return &mockICQUserFinder_FindByICQKeyword_Call{Call: _e.mock.On("FindByICQKeyword", keyword, limit)}
 }
 
-func (_c *mockICQUserFinder_FindByICQKeyword_Call) Run(run func(keyword string)) *mockICQUserFinder_FindByICQKeyword_Call {
+func (_c *mockICQUserFinder_FindByICQKeyword_Call) Run(run func(keyword string, limit int)) *mockICQUserFinder_FindByICQKeyword_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(string), args[1].(int))
 	})
 	return _c
 }
 
-func (_c *mockICQUserFinder_FindByICQKeyword_Call) Return(_a0 []state.User, _a1 error) *mockICQUserFinder_FindByICQKeyword_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *mockICQUserFinder_FindByICQKeyword_Call) Return(_a0 []state.User, _a1 int, _a2 error) *mockICQUserFinder_FindByICQKeyword_Call {
+	_c.Call.Return(_a0, _a1, _a2)
 	return _c
 }
 
-func (_c *mockICQUserFinder_FindByICQKeyword_Call) RunAndReturn(run func(string) ([]state.User, error)) *mockICQUserFinder_FindByICQKeyword_Call {
+func (_c *mockICQUserFinder_FindByICQKeyword_Call) RunAndReturn(run func(string, int) ([]state.User, int, error)) *mockICQUserFinder_FindByICQKeyword_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// FindByICQName provides a mock function with given fields: firstName, lastName, nickName
-func (_m *mockICQUserFinder) FindByICQName(firstName string, lastName string, nickName string) ([]state.User, error) {
-	ret := _m.Called(firstName, lastName, nickName)
+// FindByICQName provides a mock function with given fields: firstName, lastName, nickName, limit
+func (_m *mockICQUserFinder) FindByICQName(firstName string, lastName string, nickName string, limit int) ([]state.User, int, error) {
+	ret := _m.Called(firstName, lastName, nickName, limit)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FindByICQName")
 	}
 
 	var r0 []state.User
-	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string, string) ([]state.User, error)); ok {
-		return rf(firstName, lastName, nickName)
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, string, string, int) ([]state.User, int, error)); ok {
+		return rf(firstName, lastName, nickName, limit)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, string) []state.User); ok {
-		r0 = rf(firstName, lastName, nickName)
+	if rf, ok := ret.Get(0).(func(string, string, string, int) []state.User); ok {
+		r0 = rf(firstName, lastName, nickName, limit)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]state.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
-		r1 = rf(firstName, lastName, nickName)
+	if rf, ok := ret.Get(1).(func(string, string, string, int) int); ok {
+		r1 = rf(firstName, lastName, nickName, limit)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(int)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(string, string, string, int) error); ok {
+		r2 = rf(firstName, lastName, nickName, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // mockICQUserFinder_FindByICQName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindByICQName'
@@ -232,23 +255,24 @@ type mockICQUserFinder_FindByICQName_Call struct {
 //   - firstName string
 //   - lastName string
 //   - nickName string
-func (_e *mockICQUserFinder_Expecter) FindByICQName(firstName interface{}, lastName interface{}, nickName interface{}) *mockICQUserFinder_FindByICQName_Call {
-	return &mockICQUserFinder_FindByICQName_Call{Call: _e.mock.On("FindByICQName", firstName, lastName, nickName)}
+//   - limit int
+func (_e *mockICQUserFinder_Expecter) FindByICQName(firstName interface{}, lastName interface{}, nickName interface{}, limit interface{}) *mockICQUserFinder_FindByICQName_Call {
+	return &mockICQUserFinder_FindByICQName_Call{Call: _e.mock.On("FindByICQName", firstName, lastName, nickName, limit)}
 }
 
-func (_c *mockICQUserFinder_FindByICQName_Call) Run(run func(firstName string, lastName string, nickName string)) *mockICQUserFinder_FindByICQName_Call {
+func (_c *mockICQUserFinder_FindByICQName_Call) Run(run func(firstName string, lastName string, nickName string, limit int)) *mockICQUserFinder_FindByICQName_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string), args[2].(string))
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(int))
 	})
 	return _c
 }
 
-func (_c *mockICQUserFinder_FindByICQName_Call) Return(_a0 []state.User, _a1 error) *mockICQUserFinder_FindByICQName_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *mockICQUserFinder_FindByICQName_Call) Return(_a0 []state.User, _a1 int, _a2 error) *mockICQUserFinder_FindByICQName_Call {
+	_c.Call.Return(_a0, _a1, _a2)
 	return _c
 }
 
-func (_c *mockICQUserFinder_FindByICQName_Call) RunAndReturn(run func(string, string, string) ([]state.User, error)) *mockICQUserFinder_FindByICQName_Call {
+func (_c *mockICQUserFinder_FindByICQName_Call) RunAndReturn(run func(string, string, string, int) ([]state.User, int, error)) *mockICQUserFinder_FindByICQName_Call {
 	_c.Call.Return(run)
 	return _c
 }
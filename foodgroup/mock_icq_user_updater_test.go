@@ -208,6 +208,53 @@ func (_c *mockICQUserUpdater_SetMoreInfo_Call) RunAndReturn(run func(state.Ident
 	return _c
 }
 
+// SetPermissions provides a mock function with given fields: name, data
+func (_m *mockICQUserUpdater) SetPermissions(name state.IdentScreenName, data state.ICQPermissions) error {
+	ret := _m.Called(name, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPermissions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, state.ICQPermissions) error); ok {
+		r0 = rf(name, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockICQUserUpdater_SetPermissions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetPermissions'
+type mockICQUserUpdater_SetPermissions_Call struct {
+	*mock.Call
+}
+
+// SetPermissions is a helper method to define mock.On call
+//   - name state.IdentScreenName
+//   - data state.ICQPermissions
+func (_e *mockICQUserUpdater_Expecter) SetPermissions(name interface{}, data interface{}) *mockICQUserUpdater_SetPermissions_Call {
+	return &mockICQUserUpdater_SetPermissions_Call{Call: _e.mock.On("SetPermissions", name, data)}
+}
+
+func (_c *mockICQUserUpdater_SetPermissions_Call) Run(run func(name state.IdentScreenName, data state.ICQPermissions)) *mockICQUserUpdater_SetPermissions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(state.ICQPermissions))
+	})
+	return _c
+}
+
+func (_c *mockICQUserUpdater_SetPermissions_Call) Return(_a0 error) *mockICQUserUpdater_SetPermissions_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockICQUserUpdater_SetPermissions_Call) RunAndReturn(run func(state.IdentScreenName, state.ICQPermissions) error) *mockICQUserUpdater_SetPermissions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SetUserNotes provides a mock function with given fields: name, data
 func (_m *mockICQUserUpdater) SetUserNotes(name state.IdentScreenName, data state.ICQUserNotes) error {
 	ret := _m.Called(name, data)
@@ -81,6 +81,123 @@ func (_c *mockSessionRegistry_AddSession_Call) RunAndReturn(run func(context.Con
 	return _c
 }
 
+// AddSessionIfAbsent provides a mock function with given fields: screenName
+func (_m *mockSessionRegistry) AddSessionIfAbsent(screenName state.DisplayScreenName) (*state.Session, error) {
+	ret := _m.Called(screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddSessionIfAbsent")
+	}
+
+	var r0 *state.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.DisplayScreenName) (*state.Session, error)); ok {
+		return rf(screenName)
+	}
+	if rf, ok := ret.Get(0).(func(state.DisplayScreenName) *state.Session); ok {
+		r0 = rf(screenName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*state.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(state.DisplayScreenName) error); ok {
+		r1 = rf(screenName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockSessionRegistry_AddSessionIfAbsent_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddSessionIfAbsent'
+type mockSessionRegistry_AddSessionIfAbsent_Call struct {
+	*mock.Call
+}
+
+// AddSessionIfAbsent is a helper method to define mock.On call
+//   - screenName state.DisplayScreenName
+func (_e *mockSessionRegistry_Expecter) AddSessionIfAbsent(screenName interface{}) *mockSessionRegistry_AddSessionIfAbsent_Call {
+	return &mockSessionRegistry_AddSessionIfAbsent_Call{Call: _e.mock.On("AddSessionIfAbsent", screenName)}
+}
+
+func (_c *mockSessionRegistry_AddSessionIfAbsent_Call) Run(run func(screenName state.DisplayScreenName)) *mockSessionRegistry_AddSessionIfAbsent_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.DisplayScreenName))
+	})
+	return _c
+}
+
+func (_c *mockSessionRegistry_AddSessionIfAbsent_Call) Return(_a0 *state.Session, _a1 error) *mockSessionRegistry_AddSessionIfAbsent_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockSessionRegistry_AddSessionIfAbsent_Call) RunAndReturn(run func(state.DisplayScreenName) (*state.Session, error)) *mockSessionRegistry_AddSessionIfAbsent_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddSessionInstance provides a mock function with given fields: ctx, screenName
+func (_m *mockSessionRegistry) AddSessionInstance(ctx context.Context, screenName state.DisplayScreenName) (*state.Session, error) {
+	ret := _m.Called(ctx, screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddSessionInstance")
+	}
+
+	var r0 *state.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, state.DisplayScreenName) (*state.Session, error)); ok {
+		return rf(ctx, screenName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, state.DisplayScreenName) *state.Session); ok {
+		r0 = rf(ctx, screenName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*state.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, state.DisplayScreenName) error); ok {
+		r1 = rf(ctx, screenName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockSessionRegistry_AddSessionInstance_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddSessionInstance'
+type mockSessionRegistry_AddSessionInstance_Call struct {
+	*mock.Call
+}
+
+// AddSessionInstance is a helper method to define mock.On call
+//   - ctx context.Context
+//   - screenName state.DisplayScreenName
+func (_e *mockSessionRegistry_Expecter) AddSessionInstance(ctx interface{}, screenName interface{}) *mockSessionRegistry_AddSessionInstance_Call {
+	return &mockSessionRegistry_AddSessionInstance_Call{Call: _e.mock.On("AddSessionInstance", ctx, screenName)}
+}
+
+func (_c *mockSessionRegistry_AddSessionInstance_Call) Run(run func(ctx context.Context, screenName state.DisplayScreenName)) *mockSessionRegistry_AddSessionInstance_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(state.DisplayScreenName))
+	})
+	return _c
+}
+
+func (_c *mockSessionRegistry_AddSessionInstance_Call) Return(_a0 *state.Session, _a1 error) *mockSessionRegistry_AddSessionInstance_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockSessionRegistry_AddSessionInstance_Call) RunAndReturn(run func(context.Context, state.DisplayScreenName) (*state.Session, error)) *mockSessionRegistry_AddSessionInstance_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // RemoveSession provides a mock function with given fields: sess
 func (_m *mockSessionRegistry) RemoveSession(sess *state.Session) {
 	_m.Called(sess)
@@ -114,6 +231,99 @@ func (_c *mockSessionRegistry_RemoveSession_Call) RunAndReturn(run func(*state.S
 	return _c
 }
 
+// RetrieveSession provides a mock function with given fields: screenName
+func (_m *mockSessionRegistry) RetrieveSession(screenName state.IdentScreenName) *state.Session {
+	ret := _m.Called(screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetrieveSession")
+	}
+
+	var r0 *state.Session
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) *state.Session); ok {
+		r0 = rf(screenName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*state.Session)
+		}
+	}
+
+	return r0
+}
+
+// mockSessionRegistry_RetrieveSession_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RetrieveSession'
+type mockSessionRegistry_RetrieveSession_Call struct {
+	*mock.Call
+}
+
+// RetrieveSession is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+func (_e *mockSessionRegistry_Expecter) RetrieveSession(screenName interface{}) *mockSessionRegistry_RetrieveSession_Call {
+	return &mockSessionRegistry_RetrieveSession_Call{Call: _e.mock.On("RetrieveSession", screenName)}
+}
+
+func (_c *mockSessionRegistry_RetrieveSession_Call) Run(run func(screenName state.IdentScreenName)) *mockSessionRegistry_RetrieveSession_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockSessionRegistry_RetrieveSession_Call) Return(_a0 *state.Session) *mockSessionRegistry_RetrieveSession_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockSessionRegistry_RetrieveSession_Call) RunAndReturn(run func(state.IdentScreenName) *state.Session) *mockSessionRegistry_RetrieveSession_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SessionCount provides a mock function with given fields:
+func (_m *mockSessionRegistry) SessionCount() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SessionCount")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// mockSessionRegistry_SessionCount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SessionCount'
+type mockSessionRegistry_SessionCount_Call struct {
+	*mock.Call
+}
+
+// SessionCount is a helper method to define mock.On call
+func (_e *mockSessionRegistry_Expecter) SessionCount() *mockSessionRegistry_SessionCount_Call {
+	return &mockSessionRegistry_SessionCount_Call{Call: _e.mock.On("SessionCount")}
+}
+
+func (_c *mockSessionRegistry_SessionCount_Call) Run(run func()) *mockSessionRegistry_SessionCount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *mockSessionRegistry_SessionCount_Call) Return(_a0 int) *mockSessionRegistry_SessionCount_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockSessionRegistry_SessionCount_Call) RunAndReturn(run func() int) *mockSessionRegistry_SessionCount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // newMockSessionRegistry creates a new instance of mockSessionRegistry. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func newMockSessionRegistry(t interface {
@@ -66,6 +66,53 @@ func (_c *mockUserManager_InsertUser_Call) RunAndReturn(run func(state.User) err
 	return _c
 }
 
+// SetPasswordHash provides a mock function with given fields: screenName, passwordHash
+func (_m *mockUserManager) SetPasswordHash(screenName state.IdentScreenName, passwordHash string) error {
+	ret := _m.Called(screenName, passwordHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPasswordHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, string) error); ok {
+		r0 = rf(screenName, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetPasswordHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetPasswordHash'
+type mockUserManager_SetPasswordHash_Call struct {
+	*mock.Call
+}
+
+// SetPasswordHash is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - passwordHash string
+func (_e *mockUserManager_Expecter) SetPasswordHash(screenName interface{}, passwordHash interface{}) *mockUserManager_SetPasswordHash_Call {
+	return &mockUserManager_SetPasswordHash_Call{Call: _e.mock.On("SetPasswordHash", screenName, passwordHash)}
+}
+
+func (_c *mockUserManager_SetPasswordHash_Call) Run(run func(screenName state.IdentScreenName, passwordHash string)) *mockUserManager_SetPasswordHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetPasswordHash_Call) Return(_a0 error) *mockUserManager_SetPasswordHash_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetPasswordHash_Call) RunAndReturn(run func(state.IdentScreenName, string) error) *mockUserManager_SetPasswordHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // User provides a mock function with given fields: screenName
 func (_m *mockUserManager) User(screenName state.IdentScreenName) (*state.User, error) {
 	ret := _m.Called(screenName)
@@ -5,8 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"log/slog"
 	"net"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,17 +24,64 @@ type OServiceService struct {
 	cfg              config.Config
 	logger           *slog.Logger
 	foodGroups       []uint16
+	// omitCapabilities are stripped from the capability lists that users
+	// set. Nothing is stripped if it's empty.
+	omitCapabilities [][16]byte
+}
+
+// hostFoodGroupVersions is the highest version of each food group that the
+// server supports.
+var hostFoodGroupVersions = map[uint16]uint16{
+	wire.OService:   4,
+	wire.Locate:     1,
+	wire.Buddy:      1,
+	wire.ICBM:       1,
+	wire.Invite:     1,
+	wire.Popup:      1,
+	wire.PermitDeny: 1,
+	wire.UserLookup: 1,
+	wire.Stats:      1,
+	wire.Translate:  1,
+	wire.ChatNav:    1,
+	wire.Chat:       1,
+	wire.ODir:       1,
+	wire.BART:       1,
+	wire.Feedbag:    4,
+	wire.ICQ:        1,
+	wire.BUCP:       1,
+	wire.Alert:      1,
+	wire.Admin:      1,
 }
 
 // ClientVersions informs the server what food group versions the client
-// supports and returns to the client what food group versions it supports.
-// This method simply regurgitates versions supplied by the client in inBody
-// back to the client in a OServiceHostVersions SNAC. The server doesn't
-// attempt to accommodate any particular food group version. The server
-// implicitly accommodates any food group version for Windows AIM clients 5.x.
-// It returns SNAC wire.OServiceHostVersions containing the server's supported
-// food group versions.
-func (s OServiceService) ClientVersions(_ context.Context, frame wire.SNACFrame, inBody wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage {
+// supports and returns to the client what food group versions the server will
+// use. For each food group the client requests that's offered by this service,
+// the negotiated version is the lower of the client's version and the
+// server's highest supported version, which lets clients that advertise an
+// older protocol version keep working. Food groups not offered by this service
+// are omitted. The negotiated versions are recorded in the session. It returns
+// SNAC wire.OServiceHostVersions containing the negotiated food group
+// versions.
+func (s OServiceService) ClientVersions(_ context.Context, sess *state.Session, frame wire.SNACFrame, inBody wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage {
+	var versions []uint16
+	negotiated := make(map[uint16]uint16)
+
+	// versions is a flat list of food group and version pairs
+	for i := 0; i+1 < len(inBody.Versions); i += 2 {
+		foodGroup, clientVersion := inBody.Versions[i], inBody.Versions[i+1]
+		if !slices.Contains(s.foodGroups, foodGroup) {
+			continue
+		}
+		hostVersion, supported := hostFoodGroupVersions[foodGroup]
+		if !supported {
+			continue
+		}
+		version := min(clientVersion, hostVersion)
+		negotiated[foodGroup] = version
+		versions = append(versions, foodGroup, version)
+	}
+	sess.SetFoodGroupVersions(negotiated)
+
 	return wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.OService,
@@ -40,13 +89,14 @@ func (s OServiceService) ClientVersions(_ context.Context, frame wire.SNACFrame,
 			RequestID: frame.RequestID,
 		},
 		Body: wire.SNAC_0x01_0x18_OServiceHostVersions{
-			Versions: inBody.Versions,
+			Versions: versions,
 		},
 	}
 }
 
 // rateLimitSNACV1 is the rate params reply sent to AIM 1.x clients that does
-// not contain LastTime and CurrentState fields.
+// not contain LastTime and CurrentState fields. The rate class levels are
+// filled in from the config by RateParamsQuery.
 var rateLimitSNACV1 = wire.SNAC_0x01_0x07_OServiceRateParamsReply{
 	RateClasses: []struct {
 		ID              uint16
@@ -63,15 +113,8 @@ var rateLimitSNACV1 = wire.SNAC_0x01_0x07_OServiceRateParamsReply{
 		} `oscar:"optional"`
 	}{
 		{
-			ID:              0x01,
-			WindowSize:      0x0050,
-			ClearLevel:      0x09C4,
-			AlertLevel:      0x07D0,
-			LimitLevel:      0x05DC,
-			DisconnectLevel: 0x0320,
-			CurrentLevel:    0x0D69,
-			MaxLevel:        0x1770,
-			V2Params:        nil,
+			ID:       0x01,
+			V2Params: nil,
 		},
 	},
 	RateGroups: []struct {
@@ -108,14 +151,7 @@ var rateLimitSNACV2 = wire.SNAC_0x01_0x07_OServiceRateParamsReply{
 		} `oscar:"optional"`
 	}{
 		{
-			ID:              0x01,
-			WindowSize:      0x0050,
-			ClearLevel:      0x09C4,
-			AlertLevel:      0x07D0,
-			LimitLevel:      0x05DC,
-			DisconnectLevel: 0x0320,
-			CurrentLevel:    0x0D69,
-			MaxLevel:        0x1770,
+			ID: 0x01,
 			V2Params: &struct {
 				LastTime     uint32
 				CurrentState uint8
@@ -458,11 +494,17 @@ func init() {
 // groups. Rate classes define limits based on specific parameters, while rate
 // groups associate these limits with relevant SNAC types.
 //
-// The current implementation does not enforce server-side rate limiting.
-// Instead, the provided values inform the client about the recommended
-// client-side rate limits.
+// All SNACs belong to a single rate class whose levels come from the
+// RATE_LIMIT_* config, so that clients limit themselves to the same rates the
+// server enforces. The class starts at its max level, like the server-side
+// rate tracking.
 //
-// The rate limit values were taken from the example SNAC dump documented here:
+// Clients that identify as AIM 1.x or that negotiated OService version 1 get
+// the original form of the reply, which lacks the LastTime and CurrentState
+// fields.
+//
+// The default rate limit values were taken from the example SNAC dump
+// documented here:
 // https://web.archive.org/web/20221207225518/https://wiki.nina.chat/wiki/Protocols/OSCAR/SNAC/OSERVICE_RATE_PARAMS_REPLY
 //
 // AIM clients silently fail when they expect a rate limit rule that does not
@@ -470,9 +512,20 @@ func init() {
 // server, update this function accordingly.
 func (s OServiceService) RateParamsQuery(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) wire.SNACMessage {
 	limits := rateLimitSNACV2
-	if strings.Contains(sess.ClientID(), "AOL Instant Messenger (TM), version 1.") {
+	if strings.Contains(sess.ClientID(), "AOL Instant Messenger (TM), version 1.") ||
+		sess.FoodGroupVersion(wire.OService) == 1 {
 		limits = rateLimitSNACV1
 	}
+	limits.RateClasses = slices.Clone(limits.RateClasses)
+	for i := range limits.RateClasses {
+		limits.RateClasses[i].WindowSize = s.cfg.RateLimitWindowSize
+		limits.RateClasses[i].ClearLevel = s.cfg.RateLimitClearLevel
+		limits.RateClasses[i].AlertLevel = s.cfg.RateLimitAlertLevel
+		limits.RateClasses[i].LimitLevel = s.cfg.RateLimitLimitLevel
+		limits.RateClasses[i].DisconnectLevel = s.cfg.RateLimitDisconnectLevel
+		limits.RateClasses[i].CurrentLevel = s.cfg.RateLimitMaxLevel
+		limits.RateClasses[i].MaxLevel = s.cfg.RateLimitMaxLevel
+	}
 	return wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.OService,
@@ -502,10 +555,45 @@ func (s OServiceService) UserInfoQuery(_ context.Context, sess *state.Session, i
 // The visibility status is set according to the inFrame TLV entry under key
 // wire.OServiceUserInfoStatus. If the value is 0x0000, set invisible. If set
 // to 0x0100, set invisible. Else, return an error for any other value.
+// Clients that run on mobile devices may also set or clear the
+// wire.OServiceUserFlagWireless flag in the wire.OServiceUserInfoUserFlags TLV
+// so that buddies see the mobile indicator. Other user flags are ignored.
 // It returns SNAC wire.OServiceUserInfoUpdate containing the user's info.
 func (s OServiceService) SetUserInfoFields(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields) (wire.SNACMessage, error) {
-	if status, hasStatus := inBody.Uint32BE(wire.OServiceUserInfoStatus); hasStatus {
+	status, hasStatus := inBody.Uint32BE(wire.OServiceUserInfoStatus)
+	if hasStatus {
 		sess.SetUserStatusBitmask(status)
+	}
+
+	// clients may only change the mobile flag. the other user flags are
+	// controlled by the server.
+	mobileChanged := false
+	if flags, hasFlags := inBody.Uint16BE(wire.OServiceUserInfoUserFlags); hasFlags {
+		isMobile := sess.UserInfoBitmask()&wire.OServiceUserFlagWireless != 0
+		if wantMobile := flags&wire.OServiceUserFlagWireless != 0; wantMobile != isMobile {
+			if wantMobile {
+				sess.SetUserInfoFlag(wire.OServiceUserFlagWireless)
+			} else {
+				sess.ClearUserInfoFlag(wire.OServiceUserFlagWireless)
+			}
+			mobileChanged = true
+		}
+	}
+
+	// relay the full capability list so that buddies see capabilities the
+	// server doesn't know about
+	b, hasCaps := inBody.Bytes(wire.OServiceUserInfoOscarCaps)
+	if hasCaps {
+		caps, err := capabilityList(b, s.omitCapabilities)
+		if err != nil {
+			return wire.SNACMessage{}, err
+		}
+		sess.SetCaps(caps)
+	}
+
+	// clients set their user info during the sign-on sequence. buddies learn
+	// about it when sign-on completes, so don't announce the user early.
+	if (hasStatus || mobileChanged || hasCaps) && sess.SignonComplete() {
 		if sess.Invisible() {
 			if err := s.buddyBroadcaster.BroadcastBuddyDeparted(ctx, sess); err != nil {
 				return wire.SNACMessage{}, err
@@ -538,6 +626,10 @@ func (s OServiceService) IdleNotification(ctx context.Context, sess *state.Sessi
 	} else {
 		sess.SetIdle(time.Duration(bodyIn.IdleTime) * time.Second)
 	}
+	if !sess.SignonComplete() {
+		// buddies learn about idle time when sign-on completes
+		return nil
+	}
 	return s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess)
 }
 
@@ -602,17 +694,25 @@ func (s OServiceService) HostOnline() wire.SNACMessage {
 // NewOServiceServiceForBOS creates a new instance of OServiceServiceForBOS.
 func NewOServiceServiceForBOS(
 	cfg config.Config,
+	build config.Build,
 	messageRelayer MessageRelayer,
 	logger *slog.Logger,
 	cookieIssuer CookieBaker,
 	chatRoomManager ChatRoomRegistry,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	offlineMessageManager OfflineMessageManager,
+	chatRoomBanRetriever ChatRoomBanRetriever,
+	omitCapabilities [][16]byte,
 ) *OServiceServiceForBOS {
 	return &OServiceServiceForBOS{
-		chatRoomManager: chatRoomManager,
-		cookieIssuer:    cookieIssuer,
-		messageRelayer:  messageRelayer,
+		build:                 build,
+		chatRoomBanRetriever:  chatRoomBanRetriever,
+		chatRoomManager:       chatRoomManager,
+		cookieIssuer:          cookieIssuer,
+		messageRelayer:        messageRelayer,
+		offlineMessageManager: offlineMessageManager,
+		sessionRetriever:      sessionRetriever,
 		OServiceService: OServiceService{
 			buddyBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
 			cfg:              cfg,
@@ -629,6 +729,7 @@ func NewOServiceServiceForBOS(
 				wire.PermitDeny,
 				wire.UserLookup,
 			},
+			omitCapabilities: omitCapabilities,
 		},
 	}
 }
@@ -637,9 +738,15 @@ func NewOServiceServiceForBOS(
 // running on the BOS server.
 type OServiceServiceForBOS struct {
 	OServiceService
-	chatRoomManager ChatRoomRegistry
-	cookieIssuer    CookieBaker
-	messageRelayer  MessageRelayer
+	build                config.Build
+	chatRoomBanRetriever ChatRoomBanRetriever
+	chatRoomManager      ChatRoomRegistry
+	cookieIssuer         CookieBaker
+	messageRelayer       MessageRelayer
+	// offlineMessageManager holds instant messages sent to AIM users while
+	// they were offline.
+	offlineMessageManager OfflineMessageManager
+	sessionRetriever      SessionRetriever
 }
 
 // chatLoginCookie represents credentials used to authenticate a user chat
@@ -647,6 +754,9 @@ type OServiceServiceForBOS struct {
 type chatLoginCookie struct {
 	ChatCookie string                  `oscar:"len_prefix=uint8"`
 	ScreenName state.DisplayScreenName `oscar:"len_prefix=uint8"`
+	// AccountCreatedAt is the Unix time that the user's account was created,
+	// or 0 if unknown.
+	AccountCreatedAt uint32
 }
 
 // ServiceRequest handles service discovery, providing a host name and metadata
@@ -777,10 +887,31 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 			return wire.SNACMessage{}, fmt.Errorf("unable to retrieve room info: %w", err)
 		}
 
-		cookie, err := fnIssueCookie(chatLoginCookie{
+		banned, err := s.chatRoomBanRetriever.IsChatRoomBanned(room.Cookie(), sess.IdentScreenName())
+		if err != nil {
+			return wire.SNACMessage{}, fmt.Errorf("unable to check chat room ban: %w", err)
+		}
+		if banned {
+			return wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceErr,
+					RequestID: inFrame.RequestID,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			}, nil
+		}
+
+		c := chatLoginCookie{
 			ChatCookie: room.Cookie(),
 			ScreenName: sess.DisplayScreenName(),
-		})
+		}
+		if createdAt := sess.AccountCreatedAt(); !createdAt.IsZero() {
+			c.AccountCreatedAt = uint32(createdAt.Unix())
+		}
+		cookie, err := fnIssueCookie(c)
 		if err != nil {
 			return wire.SNACMessage{}, err
 		}
@@ -845,7 +976,8 @@ func (s OServiceServiceForBOS) ServiceRequest(ctx context.Context, sess *state.S
 
 // ClientOnline runs when the current user is ready to join.
 // It announces current user's arrival to users who have the current user on
-// their buddy list.
+// their buddy list. If a server name is configured, it also sends the user a
+// message of the day that identifies the server name and build.
 func (s OServiceServiceForBOS) ClientOnline(ctx context.Context, _ wire.SNAC_0x01_0x02_OServiceClientOnline, sess *state.Session) error {
 	sess.SetSignonComplete()
 
@@ -853,9 +985,88 @@ func (s OServiceServiceForBOS) ClientOnline(ctx context.Context, _ wire.SNAC_0x0
 		return fmt.Errorf("unable to send buddy arrival notification: %w", err)
 	}
 
+	if s.cfg.ServerName != "" {
+		s.messageRelayer.RelayToScreenName(ctx, sess.IdentScreenName(), s.serverInfo())
+	}
+
+	if s.cfg.AIMOfflineMessages && !sess.DisplayScreenName().IsUIN() {
+		if err := s.deliverOfflineMessages(ctx, sess); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// deliverOfflineMessages sends the instant messages that were stored while
+// the AIM user was offline, then removes them from the store. Each message is
+// stamped with the time it was originally sent. ICQ users retrieve their
+// offline messages on demand via the ICQ food group instead.
+func (s OServiceServiceForBOS) deliverOfflineMessages(ctx context.Context, sess *state.Session) error {
+	messages, err := s.offlineMessageManager.RetrieveMessages(sess.IdentScreenName())
+	if err != nil {
+		return fmt.Errorf("unable to retrieve offline messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	for _, msg := range messages {
+		userInfo := wire.TLVUserInfo{
+			ScreenName: msg.Sender.String(),
+		}
+		if senderSess := s.sessionRetriever.RetrieveSession(msg.Sender); senderSess != nil {
+			userInfo = senderSess.TLVUserInfo()
+		}
+
+		clientIM := wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			Cookie:      msg.Message.Cookie,
+			ChannelID:   msg.Message.ChannelID,
+			TLVUserInfo: userInfo,
+		}
+		for _, tlv := range msg.Message.TLVRestBlock.TLVList {
+			if tlv.Tag == wire.ICBMTLVRequestHostAck || tlv.Tag == wire.ICBMTLVStore {
+				continue
+			}
+			clientIM.Append(tlv)
+		}
+		clientIM.Append(wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(msg.Sent.Unix())))
+
+		s.messageRelayer.RelayToScreenName(ctx, sess.IdentScreenName(), wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.ICBM,
+				SubGroup:  wire.ICBMChannelMsgToClient,
+			},
+			Body: clientIM,
+		})
+	}
+
+	if err := s.offlineMessageManager.DeleteMessages(sess.IdentScreenName()); err != nil {
+		return fmt.Errorf("unable to delete offline messages: %w", err)
+	}
+	return nil
+}
+
+// serverInfo returns SNAC wire.OServiceMotd containing the server name,
+// version, and commit.
+func (s OServiceServiceForBOS) serverInfo() wire.SNACMessage {
+	msg := fmt.Sprintf("%s (version %s, commit %s)", s.cfg.ServerName, s.build.Version, s.build.Commit)
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.OService,
+			SubGroup:  wire.OServiceMotd,
+		},
+		Body: wire.SNAC_0x01_0x13_OServiceMOTD{
+			MessageType: wire.OServiceMOTDTypeNormal,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.OServiceMOTDTLVMessage, msg),
+				},
+			},
+		},
+	}
+}
+
 // NewOServiceServiceForChat creates a new instance of NewOServiceServiceForChat.
 func NewOServiceServiceForChat(
 	cfg config.Config,
@@ -865,12 +1076,14 @@ func NewOServiceServiceForChat(
 	chatMessageRelayer ChatMessageRelayer,
 	buddyListRetriever BuddyListRetriever,
 	sessionRetriever SessionRetriever,
+	omitCapabilities [][16]byte,
 ) *OServiceServiceForChat {
 	return &OServiceServiceForChat{
 		OServiceService: OServiceService{
 			buddyBroadcaster: newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
 			cfg:              cfg,
 			logger:           logger,
+			omitCapabilities: omitCapabilities,
 			foodGroups: []uint16{
 				wire.OService,
 				wire.Chat,
@@ -907,6 +1120,11 @@ func (s OServiceServiceForChat) ClientOnline(ctx context.Context, _ wire.SNAC_0x
 	sendChatRoomInfoUpdate(ctx, sess, s.chatMessageRelayer, room)
 	alertUserJoined(ctx, sess, s.chatMessageRelayer)
 
+	if topic := room.Topic(); topic != "" {
+		s.chatMessageRelayer.RelayToScreenName(ctx, room.Cookie(), sess.IdentScreenName(),
+			onlineHostChatMsg(0, wire.ICBMChannelMIME, "Topic: "+html.EscapeString(topic)))
+	}
+
 	return nil
 }
 
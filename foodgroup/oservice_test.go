@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
@@ -251,12 +252,22 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 							},
 						},
 					},
+					chatRoomBanRetrieverParams: chatRoomBanRetrieverParams{
+						isChatRoomBannedParams: isChatRoomBannedParams{
+							{
+								cookie:     chatRoom.Cookie(),
+								screenName: state.NewIdentScreenName("me"),
+								result:     false,
+							},
+						},
+					},
 					cookieBakerParams: cookieBakerParams{
 						cookieIssueParams: cookieIssueParams{
 							{
 								dataIn: []byte{
 									0x11, '4', '-', '0', '-', 't', 'h', 'e', '-', 'c', 'h', 'a', 't', '-', 'r', 'o', 'o', 'm',
 									0x02, 'm', 'e',
+									0x0, 0x0, 0x0, 0x0, // no account creation time
 								},
 								cookieOut: []byte("the-auth-cookie"),
 							},
@@ -265,6 +276,60 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 				}
 			}(),
 		},
+		{
+			name: "request info for connecting to chat room the user is banned from, return request denied",
+			cfg: config.Config{
+				OSCARHost: "127.0.0.1",
+				ChatPort:  "1234",
+			},
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x04_OServiceServiceRequest{
+					FoodGroup: wire.Chat,
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(0x01, wire.SNAC_0x01_0x04_TLVRoomInfo{
+								Exchange:       chatRoom.Exchange(),
+								Cookie:         chatRoom.Cookie(),
+								InstanceNumber: chatRoom.InstanceNumber(),
+							}),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceErr,
+					RequestID: 1234,
+				},
+				Body: wire.SNACError{
+					Code: wire.ErrorCodeRequestDenied,
+				},
+			},
+			mockParams: mockParams{
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: chatRoom.Cookie(),
+							room:   chatRoom,
+						},
+					},
+				},
+				chatRoomBanRetrieverParams: chatRoomBanRetrieverParams{
+					isChatRoomBannedParams: isChatRoomBannedParams{
+						{
+							cookie:     chatRoom.Cookie(),
+							screenName: state.NewIdentScreenName("me"),
+							result:     true,
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "request info for connecting to non-existent chat room, return ErrChatRoomNotFound",
 			cfg: config.Config{
@@ -314,6 +379,12 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 					ChatRoomByCookie(params.cookie).
 					Return(params.room, params.err)
 			}
+			chatRoomBanRetriever := newMockChatRoomBanRetriever(t)
+			for _, params := range tc.mockParams.isChatRoomBannedParams {
+				chatRoomBanRetriever.EXPECT().
+					IsChatRoomBanned(params.cookie, params.screenName).
+					Return(params.result, params.err)
+			}
 			cookieIssuer := newMockCookieBaker(t)
 			for _, params := range tc.mockParams.cookieIssueParams {
 				cookieIssuer.EXPECT().
@@ -323,7 +394,7 @@ func TestOServiceServiceForBOS_ServiceRequest(t *testing.T) {
 			//
 			// send input SNAC
 			//
-			svc := NewOServiceServiceForBOS(tc.cfg, nil, slog.Default(), cookieIssuer, chatRoomManager, nil, nil)
+			svc := NewOServiceServiceForBOS(tc.cfg, config.Build{}, nil, slog.Default(), cookieIssuer, chatRoomManager, nil, nil, nil, chatRoomBanRetriever, nil)
 
 			outputSNAC, err := svc.ServiceRequest(nil, tc.userSession, tc.inputSNAC.Frame,
 				tc.inputSNAC.Body.(wire.SNAC_0x01_0x04_OServiceServiceRequest))
@@ -366,7 +437,7 @@ func TestSetUserInfoFields(t *testing.T) {
 	}{
 		{
 			name:        "set user status to visible",
-			userSession: newTestSession("me"),
+			userSession: newTestSession("me", sessOptSignonComplete),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
@@ -401,7 +472,7 @@ func TestSetUserInfoFields(t *testing.T) {
 		},
 		{
 			name:        "set user status to invisible",
-			userSession: newTestSession("me"),
+			userSession: newTestSession("me", sessOptSignonComplete),
 			inputSNAC: wire.SNACMessage{
 				Frame: wire.SNACFrame{
 					RequestID: 1234,
@@ -434,6 +505,128 @@ func TestSetUserInfoFields(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "set mobile flag",
+			userSession: newTestSession("me", sessOptSignonComplete),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagWireless|wire.OServiceUserFlagAdministrator),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceUserInfoUpdate,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+					TLVUserInfo: newTestSession("me", sessOptMobile).TLVUserInfo(),
+				},
+			},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "clear mobile flag",
+			userSession: newTestSession("me", sessOptMobile, sessOptSignonComplete),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.OServiceUserInfoUserFlags, uint16(0)),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceUserInfoUpdate,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+					TLVUserInfo: newTestSession("me").TLVUserInfo(),
+				},
+			},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "set user status before sign-on completes, don't notify buddies",
+			userSession: newTestSession("me"),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.OServiceUserInfoStatus, uint32(0x0000)),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceUserInfoUpdate,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+					TLVUserInfo: newTestSession("me").TLVUserInfo(),
+				},
+			},
+		},
+		{
+			name:        "mobile flag unchanged",
+			userSession: newTestSession("me", sessOptMobile),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+					TLVRestBlock: wire.TLVRestBlock{
+						TLVList: wire.TLVList{
+							wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagWireless),
+						},
+					},
+				},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceUserInfoUpdate,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x0F_OServiceUserInfoUpdate{
+					TLVUserInfo: newTestSession("me", sessOptMobile).TLVUserInfo(),
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -465,6 +658,66 @@ func TestSetUserInfoFields(t *testing.T) {
 	}
 }
 
+func TestSetUserInfoFields_RelaysCapabilities(t *testing.T) {
+	ctx := context.Background()
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	mySess, err := sessionManager.AddSession(ctx, "me")
+	require.NoError(t, err)
+	mySess.SetSignonComplete()
+	buddySess, err := sessionManager.AddSession(ctx, "buddy")
+	require.NoError(t, err)
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		AllRelationships(state.NewIdentScreenName("me"), []state.IdentScreenName(nil)).
+		Return([]state.Relationship{
+			{
+				User:          state.NewIdentScreenName("buddy"),
+				IsOnTheirList: true,
+			},
+		}, nil)
+	buddyListRetriever.EXPECT().
+		BuddyIconRefByName(state.NewIdentScreenName("me")).
+		Return(nil, nil)
+
+	svc := OServiceService{
+		cfg:              config.Config{},
+		logger:           slog.Default(),
+		buddyBroadcaster: newBuddyNotifier(buddyListRetriever, sessionManager, sessionManager),
+	}
+
+	caps := [][16]byte{
+		// known: chat
+		wire.CapChat,
+		// known: file transfer
+		wire.CapFileTransfer,
+		// unknown to the server
+		{0xDE, 0xAD, 0xBE, 0xEF, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C},
+	}
+	_, err = svc.SetUserInfoFields(ctx, mySess, wire.SNACFrame{}, wire.SNAC_0x01_0x1E_OServiceSetUserInfoFields{
+		TLVRestBlock: wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.OServiceUserInfoOscarCaps, caps),
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, caps, mySess.Caps())
+
+	// the buddy sees every capability, including the unknown one
+	msg := receiveSNAC(t, buddySess)
+	assert.Equal(t, wire.BuddyArrived, msg.Frame.SubGroup)
+	userInfo := msg.Body.(wire.SNAC_0x03_0x0B_BuddyArrived).TLVUserInfo
+	b, ok := userInfo.Bytes(wire.OServiceUserInfoOscarCaps)
+	require.True(t, ok)
+	var want []byte
+	for _, c := range caps {
+		want = append(want, c[:]...)
+	}
+	assert.Equal(t, want, b)
+}
+
 func TestOServiceService_RateParamsQuery(t *testing.T) {
 	expectRateGroups := []struct {
 		ID    uint16
@@ -1480,7 +1733,7 @@ func TestOServiceService_RateParamsQuery(t *testing.T) {
 							AlertLevel:      0x000007D0,
 							LimitLevel:      0x000005DC,
 							DisconnectLevel: 0x00000320,
-							CurrentLevel:    0x00000D69,
+							CurrentLevel:    0x00001770,
 							MaxLevel:        0x00001770,
 							V2Params: &struct {
 								LastTime     uint32
@@ -1529,7 +1782,49 @@ func TestOServiceService_RateParamsQuery(t *testing.T) {
 							AlertLevel:      0x000007D0,
 							LimitLevel:      0x000005DC,
 							DisconnectLevel: 0x00000320,
-							CurrentLevel:    0x00000D69,
+							CurrentLevel:    0x00001770,
+							MaxLevel:        0x00001770,
+						},
+					},
+					RateGroups: expectRateGroups,
+				},
+			},
+		},
+		{
+			name:        "get rate limits for client that negotiated OService version 1",
+			userSession: newTestSession("me", sessFoodGroupVersions(map[uint16]uint16{wire.OService: 1})),
+			inputSNAC: wire.SNACMessage{
+				Frame: wire.SNACFrame{RequestID: 1234},
+			},
+			expectOutput: wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceRateParamsReply,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x07_OServiceRateParamsReply{
+					RateClasses: []struct {
+						ID              uint16
+						WindowSize      uint32
+						ClearLevel      uint32
+						AlertLevel      uint32
+						LimitLevel      uint32
+						DisconnectLevel uint32
+						CurrentLevel    uint32
+						MaxLevel        uint32
+						V2Params        *struct {
+							LastTime     uint32
+							CurrentState uint8
+						} `oscar:"optional"`
+					}{
+						{
+							ID:              0x0001,
+							WindowSize:      0x00000050,
+							ClearLevel:      0x000009C4,
+							AlertLevel:      0x000007D0,
+							LimitLevel:      0x000005DC,
+							DisconnectLevel: 0x00000320,
+							CurrentLevel:    0x00001770,
 							MaxLevel:        0x00001770,
 						},
 					},
@@ -1542,7 +1837,14 @@ func TestOServiceService_RateParamsQuery(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			svc := OServiceService{
-				cfg:    config.Config{},
+				cfg: config.Config{
+					RateLimitWindowSize:      0x50,
+					RateLimitClearLevel:      0x9C4,
+					RateLimitAlertLevel:      0x7D0,
+					RateLimitLimitLevel:      0x5DC,
+					RateLimitDisconnectLevel: 0x320,
+					RateLimitMaxLevel:        0x1770,
+				},
 				logger: slog.Default(),
 			}
 			have := svc.RateParamsQuery(nil, tc.userSession, tc.inputSNAC.Frame)
@@ -1553,7 +1855,7 @@ func TestOServiceService_RateParamsQuery(t *testing.T) {
 
 func TestOServiceServiceForBOS_OServiceHostOnline(t *testing.T) {
 	cookieIssuer := newMockCookieBaker(t)
-	svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), cookieIssuer, nil, nil, nil)
+	svc := NewOServiceServiceForBOS(config.Config{}, config.Build{}, nil, slog.Default(), cookieIssuer, nil, nil, nil, nil, nil, nil)
 
 	want := wire.SNACMessage{
 		Frame: wire.SNACFrame{
@@ -1581,7 +1883,7 @@ func TestOServiceServiceForBOS_OServiceHostOnline(t *testing.T) {
 }
 
 func TestOServiceServiceForChat_OServiceHostOnline(t *testing.T) {
-	svc := NewOServiceServiceForChat(config.Config{}, slog.Default(), nil, nil, nil, nil, nil)
+	svc := NewOServiceServiceForChat(config.Config{}, slog.Default(), nil, nil, nil, nil, nil, nil)
 
 	want := wire.SNACMessage{
 		Frame: wire.SNACFrame{
@@ -1601,29 +1903,100 @@ func TestOServiceServiceForChat_OServiceHostOnline(t *testing.T) {
 }
 
 func TestOServiceService_ClientVersions(t *testing.T) {
-	svc := OServiceService{
-		cfg:    config.Config{},
-		logger: slog.Default(),
-	}
-
-	want := wire.SNACMessage{
-		Frame: wire.SNACFrame{
-			FoodGroup: wire.OService,
-			SubGroup:  wire.OServiceHostVersions,
-			RequestID: 1234,
+	tests := []struct {
+		name         string
+		foodGroups   []uint16
+		clientVers   []uint16
+		wantVersions []uint16
+		wantSession  map[uint16]uint16
+	}{
+		{
+			name:         "client versions at or below host versions are kept",
+			foodGroups:   []uint16{wire.OService, wire.Feedbag, wire.ICBM},
+			clientVers:   []uint16{wire.OService, 3, wire.Feedbag, 1, wire.ICBM, 1},
+			wantVersions: []uint16{wire.OService, 3, wire.Feedbag, 1, wire.ICBM, 1},
+			wantSession:  map[uint16]uint16{wire.OService: 3, wire.Feedbag: 1, wire.ICBM: 1},
+		},
+		{
+			name:         "client versions above host versions are lowered",
+			foodGroups:   []uint16{wire.OService, wire.Feedbag},
+			clientVers:   []uint16{wire.OService, 9, wire.Feedbag, 9},
+			wantVersions: []uint16{wire.OService, 4, wire.Feedbag, 4},
+			wantSession:  map[uint16]uint16{wire.OService: 4, wire.Feedbag: 4},
 		},
-		Body: wire.SNAC_0x01_0x18_OServiceHostVersions{
-			Versions: []uint16{5, 6, 7, 8},
+		{
+			name:         "client downgrades to a low version",
+			foodGroups:   []uint16{wire.OService, wire.Chat},
+			clientVers:   []uint16{wire.OService, 1, wire.Chat, 1},
+			wantVersions: []uint16{wire.OService, 1, wire.Chat, 1},
+			wantSession:  map[uint16]uint16{wire.OService: 1, wire.Chat: 1},
+		},
+		{
+			name:         "food groups not offered by the service are omitted",
+			foodGroups:   []uint16{wire.OService, wire.Chat},
+			clientVers:   []uint16{wire.OService, 3, wire.Feedbag, 4, 0x1234, 1},
+			wantVersions: []uint16{wire.OService, 3},
+			wantSession:  map[uint16]uint16{wire.OService: 3},
+		},
+		{
+			name:         "trailing food group without a version is ignored",
+			foodGroups:   []uint16{wire.OService},
+			clientVers:   []uint16{wire.OService, 3, wire.OService},
+			wantVersions: []uint16{wire.OService, 3},
+			wantSession:  map[uint16]uint16{wire.OService: 3},
 		},
 	}
 
-	have := svc.ClientVersions(nil, wire.SNACFrame{
-		RequestID: 1234,
-	}, wire.SNAC_0x01_0x17_OServiceClientVersions{
-		Versions: []uint16{5, 6, 7, 8},
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := OServiceService{
+				cfg:        config.Config{},
+				foodGroups: tt.foodGroups,
+				logger:     slog.Default(),
+			}
+			sess := newTestSession("me")
+
+			want := wire.SNACMessage{
+				Frame: wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceHostVersions,
+					RequestID: 1234,
+				},
+				Body: wire.SNAC_0x01_0x18_OServiceHostVersions{
+					Versions: tt.wantVersions,
+				},
+			}
+
+			have := svc.ClientVersions(nil, sess, wire.SNACFrame{
+				RequestID: 1234,
+			}, wire.SNAC_0x01_0x17_OServiceClientVersions{
+				Versions: tt.clientVers,
+			})
+
+			assert.Equal(t, want, have)
+			for foodGroup, version := range tt.wantSession {
+				assert.Equal(t, version, sess.FoodGroupVersion(foodGroup))
+			}
+		})
+	}
+}
+
+// TestOServiceService_ClientVersions_Downgrade verifies that a client that
+// negotiates a low OService version gets a rate limit reply it can parse.
+func TestOServiceService_ClientVersions_Downgrade(t *testing.T) {
+	svc := OServiceService{
+		cfg:        config.Config{},
+		foodGroups: []uint16{wire.OService, wire.ICBM},
+		logger:     slog.Default(),
+	}
+	sess := newTestSession("me")
+
+	svc.ClientVersions(nil, sess, wire.SNACFrame{}, wire.SNAC_0x01_0x17_OServiceClientVersions{
+		Versions: []uint16{wire.OService, 1, wire.ICBM, 1},
 	})
 
-	assert.Equal(t, want, have)
+	reply := svc.RateParamsQuery(nil, sess, wire.SNACFrame{})
+	assert.Equal(t, rateLimitSNACV1, reply.Body)
 }
 
 func TestOServiceService_UserInfoQuery(t *testing.T) {
@@ -1661,7 +2034,7 @@ func TestOServiceService_IdleNotification(t *testing.T) {
 	}{
 		{
 			name: "set idle from active",
-			sess: newTestSession("me"),
+			sess: newTestSession("me", sessOptSignonComplete),
 			bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
 				IdleTime: 90,
 			},
@@ -1677,7 +2050,7 @@ func TestOServiceService_IdleNotification(t *testing.T) {
 		},
 		{
 			name: "set active from idle",
-			sess: newTestSession("me", sessOptIdle(90*time.Second)),
+			sess: newTestSession("me", sessOptIdle(90*time.Second), sessOptSignonComplete),
 			bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
 				IdleTime: 0,
 			},
@@ -1691,6 +2064,13 @@ func TestOServiceService_IdleNotification(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "set idle before sign-on completes, don't notify buddies",
+			sess: newTestSession("me"),
+			bodyIn: wire.SNAC_0x01_0x11_OServiceIdleNotification{
+				IdleTime: 90,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1715,6 +2095,10 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 	tests := []struct {
 		// name is the name of the test
 		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// build is the server build information
+		build config.Build
 		// joiningChatter is the session of the arriving user
 		sess *state.Session
 		// bodyIn is the SNAC body sent from the arriving user's client to the
@@ -1745,6 +2129,194 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 			},
 			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
 		},
+		{
+			name: "notify that user is online and send server info",
+			cfg: config.Config{
+				ServerName: "Retro AIM Server",
+			},
+			build: config.Build{
+				Version: "v1.2.3",
+				Commit:  "abc123",
+			},
+			sess:   newTestSession("me", sessOptCannedSignonTime),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("me"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.OService,
+									SubGroup:  wire.OServiceMotd,
+								},
+								Body: wire.SNAC_0x01_0x13_OServiceMOTD{
+									MessageType: wire.OServiceMOTDTypeNormal,
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.OServiceMOTDTLVMessage, "Retro AIM Server (version v1.2.3, commit abc123)"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+		},
+		{
+			name: "notify that user is online and deliver AIM offline messages",
+			cfg: config.Config{
+				AIMOfflineMessages: true,
+			},
+			sess:   newTestSession("me", sessOptCannedSignonTime),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("me"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					retrieveMessagesParams: retrieveMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+							messagesOut: []state.OfflineMessage{
+								{
+									Sender:    state.NewIdentScreenName("offline-sender"),
+									Recipient: state.NewIdentScreenName("me"),
+									Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+										Cookie:     1234,
+										ChannelID:  wire.ICBMChannelIM,
+										ScreenName: "me",
+										TLVRestBlock: wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{1, 2, 3}),
+												wire.NewTLVBE(wire.ICBMTLVRequestHostAck, []byte{}),
+											},
+										},
+									},
+									Sent: time.Unix(1000, 0),
+								},
+								{
+									Sender:    state.NewIdentScreenName("online-sender"),
+									Recipient: state.NewIdentScreenName("me"),
+									Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+										Cookie:     5678,
+										ChannelID:  wire.ICBMChannelIM,
+										ScreenName: "me",
+										TLVRestBlock: wire.TLVRestBlock{
+											TLVList: wire.TLVList{
+												wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{4, 5, 6}),
+											},
+										},
+									},
+									Sent: time.Unix(2000, 0),
+								},
+							},
+						},
+					},
+					deleteMessagesParams: deleteMessagesParams{
+						{
+							recipIn: state.NewIdentScreenName("me"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionParams: retrieveSessionParams{
+						{
+							screenName: state.NewIdentScreenName("offline-sender"),
+							result:     nil,
+						},
+						{
+							screenName: state.NewIdentScreenName("online-sender"),
+							result:     newTestSession("online-sender", sessOptCannedSignonTime),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									Cookie:    1234,
+									ChannelID: wire.ICBMChannelIM,
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "offline-sender",
+									},
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{1, 2, 3}),
+											wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(1000)),
+										},
+									},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("me"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									Cookie:      5678,
+									ChannelID:   wire.ICBMChannelIM,
+									TLVUserInfo: newTestSession("online-sender", sessOptCannedSignonTime).TLVUserInfo(),
+									TLVRestBlock: wire.TLVRestBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte{4, 5, 6}),
+											wire.NewTLVBE(wire.ICBMTLVSendTime, uint32(2000)),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("me", sessOptCannedSignonTime, sessOptSignonComplete),
+		},
+		{
+			name: "notify that ICQ user is online without delivering offline messages",
+			cfg: config.Config{
+				AIMOfflineMessages: true,
+			},
+			sess:   newTestSession("100003", sessOptCannedSignonTime),
+			bodyIn: wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:             state.NewIdentScreenName("100003"),
+							filter:           nil,
+							doSendDepartures: false,
+						},
+					},
+				},
+			},
+			wantSess: newTestSession("100003", sessOptCannedSignonTime, sessOptSignonComplete),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1754,8 +2326,31 @@ func TestOServiceServiceForBOS_ClientOnline(t *testing.T) {
 					BroadcastVisibility(mock.Anything, matchSession(params.from), params.filter, params.doSendDepartures).
 					Return(params.err)
 			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tt.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tt.mockParams.retrieveMessagesParams {
+				offlineMessageManager.EXPECT().
+					RetrieveMessages(params.recipIn).
+					Return(params.messagesOut, params.err)
+			}
+			for _, params := range tt.mockParams.deleteMessagesParams {
+				offlineMessageManager.EXPECT().
+					DeleteMessages(params.recipIn).
+					Return(params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tt.mockParams.retrieveSessionParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
 
-			svc := NewOServiceServiceForBOS(config.Config{}, nil, slog.Default(), nil, nil, nil, nil)
+			svc := NewOServiceServiceForBOS(tt.cfg, tt.build, messageRelayer, slog.Default(), nil, nil, nil, sessionRetriever, offlineMessageManager, nil, nil)
 			svc.buddyBroadcaster = buddyUpdateBroadcaster
 			haveErr := svc.ClientOnline(nil, tt.bodyIn, tt.sess)
 			assert.ErrorIs(t, tt.wantErr, haveErr)
@@ -1768,6 +2363,31 @@ func TestOServiceServiceForChat_ClientOnline(t *testing.T) {
 	chatRoom := state.NewChatRoom("the-chat-room", state.NewIdentScreenName("creator"), state.PrivateExchange)
 	chatter1 := newTestSession("chatter-1", sessOptChatRoomCookie(chatRoom.Cookie()))
 	chatter2 := newTestSession("chatter-2", sessOptChatRoomCookie(chatRoom.Cookie()))
+	spectator := newTestSession("spectator", sessOptChatRoomCookie(chatRoom.Cookie()), sessOptSpectator)
+
+	roomInfoUpdate := func(to state.IdentScreenName) chatRelayToScreenNameParams {
+		return chatRelayToScreenNameParams{
+			{
+				cookie:     chatRoom.Cookie(),
+				screenName: to,
+				message: wire.SNACMessage{
+					Frame: wire.SNACFrame{
+						FoodGroup: wire.Chat,
+						SubGroup:  wire.ChatRoomInfoUpdate,
+					},
+					Body: wire.SNAC_0x0E_0x02_ChatRoomInfoUpdate{
+						Exchange:       chatRoom.Exchange(),
+						Cookie:         chatRoom.Cookie(),
+						InstanceNumber: chatRoom.InstanceNumber(),
+						DetailLevel:    chatRoom.DetailLevel(),
+						TLVBlock: wire.TLVBlock{
+							TLVList: chatRoom.TLVList(),
+						},
+					},
+				},
+			},
+		}
+	}
 
 	tests := []struct {
 		// name is the name of the test
@@ -1862,6 +2482,162 @@ func TestOServiceServiceForChat_ClientOnline(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:           "upon joining, hide spectators from the participant list sent to joining user",
+			joiningChatter: chatter1,
+			bodyIn:         wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatRelayToAllExceptParams: chatRelayToAllExceptParams{
+						{
+							screenName: state.NewIdentScreenName("chatter-1"),
+							cookie:     chatRoom.Cookie(),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Chat,
+									SubGroup:  wire.ChatUsersJoined,
+								},
+								Body: wire.SNAC_0x0E_0x03_ChatUsersJoined{
+									Users: []wire.TLVUserInfo{
+										chatter1.TLVUserInfo(),
+									},
+								},
+							},
+						},
+					},
+					chatAllSessionsParams: chatAllSessionsParams{
+						{
+							cookie: chatRoom.Cookie(),
+							sessions: []*state.Session{
+								chatter1,
+								spectator,
+								chatter2,
+							},
+						},
+					},
+					chatRelayToScreenNameParams: append(roomInfoUpdate(chatter1.IdentScreenName()),
+						chatRelayToScreenNameParams{
+							{
+								cookie:     chatRoom.Cookie(),
+								screenName: chatter1.IdentScreenName(),
+								message: wire.SNACMessage{
+									Frame: wire.SNACFrame{
+										FoodGroup: wire.Chat,
+										SubGroup:  wire.ChatUsersJoined,
+									},
+									Body: wire.SNAC_0x0E_0x03_ChatUsersJoined{
+										Users: []wire.TLVUserInfo{
+											chatter1.TLVUserInfo(),
+											chatter2.TLVUserInfo(),
+										},
+									},
+								},
+							},
+						}...),
+				},
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: chatRoom.Cookie(),
+							room:   chatRoom,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "upon joining as spectator, send chat room metadata and participant list; don't alert arrival to existing participants",
+			joiningChatter: spectator,
+			bodyIn:         wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatAllSessionsParams: chatAllSessionsParams{
+						{
+							cookie: chatRoom.Cookie(),
+							sessions: []*state.Session{
+								chatter1,
+								spectator,
+							},
+						},
+					},
+					chatRelayToScreenNameParams: append(roomInfoUpdate(spectator.IdentScreenName()),
+						chatRelayToScreenNameParams{
+							{
+								cookie:     chatRoom.Cookie(),
+								screenName: spectator.IdentScreenName(),
+								message: wire.SNACMessage{
+									Frame: wire.SNACFrame{
+										FoodGroup: wire.Chat,
+										SubGroup:  wire.ChatUsersJoined,
+									},
+									Body: wire.SNAC_0x0E_0x03_ChatUsersJoined{
+										Users: []wire.TLVUserInfo{
+											chatter1.TLVUserInfo(),
+											spectator.TLVUserInfo(),
+										},
+									},
+								},
+							},
+						}...),
+				},
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: chatRoom.Cookie(),
+							room:   chatRoom,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "upon joining a room with a topic, send the topic to joining user",
+			joiningChatter: spectator,
+			bodyIn:         wire.SNAC_0x01_0x02_OServiceClientOnline{},
+			mockParams: mockParams{
+				chatMessageRelayerParams: chatMessageRelayerParams{
+					chatAllSessionsParams: chatAllSessionsParams{
+						{
+							cookie: chatRoom.Cookie(),
+							sessions: []*state.Session{
+								spectator,
+							},
+						},
+					},
+					chatRelayToScreenNameParams: append(roomInfoUpdate(spectator.IdentScreenName()),
+						chatRelayToScreenNameParams{
+							{
+								cookie:     chatRoom.Cookie(),
+								screenName: spectator.IdentScreenName(),
+								message: wire.SNACMessage{
+									Frame: wire.SNACFrame{
+										FoodGroup: wire.Chat,
+										SubGroup:  wire.ChatUsersJoined,
+									},
+									Body: wire.SNAC_0x0E_0x03_ChatUsersJoined{
+										Users: []wire.TLVUserInfo{
+											spectator.TLVUserInfo(),
+										},
+									},
+								},
+							},
+							{
+								cookie:     chatRoom.Cookie(),
+								screenName: spectator.IdentScreenName(),
+								message:    onlineHostChatMsg(0, wire.ICBMChannelMIME, "Topic: cats &amp; dogs"),
+							},
+						}...),
+				},
+				chatRoomRegistryParams: chatRoomRegistryParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: chatRoom.Cookie(),
+							room:   chatRoom.WithTopic("cats & dogs"),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1886,7 +2662,7 @@ func TestOServiceServiceForChat_ClientOnline(t *testing.T) {
 					RelayToScreenName(mock.Anything, params.cookie, params.screenName, params.message)
 			}
 
-			svc := NewOServiceServiceForChat(config.Config{}, slog.Default(), nil, chatRoomManager, chatMessageRelayer, nil, nil)
+			svc := NewOServiceServiceForChat(config.Config{}, slog.Default(), nil, chatRoomManager, chatMessageRelayer, nil, nil, nil)
 
 			haveErr := svc.ClientOnline(nil, wire.SNAC_0x01_0x02_OServiceClientOnline{}, tt.joiningChatter)
 			assert.ErrorIs(t, tt.wantErr, haveErr)
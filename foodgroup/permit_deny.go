@@ -142,6 +142,20 @@ func (s PermitDenyService) DelPermListEntries(
 	return s.maybeBroadcastVisibility(ctx, sess, body.Users)
 }
 
+// SetGroupPermitMask sets the classes of users (AIM, ICQ, AOL staff,
+// unconfirmed, etc.) that you can interact with. Users whose class isn't in
+// the mask are treated as if they were on your block list: they can't message
+// you or see your presence, and you can't see theirs. Your buddy list and your
+// relations' buddy lists are updated to reflect the new mask.
+func (s PermitDenyService) SetGroupPermitMask(
+	ctx context.Context,
+	sess *state.Session,
+	body wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask,
+) error {
+	sess.SetPermitMask(uint16(body.PermMask))
+	return s.maybeBroadcastVisibility(ctx, sess, nil)
+}
+
 // maybeBroadcastVisibility broadcasts visibility changes to a list users only
 // if the client has finished signing in, which prevents duplicate arrival
 // notifications, which are ultimately sent at the end of the sign on flow.
@@ -2,8 +2,11 @@ package foodgroup
 
 import (
 	"context"
+	"log/slog"
+	"path/filepath"
 	"testing"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/stretchr/testify/assert"
 
@@ -665,3 +668,223 @@ func TestPermitDenyService_DelPermListEntries(t *testing.T) {
 		})
 	}
 }
+
+// TestPermitDenyService_DenyOnlineUser_LivePresence verifies that blocking and
+// unblocking an online buddy updates both users' presence without either of
+// them having to reconnect.
+func TestPermitDenyService_DenyOnlineUser_LivePresence(t *testing.T) {
+	userStore, err := state.NewSQLiteUserStore(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	// both users have each other on their client-side buddy lists
+	for _, pair := range [][2]state.IdentScreenName{{me, them}, {them, me}} {
+		assert.NoError(t, userStore.SetPDMode(pair[0], wire.FeedbagPDModePermitAll))
+		assert.NoError(t, userStore.AddBuddy(pair[0], pair[1]))
+	}
+
+	mySess, err := sessionManager.AddSession(context.Background(), "me")
+	assert.NoError(t, err)
+	mySess.SetSignonComplete()
+	theirSess, err := sessionManager.AddSession(context.Background(), "them")
+	assert.NoError(t, err)
+	theirSess.SetSignonComplete()
+
+	// receive returns the type of the next message relayed to sess and the
+	// screen name of the user it pertains to
+	receive := func(sess *state.Session) (uint16, string) {
+		select {
+		case msg := <-sess.ReceiveMessage():
+			switch body := msg.Body.(type) {
+			case wire.SNAC_0x03_0x0B_BuddyArrived:
+				return msg.Frame.SubGroup, body.ScreenName
+			case wire.SNAC_0x03_0x0C_BuddyDeparted:
+				return msg.Frame.SubGroup, body.ScreenName
+			}
+			t.Fatalf("unexpected message %+v", msg)
+		default:
+			t.Fatalf("expected a message for %s", sess.IdentScreenName())
+		}
+		return 0, ""
+	}
+
+	svc := NewPermitDenyService(userStore, userStore, sessionManager, sessionManager)
+
+	// block them: each user should see the other go offline
+	err = svc.AddDenyListEntries(context.Background(), mySess, wire.SNAC_0x09_0x07_PermitDenyAddDenyListEntries{
+		Users: []struct {
+			ScreenName string `oscar:"len_prefix=uint8"`
+		}{
+			{ScreenName: "them"},
+		},
+	})
+	assert.NoError(t, err)
+
+	subGroup, sn := receive(theirSess)
+	assert.Equal(t, wire.BuddyDeparted, subGroup)
+	assert.Equal(t, me.String(), sn)
+	subGroup, sn = receive(mySess)
+	assert.Equal(t, wire.BuddyDeparted, subGroup)
+	assert.Equal(t, them.String(), sn)
+
+	// their presence changes must stay hidden from me while they're blocked
+	buddyService := NewBuddyService(config.Config{}, sessionManager, userStore, userStore, sessionManager, userStore, userStore)
+	assert.NoError(t, buddyService.BroadcastBuddyArrived(context.Background(), theirSess))
+	assert.Empty(t, mySess.ReceiveMessage())
+
+	// unblock them: each user should see the other come back online
+	err = svc.DelDenyListEntries(context.Background(), mySess, wire.SNAC_0x09_0x08_PermitDenyDelDenyListEntries{
+		Users: []struct {
+			ScreenName string `oscar:"len_prefix=uint8"`
+		}{
+			{ScreenName: "them"},
+		},
+	})
+	assert.NoError(t, err)
+
+	subGroup, sn = receive(theirSess)
+	assert.Equal(t, wire.BuddyArrived, subGroup)
+	assert.Equal(t, me.String(), sn)
+	subGroup, sn = receive(mySess)
+	assert.Equal(t, wire.BuddyArrived, subGroup)
+	assert.Equal(t, them.String(), sn)
+}
+
+func TestPermitDenyService_SetGroupPermitMask(t *testing.T) {
+	tests := []struct {
+		// name is the name of the test
+		name string
+		// sess is the client session
+		sess *state.Session
+		// bodyIn is the input SNAC
+		bodyIn wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask
+		// wantMask is the group permit mask set on the session
+		wantMask uint16
+		// mockParams is the list of params sent to mocks that satisfy this
+		// method's dependencies
+		mockParams mockParams
+	}{
+		{
+			name: "set mask after sign on",
+			sess: newTestSession("me", sessOptSignonComplete),
+			bodyIn: wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask{
+				PermMask: uint32(wire.OServiceUserFlagOSCARFree | wire.OServiceUserFlagAOL),
+			},
+			wantMask: wire.OServiceUserFlagOSCARFree | wire.OServiceUserFlagAOL,
+			mockParams: mockParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastVisibilityParams: broadcastVisibilityParams{
+						{
+							from:   state.NewIdentScreenName("me"),
+							filter: nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "set mask during sign on",
+			sess: newTestSession("me"),
+			bodyIn: wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask{
+				PermMask: uint32(wire.OServiceUserFlagICQ),
+			},
+			wantMask: wire.OServiceUserFlagICQ,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBuddyBroadcaster := newMockbuddyBroadcaster(t)
+			for _, item := range tt.mockParams.broadcastVisibilityParams {
+				mockBuddyBroadcaster.EXPECT().
+					BroadcastVisibility(context.TODO(), matchSession(item.from), item.filter, true).
+					Return(item.err)
+			}
+
+			svc := PermitDenyService{
+				buddyBroadcaster: mockBuddyBroadcaster,
+			}
+			err := svc.SetGroupPermitMask(context.TODO(), tt.sess, tt.bodyIn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMask, tt.sess.PermitMask())
+		})
+	}
+}
+
+// TestPermitDenyService_SetGroupPermitMask_LivePresence verifies that
+// excluding an online buddy's user class via the group permit mask hides both
+// users' presence from each other until the mask permits the class again.
+func TestPermitDenyService_SetGroupPermitMask_LivePresence(t *testing.T) {
+	userStore, err := state.NewSQLiteUserStore(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	me := state.NewIdentScreenName("me")
+	them := state.NewIdentScreenName("them")
+
+	// both users have each other on their client-side buddy lists
+	for _, pair := range [][2]state.IdentScreenName{{me, them}, {them, me}} {
+		assert.NoError(t, userStore.SetPDMode(pair[0], wire.FeedbagPDModePermitAll))
+		assert.NoError(t, userStore.AddBuddy(pair[0], pair[1]))
+	}
+
+	mySess, err := sessionManager.AddSession(context.Background(), "me")
+	assert.NoError(t, err)
+	mySess.SetSignonComplete()
+	theirSess, err := sessionManager.AddSession(context.Background(), "them")
+	assert.NoError(t, err)
+	theirSess.SetSignonComplete()
+
+	// receive returns the type of the next message relayed to sess and the
+	// screen name of the user it pertains to
+	receive := func(sess *state.Session) (uint16, string) {
+		select {
+		case msg := <-sess.ReceiveMessage():
+			switch body := msg.Body.(type) {
+			case wire.SNAC_0x03_0x0B_BuddyArrived:
+				return msg.Frame.SubGroup, body.ScreenName
+			case wire.SNAC_0x03_0x0C_BuddyDeparted:
+				return msg.Frame.SubGroup, body.ScreenName
+			}
+			t.Fatalf("unexpected message %+v", msg)
+		default:
+			t.Fatalf("expected a message for %s", sess.IdentScreenName())
+		}
+		return 0, ""
+	}
+
+	svc := NewPermitDenyService(userStore, userStore, sessionManager, sessionManager)
+
+	// permit ICQ users only: each AIM user should see the other go offline
+	err = svc.SetGroupPermitMask(context.Background(), mySess, wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask{
+		PermMask: uint32(wire.OServiceUserFlagICQ),
+	})
+	assert.NoError(t, err)
+
+	subGroup, sn := receive(theirSess)
+	assert.Equal(t, wire.BuddyDeparted, subGroup)
+	assert.Equal(t, me.String(), sn)
+	subGroup, sn = receive(mySess)
+	assert.Equal(t, wire.BuddyDeparted, subGroup)
+	assert.Equal(t, them.String(), sn)
+
+	// their presence changes must stay hidden from me while they're masked
+	buddyService := NewBuddyService(config.Config{}, sessionManager, userStore, userStore, sessionManager, userStore, userStore)
+	assert.NoError(t, buddyService.BroadcastBuddyArrived(context.Background(), theirSess))
+	assert.Empty(t, mySess.ReceiveMessage())
+
+	// permit AIM users again: each user should see the other come back online
+	err = svc.SetGroupPermitMask(context.Background(), mySess, wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask{
+		PermMask: uint32(wire.OServiceUserFlagOSCARFree | wire.OServiceUserFlagICQ),
+	})
+	assert.NoError(t, err)
+
+	subGroup, sn = receive(theirSess)
+	assert.Equal(t, wire.BuddyArrived, subGroup)
+	assert.Equal(t, me.String(), sn)
+	subGroup, sn = receive(mySess)
+	assert.Equal(t, wire.BuddyArrived, subGroup)
+	assert.Equal(t, them.String(), sn)
+}
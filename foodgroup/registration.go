@@ -0,0 +1,88 @@
+package foodgroup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// NewRegistrationWebhook creates a new instance of RegistrationWebhook that
+// uses httpClient to ask the service at webhookURL to approve new accounts.
+func NewRegistrationWebhook(logger *slog.Logger, webhookURL string, httpClient *http.Client) RegistrationWebhook {
+	return RegistrationWebhook{
+		httpClient: httpClient,
+		logger:     logger,
+		timeNow:    time.Now,
+		webhookURL: webhookURL,
+	}
+}
+
+// RegistrationWebhook approves new accounts by posting each registration to
+// a webhook, such as a service that checks whether the user solved a CAPTCHA.
+// A 2xx response approves the registration and a 4xx response rejects it.
+// Any other response is treated as a failure to decide.
+type RegistrationWebhook struct {
+	httpClient *http.Client
+	logger     *slog.Logger
+	timeNow    func() time.Time
+	webhookURL string
+}
+
+// registrationEvent is the JSON body posted to the webhook.
+type registrationEvent struct {
+	Event      string    `json:"event"`
+	ScreenName string    `json:"screen_name"`
+	ClientID   string    `json:"client_id"`
+	RemoteAddr string    `json:"remote_addr"`
+	Time       time.Time `json:"time"`
+}
+
+// VerifyRegistration posts the registration to the webhook and reports
+// whether it was approved. Unlike the watched account webhook, the webhook is
+// delivered synchronously because the login waits on its decision.
+func (v RegistrationWebhook) VerifyRegistration(screenName state.DisplayScreenName, clientID string, remoteAddr netip.Addr) (bool, error) {
+	event := registrationEvent{
+		Event:      "registration",
+		ScreenName: screenName.String(),
+		ClientID:   clientID,
+		RemoteAddr: remoteAddr.String(),
+		Time:       v.timeNow().UTC(),
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("http.NewRequest: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("httpClient.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return true, nil
+	case resp.StatusCode >= 400 && resp.StatusCode <= 499:
+		v.logger.Info("registration rejected by webhook",
+			"screen_name", screenName.String(), "status", resp.StatusCode)
+		return false, nil
+	default:
+		return false, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,74 @@
+package foodgroup
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationWebhook_VerifyRegistration(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// status is the status code the webhook responds with
+		status int
+		// wantApproved indicates whether the registration is approved
+		wantApproved bool
+		// wantErr indicates whether an error is expected
+		wantErr bool
+	}{
+		{
+			name:         "webhook approves registration",
+			status:       http.StatusOK,
+			wantApproved: true,
+		},
+		{
+			name:         "webhook rejects registration",
+			status:       http.StatusForbidden,
+			wantApproved: false,
+		},
+		{
+			name:    "webhook fails",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				event := registrationEvent{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+				assert.Equal(t, registrationEvent{
+					Event:      "registration",
+					ScreenName: "New User",
+					ClientID:   "AIM Client",
+					RemoteAddr: "203.0.113.1",
+					Time:       time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC),
+				}, event)
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			verifier := NewRegistrationWebhook(slog.Default(), srv.URL, srv.Client())
+			verifier.timeNow = func() time.Time {
+				return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+			}
+			approved, err := verifier.VerifyRegistration("New User", "AIM Client", netip.MustParseAddr("203.0.113.1"))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantApproved, approved)
+		})
+	}
+}
@@ -14,16 +14,23 @@ import (
 // in one place for a table test
 type mockParams struct {
 	accountManagerParams
+	banListParams
 	bartManagerParams
 	buddyBroadcasterParams
 	buddyListRetrieverParams
 	chatMessageRelayerParams
+	chatModeratorRetrieverParams
+	chatRoomBanRetrieverParams
+	chatRoomModerationManagerParams
 	chatRoomRegistryParams
+	chatSlowModeLimiterParams
 	cookieBakerParams
 	feedbagManagerParams
 	icqUserFinderParams
 	icqUserUpdaterParams
+	liveChatRoomCounterParams
 	localBuddyListManagerParams
+	messageFilterParams
 	messageRelayerParams
 	offlineMessageManagerParams
 	profileManagerParams
@@ -32,6 +39,32 @@ type mockParams struct {
 	userManagerParams
 }
 
+// banListParams is a helper struct that contains mock parameters for BanList
+// methods
+type banListParams struct {
+	bannedParams
+}
+
+// bannedParams is the list of parameters passed at the mock BanList.Banned
+// call site
+type bannedParams []struct {
+	screenName state.IdentScreenName
+	result     bool
+}
+
+// messageFilterParams is a helper struct that contains mock parameters for
+// MessageFilter methods
+type messageFilterParams struct {
+	matchParams
+}
+
+// matchParams is the list of parameters passed at the mock
+// MessageFilter.Match call site
+type matchParams []struct {
+	text   string
+	result bool
+}
+
 // buddyListRetrieverParams is a helper struct that contains mock parameters
 // for BuddyListRetriever methods
 type buddyListRetrieverParams struct {
@@ -124,6 +157,7 @@ type icqUserFinderParams struct {
 type findByKeywordParams []struct {
 	keyword string
 	result  []state.User
+	total   int
 	err     error
 }
 
@@ -150,6 +184,7 @@ type findByDetailsParams []struct {
 	lastName  string
 	nickName  string
 	result    []state.User
+	total     int
 	err       error
 }
 
@@ -159,6 +194,7 @@ type findByInterestsParams []struct {
 	code     uint16
 	keywords []string
 	result   []state.User
+	total    int
 	err      error
 }
 
@@ -169,6 +205,7 @@ type icqUserUpdaterParams struct {
 	setBasicInfoParams
 	setInterestsParams
 	setMoreInfoParams
+	setPermissionsParams
 	setUserNotesParams
 	setWorkInfoParams
 }
@@ -189,6 +226,14 @@ type setInterestsParams []struct {
 	err  error
 }
 
+// setPermissionsParams is the list of parameters passed at the mock
+// ICQUserUpdater.SetPermissions call site
+type setPermissionsParams []struct {
+	name state.IdentScreenName
+	data state.ICQPermissions
+	err  error
+}
+
 // setUserNotesParams is the list of parameters passed at the mock
 // ICQUserUpdater.SetUserNotes call site
 type setUserNotesParams []struct {
@@ -247,6 +292,7 @@ type bartManagerUpsertParams []struct {
 type userManagerParams struct {
 	getUserParams
 	insertUserParams
+	setPasswordHashParams
 }
 
 // getUserParams is the list of parameters passed at the mock
@@ -264,10 +310,19 @@ type insertUserParams []struct {
 	err  error
 }
 
+// setPasswordHashParams is the list of parameters passed at the mock
+// UserManager.SetPasswordHash call site
+type setPasswordHashParams []struct {
+	screenName state.IdentScreenName
+	err        error
+}
+
 // sessionRegistryParams is a helper struct that contains mock parameters for
 // SessionRegistry methods
 type sessionRegistryParams struct {
 	addSessionParams
+	addSessionIfAbsentParams
+	addSessionInstanceParams
 	removeSessionParams
 }
 
@@ -279,6 +334,22 @@ type addSessionParams []struct {
 	err        error
 }
 
+// addSessionIfAbsentParams is the list of parameters passed at the mock
+// SessionRegistry.AddSessionIfAbsent call site
+type addSessionIfAbsentParams []struct {
+	screenName state.DisplayScreenName
+	result     *state.Session
+	err        error
+}
+
+// addSessionInstanceParams is the list of parameters passed at the mock
+// SessionRegistry.AddSessionInstance call site
+type addSessionInstanceParams []struct {
+	screenName state.DisplayScreenName
+	result     *state.Session
+	err        error
+}
+
 // removeSessionParams is the list of parameters passed at the mock
 // SessionRegistry.RemoveSession call site
 type removeSessionParams []struct {
@@ -294,6 +365,14 @@ type feedbagManagerParams struct {
 	feedbagParams
 	feedbagLastModifiedParams
 	feedbagDeleteParams
+	useFeedbagParams
+}
+
+// useFeedbagParams is the list of parameters passed at the mock
+// FeedbagManager.UseFeedbag call site
+type useFeedbagParams []struct {
+	screenName state.IdentScreenName
+	err        error
 }
 
 // adjacentUsersParams is the list of parameters passed at the mock
@@ -309,6 +388,7 @@ type adjacentUsersParams []struct {
 type feedbagUpsertParams []struct {
 	screenName state.IdentScreenName
 	items      []wire.FeedbagItem
+	err        error
 }
 
 // buddiesParams is the list of parameters passed at the mock
@@ -337,6 +417,7 @@ type feedbagLastModifiedParams []struct {
 type feedbagDeleteParams []struct {
 	screenName state.IdentScreenName
 	items      []wire.FeedbagItem
+	err        error
 }
 
 // messageRelayerParams is a helper struct that contains mock parameters for
@@ -470,6 +551,83 @@ type chatRelayToScreenNameParams []struct {
 	err        error
 }
 
+// chatModeratorRetrieverParams is a helper struct that contains mock
+// parameters for ChatModeratorRetriever methods
+type chatModeratorRetrieverParams struct {
+	isChatRoomModeratorParams
+}
+
+// isChatRoomModeratorParams is the list of parameters passed at the mock
+// ChatModeratorRetriever.IsChatRoomModerator call site
+type isChatRoomModeratorParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	result     bool
+	err        error
+}
+
+// chatRoomBanRetrieverParams is a helper struct that contains mock
+// parameters for ChatRoomBanRetriever methods
+type chatRoomBanRetrieverParams struct {
+	isChatRoomBannedParams
+}
+
+// isChatRoomBannedParams is the list of parameters passed at the mock
+// ChatRoomBanRetriever.IsChatRoomBanned call site
+type isChatRoomBannedParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	result     bool
+	err        error
+}
+
+// chatRoomModerationManagerParams is a helper struct that contains mock
+// parameters for ChatRoomModerationManager methods
+type chatRoomModerationManagerParams struct {
+	addChatRoomBanParams
+	removeChatRoomBanParams
+	setChatRoomTopicParams
+}
+
+// addChatRoomBanParams is the list of parameters passed at the mock
+// ChatRoomModerationManager.AddChatRoomBan call site
+type addChatRoomBanParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// removeChatRoomBanParams is the list of parameters passed at the mock
+// ChatRoomModerationManager.RemoveChatRoomBan call site
+type removeChatRoomBanParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// setChatRoomTopicParams is the list of parameters passed at the mock
+// ChatRoomModerationManager.SetChatRoomTopic call site
+type setChatRoomTopicParams []struct {
+	cookie string
+	topic  string
+	err    error
+}
+
+// chatSlowModeLimiterParams is a helper struct that contains mock parameters
+// for ChatSlowModeLimiter methods
+type chatSlowModeLimiterParams struct {
+	allowMessageParams
+}
+
+// allowMessageParams is the list of parameters passed at the mock
+// ChatSlowModeLimiter.AllowMessage call site
+type allowMessageParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	allowed    bool
+	interval   time.Duration
+}
+
 // localBuddyListManagerParams is a helper struct that contains mock
 // parameters for LocalBuddyListManager methods
 type localBuddyListManagerParams struct {
@@ -684,6 +842,18 @@ type chatRoomByNameParams []struct {
 	err      error
 }
 
+// liveChatRoomCounterParams is a helper struct that contains mock parameters
+// for LiveChatRoomCounter methods
+type liveChatRoomCounterParams struct {
+	liveChatRoomCountParams
+}
+
+// liveChatRoomCountParams is the list of parameters passed at the mock
+// LiveChatRoomCounter.LiveChatRoomCount call site
+type liveChatRoomCountParams []struct {
+	count int
+}
+
 // createChatRoomParams is the list of parameters passed at the mock
 // ChatRoomRegistry.CreateChatRoom call site
 type createChatRoomParams []struct {
@@ -717,12 +887,46 @@ func sessOptChatRoomCookie(cookie string) func(session *state.Session) {
 	}
 }
 
+// sessOptSpectator marks the chat session as a read-only spectator
+func sessOptSpectator(session *state.Session) {
+	session.SetSpectator(true)
+}
+
+// sessOptDropped overflows the session's message queue and closes the
+// session, as the session manager does for clients that can't keep up. The
+// session is left with missed undelivered messages.
+func sessOptDropped(missed int) func(session *state.Session) {
+	return func(session *state.Session) {
+		for session.RelayMessage(wire.SNACMessage{}) != state.SessQueueFull {
+		}
+		session.Close()
+		for i := 1; i < missed; i++ {
+			session.RelayMessage(wire.SNACMessage{})
+		}
+	}
+}
+
 // sessOptInvisible sets the invisible flag to true on the session
 // object
 func sessOptInvisible(session *state.Session) {
 	session.SetUserStatusBitmask(wire.OServiceUserStatusInvisible)
 }
 
+// sessOptMobile sets the mobile device flag on the session object
+func sessOptMobile(session *state.Session) {
+	session.SetUserInfoFlag(wire.OServiceUserFlagWireless)
+}
+
+// sessOptUnconfirmed sets the unconfirmed account flag on the session object
+func sessOptUnconfirmed(session *state.Session) {
+	session.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+}
+
+// sessOptDND sets the "do not disturb" status flag on the session object
+func sessOptDND(session *state.Session) {
+	session.SetUserStatusBitmask(wire.OServiceUserStatusDND)
+}
+
 // sessOptIdle sets the idle flag to dur on the session object
 func sessOptIdle(dur time.Duration) func(session *state.Session) {
 	return func(session *state.Session) {
@@ -735,6 +939,19 @@ func sessOptSignonComplete(session *state.Session) {
 	session.SetSignonComplete()
 }
 
+// sessOptFeedbagInUse marks the server-side buddy list as activated for the
+// session
+func sessOptFeedbagInUse(session *state.Session) {
+	session.SetFeedbagInUse()
+}
+
+// sessOptPermitMask sets the group permit mask
+func sessOptPermitMask(mask uint16) func(session *state.Session) {
+	return func(session *state.Session) {
+		session.SetPermitMask(mask)
+	}
+}
+
 // sessOptCaps sets caps
 func sessOptUIN(UIN uint32) func(session *state.Session) {
 	return func(session *state.Session) {
@@ -749,6 +966,14 @@ func sessClientID(clientID string) func(session *state.Session) {
 	}
 }
 
+// sessFoodGroupVersions sets the food group versions negotiated with the
+// client
+func sessFoodGroupVersions(versions map[uint16]uint16) func(session *state.Session) {
+	return func(session *state.Session) {
+		session.SetFoodGroupVersions(versions)
+	}
+}
+
 // newTestSession creates a session object with 0 or more functional options
 // applied
 func newTestSession(screenName state.DisplayScreenName, options ...func(session *state.Session)) *state.Session {
@@ -36,6 +36,7 @@ package foodgroup
 import (
 	"context"
 	"net/mail"
+	"net/netip"
 	"time"
 
 	"github.com/mk6i/retro-aim-server/state"
@@ -50,6 +51,7 @@ type AccountManager interface {
 	RegStatusByName(screenName state.IdentScreenName) (uint16, error)
 	UpdateConfirmStatus(confirmStatus bool, screenName state.IdentScreenName) error
 	ConfirmStatusByName(screnName state.IdentScreenName) (bool, error)
+	SetConfirmToken(token string, expiresAt time.Time, screenName state.IdentScreenName) error
 }
 
 type BARTManager interface {
@@ -86,6 +88,72 @@ type ChatMessageRelayer interface {
 	RelayToScreenName(ctx context.Context, chatCookie string, recipient state.IdentScreenName, msg wire.SNACMessage)
 }
 
+// ChatModeratorRetriever defines the interface for looking up chat room
+// moderators.
+type ChatModeratorRetriever interface {
+	// IsChatRoomModerator indicates whether the user is a moderator of the
+	// chat room.
+	IsChatRoomModerator(chatCookie string, screenName state.IdentScreenName) (bool, error)
+}
+
+// ChatRoomBanRetriever defines the interface for looking up users banned
+// from chat rooms.
+type ChatRoomBanRetriever interface {
+	// IsChatRoomBanned indicates whether the user is banned from the chat
+	// room.
+	IsChatRoomBanned(chatCookie string, screenName state.IdentScreenName) (bool, error)
+}
+
+// ChatRoomModerationManager defines the interface for persisting the bans and
+// topics that moderators set for chat rooms.
+type ChatRoomModerationManager interface {
+	// AddChatRoomBan bans the user from the chat room.
+	AddChatRoomBan(chatCookie string, screenName state.IdentScreenName) error
+
+	// RemoveChatRoomBan lifts the user's ban from the chat room.
+	RemoveChatRoomBan(chatCookie string, screenName state.IdentScreenName) error
+
+	// SetChatRoomTopic sets the chat room topic.
+	SetChatRoomTopic(chatCookie string, topic string) error
+}
+
+// ChatTranscriptRecorder defines the interface for recording chat room
+// messages to the room's transcript.
+type ChatTranscriptRecorder interface {
+	// AppendChatTranscript records a message in the transcript of the chat
+	// room identified by chatCookie.
+	AppendChatTranscript(chatCookie string, entry state.ChatTranscriptEntry) error
+}
+
+// ChatSlowModeLimiter defines the interface for enforcing a minimum interval
+// between a user's chat room messages.
+type ChatSlowModeLimiter interface {
+	// AllowMessage indicates whether the user may send a message to the chat
+	// room. If not, it returns the room's slow mode interval.
+	AllowMessage(chatCookie string, screenName state.IdentScreenName) (bool, time.Duration)
+}
+
+// FileTransferLimiter defines the interface for capping the number of file
+// transfers that may run at once.
+type FileTransferLimiter interface {
+	// StartTransfer records a file transfer proposal. It returns false if the
+	// transfer would exceed a cap.
+	StartTransfer(cookie [8]byte, sender state.IdentScreenName, recipient state.IdentScreenName, proxied bool) bool
+	// AcceptTransfer stops tracking a direct file transfer once it's
+	// accepted.
+	AcceptTransfer(cookie [8]byte)
+	// EndTransfer stops tracking a file transfer.
+	EndTransfer(cookie [8]byte)
+}
+
+// AutoSuspender defines the interface for suspending users who receive too
+// many warnings.
+type AutoSuspender interface {
+	// RecordWarning records a warning received by screenName. It returns
+	// true if the user is now suspended.
+	RecordWarning(screenName state.IdentScreenName) bool
+}
+
 // ChatRoomRegistry defines the interface for storing and retrieving chat
 // rooms in a persistent store. The persistent store has two purposes:
 // - Remember user-created chat rooms (exchange 4) so that clients can
@@ -118,6 +186,21 @@ type ChatSessionRegistry interface {
 	RemoveSession(sess *state.Session)
 }
 
+// LiveChatRoomCounter counts the chat rooms that are in use.
+type LiveChatRoomCounter interface {
+	// LiveChatRoomCount returns the number of chat rooms that have at least
+	// one participant.
+	LiveChatRoomCount() int
+}
+
+// BanList checks whether a user is barred from signing on.
+type BanList interface {
+	Banned(screenName state.IdentScreenName) bool
+	// BannedAddr indicates whether users are barred from signing on from
+	// addr.
+	BannedAddr(addr netip.Addr) bool
+}
+
 type CookieBaker interface {
 	Crack(data []byte) ([]byte, error)
 	Issue(data []byte) ([]byte, error)
@@ -136,15 +219,18 @@ type ICQUserFinder interface {
 	FindByUIN(UIN uint32) (state.User, error)
 	// FindByICQEmail returns a user with a matching email address.
 	FindByICQEmail(email string) (state.User, error)
-	// FindByICQName returns users with matching first name, last name, and
-	// nickname. Empty values are not included in the search parameters.
-	FindByICQName(firstName, lastName, nickName string) ([]state.User, error)
-	// FindByICQInterests returns users who have at least one matching interest
-	// for a given category code.
-	FindByICQInterests(code uint16, keywords []string) ([]state.User, error)
-	// FindByICQKeyword returns users with matching interest keyword across all
-	// interest categories.
-	FindByICQKeyword(keyword string) ([]state.User, error)
+	// FindByICQName returns up to limit users with matching first name, last
+	// name, and nickname, along with the total number of matching users.
+	// Empty values are not included in the search parameters.
+	FindByICQName(firstName, lastName, nickName string, limit int) ([]state.User, int, error)
+	// FindByICQInterests returns up to limit users who have at least one
+	// matching interest for a given category code, along with the total
+	// number of matching users.
+	FindByICQInterests(code uint16, keywords []string, limit int) ([]state.User, int, error)
+	// FindByICQKeyword returns up to limit users with matching interest
+	// keyword across all interest categories, along with the total number of
+	// matching users.
+	FindByICQKeyword(keyword string, limit int) ([]state.User, int, error)
 }
 
 type ICQUserUpdater interface {
@@ -152,6 +238,7 @@ type ICQUserUpdater interface {
 	SetBasicInfo(name state.IdentScreenName, data state.ICQBasicInfo) error
 	SetInterests(name state.IdentScreenName, data state.ICQInterests) error
 	SetMoreInfo(name state.IdentScreenName, data state.ICQMoreInfo) error
+	SetPermissions(name state.IdentScreenName, data state.ICQPermissions) error
 	SetUserNotes(name state.IdentScreenName, data state.ICQUserNotes) error
 	SetWorkInfo(name state.IdentScreenName, data state.ICQWorkInfo) error
 }
@@ -189,16 +276,88 @@ type ProfileManager interface {
 	User(screenName state.IdentScreenName) (*state.User, error)
 }
 
+// ScreenNameReserver keeps virtual user screen names from being registered
+// while the virtual user is signed on.
+type ScreenNameReserver interface {
+	// ReserveScreenName reserves screenName, returning
+	// state.ErrVirtualUserConflict if it belongs to a registered account.
+	ReserveScreenName(screenName state.IdentScreenName) error
+	// ReleaseScreenName releases a screen name reserved by
+	// ReserveScreenName.
+	ReleaseScreenName(screenName state.IdentScreenName)
+}
+
+// ScreenNameResolver maps screen name aliases to the accounts they belong to.
+type ScreenNameResolver interface {
+	// CanonicalScreenName returns the screen name of the account that
+	// screenName is an alias of, or screenName itself if it's not an alias.
+	CanonicalScreenName(screenName state.IdentScreenName) (state.IdentScreenName, error)
+}
+
 type SessionRegistry interface {
 	AddSession(ctx context.Context, screenName state.DisplayScreenName) (*state.Session, error)
+	// AddSessionIfAbsent adds a session for screenName unless the user
+	// already has an active session, in which case it returns
+	// state.ErrSessionActive.
+	AddSessionIfAbsent(screenName state.DisplayScreenName) (*state.Session, error)
+	// AddSessionInstance adds a session for screenName alongside the user's
+	// active sessions, if any.
+	AddSessionInstance(ctx context.Context, screenName state.DisplayScreenName) (*state.Session, error)
 	RemoveSession(sess *state.Session)
+	RetrieveSession(screenName state.IdentScreenName) *state.Session
+	SessionCount() int
 }
 
 type SessionRetriever interface {
 	RetrieveSession(screenName state.IdentScreenName) *state.Session
 }
 
+// SessionLister lists the sessions of all signed-on users.
+type SessionLister interface {
+	// AllSessions returns all sessions in the session pool.
+	AllSessions() []*state.Session
+}
+
+// MessageFilter checks message text against a list of prohibited words and
+// phrases.
+type MessageFilter interface {
+	Match(text string) bool
+}
+
 type UserManager interface {
 	User(screenName state.IdentScreenName) (*state.User, error)
 	InsertUser(u state.User) error
+	SetPasswordHash(screenName state.IdentScreenName, passwordHash string) error
+}
+
+// ConfirmationSender delivers account confirmation links to users.
+type ConfirmationSender interface {
+	// SendConfirmation sends a link containing token, which confirms the
+	// account of screenName, to the user's email address.
+	SendConfirmation(screenName state.DisplayScreenName, emailAddress *mail.Address, token string)
+}
+
+// RegistrationLimiter caps how many accounts may be registered from an IP
+// address.
+type RegistrationLimiter interface {
+	// Allow records a registration attempt from addr and reports whether the
+	// attempt is within the limit.
+	Allow(addr netip.Addr) bool
+}
+
+// RegistrationVerifier decides whether a new account may be registered, such
+// as by consulting a CAPTCHA or approval service.
+type RegistrationVerifier interface {
+	// VerifyRegistration reports whether screenName may be registered by a
+	// client connecting from remoteAddr. It returns an error if the decision
+	// couldn't be made.
+	VerifyRegistration(screenName state.DisplayScreenName, clientID string, remoteAddr netip.Addr) (bool, error)
+}
+
+// WatchedAccountNotifier notifies operators about activity on watched
+// accounts.
+type WatchedAccountNotifier interface {
+	// WatchedSignOn reports that a watched account signed on with the given
+	// client.
+	WatchedSignOn(screenName state.DisplayScreenName, clientID string)
 }
@@ -3,37 +3,52 @@ package foodgroup
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
 // NewUserLookupService returns a new instance of UserLookupService.
-func NewUserLookupService(profileManager ProfileManager) UserLookupService {
+func NewUserLookupService(profileManager ProfileManager, cfg config.Config) UserLookupService {
 	return UserLookupService{
+		cfg:            cfg,
 		profileManager: profileManager,
+		timeNow:        time.Now,
 	}
 }
 
 // UserLookupService implements the UserLookup food group.
 type UserLookupService struct {
+	cfg            config.Config
 	profileManager ProfileManager
+	timeNow        func() time.Time
 }
 
-// FindByEmail searches for a user by email address.
-func (s UserLookupService) FindByEmail(_ context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error) {
+// FindByEmail searches for a user by email address. Lookups made faster than
+// the configured minimum interval get a rate limit error. Once the user
+// reaches the configured maximum number of lookups for the session, every
+// lookup gets a no-results reply so that screen names can't be harvested.
+func (s UserLookupService) FindByEmail(_ context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error) {
+	now := s.timeNow()
+	if s.cfg.UserLookupMinIntervalMs > 0 {
+		minInterval := time.Duration(s.cfg.UserLookupMinIntervalMs) * time.Millisecond
+		if now.Sub(sess.LastUserLookupTime()) < minInterval {
+			return userLookupErr(inFrame, wire.ErrorCodeRateToHost), nil
+		}
+	}
+
+	count := sess.RecordUserLookup(now)
+	if s.cfg.UserLookupMaxPerSession > 0 && count > s.cfg.UserLookupMaxPerSession {
+		return userLookupErr(inFrame, wire.UserLookupErrNoUserFound), nil
+	}
+
 	user, err := s.profileManager.FindByAIMEmail(string(inBody.Email))
 
 	switch {
 	case errors.Is(err, state.ErrNoUser):
-		return wire.SNACMessage{
-			Frame: wire.SNACFrame{
-				FoodGroup: wire.UserLookup,
-				SubGroup:  wire.UserLookupErr,
-				RequestID: inFrame.RequestID,
-			},
-			Body: wire.UserLookupErrNoUserFound,
-		}, nil
+		return userLookupErr(inFrame, wire.UserLookupErrNoUserFound), nil
 	case err != nil:
 		return wire.SNACMessage{}, err
 	}
@@ -53,3 +68,15 @@ func (s UserLookupService) FindByEmail(_ context.Context, inFrame wire.SNACFrame
 		},
 	}, nil
 }
+
+// userLookupErr creates a UserLookup error reply with the given error code.
+func userLookupErr(inFrame wire.SNACFrame, code uint16) wire.SNACMessage {
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.UserLookup,
+			SubGroup:  wire.UserLookupErr,
+			RequestID: inFrame.RequestID,
+		},
+		Body: code,
+	}
+}
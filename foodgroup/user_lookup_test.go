@@ -3,7 +3,9 @@ package foodgroup
 import (
 	"io"
 	"testing"
+	"time"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 
@@ -126,10 +128,114 @@ func TestUserLookupService_FindByEmail(t *testing.T) {
 					Return(params.result, params.err)
 			}
 
-			svc := NewUserLookupService(profileManager)
-			actual, err := svc.FindByEmail(nil, tc.inputSNAC.Frame, tc.inputSNAC.Body.(wire.SNAC_0x0A_0x02_UserLookupFindByEmail))
+			svc := NewUserLookupService(profileManager, config.Config{})
+			actual, err := svc.FindByEmail(nil, newTestSession("me"), tc.inputSNAC.Frame, tc.inputSNAC.Body.(wire.SNAC_0x0A_0x02_UserLookupFindByEmail))
 			assert.ErrorIs(t, err, tc.expectErr)
 			assert.Equal(t, tc.expectOutput, actual)
 		})
 	}
 }
+
+func TestUserLookupService_FindByEmail_Throttled(t *testing.T) {
+	findReply := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.UserLookup,
+			SubGroup:  wire.UserLookupFindReply,
+			RequestID: 1234,
+		},
+		Body: wire.SNAC_0x0A_0x03_UserLookupFindReply{
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.UserLookupTLVEmailAddress, "ChattingChuck"),
+				},
+			},
+		},
+	}
+	errReply := func(code uint16) wire.SNACMessage {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.UserLookup,
+				SubGroup:  wire.UserLookupErr,
+				RequestID: 1234,
+			},
+			Body: code,
+		}
+	}
+
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// cfg is the app configuration
+		cfg config.Config
+		// lookupOffsets are the times, relative to the first lookup, at which
+		// the user looks up an email address
+		lookupOffsets []time.Duration
+		// expectOutputs are the replies to each lookup
+		expectOutputs []wire.SNACMessage
+		// expectSearches is the number of lookups that reach the database
+		expectSearches int
+	}{
+		{
+			name: "lookups faster than the minimum interval are rate limited",
+			cfg: config.Config{
+				UserLookupMinIntervalMs: 1000,
+			},
+			lookupOffsets: []time.Duration{0, 500 * time.Millisecond, 1000 * time.Millisecond},
+			expectOutputs: []wire.SNACMessage{
+				findReply,
+				errReply(wire.ErrorCodeRateToHost),
+				findReply,
+			},
+			expectSearches: 2,
+		},
+		{
+			name: "lookups past the per-session maximum return no results",
+			cfg: config.Config{
+				UserLookupMaxPerSession: 2,
+			},
+			lookupOffsets: []time.Duration{0, time.Second, 2 * time.Second},
+			expectOutputs: []wire.SNACMessage{
+				findReply,
+				findReply,
+				errReply(wire.UserLookupErrNoUserFound),
+			},
+			expectSearches: 2,
+		},
+		{
+			name:          "lookups are unlimited by default",
+			cfg:           config.Config{},
+			lookupOffsets: []time.Duration{0, 0, 0},
+			expectOutputs: []wire.SNACMessage{
+				findReply,
+				findReply,
+				findReply,
+			},
+			expectSearches: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			profileManager := newMockProfileManager(t)
+			profileManager.EXPECT().
+				FindByAIMEmail("user@aol.com").
+				Return(state.User{DisplayScreenName: "ChattingChuck"}, nil).
+				Times(tc.expectSearches)
+
+			svc := NewUserLookupService(profileManager, tc.cfg)
+			start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			sess := newTestSession("me")
+
+			for i, offset := range tc.lookupOffsets {
+				svc.timeNow = func() time.Time { return start.Add(offset) }
+				inFrame := wire.SNACFrame{RequestID: 1234}
+				inBody := wire.SNAC_0x0A_0x02_UserLookupFindByEmail{
+					Email: []byte("user@aol.com"),
+				}
+				actual, err := svc.FindByEmail(nil, sess, inFrame, inBody)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectOutputs[i], actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package foodgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// NewVirtualUserService creates a new instance of VirtualUserService. Instant
+// messages sent to virtual users are posted to webhookURL using httpClient. If
+// webhookURL is empty, the messages are dropped.
+func NewVirtualUserService(
+	logger *slog.Logger,
+	screenNameReserver ScreenNameReserver,
+	sessionRegistry SessionRegistry,
+	sessionRetriever SessionRetriever,
+	buddyListRetriever BuddyListRetriever,
+	messageRelayer MessageRelayer,
+	webhookURL string,
+	httpClient *http.Client,
+) *VirtualUserService {
+	return &VirtualUserService{
+		buddyBroadcaster:   newBuddyNotifier(buddyListRetriever, messageRelayer, sessionRetriever),
+		httpClient:         httpClient,
+		logger:             logger,
+		screenNameReserver: screenNameReserver,
+		sessionRegistry:    sessionRegistry,
+		sessionRetriever:   sessionRetriever,
+		sessions:           make(map[state.IdentScreenName]*state.Session),
+		timeNow:            time.Now,
+		webhookURL:         webhookURL,
+	}
+}
+
+// VirtualUserService manages the presence of virtual users, which are
+// contacts bridged from an external system such as an XMPP gateway. A virtual
+// user has a session without an OSCAR connection, so buddies see it come
+// online, go away, and sign off just like a normal user. Virtual users can't
+// sign on from a client. It is safe to use with multiple goroutines.
+type VirtualUserService struct {
+	buddyBroadcaster   buddyBroadcaster
+	httpClient         *http.Client
+	logger             *slog.Logger
+	mutex              sync.Mutex
+	screenNameReserver ScreenNameReserver
+	sessionRegistry    SessionRegistry
+	sessionRetriever   SessionRetriever
+	sessions           map[state.IdentScreenName]*state.Session
+	timeNow            func() time.Time
+	webhookURL         string
+}
+
+// virtualUserMessage is the JSON body posted to the webhook when a virtual
+// user receives an instant message.
+type virtualUserMessage struct {
+	Event   string    `json:"event"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// SetOnline signs on the virtual user screenName, or updates its presence if
+// it's already signed on, and notifies the user's buddies. The user is shown
+// as away with awayMessage, or as available if awayMessage is empty, and as
+// connected from a mobile device if mobile is true. The screen name can't be
+// registered while the virtual user is signed on. It returns
+// state.ErrVirtualUserConflict if screenName belongs to a registered account
+// or a signed-on user.
+func (s *VirtualUserService) SetOnline(ctx context.Context, screenName state.DisplayScreenName, awayMessage string, mobile bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess, ok := s.sessions[screenName.IdentScreenName()]
+	if !ok {
+		if err := s.screenNameReserver.ReserveScreenName(screenName.IdentScreenName()); err != nil {
+			if errors.Is(err, state.ErrVirtualUserConflict) {
+				return err
+			}
+			return fmt.Errorf("screenNameReserver.ReserveScreenName: %w", err)
+		}
+		if s.sessionRetriever.RetrieveSession(screenName.IdentScreenName()) != nil {
+			s.screenNameReserver.ReleaseScreenName(screenName.IdentScreenName())
+			return state.ErrVirtualUserConflict
+		}
+
+		var err error
+		sess, err = s.sessionRegistry.AddSession(ctx, screenName)
+		if err != nil {
+			s.screenNameReserver.ReleaseScreenName(screenName.IdentScreenName())
+			return fmt.Errorf("sessionRegistry.AddSession: %w", err)
+		}
+		sess.SetSignonComplete()
+		s.sessions[screenName.IdentScreenName()] = sess
+
+		go s.forwardMessages(sess)
+	}
+
+	sess.SetAwayMessage(awayMessage)
+	if mobile {
+		sess.SetUserInfoFlag(wire.OServiceUserFlagWireless)
+	} else {
+		sess.ClearUserInfoFlag(wire.OServiceUserFlagWireless)
+	}
+	if err := s.buddyBroadcaster.BroadcastBuddyArrived(ctx, sess); err != nil {
+		return fmt.Errorf("unable to send buddy arrival notification: %w", err)
+	}
+
+	return nil
+}
+
+// SetOffline signs off the virtual user screenName and notifies the user's
+// buddies. It returns state.ErrVirtualUserNotFound if the virtual user is not
+// signed on.
+func (s *VirtualUserService) SetOffline(ctx context.Context, screenName state.IdentScreenName) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess, ok := s.sessions[screenName]
+	if !ok {
+		return state.ErrVirtualUserNotFound
+	}
+	delete(s.sessions, screenName)
+	sess.Close()
+	s.sessionRegistry.RemoveSession(sess)
+	s.screenNameReserver.ReleaseScreenName(screenName)
+
+	if err := s.buddyBroadcaster.BroadcastBuddyDeparted(ctx, sess); err != nil {
+		return fmt.Errorf("unable to send buddy departure notification: %w", err)
+	}
+
+	return nil
+}
+
+// forwardMessages consumes the messages sent to a virtual user's session
+// until the session is closed. The session is removed from the session pool
+// and its screen name released when it closes, which lets another sign-on
+// take its place.
+func (s *VirtualUserService) forwardMessages(sess *state.Session) {
+	for {
+		select {
+		case msg := <-sess.ReceiveMessage():
+			s.forwardMessage(sess, msg)
+		case <-sess.Closed():
+			s.mutex.Lock()
+			if s.sessions[sess.IdentScreenName()] == sess {
+				delete(s.sessions, sess.IdentScreenName())
+				s.screenNameReserver.ReleaseScreenName(sess.IdentScreenName())
+			}
+			s.mutex.Unlock()
+			s.sessionRegistry.RemoveSession(sess)
+			return
+		}
+	}
+}
+
+// forwardMessage posts instant messages sent to a virtual user to the
+// webhook. The webhook is delivered in the background, bounded by
+// webhookTimeout, so that a slow endpoint doesn't back up the session's
+// message queue. All other messages are dropped.
+func (s *VirtualUserService) forwardMessage(sess *state.Session, msg wire.SNACMessage) {
+	body, ok := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+	if !ok || body.ChannelID != wire.ICBMChannelIM || s.webhookURL == "" {
+		return
+	}
+
+	b, ok := body.Bytes(wire.ICBMTLVAOLIMData)
+	if !ok {
+		return
+	}
+	text, err := wire.UnmarshalICBMMessageText(b)
+	if err != nil {
+		s.logger.Debug("unable to parse instant message sent to virtual user", "err", err.Error())
+		return
+	}
+
+	event := virtualUserMessage{
+		Event:   "instant_message",
+		From:    body.ScreenName,
+		To:      sess.DisplayScreenName().String(),
+		Message: text,
+		Time:    s.timeNow().UTC(),
+	}
+	go func() {
+		if err := postWebhook(s.httpClient, s.webhookURL, event); err != nil {
+			s.logger.Error("unable to deliver virtual user webhook", "err", err.Error())
+		}
+	}()
+}
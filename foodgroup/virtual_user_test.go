@@ -0,0 +1,259 @@
+package foodgroup
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveSNAC returns the next message sent to sess, failing the test if
+// none arrives.
+func receiveSNAC(t *testing.T, sess *state.Session) wire.SNACMessage {
+	select {
+	case msg := <-sess.ReceiveMessage():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+		return wire.SNACMessage{}
+	}
+}
+
+func TestVirtualUserService_SetOnline(t *testing.T) {
+	ctx := context.Background()
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	buddySess, err := sessionManager.AddSession(ctx, "buddy")
+	require.NoError(t, err)
+
+	screenNameReserver := newMockScreenNameReserver(t)
+	screenNameReserver.EXPECT().
+		ReserveScreenName(state.NewIdentScreenName("Bridged Bob")).
+		Return(nil)
+	screenNameReserver.EXPECT().
+		ReleaseScreenName(state.NewIdentScreenName("Bridged Bob"))
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		AllRelationships(state.NewIdentScreenName("Bridged Bob"), []state.IdentScreenName(nil)).
+		Return([]state.Relationship{
+			{
+				User:          state.NewIdentScreenName("buddy"),
+				IsOnTheirList: true,
+			},
+		}, nil)
+	buddyListRetriever.EXPECT().
+		BuddyIconRefByName(state.NewIdentScreenName("Bridged Bob")).
+		Return(nil, nil)
+
+	svc := NewVirtualUserService(slog.Default(), screenNameReserver, sessionManager, sessionManager,
+		buddyListRetriever, sessionManager, "", nil)
+
+	// the buddy sees the virtual user come online
+	assert.NoError(t, svc.SetOnline(ctx, "Bridged Bob", "", false))
+	msg := receiveSNAC(t, buddySess)
+	assert.Equal(t, wire.BuddyArrived, msg.Frame.SubGroup)
+	userInfo := msg.Body.(wire.SNAC_0x03_0x0B_BuddyArrived).TLVUserInfo
+	assert.Equal(t, "Bridged Bob", userInfo.ScreenName)
+	flags, _ := userInfo.Uint16BE(wire.OServiceUserInfoUserFlags)
+	assert.Zero(t, flags&wire.OServiceUserFlagUnavailable)
+
+	// the buddy sees the virtual user go away
+	assert.NoError(t, svc.SetOnline(ctx, "Bridged Bob", "gone fishing", false))
+	msg = receiveSNAC(t, buddySess)
+	assert.Equal(t, wire.BuddyArrived, msg.Frame.SubGroup)
+	userInfo = msg.Body.(wire.SNAC_0x03_0x0B_BuddyArrived).TLVUserInfo
+	flags, _ = userInfo.Uint16BE(wire.OServiceUserInfoUserFlags)
+	assert.NotZero(t, flags&wire.OServiceUserFlagUnavailable)
+	assert.Zero(t, flags&wire.OServiceUserFlagWireless)
+
+	// the buddy sees the virtual user switch to a mobile device
+	assert.NoError(t, svc.SetOnline(ctx, "Bridged Bob", "gone fishing", true))
+	msg = receiveSNAC(t, buddySess)
+	assert.Equal(t, wire.BuddyArrived, msg.Frame.SubGroup)
+	userInfo = msg.Body.(wire.SNAC_0x03_0x0B_BuddyArrived).TLVUserInfo
+	flags, _ = userInfo.Uint16BE(wire.OServiceUserInfoUserFlags)
+	assert.NotZero(t, flags&wire.OServiceUserFlagWireless)
+
+	// the buddy sees the virtual user sign off
+	assert.NoError(t, svc.SetOffline(ctx, state.NewIdentScreenName("Bridged Bob")))
+	msg = receiveSNAC(t, buddySess)
+	assert.Equal(t, wire.BuddyDeparted, msg.Frame.SubGroup)
+	assert.Nil(t, sessionManager.RetrieveSession(state.NewIdentScreenName("Bridged Bob")))
+
+	assert.ErrorIs(t, svc.SetOffline(ctx, state.NewIdentScreenName("Bridged Bob")), state.ErrVirtualUserNotFound)
+}
+
+func TestVirtualUserService_SetOnline_Conflict(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("registered account", func(t *testing.T) {
+		sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+		screenNameReserver := newMockScreenNameReserver(t)
+		screenNameReserver.EXPECT().
+			ReserveScreenName(state.NewIdentScreenName("ChattingChuck")).
+			Return(state.ErrVirtualUserConflict)
+
+		svc := NewVirtualUserService(slog.Default(), screenNameReserver, sessionManager, sessionManager,
+			nil, sessionManager, "", nil)
+		assert.ErrorIs(t, svc.SetOnline(ctx, "ChattingChuck", "", false), state.ErrVirtualUserConflict)
+	})
+
+	t.Run("signed-on user", func(t *testing.T) {
+		sessionManager := state.NewInMemorySessionManager(slog.Default())
+		_, err := sessionManager.AddSession(ctx, "ChattingChuck")
+		require.NoError(t, err)
+
+		screenNameReserver := newMockScreenNameReserver(t)
+		screenNameReserver.EXPECT().
+			ReserveScreenName(state.NewIdentScreenName("ChattingChuck")).
+			Return(nil)
+		screenNameReserver.EXPECT().
+			ReleaseScreenName(state.NewIdentScreenName("ChattingChuck"))
+
+		svc := NewVirtualUserService(slog.Default(), screenNameReserver, sessionManager, sessionManager,
+			nil, sessionManager, "", nil)
+		assert.ErrorIs(t, svc.SetOnline(ctx, "ChattingChuck", "", false), state.ErrVirtualUserConflict)
+	})
+}
+
+func TestVirtualUserService_ForwardsInstantMessages(t *testing.T) {
+	ctx := context.Background()
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	received := make(chan virtualUserMessage, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := virtualUserMessage{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+	}))
+	defer webhook.Close()
+
+	screenNameReserver := newMockScreenNameReserver(t)
+	screenNameReserver.EXPECT().
+		ReserveScreenName(state.NewIdentScreenName("Bridged Bob")).
+		Return(nil)
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		AllRelationships(state.NewIdentScreenName("Bridged Bob"), []state.IdentScreenName(nil)).
+		Return(nil, nil)
+	buddyListRetriever.EXPECT().
+		BuddyIconRefByName(state.NewIdentScreenName("Bridged Bob")).
+		Return(nil, nil)
+
+	svc := NewVirtualUserService(slog.Default(), screenNameReserver, sessionManager, sessionManager,
+		buddyListRetriever, sessionManager, webhook.URL, webhook.Client())
+	svc.timeNow = func() time.Time {
+		return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	}
+	require.NoError(t, svc.SetOnline(ctx, "Bridged Bob", "", false))
+
+	frags, err := wire.ICBMFragmentList("hello bob")
+	require.NoError(t, err)
+	sessionManager.RelayToScreenName(ctx, state.NewIdentScreenName("Bridged Bob"), wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: wire.ICBMChannelIM,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: "ChattingChuck",
+			},
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+				},
+			},
+		},
+	})
+
+	select {
+	case event := <-received:
+		assert.Equal(t, virtualUserMessage{
+			Event:   "instant_message",
+			From:    "ChattingChuck",
+			To:      "Bridged Bob",
+			Message: "hello bob",
+			Time:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}, event)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for webhook")
+	}
+}
+
+func TestVirtualUserService_ForwardsInstantMessages_SlowWebhook(t *testing.T) {
+	ctx := context.Background()
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+
+	// the webhook holds every request until the test ends
+	received := make(chan virtualUserMessage, 2)
+	release := make(chan struct{})
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := virtualUserMessage{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		<-release
+	}))
+	defer webhook.Close()
+	defer close(release)
+
+	screenNameReserver := newMockScreenNameReserver(t)
+	screenNameReserver.EXPECT().
+		ReserveScreenName(state.NewIdentScreenName("Bridged Bob")).
+		Return(nil)
+
+	buddyListRetriever := newMockBuddyListRetriever(t)
+	buddyListRetriever.EXPECT().
+		AllRelationships(state.NewIdentScreenName("Bridged Bob"), []state.IdentScreenName(nil)).
+		Return(nil, nil)
+	buddyListRetriever.EXPECT().
+		BuddyIconRefByName(state.NewIdentScreenName("Bridged Bob")).
+		Return(nil, nil)
+
+	svc := NewVirtualUserService(slog.Default(), screenNameReserver, sessionManager, sessionManager,
+		buddyListRetriever, sessionManager, webhook.URL, webhook.Client())
+	require.NoError(t, svc.SetOnline(ctx, "Bridged Bob", "", false))
+
+	for _, text := range []string{"hello bob", "are you there?"} {
+		frags, err := wire.ICBMFragmentList(text)
+		require.NoError(t, err)
+		sessionManager.RelayToScreenName(ctx, state.NewIdentScreenName("Bridged Bob"), wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.ICBM,
+				SubGroup:  wire.ICBMChannelMsgToClient,
+			},
+			Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+				ChannelID: wire.ICBMChannelIM,
+				TLVUserInfo: wire.TLVUserInfo{
+					ScreenName: "ChattingChuck",
+				},
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+					},
+				},
+			},
+		})
+	}
+
+	// the second message is delivered while the first is still pending
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for webhook")
+		}
+	}
+}
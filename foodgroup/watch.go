@@ -0,0 +1,127 @@
+package foodgroup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// webhookTimeout is the maximum amount of time spent delivering a webhook.
+const webhookTimeout = 10 * time.Second
+
+// NewWebhookClient creates the HTTP client that delivers webhooks. If proxyURL
+// is set, requests go through that proxy, except for hosts listed in
+// NO_PROXY. Otherwise, the proxy is taken from the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables.
+func NewWebhookClient(proxyURL string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("url.Parse: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("proxy URL %q must include a scheme and host, such as http://proxy:3128", proxyURL)
+		}
+		proxyCfg := httpproxy.Config{
+			HTTPProxy:  proxyURL,
+			HTTPSProxy: proxyURL,
+			NoProxy:    httpproxy.FromEnvironment().NoProxy,
+		}
+		proxyFunc := proxyCfg.ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+	return &http.Client{Timeout: webhookTimeout, Transport: transport}, nil
+}
+
+// NewWatchAuditor creates a new instance of WatchAuditor that delivers
+// webhooks using httpClient. If webhookURL is empty, notifications are only
+// written to the audit log.
+func NewWatchAuditor(logger *slog.Logger, webhookURL string, httpClient *http.Client) WatchAuditor {
+	return WatchAuditor{
+		httpClient: httpClient,
+		logger:     logger,
+		timeNow:    time.Now,
+		webhookURL: webhookURL,
+	}
+}
+
+// WatchAuditor tells operators when a watched account signs on. Each sign-on
+// is written to the audit log and optionally posted to a webhook.
+type WatchAuditor struct {
+	httpClient *http.Client
+	logger     *slog.Logger
+	timeNow    func() time.Time
+	webhookURL string
+}
+
+// watchEvent is the JSON body posted to the webhook.
+type watchEvent struct {
+	Event      string    `json:"event"`
+	ScreenName string    `json:"screen_name"`
+	ClientID   string    `json:"client_id"`
+	Time       time.Time `json:"time"`
+}
+
+// WatchedSignOn writes an audit entry for a watched account's sign-on and
+// posts it to the webhook, if one is configured. The webhook is delivered in
+// the background so that it doesn't hold up the login.
+func (a WatchAuditor) WatchedSignOn(screenName state.DisplayScreenName, clientID string) {
+	a.logger.Warn("audit: watched account signed on",
+		"screen_name", screenName.String(), "client_id", clientID)
+
+	if a.webhookURL == "" {
+		return
+	}
+
+	event := watchEvent{
+		Event:      "sign_on",
+		ScreenName: screenName.String(),
+		ClientID:   clientID,
+		Time:       a.timeNow().UTC(),
+	}
+	go func() {
+		if err := postWebhook(a.httpClient, a.webhookURL, event); err != nil {
+			a.logger.Error("unable to deliver watched account webhook", "err", err.Error())
+		}
+	}()
+}
+
+// postWebhook posts event to webhookURL as JSON.
+func postWebhook(httpClient *http.Client, webhookURL string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequest: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("httpClient.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package foodgroup
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchAuditor_WatchedSignOn(t *testing.T) {
+	events := make(chan watchEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		event := watchEvent{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+	}))
+	defer srv.Close()
+
+	auditor := NewWatchAuditor(slog.Default(), srv.URL, srv.Client())
+	auditor.timeNow = func() time.Time {
+		return time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC)
+	}
+	auditor.WatchedSignOn("Watched User", "AIM Client")
+
+	select {
+	case event := <-events:
+		assert.Equal(t, watchEvent{
+			Event:      "sign_on",
+			ScreenName: "Watched User",
+			ClientID:   "AIM Client",
+			Time:       time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC),
+		}, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestNewWebhookClient(t *testing.T) {
+	t.Run("webhooks are delivered through the proxy", func(t *testing.T) {
+		t.Setenv("NO_PROXY", "")
+		t.Setenv("no_proxy", "")
+
+		// the proxy receives requests for the webhook host and answers on
+		// its behalf
+		hosts := make(chan string, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hosts <- r.URL.Host
+		}))
+		defer proxy.Close()
+
+		client, err := NewWebhookClient(proxy.URL)
+		assert.NoError(t, err)
+		assert.NoError(t, postWebhook(client, "http://webhook.example/hook", watchEvent{}))
+		assert.Equal(t, "webhook.example", <-hosts)
+	})
+
+	t.Run("hosts in NO_PROXY are reached directly", func(t *testing.T) {
+		t.Setenv("NO_PROXY", "webhook.example")
+
+		client, err := NewWebhookClient("http://proxy.example:3128")
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "http://webhook.example/hook", nil)
+		proxyURL, err := client.Transport.(*http.Transport).Proxy(req)
+		assert.NoError(t, err)
+		assert.Nil(t, proxyURL)
+	})
+
+	t.Run("proxy URL without a scheme is rejected", func(t *testing.T) {
+		_, err := NewWebhookClient("proxy.example:3128")
+		assert.Error(t, err)
+	})
+}
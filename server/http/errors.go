@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// errorCode is a machine-readable error code returned in the code field of a
+// Management API error response.
+type errorCode string
+
+const (
+	errCodeAwayTemplateNotFound       errorCode = "away_template_not_found"
+	errCodeBuddyExists                errorCode = "buddy_exists"
+	errCodeBuddyNotFound              errorCode = "buddy_not_found"
+	errCodeCategoryInUse              errorCode = "category_in_use"
+	errCodeCategoryNotFound           errorCode = "category_not_found"
+	errCodeChatRoomNotFound           errorCode = "chat_room_not_found"
+	errCodeFeedbagFull                errorCode = "feedbag_full"
+	errCodeIconNotFound               errorCode = "icon_not_found"
+	errCodeInternal                   errorCode = "internal_error"
+	errCodeInvalidInput               errorCode = "invalid_input"
+	errCodeKeywordNotFound            errorCode = "keyword_not_found"
+	errCodeMailboxFull                errorCode = "mailbox_full"
+	errCodeMalformedInput             errorCode = "malformed_input"
+	errCodeNameTaken                  errorCode = "name_taken"
+	errCodeNotICQAccount              errorCode = "not_icq_account"
+	errCodePasswordResetTokenNotFound errorCode = "password_reset_token_not_found"
+	errCodeScreenNameAliasNotFound    errorCode = "screen_name_alias_not_found"
+	errCodeSessionNotFound            errorCode = "session_not_found"
+	errCodeSharedGroupMemberNotFound  errorCode = "shared_group_member_not_found"
+	errCodeUINsExhausted              errorCode = "uins_exhausted"
+	errCodeUserNotFound               errorCode = "user_not_found"
+	errCodeVirtualUserConflict        errorCode = "virtual_user_conflict"
+	errCodeVirtualUserNotFound        errorCode = "virtual_user_not_found"
+)
+
+// errorBody is the JSON envelope returned by every Management API error
+// response.
+type errorBody struct {
+	Error   string    `json:"error"`
+	Code    errorCode `json:"code"`
+	Details string    `json:"details,omitempty"`
+}
+
+// errorMsg sends an error response with a human-readable message, HTTP status
+// and machine-readable error code.
+func errorMsg(w http.ResponseWriter, msg string, status int, code errorCode) {
+	errorMsgDetails(w, msg, "", status, code)
+}
+
+// errorMsgDetails sends an error response like errorMsg, along with details
+// that explain the cause of the error.
+func errorMsgDetails(w http.ResponseWriter, msg string, details string, status int, code errorCode) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorBody{
+		Error:   msg,
+		Code:    code,
+		Details: details,
+	})
+}
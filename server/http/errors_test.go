@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorMsg(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      func(w http.ResponseWriter)
+		statusCode int
+		want       string
+	}{
+		{
+			name: "error without details",
+			write: func(w http.ResponseWriter) {
+				errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			},
+			statusCode: http.StatusNotFound,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+		},
+		{
+			name: "error with details",
+			write: func(w http.ResponseWriter) {
+				errorMsgDetails(w, "invalid password", "password too short", http.StatusBadRequest, errCodeInvalidInput)
+			},
+			statusCode: http.StatusBadRequest,
+			want:       `{"error":"invalid password","code":"invalid_input","details":"password too short"}`,
+		},
+		{
+			name: "name conflict",
+			write: func(w http.ResponseWriter) {
+				errorMsg(w, "user already exists", http.StatusConflict, errCodeNameTaken)
+			},
+			statusCode: http.StatusConflict,
+			want:       `{"error":"user already exists","code":"name_taken"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			tc.write(responseRecorder)
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
@@ -3,7 +3,9 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +23,19 @@ import (
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
+// passwordResetTokenTTL is how long a password reset token stays valid.
+const passwordResetTokenTTL = 24 * time.Hour
+
+// maxUserInfoLen is the maximum length of a profile or away message. It
+// matches the max signature length advertised by LocateService.RightsQuery.
+const maxUserInfoLen = 1000
+
+// maxBroadcastLen is the maximum length of a server-wide broadcast. It matches
+// the max instant message length that AIM clients accept by default, so the
+// whole message is shown whether it arrives as an instant message or a message
+// of the day.
+const maxBroadcastLen = 512
+
 func NewManagementAPI(
 	bld config.Build,
 	cfg config.Config,
@@ -28,6 +43,7 @@ func NewManagementAPI(
 	sessionRetriever SessionRetriever,
 	chatRoomRetriever ChatRoomRetriever,
 	chatRoomCreator ChatRoomCreator,
+	chatRoomDeleter ChatRoomDeleter,
 	chatSessionRetriever ChatSessionRetriever,
 	directoryManager DirectoryManager,
 	messageRelayer MessageRelayer,
@@ -35,10 +51,30 @@ func NewManagementAPI(
 	feedbagRetriever FeedBagRetriever,
 	accountRetriever AccountRetriever,
 	profileRetriever ProfileRetriever,
+	profileUpdater ProfileUpdater,
+	buddyBroadcaster BuddyBroadcaster,
+	feedbagManager FeedbagManager,
+	uinAllocator UINAllocator,
+	chatSlowModeSetter ChatSlowModeSetter,
+	chatSpectatorManager ChatSpectatorManager,
+	trafficReporter TrafficReporter,
+	offlineMessageManager OfflineMessageManager,
+	chatModeratorManager ChatModeratorManager,
+	chatTranscriptRetriever ChatTranscriptRetriever,
+	quietHours *state.QuietHours,
+	awayTemplateManager AwayTemplateManager,
+	screenNamePolicy state.ScreenNamePolicy,
+	virtualUserManager VirtualUserManager,
+	sharedGroupManager SharedGroupManager,
 	logger *slog.Logger,
 ) *Server {
 	mux := http.NewServeMux()
 
+	icqDefaultPerms := state.ICQPermissions{
+		AuthRequired: cfg.ICQDefaultRequireAuth,
+		WebAware:     cfg.ICQDefaultWebAware,
+	}
+
 	// Handlers for '/user' route
 	mux.HandleFunc("DELETE /user", func(w http.ResponseWriter, r *http.Request) {
 		deleteUserHandler(w, r, userManager, logger)
@@ -47,7 +83,12 @@ func NewManagementAPI(
 		getUserHandler(w, userManager, logger)
 	})
 	mux.HandleFunc("POST /user", func(w http.ResponseWriter, r *http.Request) {
-		postUserHandler(w, r, userManager, uuid.New, logger)
+		postUserHandler(w, r, userManager, icqDefaultPerms, screenNamePolicy, uuid.New, logger)
+	})
+
+	// Handlers for '/user/icq' route
+	mux.HandleFunc("POST /user/icq", func(w http.ResponseWriter, r *http.Request) {
+		postICQUserHandler(w, r, userManager, uinAllocator, cfg.ICQUINStart, cfg.ICQUINEnd, icqDefaultPerms, uuid.New, logger)
 	})
 
 	// Handlers for '/user/password' route
@@ -65,11 +106,128 @@ func NewManagementAPI(
 		getUserAccountHandler(w, r, userManager, accountRetriever, profileRetriever, logger)
 	})
 
+	// Handlers for '/user/{screenname}/watch' route
+	mux.HandleFunc("PUT /user/{screenname}/watch", func(w http.ResponseWriter, r *http.Request) {
+		putUserWatchHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/chat-room-creator' route
+	mux.HandleFunc("PUT /user/{screenname}/chat-room-creator", func(w http.ResponseWriter, r *http.Request) {
+		putUserChatRoomCreatorHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/official' route
+	mux.HandleFunc("PUT /user/{screenname}/official", func(w http.ResponseWriter, r *http.Request) {
+		putUserOfficialHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/concurrent-login' route
+	mux.HandleFunc("PUT /user/{screenname}/concurrent-login", func(w http.ResponseWriter, r *http.Request) {
+		putUserConcurrentLoginHandler(w, r, cfg, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/ban' route
+	mux.HandleFunc("PUT /user/{screenname}/ban", func(w http.ResponseWriter, r *http.Request) {
+		putUserBanHandler(w, r, userManager, sessionRetriever, logger)
+	})
+	mux.HandleFunc("DELETE /user/{screenname}/ban", func(w http.ResponseWriter, r *http.Request) {
+		deleteUserBanHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/allowed-networks' route
+	mux.HandleFunc("PUT /user/{screenname}/allowed-networks", func(w http.ResponseWriter, r *http.Request) {
+		putUserAllowedNetworksHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/alias' route
+	mux.HandleFunc("GET /user/{screenname}/alias", func(w http.ResponseWriter, r *http.Request) {
+		getUserAliasHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/alias/{alias}' route
+	mux.HandleFunc("PUT /user/{screenname}/alias/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		putUserAliasHandler(w, r, userManager, screenNamePolicy, logger)
+	})
+	mux.HandleFunc("DELETE /user/{screenname}/alias/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		deleteUserAliasHandler(w, r, userManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/password-reset' route
+	mux.HandleFunc("POST /user/{screenname}/password-reset", func(w http.ResponseWriter, r *http.Request) {
+		postUserPasswordResetHandler(w, r, userManager, newPasswordResetToken, logger)
+	})
+
+	// Handlers for '/user/{screenname}/storage' route
+	mux.HandleFunc("GET /user/{screenname}/storage", func(w http.ResponseWriter, r *http.Request) {
+		getUserStorageHandler(w, r, userManager, logger)
+	})
+	mux.HandleFunc("PUT /user/{screenname}/storage", func(w http.ResponseWriter, r *http.Request) {
+		putUserStorageHandler(w, r, userManager, logger)
+	})
+
 	// Handlers for '/user/{screenname}/icon' route
 	mux.HandleFunc("GET /user/{screenname}/icon", func(w http.ResponseWriter, r *http.Request) {
 		getUserBuddyIconHandler(w, r, userManager, feedbagRetriever, bartRetriever, logger)
 	})
 
+	// Handlers for '/user/{screenname}/profile' route
+	mux.HandleFunc("GET /user/{screenname}/profile", func(w http.ResponseWriter, r *http.Request) {
+		getUserProfileHandler(w, r, userManager, profileRetriever, logger)
+	})
+	mux.HandleFunc("PUT /user/{screenname}/profile", func(w http.ResponseWriter, r *http.Request) {
+		putUserProfileHandler(w, r, userManager, profileUpdater, sessionRetriever, buddyBroadcaster, logger)
+	})
+
+	// Handlers for '/user/{screenname}/away' route
+	mux.HandleFunc("GET /user/{screenname}/away", func(w http.ResponseWriter, r *http.Request) {
+		getUserAwayHandler(w, r, sessionRetriever)
+	})
+	mux.HandleFunc("PUT /user/{screenname}/away", func(w http.ResponseWriter, r *http.Request) {
+		putUserAwayHandler(w, r, sessionRetriever, buddyBroadcaster, logger)
+	})
+
+	// Handlers for '/user/{screenname}/away-template' route
+	mux.HandleFunc("POST /user/{screenname}/away-template", func(w http.ResponseWriter, r *http.Request) {
+		postUserAwayTemplateHandler(w, r, sessionRetriever, awayTemplateManager, buddyBroadcaster, logger)
+	})
+
+	// Handlers for '/user/{screenname}/buddy/{buddy}' route
+	mux.HandleFunc("PUT /user/{screenname}/buddy/{buddy}", func(w http.ResponseWriter, r *http.Request) {
+		putUserBuddyHandler(w, r, userManager, feedbagManager, messageRelayer, cfg.DefaultGroupName, logger)
+	})
+	mux.HandleFunc("DELETE /user/{screenname}/buddy/{buddy}", func(w http.ResponseWriter, r *http.Request) {
+		deleteUserBuddyHandler(w, r, userManager, feedbagManager, messageRelayer, logger)
+	})
+
+	// Handlers for '/user/{screenname}/feedbag' route
+	mux.HandleFunc("GET /user/{screenname}/feedbag", func(w http.ResponseWriter, r *http.Request) {
+		getUserFeedbagHandler(w, r, userManager, feedbagManager, logger)
+	})
+	mux.HandleFunc("PUT /user/{screenname}/feedbag", func(w http.ResponseWriter, r *http.Request) {
+		putUserFeedbagHandler(w, r, userManager, feedbagManager, logger)
+	})
+
+	// Handlers for '/user/{screenname}/icq-alert' route
+	mux.HandleFunc("POST /user/{screenname}/icq-alert", func(w http.ResponseWriter, r *http.Request) {
+		postUserICQAlertHandler(w, r, userManager, sessionRetriever, messageRelayer, offlineMessageManager, quietHours, time.Now, func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		}, logger)
+	})
+
+	// Handlers for '/icq-broadcast' route
+	mux.HandleFunc("POST /icq-broadcast", func(w http.ResponseWriter, r *http.Request) {
+		postICQBroadcastHandler(w, r, userManager, sessionRetriever, messageRelayer, offlineMessageManager, cfg.ICQBroadcastOffline, quietHours, time.Now, func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		}, logger)
+	})
+
+	// Handlers for '/broadcast' route
+	mux.HandleFunc("POST /broadcast", func(w http.ResponseWriter, r *http.Request) {
+		postBroadcastHandler(w, r, sessionRetriever, messageRelayer, cfg.BroadcastScreenName, quietHours, time.Now, func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		}, logger)
+	})
+
 	// Handlers for '/session' route
 	mux.HandleFunc("GET /session", func(w http.ResponseWriter, r *http.Request) {
 		getSessionHandler(w, r, sessionRetriever, time.Since)
@@ -79,6 +237,14 @@ func NewManagementAPI(
 	mux.HandleFunc("GET /session/{screenname}", func(w http.ResponseWriter, r *http.Request) {
 		getSessionHandler(w, r, sessionRetriever, time.Since)
 	})
+	mux.HandleFunc("DELETE /session/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		deleteSessionHandler(w, r, sessionRetriever, logger)
+	})
+
+	// Handlers for '/metrics' route
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		getMetricsHandler(w, sessionRetriever, trafficReporter)
+	})
 
 	// Handlers for '/chat/room/public' route
 	mux.HandleFunc("GET /chat/room/public", func(w http.ResponseWriter, r *http.Request) {
@@ -93,16 +259,109 @@ func NewManagementAPI(
 		getPrivateChatHandler(w, r, chatRoomRetriever, chatSessionRetriever, logger)
 	})
 
+	// Handlers for '/chat-rooms/{cookie}' route
+	mux.HandleFunc("DELETE /chat-rooms/{cookie}", func(w http.ResponseWriter, r *http.Request) {
+		deleteChatRoomHandler(w, r, chatRoomRetriever, chatRoomDeleter, chatSpectatorManager, chatSessionRetriever, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/slow-mode' route
+	mux.HandleFunc("POST /chat-rooms/{cookie}/slow-mode", func(w http.ResponseWriter, r *http.Request) {
+		postChatRoomSlowModeHandler(w, r, chatRoomRetriever, chatSlowModeSetter, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/spectators' route
+	mux.HandleFunc("POST /chat-rooms/{cookie}/spectators", func(w http.ResponseWriter, r *http.Request) {
+		postChatRoomSpectatorHandler(w, r, chatRoomRetriever, userManager, chatSpectatorManager, logger)
+	})
+	mux.HandleFunc("DELETE /chat-rooms/{cookie}/spectators/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		deleteChatRoomSpectatorHandler(w, r, chatRoomRetriever, chatSpectatorManager, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/moderators' route
+	mux.HandleFunc("GET /chat-rooms/{cookie}/moderators", func(w http.ResponseWriter, r *http.Request) {
+		getChatRoomModeratorHandler(w, r, chatRoomRetriever, chatModeratorManager, logger)
+	})
+	mux.HandleFunc("POST /chat-rooms/{cookie}/moderators", func(w http.ResponseWriter, r *http.Request) {
+		postChatRoomModeratorHandler(w, r, chatRoomRetriever, userManager, chatModeratorManager, logger)
+	})
+	mux.HandleFunc("DELETE /chat-rooms/{cookie}/moderators/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		deleteChatRoomModeratorHandler(w, r, chatRoomRetriever, chatModeratorManager, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/bans' route
+	mux.HandleFunc("GET /chat-rooms/{cookie}/bans", func(w http.ResponseWriter, r *http.Request) {
+		getChatRoomBanHandler(w, r, chatRoomRetriever, chatModeratorManager, logger)
+	})
+	mux.HandleFunc("POST /chat-rooms/{cookie}/bans", func(w http.ResponseWriter, r *http.Request) {
+		postChatRoomBanHandler(w, r, chatRoomRetriever, userManager, chatModeratorManager, chatSessionRetriever, logger)
+	})
+	mux.HandleFunc("DELETE /chat-rooms/{cookie}/bans/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		deleteChatRoomBanHandler(w, r, chatRoomRetriever, chatModeratorManager, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/participants' route
+	mux.HandleFunc("DELETE /chat-rooms/{cookie}/participants/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		deleteChatRoomParticipantHandler(w, r, chatRoomRetriever, chatSessionRetriever, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/topic' route
+	mux.HandleFunc("PUT /chat-rooms/{cookie}/topic", func(w http.ResponseWriter, r *http.Request) {
+		putChatRoomTopicHandler(w, r, chatRoomRetriever, chatModeratorManager, logger)
+	})
+
+	// Handlers for '/chat-rooms/{cookie}/transcript' route
+	mux.HandleFunc("GET /chat-rooms/{cookie}/transcript", func(w http.ResponseWriter, r *http.Request) {
+		getChatRoomTranscriptHandler(w, r, chatRoomRetriever, chatTranscriptRetriever, time.Duration(cfg.ChatTranscriptTTLHours)*time.Hour, time.Now, logger)
+	})
+
 	// Handlers for '/instant-message' route
 	mux.HandleFunc("POST /instant-message", func(w http.ResponseWriter, r *http.Request) {
 		postInstantMessageHandler(w, r, messageRelayer, logger)
 	})
 
+	// Handlers for '/debug/snac' route
+	if cfg.EnableDebugAPI {
+		mux.HandleFunc("POST /debug/snac", func(w http.ResponseWriter, r *http.Request) {
+			postDebugSNACHandler(w, r, sessionRetriever, messageRelayer, logger)
+		})
+	}
+
 	// Handlers for '/version' route
 	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
 		getVersionHandler(w, bld)
 	})
 
+	// Handlers for '/virtual-user/{screenname}/presence' route
+	mux.HandleFunc("PUT /virtual-user/{screenname}/presence", func(w http.ResponseWriter, r *http.Request) {
+		putVirtualUserPresenceHandler(w, r, virtualUserManager, screenNamePolicy, logger)
+	})
+
+	// Handlers for '/away-template' route
+	mux.HandleFunc("GET /away-template", func(w http.ResponseWriter, r *http.Request) {
+		getAwayTemplateHandler(w, awayTemplateManager, logger)
+	})
+
+	// Handlers for '/away-template/{name}' route
+	mux.HandleFunc("PUT /away-template/{name}", func(w http.ResponseWriter, r *http.Request) {
+		putAwayTemplateHandler(w, r, awayTemplateManager, logger)
+	})
+	mux.HandleFunc("DELETE /away-template/{name}", func(w http.ResponseWriter, r *http.Request) {
+		deleteAwayTemplateHandler(w, r, awayTemplateManager, logger)
+	})
+
+	// Handlers for '/shared-group' route
+	mux.HandleFunc("GET /shared-group", func(w http.ResponseWriter, r *http.Request) {
+		getSharedGroupHandler(w, sharedGroupManager, logger)
+	})
+
+	// Handlers for '/shared-group/{group}/member/{screenname}' route
+	mux.HandleFunc("PUT /shared-group/{group}/member/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		putSharedGroupMemberHandler(w, r, sharedGroupManager, sessionRetriever, messageRelayer, logger)
+	})
+	mux.HandleFunc("DELETE /shared-group/{group}/member/{screenname}", func(w http.ResponseWriter, r *http.Request) {
+		deleteSharedGroupMemberHandler(w, r, sharedGroupManager, sessionRetriever, messageRelayer, logger)
+	})
+
 	// Handlers for '/directory/category' route
 	mux.HandleFunc("GET /directory/category", func(w http.ResponseWriter, r *http.Request) {
 		getDirectoryCategoryHandler(w, directoryManager, logger)
@@ -137,6 +396,7 @@ func NewManagementAPI(
 			Handler: mux,
 		},
 		Logger: logger,
+		name:   "management API",
 	}
 
 }
@@ -144,15 +404,17 @@ func NewManagementAPI(
 type Server struct {
 	http.Server
 	Logger *slog.Logger
+	// name identifies the server in log and error messages.
+	name string
 }
 
 func (s *Server) Start(ctx context.Context) error {
 	ch := make(chan error)
 
 	go func() {
-		s.Logger.Info("starting management API server", "addr", s.Addr)
+		s.Logger.Info("starting "+s.name+" server", "addr", s.Addr)
 		if err := s.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			ch <- fmt.Errorf("unable to start management API server: %w", err)
+			ch <- fmt.Errorf("unable to start %s server: %w", s.name, err)
 		}
 	}()
 
@@ -166,7 +428,7 @@ func (s *Server) Start(ctx context.Context) error {
 	defer cancel()
 
 	if err := s.Shutdown(shutdownCtx); err != nil {
-		s.Logger.Error("unable to shutdown management API server", "err", err.Error())
+		s.Logger.Error("unable to shutdown "+s.name+" server", "err", err.Error())
 	}
 	return nil
 }
@@ -175,18 +437,18 @@ func (s *Server) Start(ctx context.Context) error {
 func deleteUserHandler(w http.ResponseWriter, r *http.Request, manager UserManager, logger *slog.Logger) {
 	user, err := userFromBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
 
 	err = manager.DeleteUser(state.NewIdentScreenName(user.ScreenName))
 	switch {
 	case errors.Is(err, state.ErrNoUser):
-		http.Error(w, "user does not exist", http.StatusNotFound)
+		errorMsg(w, "user does not exist", http.StatusNotFound, errCodeUserNotFound)
 		return
 	case err != nil:
 		logger.Error("error deleting user DELETE /user", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
@@ -198,7 +460,7 @@ func deleteUserHandler(w http.ResponseWriter, r *http.Request, manager UserManag
 func putUserPasswordHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
 	input, err := userFromBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
 
@@ -207,14 +469,14 @@ func putUserPasswordHandler(w http.ResponseWriter, r *http.Request, userManager
 	if err := userManager.SetUserPassword(sn, input.Password); err != nil {
 		switch {
 		case errors.Is(err, state.ErrNoUser):
-			http.Error(w, "user does not exist", http.StatusNotFound)
+			errorMsg(w, "user does not exist", http.StatusNotFound, errCodeUserNotFound)
 			return
 		case errors.Is(err, state.ErrPasswordInvalid):
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			errorMsgDetails(w, "invalid password", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
 			return
 		default:
 			logger.Error("error updating user password PUT /user/password", "err", err.Error())
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 			return
 		}
 	}
@@ -223,6 +485,50 @@ func putUserPasswordHandler(w http.ResponseWriter, r *http.Request, userManager
 	_, _ = fmt.Fprintln(w, "Password successfully reset.")
 }
 
+// postUserPasswordResetHandler handles the POST
+// /user/{screenname}/password-reset endpoint. It flags the account as pending
+// a password reset and returns a one-time token that sets a new password when
+// passed to the public API POST /password-reset endpoint before it expires. A
+// new request replaces any previous token.
+func postUserPasswordResetHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, newToken func() (string, error), logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := newToken()
+	if err != nil {
+		logger.Error("error in POST /user/{screenname}/password-reset", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetPasswordResetToken(screenName, token, time.Now().Add(passwordResetTokenTTL)); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in POST /user/{screenname}/password-reset", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("password reset requested via management API",
+		"screen_name", screenName.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(passwordResetToken{ScreenName: screenName.String(), Token: token}); err != nil {
+		logger.Error("error encoding response POST /user/{screenname}/password-reset", "err", err.Error())
+	}
+}
+
+// newPasswordResetToken creates a random, hex-encoded password reset token.
+func newPasswordResetToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // getSessionHandler handles GET /session
 func getSessionHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever, funcTimeSince func(t time.Time) time.Duration) {
 	w.Header().Set("Content-Type", "application/json")
@@ -232,7 +538,7 @@ func getSessionHandler(w http.ResponseWriter, r *http.Request, sessionRetriever
 	if screenName := r.PathValue("screenname"); screenName != "" {
 		session := sessionRetriever.RetrieveSession(state.NewIdentScreenName(screenName))
 		if session == nil {
-			http.Error(w, "session not found", http.StatusNotFound)
+			errorMsg(w, "session not found", http.StatusNotFound, errCodeSessionNotFound)
 			return
 		}
 		allUsers = append(allUsers, session)
@@ -260,11 +566,50 @@ func getSessionHandler(w http.ResponseWriter, r *http.Request, sessionRetriever
 			AwayMessage:   s.AwayMessage(),
 			IdleSeconds:   idleSeconds,
 			IsICQ:         s.UIN() > 0,
+			BytesIn:       s.Traffic().BytesIn(),
+			BytesOut:      s.Traffic().BytesOut(),
+			KeepAlivesIn:  s.Traffic().KeepAlives(),
 		}
 	}
 
 	if err := json.NewEncoder(w).Encode(ou); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+}
+
+// deleteSessionHandler handles the DELETE /session/{screenname} endpoint. It
+// signs the user off, which tells their client that it was disconnected.
+func deleteSessionHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	session := sessionRetriever.RetrieveSession(screenName)
+	if session == nil {
+		errorMsg(w, "session not found", http.StatusNotFound, errCodeSessionNotFound)
+		return
+	}
+	session.Kick()
+
+	logger.Info("user kicked via management API",
+		"screen_name", screenName.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getMetricsHandler handles the GET /metrics endpoint. It reports the number
+// of online users and the total bytes received from and sent to clients since
+// the server started.
+func getMetricsHandler(w http.ResponseWriter, sessionRetriever SessionRetriever, trafficReporter TrafficReporter) {
+	w.Header().Set("Content-Type", "application/json")
+
+	m := serverMetrics{
+		OnlineUsers: len(sessionRetriever.AllSessions()),
+		BytesIn:     trafficReporter.BytesIn(),
+		BytesOut:    trafficReporter.BytesOut(),
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 }
@@ -276,7 +621,7 @@ func getUserHandler(w http.ResponseWriter, userManager UserManager, logger *slog
 	users, err := userManager.AllUsers()
 	if err != nil {
 		logger.Error("error in GET /user", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
@@ -290,16 +635,18 @@ func getUserHandler(w http.ResponseWriter, userManager UserManager, logger *slog
 	}
 
 	if err := json.NewEncoder(w).Encode(out); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 }
 
-// postUserHandler handles the POST /user endpoint.
-func postUserHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, newUUID func() uuid.UUID, logger *slog.Logger) {
+// postUserHandler handles the POST /user endpoint. New ICQ accounts start
+// with icqDefaultPerms privacy settings. AIM screen names must satisfy
+// screenNamePolicy.
+func postUserHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, icqDefaultPerms state.ICQPermissions, screenNamePolicy state.ScreenNamePolicy, newUUID func() uuid.UUID, logger *slog.Logger) {
 	input, err := userFromBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
 
@@ -307,12 +654,12 @@ func postUserHandler(w http.ResponseWriter, r *http.Request, userManager UserMan
 
 	if sn.IsUIN() {
 		if err := sn.ValidateUIN(); err != nil {
-			http.Error(w, fmt.Sprintf("invalid uin: %s", err), http.StatusBadRequest)
+			errorMsgDetails(w, "invalid uin", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
 			return
 		}
 	} else {
-		if err := sn.ValidateAIMHandle(); err != nil {
-			http.Error(w, fmt.Sprintf("invalid screen name: %s", err), http.StatusBadRequest)
+		if err := state.ValidateScreenName(screenNamePolicy, sn); err != nil {
+			errorMsgDetails(w, "invalid screen name", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
 			return
 		}
 	}
@@ -323,20 +670,23 @@ func postUserHandler(w http.ResponseWriter, r *http.Request, userManager UserMan
 		IdentScreenName:   sn.IdentScreenName(),
 		IsICQ:             sn.IsUIN(),
 	}
+	if user.IsICQ {
+		user.ICQPermissions = icqDefaultPerms
+	}
 
 	if err := user.HashPassword(input.Password); err != nil {
-		http.Error(w, fmt.Sprintf("invalid password: %s", err), http.StatusBadRequest)
+		errorMsgDetails(w, "invalid password", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
 	err = userManager.InsertUser(user)
 	switch {
 	case errors.Is(err, state.ErrDupUser):
-		http.Error(w, "user already exists", http.StatusConflict)
+		errorMsg(w, "user already exists", http.StatusConflict, errCodeNameTaken)
 		return
 	case err != nil:
 		logger.Error("error inserting user POST /user", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
@@ -344,6 +694,66 @@ func postUserHandler(w http.ResponseWriter, r *http.Request, userManager UserMan
 	_, _ = fmt.Fprintln(w, "User account created successfully.")
 }
 
+// postICQUserHandler handles the POST /user/icq endpoint. It registers a new
+// ICQ account with the next available UIN in the range [firstUIN, lastUIN]
+// and icqDefaultPerms privacy settings.
+func postICQUserHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, uinAllocator UINAllocator, firstUIN, lastUIN uint32, icqDefaultPerms state.ICQPermissions, newUUID func() uuid.UUID, logger *slog.Logger) {
+	input, err := userFromBody(r)
+	if err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	user := state.User{
+		AuthKey:        newUUID().String(),
+		ICQPermissions: icqDefaultPerms,
+		IsICQ:          true,
+	}
+
+	// hash the password before allocating a UIN so that bad requests don't
+	// burn UINs
+	if err := user.HashPassword(input.Password); err != nil {
+		errorMsgDetails(w, "invalid password", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	uin, err := uinAllocator.NextUIN(firstUIN, lastUIN)
+	switch {
+	case errors.Is(err, state.ErrUINRangeExhausted):
+		errorMsg(w, "no UINs available", http.StatusServiceUnavailable, errCodeUINsExhausted)
+		return
+	case err != nil:
+		logger.Error("error allocating UIN POST /user/icq", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	user.DisplayScreenName = state.DisplayScreenName(strconv.Itoa(int(uin)))
+	user.IdentScreenName = user.DisplayScreenName.IdentScreenName()
+
+	err = userManager.InsertUser(user)
+	switch {
+	case errors.Is(err, state.ErrDupUser):
+		errorMsg(w, "user already exists", http.StatusConflict, errCodeNameTaken)
+		return
+	case err != nil:
+		logger.Error("error inserting user POST /user/icq", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	out := userHandle{
+		ID:         user.IdentScreenName.String(),
+		ScreenName: user.DisplayScreenName.String(),
+		IsICQ:      user.IsICQ,
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Error("error encoding response POST /user/icq", "err", err.Error())
+	}
+}
+
 func userFromBody(r *http.Request) (userWithPassword, error) {
 	user := userWithPassword{}
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
@@ -352,6 +762,21 @@ func userFromBody(r *http.Request) (userWithPassword, error) {
 	return user, nil
 }
 
+// requestLocation returns the timezone in which to render timestamps for a
+// request. The timezone is an IANA name, such as America/New_York, taken from
+// the tz query param or, failing that, the Accept-Timezone header. It defaults
+// to UTC.
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		name = r.Header.Get("Accept-Timezone")
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
 // getUserLoginHandler is a temporary endpoint for validating user credentials for
 // chivanet. do not rely on this endpoint, as it will be eventually removed.
 func getUserLoginHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
@@ -394,7 +819,7 @@ func getUserLoginHandler(w http.ResponseWriter, r *http.Request, userManager Use
 		logger.Error("error getting user", "err", err.Error())
 		return
 	}
-	if user == nil || !user.ValidateHash(wire.StrongMD5PasswordHash(password, user.AuthKey)) {
+	if user == nil || !user.ValidatePassword(password) {
 		w.WriteHeader(http.StatusUnauthorized)
 		_, _ = w.Write([]byte("401 Unauthorized: Invalid Credentials\n"))
 		return
@@ -406,12 +831,17 @@ func getUserLoginHandler(w http.ResponseWriter, r *http.Request, userManager Use
 }
 
 // getPublicChatHandler handles the GET /chat/room/public endpoint.
-func getPublicChatHandler(w http.ResponseWriter, _ *http.Request, chatRoomRetriever ChatRoomRetriever, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
+func getPublicChatHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
 	w.Header().Set("Content-Type", "application/json")
+	loc, err := requestLocation(r)
+	if err != nil {
+		errorMsgDetails(w, "invalid timezone", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
 	rooms, err := chatRoomRetriever.AllChatRooms(state.PublicExchange)
 	if err != nil {
 		logger.Error("error in GET /chat/rooms/public", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
@@ -419,7 +849,7 @@ func getPublicChatHandler(w http.ResponseWriter, _ *http.Request, chatRoomRetrie
 	for i, room := range rooms {
 		sessions := chatSessionRetriever.AllSessions(room.Cookie())
 		cr := chatRoom{
-			CreateTime:   room.CreateTime(),
+			CreateTime:   room.CreateTime().In(loc),
 			Name:         room.Name(),
 			Participants: make([]aimChatUserHandle, len(sessions)),
 			URL:          room.URL().String(),
@@ -441,13 +871,13 @@ func getPublicChatHandler(w http.ResponseWriter, _ *http.Request, chatRoomRetrie
 func postPublicChatHandler(w http.ResponseWriter, r *http.Request, chatRoomCreator ChatRoomCreator, logger *slog.Logger) {
 	input := chatRoomCreate{}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "invalid input", http.StatusBadRequest)
+		errorMsg(w, "invalid input", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
 	input.Name = strings.TrimSpace(input.Name)
 	if input.Name == "" || len(input.Name) > 50 {
-		http.Error(w, "chat room name must be between 1 and 50 characters", http.StatusBadRequest)
+		errorMsg(w, "chat room name must be between 1 and 50 characters", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
@@ -456,11 +886,11 @@ func postPublicChatHandler(w http.ResponseWriter, r *http.Request, chatRoomCreat
 	err := chatRoomCreator.CreateChatRoom(&cr)
 	switch {
 	case errors.Is(err, state.ErrDupChatRoom):
-		http.Error(w, "Chat room already exists.", http.StatusConflict)
+		errorMsg(w, "Chat room already exists.", http.StatusConflict, errCodeNameTaken)
 		return
 	case err != nil:
 		logger.Error("error inserting chat room POST /chat/room/public", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
@@ -468,195 +898,1833 @@ func postPublicChatHandler(w http.ResponseWriter, r *http.Request, chatRoomCreat
 	_, _ = fmt.Fprintln(w, "Chat room created successfully.")
 }
 
-// getPrivateChatHandler handles the GET /chat/room/private endpoint.
-func getPrivateChatHandler(w http.ResponseWriter, _ *http.Request, chatRoomRetriever ChatRoomRetriever, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
-	w.Header().Set("Content-Type", "application/json")
-	rooms, err := chatRoomRetriever.AllChatRooms(state.PrivateExchange)
-	if err != nil {
-		logger.Error("error in GET /chat/rooms/private", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+// postChatRoomSlowModeHandler handles the POST /chat-rooms/{cookie}/slow-mode
+// endpoint. It sets the minimum number of seconds between a user's messages in
+// the chat room. A value of 0 disables slow mode.
+func postChatRoomSlowModeHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatSlowModeSetter ChatSlowModeSetter, logger *slog.Logger) {
+	input := chatRoomSlowMode{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
-
-	out := make([]chatRoom, len(rooms))
-	for i, room := range rooms {
-		sessions := chatSessionRetriever.AllSessions(room.Cookie())
-		cr := chatRoom{
-			CreateTime:   room.CreateTime(),
-			CreatorID:    room.Creator().String(),
-			Name:         room.Name(),
-			Participants: make([]aimChatUserHandle, len(sessions)),
-			URL:          room.URL().String(),
-		}
-		for j, sess := range sessions {
-			cr.Participants[j] = aimChatUserHandle{
-				ID:         sess.IdentScreenName().String(),
-				ScreenName: sess.DisplayScreenName().String(),
-			}
-		}
-
-		out[i] = cr
-	}
-
-	writeUnescapeChatURL(w, out)
-}
-
-// writeUnescapeChatURL writes a JSON-encoded list of chat rooms with unescaped
-// ampersands preceding the exchange query param.
-//
-//	before: aim:gochat?roomname=Office+Hijinks\u0026exchange=5
-//	after:  aim:gochat?roomname=Office+Hijinks&exchange=5
-//
-// This makes it easier to copy the gochat URL into AIM, which does not
-// recognize the ampersand unicode character \u0026.
-func writeUnescapeChatURL(w http.ResponseWriter, out []chatRoom) {
-	buf := &bytes.Buffer{}
-	if err := json.NewEncoder(buf).Encode(out); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if input.Seconds < 0 {
+		errorMsg(w, "seconds must not be negative", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
-	b := bytes.ReplaceAll(buf.Bytes(), []byte(`\u0026exchange`), []byte(`&exchange`))
-	if _, err := w.Write(b); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	cookie := r.PathValue("cookie")
+	room, err := chatRoomRetriever.ChatRoomByCookie(cookie)
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in POST /chat-rooms/{cookie}/slow-mode", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
+
+	chatSlowModeSetter.SetSlowMode(room.Cookie(), time.Duration(input.Seconds)*time.Second)
+	logger.Info("chat room slow mode set via management API", "room", room.Name(), "seconds", input.Seconds)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// postIMHandler handles the POST /instant-message endpoint.
-func postInstantMessageHandler(w http.ResponseWriter, r *http.Request, messageRelayer MessageRelayer, logger *slog.Logger) {
-	input := instantMessage{}
+// postChatRoomSpectatorHandler handles the POST /chat-rooms/{cookie}/spectators
+// endpoint. It lets a user join the chat room as a read-only spectator. The
+// user must join (or rejoin) the room for it to take effect.
+func postChatRoomSpectatorHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, userManager UserManager, chatSpectatorManager ChatSpectatorManager, logger *slog.Logger) {
+	input := chatRoomSpectator{}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "malformed input", http.StatusBadRequest)
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
 
-	tlv, err := wire.ICBMFragmentList(input.Text)
-	if err != nil {
-		logger.Error("error sending message POST /instant-message", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in POST /chat-rooms/{cookie}/spectators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
-	msg := wire.SNACMessage{
-		Frame: wire.SNACFrame{
-			FoodGroup: wire.ICBM,
-			SubGroup:  wire.ICBMChannelMsgToClient,
-		},
-		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
-			ChannelID: 1,
-			TLVUserInfo: wire.TLVUserInfo{
-				ScreenName: input.From,
-			},
-			TLVRestBlock: wire.TLVRestBlock{
-				TLVList: wire.TLVList{
-					wire.NewTLVBE(wire.ICBMTLVAOLIMData, tlv),
-				},
-			},
-		},
-	}
-	messageRelayer.RelayToScreenName(context.Background(), state.NewIdentScreenName(input.To), msg)
-
-	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprintln(w, "Message sent successfully.")
-}
-
-// getUserBuddyIconHandler handles the GET /user/{screenname}/icon endpoint.
-func getUserBuddyIconHandler(w http.ResponseWriter, r *http.Request, u UserManager, f FeedBagRetriever, b BARTRetriever, logger *slog.Logger) {
-	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
-	user, err := u.User(screenName)
+	user, err := userManager.User(state.NewIdentScreenName(input.ScreenName))
 	if err != nil {
-		logger.Error("error retrieving user", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		logger.Error("error in POST /chat-rooms/{cookie}/spectators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 	if user == nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
 		return
 	}
-	iconRef, err := f.BuddyIconRefByName(screenName)
-	if err != nil {
-		logger.Error("error retrieving buddy icon ref", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+
+	chatSpectatorManager.AddSpectator(room.Cookie(), user.IdentScreenName)
+	logger.Info("chat room spectator added via management API", "room", room.Name(), "screen_name", user.IdentScreenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteChatRoomSpectatorHandler handles the DELETE
+// /chat-rooms/{cookie}/spectators/{screenname} endpoint. It revokes a user's
+// spectator status for the chat room. The user must join (or rejoin) the room
+// for it to take effect.
+func deleteChatRoomSpectatorHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatSpectatorManager ChatSpectatorManager, logger *slog.Logger) {
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in DELETE /chat-rooms/{cookie}/spectators/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	chatSpectatorManager.RemoveSpectator(room.Cookie(), screenName)
+	logger.Info("chat room spectator removed via management API", "room", room.Name(), "screen_name", screenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getChatRoomModeratorHandler handles the GET /chat-rooms/{cookie}/moderators
+// endpoint. It returns the screen names of the chat room's moderators.
+func getChatRoomModeratorHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatModeratorManager ChatModeratorManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in GET /chat-rooms/{cookie}/moderators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	moderators, err := chatModeratorManager.ChatRoomModerators(room.Cookie())
+	if err != nil {
+		logger.Error("error in GET /chat-rooms/{cookie}/moderators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := make([]chatRoomModerator, len(moderators))
+	for i, moderator := range moderators {
+		out[i] = chatRoomModerator{ScreenName: moderator.String()}
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Error("error in GET /chat-rooms/{cookie}/moderators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// getChatRoomTranscriptHandler handles the GET
+// /chat-rooms/{cookie}/transcript endpoint. It returns the messages recorded
+// for the chat room, oldest first. Messages older than ttl are omitted, unless
+// ttl is 0.
+func getChatRoomTranscriptHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatTranscriptRetriever ChatTranscriptRetriever, ttl time.Duration, timeNow func() time.Time, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		errorMsgDetails(w, "invalid timezone", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in GET /chat-rooms/{cookie}/transcript", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	var since time.Time
+	if ttl > 0 {
+		since = timeNow().Add(-ttl)
+	}
+
+	entries, err := chatTranscriptRetriever.ChatTranscript(room.Cookie(), since)
+	if err != nil {
+		logger.Error("error in GET /chat-rooms/{cookie}/transcript", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := make([]chatTranscriptEntry, len(entries))
+	for i, entry := range entries {
+		out[i] = chatTranscriptEntry{
+			ScreenName: entry.ScreenName.String(),
+			Text:       entry.Text,
+			Sent:       entry.Sent.In(loc),
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Error("error in GET /chat-rooms/{cookie}/transcript", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// postChatRoomModeratorHandler handles the POST
+// /chat-rooms/{cookie}/moderators endpoint. It makes a user a moderator of the
+// chat room. Moderators can manage the room with the //kick, //ban, //unban
+// and //topic chat commands.
+func postChatRoomModeratorHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, userManager UserManager, chatModeratorManager ChatModeratorManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := chatRoomModerator{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in POST /chat-rooms/{cookie}/moderators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	user, err := userManager.User(state.NewIdentScreenName(input.ScreenName))
+	if err != nil {
+		logger.Error("error in POST /chat-rooms/{cookie}/moderators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	if err := chatModeratorManager.AddChatRoomModerator(room.Cookie(), user.IdentScreenName); err != nil {
+		logger.Error("error in POST /chat-rooms/{cookie}/moderators", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	logger.Info("chat room moderator added via management API", "room", room.Name(), "screen_name", user.IdentScreenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteChatRoomModeratorHandler handles the DELETE
+// /chat-rooms/{cookie}/moderators/{screenname} endpoint. It revokes a user's
+// moderator status for the chat room.
+func deleteChatRoomModeratorHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatModeratorManager ChatModeratorManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in DELETE /chat-rooms/{cookie}/moderators/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := chatModeratorManager.RemoveChatRoomModerator(room.Cookie(), screenName); err != nil {
+		logger.Error("error in DELETE /chat-rooms/{cookie}/moderators/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	logger.Info("chat room moderator removed via management API", "room", room.Name(), "screen_name", screenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getChatRoomBanHandler handles the GET /chat-rooms/{cookie}/bans endpoint. It
+// returns the screen names of the users banned from the chat room.
+func getChatRoomBanHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatModeratorManager ChatModeratorManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in GET /chat-rooms/{cookie}/bans", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	bans, err := chatModeratorManager.ChatRoomBans(room.Cookie())
+	if err != nil {
+		logger.Error("error in GET /chat-rooms/{cookie}/bans", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := make([]chatRoomBan, len(bans))
+	for i, ban := range bans {
+		out[i] = chatRoomBan{ScreenName: ban.String()}
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Error("error in GET /chat-rooms/{cookie}/bans", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// postChatRoomBanHandler handles the POST /chat-rooms/{cookie}/bans endpoint.
+// It bans a user from the chat room and removes them from the room if they're
+// in it.
+func postChatRoomBanHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, userManager UserManager, chatModeratorManager ChatModeratorManager, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := chatRoomBan{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in POST /chat-rooms/{cookie}/bans", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	user, err := userManager.User(state.NewIdentScreenName(input.ScreenName))
+	if err != nil {
+		logger.Error("error in POST /chat-rooms/{cookie}/bans", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	if err := chatModeratorManager.AddChatRoomBan(room.Cookie(), user.IdentScreenName); err != nil {
+		logger.Error("error in POST /chat-rooms/{cookie}/bans", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	for _, sess := range chatSessionRetriever.AllSessions(room.Cookie()) {
+		if sess.IdentScreenName() == user.IdentScreenName {
+			sess.Close()
+		}
+	}
+	logger.Info("chat room ban added via management API", "room", room.Name(), "screen_name", user.IdentScreenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteChatRoomBanHandler handles the DELETE
+// /chat-rooms/{cookie}/bans/{screenname} endpoint. It lifts a user's ban from
+// the chat room.
+func deleteChatRoomBanHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatModeratorManager ChatModeratorManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in DELETE /chat-rooms/{cookie}/bans/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := chatModeratorManager.RemoveChatRoomBan(room.Cookie(), screenName); err != nil {
+		logger.Error("error in DELETE /chat-rooms/{cookie}/bans/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	logger.Info("chat room ban removed via management API", "room", room.Name(), "screen_name", screenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteChatRoomHandler handles the DELETE /chat-rooms/{cookie} endpoint. It
+// deletes the chat room along with its moderators, bans and spectators, and
+// disconnects everyone who is in the room.
+func deleteChatRoomHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatRoomDeleter ChatRoomDeleter, chatSpectatorManager ChatSpectatorManager, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in DELETE /chat-rooms/{cookie}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	err = chatRoomDeleter.DeleteChatRoom(room.Cookie())
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in DELETE /chat-rooms/{cookie}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	chatSpectatorManager.RemoveAllSpectators(room.Cookie())
+	for _, sess := range chatSessionRetriever.AllSessions(room.Cookie()) {
+		sess.Close()
+	}
+	logger.Info("chat room deleted via management API", "room", room.Name())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteChatRoomParticipantHandler handles the DELETE
+// /chat-rooms/{cookie}/participants/{screenname} endpoint. It removes a user
+// from the chat room. Unlike a ban, the user may rejoin the room.
+func deleteChatRoomParticipantHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in DELETE /chat-rooms/{cookie}/participants/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	for _, sess := range chatSessionRetriever.AllSessions(room.Cookie()) {
+		if sess.IdentScreenName() == screenName {
+			sess.Close()
+			logger.Info("chat room participant removed via management API", "room", room.Name(), "screen_name", screenName.String())
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	errorMsg(w, "user is not in the chat room", http.StatusNotFound, errCodeSessionNotFound)
+}
+
+// putChatRoomTopicHandler handles the PUT /chat-rooms/{cookie}/topic endpoint.
+// It sets the chat room's topic, which is shown to users when they join the
+// room. An empty topic clears it.
+func putChatRoomTopicHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatModeratorManager ChatModeratorManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := chatRoomTopic{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	room, err := chatRoomRetriever.ChatRoomByCookie(r.PathValue("cookie"))
+	switch {
+	case errors.Is(err, state.ErrChatRoomNotFound):
+		errorMsg(w, "chat room not found", http.StatusNotFound, errCodeChatRoomNotFound)
+		return
+	case err != nil:
+		logger.Error("error in PUT /chat-rooms/{cookie}/topic", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if err := chatModeratorManager.SetChatRoomTopic(room.Cookie(), input.Topic); err != nil {
+		logger.Error("error in PUT /chat-rooms/{cookie}/topic", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	logger.Info("chat room topic set via management API", "room", room.Name(), "topic", input.Topic)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getPrivateChatHandler handles the GET /chat/room/private endpoint.
+func getPrivateChatHandler(w http.ResponseWriter, r *http.Request, chatRoomRetriever ChatRoomRetriever, chatSessionRetriever ChatSessionRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	loc, err := requestLocation(r)
+	if err != nil {
+		errorMsgDetails(w, "invalid timezone", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+	rooms, err := chatRoomRetriever.AllChatRooms(state.PrivateExchange)
+	if err != nil {
+		logger.Error("error in GET /chat/rooms/private", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := make([]chatRoom, len(rooms))
+	for i, room := range rooms {
+		sessions := chatSessionRetriever.AllSessions(room.Cookie())
+		cr := chatRoom{
+			CreateTime:   room.CreateTime().In(loc),
+			CreatorID:    room.Creator().String(),
+			Name:         room.Name(),
+			Participants: make([]aimChatUserHandle, len(sessions)),
+			URL:          room.URL().String(),
+		}
+		for j, sess := range sessions {
+			cr.Participants[j] = aimChatUserHandle{
+				ID:         sess.IdentScreenName().String(),
+				ScreenName: sess.DisplayScreenName().String(),
+			}
+		}
+
+		out[i] = cr
+	}
+
+	writeUnescapeChatURL(w, out)
+}
+
+// writeUnescapeChatURL writes a JSON-encoded list of chat rooms with unescaped
+// ampersands preceding the exchange query param.
+//
+//	before: aim:gochat?roomname=Office+Hijinks\u0026exchange=5
+//	after:  aim:gochat?roomname=Office+Hijinks&exchange=5
+//
+// This makes it easier to copy the gochat URL into AIM, which does not
+// recognize the ampersand unicode character \u0026.
+func writeUnescapeChatURL(w http.ResponseWriter, out []chatRoom) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(out); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	b := bytes.ReplaceAll(buf.Bytes(), []byte(`\u0026exchange`), []byte(`&exchange`))
+	if _, err := w.Write(b); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+}
+
+// postIMHandler handles the POST /instant-message endpoint.
+func postInstantMessageHandler(w http.ResponseWriter, r *http.Request, messageRelayer MessageRelayer, logger *slog.Logger) {
+	input := instantMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	tlv, err := wire.ICBMFragmentList(input.Text)
+	if err != nil {
+		logger.Error("error sending message POST /instant-message", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	msg := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: 1,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: input.From,
+			},
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, tlv),
+				},
+			},
+		},
+	}
+	messageRelayer.RelayToScreenName(context.Background(), state.NewIdentScreenName(input.To), msg)
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintln(w, "Message sent successfully.")
+}
+
+// postDebugSNACHandler handles the POST /debug/snac endpoint. It relays a
+// SNAC with an arbitrary food group, subgroup, and hex-encoded body to an
+// online user. The body is sent as-is without validation.
+func postDebugSNACHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, logger *slog.Logger) {
+	input := debugSNAC{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	body, err := hex.DecodeString(input.HexBody)
+	if err != nil {
+		errorMsg(w, "hex_body is not a valid hex string", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	sn := state.NewIdentScreenName(input.ScreenName)
+	if sessionRetriever.RetrieveSession(sn) == nil {
+		errorMsg(w, "session not found", http.StatusNotFound, errCodeSessionNotFound)
+		return
+	}
+
+	msg := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: input.FoodGroup,
+			SubGroup:  input.SubGroup,
+		},
+		Body: body,
+	}
+	messageRelayer.RelayToScreenName(r.Context(), sn, msg)
+	logger.Info("debug SNAC sent via management API", "screen_name", sn.String(),
+		"food_group", wire.FoodGroupName(input.FoodGroup), "sub_group", input.SubGroup, "len", len(body))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserBuddyIconHandler handles the GET /user/{screenname}/icon endpoint.
+func getUserBuddyIconHandler(w http.ResponseWriter, r *http.Request, u UserManager, f FeedBagRetriever, b BARTRetriever, logger *slog.Logger) {
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	user, err := u.User(screenName)
+	if err != nil {
+		logger.Error("error retrieving user", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+	iconRef, err := f.BuddyIconRefByName(screenName)
+	if err != nil {
+		logger.Error("error retrieving buddy icon ref", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if iconRef == nil || iconRef.HasClearIconHash() {
+		errorMsg(w, "icon not found", http.StatusNotFound, errCodeIconNotFound)
+		return
+	}
+	icon, err := b.BARTRetrieve(iconRef.Hash)
+	if err != nil {
+		logger.Error("error retrieving buddy icon bart item", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(icon))
+	w.Write(icon)
+}
+
+// getUserAccountHandler handles the GET /user/{screenname}/account endpoint.
+func getUserAccountHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, a AccountRetriever, p ProfileRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	screenName := r.PathValue("screenname")
+	user, err := userManager.User(state.NewIdentScreenName(screenName))
+	if err != nil {
+		logger.Error("error in GET /user/{screenname}/account", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	emailAddress := ""
+	email, err := a.EmailAddressByName(user.IdentScreenName)
+	if err != nil {
+		emailAddress = ""
+	} else {
+		emailAddress = email.String()
+	}
+	regStatus, err := a.RegStatusByName(user.IdentScreenName)
+	if err != nil {
+		logger.Error("error in GET /user/*/account RegStatus", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	confirmStatus, err := a.ConfirmStatusByName(user.IdentScreenName)
+	if err != nil {
+		logger.Error("error in GET /user/*/account ConfirmStatus", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	profile, err := p.Profile(user.IdentScreenName)
+	if err != nil {
+		logger.Error("error in GET /user/*/account Profile", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := userAccountHandle{
+		ID:                 user.IdentScreenName.String(),
+		ScreenName:         user.DisplayScreenName.String(),
+		EmailAddress:       emailAddress,
+		RegStatus:          regStatus,
+		Confirmed:          confirmStatus,
+		Profile:            profile,
+		IsICQ:              user.IsICQ,
+		Watched:            user.IsWatched,
+		CanCreateChatRooms: user.CanCreateChatRooms,
+		Official:           user.IsOfficial,
+		ConcurrentLogin:    user.ConcurrentLoginPolicy,
+		Banned:             user.IsBanned,
+		BanReason:          user.BanReason,
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+}
+
+// putUserWatchHandler handles the PUT /user/{screenname}/watch endpoint.
+// Operators are notified when a watched user signs on.
+func putUserWatchHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userWatch{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetWatched(screenName, input.Watched); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/watch", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user watch flag updated via management API",
+		"screen_name", screenName.String(), "watched", input.Watched, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putUserChatRoomCreatorHandler handles the PUT
+// /user/{screenname}/chat-room-creator endpoint. Flagged users may create chat
+// rooms when CHAT_ROOM_CREATION is set to 'flagged'.
+func putUserChatRoomCreatorHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userChatRoomCreator{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetCanCreateChatRooms(screenName, input.CanCreateChatRooms); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/chat-room-creator", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user chat room creation flag updated via management API",
+		"screen_name", screenName.String(), "can_create_chat_rooms", input.CanCreateChatRooms, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putUserOfficialHandler handles the PUT /user/{screenname}/official
+// endpoint. Official users are badged in the user info that other users see
+// from their next sign-on.
+func putUserOfficialHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userOfficial{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetOfficial(screenName, input.Official); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/official", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user official flag updated via management API",
+		"screen_name", screenName.String(), "official", input.Official, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putUserConcurrentLoginHandler handles the PUT
+// /user/{screenname}/concurrent-login endpoint. The policy overrides
+// CONCURRENT_LOGIN_POLICY for the user, and an empty policy restores the
+// server default. The reject-new policy is refused unless server keepalives
+// are enabled, since a dead connection would otherwise lock the user out.
+func putUserConcurrentLoginHandler(w http.ResponseWriter, r *http.Request, cfg config.Config, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userConcurrentLogin{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	switch input.Policy {
+	case "", config.ConcurrentLoginKickOld, config.ConcurrentLoginAllowMultiple:
+	case config.ConcurrentLoginRejectNew:
+		if cfg.ServerKeepaliveSec <= 0 {
+			errorMsg(w, fmt.Sprintf("policy '%s' requires SERVER_KEEPALIVE_SEC to be set",
+				config.ConcurrentLoginRejectNew), http.StatusBadRequest, errCodeInvalidInput)
+			return
+		}
+	default:
+		errorMsg(w, fmt.Sprintf("policy must be one of '%s', '%s', '%s', or empty",
+			config.ConcurrentLoginKickOld, config.ConcurrentLoginRejectNew, config.ConcurrentLoginAllowMultiple),
+			http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetConcurrentLoginPolicy(screenName, input.Policy); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/concurrent-login", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user concurrent login policy updated via management API",
+		"screen_name", screenName.String(), "policy", input.Policy, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putUserBanHandler handles the PUT /user/{screenname}/ban endpoint. Banned
+// users can't sign on, and a banned user who is signed on is kicked.
+func putUserBanHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, sessionRetriever SessionRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userBan{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.BanUser(screenName, input.Reason); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/ban", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if session := sessionRetriever.RetrieveSession(screenName); session != nil {
+		session.Kick()
+	}
+
+	logger.Info("user banned via management API",
+		"screen_name", screenName.String(), "reason", input.Reason, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteUserBanHandler handles the DELETE /user/{screenname}/ban endpoint.
+func deleteUserBanHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.UnbanUser(screenName); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in DELETE /user/{screenname}/ban", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user unbanned via management API",
+		"screen_name", screenName.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putUserAllowedNetworksHandler handles the PUT
+// /user/{screenname}/allowed-networks endpoint. The user may only log in from
+// the given networks, or from anywhere if the list is empty.
+func putUserAllowedNetworksHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userAllowedNetworks{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	networks, err := state.ParseAllowedNetworks(strings.Join(input.AllowedNetworks, ","))
+	if err != nil {
+		errorMsg(w, err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetAllowedNetworks(screenName, networks); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/allowed-networks", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user allowed networks updated via management API",
+		"screen_name", screenName.String(), "allowed_networks", input.AllowedNetworks, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserAliasHandler handles the GET /user/{screenname}/alias endpoint. It
+// lists the screen name aliases of the user's account.
+func getUserAliasHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
+	if err != nil {
+		logger.Error("error in GET /user/{screenname}/alias", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	aliases, err := userManager.ScreenNameAliases(user.IdentScreenName)
+	if err != nil {
+		logger.Error("error in GET /user/{screenname}/alias", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := userAliases{Aliases: []string{}}
+	for _, alias := range aliases {
+		out.Aliases = append(out.Aliases, alias.String())
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// putUserAliasHandler handles the PUT /user/{screenname}/alias/{alias}
+// endpoint. It makes alias another name for the user's account, which the
+// user can sign on with and receive messages at. Aliases must satisfy
+// screenNamePolicy.
+func putUserAliasHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, screenNamePolicy state.ScreenNamePolicy, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	alias := state.DisplayScreenName(r.PathValue("alias"))
+	if alias.IsUIN() {
+		errorMsg(w, "invalid alias", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+	if err := state.ValidateScreenName(screenNamePolicy, alias); err != nil {
+		errorMsgDetails(w, "invalid alias", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.AddScreenNameAlias(screenName, alias.IdentScreenName()); err != nil {
+		switch {
+		case errors.Is(err, state.ErrNoUser):
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		case errors.Is(err, state.ErrDupUser):
+			errorMsg(w, "screen name already taken", http.StatusConflict, errCodeNameTaken)
+		default:
+			logger.Error("error in PUT /user/{screenname}/alias/{alias}", "err", err.Error())
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		}
+		return
+	}
+
+	logger.Info("screen name alias added via management API",
+		"screen_name", screenName.String(), "alias", alias.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteUserAliasHandler handles the DELETE /user/{screenname}/alias/{alias}
+// endpoint. It removes one of the user's screen name aliases.
+func deleteUserAliasHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	alias := state.NewIdentScreenName(r.PathValue("alias"))
+	if err := userManager.DeleteScreenNameAlias(screenName, alias); err != nil {
+		if errors.Is(err, state.ErrScreenNameAliasNotFound) {
+			errorMsg(w, "alias not found", http.StatusNotFound, errCodeScreenNameAliasNotFound)
+			return
+		}
+		logger.Error("error in DELETE /user/{screenname}/alias/{alias}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("screen name alias removed via management API",
+		"screen_name", screenName.String(), "alias", alias.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserStorageHandler handles the GET /user/{screenname}/storage endpoint.
+func getUserStorageHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	usage, err := userManager.StorageUsage(screenName)
+	if err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in GET /user/{screenname}/storage", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := userStorageHandle{
+		OfflineMessageBytes: usage.OfflineMessageBytes,
+		ProfileBytes:        usage.ProfileBytes,
+		FeedbagBytes:        usage.FeedbagBytes,
+		UsedBytes:           usage.UsedBytes(),
+		QuotaBytes:          usage.QuotaBytes,
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Error("error in GET /user/{screenname}/storage", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+}
+
+// putUserStorageHandler handles the PUT /user/{screenname}/storage endpoint.
+// It sets the user's storage quota, or reverts the user to the default quota
+// if the quota is null.
+func putUserStorageHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userStorageQuota{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if input.QuotaBytes != nil && *input.QuotaBytes < 0 {
+		errorMsg(w, "quota_bytes must not be negative", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := userManager.SetStorageQuota(screenName, input.QuotaBytes); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/storage", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user storage quota updated via management API",
+		"screen_name", screenName.String(), "quota_bytes", input.QuotaBytes, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserProfileHandler handles the GET /user/{screenname}/profile endpoint.
+func getUserProfileHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, p ProfileRetriever, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
+	if err != nil {
+		logger.Error("error in GET /user/{screenname}/profile", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	profile, err := p.Profile(user.IdentScreenName)
+	if err != nil {
+		logger.Error("error in GET /user/{screenname}/profile", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(userProfile{Profile: profile}); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// putUserProfileHandler handles the PUT /user/{screenname}/profile endpoint.
+// The profile is sanitized the same way as profiles set by clients and stored
+// for both online and offline users. If the user is online, their buddies are
+// sent the user's latest info.
+func putUserProfileHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, p ProfileUpdater, sessionRetriever SessionRetriever, buddyBroadcaster BuddyBroadcaster, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userProfile{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if len(input.Profile) > maxUserInfoLen {
+		errorMsg(w, fmt.Sprintf("profile must be no longer than %d characters", maxUserInfoLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
+	if err != nil {
+		logger.Error("error in PUT /user/{screenname}/profile", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	if err := p.SetProfile(user.IdentScreenName, state.SanitizeUserInfo(input.Profile)); err != nil {
+		logger.Error("error in PUT /user/{screenname}/profile", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	// let the user's buddies know that their info changed
+	if sess := sessionRetriever.RetrieveSession(user.IdentScreenName); sess != nil && sess.SignonComplete() {
+		if err := buddyBroadcaster.BroadcastBuddyArrived(r.Context(), sess); err != nil {
+			logger.Error("error in PUT /user/{screenname}/profile", "err", err.Error())
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+			return
+		}
+	}
+
+	logger.Info("user profile updated via management API",
+		"screen_name", user.IdentScreenName.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserAwayHandler handles the GET /user/{screenname}/away endpoint. Away
+// messages are not stored, so the user must be online.
+func getUserAwayHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess := sessionRetriever.RetrieveSession(state.NewIdentScreenName(r.PathValue("screenname")))
+	if sess == nil {
+		errorMsg(w, "session not found", http.StatusNotFound, errCodeSessionNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(userAwayMessage{AwayMessage: sess.AwayMessage()}); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// putUserAwayHandler handles the PUT /user/{screenname}/away endpoint. It sets
+// the away message of an online user and notifies their buddies of the
+// change.
+func putUserAwayHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever, buddyBroadcaster BuddyBroadcaster, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := userAwayMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if len(input.AwayMessage) > maxUserInfoLen {
+		errorMsg(w, fmt.Sprintf("away message must be no longer than %d characters", maxUserInfoLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	sess := sessionRetriever.RetrieveSession(state.NewIdentScreenName(r.PathValue("screenname")))
+	if sess == nil {
+		errorMsg(w, "session not found", http.StatusNotFound, errCodeSessionNotFound)
+		return
+	}
+
+	sess.SetAwayMessage(state.SanitizeUserInfo(input.AwayMessage))
+	if err := buddyBroadcaster.BroadcastBuddyArrived(r.Context(), sess); err != nil {
+		logger.Error("error in PUT /user/{screenname}/away", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("user away message updated via management API",
+		"screen_name", sess.IdentScreenName().String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postUserAwayTemplateHandler handles the POST
+// /user/{screenname}/away-template endpoint. It sets the user's away message
+// to the message of an operator-defined template and notifies the user's
+// buddies, just as if the user had set the away message from their client.
+func postUserAwayTemplateHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever, awayTemplateManager AwayTemplateManager, buddyBroadcaster BuddyBroadcaster, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := awayTemplateApply{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	template, err := awayTemplateManager.AwayTemplate(input.Name)
+	if err != nil {
+		if errors.Is(err, state.ErrAwayTemplateNotFound) {
+			errorMsg(w, "away template not found", http.StatusNotFound, errCodeAwayTemplateNotFound)
+			return
+		}
+		logger.Error("error in POST /user/{screenname}/away-template", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	sess := sessionRetriever.RetrieveSession(state.NewIdentScreenName(r.PathValue("screenname")))
+	if sess == nil {
+		errorMsg(w, "session not found", http.StatusNotFound, errCodeSessionNotFound)
+		return
+	}
+
+	sess.SetAwayMessage(template.Message)
+	if err := buddyBroadcaster.BroadcastBuddyArrived(r.Context(), sess); err != nil {
+		logger.Error("error in POST /user/{screenname}/away-template", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("away template applied via management API",
+		"screen_name", sess.IdentScreenName().String(), "template", template.Name, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postUserICQAlertHandler handles the POST /user/{screenname}/icq-alert
+// endpoint. It sends an ICQ server message, such as a birthday reminder, to
+// an ICQ user over the ICQ message channel. If the user is offline, the alert
+// is stored and delivered with the user's offline messages at next sign-on.
+// Alerts that are not marked critical are held back during quiet hours and
+// delivered once quiet hours end.
+func postUserICQAlertHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, offlineMessageManager OfflineMessageManager, quietHours *state.QuietHours, timeNow func() time.Time, afterFunc func(d time.Duration, f func()), logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := icqAlert{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if input.Message == "" {
+		errorMsg(w, "message is required", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+	if len(input.Message) > maxUserInfoLen {
+		errorMsg(w, fmt.Sprintf("message must be no longer than %d characters", maxUserInfoLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
+	if err != nil {
+		logger.Error("error in POST /user/{screenname}/icq-alert", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+	if !user.IsICQ {
+		errorMsg(w, "user is not an ICQ account", http.StatusBadRequest, errCodeNotICQAccount)
+		return
+	}
+
+	if wait := quietHours.Remaining(timeNow()); wait > 0 && !input.Critical {
+		afterFunc(wait, func() {
+			if _, err := deliverICQAlert(context.Background(), user.IdentScreenName, input.Message, sessionRetriever, messageRelayer, offlineMessageManager, timeNow); err != nil {
+				logger.Error("unable to deliver ICQ alert after quiet hours", "err", err.Error())
+				return
+			}
+			logger.Info("ICQ alert delivered after quiet hours", "screen_name", user.IdentScreenName.String())
+		})
+		logger.Info("ICQ alert held until quiet hours end via management API",
+			"screen_name", user.IdentScreenName.String(), "delay", wait.String(), "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	stored, err := deliverICQAlert(r.Context(), user.IdentScreenName, input.Message, sessionRetriever, messageRelayer, offlineMessageManager, timeNow)
+	if err != nil {
+		if errors.Is(err, state.ErrStorageQuotaExceeded) || errors.Is(err, state.ErrOfflineMessageLimit) {
+			errorMsg(w, "user can't receive any more offline messages", http.StatusConflict, errCodeMailboxFull)
+			return
+		}
+		logger.Error("error in POST /user/{screenname}/icq-alert", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if stored {
+		logger.Info("ICQ alert stored for offline delivery via management API",
+			"screen_name", user.IdentScreenName.String(), "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	logger.Info("ICQ alert sent via management API",
+		"screen_name", user.IdentScreenName.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deliverICQAlert sends an ICQ server message to an online user, or stores it
+// as an offline message if the user is offline. It reports whether the alert
+// was stored.
+func deliverICQAlert(ctx context.Context, recipient state.IdentScreenName, message string, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, offlineMessageManager OfflineMessageManager, timeNow func() time.Time) (bool, error) {
+	tlvs, err := newICQAlert(message)
+	if err != nil {
+		return false, err
+	}
+
+	if sessionRetriever.RetrieveSession(recipient) == nil {
+		err := storeICQAlert(recipient, tlvs, offlineMessageManager, timeNow)
+		return err == nil, err
+	}
+
+	relayICQAlert(ctx, recipient, tlvs, messageRelayer)
+	return false, nil
+}
+
+// postICQBroadcastHandler handles the POST /icq-broadcast endpoint. It sends
+// an ICQ server message to every online ICQ user over the ICQ message
+// channel. If storeOffline is set, the message is also stored for offline ICQ
+// users and delivered with their offline messages at next sign-on. Broadcasts
+// that are not marked critical are held back during quiet hours and sent once
+// quiet hours end.
+func postICQBroadcastHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, offlineMessageManager OfflineMessageManager, storeOffline bool, quietHours *state.QuietHours, timeNow func() time.Time, afterFunc func(d time.Duration, f func()), logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := icqAlert{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if input.Message == "" {
+		errorMsg(w, "message is required", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+	if len(input.Message) > maxUserInfoLen {
+		errorMsg(w, fmt.Sprintf("message must be no longer than %d characters", maxUserInfoLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	if wait := quietHours.Remaining(timeNow()); wait > 0 && !input.Critical {
+		afterFunc(wait, func() {
+			result, err := broadcastICQAlert(context.Background(), input.Message, storeOffline, userManager, sessionRetriever, messageRelayer, offlineMessageManager, timeNow)
+			if err != nil {
+				logger.Error("unable to send ICQ broadcast after quiet hours", "err", err.Error())
+				return
+			}
+			logger.Info("ICQ broadcast sent after quiet hours", "sent", result.Sent, "stored", result.Stored,
+				"skipped", result.Skipped)
+		})
+		logger.Info("ICQ broadcast held until quiet hours end via management API",
+			"delay", wait.String(), "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	result, err := broadcastICQAlert(r.Context(), input.Message, storeOffline, userManager, sessionRetriever, messageRelayer, offlineMessageManager, timeNow)
+	if err != nil {
+		logger.Error("error in POST /icq-broadcast", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("ICQ broadcast sent via management API",
+		"sent", result.Sent, "stored", result.Stored, "skipped", result.Skipped, "remote_addr", r.RemoteAddr)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Error("error in POST /icq-broadcast", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// broadcastICQAlert sends an ICQ server message to all online ICQ users. If
+// storeOffline is set, the message is stored for offline ICQ users as well.
+// Offline users who can't receive any more offline messages are skipped.
+func broadcastICQAlert(ctx context.Context, message string, storeOffline bool, userManager UserManager, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, offlineMessageManager OfflineMessageManager, timeNow func() time.Time) (icqBroadcastResult, error) {
+	result := icqBroadcastResult{}
+
+	tlvs, err := newICQAlert(message)
+	if err != nil {
+		return result, err
+	}
+
+	for _, sess := range sessionRetriever.AllSessions() {
+		if sess.UIN() == 0 {
+			continue
+		}
+		relayICQAlert(ctx, sess.IdentScreenName(), tlvs, messageRelayer)
+		result.Sent++
+	}
+
+	if !storeOffline {
+		return result, nil
+	}
+
+	users, err := userManager.AllUsers()
+	if err != nil {
+		return result, fmt.Errorf("AllUsers: %w", err)
+	}
+	for _, user := range users {
+		if !user.IsICQ || sessionRetriever.RetrieveSession(user.IdentScreenName) != nil {
+			continue
+		}
+		if err := storeICQAlert(user.IdentScreenName, tlvs, offlineMessageManager, timeNow); err != nil {
+			if errors.Is(err, state.ErrStorageQuotaExceeded) || errors.Is(err, state.ErrOfflineMessageLimit) {
+				// skip users who are out of storage or whose mailbox is full
+				result.Skipped++
+				continue
+			}
+			return result, fmt.Errorf("SaveMessage: %w", err)
+		}
+		result.Stored++
+	}
+
+	return result, nil
+}
+
+// newICQAlert creates the ICBM payload for an ICQ server message.
+func newICQAlert(message string) (wire.TLVRestBlock, error) {
+	buf := &bytes.Buffer{}
+	if err := wire.MarshalLE(wire.ICBMCh4Message{
+		MessageType: wire.ICBMMsgTypeServer,
+		Message:     message,
+	}, buf); err != nil {
+		return wire.TLVRestBlock{}, err
+	}
+	return wire.TLVRestBlock{
+		TLVList: wire.TLVList{
+			wire.NewTLVBE(wire.ICBMTLVData, buf.Bytes()),
+		},
+	}, nil
+}
+
+// icqAlertSender is the sender of ICQ server messages. The message doesn't
+// originate from a user, so it's sent from UIN 0.
+var icqAlertSender = state.NewIdentScreenName("0")
+
+// relayICQAlert sends an ICQ server message to an online user.
+func relayICQAlert(ctx context.Context, recipient state.IdentScreenName, tlvs wire.TLVRestBlock, messageRelayer MessageRelayer) {
+	messageRelayer.RelayToScreenName(ctx, recipient, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: wire.ICBMChannelICQ,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: icqAlertSender.String(),
+			},
+			TLVRestBlock: tlvs,
+		},
+	})
+}
+
+// storeICQAlert stores an ICQ server message as an offline message.
+func storeICQAlert(recipient state.IdentScreenName, tlvs wire.TLVRestBlock, offlineMessageManager OfflineMessageManager, timeNow func() time.Time) error {
+	return offlineMessageManager.SaveMessage(state.OfflineMessage{
+		Sender:    icqAlertSender,
+		Recipient: recipient,
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			ChannelID:    wire.ICBMChannelICQ,
+			ScreenName:   recipient.String(),
+			TLVRestBlock: tlvs,
+		},
+		Sent: timeNow().UTC(),
+	})
+}
+
+// postBroadcastHandler handles the POST /broadcast endpoint. It sends a
+// message to every online user, such as a maintenance announcement. If
+// fromScreenName is set, the message is delivered as an instant message from
+// that screen name. Otherwise, it's delivered as a message of the day.
+// Broadcasts that are not marked critical are held back during quiet hours and
+// sent to the users who are online once quiet hours end.
+func postBroadcastHandler(w http.ResponseWriter, r *http.Request, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, fromScreenName string, quietHours *state.QuietHours, timeNow func() time.Time, afterFunc func(d time.Duration, f func()), logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := broadcast{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if input.Message == "" {
+		errorMsg(w, "message is required", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+	if len(input.Message) > maxBroadcastLen {
+		errorMsg(w, fmt.Sprintf("message must be no longer than %d characters", maxBroadcastLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	msg, err := newBroadcastMessage(input.Message, fromScreenName)
+	if err != nil {
+		logger.Error("error in POST /broadcast", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
 	}
-	if iconRef == nil || iconRef.HasClearIconHash() {
-		http.Error(w, "icon not found", http.StatusNotFound)
+
+	if wait := quietHours.Remaining(timeNow()); wait > 0 && !input.Critical {
+		afterFunc(wait, func() {
+			result := sendBroadcast(context.Background(), msg, sessionRetriever, messageRelayer)
+			logger.Info("broadcast sent after quiet hours", "sent", result.Sent)
+		})
+		logger.Info("broadcast held until quiet hours end via management API",
+			"delay", wait.String(), "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusAccepted)
 		return
 	}
-	icon, err := b.BARTRetrieve(iconRef.Hash)
+
+	result := sendBroadcast(r.Context(), msg, sessionRetriever, messageRelayer)
+
+	logger.Info("broadcast sent via management API", "sent", result.Sent, "remote_addr", r.RemoteAddr)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Error("error in POST /broadcast", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// sendBroadcast relays msg to every online user.
+func sendBroadcast(ctx context.Context, msg wire.SNACMessage, sessionRetriever SessionRetriever, messageRelayer MessageRelayer) broadcastResult {
+	result := broadcastResult{}
+	for _, sess := range sessionRetriever.AllSessions() {
+		messageRelayer.RelayToScreenName(ctx, sess.IdentScreenName(), msg)
+		result.Sent++
+	}
+	return result
+}
+
+// newBroadcastMessage creates the SNAC that delivers a broadcast. It's an
+// instant message from fromScreenName, or a message of the day if
+// fromScreenName is empty.
+func newBroadcastMessage(message string, fromScreenName string) (wire.SNACMessage, error) {
+	if fromScreenName == "" {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.OService,
+				SubGroup:  wire.OServiceMotd,
+			},
+			Body: wire.SNAC_0x01_0x13_OServiceMOTD{
+				MessageType: wire.OServiceMOTDTypeNormal,
+				TLVRestBlock: wire.TLVRestBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.OServiceMOTDTLVMessage, message),
+					},
+				},
+			},
+		}, nil
+	}
+
+	frags, err := wire.ICBMFragmentList(message)
 	if err != nil {
-		logger.Error("error retrieving buddy icon bart item", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return wire.SNACMessage{}, err
+	}
+	return wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: wire.ICBMChannelIM,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: fromScreenName,
+			},
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+				},
+			},
+		},
+	}, nil
+}
+
+// putUserBuddyHandler handles the PUT /user/{screenname}/buddy/{buddy}
+// endpoint. It adds a buddy to the user's server-side buddy list, creating
+// the buddy group if it doesn't exist. Buddies added without a group are
+// placed in defaultGroup. If the user is online, their client is sent the
+// changes so that its buddy list stays in sync with the server.
+func putUserBuddyHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, feedbagManager FeedbagManager, messageRelayer MessageRelayer, defaultGroup string, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := buddyCreate{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+			return
+		}
+	}
+	input.Group = strings.TrimSpace(input.Group)
+	if input.Group == "" {
+		input.Group = defaultGroup
+	}
+
+	buddy := state.DisplayScreenName(r.PathValue("buddy"))
+	if buddy.IdentScreenName().String() == "" {
+		errorMsg(w, "invalid buddy screen name", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
-	w.Header().Set("Content-Type", http.DetectContentType(icon))
-	w.Write(icon)
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
+	if err != nil {
+		logger.Error("error in PUT /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	items, err := feedbagManager.Feedbag(user.IdentScreenName)
+	if err != nil {
+		logger.Error("error in PUT /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	for _, item := range items {
+		if item.ClassID == wire.FeedbagClassIdBuddy && state.NewIdentScreenName(item.Name) == buddy.IdentScreenName() {
+			errorMsg(w, "buddy already exists", http.StatusConflict, errCodeBuddyExists)
+			return
+		}
+	}
+
+	inserted, updated, err := state.AddBuddyToFeedbag(items, buddy, input.Group)
+	if err != nil {
+		if errors.Is(err, state.ErrFeedbagFull) {
+			errorMsg(w, "buddy list is full", http.StatusConflict, errCodeFeedbagFull)
+			return
+		}
+		logger.Error("error in PUT /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if err := feedbagManager.FeedbagUpsert(user.IdentScreenName, append(inserted, updated...)); err != nil {
+		logger.Error("error in PUT /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	syncFeedbag(r.Context(), messageRelayer, user.IdentScreenName, wire.FeedbagInsertItem, inserted)
+	syncFeedbag(r.Context(), messageRelayer, user.IdentScreenName, wire.FeedbagUpdateItem, updated)
+
+	logger.Info("buddy added via management API", "screen_name", user.IdentScreenName.String(),
+		"buddy", buddy.IdentScreenName().String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// getUserAccountHandler handles the GET /user/{screenname}/account endpoint.
-func getUserAccountHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, a AccountRetriever, p ProfileRetriever, logger *slog.Logger) {
+// deleteUserBuddyHandler handles the DELETE /user/{screenname}/buddy/{buddy}
+// endpoint. It removes a buddy from the user's server-side buddy list. If the
+// user is online, their client is sent the changes so that its buddy list
+// stays in sync with the server.
+func deleteUserBuddyHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, feedbagManager FeedbagManager, messageRelayer MessageRelayer, logger *slog.Logger) {
 	w.Header().Set("Content-Type", "application/json")
 
-	screenName := r.PathValue("screenname")
-	user, err := userManager.User(state.NewIdentScreenName(screenName))
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
 	if err != nil {
-		logger.Error("error in GET /user/{screenname}/account", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		logger.Error("error in DELETE /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 	if user == nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
 		return
 	}
 
-	emailAddress := ""
-	email, err := a.EmailAddressByName(user.IdentScreenName)
+	items, err := feedbagManager.Feedbag(user.IdentScreenName)
 	if err != nil {
-		emailAddress = ""
-	} else {
-		emailAddress = email.String()
+		logger.Error("error in DELETE /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
 	}
-	regStatus, err := a.RegStatusByName(user.IdentScreenName)
+
+	deleted, updated, err := state.RemoveBuddyFromFeedbag(items, state.NewIdentScreenName(r.PathValue("buddy")))
 	if err != nil {
-		logger.Error("error in GET /user/*/account RegStatus", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		logger.Error("error in DELETE /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
-	confirmStatus, err := a.ConfirmStatusByName(user.IdentScreenName)
+	if len(deleted) == 0 {
+		errorMsg(w, "buddy not found", http.StatusNotFound, errCodeBuddyNotFound)
+		return
+	}
+
+	if err := feedbagManager.FeedbagDelete(user.IdentScreenName, deleted); err != nil {
+		logger.Error("error in DELETE /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if err := feedbagManager.FeedbagUpsert(user.IdentScreenName, updated); err != nil {
+		logger.Error("error in DELETE /user/{screenname}/buddy/{buddy}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	syncFeedbag(r.Context(), messageRelayer, user.IdentScreenName, wire.FeedbagDeleteItem, deleted)
+	syncFeedbag(r.Context(), messageRelayer, user.IdentScreenName, wire.FeedbagUpdateItem, updated)
+
+	logger.Info("buddy removed via management API", "screen_name", user.IdentScreenName.String(),
+		"buddy", r.PathValue("buddy"), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserFeedbagHandler handles the GET /user/{screenname}/feedbag endpoint.
+// It exports the user's server-side buddy list, including groups, buddies,
+// permit/deny entries, and buddy icons, in the format accepted by
+// putUserFeedbagHandler.
+func getUserFeedbagHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, feedbagManager FeedbagManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
 	if err != nil {
-		logger.Error("error in GET /user/*/account ConfirmStatus", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		logger.Error("error in GET /user/{screenname}/feedbag", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
-	profile, err := p.Profile(user.IdentScreenName)
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	items, err := feedbagManager.Feedbag(user.IdentScreenName)
 	if err != nil {
-		logger.Error("error in GET /user/*/account Profile", "err", err.Error())
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		logger.Error("error in GET /user/{screenname}/feedbag", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
-	out := userAccountHandle{
-		ID:           user.IdentScreenName.String(),
-		ScreenName:   user.DisplayScreenName.String(),
-		EmailAddress: emailAddress,
-		RegStatus:    regStatus,
-		Confirmed:    confirmStatus,
-		Profile:      profile,
-		IsICQ:        user.IsICQ,
+	out := feedbag{Items: make([]feedbagItem, 0, len(items))}
+	for _, item := range state.OrderFeedbag(items) {
+		attrs := make([]feedbagAttribute, 0, len(item.TLVList))
+		for _, tlv := range item.TLVList {
+			attrs = append(attrs, feedbagAttribute{Tag: tlv.Tag, Value: tlv.Value})
+		}
+		out.Items = append(out.Items, feedbagItem{
+			GroupID:    item.GroupID,
+			ItemID:     item.ItemID,
+			ClassID:    item.ClassID,
+			Name:       item.Name,
+			Attributes: attrs,
+		})
 	}
 
 	if err := json.NewEncoder(w).Encode(out); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// putUserFeedbagHandler handles the PUT /user/{screenname}/feedbag endpoint.
+// It replaces the user's server-side buddy list with one exported by
+// getUserFeedbagHandler. Signed-on clients aren't sent the changes; they pick
+// up the new list the next time they check whether it was modified.
+func putUserFeedbagHandler(w http.ResponseWriter, r *http.Request, userManager UserManager, feedbagManager FeedbagManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := feedbag{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+
+	items := make([]wire.FeedbagItem, 0, len(input.Items))
+	for _, in := range input.Items {
+		item := wire.FeedbagItem{
+			GroupID: in.GroupID,
+			ItemID:  in.ItemID,
+			ClassID: in.ClassID,
+			Name:    in.Name,
+		}
+		for _, attr := range in.Attributes {
+			item.Append(wire.TLV{Tag: attr.Tag, Value: attr.Value})
+		}
+		items = append(items, item)
+	}
+	if err := state.ValidateFeedbag(items); err != nil {
+		errorMsgDetails(w, "invalid feedbag", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	user, err := userManager.User(state.NewIdentScreenName(r.PathValue("screenname")))
+	if err != nil {
+		logger.Error("error in PUT /user/{screenname}/feedbag", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+	if user == nil {
+		errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+		return
+	}
+
+	if err := feedbagManager.FeedbagReplace(user.IdentScreenName, items); err != nil {
+		logger.Error("error in PUT /user/{screenname}/feedbag", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
+
+	logger.Info("feedbag imported via management API", "screen_name", user.IdentScreenName.String(),
+		"items", len(items), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // getVersionHandler handles the GET /version endpoint.
 func getVersionHandler(w http.ResponseWriter, bld config.Build) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(bld); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 }
@@ -667,7 +2735,7 @@ func getDirectoryCategoryHandler(w http.ResponseWriter, manager DirectoryManager
 	categories, err := manager.Categories()
 	if err != nil {
 		logger.Error("error in GET /directory/category", "err", err.Error())
-		errorMsg(w, "internal server error", http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		return
 	}
 
@@ -680,7 +2748,7 @@ func getDirectoryCategoryHandler(w http.ResponseWriter, manager DirectoryManager
 	}
 
 	if err := json.NewEncoder(w).Encode(out); err != nil {
-		errorMsg(w, err.Error(), http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 	}
 }
 
@@ -688,17 +2756,17 @@ func getDirectoryCategoryHandler(w http.ResponseWriter, manager DirectoryManager
 func postDirectoryCategoryHandler(w http.ResponseWriter, r *http.Request, manager DirectoryManager, logger *slog.Logger) {
 	input := directoryCategoryCreate{}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		errorMsg(w, "malformed input", http.StatusBadRequest)
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
 
 	category, err := manager.CreateCategory(input.Name)
 	if err != nil {
 		if errors.Is(err, state.ErrKeywordCategoryExists) {
-			errorMsg(w, "category already exists", http.StatusConflict)
+			errorMsg(w, "category already exists", http.StatusConflict, errCodeNameTaken)
 		} else {
 			logger.Error("error in POST /directory/category", "err", err.Error())
-			errorMsg(w, "internal server error", http.StatusInternalServerError)
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		}
 		return
 	}
@@ -709,7 +2777,7 @@ func postDirectoryCategoryHandler(w http.ResponseWriter, r *http.Request, manage
 		Name: category.Name,
 	}
 	if err := json.NewEncoder(w).Encode(dc); err != nil {
-		errorMsg(w, err.Error(), http.StatusBadRequest)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 	}
 }
 
@@ -717,21 +2785,21 @@ func postDirectoryCategoryHandler(w http.ResponseWriter, r *http.Request, manage
 func deleteDirectoryCategoryHandler(w http.ResponseWriter, r *http.Request, manager DirectoryManager, logger *slog.Logger) {
 	categoryID, err := strconv.ParseUint(r.PathValue("id"), 10, 8)
 	if err != nil {
-		http.Error(w, "invalid category ID", http.StatusBadRequest)
+		errorMsg(w, "invalid category ID", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
 	if err := manager.DeleteCategory(uint8(categoryID)); err != nil {
 		switch {
 		case errors.Is(err, state.ErrKeywordCategoryNotFound):
-			errorMsg(w, "category not found", http.StatusNotFound)
+			errorMsg(w, "category not found", http.StatusNotFound, errCodeCategoryNotFound)
 			return
 		case errors.Is(err, state.ErrKeywordInUse):
-			errorMsg(w, "can't delete because category in use by a user", http.StatusConflict)
+			errorMsg(w, "can't delete because category in use by a user", http.StatusConflict, errCodeCategoryInUse)
 			return
 		default:
 			logger.Error("error in DELETE /directory/category/{id}", "err", err.Error())
-			errorMsg(w, "internal server error", http.StatusInternalServerError)
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 			return
 		}
 	}
@@ -745,17 +2813,17 @@ func getDirectoryCategoryKeywordHandler(w http.ResponseWriter, r *http.Request,
 
 	categoryID, err := strconv.ParseUint(r.PathValue("id"), 10, 8)
 	if err != nil {
-		errorMsg(w, "invalid category ID", http.StatusBadRequest)
+		errorMsg(w, "invalid category ID", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
 	categories, err := manager.KeywordsByCategory(uint8(categoryID))
 	if err != nil {
 		if errors.Is(err, state.ErrKeywordCategoryNotFound) {
-			errorMsg(w, "category not found", http.StatusNotFound)
+			errorMsg(w, "category not found", http.StatusNotFound, errCodeCategoryNotFound)
 		} else {
 			logger.Error("error in GET /directory/category/{id}/keyword", "err", err.Error())
-			errorMsg(w, "internal server error", http.StatusInternalServerError)
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 		}
 		return
 	}
@@ -769,7 +2837,7 @@ func getDirectoryCategoryKeywordHandler(w http.ResponseWriter, r *http.Request,
 	}
 
 	if err := json.NewEncoder(w).Encode(out); err != nil {
-		errorMsg(w, err.Error(), http.StatusInternalServerError)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 	}
 }
 
@@ -779,7 +2847,7 @@ func postDirectoryKeywordHandler(w http.ResponseWriter, r *http.Request, manager
 
 	input := directoryKeywordCreate{}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		errorMsg(w, "malformed input", http.StatusBadRequest)
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
 		return
 	}
 
@@ -787,14 +2855,14 @@ func postDirectoryKeywordHandler(w http.ResponseWriter, r *http.Request, manager
 	if err != nil {
 		switch {
 		case errors.Is(err, state.ErrKeywordCategoryNotFound):
-			errorMsg(w, "category not found", http.StatusNotFound)
+			errorMsg(w, "category not found", http.StatusNotFound, errCodeCategoryNotFound)
 			return
 		case errors.Is(err, state.ErrKeywordExists):
-			errorMsg(w, "keyword already exists", http.StatusConflict)
+			errorMsg(w, "keyword already exists", http.StatusConflict, errCodeNameTaken)
 			return
 		default:
 			logger.Error("error in POST /directory/keyword", "err", err.Error())
-			errorMsg(w, "internal server error", http.StatusInternalServerError)
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 			return
 		}
 	}
@@ -806,7 +2874,7 @@ func postDirectoryKeywordHandler(w http.ResponseWriter, r *http.Request, manager
 		Name: kw.Name,
 	}
 	if err := json.NewEncoder(w).Encode(dc); err != nil {
-		errorMsg(w, err.Error(), http.StatusBadRequest)
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
 	}
 }
 
@@ -814,33 +2882,285 @@ func postDirectoryKeywordHandler(w http.ResponseWriter, r *http.Request, manager
 func deleteDirectoryKeywordHandler(w http.ResponseWriter, r *http.Request, manager DirectoryManager, logger *slog.Logger) {
 	keywordID, err := strconv.ParseUint(r.PathValue("id"), 10, 8)
 	if err != nil {
-		errorMsg(w, "invalid keyword ID", http.StatusBadRequest)
+		errorMsg(w, "invalid keyword ID", http.StatusBadRequest, errCodeInvalidInput)
 		return
 	}
 
 	if err := manager.DeleteKeyword(uint8(keywordID)); err != nil {
 		switch {
 		case errors.Is(err, state.ErrKeywordInUse):
-			errorMsg(w, "can't delete because category in use by a user", http.StatusConflict)
+			errorMsg(w, "can't delete because category in use by a user", http.StatusConflict, errCodeCategoryInUse)
 			return
 		case errors.Is(err, state.ErrKeywordNotFound):
-			errorMsg(w, "keyword not found", http.StatusNotFound)
+			errorMsg(w, "keyword not found", http.StatusNotFound, errCodeKeywordNotFound)
 			return
 		default:
 			logger.Error("error in DELETE /directory/keyword/{id}", "err", err.Error())
-			errorMsg(w, "internal server error", http.StatusInternalServerError)
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putVirtualUserPresenceHandler handles the PUT
+// /virtual-user/{screenname}/presence endpoint. It signs a virtual user on or
+// off, or changes its away message, and notifies the user's buddies as if the
+// user were signed on from a client.
+func putVirtualUserPresenceHandler(w http.ResponseWriter, r *http.Request, virtualUserManager VirtualUserManager, screenNamePolicy state.ScreenNamePolicy, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := virtualUserPresence{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if len(input.AwayMessage) > maxUserInfoLen {
+		errorMsg(w, fmt.Sprintf("away message must be no longer than %d characters", maxUserInfoLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName := state.DisplayScreenName(r.PathValue("screenname"))
+
+	var err error
+	if input.Online {
+		if err := state.ValidateScreenName(screenNamePolicy, screenName); err != nil {
+			errorMsgDetails(w, "invalid screen name", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+			return
+		}
+		err = virtualUserManager.SetOnline(r.Context(), screenName, input.AwayMessage, input.Mobile)
+	} else {
+		err = virtualUserManager.SetOffline(r.Context(), screenName.IdentScreenName())
+	}
+
+	switch {
+	case errors.Is(err, state.ErrVirtualUserConflict):
+		errorMsg(w, "screen name belongs to a registered or signed-on user", http.StatusConflict, errCodeVirtualUserConflict)
+		return
+	case errors.Is(err, state.ErrVirtualUserNotFound):
+		errorMsg(w, "virtual user not found", http.StatusNotFound, errCodeVirtualUserNotFound)
+		return
+	case err != nil:
+		logger.Error("error in PUT /virtual-user/{screenname}/presence", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("virtual user presence updated via management API",
+		"screen_name", screenName.String(), "online", input.Online, "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAwayTemplateHandler handles the GET /away-template endpoint.
+func getAwayTemplateHandler(w http.ResponseWriter, awayTemplateManager AwayTemplateManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	templates, err := awayTemplateManager.AwayTemplates()
+	if err != nil {
+		logger.Error("error in GET /away-template", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := make([]awayTemplate, len(templates))
+	for i, template := range templates {
+		out[i] = awayTemplate{
+			Name:    template.Name,
+			Message: template.Message,
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// putAwayTemplateHandler handles the PUT /away-template/{name} endpoint. It
+// creates the template or replaces the message of an existing one.
+func putAwayTemplateHandler(w http.ResponseWriter, r *http.Request, awayTemplateManager AwayTemplateManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input := awayTemplateUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if input.Message == "" {
+		errorMsg(w, "message is required", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+	if len(input.Message) > maxUserInfoLen {
+		errorMsg(w, fmt.Sprintf("message must be no longer than %d characters", maxUserInfoLen), http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	template := state.AwayTemplate{
+		Name:    r.PathValue("name"),
+		Message: input.Message,
+	}
+	if err := awayTemplateManager.SetAwayTemplate(template); err != nil {
+		logger.Error("error in PUT /away-template/{name}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteAwayTemplateHandler handles the DELETE /away-template/{name} endpoint.
+func deleteAwayTemplateHandler(w http.ResponseWriter, r *http.Request, awayTemplateManager AwayTemplateManager, logger *slog.Logger) {
+	if err := awayTemplateManager.DeleteAwayTemplate(r.PathValue("name")); err != nil {
+		if errors.Is(err, state.ErrAwayTemplateNotFound) {
+			errorMsg(w, "away template not found", http.StatusNotFound, errCodeAwayTemplateNotFound)
+			return
+		}
+		logger.Error("error in DELETE /away-template/{name}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSharedGroupHandler handles the GET /shared-group endpoint. It lists the
+// shared groups and their members.
+func getSharedGroupHandler(w http.ResponseWriter, sharedGroupManager SharedGroupManager, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	members, err := sharedGroupManager.SharedGroupMembers()
+	if err != nil {
+		logger.Error("error in GET /shared-group", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	out := []sharedGroup{}
+	for _, member := range members {
+		if len(out) == 0 || out[len(out)-1].Name != member.Group {
+			out = append(out, sharedGroup{Name: member.Group})
+		}
+		out[len(out)-1].Members = append(out[len(out)-1].Members, member.ScreenName.String())
+	}
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+	}
+}
+
+// putSharedGroupMemberHandler handles the PUT
+// /shared-group/{group}/member/{screenname} endpoint. It adds a user to a
+// shared group, creating the group if it doesn't exist, and sends the change
+// to the buddy lists of users who are online.
+func putSharedGroupMemberHandler(w http.ResponseWriter, r *http.Request, sharedGroupManager SharedGroupManager, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	group := strings.TrimSpace(r.PathValue("group"))
+	if group == "" {
+		errorMsg(w, "group name is required", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := sharedGroupManager.AddSharedGroupMember(group, screenName); err != nil {
+		if errors.Is(err, state.ErrNoUser) {
+			errorMsg(w, "user not found", http.StatusNotFound, errCodeUserNotFound)
+			return
+		}
+		logger.Error("error in PUT /shared-group/{group}/member/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if err := syncSharedGroup(r.Context(), sharedGroupManager, sessionRetriever, messageRelayer, group); err != nil {
+		logger.Error("error in PUT /shared-group/{group}/member/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	logger.Info("shared group member added via management API", "group", group,
+		"screen_name", screenName.String(), "remote_addr", r.RemoteAddr)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSharedGroupMemberHandler handles the DELETE
+// /shared-group/{group}/member/{screenname} endpoint. It removes a user from a
+// shared group and sends the change to the buddy lists of users who are
+// online. The group is removed from buddy lists once its last member is
+// removed.
+func deleteSharedGroupMemberHandler(w http.ResponseWriter, r *http.Request, sharedGroupManager SharedGroupManager, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	group := strings.TrimSpace(r.PathValue("group"))
+	screenName := state.NewIdentScreenName(r.PathValue("screenname"))
+	if err := sharedGroupManager.RemoveSharedGroupMember(group, screenName); err != nil {
+		if errors.Is(err, state.ErrSharedGroupMemberNotFound) {
+			errorMsg(w, "shared group member not found", http.StatusNotFound, errCodeSharedGroupMemberNotFound)
 			return
 		}
+		logger.Error("error in DELETE /shared-group/{group}/member/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
+	}
+
+	if err := syncSharedGroup(r.Context(), sharedGroupManager, sessionRetriever, messageRelayer, group); err != nil {
+		logger.Error("error in DELETE /shared-group/{group}/member/{screenname}", "err", err.Error())
+		errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		return
 	}
 
+	logger.Info("shared group member removed via management API", "group", group,
+		"screen_name", screenName.String(), "remote_addr", r.RemoteAddr)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// errorMsg sends an error response message and code.
-func errorMsg(w http.ResponseWriter, error string, code int) {
-	msg := messageBody{Message: error}
-	w.WriteHeader(code)
-	if err := json.NewEncoder(w).Encode(msg); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// syncSharedGroup brings a shared group up to date in the buddy lists of
+// online users who use server-side buddy lists. Everyone else picks up the
+// change the next time they sign on.
+func syncSharedGroup(ctx context.Context, sharedGroupManager SharedGroupManager, sessionRetriever SessionRetriever, messageRelayer MessageRelayer, group string) error {
+	for _, sess := range sessionRetriever.AllSessions() {
+		if !sess.FeedbagInUse() {
+			continue
+		}
+		inserted, updated, deleted, err := sharedGroupManager.SyncSharedGroup(sess.IdentScreenName(), group)
+		if err != nil {
+			return err
+		}
+		syncFeedbag(ctx, messageRelayer, sess.IdentScreenName(), wire.FeedbagDeleteItem, deleted)
+		syncFeedbag(ctx, messageRelayer, sess.IdentScreenName(), wire.FeedbagInsertItem, inserted)
+		syncFeedbag(ctx, messageRelayer, sess.IdentScreenName(), wire.FeedbagUpdateItem, updated)
+	}
+	return nil
+}
+
+// syncFeedbag sends feedbag changes made outside the client to the user's
+// online session, so that the client's buddy list stays consistent with the
+// server. subGroup is one of wire.FeedbagInsertItem, wire.FeedbagUpdateItem,
+// or wire.FeedbagDeleteItem. Nothing is sent if the user is offline, since the
+// client retrieves the full list at the next sign-on.
+func syncFeedbag(ctx context.Context, messageRelayer MessageRelayer, screenName state.IdentScreenName, subGroup uint16, items []wire.FeedbagItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	var body any
+	switch subGroup {
+	case wire.FeedbagInsertItem:
+		body = wire.SNAC_0x13_0x08_FeedbagInsertItem{Items: items}
+	case wire.FeedbagUpdateItem:
+		body = wire.SNAC_0x13_0x09_FeedbagUpdateItem{Items: items}
+	case wire.FeedbagDeleteItem:
+		body = wire.SNAC_0x13_0x0A_FeedbagDeleteItem{Items: items}
+	default:
+		return
 	}
+
+	messageRelayer.RelayToScreenName(ctx, screenName, wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  subGroup,
+		},
+		Body: body,
+	})
 }
@@ -1,13 +1,17 @@
 package http
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/mail"
+	"net/netip"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -52,7 +56,7 @@ func TestSessionHandler_GET(t *testing.T) {
 		},
 		{
 			name:          "with sessions",
-			want:          `{"count":3,"sessions":[{"id":"usera","screen_name":"userA","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":false},{"id":"userb","screen_name":"userB","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":false},{"id":"100003","screen_name":"100003","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":true}]}`,
+			want:          `{"count":3,"sessions":[{"id":"usera","screen_name":"userA","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":false,"bytes_in":1024,"bytes_out":2048,"keepalives_in":0},{"id":"userb","screen_name":"userB","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":false,"bytes_in":0,"bytes_out":0,"keepalives_in":0},{"id":"100003","screen_name":"100003","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":true,"bytes_in":0,"bytes_out":0,"keepalives_in":0}]}`,
 			statusCode:    http.StatusOK,
 			timeSinceFunc: func(t time.Time) time.Duration { t0 := time.Now(); return t0.Sub(t0) },
 			mockParams: mockParams{
@@ -60,7 +64,12 @@ func TestSessionHandler_GET(t *testing.T) {
 					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
 						{
 							result: []*state.Session{
-								fnNewSess("userA", 0),
+								func() *state.Session {
+									sess := fnNewSess("userA", 0)
+									sess.Traffic().AddBytesIn(1024)
+									sess.Traffic().AddBytesOut(2048)
+									return sess
+								}(),
 								fnNewSess("userB", 0),
 								fnNewSess("100003", 100003),
 							},
@@ -96,6 +105,27 @@ func TestSessionHandler_GET(t *testing.T) {
 	}
 }
 
+func TestMetricsHandler_GET(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+
+	sessionRetriever := newMockSessionRetriever(t)
+	sessionRetriever.EXPECT().
+		AllSessions().
+		Return([]*state.Session{state.NewSession(), state.NewSession()})
+	trafficReporter := newMockTrafficReporter(t)
+	trafficReporter.EXPECT().
+		BytesIn().
+		Return(1024)
+	trafficReporter.EXPECT().
+		BytesOut().
+		Return(2048)
+
+	getMetricsHandler(responseRecorder, sessionRetriever, trafficReporter)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, `{"online_users":2,"bytes_in":1024,"bytes_out":2048}`, strings.TrimSpace(responseRecorder.Body.String()))
+}
+
 func TestSessionHandlerScreenname_GET(t *testing.T) {
 	fnNewSess := func(screenName string, uin uint32) *state.Session {
 		sess := state.NewSession()
@@ -117,7 +147,7 @@ func TestSessionHandlerScreenname_GET(t *testing.T) {
 			name:              "no session for screenname",
 			sessions:          []*state.Session{},
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              `session not found`,
+			want:              `{"error":"session not found","code":"session_not_found"}`,
 			statusCode:        http.StatusNotFound,
 			mockParams: mockParams{
 				sessionRetrieverParams: sessionRetrieverParams{
@@ -133,7 +163,7 @@ func TestSessionHandlerScreenname_GET(t *testing.T) {
 		{
 			name:              "active session found for screenname",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              `{"count":1,"sessions":[{"id":"usera","screen_name":"userA","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":false}]}`,
+			want:              `{"count":1,"sessions":[{"id":"usera","screen_name":"userA","online_seconds":0,"away_message":"","idle_seconds":0,"is_icq":false,"bytes_in":0,"bytes_out":0,"keepalives_in":0}]}`,
 			statusCode:        http.StatusOK,
 			timeSinceFunc:     func(t time.Time) time.Duration { t0 := time.Now(); return t0.Sub(t0) },
 			mockParams: mockParams{
@@ -175,6 +205,56 @@ func TestSessionHandlerScreenname_GET(t *testing.T) {
 	}
 }
 
+func TestSessionHandler_DELETE(t *testing.T) {
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		session           *state.Session
+		want              string
+		statusCode        int
+	}{
+		{
+			name:              "kick signed-on user",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			session:           state.NewSession(),
+			statusCode:        http.StatusNoContent,
+		},
+		{
+			name:              "no session for screenname",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"error":"session not found","code":"session_not_found"}`,
+			statusCode:        http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/session/"+tc.requestScreenName.String(), nil)
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			responseRecorder := httptest.NewRecorder()
+
+			sessionRetriever := newMockSessionRetriever(t)
+			sessionRetriever.EXPECT().
+				RetrieveSession(tc.requestScreenName).
+				Return(tc.session)
+
+			deleteSessionHandler(responseRecorder, request, sessionRetriever, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+
+			if tc.session != nil {
+				assert.True(t, tc.session.Kicked())
+			}
+		})
+	}
+}
+
 func TestUserAccountHandler_GET(t *testing.T) {
 	tt := []struct {
 		name              string
@@ -186,7 +266,7 @@ func TestUserAccountHandler_GET(t *testing.T) {
 		{
 			name:              "invalid account",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              `user not found`,
+			want:              `{"error":"user not found","code":"user_not_found"}`,
 			statusCode:        http.StatusNotFound,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
@@ -202,7 +282,7 @@ func TestUserAccountHandler_GET(t *testing.T) {
 		{
 			name:              "valid aim account",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              `{"id":"usera","screen_name":"userA","profile":"My Profile Text","email_address":"\u003cuserA@aol.com\u003e","reg_status":2,"confirmed":true,"is_icq":false}`,
+			want:              `{"id":"usera","screen_name":"userA","profile":"My Profile Text","email_address":"\u003cuserA@aol.com\u003e","reg_status":2,"confirmed":true,"is_icq":false,"watched":false,"can_create_chat_rooms":false,"official":false,"concurrent_login_policy":"","banned":false,"ban_reason":""}`,
 			statusCode:        http.StatusOK,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
@@ -300,27 +380,19 @@ func TestUserAccountHandler_GET(t *testing.T) {
 	}
 }
 
-func TestUserBuddyIconHandler_GET(t *testing.T) {
-	sampleGIF := []byte{
-		0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x32, 0x00, 0x32, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
-		0x32, 0x00, 0x32, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b}
-
-	sampleJPG := []byte{0xFF, 0xD8, 0xFF, 0x43, 0x13, 0x37}
+func TestUserProfileHandler_GET(t *testing.T) {
 	tt := []struct {
 		name              string
 		requestScreenName state.IdentScreenName
 		want              string
 		statusCode        int
-		contentType       string
 		mockParams        mockParams
 	}{
 		{
-			name:              "invalid account",
+			name:              "user not found",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              `user not found`,
+			want:              `{"error":"user not found","code":"user_not_found"}`,
 			statusCode:        http.StatusNotFound,
-			contentType:       "text/plain; charset=utf-8",
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
@@ -333,11 +405,10 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 			},
 		},
 		{
-			name:              "account with gif buddy icon",
+			name:              "retrieve profile",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              string(sampleGIF),
+			want:              `{"profile":"My Profile Text"}`,
 			statusCode:        http.StatusOK,
-			contentType:       "image/gif",
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
@@ -350,36 +421,21 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 						},
 					},
 				},
-				feedBagRetrieverParams: feedBagRetrieverParams{
-					buddyIconRefByNameParams: buddyIconRefByNameParams{
+				profileRetrieverParams: profileRetrieverParams{
+					retrieveProfileParams: retrieveProfileParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
-							result: &wire.BARTID{
-								Type: wire.BARTTypesBuddyIcon,
-								BARTInfo: wire.BARTInfo{
-									Flags: 0x00,
-									Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-								},
-							},
-						},
-					},
-				},
-				bartRetrieverParams: bartRetrieverParams{
-					bartRetrieveParams: bartRetrieveParams{
-						{
-							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-							result:   sampleGIF,
+							result:     "My Profile Text",
 						},
 					},
 				},
 			},
 		},
 		{
-			name:              "account with jpg buddy icon",
+			name:              "profile retriever returns runtime error",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              string(sampleJPG),
-			statusCode:        http.StatusOK,
-			contentType:       "image/jpeg",
+			want:              `{"error":"internal server error","code":"internal_error"}`,
+			statusCode:        http.StatusInternalServerError,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
@@ -392,36 +448,64 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 						},
 					},
 				},
-				feedBagRetrieverParams: feedBagRetrieverParams{
-					buddyIconRefByNameParams: buddyIconRefByNameParams{
+				profileRetrieverParams: profileRetrieverParams{
+					retrieveProfileParams: retrieveProfileParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
-							result: &wire.BARTID{
-								Type: wire.BARTTypesBuddyIcon,
-								BARTInfo: wire.BARTInfo{
-									Flags: 0x00,
-									Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-								},
-							},
-						},
-					},
-				},
-				bartRetrieverParams: bartRetrieverParams{
-					bartRetrieveParams: bartRetrieveParams{
-						{
-							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-							result:   sampleJPG,
+							err:        io.EOF,
 						},
 					},
 				},
 			},
 		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.requestScreenName.String()+"/profile", nil)
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			profileRetriever := newMockProfileRetriever(t)
+			for _, params := range tc.mockParams.profileRetrieverParams.retrieveProfileParams {
+				profileRetriever.EXPECT().
+					Profile(params.screenName).
+					Return(params.result, params.err)
+			}
+
+			getUserProfileHandler(responseRecorder, request, userManager, profileRetriever, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserProfileHandler_PUT(t *testing.T) {
+	onlineSess := state.NewSession()
+	onlineSess.SetIdentScreenName(state.NewIdentScreenName("userA"))
+	onlineSess.SetSignonComplete()
+
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		sess              *state.Session
+		body              string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
 		{
-			name:              "account with unknown format buddy icon",
+			name:              "update profile of offline user",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              string([]byte{0x13, 0x37, 0x13, 0x37, 0x13, 0x37}),
-			statusCode:        http.StatusOK,
-			contentType:       "application/octet-stream",
+			body:              `{"profile":"My New Profile"}`,
+			statusCode:        http.StatusNoContent,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
@@ -434,36 +518,29 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 						},
 					},
 				},
-				feedBagRetrieverParams: feedBagRetrieverParams{
-					buddyIconRefByNameParams: buddyIconRefByNameParams{
+				profileUpdaterParams: profileUpdaterParams{
+					setProfileParams: setProfileParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
-							result: &wire.BARTID{
-								Type: wire.BARTTypesBuddyIcon,
-								BARTInfo: wire.BARTInfo{
-									Flags: 0x00,
-									Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-								},
-							},
+							body:       "My New Profile",
 						},
 					},
 				},
-				bartRetrieverParams: bartRetrieverParams{
-					bartRetrieveParams: bartRetrieveParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
-							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
-							result:   []byte{0x13, 0x37, 0x13, 0x37, 0x13, 0x37},
+							screenName: state.NewIdentScreenName("userA"),
 						},
 					},
 				},
 			},
 		},
 		{
-			name:              "account with cleared buddy icon",
+			name:              "update profile of online user, sanitize it and notify buddies",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              "icon not found",
-			statusCode:        http.StatusNotFound,
-			contentType:       "text/plain; charset=utf-8",
+			sess:              onlineSess,
+			body:              `{"profile":"<B>My New Profile</B><SCRIPT>alert(1)</SCRIPT>"}`,
+			statusCode:        http.StatusNoContent,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
@@ -476,42 +553,39 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 						},
 					},
 				},
-				feedBagRetrieverParams: feedBagRetrieverParams{
-					buddyIconRefByNameParams: buddyIconRefByNameParams{
+				profileUpdaterParams: profileUpdaterParams{
+					setProfileParams: setProfileParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							body:       "<B>My New Profile</B>",
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
-							result: &wire.BARTID{
-								Type: wire.BARTTypesBuddyIcon,
-								BARTInfo: wire.BARTInfo{
-									Flags: 0x00,
-									Hash:  wire.GetClearIconHash(),
-								},
-							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:              "account with no buddy icon",
+			name:              "user not found",
 			requestScreenName: state.NewIdentScreenName("userA"),
-			want:              "icon not found",
+			body:              `{"profile":"My New Profile"}`,
+			want:              `{"error":"user not found","code":"user_not_found"}`,
 			statusCode:        http.StatusNotFound,
-			contentType:       "text/plain; charset=utf-8",
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
 					getUserParams: getUserParams{
-						{
-							screenName: state.NewIdentScreenName("userA"),
-							result: &state.User{
-								DisplayScreenName: "userA",
-								IdentScreenName:   state.NewIdentScreenName("userA"),
-							},
-						},
-					},
-				},
-				feedBagRetrieverParams: feedBagRetrieverParams{
-					buddyIconRefByNameParams: buddyIconRefByNameParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
 							result:     nil,
@@ -520,11 +594,25 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:              "profile too long",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"profile":"` + strings.Repeat("a", 1001) + `"}`,
+			want:              `{"error":"profile must be no longer than 1000 characters","code":"invalid_input"}`,
+			statusCode:        http.StatusBadRequest,
+		},
+		{
+			name:              "malformed body",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"profile":"My New Profile"`,
+			want:              `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:        http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.requestScreenName.String()+"/icon", nil)
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.requestScreenName.String()+"/profile", strings.NewReader(tc.body))
 			request.SetPathValue("screenname", tc.requestScreenName.String())
 			responseRecorder := httptest.NewRecorder()
 
@@ -534,98 +622,74 @@ func TestUserBuddyIconHandler_GET(t *testing.T) {
 					User(params.screenName).
 					Return(params.result, params.err)
 			}
-
-			feedbagRetriever := newMockFeedBagRetriever(t)
-			for _, params := range tc.mockParams.feedBagRetrieverParams.buddyIconRefByNameParams {
-				feedbagRetriever.EXPECT().
-					BuddyIconRefByName(params.screenName).
-					Return(params.result, params.err)
+			profileUpdater := newMockProfileUpdater(t)
+			for _, params := range tc.mockParams.profileUpdaterParams.setProfileParams {
+				profileUpdater.EXPECT().
+					SetProfile(params.screenName, params.body).
+					Return(params.err)
 			}
-
-			bartRetriever := newMockBARTRetriever(t)
-			for _, params := range tc.mockParams.bartRetrieverParams.bartRetrieveParams {
-				bartRetriever.EXPECT().
-					BARTRetrieve(params.itemHash).
-					Return(params.result, params.err)
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(tc.sess)
 			}
-
-			getUserBuddyIconHandler(responseRecorder, request, userManager, feedbagRetriever, bartRetriever, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			buddyBroadcaster := newMockBuddyBroadcaster(t)
+			for _, params := range tc.mockParams.buddyBroadcasterParams.broadcastBuddyArrivedParams {
+				buddyBroadcaster.EXPECT().
+					BroadcastBuddyArrived(mock.Anything, mock.MatchedBy(func(s *state.Session) bool {
+						return s.IdentScreenName() == params.screenName
+					})).
+					Return(params.err)
 			}
 
-			contentType := responseRecorder.Header().Get("Content-Type")
-			if contentType != tc.contentType {
-				t.Errorf("Want content type '%s', got '%s'", tc.contentType, contentType)
-			}
+			putUserProfileHandler(responseRecorder, request, userManager, profileUpdater, sessionRetriever, buddyBroadcaster, slog.Default())
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
-			}
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
 		})
 	}
 }
 
-func TestUserHandler_GET(t *testing.T) {
+func TestUserAwayHandler_GET(t *testing.T) {
+	sess := state.NewSession()
+	sess.SetIdentScreenName(state.NewIdentScreenName("userA"))
+	sess.SetAwayMessage("gone fishing")
+
 	tt := []struct {
-		name       string
-		want       string
-		statusCode int
-		mockParams mockParams
+		name              string
+		requestScreenName state.IdentScreenName
+		want              string
+		statusCode        int
+		mockParams        mockParams
 	}{
 		{
-			name:       "empty user store",
-			want:       `[]`,
-			statusCode: http.StatusOK,
+			name:              "retrieve away message of online user",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"away_message":"gone fishing"}`,
+			statusCode:        http.StatusOK,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					allUsersParams: allUsersParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
-							result: []state.User{},
+							screenName: state.NewIdentScreenName("userA"),
+							result:     sess,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "user store containing 3 users",
-			want:       `[{"id":"usera","screen_name":"userA","is_icq":false},{"id":"userb","screen_name":"userB","is_icq":false},{"id":"100003","screen_name":"100003","is_icq":true}]`,
-			statusCode: http.StatusOK,
+			name:              "user is offline",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"error":"session not found","code":"session_not_found"}`,
+			statusCode:        http.StatusNotFound,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					allUsersParams: allUsersParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
-							result: []state.User{
-								{
-									DisplayScreenName: "userA",
-									IdentScreenName:   state.NewIdentScreenName("userA"),
-								},
-								{
-									DisplayScreenName: "userB",
-									IdentScreenName:   state.NewIdentScreenName("userB"),
-								},
-								{
-									DisplayScreenName: "100003",
-									IdentScreenName:   state.NewIdentScreenName("100003"),
-									IsICQ:             true,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-		{
-			name:       "user handler error",
-			want:       `internal server error`,
-			statusCode: http.StatusInternalServerError,
-			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					allUsersParams: allUsersParams{
-						{
-							result: []state.User{},
-							err:    io.EOF,
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
 						},
 					},
 				},
@@ -635,207 +699,165 @@ func TestUserHandler_GET(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.requestScreenName.String()+"/away", nil)
+			request.SetPathValue("screenname", tc.requestScreenName.String())
 			responseRecorder := httptest.NewRecorder()
 
-			userManager := newMockUserManager(t)
-			for _, params := range tc.mockParams.userManagerParams.allUsersParams {
-				userManager.EXPECT().
-					AllUsers().
-					Return(params.result, params.err)
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
 			}
 
-			getUserHandler(responseRecorder, userManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
-			}
+			getUserAwayHandler(responseRecorder, request, sessionRetriever)
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
-			}
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
 		})
 	}
 }
 
-func TestUserHandler_POST(t *testing.T) {
+func TestUserAwayHandler_PUT(t *testing.T) {
+	newSess := func() *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName("userA"))
+		return sess
+	}
+
 	tt := []struct {
-		name       string
-		body       string
-		UUID       uuid.UUID
-		want       string
-		password   string
-		statusCode int
-		mockParams mockParams
+		name              string
+		requestScreenName state.IdentScreenName
+		sess              *state.Session
+		body              string
+		want              string
+		wantAwayMessage   string
+		statusCode        int
+		mockParams        mockParams
 	}{
 		{
-			name: "with valid AIM user",
-			body: `{"screen_name":"userA", "password":"thepassword"}`,
-			UUID: uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-
-			want:       `User account created successfully.`,
-			password:   "thepassword",
-			statusCode: http.StatusCreated,
-			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					insertUserParams: insertUserParams{
-						{
-							u: state.User{
-								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
-								DisplayScreenName: "userA",
-								IdentScreenName:   state.NewIdentScreenName("userA"),
-							},
-							err: nil,
-						},
-					},
-				},
-			},
-		},
-		{
-			name:       "with valid ICQ user",
-			body:       `{"screen_name":"100003", "password":"thepass"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `User account created successfully.`,
-			password:   "thepass",
-			statusCode: http.StatusCreated,
+			name:              "update away message of online user and notify buddies",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			sess:              newSess(),
+			body:              `{"away_message":"gone fishing"}`,
+			wantAwayMessage:   "gone fishing",
+			statusCode:        http.StatusNoContent,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					insertUserParams: insertUserParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
-							u: state.User{
-								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
-								DisplayScreenName: "100003",
-								IdentScreenName:   state.NewIdentScreenName("100003"),
-								IsICQ:             true,
-							},
-							err: nil,
+							screenName: state.NewIdentScreenName("userA"),
 						},
 					},
 				},
-			},
-		},
-		{
-			name:       "with malformed body",
-			body:       `{"screen_name":"userA", "password":"thepassword"`, // missing closing }
-			want:       `malformed input`,
-			statusCode: http.StatusBadRequest,
-		},
-		{
-			name:       "user handler error",
-			body:       `{"screen_name":"userA", "password":"thepassword"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `internal server error`,
-			password:   "thepassword",
-			statusCode: http.StatusInternalServerError,
-			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					insertUserParams: insertUserParams{
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
 						{
-							u: state.User{
-								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
-								DisplayScreenName: "userA",
-								IdentScreenName:   state.NewIdentScreenName("userA"),
-							},
-							err: io.EOF,
+							screenName: state.NewIdentScreenName("userA"),
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "duplicate user",
-			body:       `{"screen_name":"userA", "password":"thepassword"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `user already exists`,
-			password:   "thepassword",
-			statusCode: http.StatusConflict,
+			name:              "user is offline",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"away_message":"gone fishing"}`,
+			want:              `{"error":"session not found","code":"session_not_found"}`,
+			statusCode:        http.StatusNotFound,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					insertUserParams: insertUserParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
-							u: state.User{
-								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
-								DisplayScreenName: "userA",
-								IdentScreenName:   state.NewIdentScreenName("userA"),
-							},
-							err: state.ErrDupUser,
+							screenName: state.NewIdentScreenName("userA"),
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "invalid AIM screen name",
-			body:       `{"screen_name":"a", "password":"thepassword"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `invalid screen name: screen name must be between 3 and 16 characters`,
-			statusCode: http.StatusBadRequest,
-		},
-		{
-			name:       "invalid AIM password",
-			body:       `{"screen_name":"userA", "password":"1"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `invalid password: invalid password length: password length must be between 4-16 characters`,
-			statusCode: http.StatusBadRequest,
-		},
-		{
-			name:       "invalid ICQ UIN",
-			body:       `{"screen_name":"1000", "password":"thepass"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `invalid uin: uin must be a number in the range 10000-2147483646`,
-			statusCode: http.StatusBadRequest,
-		},
-		{
-			name:       "invalid ICQ password",
-			body:       `{"screen_name":"100003", "password":"thelongpassword"}`,
-			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
-			want:       `invalid password: invalid password length: password must be between 6-8 characters`,
-			statusCode: http.StatusBadRequest,
+			name:              "away message too long",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"away_message":"` + strings.Repeat("a", 1001) + `"}`,
+			want:              `{"error":"away message must be no longer than 1000 characters","code":"invalid_input"}`,
+			statusCode:        http.StatusBadRequest,
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(tc.body))
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.requestScreenName.String()+"/away", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.requestScreenName.String())
 			responseRecorder := httptest.NewRecorder()
 
-			userManager := newMockUserManager(t)
-			for _, params := range tc.mockParams.userManagerParams.insertUserParams {
-				assert.NoError(t, params.u.HashPassword(tc.password))
-				userManager.EXPECT().
-					InsertUser(params.u).
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(tc.sess)
+			}
+			buddyBroadcaster := newMockBuddyBroadcaster(t)
+			for _, params := range tc.mockParams.buddyBroadcasterParams.broadcastBuddyArrivedParams {
+				buddyBroadcaster.EXPECT().
+					BroadcastBuddyArrived(mock.Anything, mock.MatchedBy(func(s *state.Session) bool {
+						return s.IdentScreenName() == params.screenName
+					})).
 					Return(params.err)
 			}
 
-			newUUID := func() uuid.UUID { return tc.UUID }
-			postUserHandler(responseRecorder, request, userManager, newUUID, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
-			}
+			putUserAwayHandler(responseRecorder, request, sessionRetriever, buddyBroadcaster, slog.Default())
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			if tc.sess != nil {
+				assert.Equal(t, tc.wantAwayMessage, tc.sess.AwayMessage())
 			}
 		})
 	}
 }
 
-func TestUserHandler_DELETE(t *testing.T) {
+func TestUserAwayTemplateHandler_POST(t *testing.T) {
+	newSess := func() *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName("userA"))
+		return sess
+	}
+
 	tt := []struct {
-		name       string
-		body       string
-		want       string
-		statusCode int
-		mockParams mockParams
+		name              string
+		requestScreenName state.IdentScreenName
+		sess              *state.Session
+		body              string
+		want              string
+		wantAwayMessage   string
+		statusCode        int
+		mockParams        mockParams
 	}{
 		{
-			name:       "with valid user",
-			body:       `{"screen_name":"userA"}`,
-			want:       `User account successfully deleted.`,
-			statusCode: http.StatusNoContent,
+			name:              "apply template to online user and notify buddies",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			sess:              newSess(),
+			body:              `{"name":"lunch"}`,
+			wantAwayMessage:   "Out to lunch",
+			statusCode:        http.StatusNoContent,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					deleteUserParams: deleteUserParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					awayTemplateParams: awayTemplateParams{
+						{
+							name:   "lunch",
+							result: state.AwayTemplate{Name: "lunch", Message: "Out to lunch"},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
 						},
@@ -844,266 +866,6486 @@ func TestUserHandler_DELETE(t *testing.T) {
 			},
 		},
 		{
-			name:       "with non-existent user",
-			body:       `{"screen_name":"userA"}`,
-			want:       `user does not exist`,
-			statusCode: http.StatusNotFound,
+			name:              "template not found",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			sess:              newSess(),
+			body:              `{"name":"lunch"}`,
+			want:              `{"error":"away template not found","code":"away_template_not_found"}`,
+			statusCode:        http.StatusNotFound,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					deleteUserParams: deleteUserParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					awayTemplateParams: awayTemplateParams{
 						{
-							screenName: state.NewIdentScreenName("userA"),
-							err:        state.ErrNoUser,
+							name: "lunch",
+							err:  state.ErrAwayTemplateNotFound,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "with malformed body",
-			body:       `{"screen_name":"userA"`, // missing closing }
-			want:       `malformed input`,
-			statusCode: http.StatusBadRequest,
-		},
-		{
-			name:       "user handler error",
-			body:       `{"screen_name":"userA"}`,
-			want:       `internal server error`,
-			statusCode: http.StatusInternalServerError,
+			name:              "user is offline",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"name":"lunch"}`,
+			want:              `{"error":"session not found","code":"session_not_found"}`,
+			statusCode:        http.StatusNotFound,
 			mockParams: mockParams{
-				userManagerParams: userManagerParams{
-					deleteUserParams: deleteUserParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					awayTemplateParams: awayTemplateParams{
+						{
+							name:   "lunch",
+							result: state.AwayTemplate{Name: "lunch", Message: "Out to lunch"},
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
 							screenName: state.NewIdentScreenName("userA"),
-							err:        io.EOF,
 						},
 					},
 				},
 			},
 		},
+		{
+			name:              "malformed body",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{`,
+			want:              `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:        http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodDelete, "/user", strings.NewReader(tc.body))
+			request := httptest.NewRequest(http.MethodPost, "/user/"+tc.requestScreenName.String()+"/away-template", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.requestScreenName.String())
 			responseRecorder := httptest.NewRecorder()
 
-			userManager := newMockUserManager(t)
-			for _, params := range tc.mockParams.userManagerParams.deleteUserParams {
-				userManager.EXPECT().
-					DeleteUser(params.screenName).
+			awayTemplateManager := newMockAwayTemplateManager(t)
+			for _, params := range tc.mockParams.awayTemplateParams {
+				awayTemplateManager.EXPECT().
+					AwayTemplate(params.name).
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(tc.sess)
+			}
+			buddyBroadcaster := newMockBuddyBroadcaster(t)
+			for _, params := range tc.mockParams.buddyBroadcasterParams.broadcastBuddyArrivedParams {
+				buddyBroadcaster.EXPECT().
+					BroadcastBuddyArrived(mock.Anything, mock.MatchedBy(func(s *state.Session) bool {
+						return s.IdentScreenName() == params.screenName
+					})).
 					Return(params.err)
 			}
 
-			deleteUserHandler(responseRecorder, request, userManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
-			}
+			postUserAwayTemplateHandler(responseRecorder, request, sessionRetriever, awayTemplateManager, buddyBroadcaster, slog.Default())
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			if tc.sess != nil {
+				assert.Equal(t, tc.wantAwayMessage, tc.sess.AwayMessage())
 			}
 		})
 	}
 }
 
-func TestUserPasswordHandler_PUT(t *testing.T) {
+func TestUserICQAlertHandler_POST(t *testing.T) {
+	icqUser := &state.User{
+		DisplayScreenName: "100003",
+		IdentScreenName:   state.NewIdentScreenName("100003"),
+		IsICQ:             true,
+	}
+	aimUser := &state.User{
+		DisplayScreenName: "userA",
+		IdentScreenName:   state.NewIdentScreenName("userA"),
+	}
+	alertTLVs := func() wire.TLVRestBlock {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, wire.MarshalLE(wire.ICBMCh4Message{
+			MessageType: wire.ICBMMsgTypeServer,
+			Message:     "happy birthday!",
+		}, buf))
+		return wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, buf.Bytes()),
+			},
+		}
+	}
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	quietHours, err := state.NewQuietHours("01:00", "05:00")
+	assert.NoError(t, err)
+
 	tt := []struct {
-		name       string
-		body       string
-		want       string
-		statusCode int
-		mockParams mockParams
+		name              string
+		requestScreenName state.IdentScreenName
+		body              string
+		quietHours        *state.QuietHours
+		want              string
+		statusCode        int
+		wantDelay         time.Duration
+		mockParams        mockParams
 	}{
 		{
-			name:       "user with valid password",
-			body:       `{"screen_name":"userA", "password":"thenewpassword"}`,
-			want:       `Password successfully reset.`,
-			statusCode: http.StatusNoContent,
+			name:              "deliver alert to online ICQ user",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{"message":"happy birthday!"}`,
+			statusCode:        http.StatusNoContent,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
-					setUserPasswordParams: setUserPasswordParams{
+					getUserParams: getUserParams{
 						{
-							screenName:  state.NewIdentScreenName("userA"),
-							newPassword: "thenewpassword",
+							screenName: state.NewIdentScreenName("100003"),
+							result:     icqUser,
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     state.NewSession(),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									ChannelID: wire.ICBMChannelICQ,
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "0",
+									},
+									TLVRestBlock: alertTLVs(),
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "user with invalid password",
-			body:       `{"screen_name":"userA", "password":"a"}`,
-			want:       `invalid password length`,
-			statusCode: http.StatusBadRequest,
+			name:              "hold alert during quiet hours and deliver it after",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{"message":"happy birthday!"}`,
+			quietHours:        quietHours,
+			statusCode:        http.StatusAccepted,
+			wantDelay:         time.Hour + 55*time.Minute + 55*time.Second,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
-					setUserPasswordParams: setUserPasswordParams{
+					getUserParams: getUserParams{
 						{
-							screenName:  state.NewIdentScreenName("userA"),
-							newPassword: "a",
-							err:         state.ErrPasswordInvalid,
+							screenName: state.NewIdentScreenName("100003"),
+							result:     icqUser,
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     state.NewSession(),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									ChannelID: wire.ICBMChannelICQ,
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "0",
+									},
+									TLVRestBlock: alertTLVs(),
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "with malformed body",
-			body:       `{"screen_name":"userA", "password":"thepassword"`, // missing closing }
-			want:       `malformed input`,
-			statusCode: http.StatusBadRequest,
+			name:              "deliver critical alert during quiet hours",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{"message":"happy birthday!","critical":true}`,
+			quietHours:        quietHours,
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     icqUser,
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							result:     state.NewSession(),
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+									ChannelID: wire.ICBMChannelICQ,
+									TLVUserInfo: wire.TLVUserInfo{
+										ScreenName: "0",
+									},
+									TLVRestBlock: alertTLVs(),
+								},
+							},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:       "password updater returns runtime error",
-			body:       `{"screen_name":"userA", "password":"thepassword"}`,
-			want:       `internal server error`,
-			statusCode: http.StatusInternalServerError,
+			name:              "store alert for offline ICQ user",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{"message":"happy birthday!"}`,
+			statusCode:        http.StatusAccepted,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
-					setUserPasswordParams: setUserPasswordParams{
+					getUserParams: getUserParams{
 						{
-							screenName:  state.NewIdentScreenName("userA"),
-							newPassword: "thepassword",
-							err:         io.EOF,
+							screenName: state.NewIdentScreenName("100003"),
+							result:     icqUser,
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessage: state.OfflineMessage{
+								Sender:    state.NewIdentScreenName("0"),
+								Recipient: state.NewIdentScreenName("100003"),
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:    wire.ICBMChannelICQ,
+									ScreenName:   "100003",
+									TLVRestBlock: alertTLVs(),
+								},
+								Sent: sent,
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "user doesn't exist",
-			body:       `{"screen_name":"userA", "password":"thepassword"}`,
-			want:       `user does not exist`,
-			statusCode: http.StatusNotFound,
+			name:              "offline ICQ user's mailbox is full",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{"message":"happy birthday!"}`,
+			want:              `{"error":"user can't receive any more offline messages","code":"mailbox_full"}`,
+			statusCode:        http.StatusConflict,
 			mockParams: mockParams{
 				userManagerParams: userManagerParams{
-					setUserPasswordParams: setUserPasswordParams{
+					getUserParams: getUserParams{
 						{
-							screenName:  state.NewIdentScreenName("userA"),
-							newPassword: "thepassword",
-							err:         state.ErrNoUser,
+							screenName: state.NewIdentScreenName("100003"),
+							result:     icqUser,
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessage: state.OfflineMessage{
+								Sender:    state.NewIdentScreenName("0"),
+								Recipient: state.NewIdentScreenName("100003"),
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:    wire.ICBMChannelICQ,
+									ScreenName:   "100003",
+									TLVRestBlock: alertTLVs(),
+								},
+								Sent: sent,
+							},
+							err: state.ErrOfflineMessageLimit,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "user is not an ICQ account",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"message":"happy birthday!"}`,
+			want:              `{"error":"user is not an ICQ account","code":"not_icq_account"}`,
+			statusCode:        http.StatusBadRequest,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     aimUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "user not found",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{"message":"happy birthday!"}`,
+			want:              `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
 						},
 					},
 				},
 			},
 		},
+		{
+			name:              "missing message",
+			requestScreenName: state.NewIdentScreenName("100003"),
+			body:              `{}`,
+			want:              `{"error":"message is required","code":"invalid_input"}`,
+			statusCode:        http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodPut, "/user", strings.NewReader(tc.body))
+			request := httptest.NewRequest(http.MethodPost, "/user/"+tc.requestScreenName.String()+"/icq-alert", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.requestScreenName.String())
 			responseRecorder := httptest.NewRecorder()
 
 			userManager := newMockUserManager(t)
-			for _, params := range tc.mockParams.userManagerParams.setUserPasswordParams {
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
 				userManager.EXPECT().
-					SetUserPassword(params.screenName, params.newPassword).
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tc.mockParams.offlineMessageManagerParams.saveMessageParams {
+				offlineMessageManager.EXPECT().
+					SaveMessage(params.offlineMessage).
 					Return(params.err)
 			}
+			timeNow := func() time.Time {
+				return sent
+			}
 
-			putUserPasswordHandler(responseRecorder, request, userManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			var delay time.Duration
+			var deferred []func()
+			afterFunc := func(d time.Duration, f func()) {
+				delay = d
+				deferred = append(deferred, f)
 			}
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			postUserICQAlertHandler(responseRecorder, request, userManager, sessionRetriever, messageRelayer, offlineMessageManager, tc.quietHours, timeNow, afterFunc, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			assert.Equal(t, tc.wantDelay, delay)
+
+			if len(deferred) > 0 {
+				// the alert must not be delivered until quiet hours end
+				messageRelayer.AssertNotCalled(t, "RelayToScreenName", mock.Anything, mock.Anything, mock.Anything)
+				for _, f := range deferred {
+					f()
+				}
 			}
 		})
 	}
 }
 
-func TestPublicChatHandler_GET(t *testing.T) {
-	fnNewSess := func(screenName string) *state.Session {
+func TestICQBroadcastHandler_POST(t *testing.T) {
+	fnNewSess := func(screenName string, uin uint32) *state.Session {
 		sess := state.NewSession()
 		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
 		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		sess.SetUIN(uin)
 		return sess
 	}
-
-	chatRoom1 := state.NewChatRoom("chat-room-1-name", state.NewIdentScreenName("chat-room-1-creator"), state.PublicExchange)
-	chatRoom2 := state.NewChatRoom("chat-room-2-name", state.NewIdentScreenName("chat-room-1-creator"), state.PublicExchange)
+	alertTLVs := func() wire.TLVRestBlock {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, wire.MarshalLE(wire.ICBMCh4Message{
+			MessageType: wire.ICBMMsgTypeServer,
+			Message:     "server maintenance tonight",
+		}, buf))
+		return wire.TLVRestBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.ICBMTLVData, buf.Bytes()),
+			},
+		}
+	}
+	alertMsg := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: wire.ICBMChannelICQ,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: "0",
+			},
+			TLVRestBlock: alertTLVs(),
+		},
+	}
+	onlineSessions := []*state.Session{
+		fnNewSess("100003", 100003),
+		fnNewSess("userA", 0),
+		fnNewSess("100004", 100004),
+	}
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	quietHours, err := state.NewQuietHours("01:00", "05:00")
+	assert.NoError(t, err)
 
 	tt := []struct {
-		name       string
-		want       string
-		statusCode int
-		mockParams mockParams
+		name         string
+		body         string
+		storeOffline bool
+		quietHours   *state.QuietHours
+		want         string
+		statusCode   int
+		wantDelay    time.Duration
+		mockParams   mockParams
 	}{
 		{
-			name:       "multiple chat rooms with participants",
-			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","url":"aim:gochat?roomname=chat-room-1-name&exchange=5","participants":[{"id":"usera","screen_name":"userA"},{"id":"userb","screen_name":"userB"}]},{"name":"chat-room-2-name","create_time":"0001-01-01T00:00:00Z","url":"aim:gochat?roomname=chat-room-2-name&exchange=5","participants":[{"id":"userc","screen_name":"userC"},{"id":"userd","screen_name":"userD"}]}]`,
+			name:       "broadcast to online ICQ users",
+			body:       `{"message":"server maintenance tonight"}`,
+			want:       `{"sent":2,"stored":0,"skipped":0}`,
 			statusCode: http.StatusOK,
 			mockParams: mockParams{
-				chatRoomRetrieverParams: chatRoomRetrieverParams{
-					allChatRoomsParams: allChatRoomsParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
 						{
-							exchange: state.PublicExchange,
-							result: []state.ChatRoom{
-								chatRoom1,
-								chatRoom2,
-							},
+							result: onlineSessions,
 						},
 					},
 				},
-				chatSessionRetrieverParams: chatSessionRetrieverParams{
-					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
 						{
-							cookie: chatRoom1.Cookie(),
-							result: []*state.Session{
-								fnNewSess("userA"),
-								fnNewSess("userB"),
-							},
+							screenName: state.NewIdentScreenName("100003"),
+							message:    alertMsg,
 						},
 						{
-							cookie: chatRoom2.Cookie(),
-							result: []*state.Session{
-								fnNewSess("userC"),
-								fnNewSess("userD"),
-							},
+							screenName: state.NewIdentScreenName("100004"),
+							message:    alertMsg,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "chat room without participants",
-			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","url":"aim:gochat?roomname=chat-room-1-name&exchange=5","participants":[]}]`,
-			statusCode: http.StatusOK,
+			name:         "broadcast to online ICQ users and store for offline ICQ users",
+			body:         `{"message":"server maintenance tonight"}`,
+			storeOffline: true,
+			want:         `{"sent":1,"stored":1,"skipped":0}`,
+			statusCode:   http.StatusOK,
 			mockParams: mockParams{
-				chatRoomRetrieverParams: chatRoomRetrieverParams{
-					allChatRoomsParams: allChatRoomsParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
 						{
-							exchange: state.PublicExchange,
-							result: []state.ChatRoom{
-								chatRoom1,
-							},
+							result: []*state.Session{fnNewSess("100003", 100003)},
 						},
 					},
-				},
-				chatSessionRetrieverParams: chatSessionRetrieverParams{
-					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
 						{
-							cookie: chatRoom1.Cookie(),
-							result: []*state.Session{},
+							screenName: state.NewIdentScreenName("100003"),
+							result:     fnNewSess("100003", 100003),
+						},
+						{
+							screenName: state.NewIdentScreenName("100004"),
 						},
 					},
 				},
-			},
-		},
-		{
-			name:       "no chat rooms",
-			want:       `[]`,
+				userManagerParams: userManagerParams{
+					allUsersParams: allUsersParams{
+						{
+							result: []state.User{
+								{IdentScreenName: state.NewIdentScreenName("100003"), IsICQ: true},
+								{IdentScreenName: state.NewIdentScreenName("userA")},
+								{IdentScreenName: state.NewIdentScreenName("100004"), IsICQ: true},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    alertMsg,
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessage: state.OfflineMessage{
+								Sender:    state.NewIdentScreenName("0"),
+								Recipient: state.NewIdentScreenName("100004"),
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:    wire.ICBMChannelICQ,
+									ScreenName:   "100004",
+									TLVRestBlock: alertTLVs(),
+								},
+								Sent: sent,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:         "skip offline ICQ users whose mailbox is full",
+			body:         `{"message":"server maintenance tonight"}`,
+			storeOffline: true,
+			want:         `{"sent":0,"stored":1,"skipped":1}`,
+			statusCode:   http.StatusOK,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{},
+					},
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+						},
+						{
+							screenName: state.NewIdentScreenName("100004"),
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					allUsersParams: allUsersParams{
+						{
+							result: []state.User{
+								{IdentScreenName: state.NewIdentScreenName("100003"), IsICQ: true},
+								{IdentScreenName: state.NewIdentScreenName("100004"), IsICQ: true},
+							},
+						},
+					},
+				},
+				offlineMessageManagerParams: offlineMessageManagerParams{
+					saveMessageParams: saveMessageParams{
+						{
+							offlineMessage: state.OfflineMessage{
+								Sender:    state.NewIdentScreenName("0"),
+								Recipient: state.NewIdentScreenName("100003"),
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:    wire.ICBMChannelICQ,
+									ScreenName:   "100003",
+									TLVRestBlock: alertTLVs(),
+								},
+								Sent: sent,
+							},
+							err: state.ErrOfflineMessageLimit,
+						},
+						{
+							offlineMessage: state.OfflineMessage{
+								Sender:    state.NewIdentScreenName("0"),
+								Recipient: state.NewIdentScreenName("100004"),
+								Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+									ChannelID:    wire.ICBMChannelICQ,
+									ScreenName:   "100004",
+									TLVRestBlock: alertTLVs(),
+								},
+								Sent: sent,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "hold broadcast during quiet hours and send it after",
+			body:       `{"message":"server maintenance tonight"}`,
+			quietHours: quietHours,
+			statusCode: http.StatusAccepted,
+			wantDelay:  time.Hour + 55*time.Minute + 55*time.Second,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: onlineSessions,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    alertMsg,
+						},
+						{
+							screenName: state.NewIdentScreenName("100004"),
+							message:    alertMsg,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "send critical broadcast during quiet hours",
+			body:       `{"message":"server maintenance tonight","critical":true}`,
+			quietHours: quietHours,
+			want:       `{"sent":2,"stored":0,"skipped":0}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: onlineSessions,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    alertMsg,
+						},
+						{
+							screenName: state.NewIdentScreenName("100004"),
+							message:    alertMsg,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:         "error retrieving users",
+			body:         `{"message":"server maintenance tonight"}`,
+			storeOffline: true,
+			want:         `{"error":"internal server error","code":"internal_error"}`,
+			statusCode:   http.StatusInternalServerError,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{},
+					},
+				},
+				userManagerParams: userManagerParams{
+					allUsersParams: allUsersParams{
+						{
+							err: io.EOF,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "missing message",
+			body:       `{}`,
+			want:       `{"error":"message is required","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed input",
+			body:       `{`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/icq-broadcast", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.allUsersParams {
+				userManager.EXPECT().
+					AllUsers().
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.sessionRetrieverAllSessionsParams {
+				sessionRetriever.EXPECT().
+					AllSessions().
+					Return(params.result)
+			}
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+			offlineMessageManager := newMockOfflineMessageManager(t)
+			for _, params := range tc.mockParams.offlineMessageManagerParams.saveMessageParams {
+				offlineMessageManager.EXPECT().
+					SaveMessage(params.offlineMessage).
+					Return(params.err)
+			}
+			timeNow := func() time.Time {
+				return sent
+			}
+
+			var delay time.Duration
+			var deferred []func()
+			afterFunc := func(d time.Duration, f func()) {
+				delay = d
+				deferred = append(deferred, f)
+			}
+
+			postICQBroadcastHandler(responseRecorder, request, userManager, sessionRetriever, messageRelayer, offlineMessageManager, tc.storeOffline, tc.quietHours, timeNow, afterFunc, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			assert.Equal(t, tc.wantDelay, delay)
+
+			if len(deferred) > 0 {
+				// the broadcast must not be sent until quiet hours end
+				messageRelayer.AssertNotCalled(t, "RelayToScreenName", mock.Anything, mock.Anything, mock.Anything)
+				for _, f := range deferred {
+					f()
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcastHandler_POST(t *testing.T) {
+	fnNewSess := func(screenName string) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		return sess
+	}
+	onlineSessions := []*state.Session{
+		fnNewSess("userA"),
+		fnNewSess("100003"),
+	}
+	motdMsg := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.OService,
+			SubGroup:  wire.OServiceMotd,
+		},
+		Body: wire.SNAC_0x01_0x13_OServiceMOTD{
+			MessageType: wire.OServiceMOTDTypeNormal,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.OServiceMOTDTLVMessage, "server maintenance tonight"),
+				},
+			},
+		},
+	}
+	frags, err := wire.ICBMFragmentList("server maintenance tonight")
+	assert.NoError(t, err)
+	imMsg := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.ICBM,
+			SubGroup:  wire.ICBMChannelMsgToClient,
+		},
+		Body: wire.SNAC_0x04_0x07_ICBMChannelMsgToClient{
+			ChannelID: wire.ICBMChannelIM,
+			TLVUserInfo: wire.TLVUserInfo{
+				ScreenName: "System",
+			},
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, frags),
+				},
+			},
+		},
+	}
+
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	quietHours, err := state.NewQuietHours("01:00", "05:00")
+	assert.NoError(t, err)
+
+	tt := []struct {
+		name           string
+		body           string
+		fromScreenName string
+		quietHours     *state.QuietHours
+		want           string
+		statusCode     int
+		wantDelay      time.Duration
+		mockParams     mockParams
+	}{
+		{
+			name:       "broadcast message of the day to online users",
+			body:       `{"message":"server maintenance tonight"}`,
+			want:       `{"sent":2}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: onlineSessions,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message:    motdMsg,
+						},
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    motdMsg,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "broadcast instant message from system screen name to online users",
+			body:           `{"message":"server maintenance tonight"}`,
+			fromScreenName: "System",
+			want:           `{"sent":2}`,
+			statusCode:     http.StatusOK,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: onlineSessions,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message:    imMsg,
+						},
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    imMsg,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "broadcast with nobody online",
+			body:       `{"message":"server maintenance tonight"}`,
+			want:       `{"sent":0}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: []*state.Session{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "hold broadcast during quiet hours and send it after",
+			body:       `{"message":"server maintenance tonight"}`,
+			quietHours: quietHours,
+			statusCode: http.StatusAccepted,
+			wantDelay:  time.Hour + 55*time.Minute + 55*time.Second,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: onlineSessions,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message:    motdMsg,
+						},
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    motdMsg,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "send critical broadcast during quiet hours",
+			body:       `{"message":"server maintenance tonight","critical":true}`,
+			quietHours: quietHours,
+			want:       `{"sent":2}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
+						{
+							result: onlineSessions,
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message:    motdMsg,
+						},
+						{
+							screenName: state.NewIdentScreenName("100003"),
+							message:    motdMsg,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "message too long",
+			body:       `{"message":"` + strings.Repeat("a", 513) + `"}`,
+			want:       `{"error":"message must be no longer than 512 characters","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "missing message",
+			body:       `{"message":""}`,
+			want:       `{"error":"message is required","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed input",
+			body:       `{"message":`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/broadcast", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.sessionRetrieverAllSessionsParams {
+				sessionRetriever.EXPECT().
+					AllSessions().
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+
+			timeNow := func() time.Time {
+				return sent
+			}
+
+			var delay time.Duration
+			var deferred []func()
+			afterFunc := func(d time.Duration, f func()) {
+				delay = d
+				deferred = append(deferred, f)
+			}
+
+			postBroadcastHandler(responseRecorder, request, sessionRetriever, messageRelayer, tc.fromScreenName, tc.quietHours, timeNow, afterFunc, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			assert.Equal(t, tc.wantDelay, delay)
+
+			if len(deferred) > 0 {
+				// the broadcast must not be sent until quiet hours end
+				messageRelayer.AssertNotCalled(t, "RelayToScreenName", mock.Anything, mock.Anything, mock.Anything)
+				for _, f := range deferred {
+					f()
+				}
+			}
+		})
+	}
+}
+
+func TestUserBuddyHandler_PUT(t *testing.T) {
+	userA := &state.User{
+		DisplayScreenName: "userA",
+		IdentScreenName:   state.NewIdentScreenName("userA"),
+	}
+	root := wire.FeedbagItem{
+		ClassID: wire.FeedbagClassIdGroup,
+		TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}),
+			},
+		},
+	}
+	friends := wire.FeedbagItem{
+		Name:    "Friends",
+		GroupID: 1,
+		ClassID: wire.FeedbagClassIdGroup,
+		TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}),
+			},
+		},
+	}
+	buddyB := wire.FeedbagItem{
+		Name:    "userB",
+		GroupID: 1,
+		ItemID:  1,
+		ClassID: wire.FeedbagClassIdBuddy,
+	}
+
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		requestBuddy      string
+		body              string
+		defaultGroup      string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "add buddy to existing group and sync client",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userC",
+			body:              `{"group":"Friends"}`,
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results:    []wire.FeedbagItem{root, friends, buddyB},
+						},
+					},
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							items: []wire.FeedbagItem{
+								{
+									Name:    "userC",
+									GroupID: 1,
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+								{
+									Name:    "Friends",
+									GroupID: 1,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagInsertItem,
+								},
+								Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "userC",
+											GroupID: 1,
+											ItemID:  2,
+											ClassID: wire.FeedbagClassIdBuddy,
+										},
+									},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagUpdateItem,
+								},
+								Body: wire.SNAC_0x13_0x09_FeedbagUpdateItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "Friends",
+											GroupID: 1,
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "add groupless buddy to configured default group",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userC",
+			defaultGroup:      "Friends",
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results:    []wire.FeedbagItem{root, friends, buddyB},
+						},
+					},
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							items: []wire.FeedbagItem{
+								{
+									Name:    "userC",
+									GroupID: 1,
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+								{
+									Name:    "Friends",
+									GroupID: 1,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagInsertItem,
+								},
+								Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "userC",
+											GroupID: 1,
+											ItemID:  2,
+											ClassID: wire.FeedbagClassIdBuddy,
+										},
+									},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagUpdateItem,
+								},
+								Body: wire.SNAC_0x13_0x09_FeedbagUpdateItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "Friends",
+											GroupID: 1,
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "add buddy to new default group and sync client",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userC",
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results:    []wire.FeedbagItem{root, friends, buddyB},
+						},
+					},
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							items: []wire.FeedbagItem{
+								{
+									Name:    "Buddies",
+									GroupID: 2,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+										},
+									},
+								},
+								{
+									Name:    "userC",
+									GroupID: 2,
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+								{
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagInsertItem,
+								},
+								Body: wire.SNAC_0x13_0x08_FeedbagInsertItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "Buddies",
+											GroupID: 2,
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+												},
+											},
+										},
+										{
+											Name:    "userC",
+											GroupID: 2,
+											ItemID:  2,
+											ClassID: wire.FeedbagClassIdBuddy,
+										},
+									},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagUpdateItem,
+								},
+								Body: wire.SNAC_0x13_0x09_FeedbagUpdateItem{
+									Items: []wire.FeedbagItem{
+										{
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "buddy already exists",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "UserB",
+			want:              `{"error":"buddy already exists","code":"buddy_exists"}`,
+			statusCode:        http.StatusConflict,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results:    []wire.FeedbagItem{root, friends, buddyB},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "buddy list is full",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userC",
+			body:              `{"group":"Friends"}`,
+			want:              `{"error":"buddy list is full","code":"feedbag_full"}`,
+			statusCode:        http.StatusConflict,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results: []wire.FeedbagItem{root, friends, {
+								Name:    "userD",
+								GroupID: friends.GroupID,
+								ItemID:  math.MaxUint16,
+								ClassID: wire.FeedbagClassIdBuddy,
+							}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "user not found",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userC",
+			want:              `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "malformed body",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userC",
+			body:              `{"group":"Friends"`,
+			want:              `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:        http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.requestScreenName.String()+"/buddy/"+tc.requestBuddy, strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			request.SetPathValue("buddy", tc.requestBuddy)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagParams {
+				feedbagManager.EXPECT().
+					Feedbag(params.screenName).
+					Return(params.results, params.err)
+			}
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagUpsertParams {
+				feedbagManager.EXPECT().
+					FeedbagUpsert(params.screenName, params.items).
+					Return(params.err)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+
+			putUserBuddyHandler(responseRecorder, request, userManager, feedbagManager, messageRelayer, tc.defaultGroup, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserBuddyHandler_DELETE(t *testing.T) {
+	userA := &state.User{
+		DisplayScreenName: "userA",
+		IdentScreenName:   state.NewIdentScreenName("userA"),
+	}
+	friends := wire.FeedbagItem{
+		Name:    "Friends",
+		GroupID: 1,
+		ClassID: wire.FeedbagClassIdGroup,
+		TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{
+				wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}),
+			},
+		},
+	}
+	buddyB := wire.FeedbagItem{
+		Name:    "userB",
+		GroupID: 1,
+		ItemID:  1,
+		ClassID: wire.FeedbagClassIdBuddy,
+	}
+	buddyC := wire.FeedbagItem{
+		Name:    "userC",
+		GroupID: 1,
+		ItemID:  2,
+		ClassID: wire.FeedbagClassIdBuddy,
+	}
+
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		requestBuddy      string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "remove buddy and sync client",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userB",
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results:    []wire.FeedbagItem{friends, buddyB, buddyC},
+						},
+					},
+					feedbagDeleteParams: feedbagDeleteParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							items:      []wire.FeedbagItem{buddyB},
+						},
+					},
+					feedbagUpsertParams: feedbagUpsertParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							items: []wire.FeedbagItem{
+								{
+									Name:    "Friends",
+									GroupID: 1,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagDeleteItem,
+								},
+								Body: wire.SNAC_0x13_0x0A_FeedbagDeleteItem{
+									Items: []wire.FeedbagItem{buddyB},
+								},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.Feedbag,
+									SubGroup:  wire.FeedbagUpdateItem,
+								},
+								Body: wire.SNAC_0x13_0x09_FeedbagUpdateItem{
+									Items: []wire.FeedbagItem{
+										{
+											Name:    "Friends",
+											GroupID: 1,
+											ClassID: wire.FeedbagClassIdGroup,
+											TLVLBlock: wire.TLVLBlock{
+												TLVList: wire.TLVList{
+													wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "buddy not found",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userD",
+			want:              `{"error":"buddy not found","code":"buddy_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results:    []wire.FeedbagItem{friends, buddyB, buddyC},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "user not found",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			requestBuddy:      "userB",
+			want:              `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/user/"+tc.requestScreenName.String()+"/buddy/"+tc.requestBuddy, nil)
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			request.SetPathValue("buddy", tc.requestBuddy)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagParams {
+				feedbagManager.EXPECT().
+					Feedbag(params.screenName).
+					Return(params.results, params.err)
+			}
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagDeleteParams {
+				feedbagManager.EXPECT().
+					FeedbagDelete(params.screenName, params.items).
+					Return(params.err)
+			}
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagUpsertParams {
+				feedbagManager.EXPECT().
+					FeedbagUpsert(params.screenName, params.items).
+					Return(params.err)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.messageRelayerParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+
+			deleteUserBuddyHandler(responseRecorder, request, userManager, feedbagManager, messageRelayer, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserFeedbagHandler_GET(t *testing.T) {
+	userA := &state.User{
+		DisplayScreenName: "userA",
+		IdentScreenName:   state.NewIdentScreenName("userA"),
+	}
+
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "export feedbag",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want: `{"items":[{"group_id":1,"item_id":0,"class_id":1,"name":"Friends","attributes":[{"tag":200,"value":"AAE="}]},` +
+				`{"group_id":1,"item_id":1,"class_id":0,"name":"userb","attributes":[]}]}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagParams: feedbagParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							results: []wire.FeedbagItem{
+								{
+									Name:    "Friends",
+									GroupID: 1,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}),
+										},
+									},
+								},
+								{
+									Name:    "userb",
+									GroupID: 1,
+									ItemID:  1,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "user not found",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.requestScreenName.String()+"/feedbag", nil)
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagParams {
+				feedbagManager.EXPECT().
+					Feedbag(params.screenName).
+					Return(params.results, params.err)
+			}
+
+			getUserFeedbagHandler(responseRecorder, request, userManager, feedbagManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserFeedbagHandler_PUT(t *testing.T) {
+	userA := &state.User{
+		DisplayScreenName: "userA",
+		IdentScreenName:   state.NewIdentScreenName("userA"),
+	}
+
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		body              string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "import feedbag",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body: `{"items":[{"group_id":0,"item_id":0,"class_id":1,"name":""},` +
+				`{"group_id":1,"item_id":0,"class_id":1,"name":"Friends","attributes":[{"tag":200,"value":"AAE="}]},` +
+				`{"group_id":1,"item_id":1,"class_id":0,"name":"userB"},` +
+				`{"group_id":0,"item_id":2,"class_id":3,"name":"spammer"}]}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     userA,
+						},
+					},
+				},
+				feedbagManagerParams: feedbagManagerParams{
+					feedbagReplaceParams: feedbagReplaceParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							items: []wire.FeedbagItem{
+								{
+									ClassID: wire.FeedbagClassIdGroup,
+								},
+								{
+									Name:    "Friends",
+									GroupID: 1,
+									ClassID: wire.FeedbagClassIdGroup,
+									TLVLBlock: wire.TLVLBlock{
+										TLVList: wire.TLVList{
+											wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}),
+										},
+									},
+								},
+								{
+									Name:    "userB",
+									GroupID: 1,
+									ItemID:  1,
+									ClassID: wire.FeedbagClassIdBuddy,
+								},
+								{
+									Name:    "spammer",
+									ItemID:  2,
+									ClassID: wire.FeedbagClassIDDeny,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "duplicate item ID",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body: `{"items":[{"group_id":1,"item_id":0,"class_id":1,"name":"Friends"},` +
+				`{"group_id":1,"item_id":1,"class_id":0,"name":"userB"},` +
+				`{"group_id":1,"item_id":1,"class_id":0,"name":"userC"}]}`,
+			want:       `{"error":"invalid feedbag","code":"invalid_input","details":"duplicate item ID 1 in group ID 1"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:              "malformed input",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"items":`,
+			want:              `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:        http.StatusBadRequest,
+		},
+		{
+			name:              "user not found",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			body:              `{"items":[]}`,
+			want:              `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.requestScreenName.String()+"/feedbag", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			feedbagManager := newMockFeedbagManager(t)
+			for _, params := range tc.mockParams.feedbagManagerParams.feedbagReplaceParams {
+				feedbagManager.EXPECT().
+					FeedbagReplace(params.screenName, params.items).
+					Return(params.err)
+			}
+
+			putUserFeedbagHandler(responseRecorder, request, userManager, feedbagManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserBuddyIconHandler_GET(t *testing.T) {
+	sampleGIF := []byte{
+		0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x32, 0x00, 0x32, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+		0x32, 0x00, 0x32, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b}
+
+	sampleJPG := []byte{0xFF, 0xD8, 0xFF, 0x43, 0x13, 0x37}
+	tt := []struct {
+		name              string
+		requestScreenName state.IdentScreenName
+		want              string
+		statusCode        int
+		contentType       string
+		mockParams        mockParams
+	}{
+		{
+			name:              "invalid account",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			contentType:       "application/json",
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "account with gif buddy icon",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              string(sampleGIF),
+			statusCode:        http.StatusOK,
+			contentType:       "image/gif",
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &state.User{
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+						},
+					},
+				},
+				feedBagRetrieverParams: feedBagRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &wire.BARTID{
+								Type: wire.BARTTypesBuddyIcon,
+								BARTInfo: wire.BARTInfo{
+									Flags: 0x00,
+									Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+								},
+							},
+						},
+					},
+				},
+				bartRetrieverParams: bartRetrieverParams{
+					bartRetrieveParams: bartRetrieveParams{
+						{
+							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+							result:   sampleGIF,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "account with jpg buddy icon",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              string(sampleJPG),
+			statusCode:        http.StatusOK,
+			contentType:       "image/jpeg",
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &state.User{
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+						},
+					},
+				},
+				feedBagRetrieverParams: feedBagRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &wire.BARTID{
+								Type: wire.BARTTypesBuddyIcon,
+								BARTInfo: wire.BARTInfo{
+									Flags: 0x00,
+									Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+								},
+							},
+						},
+					},
+				},
+				bartRetrieverParams: bartRetrieverParams{
+					bartRetrieveParams: bartRetrieveParams{
+						{
+							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+							result:   sampleJPG,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "account with unknown format buddy icon",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              string([]byte{0x13, 0x37, 0x13, 0x37, 0x13, 0x37}),
+			statusCode:        http.StatusOK,
+			contentType:       "application/octet-stream",
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &state.User{
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+						},
+					},
+				},
+				feedBagRetrieverParams: feedBagRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &wire.BARTID{
+								Type: wire.BARTTypesBuddyIcon,
+								BARTInfo: wire.BARTInfo{
+									Flags: 0x00,
+									Hash:  []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+								},
+							},
+						},
+					},
+				},
+				bartRetrieverParams: bartRetrieverParams{
+					bartRetrieveParams: bartRetrieveParams{
+						{
+							itemHash: []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'},
+							result:   []byte{0x13, 0x37, 0x13, 0x37, 0x13, 0x37},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "account with cleared buddy icon",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"error":"icon not found","code":"icon_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			contentType:       "application/json",
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &state.User{
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+						},
+					},
+				},
+				feedBagRetrieverParams: feedBagRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &wire.BARTID{
+								Type: wire.BARTTypesBuddyIcon,
+								BARTInfo: wire.BARTInfo{
+									Flags: 0x00,
+									Hash:  wire.GetClearIconHash(),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "account with no buddy icon",
+			requestScreenName: state.NewIdentScreenName("userA"),
+			want:              `{"error":"icon not found","code":"icon_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			contentType:       "application/json",
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: &state.User{
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+						},
+					},
+				},
+				feedBagRetrieverParams: feedBagRetrieverParams{
+					buddyIconRefByNameParams: buddyIconRefByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.requestScreenName.String()+"/icon", nil)
+			request.SetPathValue("screenname", tc.requestScreenName.String())
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+
+			feedbagRetriever := newMockFeedBagRetriever(t)
+			for _, params := range tc.mockParams.feedBagRetrieverParams.buddyIconRefByNameParams {
+				feedbagRetriever.EXPECT().
+					BuddyIconRefByName(params.screenName).
+					Return(params.result, params.err)
+			}
+
+			bartRetriever := newMockBARTRetriever(t)
+			for _, params := range tc.mockParams.bartRetrieverParams.bartRetrieveParams {
+				bartRetriever.EXPECT().
+					BARTRetrieve(params.itemHash).
+					Return(params.result, params.err)
+			}
+
+			getUserBuddyIconHandler(responseRecorder, request, userManager, feedbagRetriever, bartRetriever, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			contentType := responseRecorder.Header().Get("Content-Type")
+			if contentType != tc.contentType {
+				t.Errorf("Want content type '%s', got '%s'", tc.contentType, contentType)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "empty user store",
+			want:       `[]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					allUsersParams: allUsersParams{
+						{
+							result: []state.User{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user store containing 3 users",
+			want:       `[{"id":"usera","screen_name":"userA","is_icq":false},{"id":"userb","screen_name":"userB","is_icq":false},{"id":"100003","screen_name":"100003","is_icq":true}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					allUsersParams: allUsersParams{
+						{
+							result: []state.User{
+								{
+									DisplayScreenName: "userA",
+									IdentScreenName:   state.NewIdentScreenName("userA"),
+								},
+								{
+									DisplayScreenName: "userB",
+									IdentScreenName:   state.NewIdentScreenName("userB"),
+								},
+								{
+									DisplayScreenName: "100003",
+									IdentScreenName:   state.NewIdentScreenName("100003"),
+									IsICQ:             true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user handler error",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					allUsersParams: allUsersParams{
+						{
+							result: []state.User{},
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.allUsersParams {
+				userManager.EXPECT().
+					AllUsers().
+					Return(params.result, params.err)
+			}
+
+			getUserHandler(responseRecorder, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserHandler_POST(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		UUID       uuid.UUID
+		want       string
+		password   string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name: "with valid AIM user",
+			body: `{"screen_name":"userA", "password":"thepassword"}`,
+			UUID: uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+
+			want:       `User account created successfully.`,
+			password:   "thepassword",
+			statusCode: http.StatusCreated,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					insertUserParams: insertUserParams{
+						{
+							u: state.User{
+								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+							err: nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with valid ICQ user",
+			body:       `{"screen_name":"100003", "password":"thepass"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `User account created successfully.`,
+			password:   "thepass",
+			statusCode: http.StatusCreated,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					insertUserParams: insertUserParams{
+						{
+							u: state.User{
+								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
+								DisplayScreenName: "100003",
+								IdentScreenName:   state.NewIdentScreenName("100003"),
+								IsICQ:             true,
+								ICQPermissions:    state.ICQPermissions{AuthRequired: true},
+							},
+							err: nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			body:       `{"screen_name":"userA", "password":"thepassword"`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user handler error",
+			body:       `{"screen_name":"userA", "password":"thepassword"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			password:   "thepassword",
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					insertUserParams: insertUserParams{
+						{
+							u: state.User{
+								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "duplicate user",
+			body:       `{"screen_name":"userA", "password":"thepassword"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"user already exists","code":"name_taken"}`,
+			password:   "thepassword",
+			statusCode: http.StatusConflict,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					insertUserParams: insertUserParams{
+						{
+							u: state.User{
+								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
+								DisplayScreenName: "userA",
+								IdentScreenName:   state.NewIdentScreenName("userA"),
+							},
+							err: state.ErrDupUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "invalid AIM screen name",
+			body:       `{"screen_name":"a", "password":"thepassword"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"invalid screen name","code":"invalid_input","details":"invalid screen name length: screen name must contain at least 3 letters and be no longer than 16 characters"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid AIM password",
+			body:       `{"screen_name":"userA", "password":"1"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"invalid password","code":"invalid_input","details":"invalid password length: password length must be between 4-16 characters"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid ICQ UIN",
+			body:       `{"screen_name":"1000", "password":"thepass"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"invalid uin","code":"invalid_input","details":"uin must be a number in the range 10000-2147483646"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid ICQ password",
+			body:       `{"screen_name":"100003", "password":"thelongpassword"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"invalid password","code":"invalid_input","details":"invalid password length: password must be between 6-8 characters"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.insertUserParams {
+				assert.NoError(t, params.u.HashPassword(tc.password))
+				userManager.EXPECT().
+					InsertUser(mock.MatchedBy(func(u state.User) bool {
+						// the argon2id hash is salted, so compare it by
+						// verifying the password
+						want := params.u
+						want.PasswordHash = u.PasswordHash
+						return u.ValidatePassword(tc.password) && assert.ObjectsAreEqual(want, u)
+					})).
+					Return(params.err)
+			}
+
+			newUUID := func() uuid.UUID { return tc.UUID }
+			postUserHandler(responseRecorder, request, userManager, state.ICQPermissions{AuthRequired: true}, state.ScreenNamePolicy{}, newUUID, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestICQUserHandler_POST(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		UUID       uuid.UUID
+		want       string
+		password   string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "with valid password",
+			body:       `{"password":"thepass"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"id":"200000","screen_name":"200000","is_icq":true}`,
+			password:   "thepass",
+			statusCode: http.StatusCreated,
+			mockParams: mockParams{
+				uinAllocatorParams: uinAllocatorParams{
+					nextUINParams: nextUINParams{
+						{
+							first:  200000,
+							last:   299999,
+							result: 200000,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					insertUserParams: insertUserParams{
+						{
+							u: state.User{
+								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
+								DisplayScreenName: "200000",
+								IdentScreenName:   state.NewIdentScreenName("200000"),
+								IsICQ:             true,
+								ICQPermissions:    state.ICQPermissions{AuthRequired: true},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			body:       `{"password":"thepass"`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "with invalid password",
+			body:       `{"password":"thelongpassword"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"invalid password","code":"invalid_input","details":"invalid password length: password must be between 6-8 characters"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "UIN range exhausted",
+			body:       `{"password":"thepass"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"no UINs available","code":"uins_exhausted"}`,
+			statusCode: http.StatusServiceUnavailable,
+			mockParams: mockParams{
+				uinAllocatorParams: uinAllocatorParams{
+					nextUINParams: nextUINParams{
+						{
+							first: 200000,
+							last:  299999,
+							err:   state.ErrUINRangeExhausted,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "UIN allocator error",
+			body:       `{"password":"thepass"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				uinAllocatorParams: uinAllocatorParams{
+					nextUINParams: nextUINParams{
+						{
+							first: 200000,
+							last:  299999,
+							err:   io.EOF,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user insert error",
+			body:       `{"password":"thepass"}`,
+			UUID:       uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b"),
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			password:   "thepass",
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				uinAllocatorParams: uinAllocatorParams{
+					nextUINParams: nextUINParams{
+						{
+							first:  200000,
+							last:   299999,
+							result: 200000,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					insertUserParams: insertUserParams{
+						{
+							u: state.User{
+								AuthKey:           uuid.MustParse("07c70701-ba68-49a9-9f9b-67a53816e37b").String(),
+								DisplayScreenName: "200000",
+								IdentScreenName:   state.NewIdentScreenName("200000"),
+								IsICQ:             true,
+								ICQPermissions:    state.ICQPermissions{AuthRequired: true},
+							},
+							err: io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/user/icq", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			uinAllocator := newMockUINAllocator(t)
+			for _, params := range tc.mockParams.uinAllocatorParams.nextUINParams {
+				uinAllocator.EXPECT().
+					NextUIN(params.first, params.last).
+					Return(params.result, params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.insertUserParams {
+				assert.NoError(t, params.u.HashPassword(tc.password))
+				userManager.EXPECT().
+					InsertUser(mock.MatchedBy(func(u state.User) bool {
+						// the argon2id hash is salted, so compare it by
+						// verifying the password
+						want := params.u
+						want.PasswordHash = u.PasswordHash
+						return u.ValidatePassword(tc.password) && assert.ObjectsAreEqual(want, u)
+					})).
+					Return(params.err)
+			}
+
+			newUUID := func() uuid.UUID { return tc.UUID }
+			postICQUserHandler(responseRecorder, request, userManager, uinAllocator, 200000, 299999, state.ICQPermissions{AuthRequired: true}, newUUID, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserHandler_DELETE(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "with valid user",
+			body:       `{"screen_name":"userA"}`,
+			want:       `User account successfully deleted.`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					deleteUserParams: deleteUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with non-existent user",
+			body:       `{"screen_name":"userA"}`,
+			want:       `{"error":"user does not exist","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					deleteUserParams: deleteUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			body:       `{"screen_name":"userA"`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user handler error",
+			body:       `{"screen_name":"userA"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					deleteUserParams: deleteUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/user", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.deleteUserParams {
+				userManager.EXPECT().
+					DeleteUser(params.screenName).
+					Return(params.err)
+			}
+
+			deleteUserHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserPasswordHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "user with valid password",
+			body:       `{"screen_name":"userA", "password":"thenewpassword"}`,
+			want:       `Password successfully reset.`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setUserPasswordParams: setUserPasswordParams{
+						{
+							screenName:  state.NewIdentScreenName("userA"),
+							newPassword: "thenewpassword",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user with invalid password",
+			body:       `{"screen_name":"userA", "password":"a"}`,
+			want:       `{"error":"invalid password","code":"invalid_input","details":"invalid password length"}`,
+			statusCode: http.StatusBadRequest,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setUserPasswordParams: setUserPasswordParams{
+						{
+							screenName:  state.NewIdentScreenName("userA"),
+							newPassword: "a",
+							err:         state.ErrPasswordInvalid,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			body:       `{"screen_name":"userA", "password":"thepassword"`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "password updater returns runtime error",
+			body:       `{"screen_name":"userA", "password":"thepassword"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setUserPasswordParams: setUserPasswordParams{
+						{
+							screenName:  state.NewIdentScreenName("userA"),
+							newPassword: "thepassword",
+							err:         io.EOF,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user doesn't exist",
+			body:       `{"screen_name":"userA", "password":"thepassword"}`,
+			want:       `{"error":"user does not exist","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setUserPasswordParams: setUserPasswordParams{
+						{
+							screenName:  state.NewIdentScreenName("userA"),
+							newPassword: "thepassword",
+							err:         state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setUserPasswordParams {
+				userManager.EXPECT().
+					SetUserPassword(params.screenName, params.newPassword).
+					Return(params.err)
+			}
+
+			putUserPasswordHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserWatchHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "watch user",
+			screenName: "userA",
+			body:       `{"watched":true}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setWatchedParams: setWatchedParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							watched:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "unwatch user",
+			screenName: "userA",
+			body:       `{"watched":false}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setWatchedParams: setWatchedParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							watched:    false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"watched":true`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"watched":true}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setWatchedParams: setWatchedParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							watched:    true,
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"watched":true}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setWatchedParams: setWatchedParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							watched:    true,
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/watch", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setWatchedParams {
+				userManager.EXPECT().
+					SetWatched(params.screenName, params.watched).
+					Return(params.err)
+			}
+
+			putUserWatchHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserChatRoomCreatorHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "flag user",
+			screenName: "userA",
+			body:       `{"can_create_chat_rooms":true}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setCanCreateChatRoomsParams: setCanCreateChatRoomsParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							allowed:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "unflag user",
+			screenName: "userA",
+			body:       `{"can_create_chat_rooms":false,"official":false}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setCanCreateChatRoomsParams: setCanCreateChatRoomsParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							allowed:    false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"can_create_chat_rooms":true`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"can_create_chat_rooms":true}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setCanCreateChatRoomsParams: setCanCreateChatRoomsParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							allowed:    true,
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"can_create_chat_rooms":true}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setCanCreateChatRoomsParams: setCanCreateChatRoomsParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							allowed:    true,
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/chat-room-creator", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setCanCreateChatRoomsParams {
+				userManager.EXPECT().
+					SetCanCreateChatRooms(params.screenName, params.allowed).
+					Return(params.err)
+			}
+
+			putUserChatRoomCreatorHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserOfficialHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "flag user",
+			screenName: "userA",
+			body:       `{"official":true}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setOfficialParams: setOfficialParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							official:   true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "unflag user",
+			screenName: "userA",
+			body:       `{"official":false}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setOfficialParams: setOfficialParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							official:   false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"official":true`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"official":true}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setOfficialParams: setOfficialParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							official:   true,
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"official":true}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setOfficialParams: setOfficialParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							official:   true,
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/official", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setOfficialParams {
+				userManager.EXPECT().
+					SetOfficial(params.screenName, params.official).
+					Return(params.err)
+			}
+
+			putUserOfficialHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserConcurrentLoginHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		want       string
+		statusCode int
+		cfg        config.Config
+		mockParams mockParams
+	}{
+		{
+			name:       "set policy",
+			screenName: "userA",
+			body:       `{"policy":"reject-new"}`,
+			statusCode: http.StatusNoContent,
+			cfg:        config.Config{ServerKeepaliveSec: 60},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setConcurrentLoginPolicyParams: setConcurrentLoginPolicyParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							policy:     config.ConcurrentLoginRejectNew,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "restore server default",
+			screenName: "userA",
+			body:       `{"policy":""}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setConcurrentLoginPolicyParams: setConcurrentLoginPolicyParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							policy:     "",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "set allow-multiple policy",
+			screenName: "userA",
+			body:       `{"policy":"allow-multiple"}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setConcurrentLoginPolicyParams: setConcurrentLoginPolicyParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							policy:     config.ConcurrentLoginAllowMultiple,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "set reject-new policy without server keepalives",
+			screenName: "userA",
+			body:       `{"policy":"reject-new"}`,
+			want:       `{"error":"policy 'reject-new' requires SERVER_KEEPALIVE_SEC to be set","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "with unknown policy",
+			screenName: "userA",
+			body:       `{"policy":"kick-new"}`,
+			want:       `{"error":"policy must be one of 'kick-old', 'reject-new', 'allow-multiple', or empty","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"policy":"reject-new"`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"policy":"reject-new"}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			cfg:        config.Config{ServerKeepaliveSec: 60},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setConcurrentLoginPolicyParams: setConcurrentLoginPolicyParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							policy:     config.ConcurrentLoginRejectNew,
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"policy":"reject-new"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			cfg:        config.Config{ServerKeepaliveSec: 60},
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setConcurrentLoginPolicyParams: setConcurrentLoginPolicyParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							policy:     config.ConcurrentLoginRejectNew,
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/concurrent-login", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setConcurrentLoginPolicyParams {
+				userManager.EXPECT().
+					SetConcurrentLoginPolicy(params.screenName, params.policy).
+					Return(params.err)
+			}
+
+			putUserConcurrentLoginHandler(responseRecorder, request, tc.cfg, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserBanHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		session    *state.Session
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "ban offline user",
+			screenName: "userA",
+			body:       `{"reason":"spamming"}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					banUserParams: banUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							reason:     "spamming",
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "ban and kick signed-on user",
+			screenName: "userA",
+			body:       `{"reason":"spamming"}`,
+			session:    state.NewSession(),
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					banUserParams: banUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							reason:     "spamming",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"reason":"spamming"`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"reason":"spamming"}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					banUserParams: banUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							reason:     "spamming",
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"reason":"spamming"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					banUserParams: banUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							reason:     "spamming",
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/ban", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.banUserParams {
+				userManager.EXPECT().
+					BanUser(params.screenName, params.reason).
+					Return(params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			if tc.session != nil {
+				sessionRetriever.EXPECT().
+					RetrieveSession(state.NewIdentScreenName(tc.screenName)).
+					Return(tc.session)
+			}
+
+			putUserBanHandler(responseRecorder, request, userManager, sessionRetriever, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+
+			if tc.session != nil {
+				assert.True(t, tc.session.Kicked())
+			}
+		})
+	}
+}
+
+func TestUserBanHandler_DELETE(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "unban user",
+			screenName: "userA",
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					unbanUserParams: unbanUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					unbanUserParams: unbanUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					unbanUserParams: unbanUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/user/"+tc.screenName+"/ban", nil)
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.unbanUserParams {
+				userManager.EXPECT().
+					UnbanUser(params.screenName).
+					Return(params.err)
+			}
+
+			deleteUserBanHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserAllowedNetworksHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "restrict user to networks",
+			screenName: "userA",
+			body:       `{"allowed_networks":["10.0.0.0/8","192.168.1.5"]}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setAllowedNetworksParams: setAllowedNetworksParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							networks: []netip.Prefix{
+								netip.MustParsePrefix("10.0.0.0/8"),
+								netip.MustParsePrefix("192.168.1.5/32"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "lift restriction",
+			screenName: "userA",
+			body:       `{"allowed_networks":[]}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setAllowedNetworksParams: setAllowedNetworksParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							networks:   nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"allowed_networks":[]`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "with invalid network",
+			screenName: "userA",
+			body:       `{"allowed_networks":["10.0.0.0/33"]}`,
+			want:       `{"error":"invalid network \"10.0.0.0/33\": netip.ParsePrefix(\"10.0.0.0/33\"): prefix length out of range","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"allowed_networks":["10.0.0.0/8"]}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setAllowedNetworksParams: setAllowedNetworksParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							networks:   []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"allowed_networks":["10.0.0.0/8"]}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setAllowedNetworksParams: setAllowedNetworksParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							networks:   []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/allowed-networks", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setAllowedNetworksParams {
+				userManager.EXPECT().
+					SetAllowedNetworks(params.screenName, params.networks).
+					Return(params.err)
+			}
+
+			putUserAllowedNetworksHandler(responseRecorder, request, userManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserAliasHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "list aliases",
+			screenName: "userA",
+			want:       `{"aliases":["useraalt","useraother"]}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("userA")},
+						},
+					},
+					screenNameAliasesParams: screenNameAliasesParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: []state.IdentScreenName{
+								state.NewIdentScreenName("userA alt"),
+								state.NewIdentScreenName("userA other"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user has no aliases",
+			screenName: "userA",
+			want:       `{"aliases":[]}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("userA")},
+						},
+					},
+					screenNameAliasesParams: screenNameAliasesParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     &state.User{IdentScreenName: state.NewIdentScreenName("userA")},
+						},
+					},
+					screenNameAliasesParams: screenNameAliasesParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.screenName+"/alias", nil)
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			for _, params := range tc.mockParams.userManagerParams.screenNameAliasesParams {
+				userManager.EXPECT().
+					ScreenNameAliases(params.screenName).
+					Return(params.result, params.err)
+			}
+
+			getUserAliasHandler(responseRecorder, request, userManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserAliasHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		alias      string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "add alias",
+			screenName: "userA",
+			alias:      "userAalt",
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					addScreenNameAliasParams: addScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userAalt"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "alias violates screen name policy",
+			screenName: "userA",
+			alias:      "ab",
+			want:       `{"error":"invalid alias","code":"invalid_input","details":"invalid screen name length: screen name must contain at least 3 letters and be no longer than 16 characters"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "alias is a UIN",
+			screenName: "userA",
+			alias:      "100003",
+			want:       `{"error":"invalid alias","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "alias already taken",
+			screenName: "userA",
+			alias:      "userB",
+			want:       `{"error":"screen name already taken","code":"name_taken"}`,
+			statusCode: http.StatusConflict,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					addScreenNameAliasParams: addScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userB"),
+							err:        state.ErrDupUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			alias:      "userAalt",
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					addScreenNameAliasParams: addScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userAalt"),
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			alias:      "userAalt",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					addScreenNameAliasParams: addScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userAalt"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/alias/"+tc.alias, nil)
+			request.SetPathValue("screenname", tc.screenName)
+			request.SetPathValue("alias", tc.alias)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.addScreenNameAliasParams {
+				userManager.EXPECT().
+					AddScreenNameAlias(params.screenName, params.alias).
+					Return(params.err)
+			}
+
+			putUserAliasHandler(responseRecorder, request, userManager, state.ScreenNamePolicy{}, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserPasswordResetHandler_POST(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		newToken   func() (string, error)
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "generate reset token",
+			screenName: "userA",
+			newToken: func() (string, error) {
+				return "the-token", nil
+			},
+			want:       `{"screen_name":"usera","token":"the-token"}`,
+			statusCode: http.StatusCreated,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setPasswordResetTokenParams: setPasswordResetTokenParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							token:      "the-token",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			newToken: func() (string, error) {
+				return "the-token", nil
+			},
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setPasswordResetTokenParams: setPasswordResetTokenParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							token:      "the-token",
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "token generation fails",
+			screenName: "userA",
+			newToken: func() (string, error) {
+				return "", io.EOF
+			},
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			newToken: func() (string, error) {
+				return "the-token", nil
+			},
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setPasswordResetTokenParams: setPasswordResetTokenParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							token:      "the-token",
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/user/"+tc.screenName+"/password-reset", nil)
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setPasswordResetTokenParams {
+				userManager.EXPECT().
+					SetPasswordResetToken(params.screenName, params.token, mock.Anything).
+					Return(params.err)
+			}
+
+			postUserPasswordResetHandler(responseRecorder, request, userManager, tc.newToken, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserAliasHandler_DELETE(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		alias      string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "remove alias",
+			screenName: "userA",
+			alias:      "userAalt",
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					deleteScreenNameAliasParams: deleteScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userAalt"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "alias doesn't exist",
+			screenName: "userA",
+			alias:      "userAalt",
+			want:       `{"error":"alias not found","code":"screen_name_alias_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					deleteScreenNameAliasParams: deleteScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userAalt"),
+							err:        state.ErrScreenNameAliasNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			alias:      "userAalt",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					deleteScreenNameAliasParams: deleteScreenNameAliasParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							alias:      state.NewIdentScreenName("userAalt"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/user/"+tc.screenName+"/alias/"+tc.alias, nil)
+			request.SetPathValue("screenname", tc.screenName)
+			request.SetPathValue("alias", tc.alias)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.deleteScreenNameAliasParams {
+				userManager.EXPECT().
+					DeleteScreenNameAlias(params.screenName, params.alias).
+					Return(params.err)
+			}
+
+			deleteUserAliasHandler(responseRecorder, request, userManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestUserStorageHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		screenName string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "get storage usage",
+			screenName: "userA",
+			want:       `{"offline_message_bytes":100,"profile_bytes":20,"feedbag_bytes":3,"used_bytes":123,"quota_bytes":1000}`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					storageUsageParams: storageUsageParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result: state.StorageUsage{
+								OfflineMessageBytes: 100,
+								ProfileBytes:        20,
+								FeedbagBytes:        3,
+								QuotaBytes:          1000,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					storageUsageParams: storageUsageParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					storageUsageParams: storageUsageParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/user/"+tc.screenName+"/storage", nil)
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.storageUsageParams {
+				userManager.EXPECT().
+					StorageUsage(params.screenName).
+					Return(params.result, params.err)
+			}
+
+			getUserStorageHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestUserStorageHandler_PUT(t *testing.T) {
+	quota := int64(1000)
+
+	tt := []struct {
+		name       string
+		screenName string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "set quota",
+			screenName: "userA",
+			body:       `{"quota_bytes":1000}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setStorageQuotaParams: setStorageQuotaParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							quota:      &quota,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "revert to default quota",
+			screenName: "userA",
+			body:       `{"quota_bytes":null}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setStorageQuotaParams: setStorageQuotaParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							quota:      nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with negative quota",
+			screenName: "userA",
+			body:       `{"quota_bytes":-1}`,
+			want:       `{"error":"quota_bytes must not be negative","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "with malformed body",
+			screenName: "userA",
+			body:       `{"quota_bytes":1000`, // missing closing }
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "user doesn't exist",
+			screenName: "userA",
+			body:       `{"quota_bytes":1000}`,
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setStorageQuotaParams: setStorageQuotaParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							quota:      &quota,
+							err:        state.ErrNoUser,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			screenName: "userA",
+			body:       `{"quota_bytes":1000}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				userManagerParams: userManagerParams{
+					setStorageQuotaParams: setStorageQuotaParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							quota:      &quota,
+							err:        io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/user/"+tc.screenName+"/storage", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
+			responseRecorder := httptest.NewRecorder()
+
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.userManagerParams.setStorageQuotaParams {
+				userManager.EXPECT().
+					SetStorageQuota(params.screenName, params.quota).
+					Return(params.err)
+			}
+
+			putUserStorageHandler(responseRecorder, request, userManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestPublicChatHandler_GET(t *testing.T) {
+	fnNewSess := func(screenName string) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		return sess
+	}
+
+	chatRoom1 := state.NewChatRoom("chat-room-1-name", state.NewIdentScreenName("chat-room-1-creator"), state.PublicExchange)
+	chatRoom2 := state.NewChatRoom("chat-room-2-name", state.NewIdentScreenName("chat-room-1-creator"), state.PublicExchange)
+
+	tt := []struct {
+		name       string
+		url        string
+		header     http.Header
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "multiple chat rooms with participants",
+			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","url":"aim:gochat?roomname=chat-room-1-name&exchange=5","participants":[{"id":"usera","screen_name":"userA"},{"id":"userb","screen_name":"userB"}]},{"name":"chat-room-2-name","create_time":"0001-01-01T00:00:00Z","url":"aim:gochat?roomname=chat-room-2-name&exchange=5","participants":[{"id":"userc","screen_name":"userC"},{"id":"userd","screen_name":"userD"}]}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PublicExchange,
+							result: []state.ChatRoom{
+								chatRoom1,
+								chatRoom2,
+							},
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: chatRoom1.Cookie(),
+							result: []*state.Session{
+								fnNewSess("userA"),
+								fnNewSess("userB"),
+							},
+						},
+						{
+							cookie: chatRoom2.Cookie(),
+							result: []*state.Session{
+								fnNewSess("userC"),
+								fnNewSess("userD"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "chat room without participants",
+			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","url":"aim:gochat?roomname=chat-room-1-name&exchange=5","participants":[]}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PublicExchange,
+							result: []state.ChatRoom{
+								chatRoom1,
+							},
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: chatRoom1.Cookie(),
+							result: []*state.Session{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "timestamps localized by tz query param",
+			url:        "/chat/room/public?tz=Etc/GMT-9",
+			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T09:00:00+09:00","url":"aim:gochat?roomname=chat-room-1-name&exchange=5","participants":[]}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PublicExchange,
+							result: []state.ChatRoom{
+								chatRoom1,
+							},
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: chatRoom1.Cookie(),
+							result: []*state.Session{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "timestamps localized by Accept-Timezone header",
+			header:     http.Header{"Accept-Timezone": []string{"Etc/GMT+5"}},
+			want:       `[{"name":"chat-room-1-name","create_time":"0000-12-31T19:00:00-05:00","url":"aim:gochat?roomname=chat-room-1-name&exchange=5","participants":[]}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PublicExchange,
+							result: []state.ChatRoom{
+								chatRoom1,
+							},
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: chatRoom1.Cookie(),
+							result: []*state.Session{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "invalid timezone",
+			url:        "/chat/room/public?tz=Not/AZone",
+			want:       `{"error":"invalid timezone","code":"invalid_input","details":"unknown time zone Not/AZone"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "no chat rooms",
+			want:       `[]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PublicExchange,
+							result:   []state.ChatRoom{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.url == "" {
+				tc.url = "/chat/room/public"
+			}
+			request := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			for k, v := range tc.header {
+				request.Header[k] = v
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomRetrieverParams.allChatRoomsParams {
+				chatRoomRetriever.EXPECT().
+					AllChatRooms(params.exchange).
+					Return(params.result, params.err)
+			}
+
+			chatSessionRetriever := newMockChatSessionRetriever(t)
+			for _, params := range tc.mockParams.chatSessionRetrieverParams.chatSessionRetrieverAllSessionsParams {
+				chatSessionRetriever.EXPECT().
+					AllSessions(params.cookie).
+					Return(params.result)
+			}
+
+			getPublicChatHandler(responseRecorder, request, chatRoomRetriever, chatSessionRetriever, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestPrivateChatHandler_GET(t *testing.T) {
+	fnNewSess := func(screenName string) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		return sess
+	}
+
+	chatRoom1 := state.NewChatRoom("chat-room-1-name", state.NewIdentScreenName("chat-room-1-creator"), state.PrivateExchange)
+	chatRoom2 := state.NewChatRoom("chat-room-2-name", state.NewIdentScreenName("chat-room-2-creator"), state.PrivateExchange)
+
+	tt := []struct {
+		name       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "multiple chat rooms with participants",
+			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","creator_id":"chat-room-1-creator","url":"aim:gochat?roomname=chat-room-1-name&exchange=4","participants":[{"id":"usera","screen_name":"userA"},{"id":"userb","screen_name":"userB"}]},{"name":"chat-room-2-name","create_time":"0001-01-01T00:00:00Z","creator_id":"chat-room-2-creator","url":"aim:gochat?roomname=chat-room-2-name&exchange=4","participants":[{"id":"userc","screen_name":"userC"},{"id":"userd","screen_name":"userD"}]}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PrivateExchange,
+							result: []state.ChatRoom{
+								chatRoom1,
+								chatRoom2,
+							},
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: chatRoom1.Cookie(),
+							result: []*state.Session{
+								fnNewSess("userA"),
+								fnNewSess("userB"),
+							},
+						},
+						{
+							cookie: chatRoom2.Cookie(),
+							result: []*state.Session{
+								fnNewSess("userC"),
+								fnNewSess("userD"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "chat room without participants",
+			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","creator_id":"chat-room-1-creator","url":"aim:gochat?roomname=chat-room-1-name&exchange=4","participants":[]}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PrivateExchange,
+							result: []state.ChatRoom{
+								chatRoom1,
+							},
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: chatRoom1.Cookie(),
+							result: []*state.Session{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "no chat rooms",
+			want:       `[]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					allChatRoomsParams: allChatRoomsParams{
+						{
+							exchange: state.PrivateExchange,
+							result:   []state.ChatRoom{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/chat/room/private", nil)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomRetrieverParams.allChatRoomsParams {
+				chatRoomRetriever.EXPECT().
+					AllChatRooms(params.exchange).
+					Return(params.result, params.err)
+			}
+
+			chatSessionRetriever := newMockChatSessionRetriever(t)
+			for _, params := range tc.mockParams.chatSessionRetrieverParams.chatSessionRetrieverAllSessionsParams {
+				chatSessionRetriever.EXPECT().
+					AllSessions(params.cookie).
+					Return(params.result)
+			}
+
+			getPrivateChatHandler(responseRecorder, request, chatRoomRetriever, chatSessionRetriever, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestChatRoomSlowModeHandler_POST(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		body          string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+	}{
+		{
+			name:          "enable slow mode",
+			requestCookie: room.Cookie(),
+			body:          `{"seconds":30}`,
+			statusCode:    http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatSlowModeSetterParams: chatSlowModeSetterParams{
+					setSlowModeParams: setSlowModeParams{
+						{
+							cookie:   room.Cookie(),
+							interval: 30 * time.Second,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "disable slow mode",
+			requestCookie: room.Cookie(),
+			body:          `{"seconds":0}`,
+			statusCode:    http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatSlowModeSetterParams: chatSlowModeSetterParams{
+					setSlowModeParams: setSlowModeParams{
+						{
+							cookie:   room.Cookie(),
+							interval: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "negative seconds",
+			requestCookie: room.Cookie(),
+			body:          `{"seconds":-1}`,
+			want:          `{"error":"seconds must not be negative","code":"invalid_input"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "malformed body",
+			requestCookie: room.Cookie(),
+			body:          `{"seconds":30`,
+			want:          `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "chat room not found",
+			requestCookie: "5-0-nonexistent",
+			body:          `{"seconds":30}`,
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "chat room lookup error",
+			requestCookie: room.Cookie(),
+			body:          `{"seconds":30}`,
+			want:          `{"error":"internal server error","code":"internal_error"}`,
+			statusCode:    http.StatusInternalServerError,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/slow-mode", strings.NewReader(tc.body))
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatSlowModeSetter := newMockChatSlowModeSetter(t)
+			for _, params := range tc.mockParams.setSlowModeParams {
+				chatSlowModeSetter.EXPECT().
+					SetSlowMode(params.cookie, params.interval)
+			}
+
+			postChatRoomSlowModeHandler(responseRecorder, request, chatRoomRetriever, chatSlowModeSetter, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomSpectatorHandler_POST(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		body          string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+	}{
+		{
+			name:          "add spectator",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Mod"}`,
+			statusCode:    http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("The Mod"),
+							result: &state.User{
+								IdentScreenName:   state.NewIdentScreenName("The Mod"),
+								DisplayScreenName: "The Mod",
+							},
+						},
+					},
+				},
+				chatSpectatorManagerParams: chatSpectatorManagerParams{
+					addSpectatorParams: addSpectatorParams{
+						{
+							cookie:     room.Cookie(),
+							screenName: state.NewIdentScreenName("The Mod"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "malformed body",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Mod"`,
+			want:          `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "chat room not found",
+			requestCookie: "5-0-nonexistent",
+			body:          `{"screen_name":"The Mod"}`,
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "user not found",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Mod"}`,
+			want:          `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("The Mod"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/spectators", strings.NewReader(tc.body))
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			chatSpectatorManager := newMockChatSpectatorManager(t)
+			for _, params := range tc.mockParams.addSpectatorParams {
+				chatSpectatorManager.EXPECT().
+					AddSpectator(params.cookie, params.screenName)
+			}
+
+			postChatRoomSpectatorHandler(responseRecorder, request, chatRoomRetriever, userManager, chatSpectatorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomSpectatorHandler_DELETE(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name              string
+		requestCookie     string
+		requestScreenName string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "remove spectator",
+			requestCookie:     room.Cookie(),
+			requestScreenName: "The Mod",
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatSpectatorManagerParams: chatSpectatorManagerParams{
+					removeSpectatorParams: removeSpectatorParams{
+						{
+							cookie:     room.Cookie(),
+							screenName: state.NewIdentScreenName("The Mod"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "chat room not found",
+			requestCookie:     "5-0-nonexistent",
+			requestScreenName: "The Mod",
+			want:              `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/spectators/"+url.PathEscape(tc.requestScreenName), nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			request.SetPathValue("screenname", tc.requestScreenName)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatSpectatorManager := newMockChatSpectatorManager(t)
+			for _, params := range tc.mockParams.removeSpectatorParams {
+				chatSpectatorManager.EXPECT().
+					RemoveSpectator(params.cookie, params.screenName)
+			}
+
+			deleteChatRoomSpectatorHandler(responseRecorder, request, chatRoomRetriever, chatSpectatorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomModeratorHandler_GET(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+	}{
+		{
+			name:          "list moderators",
+			requestCookie: room.Cookie(),
+			want:          `[{"screen_name":"mod1"},{"screen_name":"mod2"}]`,
+			statusCode:    http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					chatRoomModeratorsParams: chatRoomModeratorsParams{
+						{
+							cookie: room.Cookie(),
+							result: []state.IdentScreenName{
+								state.NewIdentScreenName("mod1"),
+								state.NewIdentScreenName("mod2"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "no moderators",
+			requestCookie: room.Cookie(),
+			want:          `[]`,
+			statusCode:    http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					chatRoomModeratorsParams: chatRoomModeratorsParams{
+						{
+							cookie: room.Cookie(),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "chat room not found",
+			requestCookie: "5-0-nonexistent",
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/moderators", nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.chatRoomModeratorsParams {
+				chatModeratorManager.EXPECT().
+					ChatRoomModerators(params.cookie).
+					Return(params.result, params.err)
+			}
+
+			getChatRoomModeratorHandler(responseRecorder, request, chatRoomRetriever, chatModeratorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomModeratorHandler_POST(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		body          string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+	}{
+		{
+			name:          "add moderator",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Mod"}`,
+			statusCode:    http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("The Mod"),
+							result: &state.User{
+								IdentScreenName:   state.NewIdentScreenName("The Mod"),
+								DisplayScreenName: "The Mod",
+							},
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					addChatRoomModeratorParams: addChatRoomModeratorParams{
+						{
+							cookie:     room.Cookie(),
+							screenName: state.NewIdentScreenName("The Mod"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "malformed body",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Mod"`,
+			want:          `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "chat room not found",
+			requestCookie: "5-0-nonexistent",
+			body:          `{"screen_name":"The Mod"}`,
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "user not found",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Mod"}`,
+			want:          `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("The Mod"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/moderators", strings.NewReader(tc.body))
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.addChatRoomModeratorParams {
+				chatModeratorManager.EXPECT().
+					AddChatRoomModerator(params.cookie, params.screenName).
+					Return(params.err)
+			}
+
+			postChatRoomModeratorHandler(responseRecorder, request, chatRoomRetriever, userManager, chatModeratorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomModeratorHandler_DELETE(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name              string
+		requestCookie     string
+		requestScreenName string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "remove moderator",
+			requestCookie:     room.Cookie(),
+			requestScreenName: "The Mod",
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					removeChatRoomModeratorParams: removeChatRoomModeratorParams{
+						{
+							cookie:     room.Cookie(),
+							screenName: state.NewIdentScreenName("The Mod"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "chat room not found",
+			requestCookie:     "5-0-nonexistent",
+			requestScreenName: "The Mod",
+			want:              `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/moderators/"+url.PathEscape(tc.requestScreenName), nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			request.SetPathValue("screenname", tc.requestScreenName)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.removeChatRoomModeratorParams {
+				chatModeratorManager.EXPECT().
+					RemoveChatRoomModerator(params.cookie, params.screenName).
+					Return(params.err)
+			}
+
+			deleteChatRoomModeratorHandler(responseRecorder, request, chatRoomRetriever, chatModeratorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomBanHandler_GET(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+	}{
+		{
+			name:          "list bans",
+			requestCookie: room.Cookie(),
+			want:          `[{"screen_name":"troll1"},{"screen_name":"troll2"}]`,
+			statusCode:    http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					chatRoomBansParams: chatRoomBansParams{
+						{
+							cookie: room.Cookie(),
+							result: []state.IdentScreenName{
+								state.NewIdentScreenName("troll1"),
+								state.NewIdentScreenName("troll2"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "chat room not found",
+			requestCookie: "5-0-nonexistent",
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/bans", nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.chatRoomBansParams {
+				chatModeratorManager.EXPECT().
+					ChatRoomBans(params.cookie).
+					Return(params.result, params.err)
+			}
+
+			getChatRoomBanHandler(responseRecorder, request, chatRoomRetriever, chatModeratorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomBanHandler_POST(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	fnNewSess := func(screenName string) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		return sess
+	}
+	trollSess := fnNewSess("The Troll")
+	otherSess := fnNewSess("Other User")
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		body          string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+		// wantClosed indicates whether the banned user's session is closed
+		wantClosed bool
+	}{
+		{
+			name:          "ban user in the room",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Troll"}`,
+			statusCode:    http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("The Troll"),
+							result: &state.User{
+								IdentScreenName:   state.NewIdentScreenName("The Troll"),
+								DisplayScreenName: "The Troll",
+							},
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					addChatRoomBanParams: addChatRoomBanParams{
+						{
+							cookie:     room.Cookie(),
+							screenName: state.NewIdentScreenName("The Troll"),
+						},
+					},
+				},
+				chatSessionRetrieverParams: chatSessionRetrieverParams{
+					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+						{
+							cookie: room.Cookie(),
+							result: []*state.Session{otherSess, trollSess},
+						},
+					},
+				},
+			},
+			wantClosed: true,
+		},
+		{
+			name:          "malformed body",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Troll"`,
+			want:          `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "user not found",
+			requestCookie: room.Cookie(),
+			body:          `{"screen_name":"The Troll"}`,
+			want:          `{"error":"user not found","code":"user_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				userManagerParams: userManagerParams{
+					getUserParams: getUserParams{
+						{
+							screenName: state.NewIdentScreenName("The Troll"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/bans", strings.NewReader(tc.body))
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			userManager := newMockUserManager(t)
+			for _, params := range tc.mockParams.getUserParams {
+				userManager.EXPECT().
+					User(params.screenName).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.addChatRoomBanParams {
+				chatModeratorManager.EXPECT().
+					AddChatRoomBan(params.cookie, params.screenName).
+					Return(params.err)
+			}
+			chatSessionRetriever := newMockChatSessionRetriever(t)
+			for _, params := range tc.mockParams.chatSessionRetrieverAllSessionsParams {
+				chatSessionRetriever.EXPECT().
+					AllSessions(params.cookie).
+					Return(params.result)
+			}
+
+			postChatRoomBanHandler(responseRecorder, request, chatRoomRetriever, userManager, chatModeratorManager, chatSessionRetriever, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			if tc.wantClosed {
+				select {
+				case <-trollSess.Closed():
+				default:
+					t.Error("expected banned user's session to be closed")
+				}
+				select {
+				case <-otherSess.Closed():
+					t.Error("expected other user's session to remain open")
+				default:
+				}
+			}
+		})
+	}
+}
+
+func TestChatRoomBanHandler_DELETE(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name              string
+		requestCookie     string
+		requestScreenName string
+		want              string
+		statusCode        int
+		mockParams        mockParams
+	}{
+		{
+			name:              "remove ban",
+			requestCookie:     room.Cookie(),
+			requestScreenName: "The Troll",
+			statusCode:        http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					removeChatRoomBanParams: removeChatRoomBanParams{
+						{
+							cookie:     room.Cookie(),
+							screenName: state.NewIdentScreenName("The Troll"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:              "chat room not found",
+			requestCookie:     "5-0-nonexistent",
+			requestScreenName: "The Troll",
+			want:              `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/bans/"+url.PathEscape(tc.requestScreenName), nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			request.SetPathValue("screenname", tc.requestScreenName)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.removeChatRoomBanParams {
+				chatModeratorManager.EXPECT().
+					RemoveChatRoomBan(params.cookie, params.screenName).
+					Return(params.err)
+			}
+
+			deleteChatRoomBanHandler(responseRecorder, request, chatRoomRetriever, chatModeratorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomHandler_DELETE(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	fnNewSess := func(screenName string) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		return sess
+	}
+
+	tt := []struct {
+		name           string
+		requestCookie  string
+		roomErr        error
+		deleteErr      error
+		sessions       []*state.Session
+		want           string
+		statusCode     int
+		wantDelete     bool
+		wantSessClosed bool
+	}{
+		{
+			name:           "delete room and disconnect participants",
+			requestCookie:  room.Cookie(),
+			sessions:       []*state.Session{fnNewSess("user1"), fnNewSess("user2")},
+			statusCode:     http.StatusNoContent,
+			wantDelete:     true,
+			wantSessClosed: true,
+		},
+		{
+			name:          "room not found",
+			requestCookie: room.Cookie(),
+			roomErr:       state.ErrChatRoomNotFound,
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+		},
+		{
+			name:          "room deleted concurrently",
+			requestCookie: room.Cookie(),
+			deleteErr:     state.ErrChatRoomNotFound,
+			sessions:      []*state.Session{fnNewSess("user1")},
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			wantDelete:    true,
+		},
+		{
+			name:          "delete error",
+			requestCookie: room.Cookie(),
+			deleteErr:     io.EOF,
+			sessions:      []*state.Session{fnNewSess("user1")},
+			want:          `{"error":"internal server error","code":"internal_error"}`,
+			statusCode:    http.StatusInternalServerError,
+			wantDelete:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/chat-rooms/"+url.PathEscape(tc.requestCookie), nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			chatRoomRetriever.EXPECT().
+				ChatRoomByCookie(tc.requestCookie).
+				Return(room, tc.roomErr)
+			chatRoomDeleter := newMockChatRoomDeleter(t)
+			if tc.wantDelete {
+				chatRoomDeleter.EXPECT().
+					DeleteChatRoom(room.Cookie()).
+					Return(tc.deleteErr)
+			}
+			chatSpectatorManager := newMockChatSpectatorManager(t)
+			chatSessionRetriever := newMockChatSessionRetriever(t)
+			if tc.wantSessClosed {
+				chatSpectatorManager.EXPECT().
+					RemoveAllSpectators(room.Cookie())
+				chatSessionRetriever.EXPECT().
+					AllSessions(room.Cookie()).
+					Return(tc.sessions)
+			}
+
+			deleteChatRoomHandler(responseRecorder, request, chatRoomRetriever, chatRoomDeleter, chatSpectatorManager, chatSessionRetriever, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			for _, sess := range tc.sessions {
+				select {
+				case <-sess.Closed():
+					assert.True(t, tc.wantSessClosed, "unexpected session closed")
+				default:
+					assert.False(t, tc.wantSessClosed, "expected session to be closed")
+				}
+			}
+		})
+	}
+}
+
+func TestChatRoomParticipantHandler_DELETE(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	fnNewSess := func(screenName string) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		return sess
+	}
+
+	tt := []struct {
+		name              string
+		requestCookie     string
+		requestScreenName string
+		sessions          []*state.Session
+		want              string
+		statusCode        int
+		// wantClosed is the index of the session expected to be closed, or -1
+		wantClosed int
+	}{
+		{
+			name:              "remove participant",
+			requestCookie:     room.Cookie(),
+			requestScreenName: "The Troll",
+			sessions:          []*state.Session{fnNewSess("Other User"), fnNewSess("The Troll")},
+			statusCode:        http.StatusNoContent,
+			wantClosed:        1,
+		},
+		{
+			name:              "user is not in the room",
+			requestCookie:     room.Cookie(),
+			requestScreenName: "The Troll",
+			sessions:          []*state.Session{fnNewSess("Other User")},
+			want:              `{"error":"user is not in the chat room","code":"session_not_found"}`,
+			statusCode:        http.StatusNotFound,
+			wantClosed:        -1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/participants/"+url.PathEscape(tc.requestScreenName), nil)
+			request.SetPathValue("cookie", tc.requestCookie)
+			request.SetPathValue("screenname", tc.requestScreenName)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			chatRoomRetriever.EXPECT().
+				ChatRoomByCookie(room.Cookie()).
+				Return(room, nil)
+			chatSessionRetriever := newMockChatSessionRetriever(t)
+			chatSessionRetriever.EXPECT().
+				AllSessions(room.Cookie()).
+				Return(tc.sessions)
+
+			deleteChatRoomParticipantHandler(responseRecorder, request, chatRoomRetriever, chatSessionRetriever, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+			for i, sess := range tc.sessions {
+				select {
+				case <-sess.Closed():
+					assert.Equal(t, tc.wantClosed, i, "unexpected session closed")
+				default:
+					assert.NotEqual(t, tc.wantClosed, i, "expected session to be closed")
+				}
+			}
+		})
+	}
+}
+
+func TestChatRoomTopicHandler_PUT(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+
+	tt := []struct {
+		name          string
+		requestCookie string
+		body          string
+		want          string
+		statusCode    int
+		mockParams    mockParams
+	}{
+		{
+			name:          "set topic",
+			requestCookie: room.Cookie(),
+			body:          `{"topic":"cats & dogs"}`,
+			statusCode:    http.StatusNoContent,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatModeratorManagerParams: chatModeratorManagerParams{
+					setChatRoomTopicParams: setChatRoomTopicParams{
+						{
+							cookie: room.Cookie(),
+							topic:  "cats & dogs",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "malformed body",
+			requestCookie: room.Cookie(),
+			body:          `{"topic":`,
+			want:          `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "chat room not found",
+			requestCookie: "5-0-nonexistent",
+			body:          `{"topic":"cats & dogs"}`,
+			want:          `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode:    http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/chat-rooms/"+url.PathEscape(tc.requestCookie)+"/topic", strings.NewReader(tc.body))
+			request.SetPathValue("cookie", tc.requestCookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatModeratorManager := newMockChatModeratorManager(t)
+			for _, params := range tc.mockParams.setChatRoomTopicParams {
+				chatModeratorManager.EXPECT().
+					SetChatRoomTopic(params.cookie, params.topic).
+					Return(params.err)
+			}
+
+			putChatRoomTopicHandler(responseRecorder, request, chatRoomRetriever, chatModeratorManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestChatRoomTranscriptHandler_GET(t *testing.T) {
+	room := state.NewChatRoom("the room", state.NewIdentScreenName("system"), state.PublicExchange)
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name       string
+		url        string
+		cookie     string
+		ttl        time.Duration
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "transcript within ttl",
+			url:        "/chat-rooms/" + url.PathEscape(room.Cookie()) + "/transcript",
+			cookie:     room.Cookie(),
+			ttl:        24 * time.Hour,
+			want:       `[{"screen_name":"Alice","text":"hello","sent":"2024-01-31T11:00:00Z"},{"screen_name":"Bob","text":"hi alice","sent":"2024-01-31T11:01:00Z"}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatTranscriptRetrieverParams: chatTranscriptRetrieverParams{
+					chatTranscriptParams: chatTranscriptParams{
+						{
+							cookie: room.Cookie(),
+							since:  now.Add(-24 * time.Hour),
+							result: []state.ChatTranscriptEntry{
+								{ScreenName: "Alice", Text: "hello", Sent: now.Add(-time.Hour)},
+								{ScreenName: "Bob", Text: "hi alice", Sent: now.Add(-59 * time.Minute)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "transcript without ttl in requested timezone",
+			url:        "/chat-rooms/" + url.PathEscape(room.Cookie()) + "/transcript?tz=Etc/GMT-9",
+			cookie:     room.Cookie(),
+			want:       `[{"screen_name":"Alice","text":"hello","sent":"2024-01-31T20:00:00+09:00"}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatTranscriptRetrieverParams: chatTranscriptRetrieverParams{
+					chatTranscriptParams: chatTranscriptParams{
+						{
+							cookie: room.Cookie(),
+							result: []state.ChatTranscriptEntry{
+								{ScreenName: "Alice", Text: "hello", Sent: now.Add(-time.Hour)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "empty transcript",
+			url:        "/chat-rooms/" + url.PathEscape(room.Cookie()) + "/transcript",
+			cookie:     room.Cookie(),
+			want:       `[]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatTranscriptRetrieverParams: chatTranscriptRetrieverParams{
+					chatTranscriptParams: chatTranscriptParams{
+						{
+							cookie: room.Cookie(),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "chat room not found",
+			url:        "/chat-rooms/5-0-nonexistent/transcript",
+			cookie:     "5-0-nonexistent",
+			want:       `{"error":"chat room not found","code":"chat_room_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: "5-0-nonexistent",
+							err:    state.ErrChatRoomNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "transcript retrieval error",
+			url:        "/chat-rooms/" + url.PathEscape(room.Cookie()) + "/transcript",
+			cookie:     room.Cookie(),
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				chatRoomRetrieverParams: chatRoomRetrieverParams{
+					chatRoomByCookieParams: chatRoomByCookieParams{
+						{
+							cookie: room.Cookie(),
+							result: room,
+						},
+					},
+				},
+				chatTranscriptRetrieverParams: chatTranscriptRetrieverParams{
+					chatTranscriptParams: chatTranscriptParams{
+						{
+							cookie: room.Cookie(),
+							err:    io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			request.SetPathValue("cookie", tc.cookie)
+			responseRecorder := httptest.NewRecorder()
+
+			chatRoomRetriever := newMockChatRoomRetriever(t)
+			for _, params := range tc.mockParams.chatRoomByCookieParams {
+				chatRoomRetriever.EXPECT().
+					ChatRoomByCookie(params.cookie).
+					Return(params.result, params.err)
+			}
+			chatTranscriptRetriever := newMockChatTranscriptRetriever(t)
+			for _, params := range tc.mockParams.chatTranscriptParams {
+				chatTranscriptRetriever.EXPECT().
+					ChatTranscript(params.cookie, params.since).
+					Return(params.result, params.err)
+			}
+
+			getChatRoomTranscriptHandler(responseRecorder, request, chatRoomRetriever, chatTranscriptRetriever, tc.ttl,
+				func() time.Time { return now }, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestInstantMessageHandler_POST(t *testing.T) {
+	type relayToScreenNameInputs struct {
+		sender    state.IdentScreenName
+		recipient state.IdentScreenName
+		msg       string
+	}
+
+	tt := []struct {
+		name                    string
+		relayToScreenNameInputs []relayToScreenNameInputs
+		body                    string
+		want                    string
+		statusCode              int
+	}{
+		{
+			name: "send an instant message",
+			relayToScreenNameInputs: []relayToScreenNameInputs{
+				{
+					sender:    state.NewIdentScreenName("sender_sn"),
+					recipient: state.NewIdentScreenName("recip_sn"),
+					msg:       "hello world!",
+				},
+			},
+			body:       `{"from":"sender_sn","to":"recip_sn","text":"hello world!"}`,
+			want:       `Message sent successfully.`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "with malformed body",
+			body:       `{"screen_name":"userA", "password":"thepassword"`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			messageRelayer := newMockMessageRelayer(t)
+
+			for _, params := range tc.relayToScreenNameInputs {
+				validateSNAC := func(msg wire.SNACMessage) bool {
+					body := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
+					assert.Equal(t, params.sender.String(), body.TLVUserInfo.ScreenName)
+
+					b, ok := body.Bytes(wire.ICBMTLVAOLIMData)
+					assert.True(t, ok)
+
+					txt, err := wire.UnmarshalICBMMessageText(b)
+					assert.NoError(t, err)
+					assert.Equal(t, params.msg, txt)
+					return true
+				}
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.recipient, mock.MatchedBy(validateSNAC))
+			}
+
+			postInstantMessageHandler(responseRecorder, request, messageRelayer, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestDebugSNACHandler_POST(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "send raw SNAC to online user",
+			body:       `{"screen_name":"userA","food_group":4,"sub_group":7,"hex_body":"0a0b0c"}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     &state.Session{},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{
+									FoodGroup: wire.ICBM,
+									SubGroup:  wire.ICBMChannelMsgToClient,
+								},
+								Body: []byte{0x0a, 0x0b, 0x0c},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user is offline",
+			body:       `{"screen_name":"userA","food_group":4,"sub_group":7,"hex_body":"0a0b0c"}`,
+			want:       `{"error":"session not found","code":"session_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "with invalid hex body",
+			body:       `{"screen_name":"userA","food_group":4,"sub_group":7,"hex_body":"zz"}`,
+			want:       `{"error":"hex_body is not a valid hex string","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "with malformed body",
+			body:       `{"screen_name":"userA"`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/debug/snac", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
+			}
+
+			postDebugSNACHandler(responseRecorder, request, sessionRetriever, messageRelayer, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestVersionHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		want       string
+		statusCode int
+		buildInfo  config.Build
+	}{
+		{
+			name:       "get ras version",
+			want:       `{"version":"13.3.7","commit":"asdfASDF12345678","date":"2024-03-01"}`,
+			statusCode: http.StatusOK,
+			buildInfo: config.Build{
+				Version: "13.3.7",
+				Commit:  "asdfASDF12345678",
+				Date:    "2024-03-01",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+
+			getVersionHandler(responseRecorder, tc.buildInfo)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestAwayTemplateHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "no templates",
+			want:       `[]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					awayTemplatesParams: awayTemplatesParams{
+						{
+							result: nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "error fetching templates",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					awayTemplatesParams: awayTemplatesParams{
+						{
+							err: errors.New("error fetching templates"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "fetch some templates",
+			want:       `[{"name":"brb","message":"Be right back"},{"name":"lunch","message":"Out to lunch"}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					awayTemplatesParams: awayTemplatesParams{
+						{
+							result: []state.AwayTemplate{
+								{
+									Name:    "brb",
+									Message: "Be right back",
+								},
+								{
+									Name:    "lunch",
+									Message: "Out to lunch",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+
+			awayTemplateManager := newMockAwayTemplateManager(t)
+			for _, params := range tc.mockParams.awayTemplatesParams {
+				awayTemplateManager.EXPECT().
+					AwayTemplates().
+					Return(params.result, params.err)
+			}
+
+			getAwayTemplateHandler(responseRecorder, awayTemplateManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestAwayTemplateHandler_PUT(t *testing.T) {
+	tt := []struct {
+		name       string
+		template   string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "save template",
+			template:   "lunch",
+			body:       `{"message":"Out to lunch"}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					setAwayTemplateParams: setAwayTemplateParams{
+						{
+							template: state.AwayTemplate{Name: "lunch", Message: "Out to lunch"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "runtime error",
+			template:   "lunch",
+			body:       `{"message":"Out to lunch"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					setAwayTemplateParams: setAwayTemplateParams{
+						{
+							template: state.AwayTemplate{Name: "lunch", Message: "Out to lunch"},
+							err:      errors.New("error saving template"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "empty message",
+			template:   "lunch",
+			body:       `{"message":""}`,
+			want:       `{"error":"message is required","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "message too long",
+			template:   "lunch",
+			body:       `{"message":"` + strings.Repeat("a", 1001) + `"}`,
+			want:       `{"error":"message must be no longer than 1000 characters","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed body",
+			template:   "lunch",
+			body:       `{`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/away-template/"+tc.template, strings.NewReader(tc.body))
+			request.SetPathValue("name", tc.template)
+			responseRecorder := httptest.NewRecorder()
+
+			awayTemplateManager := newMockAwayTemplateManager(t)
+			for _, params := range tc.mockParams.setAwayTemplateParams {
+				awayTemplateManager.EXPECT().
+					SetAwayTemplate(params.template).
+					Return(params.err)
+			}
+
+			putAwayTemplateHandler(responseRecorder, request, awayTemplateManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestAwayTemplateHandler_DELETE(t *testing.T) {
+	tt := []struct {
+		name       string
+		template   string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "successful deletion",
+			template:   "lunch",
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					deleteAwayTemplateParams: deleteAwayTemplateParams{
+						{
+							name: "lunch",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "template not found",
+			template:   "lunch",
+			want:       `{"error":"away template not found","code":"away_template_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					deleteAwayTemplateParams: deleteAwayTemplateParams{
+						{
+							name: "lunch",
+							err:  state.ErrAwayTemplateNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "runtime error",
+			template:   "lunch",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				awayTemplateManagerParams: awayTemplateManagerParams{
+					deleteAwayTemplateParams: deleteAwayTemplateParams{
+						{
+							name: "lunch",
+							err:  errors.New("error deleting template"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, "/away-template/"+tc.template, nil)
+			request.SetPathValue("name", tc.template)
+			responseRecorder := httptest.NewRecorder()
+
+			awayTemplateManager := newMockAwayTemplateManager(t)
+			for _, params := range tc.mockParams.deleteAwayTemplateParams {
+				awayTemplateManager.EXPECT().
+					DeleteAwayTemplate(params.name).
+					Return(params.err)
+			}
+
+			deleteAwayTemplateHandler(responseRecorder, request, awayTemplateManager, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
+
+func TestDirectoryCategoryHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "no categories",
+			want:       `[]`,
 			statusCode: http.StatusOK,
 			mockParams: mockParams{
-				chatRoomRetrieverParams: chatRoomRetrieverParams{
-					allChatRoomsParams: allChatRoomsParams{
+				directoryManagerParams: directoryManagerParams{
+					categoriesParams: categoriesParams{
 						{
-							exchange: state.PublicExchange,
-							result:   []state.ChatRoom{},
+							result: nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "error fetching categories",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					categoriesParams: categoriesParams{
+						{
+							result: nil,
+							err:    errors.New("error fetching categories"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "fetch some categories",
+			want:       `[{"id":1,"name":"category-1"},{"id":2,"name":"category-2"}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					categoriesParams: categoriesParams{
+						{
+							result: []state.Category{
+								{
+									ID:   1,
+									Name: "category-1",
+								},
+								{
+									ID:   2,
+									Name: "category-2",
+								},
+							},
 						},
 					},
 				},
@@ -1113,24 +7355,138 @@ func TestPublicChatHandler_GET(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodGet, "/chat/room/public", nil)
 			responseRecorder := httptest.NewRecorder()
 
-			chatRoomRetriever := newMockChatRoomRetriever(t)
-			for _, params := range tc.mockParams.chatRoomRetrieverParams.allChatRoomsParams {
-				chatRoomRetriever.EXPECT().
-					AllChatRooms(params.exchange).
+			directoryManager := newMockDirectoryManager(t)
+			for _, params := range tc.mockParams.categoriesParams {
+				directoryManager.EXPECT().
+					Categories().
 					Return(params.result, params.err)
 			}
 
-			chatSessionRetriever := newMockChatSessionRetriever(t)
-			for _, params := range tc.mockParams.chatSessionRetrieverParams.chatSessionRetrieverAllSessionsParams {
-				chatSessionRetriever.EXPECT().
-					AllSessions(params.cookie).
-					Return(params.result)
+			getDirectoryCategoryHandler(responseRecorder, directoryManager, slog.Default())
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func TestDirectoryCategoryKeywordHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		categoryID int
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "category not found",
+			categoryID: 1,
+			want:       `{"error":"category not found","code":"category_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					keywordsByCategoryParams: keywordsByCategoryParams{
+						{
+							categoryID: 1,
+							result:     nil,
+							err:        state.ErrKeywordCategoryNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "error fetching keywords by category",
+			categoryID: 1,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					keywordsByCategoryParams: keywordsByCategoryParams{
+						{
+							categoryID: 1,
+							result:     nil,
+							err:        errors.New("error fetching keywords by category"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "invalid category ID",
+			categoryID: -1,
+			want:       `{"error":"invalid category ID","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					keywordsByCategoryParams: keywordsByCategoryParams{},
+				},
+			},
+		},
+		{
+			name:       "no keywords",
+			categoryID: 1,
+			want:       `[]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					keywordsByCategoryParams: keywordsByCategoryParams{
+						{
+							categoryID: 1,
+							result:     nil,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "fetch some keywords by category",
+			categoryID: 1,
+			want:       `[{"id":1,"name":"keyword-1"},{"id":2,"name":"keyword-2"}]`,
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					keywordsByCategoryParams: keywordsByCategoryParams{
+						{
+							categoryID: 1,
+							result: []state.Keyword{
+								{
+									ID:   1,
+									Name: "keyword-1",
+								},
+								{
+									ID:   2,
+									Name: "keyword-2",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/directory/category/%d/keyword", tc.categoryID), nil)
+			request.SetPathValue("id", fmt.Sprintf("%d", tc.categoryID))
+			responseRecorder := httptest.NewRecorder()
+
+			directoryManager := newMockDirectoryManager(t)
+			for _, params := range tc.mockParams.keywordsByCategoryParams {
+				directoryManager.EXPECT().
+					KeywordsByCategory(params.categoryID).
+					Return(params.result, params.err)
 			}
 
-			getPublicChatHandler(responseRecorder, request, chatRoomRetriever, chatSessionRetriever, slog.Default())
+			getDirectoryCategoryKeywordHandler(responseRecorder, request, directoryManager, slog.Default())
 
 			if responseRecorder.Code != tc.statusCode {
 				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
@@ -1143,96 +7499,85 @@ func TestPublicChatHandler_GET(t *testing.T) {
 	}
 }
 
-func TestPrivateChatHandler_GET(t *testing.T) {
-	fnNewSess := func(screenName string) *state.Session {
-		sess := state.NewSession()
-		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
-		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
-		return sess
-	}
-
-	chatRoom1 := state.NewChatRoom("chat-room-1-name", state.NewIdentScreenName("chat-room-1-creator"), state.PrivateExchange)
-	chatRoom2 := state.NewChatRoom("chat-room-2-name", state.NewIdentScreenName("chat-room-2-creator"), state.PrivateExchange)
-
+func TestDirectoryCategoryHandler_DELETE(t *testing.T) {
 	tt := []struct {
 		name       string
+		categoryID int
 		want       string
 		statusCode int
 		mockParams mockParams
 	}{
 		{
-			name:       "multiple chat rooms with participants",
-			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","creator_id":"chat-room-1-creator","url":"aim:gochat?roomname=chat-room-1-name&exchange=4","participants":[{"id":"usera","screen_name":"userA"},{"id":"userb","screen_name":"userB"}]},{"name":"chat-room-2-name","create_time":"0001-01-01T00:00:00Z","creator_id":"chat-room-2-creator","url":"aim:gochat?roomname=chat-room-2-name&exchange=4","participants":[{"id":"userc","screen_name":"userC"},{"id":"userd","screen_name":"userD"}]}]`,
-			statusCode: http.StatusOK,
+			name:       "category not found",
+			categoryID: 1,
+			want:       `{"error":"category not found","code":"category_not_found"}`,
+			statusCode: http.StatusNotFound,
 			mockParams: mockParams{
-				chatRoomRetrieverParams: chatRoomRetrieverParams{
-					allChatRoomsParams: allChatRoomsParams{
+				directoryManagerParams: directoryManagerParams{
+					deleteCategoryParams: deleteCategoryParams{
 						{
-							exchange: state.PrivateExchange,
-							result: []state.ChatRoom{
-								chatRoom1,
-								chatRoom2,
-							},
+							categoryID: 1,
+							err:        state.ErrKeywordCategoryNotFound,
 						},
 					},
 				},
-				chatSessionRetrieverParams: chatSessionRetrieverParams{
-					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
-						{
-							cookie: chatRoom1.Cookie(),
-							result: []*state.Session{
-								fnNewSess("userA"),
-								fnNewSess("userB"),
-							},
-						},
+			},
+		},
+		{
+			name:       "keyword in use by user",
+			categoryID: 1,
+			want:       `{"error":"can't delete because category in use by a user","code":"category_in_use"}`,
+			statusCode: http.StatusConflict,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					deleteCategoryParams: deleteCategoryParams{
 						{
-							cookie: chatRoom2.Cookie(),
-							result: []*state.Session{
-								fnNewSess("userC"),
-								fnNewSess("userD"),
-							},
+							categoryID: 1,
+							err:        state.ErrKeywordInUse,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "chat room without participants",
-			want:       `[{"name":"chat-room-1-name","create_time":"0001-01-01T00:00:00Z","creator_id":"chat-room-1-creator","url":"aim:gochat?roomname=chat-room-1-name&exchange=4","participants":[]}]`,
-			statusCode: http.StatusOK,
+			name:       "runtime error",
+			categoryID: 1,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
 			mockParams: mockParams{
-				chatRoomRetrieverParams: chatRoomRetrieverParams{
-					allChatRoomsParams: allChatRoomsParams{
+				directoryManagerParams: directoryManagerParams{
+					deleteCategoryParams: deleteCategoryParams{
 						{
-							exchange: state.PrivateExchange,
-							result: []state.ChatRoom{
-								chatRoom1,
-							},
+							categoryID: 1,
+							err:        errors.New("error deleting keyword"),
 						},
 					},
 				},
-				chatSessionRetrieverParams: chatSessionRetrieverParams{
-					chatSessionRetrieverAllSessionsParams: chatSessionRetrieverAllSessionsParams{
+			},
+		},
+		{
+			name:       "successful deletion",
+			categoryID: 1,
+			want:       ``,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					deleteCategoryParams: deleteCategoryParams{
 						{
-							cookie: chatRoom1.Cookie(),
-							result: []*state.Session{},
+							categoryID: 1,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "no chat rooms",
-			want:       `[]`,
-			statusCode: http.StatusOK,
+			name:       "invalid category ID",
+			categoryID: -1,
+			want:       `{"error":"invalid category ID","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
 			mockParams: mockParams{
-				chatRoomRetrieverParams: chatRoomRetrieverParams{
-					allChatRoomsParams: allChatRoomsParams{
-						{
-							exchange: state.PrivateExchange,
-							result:   []state.ChatRoom{},
-						},
-					},
+				directoryManagerParams: directoryManagerParams{
+					deleteCategoryParams: deleteCategoryParams{},
 				},
 			},
 		},
@@ -1240,24 +7585,18 @@ func TestPrivateChatHandler_GET(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodGet, "/chat/room/private", nil)
+			request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/directory/category/%d/keyword", tc.categoryID), nil)
+			request.SetPathValue("id", fmt.Sprintf("%d", tc.categoryID))
 			responseRecorder := httptest.NewRecorder()
 
-			chatRoomRetriever := newMockChatRoomRetriever(t)
-			for _, params := range tc.mockParams.chatRoomRetrieverParams.allChatRoomsParams {
-				chatRoomRetriever.EXPECT().
-					AllChatRooms(params.exchange).
-					Return(params.result, params.err)
-			}
-
-			chatSessionRetriever := newMockChatSessionRetriever(t)
-			for _, params := range tc.mockParams.chatSessionRetrieverParams.chatSessionRetrieverAllSessionsParams {
-				chatSessionRetriever.EXPECT().
-					AllSessions(params.cookie).
-					Return(params.result)
+			directoryManager := newMockDirectoryManager(t)
+			for _, params := range tc.mockParams.deleteCategoryParams {
+				directoryManager.EXPECT().
+					DeleteCategory(params.categoryID).
+					Return(params.err)
 			}
 
-			getPrivateChatHandler(responseRecorder, request, chatRoomRetriever, chatSessionRetriever, slog.Default())
+			deleteDirectoryCategoryHandler(responseRecorder, request, directoryManager, slog.Default())
 
 			if responseRecorder.Code != tc.statusCode {
 				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
@@ -1270,102 +7609,91 @@ func TestPrivateChatHandler_GET(t *testing.T) {
 	}
 }
 
-func TestInstantMessageHandler_POST(t *testing.T) {
-	type relayToScreenNameInputs struct {
-		sender    state.IdentScreenName
-		recipient state.IdentScreenName
-		msg       string
-	}
-
+func TestDirectoryCategoryHandler_POST(t *testing.T) {
 	tt := []struct {
-		name                    string
-		relayToScreenNameInputs []relayToScreenNameInputs
-		body                    string
-		want                    string
-		statusCode              int
+		name       string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
 	}{
 		{
-			name: "send an instant message",
-			relayToScreenNameInputs: []relayToScreenNameInputs{
-				{
-					sender:    state.NewIdentScreenName("sender_sn"),
-					recipient: state.NewIdentScreenName("recip_sn"),
-					msg:       "hello world!",
+			name:       "category already exists",
+			body:       `{"name":"the_category"}`,
+			want:       `{"error":"category already exists","code":"name_taken"}`,
+			statusCode: http.StatusConflict,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					createCategoryParams: createCategoryParams{
+						{
+							name: "the_category",
+							err:  state.ErrKeywordCategoryExists,
+						},
+					},
 				},
 			},
-			body:       `{"from":"sender_sn","to":"recip_sn","text":"hello world!"}`,
-			want:       `Message sent successfully.`,
-			statusCode: http.StatusOK,
 		},
 		{
-			name:       "with malformed body",
-			body:       `{"screen_name":"userA", "password":"thepassword"`,
-			want:       `malformed input`,
+			name:       "runtime error",
+			body:       `{"name":"the_category"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					createCategoryParams: createCategoryParams{
+						{
+							name: "the_category",
+							err:  errors.New("error creating category"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "bad input",
+			body:       `{"name":"the_category"`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
 			statusCode: http.StatusBadRequest,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					createCategoryParams: createCategoryParams{},
+				},
+			},
 		},
-	}
-
-	for _, tc := range tt {
-		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(tc.body))
-			responseRecorder := httptest.NewRecorder()
-
-			messageRelayer := newMockMessageRelayer(t)
-
-			for _, params := range tc.relayToScreenNameInputs {
-				validateSNAC := func(msg wire.SNACMessage) bool {
-					body := msg.Body.(wire.SNAC_0x04_0x07_ICBMChannelMsgToClient)
-					assert.Equal(t, params.sender.String(), body.TLVUserInfo.ScreenName)
-
-					b, ok := body.Bytes(wire.ICBMTLVAOLIMData)
-					assert.True(t, ok)
-
-					txt, err := wire.UnmarshalICBMMessageText(b)
-					assert.NoError(t, err)
-					assert.Equal(t, params.msg, txt)
-					return true
-				}
-				messageRelayer.EXPECT().
-					RelayToScreenName(mock.Anything, params.recipient, mock.MatchedBy(validateSNAC))
-			}
-
-			postInstantMessageHandler(responseRecorder, request, messageRelayer, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
-			}
-
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("want '%s', got '%s'", tc.want, responseRecorder.Body)
-			}
-		})
-	}
-}
-
-func TestVersionHandler_GET(t *testing.T) {
-	tt := []struct {
-		name       string
-		want       string
-		statusCode int
-		buildInfo  config.Build
-	}{
 		{
-			name:       "get ras version",
-			want:       `{"version":"13.3.7","commit":"asdfASDF12345678","date":"2024-03-01"}`,
-			statusCode: http.StatusOK,
-			buildInfo: config.Build{
-				Version: "13.3.7",
-				Commit:  "asdfASDF12345678",
-				Date:    "2024-03-01",
+			name:       "successful creation",
+			body:       `{"name":"the_category"}`,
+			want:       `{"id":1,"name":"the_category"}`,
+			statusCode: http.StatusCreated,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					createCategoryParams: createCategoryParams{
+						{
+							name: "the_category",
+							result: state.Category{
+								ID:   1,
+								Name: "the_category",
+							},
+						},
+					},
+				},
 			},
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/directory/category", strings.NewReader(tc.body))
 			responseRecorder := httptest.NewRecorder()
 
-			getVersionHandler(responseRecorder, tc.buildInfo)
+			directoryManager := newMockDirectoryManager(t)
+			for _, params := range tc.mockParams.createCategoryParams {
+				directoryManager.EXPECT().
+					CreateCategory(params.name).
+					Return(params.result, params.err)
+			}
+
+			postDirectoryCategoryHandler(responseRecorder, request, directoryManager, slog.Default())
 
 			if responseRecorder.Code != tc.statusCode {
 				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
@@ -1378,59 +7706,90 @@ func TestVersionHandler_GET(t *testing.T) {
 	}
 }
 
-func TestDirectoryCategoryHandler_GET(t *testing.T) {
+func TestDirectoryKeywordHandler_POST(t *testing.T) {
 	tt := []struct {
 		name       string
+		body       string
 		want       string
 		statusCode int
 		mockParams mockParams
 	}{
 		{
-			name:       "no categories",
-			want:       `[]`,
-			statusCode: http.StatusOK,
+			name:       "keyword already exists",
+			body:       `{"category_id":1,"name":"the_keyword"}`,
+			want:       `{"error":"keyword already exists","code":"name_taken"}`,
+			statusCode: http.StatusConflict,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					categoriesParams: categoriesParams{
+					createKeywordParams: createKeywordParams{
 						{
-							result: nil,
+							name:       "the_keyword",
+							categoryID: 1,
+							err:        state.ErrKeywordExists,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "error fetching categories",
-			want:       `{"message":"internal server error"}`,
+			name:       "category not found",
+			body:       `{"category_id":1,"name":"the_keyword"}`,
+			want:       `{"error":"category not found","code":"category_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					createKeywordParams: createKeywordParams{
+						{
+							name:       "the_keyword",
+							categoryID: 1,
+							err:        state.ErrKeywordCategoryNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "runtime error",
+			body:       `{"category_id":1,"name":"the_keyword"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
 			statusCode: http.StatusInternalServerError,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					categoriesParams: categoriesParams{
+					createKeywordParams: createKeywordParams{
 						{
-							result: nil,
-							err:    errors.New("error fetching categories"),
+							name:       "the_keyword",
+							categoryID: 1,
+							err:        errors.New("error creating keyword"),
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "fetch some categories",
-			want:       `[{"id":1,"name":"category-1"},{"id":2,"name":"category-2"}]`,
-			statusCode: http.StatusOK,
+			name:       "bad input",
+			body:       `{"category_id":1,"name":"the_keyword"`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					categoriesParams: categoriesParams{
+					createKeywordParams: createKeywordParams{},
+				},
+			},
+		},
+		{
+			name:       "successful creation",
+			body:       `{"category_id":1,"name":"the_keyword"}`,
+			want:       `{"id":1,"name":"the_keyword"}`,
+			statusCode: http.StatusCreated,
+			mockParams: mockParams{
+				directoryManagerParams: directoryManagerParams{
+					createKeywordParams: createKeywordParams{
 						{
-							result: []state.Category{
-								{
-									ID:   1,
-									Name: "category-1",
-								},
-								{
-									ID:   2,
-									Name: "category-2",
-								},
+							name:       "the_keyword",
+							categoryID: 1,
+							result: state.Keyword{
+								ID:   1,
+								Name: "the_keyword",
 							},
 						},
 					},
@@ -1441,16 +7800,17 @@ func TestDirectoryCategoryHandler_GET(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/directory/keyword", strings.NewReader(tc.body))
 			responseRecorder := httptest.NewRecorder()
 
 			directoryManager := newMockDirectoryManager(t)
-			for _, params := range tc.mockParams.categoriesParams {
+			for _, params := range tc.mockParams.createKeywordParams {
 				directoryManager.EXPECT().
-					Categories().
+					CreateKeyword(params.name, params.categoryID).
 					Return(params.result, params.err)
 			}
 
-			getDirectoryCategoryHandler(responseRecorder, directoryManager, slog.Default())
+			postDirectoryKeywordHandler(responseRecorder, request, directoryManager, slog.Default())
 
 			if responseRecorder.Code != tc.statusCode {
 				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
@@ -1463,7 +7823,7 @@ func TestDirectoryCategoryHandler_GET(t *testing.T) {
 	}
 }
 
-func TestDirectoryCategoryKeywordHandler_GET(t *testing.T) {
+func TestDirectoryKeywordHandler_DELETE(t *testing.T) {
 	tt := []struct {
 		name       string
 		categoryID int
@@ -1472,88 +7832,76 @@ func TestDirectoryCategoryKeywordHandler_GET(t *testing.T) {
 		mockParams mockParams
 	}{
 		{
-			name:       "category not found",
+			name:       "keyword not found",
 			categoryID: 1,
-			want:       `{"message":"category not found"}`,
+			want:       `{"error":"keyword not found","code":"keyword_not_found"}`,
 			statusCode: http.StatusNotFound,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					keywordsByCategoryParams: keywordsByCategoryParams{
+					deleteKeywordParams: deleteKeywordParams{
 						{
-							categoryID: 1,
-							result:     nil,
-							err:        state.ErrKeywordCategoryNotFound,
+							id:  1,
+							err: state.ErrKeywordNotFound,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "error fetching keywords by category",
+			name:       "keyword in use by user",
 			categoryID: 1,
-			want:       `{"message":"internal server error"}`,
-			statusCode: http.StatusInternalServerError,
+			want:       `{"error":"can't delete because category in use by a user","code":"category_in_use"}`,
+			statusCode: http.StatusConflict,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					keywordsByCategoryParams: keywordsByCategoryParams{
+					deleteKeywordParams: deleteKeywordParams{
 						{
-							categoryID: 1,
-							result:     nil,
-							err:        errors.New("error fetching keywords by category"),
+							id:  1,
+							err: state.ErrKeywordInUse,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "invalid category ID",
-			categoryID: -1,
-			want:       `{"message":"invalid category ID"}`,
-			statusCode: http.StatusBadRequest,
+			name:       "runtime error",
+			categoryID: 1,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					keywordsByCategoryParams: keywordsByCategoryParams{},
+					deleteKeywordParams: deleteKeywordParams{
+						{
+							id:  1,
+							err: errors.New("error deleting keyword"),
+						},
+					},
 				},
 			},
 		},
 		{
-			name:       "no keywords",
+			name:       "successful deletion",
 			categoryID: 1,
-			want:       `[]`,
-			statusCode: http.StatusOK,
+			want:       ``,
+			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					keywordsByCategoryParams: keywordsByCategoryParams{
+					deleteKeywordParams: deleteKeywordParams{
 						{
-							categoryID: 1,
-							result:     nil,
+							id: 1,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "fetch some keywords by category",
-			categoryID: 1,
-			want:       `[{"id":1,"name":"keyword-1"},{"id":2,"name":"keyword-2"}]`,
-			statusCode: http.StatusOK,
+			name:       "invalid keyword ID",
+			categoryID: -1,
+			want:       `{"error":"invalid keyword ID","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
 			mockParams: mockParams{
 				directoryManagerParams: directoryManagerParams{
-					keywordsByCategoryParams: keywordsByCategoryParams{
-						{
-							categoryID: 1,
-							result: []state.Keyword{
-								{
-									ID:   1,
-									Name: "keyword-1",
-								},
-								{
-									ID:   2,
-									Name: "keyword-2",
-								},
-							},
-						},
-					},
+					deleteKeywordParams: deleteKeywordParams{},
 				},
 			},
 		},
@@ -1561,18 +7909,18 @@ func TestDirectoryCategoryKeywordHandler_GET(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/directory/category/%d/keyword", tc.categoryID), nil)
+			request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/directory/keyword/%d", tc.categoryID), nil)
 			request.SetPathValue("id", fmt.Sprintf("%d", tc.categoryID))
 			responseRecorder := httptest.NewRecorder()
 
 			directoryManager := newMockDirectoryManager(t)
-			for _, params := range tc.mockParams.keywordsByCategoryParams {
+			for _, params := range tc.mockParams.deleteKeywordParams {
 				directoryManager.EXPECT().
-					KeywordsByCategory(params.categoryID).
-					Return(params.result, params.err)
+					DeleteKeyword(params.id).
+					Return(params.err)
 			}
 
-			getDirectoryCategoryKeywordHandler(responseRecorder, request, directoryManager, slog.Default())
+			deleteDirectoryKeywordHandler(responseRecorder, request, directoryManager, slog.Default())
 
 			if responseRecorder.Code != tc.statusCode {
 				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
@@ -1585,85 +7933,146 @@ func TestDirectoryCategoryKeywordHandler_GET(t *testing.T) {
 	}
 }
 
-func TestDirectoryCategoryHandler_DELETE(t *testing.T) {
+func TestVirtualUserPresenceHandler_PUT(t *testing.T) {
 	tt := []struct {
 		name       string
-		categoryID int
+		screenName string
+		body       string
 		want       string
 		statusCode int
 		mockParams mockParams
 	}{
 		{
-			name:       "category not found",
-			categoryID: 1,
-			want:       `{"message":"category not found"}`,
-			statusCode: http.StatusNotFound,
+			name:       "sign on virtual user",
+			screenName: "Bridged Bob",
+			body:       `{"online":true}`,
+			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteCategoryParams: deleteCategoryParams{
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOnlineParams: setOnlineParams{
 						{
-							categoryID: 1,
-							err:        state.ErrKeywordCategoryNotFound,
+							screenName: "Bridged Bob",
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "keyword in use by user",
-			categoryID: 1,
-			want:       `{"message":"can't delete because category in use by a user"}`,
-			statusCode: http.StatusConflict,
+			name:       "set virtual user away",
+			screenName: "Bridged Bob",
+			body:       `{"online":true,"away_message":"gone fishing"}`,
+			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteCategoryParams: deleteCategoryParams{
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOnlineParams: setOnlineParams{
 						{
-							categoryID: 1,
-							err:        state.ErrKeywordInUse,
+							screenName:  "Bridged Bob",
+							awayMessage: "gone fishing",
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "runtime error",
-			categoryID: 1,
-			want:       `{"message":"internal server error"}`,
-			statusCode: http.StatusInternalServerError,
+			name:       "set virtual user on mobile device",
+			screenName: "Bridged Bob",
+			body:       `{"online":true,"mobile":true}`,
+			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteCategoryParams: deleteCategoryParams{
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOnlineParams: setOnlineParams{
 						{
-							categoryID: 1,
-							err:        errors.New("error deleting keyword"),
+							screenName: "Bridged Bob",
+							mobile:     true,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "successful deletion",
-			categoryID: 1,
-			want:       ``,
+			name:       "sign off virtual user",
+			screenName: "Bridged Bob",
+			body:       `{"online":false}`,
 			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteCategoryParams: deleteCategoryParams{
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOfflineParams: setOfflineParams{
 						{
-							categoryID: 1,
+							screenName: state.NewIdentScreenName("Bridged Bob"),
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "invalid category ID",
-			categoryID: -1,
-			want:       `invalid category ID`,
+			name:       "sign off virtual user that isn't signed on",
+			screenName: "Bridged Bob",
+			body:       `{"online":false}`,
+			want:       `{"error":"virtual user not found","code":"virtual_user_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOfflineParams: setOfflineParams{
+						{
+							screenName: state.NewIdentScreenName("Bridged Bob"),
+							err:        state.ErrVirtualUserNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "screen name belongs to a registered user",
+			screenName: "ChattingChuck",
+			body:       `{"online":true}`,
+			want:       `{"error":"screen name belongs to a registered or signed-on user","code":"virtual_user_conflict"}`,
+			statusCode: http.StatusConflict,
+			mockParams: mockParams{
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOnlineParams: setOnlineParams{
+						{
+							screenName: "ChattingChuck",
+							err:        state.ErrVirtualUserConflict,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "invalid screen name",
+			screenName: "b@d",
+			body:       `{"online":true}`,
+			want:       `{"error":"invalid screen name","code":"invalid_input","details":"invalid screen name length: screen name must contain at least 3 letters and be no longer than 16 characters"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "away message too long",
+			screenName: "Bridged Bob",
+			body:       `{"online":true,"away_message":"` + strings.Repeat("a", maxUserInfoLen+1) + `"}`,
+			want:       fmt.Sprintf(`{"error":"away message must be no longer than %d characters","code":"invalid_input"}`, maxUserInfoLen),
 			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed input",
+			screenName: "Bridged Bob",
+			body:       `{"online":`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "runtime error",
+			screenName: "Bridged Bob",
+			body:       `{"online":true}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteCategoryParams: deleteCategoryParams{},
+				virtualUserManagerParams: virtualUserManagerParams{
+					setOnlineParams: setOnlineParams{
+						{
+							screenName: "Bridged Bob",
+							err:        io.EOF,
+						},
+					},
 				},
 			},
 		},
@@ -1671,95 +8080,78 @@ func TestDirectoryCategoryHandler_DELETE(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/directory/category/%d/keyword", tc.categoryID), nil)
-			request.SetPathValue("id", fmt.Sprintf("%d", tc.categoryID))
+			request := httptest.NewRequest(http.MethodPut, "/virtual-user/"+url.PathEscape(tc.screenName)+"/presence", strings.NewReader(tc.body))
+			request.SetPathValue("screenname", tc.screenName)
 			responseRecorder := httptest.NewRecorder()
 
-			directoryManager := newMockDirectoryManager(t)
-			for _, params := range tc.mockParams.deleteCategoryParams {
-				directoryManager.EXPECT().
-					DeleteCategory(params.categoryID).
+			virtualUserManager := newMockVirtualUserManager(t)
+			for _, params := range tc.mockParams.setOnlineParams {
+				virtualUserManager.EXPECT().
+					SetOnline(mock.Anything, params.screenName, params.awayMessage, params.mobile).
+					Return(params.err)
+			}
+			for _, params := range tc.mockParams.setOfflineParams {
+				virtualUserManager.EXPECT().
+					SetOffline(mock.Anything, params.screenName).
 					Return(params.err)
 			}
 
-			deleteDirectoryCategoryHandler(responseRecorder, request, directoryManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
-			}
+			putVirtualUserPresenceHandler(responseRecorder, request, virtualUserManager, state.ScreenNamePolicy{}, slog.Default())
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
-			}
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
 		})
 	}
 }
 
-func TestDirectoryCategoryHandler_POST(t *testing.T) {
+func TestSharedGroupHandler_GET(t *testing.T) {
 	tt := []struct {
 		name       string
-		body       string
 		want       string
 		statusCode int
 		mockParams mockParams
 	}{
 		{
-			name:       "category already exists",
-			body:       `{"name":"the_category"}`,
-			want:       `{"message":"category already exists"}`,
-			statusCode: http.StatusConflict,
+			name:       "list shared groups",
+			want:       `[{"name":"Event Hosts","members":["HostHal"]},{"name":"Staff","members":["StaffAnn","StaffBob"]}]`,
+			statusCode: http.StatusOK,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createCategoryParams: createCategoryParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					sharedGroupMembersParams: sharedGroupMembersParams{
 						{
-							name: "the_category",
-							err:  state.ErrKeywordCategoryExists,
+							result: []state.SharedGroupMember{
+								{Group: "Event Hosts", ScreenName: "HostHal"},
+								{Group: "Staff", ScreenName: "StaffAnn"},
+								{Group: "Staff", ScreenName: "StaffBob"},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "runtime error",
-			body:       `{"name":"the_category"}`,
-			want:       `{"message":"internal server error"}`,
-			statusCode: http.StatusInternalServerError,
+			name:       "no shared groups",
+			want:       `[]`,
+			statusCode: http.StatusOK,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createCategoryParams: createCategoryParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					sharedGroupMembersParams: sharedGroupMembersParams{
 						{
-							name: "the_category",
-							err:  errors.New("error creating category"),
+							result: nil,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "bad input",
-			body:       `{"name":"the_category"`,
-			want:       `{"message":"malformed input"}`,
-			statusCode: http.StatusBadRequest,
-			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createCategoryParams: createCategoryParams{},
-				},
-			},
-		},
-		{
-			name:       "successful creation",
-			body:       `{"name":"the_category"}`,
-			want:       `{"id":1,"name":"the_category"}`,
-			statusCode: http.StatusCreated,
+			name:       "runtime error",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createCategoryParams: createCategoryParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					sharedGroupMembersParams: sharedGroupMembersParams{
 						{
-							name: "the_category",
-							result: state.Category{
-								ID:   1,
-								Name: "the_category",
-							},
+							err: errors.New("error listing shared groups"),
 						},
 					},
 				},
@@ -1769,114 +8161,157 @@ func TestDirectoryCategoryHandler_POST(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodPost, "/directory/category", strings.NewReader(tc.body))
 			responseRecorder := httptest.NewRecorder()
 
-			directoryManager := newMockDirectoryManager(t)
-			for _, params := range tc.mockParams.createCategoryParams {
-				directoryManager.EXPECT().
-					CreateCategory(params.name).
+			sharedGroupManager := newMockSharedGroupManager(t)
+			for _, params := range tc.mockParams.sharedGroupMembersParams {
+				sharedGroupManager.EXPECT().
+					SharedGroupMembers().
 					Return(params.result, params.err)
 			}
 
-			postDirectoryCategoryHandler(responseRecorder, request, directoryManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
-			}
+			getSharedGroupHandler(responseRecorder, sharedGroupManager, slog.Default())
 
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
-			}
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
 		})
 	}
 }
 
-func TestDirectoryKeywordHandler_POST(t *testing.T) {
+func TestSharedGroupMemberHandler_PUT(t *testing.T) {
+	newSess := func(screenName string, feedbagInUse bool) *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName(screenName))
+		sess.SetDisplayScreenName(state.DisplayScreenName(screenName))
+		if feedbagInUse {
+			sess.SetFeedbagInUse()
+		}
+		return sess
+	}
+	staffGroup := wire.FeedbagItem{Name: "Staff", GroupID: 5, ClassID: wire.FeedbagClassIdGroup}
+	staffBuddy := wire.FeedbagItem{Name: "StaffAnn", GroupID: 5, ItemID: 9, ClassID: wire.FeedbagClassIdBuddy}
+	rootGroup := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+
 	tt := []struct {
 		name       string
-		body       string
+		group      string
+		screenName string
 		want       string
 		statusCode int
 		mockParams mockParams
 	}{
 		{
-			name:       "keyword already exists",
-			body:       `{"category_id":1,"name":"the_keyword"}`,
-			want:       `{"message":"keyword already exists"}`,
-			statusCode: http.StatusConflict,
+			name:       "add staff member and sync online users' buddy lists",
+			group:      "Staff",
+			screenName: "StaffAnn",
+			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createKeywordParams: createKeywordParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					addSharedGroupMemberParams: addSharedGroupMemberParams{
 						{
-							name:       "the_keyword",
-							categoryID: 1,
-							err:        state.ErrKeywordExists,
+							group:      "Staff",
+							screenName: state.NewIdentScreenName("StaffAnn"),
+						},
+					},
+					syncSharedGroupParams: syncSharedGroupParams{
+						{
+							owner:    state.NewIdentScreenName("userA"),
+							group:    "Staff",
+							inserted: []wire.FeedbagItem{staffGroup, staffBuddy},
+							updated:  []wire.FeedbagItem{rootGroup},
+						},
+						{
+							owner:    state.NewIdentScreenName("userB"),
+							group:    "Staff",
+							inserted: []wire.FeedbagItem{staffGroup, staffBuddy},
+							updated:  []wire.FeedbagItem{rootGroup},
 						},
 					},
 				},
-			},
-		},
-		{
-			name:       "category not found",
-			body:       `{"category_id":1,"name":"the_keyword"}`,
-			want:       `{"message":"category not found"}`,
-			statusCode: http.StatusNotFound,
-			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createKeywordParams: createKeywordParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
 						{
-							name:       "the_keyword",
-							categoryID: 1,
-							err:        state.ErrKeywordCategoryNotFound,
+							result: []*state.Session{
+								newSess("userA", true),
+								newSess("userB", true),
+								// client-side buddy lists aren't synced
+								newSess("userC", false),
+							},
+						},
+					},
+				},
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagInsertItem},
+								Body:  wire.SNAC_0x13_0x08_FeedbagInsertItem{Items: []wire.FeedbagItem{staffGroup, staffBuddy}},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagUpdateItem},
+								Body:  wire.SNAC_0x13_0x09_FeedbagUpdateItem{Items: []wire.FeedbagItem{rootGroup}},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userB"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagInsertItem},
+								Body:  wire.SNAC_0x13_0x08_FeedbagInsertItem{Items: []wire.FeedbagItem{staffGroup, staffBuddy}},
+							},
+						},
+						{
+							screenName: state.NewIdentScreenName("userB"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagUpdateItem},
+								Body:  wire.SNAC_0x13_0x09_FeedbagUpdateItem{Items: []wire.FeedbagItem{rootGroup}},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "runtime error",
-			body:       `{"category_id":1,"name":"the_keyword"}`,
-			want:       `{"message":"internal server error"}`,
-			statusCode: http.StatusInternalServerError,
+			name:       "user not found",
+			group:      "Staff",
+			screenName: "nobody",
+			want:       `{"error":"user not found","code":"user_not_found"}`,
+			statusCode: http.StatusNotFound,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createKeywordParams: createKeywordParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					addSharedGroupMemberParams: addSharedGroupMemberParams{
 						{
-							name:       "the_keyword",
-							categoryID: 1,
-							err:        errors.New("error creating keyword"),
+							group:      "Staff",
+							screenName: state.NewIdentScreenName("nobody"),
+							err:        state.ErrNoUser,
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "bad input",
-			body:       `{"category_id":1,"name":"the_keyword"`,
-			want:       `{"message":"malformed input"}`,
+			name:       "blank group name",
+			group:      " ",
+			screenName: "StaffAnn",
+			want:       `{"error":"group name is required","code":"invalid_input"}`,
 			statusCode: http.StatusBadRequest,
-			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createKeywordParams: createKeywordParams{},
-				},
-			},
 		},
 		{
-			name:       "successful creation",
-			body:       `{"category_id":1,"name":"the_keyword"}`,
-			want:       `{"id":1,"name":"the_keyword"}`,
-			statusCode: http.StatusCreated,
+			name:       "runtime error",
+			group:      "Staff",
+			screenName: "StaffAnn",
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					createKeywordParams: createKeywordParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					addSharedGroupMemberParams: addSharedGroupMemberParams{
 						{
-							name:       "the_keyword",
-							categoryID: 1,
-							result: state.Keyword{
-								ID:   1,
-								Name: "the_keyword",
-							},
+							group:      "Staff",
+							screenName: state.NewIdentScreenName("StaffAnn"),
+							err:        errors.New("error adding member"),
 						},
 					},
 				},
@@ -1886,108 +8321,106 @@ func TestDirectoryKeywordHandler_POST(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodPost, "/directory/keyword", strings.NewReader(tc.body))
+			request := httptest.NewRequest(http.MethodPut, "/shared-group/group/member/screenname", nil)
+			request.SetPathValue("group", tc.group)
+			request.SetPathValue("screenname", tc.screenName)
 			responseRecorder := httptest.NewRecorder()
 
-			directoryManager := newMockDirectoryManager(t)
-			for _, params := range tc.mockParams.createKeywordParams {
-				directoryManager.EXPECT().
-					CreateKeyword(params.name, params.categoryID).
-					Return(params.result, params.err)
+			sharedGroupManager := newMockSharedGroupManager(t)
+			for _, params := range tc.mockParams.addSharedGroupMemberParams {
+				sharedGroupManager.EXPECT().
+					AddSharedGroupMember(params.group, params.screenName).
+					Return(params.err)
 			}
-
-			postDirectoryKeywordHandler(responseRecorder, request, directoryManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			for _, params := range tc.mockParams.syncSharedGroupParams {
+				sharedGroupManager.EXPECT().
+					SyncSharedGroup(params.owner, params.group).
+					Return(params.inserted, params.updated, params.deleted, params.err)
 			}
-
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverAllSessionsParams {
+				sessionRetriever.EXPECT().
+					AllSessions().
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
 			}
+
+			putSharedGroupMemberHandler(responseRecorder, request, sharedGroupManager, sessionRetriever, messageRelayer, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
 		})
 	}
 }
 
-func TestDirectoryKeywordHandler_DELETE(t *testing.T) {
+func TestSharedGroupMemberHandler_DELETE(t *testing.T) {
+	sess := state.NewSession()
+	sess.SetIdentScreenName(state.NewIdentScreenName("userA"))
+	sess.SetFeedbagInUse()
+	staffBuddy := wire.FeedbagItem{Name: "StaffAnn", GroupID: 5, ItemID: 9, ClassID: wire.FeedbagClassIdBuddy}
+
 	tt := []struct {
 		name       string
-		categoryID int
 		want       string
 		statusCode int
 		mockParams mockParams
 	}{
 		{
-			name:       "keyword not found",
-			categoryID: 1,
-			want:       `{"message":"keyword not found"}`,
-			statusCode: http.StatusNotFound,
+			name:       "remove member and sync online users' buddy lists",
+			statusCode: http.StatusNoContent,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteKeywordParams: deleteKeywordParams{
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					removeSharedGroupMemberParams: removeSharedGroupMemberParams{
 						{
-							id:  1,
-							err: state.ErrKeywordNotFound,
+							group:      "Staff",
+							screenName: state.NewIdentScreenName("StaffAnn"),
 						},
 					},
-				},
-			},
-		},
-		{
-			name:       "keyword in use by user",
-			categoryID: 1,
-			want:       `{"message":"can't delete because category in use by a user"}`,
-			statusCode: http.StatusConflict,
-			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteKeywordParams: deleteKeywordParams{
+					syncSharedGroupParams: syncSharedGroupParams{
 						{
-							id:  1,
-							err: state.ErrKeywordInUse,
+							owner:   state.NewIdentScreenName("userA"),
+							group:   "Staff",
+							deleted: []wire.FeedbagItem{staffBuddy},
 						},
 					},
 				},
-			},
-		},
-		{
-			name:       "runtime error",
-			categoryID: 1,
-			want:       `{"message":"internal server error"}`,
-			statusCode: http.StatusInternalServerError,
-			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteKeywordParams: deleteKeywordParams{
+				sessionRetrieverParams: sessionRetrieverParams{
+					sessionRetrieverAllSessionsParams: sessionRetrieverAllSessionsParams{
 						{
-							id:  1,
-							err: errors.New("error deleting keyword"),
+							result: []*state.Session{sess},
 						},
 					},
 				},
-			},
-		},
-		{
-			name:       "successful deletion",
-			categoryID: 1,
-			want:       ``,
-			statusCode: http.StatusNoContent,
-			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteKeywordParams: deleteKeywordParams{
+				messageRelayerParams: messageRelayerParams{
+					relayToScreenNameParams: relayToScreenNameParams{
 						{
-							id: 1,
+							screenName: state.NewIdentScreenName("userA"),
+							message: wire.SNACMessage{
+								Frame: wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagDeleteItem},
+								Body:  wire.SNAC_0x13_0x0A_FeedbagDeleteItem{Items: []wire.FeedbagItem{staffBuddy}},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name:       "invalid keyword ID",
-			categoryID: -1,
-			want:       `{"message":"invalid keyword ID"}`,
-			statusCode: http.StatusBadRequest,
+			name:       "member not found",
+			want:       `{"error":"shared group member not found","code":"shared_group_member_not_found"}`,
+			statusCode: http.StatusNotFound,
 			mockParams: mockParams{
-				directoryManagerParams: directoryManagerParams{
-					deleteKeywordParams: deleteKeywordParams{},
+				sharedGroupManagerParams: sharedGroupManagerParams{
+					removeSharedGroupMemberParams: removeSharedGroupMemberParams{
+						{
+							group:      "Staff",
+							screenName: state.NewIdentScreenName("StaffAnn"),
+							err:        state.ErrSharedGroupMemberNotFound,
+						},
+					},
 				},
 			},
 		},
@@ -1995,26 +8428,38 @@ func TestDirectoryKeywordHandler_DELETE(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/directory/keyword/%d", tc.categoryID), nil)
-			request.SetPathValue("id", fmt.Sprintf("%d", tc.categoryID))
+			request := httptest.NewRequest(http.MethodDelete, "/shared-group/Staff/member/StaffAnn", nil)
+			request.SetPathValue("group", "Staff")
+			request.SetPathValue("screenname", "StaffAnn")
 			responseRecorder := httptest.NewRecorder()
 
-			directoryManager := newMockDirectoryManager(t)
-			for _, params := range tc.mockParams.deleteKeywordParams {
-				directoryManager.EXPECT().
-					DeleteKeyword(params.id).
+			sharedGroupManager := newMockSharedGroupManager(t)
+			for _, params := range tc.mockParams.removeSharedGroupMemberParams {
+				sharedGroupManager.EXPECT().
+					RemoveSharedGroupMember(params.group, params.screenName).
 					Return(params.err)
 			}
-
-			deleteDirectoryKeywordHandler(responseRecorder, request, directoryManager, slog.Default())
-
-			if responseRecorder.Code != tc.statusCode {
-				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			for _, params := range tc.mockParams.syncSharedGroupParams {
+				sharedGroupManager.EXPECT().
+					SyncSharedGroup(params.owner, params.group).
+					Return(params.inserted, params.updated, params.deleted, params.err)
 			}
-
-			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
-				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverAllSessionsParams {
+				sessionRetriever.EXPECT().
+					AllSessions().
+					Return(params.result)
+			}
+			messageRelayer := newMockMessageRelayer(t)
+			for _, params := range tc.mockParams.relayToScreenNameParams {
+				messageRelayer.EXPECT().
+					RelayToScreenName(mock.Anything, params.screenName, params.message)
 			}
+
+			deleteSharedGroupMemberHandler(responseRecorder, request, sharedGroupManager, sessionRetriever, messageRelayer, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
 		})
 	}
 }
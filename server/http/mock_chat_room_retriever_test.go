@@ -78,6 +78,62 @@ func (_c *mockChatRoomRetriever_AllChatRooms_Call) RunAndReturn(run func(uint16)
 	return _c
 }
 
+// ChatRoomByCookie provides a mock function with given fields: cookie
+func (_m *mockChatRoomRetriever) ChatRoomByCookie(cookie string) (state.ChatRoom, error) {
+	ret := _m.Called(cookie)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChatRoomByCookie")
+	}
+
+	var r0 state.ChatRoom
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (state.ChatRoom, error)); ok {
+		return rf(cookie)
+	}
+	if rf, ok := ret.Get(0).(func(string) state.ChatRoom); ok {
+		r0 = rf(cookie)
+	} else {
+		r0 = ret.Get(0).(state.ChatRoom)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(cookie)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockChatRoomRetriever_ChatRoomByCookie_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ChatRoomByCookie'
+type mockChatRoomRetriever_ChatRoomByCookie_Call struct {
+	*mock.Call
+}
+
+// ChatRoomByCookie is a helper method to define mock.On call
+//   - cookie string
+func (_e *mockChatRoomRetriever_Expecter) ChatRoomByCookie(cookie interface{}) *mockChatRoomRetriever_ChatRoomByCookie_Call {
+	return &mockChatRoomRetriever_ChatRoomByCookie_Call{Call: _e.mock.On("ChatRoomByCookie", cookie)}
+}
+
+func (_c *mockChatRoomRetriever_ChatRoomByCookie_Call) Run(run func(cookie string)) *mockChatRoomRetriever_ChatRoomByCookie_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *mockChatRoomRetriever_ChatRoomByCookie_Call) Return(_a0 state.ChatRoom, _a1 error) *mockChatRoomRetriever_ChatRoomByCookie_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockChatRoomRetriever_ChatRoomByCookie_Call) RunAndReturn(run func(string) (state.ChatRoom, error)) *mockChatRoomRetriever_ChatRoomByCookie_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // newMockChatRoomRetriever creates a new instance of mockChatRoomRetriever. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func newMockChatRoomRetriever(t interface {
@@ -3,8 +3,12 @@
 package http
 
 import (
+	netip "net/netip"
+
 	state "github.com/mk6i/retro-aim-server/state"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // mockUserManager is an autogenerated mock type for the UserManager type
@@ -20,6 +24,53 @@ func (_m *mockUserManager) EXPECT() *mockUserManager_Expecter {
 	return &mockUserManager_Expecter{mock: &_m.Mock}
 }
 
+// AddScreenNameAlias provides a mock function with given fields: screenName, alias
+func (_m *mockUserManager) AddScreenNameAlias(screenName state.IdentScreenName, alias state.IdentScreenName) error {
+	ret := _m.Called(screenName, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddScreenNameAlias")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, state.IdentScreenName) error); ok {
+		r0 = rf(screenName, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_AddScreenNameAlias_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddScreenNameAlias'
+type mockUserManager_AddScreenNameAlias_Call struct {
+	*mock.Call
+}
+
+// AddScreenNameAlias is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - alias state.IdentScreenName
+func (_e *mockUserManager_Expecter) AddScreenNameAlias(screenName interface{}, alias interface{}) *mockUserManager_AddScreenNameAlias_Call {
+	return &mockUserManager_AddScreenNameAlias_Call{Call: _e.mock.On("AddScreenNameAlias", screenName, alias)}
+}
+
+func (_c *mockUserManager_AddScreenNameAlias_Call) Run(run func(screenName state.IdentScreenName, alias state.IdentScreenName)) *mockUserManager_AddScreenNameAlias_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_AddScreenNameAlias_Call) Return(_a0 error) *mockUserManager_AddScreenNameAlias_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_AddScreenNameAlias_Call) RunAndReturn(run func(state.IdentScreenName, state.IdentScreenName) error) *mockUserManager_AddScreenNameAlias_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // AllUsers provides a mock function with given fields:
 func (_m *mockUserManager) AllUsers() ([]state.User, error) {
 	ret := _m.Called()
@@ -77,6 +128,100 @@ func (_c *mockUserManager_AllUsers_Call) RunAndReturn(run func() ([]state.User,
 	return _c
 }
 
+// BanUser provides a mock function with given fields: screenName, reason
+func (_m *mockUserManager) BanUser(screenName state.IdentScreenName, reason string) error {
+	ret := _m.Called(screenName, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BanUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, string) error); ok {
+		r0 = rf(screenName, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_BanUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BanUser'
+type mockUserManager_BanUser_Call struct {
+	*mock.Call
+}
+
+// BanUser is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - reason string
+func (_e *mockUserManager_Expecter) BanUser(screenName interface{}, reason interface{}) *mockUserManager_BanUser_Call {
+	return &mockUserManager_BanUser_Call{Call: _e.mock.On("BanUser", screenName, reason)}
+}
+
+func (_c *mockUserManager_BanUser_Call) Run(run func(screenName state.IdentScreenName, reason string)) *mockUserManager_BanUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_BanUser_Call) Return(_a0 error) *mockUserManager_BanUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_BanUser_Call) RunAndReturn(run func(state.IdentScreenName, string) error) *mockUserManager_BanUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteScreenNameAlias provides a mock function with given fields: screenName, alias
+func (_m *mockUserManager) DeleteScreenNameAlias(screenName state.IdentScreenName, alias state.IdentScreenName) error {
+	ret := _m.Called(screenName, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteScreenNameAlias")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, state.IdentScreenName) error); ok {
+		r0 = rf(screenName, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_DeleteScreenNameAlias_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteScreenNameAlias'
+type mockUserManager_DeleteScreenNameAlias_Call struct {
+	*mock.Call
+}
+
+// DeleteScreenNameAlias is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - alias state.IdentScreenName
+func (_e *mockUserManager_Expecter) DeleteScreenNameAlias(screenName interface{}, alias interface{}) *mockUserManager_DeleteScreenNameAlias_Call {
+	return &mockUserManager_DeleteScreenNameAlias_Call{Call: _e.mock.On("DeleteScreenNameAlias", screenName, alias)}
+}
+
+func (_c *mockUserManager_DeleteScreenNameAlias_Call) Run(run func(screenName state.IdentScreenName, alias state.IdentScreenName)) *mockUserManager_DeleteScreenNameAlias_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_DeleteScreenNameAlias_Call) Return(_a0 error) *mockUserManager_DeleteScreenNameAlias_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_DeleteScreenNameAlias_Call) RunAndReturn(run func(state.IdentScreenName, state.IdentScreenName) error) *mockUserManager_DeleteScreenNameAlias_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteUser provides a mock function with given fields: screenName
 func (_m *mockUserManager) DeleteUser(screenName state.IdentScreenName) error {
 	ret := _m.Called(screenName)
@@ -169,6 +314,347 @@ func (_c *mockUserManager_InsertUser_Call) RunAndReturn(run func(state.User) err
 	return _c
 }
 
+// ScreenNameAliases provides a mock function with given fields: screenName
+func (_m *mockUserManager) ScreenNameAliases(screenName state.IdentScreenName) ([]state.IdentScreenName, error) {
+	ret := _m.Called(screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScreenNameAliases")
+	}
+
+	var r0 []state.IdentScreenName
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) ([]state.IdentScreenName, error)); ok {
+		return rf(screenName)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) []state.IdentScreenName); ok {
+		r0 = rf(screenName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]state.IdentScreenName)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName) error); ok {
+		r1 = rf(screenName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockUserManager_ScreenNameAliases_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ScreenNameAliases'
+type mockUserManager_ScreenNameAliases_Call struct {
+	*mock.Call
+}
+
+// ScreenNameAliases is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+func (_e *mockUserManager_Expecter) ScreenNameAliases(screenName interface{}) *mockUserManager_ScreenNameAliases_Call {
+	return &mockUserManager_ScreenNameAliases_Call{Call: _e.mock.On("ScreenNameAliases", screenName)}
+}
+
+func (_c *mockUserManager_ScreenNameAliases_Call) Run(run func(screenName state.IdentScreenName)) *mockUserManager_ScreenNameAliases_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_ScreenNameAliases_Call) Return(_a0 []state.IdentScreenName, _a1 error) *mockUserManager_ScreenNameAliases_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockUserManager_ScreenNameAliases_Call) RunAndReturn(run func(state.IdentScreenName) ([]state.IdentScreenName, error)) *mockUserManager_ScreenNameAliases_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetAllowedNetworks provides a mock function with given fields: screenName, networks
+func (_m *mockUserManager) SetAllowedNetworks(screenName state.IdentScreenName, networks []netip.Prefix) error {
+	ret := _m.Called(screenName, networks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAllowedNetworks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, []netip.Prefix) error); ok {
+		r0 = rf(screenName, networks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetAllowedNetworks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetAllowedNetworks'
+type mockUserManager_SetAllowedNetworks_Call struct {
+	*mock.Call
+}
+
+// SetAllowedNetworks is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - networks []netip.Prefix
+func (_e *mockUserManager_Expecter) SetAllowedNetworks(screenName interface{}, networks interface{}) *mockUserManager_SetAllowedNetworks_Call {
+	return &mockUserManager_SetAllowedNetworks_Call{Call: _e.mock.On("SetAllowedNetworks", screenName, networks)}
+}
+
+func (_c *mockUserManager_SetAllowedNetworks_Call) Run(run func(screenName state.IdentScreenName, networks []netip.Prefix)) *mockUserManager_SetAllowedNetworks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].([]netip.Prefix))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetAllowedNetworks_Call) Return(_a0 error) *mockUserManager_SetAllowedNetworks_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetAllowedNetworks_Call) RunAndReturn(run func(state.IdentScreenName, []netip.Prefix) error) *mockUserManager_SetAllowedNetworks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetCanCreateChatRooms provides a mock function with given fields: screenName, allowed
+func (_m *mockUserManager) SetCanCreateChatRooms(screenName state.IdentScreenName, allowed bool) error {
+	ret := _m.Called(screenName, allowed)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCanCreateChatRooms")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, bool) error); ok {
+		r0 = rf(screenName, allowed)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetCanCreateChatRooms_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetCanCreateChatRooms'
+type mockUserManager_SetCanCreateChatRooms_Call struct {
+	*mock.Call
+}
+
+// SetCanCreateChatRooms is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - allowed bool
+func (_e *mockUserManager_Expecter) SetCanCreateChatRooms(screenName interface{}, allowed interface{}) *mockUserManager_SetCanCreateChatRooms_Call {
+	return &mockUserManager_SetCanCreateChatRooms_Call{Call: _e.mock.On("SetCanCreateChatRooms", screenName, allowed)}
+}
+
+func (_c *mockUserManager_SetCanCreateChatRooms_Call) Run(run func(screenName state.IdentScreenName, allowed bool)) *mockUserManager_SetCanCreateChatRooms_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetCanCreateChatRooms_Call) Return(_a0 error) *mockUserManager_SetCanCreateChatRooms_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetCanCreateChatRooms_Call) RunAndReturn(run func(state.IdentScreenName, bool) error) *mockUserManager_SetCanCreateChatRooms_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetConcurrentLoginPolicy provides a mock function with given fields: screenName, policy
+func (_m *mockUserManager) SetConcurrentLoginPolicy(screenName state.IdentScreenName, policy string) error {
+	ret := _m.Called(screenName, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetConcurrentLoginPolicy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, string) error); ok {
+		r0 = rf(screenName, policy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetConcurrentLoginPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetConcurrentLoginPolicy'
+type mockUserManager_SetConcurrentLoginPolicy_Call struct {
+	*mock.Call
+}
+
+// SetConcurrentLoginPolicy is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - policy string
+func (_e *mockUserManager_Expecter) SetConcurrentLoginPolicy(screenName interface{}, policy interface{}) *mockUserManager_SetConcurrentLoginPolicy_Call {
+	return &mockUserManager_SetConcurrentLoginPolicy_Call{Call: _e.mock.On("SetConcurrentLoginPolicy", screenName, policy)}
+}
+
+func (_c *mockUserManager_SetConcurrentLoginPolicy_Call) Run(run func(screenName state.IdentScreenName, policy string)) *mockUserManager_SetConcurrentLoginPolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetConcurrentLoginPolicy_Call) Return(_a0 error) *mockUserManager_SetConcurrentLoginPolicy_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetConcurrentLoginPolicy_Call) RunAndReturn(run func(state.IdentScreenName, string) error) *mockUserManager_SetConcurrentLoginPolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetOfficial provides a mock function with given fields: screenName, official
+func (_m *mockUserManager) SetOfficial(screenName state.IdentScreenName, official bool) error {
+	ret := _m.Called(screenName, official)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOfficial")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, bool) error); ok {
+		r0 = rf(screenName, official)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetOfficial_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetOfficial'
+type mockUserManager_SetOfficial_Call struct {
+	*mock.Call
+}
+
+// SetOfficial is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - official bool
+func (_e *mockUserManager_Expecter) SetOfficial(screenName interface{}, official interface{}) *mockUserManager_SetOfficial_Call {
+	return &mockUserManager_SetOfficial_Call{Call: _e.mock.On("SetOfficial", screenName, official)}
+}
+
+func (_c *mockUserManager_SetOfficial_Call) Run(run func(screenName state.IdentScreenName, official bool)) *mockUserManager_SetOfficial_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetOfficial_Call) Return(_a0 error) *mockUserManager_SetOfficial_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetOfficial_Call) RunAndReturn(run func(state.IdentScreenName, bool) error) *mockUserManager_SetOfficial_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetPasswordResetToken provides a mock function with given fields: screenName, token, expiresAt
+func (_m *mockUserManager) SetPasswordResetToken(screenName state.IdentScreenName, token string, expiresAt time.Time) error {
+	ret := _m.Called(screenName, token, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPasswordResetToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, string, time.Time) error); ok {
+		r0 = rf(screenName, token, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetPasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetPasswordResetToken'
+type mockUserManager_SetPasswordResetToken_Call struct {
+	*mock.Call
+}
+
+// SetPasswordResetToken is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - token string
+//   - expiresAt time.Time
+func (_e *mockUserManager_Expecter) SetPasswordResetToken(screenName interface{}, token interface{}, expiresAt interface{}) *mockUserManager_SetPasswordResetToken_Call {
+	return &mockUserManager_SetPasswordResetToken_Call{Call: _e.mock.On("SetPasswordResetToken", screenName, token, expiresAt)}
+}
+
+func (_c *mockUserManager_SetPasswordResetToken_Call) Run(run func(screenName state.IdentScreenName, token string, expiresAt time.Time)) *mockUserManager_SetPasswordResetToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(string), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetPasswordResetToken_Call) Return(_a0 error) *mockUserManager_SetPasswordResetToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetPasswordResetToken_Call) RunAndReturn(run func(state.IdentScreenName, string, time.Time) error) *mockUserManager_SetPasswordResetToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetStorageQuota provides a mock function with given fields: screenName, quota
+func (_m *mockUserManager) SetStorageQuota(screenName state.IdentScreenName, quota *int64) error {
+	ret := _m.Called(screenName, quota)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetStorageQuota")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, *int64) error); ok {
+		r0 = rf(screenName, quota)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetStorageQuota_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetStorageQuota'
+type mockUserManager_SetStorageQuota_Call struct {
+	*mock.Call
+}
+
+// SetStorageQuota is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - quota *int64
+func (_e *mockUserManager_Expecter) SetStorageQuota(screenName interface{}, quota interface{}) *mockUserManager_SetStorageQuota_Call {
+	return &mockUserManager_SetStorageQuota_Call{Call: _e.mock.On("SetStorageQuota", screenName, quota)}
+}
+
+func (_c *mockUserManager_SetStorageQuota_Call) Run(run func(screenName state.IdentScreenName, quota *int64)) *mockUserManager_SetStorageQuota_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(*int64))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetStorageQuota_Call) Return(_a0 error) *mockUserManager_SetStorageQuota_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetStorageQuota_Call) RunAndReturn(run func(state.IdentScreenName, *int64) error) *mockUserManager_SetStorageQuota_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SetUserPassword provides a mock function with given fields: screenName, newPassword
 func (_m *mockUserManager) SetUserPassword(screenName state.IdentScreenName, newPassword string) error {
 	ret := _m.Called(screenName, newPassword)
@@ -216,6 +702,155 @@ func (_c *mockUserManager_SetUserPassword_Call) RunAndReturn(run func(state.Iden
 	return _c
 }
 
+// SetWatched provides a mock function with given fields: screenName, watched
+func (_m *mockUserManager) SetWatched(screenName state.IdentScreenName, watched bool) error {
+	ret := _m.Called(screenName, watched)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetWatched")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName, bool) error); ok {
+		r0 = rf(screenName, watched)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_SetWatched_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetWatched'
+type mockUserManager_SetWatched_Call struct {
+	*mock.Call
+}
+
+// SetWatched is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+//   - watched bool
+func (_e *mockUserManager_Expecter) SetWatched(screenName interface{}, watched interface{}) *mockUserManager_SetWatched_Call {
+	return &mockUserManager_SetWatched_Call{Call: _e.mock.On("SetWatched", screenName, watched)}
+}
+
+func (_c *mockUserManager_SetWatched_Call) Run(run func(screenName state.IdentScreenName, watched bool)) *mockUserManager_SetWatched_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_SetWatched_Call) Return(_a0 error) *mockUserManager_SetWatched_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_SetWatched_Call) RunAndReturn(run func(state.IdentScreenName, bool) error) *mockUserManager_SetWatched_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StorageUsage provides a mock function with given fields: screenName
+func (_m *mockUserManager) StorageUsage(screenName state.IdentScreenName) (state.StorageUsage, error) {
+	ret := _m.Called(screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StorageUsage")
+	}
+
+	var r0 state.StorageUsage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) (state.StorageUsage, error)); ok {
+		return rf(screenName)
+	}
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) state.StorageUsage); ok {
+		r0 = rf(screenName)
+	} else {
+		r0 = ret.Get(0).(state.StorageUsage)
+	}
+
+	if rf, ok := ret.Get(1).(func(state.IdentScreenName) error); ok {
+		r1 = rf(screenName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// mockUserManager_StorageUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StorageUsage'
+type mockUserManager_StorageUsage_Call struct {
+	*mock.Call
+}
+
+// StorageUsage is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+func (_e *mockUserManager_Expecter) StorageUsage(screenName interface{}) *mockUserManager_StorageUsage_Call {
+	return &mockUserManager_StorageUsage_Call{Call: _e.mock.On("StorageUsage", screenName)}
+}
+
+func (_c *mockUserManager_StorageUsage_Call) Run(run func(screenName state.IdentScreenName)) *mockUserManager_StorageUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_StorageUsage_Call) Return(_a0 state.StorageUsage, _a1 error) *mockUserManager_StorageUsage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *mockUserManager_StorageUsage_Call) RunAndReturn(run func(state.IdentScreenName) (state.StorageUsage, error)) *mockUserManager_StorageUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UnbanUser provides a mock function with given fields: screenName
+func (_m *mockUserManager) UnbanUser(screenName state.IdentScreenName) error {
+	ret := _m.Called(screenName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnbanUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(state.IdentScreenName) error); ok {
+		r0 = rf(screenName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockUserManager_UnbanUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnbanUser'
+type mockUserManager_UnbanUser_Call struct {
+	*mock.Call
+}
+
+// UnbanUser is a helper method to define mock.On call
+//   - screenName state.IdentScreenName
+func (_e *mockUserManager_Expecter) UnbanUser(screenName interface{}) *mockUserManager_UnbanUser_Call {
+	return &mockUserManager_UnbanUser_Call{Call: _e.mock.On("UnbanUser", screenName)}
+}
+
+func (_c *mockUserManager_UnbanUser_Call) Run(run func(screenName state.IdentScreenName)) *mockUserManager_UnbanUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(state.IdentScreenName))
+	})
+	return _c
+}
+
+func (_c *mockUserManager_UnbanUser_Call) Return(_a0 error) *mockUserManager_UnbanUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockUserManager_UnbanUser_Call) RunAndReturn(run func(state.IdentScreenName) error) *mockUserManager_UnbanUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // User provides a mock function with given fields: screenName
 func (_m *mockUserManager) User(screenName state.IdentScreenName) (*state.User, error) {
 	ret := _m.Called(screenName)
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/mk6i/retro-aim-server/config"
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// confirmPage is the page served by the public API /confirm endpoint. When
+// Token is set, it shows a form that submits the token. Otherwise, it shows
+// Message.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Confirm your account</title></head>
+<body>
+{{if .Token}}<form method="post" action="confirm">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Confirm my account</button>
+</form>{{else}}<p>{{.Message}}</p>{{end}}
+</body>
+</html>
+`))
+
+// confirmPageData is the data rendered by confirmPage.
+type confirmPageData struct {
+	Token   string
+	Message string
+}
+
+// NewPublicAPI creates the HTTP server for endpoints that are reached by end
+// users rather than operators, such as account confirmation links and password
+// resets. It listens separately from the management API so that the management
+// API never has to be exposed to users.
+func NewPublicAPI(
+	cfg config.Config,
+	accountConfirmer AccountConfirmer,
+	passwordResetter PasswordResetter,
+	sessionRetriever SessionRetriever,
+	buddyBroadcaster BuddyBroadcaster,
+	logger *slog.Logger,
+) *Server {
+	mux := http.NewServeMux()
+
+	// Handlers for '/confirm' route
+	mux.HandleFunc("GET /confirm", func(w http.ResponseWriter, r *http.Request) {
+		getConfirmHandler(w, r)
+	})
+	mux.HandleFunc("POST /confirm", func(w http.ResponseWriter, r *http.Request) {
+		postConfirmHandler(w, r, accountConfirmer, sessionRetriever, buddyBroadcaster, logger)
+	})
+
+	// Handlers for '/password-reset' route
+	mux.HandleFunc("POST /password-reset", func(w http.ResponseWriter, r *http.Request) {
+		postPasswordResetHandler(w, r, passwordResetter, logger)
+	})
+
+	return &Server{
+		Server: http.Server{
+			Addr:    net.JoinHostPort(cfg.PublicApiHost, cfg.PublicApiPort),
+			Handler: mux,
+		},
+		Logger: logger,
+		name:   "public API",
+	}
+}
+
+// getConfirmHandler handles the GET /confirm endpoint, which users reach by
+// following the link in an account confirmation email. It shows a form that
+// submits the token to POST /confirm. The account isn't confirmed here, so
+// that mail scanners that follow links can't confirm accounts.
+func getConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		renderConfirmPage(w, http.StatusBadRequest, confirmPageData{Message: "The confirmation link is missing its token."})
+		return
+	}
+	renderConfirmPage(w, http.StatusOK, confirmPageData{Token: token})
+}
+
+// postConfirmHandler handles the POST /confirm endpoint. It confirms the
+// account that owns the token. If the user is online, their buddies are told
+// that the account is no longer unconfirmed.
+func postConfirmHandler(w http.ResponseWriter, r *http.Request, accountConfirmer AccountConfirmer, sessionRetriever SessionRetriever, buddyBroadcaster BuddyBroadcaster, logger *slog.Logger) {
+	token := r.PostFormValue("token")
+	if token == "" {
+		renderConfirmPage(w, http.StatusBadRequest, confirmPageData{Message: "The confirmation link is missing its token."})
+		return
+	}
+
+	screenName, err := accountConfirmer.ConfirmAccountByToken(token)
+	if err != nil {
+		if errors.Is(err, state.ErrConfirmTokenNotFound) {
+			renderConfirmPage(w, http.StatusNotFound, confirmPageData{Message: "The confirmation link is invalid, expired, or already used."})
+			return
+		}
+		logger.Error("error in POST /confirm", "err", err.Error())
+		renderConfirmPage(w, http.StatusInternalServerError, confirmPageData{Message: "Your account could not be confirmed. Try again later."})
+		return
+	}
+
+	logger.Info("account confirmed via email", "screen_name", screenName.String())
+
+	if sess := sessionRetriever.RetrieveSession(screenName); sess != nil {
+		sess.ClearUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+		if err := buddyBroadcaster.BroadcastBuddyArrived(r.Context(), sess); err != nil {
+			// the account is confirmed regardless, so don't fail the request
+			logger.Error("error in POST /confirm", "err", err.Error())
+		}
+	}
+
+	renderConfirmPage(w, http.StatusOK, confirmPageData{Message: "Your account is confirmed."})
+}
+
+// postPasswordResetHandler handles the POST /password-reset endpoint. It sets
+// a new password on the account that owns the password reset token, which
+// completes the reset and invalidates the token.
+func postPasswordResetHandler(w http.ResponseWriter, r *http.Request, passwordResetter PasswordResetter, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input passwordResetCompletion
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorMsg(w, "malformed input", http.StatusBadRequest, errCodeMalformedInput)
+		return
+	}
+	if input.Token == "" {
+		errorMsg(w, "token is required", http.StatusBadRequest, errCodeInvalidInput)
+		return
+	}
+
+	screenName, err := passwordResetter.ResetPasswordByToken(input.Token, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, state.ErrPasswordResetTokenNotFound):
+			errorMsg(w, "password reset token not found", http.StatusNotFound, errCodePasswordResetTokenNotFound)
+		case errors.Is(err, state.ErrPasswordInvalid):
+			errorMsgDetails(w, "invalid password", err.Error(), http.StatusBadRequest, errCodeInvalidInput)
+		default:
+			logger.Error("error in POST /password-reset", "err", err.Error())
+			errorMsg(w, "internal server error", http.StatusInternalServerError, errCodeInternal)
+		}
+		return
+	}
+
+	logger.Info("password reset completed", "screen_name", screenName.String())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// renderConfirmPage writes confirmPage with the given status code.
+func renderConfirmPage(w http.ResponseWriter, statusCode int, data confirmPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_ = confirmPage.Execute(w, data)
+}
@@ -0,0 +1,315 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+func TestConfirmHandler_GET(t *testing.T) {
+	tt := []struct {
+		name       string
+		token      string
+		want       string
+		statusCode int
+	}{
+		{
+			name:       "show form that submits the token",
+			token:      "the-token",
+			want:       `<input type="hidden" name="token" value="the-token">`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "escape token",
+			token:      `"><script>`,
+			want:       `value="&#34;&gt;&lt;script&gt;"`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "missing token",
+			want:       "The confirmation link is missing its token.",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/confirm?token="+url.QueryEscape(tc.token), nil)
+			responseRecorder := httptest.NewRecorder()
+
+			getConfirmHandler(responseRecorder, request)
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, "text/html; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+			assert.Contains(t, responseRecorder.Body.String(), tc.want)
+		})
+	}
+}
+
+func TestConfirmHandler_POST(t *testing.T) {
+	newSess := func() *state.Session {
+		sess := state.NewSession()
+		sess.SetIdentScreenName(state.NewIdentScreenName("userA"))
+		sess.SetUserInfoFlag(wire.OServiceUserFlagUnconfirmed)
+		return sess
+	}
+
+	tt := []struct {
+		name       string
+		token      string
+		sess       *state.Session
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "confirm account of online user and notify buddies",
+			token:      "the-token",
+			sess:       newSess(),
+			want:       "Your account is confirmed.",
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				accountConfirmerParams: accountConfirmerParams{
+					confirmAccountByTokenParams: confirmAccountByTokenParams{
+						{
+							token:  "the-token",
+							result: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+				buddyBroadcasterParams: buddyBroadcasterParams{
+					broadcastBuddyArrivedParams: broadcastBuddyArrivedParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "confirm account of offline user",
+			token:      "the-token",
+			want:       "Your account is confirmed.",
+			statusCode: http.StatusOK,
+			mockParams: mockParams{
+				accountConfirmerParams: accountConfirmerParams{
+					confirmAccountByTokenParams: confirmAccountByTokenParams{
+						{
+							token:  "the-token",
+							result: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+				sessionRetrieverParams: sessionRetrieverParams{
+					retrieveSessionByNameParams: retrieveSessionByNameParams{
+						{
+							screenName: state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "token not found",
+			token:      "the-token",
+			want:       "The confirmation link is invalid, expired, or already used.",
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				accountConfirmerParams: accountConfirmerParams{
+					confirmAccountByTokenParams: confirmAccountByTokenParams{
+						{
+							token: "the-token",
+							err:   state.ErrConfirmTokenNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "runtime error",
+			token:      "the-token",
+			want:       "Your account could not be confirmed. Try again later.",
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				accountConfirmerParams: accountConfirmerParams{
+					confirmAccountByTokenParams: confirmAccountByTokenParams{
+						{
+							token: "the-token",
+							err:   errors.New("error confirming account"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "missing token",
+			want:       "The confirmation link is missing its token.",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			form := url.Values{"token": {tc.token}}
+			request := httptest.NewRequest(http.MethodPost, "/confirm", strings.NewReader(form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			responseRecorder := httptest.NewRecorder()
+
+			accountConfirmer := newMockAccountConfirmer(t)
+			for _, params := range tc.mockParams.confirmAccountByTokenParams {
+				accountConfirmer.EXPECT().
+					ConfirmAccountByToken(params.token).
+					Return(params.result, params.err)
+			}
+			sessionRetriever := newMockSessionRetriever(t)
+			for _, params := range tc.mockParams.sessionRetrieverParams.retrieveSessionByNameParams {
+				sessionRetriever.EXPECT().
+					RetrieveSession(params.screenName).
+					Return(tc.sess)
+			}
+			buddyBroadcaster := newMockBuddyBroadcaster(t)
+			for _, params := range tc.mockParams.buddyBroadcasterParams.broadcastBuddyArrivedParams {
+				buddyBroadcaster.EXPECT().
+					BroadcastBuddyArrived(mock.Anything, mock.MatchedBy(func(s *state.Session) bool {
+						return s.IdentScreenName() == params.screenName
+					})).
+					Return(params.err)
+			}
+
+			postConfirmHandler(responseRecorder, request, accountConfirmer, sessionRetriever, buddyBroadcaster, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), tc.want)
+			if tc.sess != nil {
+				assert.Zero(t, tc.sess.UserInfoBitmask()&wire.OServiceUserFlagUnconfirmed)
+			}
+		})
+	}
+}
+
+func TestPasswordResetHandler_POST(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		want       string
+		statusCode int
+		mockParams mockParams
+	}{
+		{
+			name:       "complete password reset",
+			body:       `{"token":"the-token","password":"newpass"}`,
+			statusCode: http.StatusNoContent,
+			mockParams: mockParams{
+				passwordResetterParams: passwordResetterParams{
+					resetPasswordByTokenParams: resetPasswordByTokenParams{
+						{
+							token:       "the-token",
+							newPassword: "newpass",
+							result:      state.NewIdentScreenName("userA"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "malformed input",
+			body:       `{"token":`,
+			want:       `{"error":"malformed input","code":"malformed_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "missing token",
+			body:       `{"password":"newpass"}`,
+			want:       `{"error":"token is required","code":"invalid_input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "token not found",
+			body:       `{"token":"the-token","password":"newpass"}`,
+			want:       `{"error":"password reset token not found","code":"password_reset_token_not_found"}`,
+			statusCode: http.StatusNotFound,
+			mockParams: mockParams{
+				passwordResetterParams: passwordResetterParams{
+					resetPasswordByTokenParams: resetPasswordByTokenParams{
+						{
+							token:       "the-token",
+							newPassword: "newpass",
+							err:         state.ErrPasswordResetTokenNotFound,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "invalid password",
+			body:       `{"token":"the-token","password":"no"}`,
+			want:       `{"error":"invalid password","code":"invalid_input","details":"invalid password length"}`,
+			statusCode: http.StatusBadRequest,
+			mockParams: mockParams{
+				passwordResetterParams: passwordResetterParams{
+					resetPasswordByTokenParams: resetPasswordByTokenParams{
+						{
+							token:       "the-token",
+							newPassword: "no",
+							err:         state.ErrPasswordInvalid,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "user manager returns runtime error",
+			body:       `{"token":"the-token","password":"newpass"}`,
+			want:       `{"error":"internal server error","code":"internal_error"}`,
+			statusCode: http.StatusInternalServerError,
+			mockParams: mockParams{
+				passwordResetterParams: passwordResetterParams{
+					resetPasswordByTokenParams: resetPasswordByTokenParams{
+						{
+							token:       "the-token",
+							newPassword: "newpass",
+							err:         io.EOF,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/password-reset", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			passwordResetter := newMockPasswordResetter(t)
+			for _, params := range tc.mockParams.passwordResetterParams.resetPasswordByTokenParams {
+				passwordResetter.EXPECT().
+					ResetPasswordByToken(params.token, params.newPassword).
+					Return(params.result, params.err)
+			}
+
+			postPasswordResetHandler(responseRecorder, request, passwordResetter, slog.Default())
+
+			assert.Equal(t, tc.statusCode, responseRecorder.Code)
+			assert.Equal(t, tc.want, strings.TrimSpace(responseRecorder.Body.String()))
+		})
+	}
+}
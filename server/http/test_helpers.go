@@ -2,21 +2,58 @@ package http
 
 import (
 	"net/mail"
+	"net/netip"
+	"time"
 
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
 type mockParams struct {
+	accountConfirmerParams
 	accountRetrieverParams
+	awayTemplateManagerParams
 	bartRetrieverParams
+	buddyBroadcasterParams
+	chatModeratorManagerParams
 	chatRoomRetrieverParams
 	chatSessionRetrieverParams
+	chatSlowModeSetterParams
+	chatSpectatorManagerParams
+	chatTranscriptRetrieverParams
 	directoryManagerParams
 	feedBagRetrieverParams
+	feedbagManagerParams
+	messageRelayerParams
+	offlineMessageManagerParams
+	passwordResetterParams
 	profileRetrieverParams
+	profileUpdaterParams
 	sessionRetrieverParams
+	sharedGroupManagerParams
+	uinAllocatorParams
 	userManagerParams
+	virtualUserManagerParams
+}
+
+// accountConfirmerParams is a helper struct that contains mock parameters for
+// AccountConfirmer methods
+type accountConfirmerParams struct {
+	confirmAccountByTokenParams
+}
+
+// confirmAccountByTokenParams is the list of parameters passed at the mock
+// AccountConfirmer.ConfirmAccountByToken call site
+type confirmAccountByTokenParams []struct {
+	token  string
+	result state.IdentScreenName
+	err    error
+}
+
+// passwordResetterParams is a helper struct that contains mock parameters for
+// PasswordResetter methods
+type passwordResetterParams struct {
+	resetPasswordByTokenParams
 }
 
 // accountRetrieverParams is a helper struct that contains mock parameters for
@@ -51,6 +88,44 @@ type confirmStatusByNameParams []struct {
 	err        error
 }
 
+// awayTemplateManagerParams is a helper struct that contains mock parameters
+// for AwayTemplateManager methods
+type awayTemplateManagerParams struct {
+	awayTemplateParams
+	awayTemplatesParams
+	deleteAwayTemplateParams
+	setAwayTemplateParams
+}
+
+// awayTemplateParams is the list of parameters passed at the mock
+// AwayTemplateManager.AwayTemplate call site
+type awayTemplateParams []struct {
+	name   string
+	result state.AwayTemplate
+	err    error
+}
+
+// awayTemplatesParams is the list of parameters passed at the mock
+// AwayTemplateManager.AwayTemplates call site
+type awayTemplatesParams []struct {
+	result []state.AwayTemplate
+	err    error
+}
+
+// deleteAwayTemplateParams is the list of parameters passed at the mock
+// AwayTemplateManager.DeleteAwayTemplate call site
+type deleteAwayTemplateParams []struct {
+	name string
+	err  error
+}
+
+// setAwayTemplateParams is the list of parameters passed at the mock
+// AwayTemplateManager.SetAwayTemplate call site
+type setAwayTemplateParams []struct {
+	template state.AwayTemplate
+	err      error
+}
+
 // bartRetrieverParams is a helper struct that contains mock parameters for
 // BARTRetriever methods
 type bartRetrieverParams struct {
@@ -65,10 +140,24 @@ type bartRetrieveParams []struct {
 	err      error
 }
 
+// buddyBroadcasterParams is a helper struct that contains mock parameters for
+// BuddyBroadcaster methods
+type buddyBroadcasterParams struct {
+	broadcastBuddyArrivedParams
+}
+
+// broadcastBuddyArrivedParams is the list of parameters passed at the mock
+// BuddyBroadcaster.BroadcastBuddyArrived call site
+type broadcastBuddyArrivedParams []struct {
+	screenName state.IdentScreenName
+	err        error
+}
+
 // chatRoomRetrieverParams is a helper struct that contains mock parameters for
 // ChatRoomRetriever methods
 type chatRoomRetrieverParams struct {
 	allChatRoomsParams
+	chatRoomByCookieParams
 }
 
 // allChatRoomsParams is the list of parameters passed at the mock
@@ -79,6 +168,131 @@ type allChatRoomsParams []struct {
 	err      error
 }
 
+// chatRoomByCookieParams is the list of parameters passed at the mock
+// ChatRoomRetriever.ChatRoomByCookie call site
+type chatRoomByCookieParams []struct {
+	cookie string
+	result state.ChatRoom
+	err    error
+}
+
+// chatTranscriptRetrieverParams is a helper struct that contains mock
+// parameters for ChatTranscriptRetriever methods
+type chatTranscriptRetrieverParams struct {
+	chatTranscriptParams
+}
+
+// chatTranscriptParams is the list of parameters passed at the mock
+// ChatTranscriptRetriever.ChatTranscript call site
+type chatTranscriptParams []struct {
+	cookie string
+	since  time.Time
+	result []state.ChatTranscriptEntry
+	err    error
+}
+
+// chatSlowModeSetterParams is a helper struct that contains mock parameters
+// for ChatSlowModeSetter methods
+type chatSlowModeSetterParams struct {
+	setSlowModeParams
+}
+
+// setSlowModeParams is the list of parameters passed at the mock
+// ChatSlowModeSetter.SetSlowMode call site
+type setSlowModeParams []struct {
+	cookie   string
+	interval time.Duration
+}
+
+// chatModeratorManagerParams is a helper struct that contains mock
+// parameters for ChatModeratorManager methods
+type chatModeratorManagerParams struct {
+	addChatRoomBanParams
+	addChatRoomModeratorParams
+	chatRoomBansParams
+	chatRoomModeratorsParams
+	removeChatRoomBanParams
+	removeChatRoomModeratorParams
+	setChatRoomTopicParams
+}
+
+// addChatRoomBanParams is the list of parameters passed at the mock
+// ChatModeratorManager.AddChatRoomBan call site
+type addChatRoomBanParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// addChatRoomModeratorParams is the list of parameters passed at the mock
+// ChatModeratorManager.AddChatRoomModerator call site
+type addChatRoomModeratorParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// chatRoomModeratorsParams is the list of parameters passed at the mock
+// ChatModeratorManager.ChatRoomModerators call site
+type chatRoomModeratorsParams []struct {
+	cookie string
+	result []state.IdentScreenName
+	err    error
+}
+
+// chatRoomBansParams is the list of parameters passed at the mock
+// ChatModeratorManager.ChatRoomBans call site
+type chatRoomBansParams []struct {
+	cookie string
+	result []state.IdentScreenName
+	err    error
+}
+
+// removeChatRoomBanParams is the list of parameters passed at the mock
+// ChatModeratorManager.RemoveChatRoomBan call site
+type removeChatRoomBanParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// removeChatRoomModeratorParams is the list of parameters passed at the mock
+// ChatModeratorManager.RemoveChatRoomModerator call site
+type removeChatRoomModeratorParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// setChatRoomTopicParams is the list of parameters passed at the mock
+// ChatModeratorManager.SetChatRoomTopic call site
+type setChatRoomTopicParams []struct {
+	cookie string
+	topic  string
+	err    error
+}
+
+// chatSpectatorManagerParams is a helper struct that contains mock parameters
+// for ChatSpectatorManager methods
+type chatSpectatorManagerParams struct {
+	addSpectatorParams
+	removeSpectatorParams
+}
+
+// addSpectatorParams is the list of parameters passed at the mock
+// ChatSpectatorManager.AddSpectator call site
+type addSpectatorParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+}
+
+// removeSpectatorParams is the list of parameters passed at the mock
+// ChatSpectatorManager.RemoveSpectator call site
+type removeSpectatorParams []struct {
+	cookie     string
+	screenName state.IdentScreenName
+}
+
 // chatRoomRetrieverParams is a helper struct that contains mock parameters for
 // ChatRoomRetriever methods
 type chatSessionRetrieverParams struct {
@@ -161,6 +375,73 @@ type buddyIconRefByNameParams []struct {
 	err        error
 }
 
+// feedbagManagerParams is a helper struct that contains mock parameters for
+// FeedbagManager methods
+type feedbagManagerParams struct {
+	feedbagParams
+	feedbagDeleteParams
+	feedbagReplaceParams
+	feedbagUpsertParams
+}
+
+// feedbagParams is the list of parameters passed at the mock
+// FeedbagManager.Feedbag call site
+type feedbagParams []struct {
+	screenName state.IdentScreenName
+	results    []wire.FeedbagItem
+	err        error
+}
+
+// feedbagDeleteParams is the list of parameters passed at the mock
+// FeedbagManager.FeedbagDelete call site
+type feedbagDeleteParams []struct {
+	screenName state.IdentScreenName
+	items      []wire.FeedbagItem
+	err        error
+}
+
+// feedbagReplaceParams is the list of parameters passed at the mock
+// FeedbagManager.FeedbagReplace call site
+type feedbagReplaceParams []struct {
+	screenName state.IdentScreenName
+	items      []wire.FeedbagItem
+	err        error
+}
+
+// feedbagUpsertParams is the list of parameters passed at the mock
+// FeedbagManager.FeedbagUpsert call site
+type feedbagUpsertParams []struct {
+	screenName state.IdentScreenName
+	items      []wire.FeedbagItem
+	err        error
+}
+
+// messageRelayerParams is a helper struct that contains mock parameters for
+// MessageRelayer methods
+type messageRelayerParams struct {
+	relayToScreenNameParams
+}
+
+// relayToScreenNameParams is the list of parameters passed at the mock
+// MessageRelayer.RelayToScreenName call site
+type relayToScreenNameParams []struct {
+	screenName state.IdentScreenName
+	message    wire.SNACMessage
+}
+
+// offlineMessageManagerParams is a helper struct that contains mock
+// parameters for OfflineMessageManager methods
+type offlineMessageManagerParams struct {
+	saveMessageParams
+}
+
+// saveMessageParams is the list of parameters passed at the mock
+// OfflineMessageManager.SaveMessage call site
+type saveMessageParams []struct {
+	offlineMessage state.OfflineMessage
+	err            error
+}
+
 // profileRetrieverParams is a helper struct that contains mock parameters for
 // ProfileRetriever methods
 type profileRetrieverParams struct {
@@ -175,6 +456,63 @@ type retrieveProfileParams []struct {
 	err        error
 }
 
+// profileUpdaterParams is a helper struct that contains mock parameters for
+// ProfileUpdater methods
+type profileUpdaterParams struct {
+	setProfileParams
+}
+
+// setProfileParams is the list of parameters passed at the mock
+// ProfileUpdater.SetProfile call site
+type setProfileParams []struct {
+	screenName state.IdentScreenName
+	body       string
+	err        error
+}
+
+// sharedGroupManagerParams is a helper struct that contains mock parameters
+// for SharedGroupManager methods
+type sharedGroupManagerParams struct {
+	addSharedGroupMemberParams
+	removeSharedGroupMemberParams
+	sharedGroupMembersParams
+	syncSharedGroupParams
+}
+
+// addSharedGroupMemberParams is the list of parameters passed at the mock
+// SharedGroupManager.AddSharedGroupMember call site
+type addSharedGroupMemberParams []struct {
+	group      string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// removeSharedGroupMemberParams is the list of parameters passed at the mock
+// SharedGroupManager.RemoveSharedGroupMember call site
+type removeSharedGroupMemberParams []struct {
+	group      string
+	screenName state.IdentScreenName
+	err        error
+}
+
+// sharedGroupMembersParams is the list of parameters passed at the mock
+// SharedGroupManager.SharedGroupMembers call site
+type sharedGroupMembersParams []struct {
+	result []state.SharedGroupMember
+	err    error
+}
+
+// syncSharedGroupParams is the list of parameters passed at the mock
+// SharedGroupManager.SyncSharedGroup call site
+type syncSharedGroupParams []struct {
+	owner    state.IdentScreenName
+	group    string
+	inserted []wire.FeedbagItem
+	updated  []wire.FeedbagItem
+	deleted  []wire.FeedbagItem
+	err      error
+}
+
 // sessionRetrieverParams is a helper struct that contains mock parameters for
 // SessionRetriever methods
 type sessionRetrieverParams struct {
@@ -198,11 +536,80 @@ type retrieveSessionByNameParams []struct {
 // userManagerParams is a helper struct that contains mock parameters for
 // UserManager methods
 type userManagerParams struct {
+	addScreenNameAliasParams
 	allUsersParams
+	banUserParams
+	deleteScreenNameAliasParams
 	deleteUserParams
 	getUserParams
 	insertUserParams
+	screenNameAliasesParams
+	setAllowedNetworksParams
+	setCanCreateChatRoomsParams
+	setConcurrentLoginPolicyParams
+	setOfficialParams
+	setPasswordResetTokenParams
+	setStorageQuotaParams
 	setUserPasswordParams
+	setWatchedParams
+	storageUsageParams
+	unbanUserParams
+}
+
+// uinAllocatorParams is a helper struct that contains mock parameters for
+// UINAllocator methods
+type uinAllocatorParams struct {
+	nextUINParams
+}
+
+// nextUINParams is the list of parameters passed at the mock
+// UINAllocator.NextUIN call site
+type nextUINParams []struct {
+	first  uint32
+	last   uint32
+	result uint32
+	err    error
+}
+
+// addScreenNameAliasParams is the list of parameters passed at the mock
+// UserManager.AddScreenNameAlias call site
+type addScreenNameAliasParams []struct {
+	screenName state.IdentScreenName
+	alias      state.IdentScreenName
+	err        error
+}
+
+// resetPasswordByTokenParams is the list of parameters passed at the mock
+// PasswordResetter.ResetPasswordByToken call site
+type resetPasswordByTokenParams []struct {
+	token       string
+	newPassword string
+	result      state.IdentScreenName
+	err         error
+}
+
+// setPasswordResetTokenParams is the list of parameters passed at the mock
+// UserManager.SetPasswordResetToken call site
+type setPasswordResetTokenParams []struct {
+	screenName state.IdentScreenName
+	token      string
+	err        error
+}
+
+// deleteScreenNameAliasParams is the list of parameters passed at the mock
+// UserManager.DeleteScreenNameAlias call site
+type deleteScreenNameAliasParams []struct {
+	screenName state.IdentScreenName
+	alias      state.IdentScreenName
+	err        error
+}
+
+// screenNameAliasesParams is the list of parameters passed at the mock
+// UserManager.ScreenNameAliases call site
+type screenNameAliasesParams []struct {
+	screenName state.IdentScreenName
+	result     []state.IdentScreenName
+	err        error
 }
 
 // allUsersParams is the list of parameters passed at the mock
@@ -212,6 +619,21 @@ type allUsersParams []struct {
 	err    error
 }
 
+// banUserParams is the list of parameters passed at the mock
+// UserManager.BanUser call site
+type banUserParams []struct {
+	screenName state.IdentScreenName
+	reason     string
+	err        error
+}
+
+// unbanUserParams is the list of parameters passed at the mock
+// UserManager.UnbanUser call site
+type unbanUserParams []struct {
+	screenName state.IdentScreenName
+	err        error
+}
+
 // deleteUserParams is the list of parameters passed at the mock
 // UserManager.DeleteUser call site
 type deleteUserParams []struct {
@@ -234,6 +656,62 @@ type insertUserParams []struct {
 	err error
 }
 
+// setCanCreateChatRoomsParams is the list of parameters passed at the mock
+// UserManager.SetCanCreateChatRooms call site
+type setCanCreateChatRoomsParams []struct {
+	screenName state.IdentScreenName
+	allowed    bool
+	err        error
+}
+
+// setAllowedNetworksParams is the list of parameters passed at the mock
+// UserManager.SetAllowedNetworks call site
+type setAllowedNetworksParams []struct {
+	screenName state.IdentScreenName
+	networks   []netip.Prefix
+	err        error
+}
+
+// setConcurrentLoginPolicyParams is the list of parameters passed at the mock
+// UserManager.SetConcurrentLoginPolicy call site
+type setConcurrentLoginPolicyParams []struct {
+	screenName state.IdentScreenName
+	policy     string
+	err        error
+}
+
+// setOfficialParams is the list of parameters passed at the mock
+// UserManager.SetOfficial call site
+type setOfficialParams []struct {
+	screenName state.IdentScreenName
+	official   bool
+	err        error
+}
+
+// setStorageQuotaParams is the list of parameters passed at the mock
+// UserManager.SetStorageQuota call site
+type setStorageQuotaParams []struct {
+	screenName state.IdentScreenName
+	quota      *int64
+	err        error
+}
+
+// storageUsageParams is the list of parameters passed at the mock
+// UserManager.StorageUsage call site
+type storageUsageParams []struct {
+	screenName state.IdentScreenName
+	result     state.StorageUsage
+	err        error
+}
+
+// setWatchedParams is the list of parameters passed at the mock
+// UserManager.SetWatched call site
+type setWatchedParams []struct {
+	screenName state.IdentScreenName
+	watched    bool
+	err        error
+}
+
 // setUserPasswordParams is the list of parameters passed at the mock
 // UserManager.SetUserPassword call site
 type setUserPasswordParams []struct {
@@ -241,3 +719,26 @@ type setUserPasswordParams []struct {
 	newPassword string
 	err         error
 }
+
+// virtualUserManagerParams is a helper struct that contains mock parameters
+// for VirtualUserManager methods
+type virtualUserManagerParams struct {
+	setOfflineParams
+	setOnlineParams
+}
+
+// setOfflineParams is the list of parameters passed at the mock
+// VirtualUserManager.SetOffline call site
+type setOfflineParams []struct {
+	screenName state.IdentScreenName
+	err        error
+}
+
+// setOnlineParams is the list of parameters passed at the mock
+// VirtualUserManager.SetOnline call site
+type setOnlineParams []struct {
+	screenName  state.DisplayScreenName
+	awayMessage string
+	mobile      bool
+	err         error
+}
@@ -3,20 +3,61 @@ package http
 import (
 	"context"
 	"net/mail"
+	"net/netip"
 	"time"
 
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
+type AccountConfirmer interface {
+	ConfirmAccountByToken(token string) (state.IdentScreenName, error)
+}
+
+type AwayTemplateManager interface {
+	AwayTemplate(name string) (state.AwayTemplate, error)
+	AwayTemplates() ([]state.AwayTemplate, error)
+	DeleteAwayTemplate(name string) error
+	SetAwayTemplate(template state.AwayTemplate) error
+}
+
 type ChatRoomRetriever interface {
 	AllChatRooms(exchange uint16) ([]state.ChatRoom, error)
+	ChatRoomByCookie(cookie string) (state.ChatRoom, error)
+}
+
+type ChatSpectatorManager interface {
+	AddSpectator(chatCookie string, screenName state.IdentScreenName)
+	RemoveAllSpectators(chatCookie string)
+	RemoveSpectator(chatCookie string, screenName state.IdentScreenName)
+}
+
+type ChatModeratorManager interface {
+	AddChatRoomBan(cookie string, screenName state.IdentScreenName) error
+	AddChatRoomModerator(cookie string, screenName state.IdentScreenName) error
+	ChatRoomBans(cookie string) ([]state.IdentScreenName, error)
+	ChatRoomModerators(cookie string) ([]state.IdentScreenName, error)
+	RemoveChatRoomBan(cookie string, screenName state.IdentScreenName) error
+	RemoveChatRoomModerator(cookie string, screenName state.IdentScreenName) error
+	SetChatRoomTopic(cookie string, topic string) error
+}
+
+type ChatSlowModeSetter interface {
+	SetSlowMode(cookie string, interval time.Duration)
 }
 
 type ChatRoomCreator interface {
 	CreateChatRoom(chatRoom *state.ChatRoom) error
 }
 
+type ChatRoomDeleter interface {
+	DeleteChatRoom(cookie string) error
+}
+
+type ChatTranscriptRetriever interface {
+	ChatTranscript(cookie string, since time.Time) ([]state.ChatTranscriptEntry, error)
+}
+
 type ChatSessionRetriever interface {
 	AllSessions(cookie string) []*state.Session
 }
@@ -26,14 +67,43 @@ type SessionRetriever interface {
 	RetrieveSession(screenName state.IdentScreenName) *state.Session
 }
 
+type SharedGroupManager interface {
+	AddSharedGroupMember(group string, screenName state.IdentScreenName) error
+	RemoveSharedGroupMember(group string, screenName state.IdentScreenName) error
+	SharedGroupMembers() ([]state.SharedGroupMember, error)
+	SyncSharedGroup(owner state.IdentScreenName, group string) (inserted []wire.FeedbagItem, updated []wire.FeedbagItem, deleted []wire.FeedbagItem, err error)
+}
+
+type TrafficReporter interface {
+	BytesIn() uint64
+	BytesOut() uint64
+}
+
 type UserManager interface {
+	AddScreenNameAlias(screenName state.IdentScreenName, alias state.IdentScreenName) error
 	AllUsers() ([]state.User, error)
+	BanUser(screenName state.IdentScreenName, reason string) error
+	DeleteScreenNameAlias(screenName state.IdentScreenName, alias state.IdentScreenName) error
 	DeleteUser(screenName state.IdentScreenName) error
 	InsertUser(u state.User) error
+	ScreenNameAliases(screenName state.IdentScreenName) ([]state.IdentScreenName, error)
+	SetAllowedNetworks(screenName state.IdentScreenName, networks []netip.Prefix) error
+	SetCanCreateChatRooms(screenName state.IdentScreenName, allowed bool) error
+	SetConcurrentLoginPolicy(screenName state.IdentScreenName, policy string) error
+	SetOfficial(screenName state.IdentScreenName, official bool) error
+	SetPasswordResetToken(screenName state.IdentScreenName, token string, expiresAt time.Time) error
+	SetStorageQuota(screenName state.IdentScreenName, quota *int64) error
 	SetUserPassword(screenName state.IdentScreenName, newPassword string) error
+	SetWatched(screenName state.IdentScreenName, watched bool) error
+	StorageUsage(screenName state.IdentScreenName) (state.StorageUsage, error)
+	UnbanUser(screenName state.IdentScreenName) error
 	User(screenName state.IdentScreenName) (*state.User, error)
 }
 
+type UINAllocator interface {
+	NextUIN(first, last uint32) (uint32, error)
+}
+
 type MessageRelayer interface {
 	RelayToScreenName(ctx context.Context, screenName state.IdentScreenName, msg wire.SNACMessage)
 }
@@ -52,10 +122,33 @@ type FeedBagRetriever interface {
 	BuddyIconRefByName(screenName state.IdentScreenName) (*wire.BARTID, error)
 }
 
+type FeedbagManager interface {
+	Feedbag(screenName state.IdentScreenName) ([]wire.FeedbagItem, error)
+	FeedbagDelete(screenName state.IdentScreenName, items []wire.FeedbagItem) error
+	FeedbagReplace(screenName state.IdentScreenName, items []wire.FeedbagItem) error
+	FeedbagUpsert(screenName state.IdentScreenName, items []wire.FeedbagItem) error
+}
+
+type OfflineMessageManager interface {
+	SaveMessage(offlineMessage state.OfflineMessage) error
+}
+
+type PasswordResetter interface {
+	ResetPasswordByToken(token string, newPassword string) (state.IdentScreenName, error)
+}
+
 type ProfileRetriever interface {
 	Profile(screenName state.IdentScreenName) (string, error)
 }
 
+type ProfileUpdater interface {
+	SetProfile(screenName state.IdentScreenName, body string) error
+}
+
+type BuddyBroadcaster interface {
+	BroadcastBuddyArrived(ctx context.Context, sess *state.Session) error
+}
+
 type DirectoryManager interface {
 	Categories() ([]state.Category, error)
 	CreateCategory(name string) (state.Category, error)
@@ -65,6 +158,13 @@ type DirectoryManager interface {
 	KeywordsByCategory(categoryID uint8) ([]state.Keyword, error)
 }
 
+// VirtualUserManager sets the presence of virtual users, which are contacts
+// bridged from an external system.
+type VirtualUserManager interface {
+	SetOffline(ctx context.Context, screenName state.IdentScreenName) error
+	SetOnline(ctx context.Context, screenName state.DisplayScreenName, awayMessage string, mobile bool) error
+}
+
 type userWithPassword struct {
 	ScreenName string `json:"screen_name"`
 	Password   string `json:"password,omitempty"`
@@ -87,13 +187,19 @@ type aimChatUserHandle struct {
 }
 
 type userAccountHandle struct {
-	ID           string `json:"id"`
-	ScreenName   string `json:"screen_name"`
-	Profile      string `json:"profile"`
-	EmailAddress string `json:"email_address"`
-	RegStatus    uint16 `json:"reg_status"`
-	Confirmed    bool   `json:"confirmed"`
-	IsICQ        bool   `json:"is_icq"`
+	ID                 string `json:"id"`
+	ScreenName         string `json:"screen_name"`
+	Profile            string `json:"profile"`
+	EmailAddress       string `json:"email_address"`
+	RegStatus          uint16 `json:"reg_status"`
+	Confirmed          bool   `json:"confirmed"`
+	IsICQ              bool   `json:"is_icq"`
+	Watched            bool   `json:"watched"`
+	CanCreateChatRooms bool   `json:"can_create_chat_rooms"`
+	Official           bool   `json:"official"`
+	ConcurrentLogin    string `json:"concurrent_login_policy"`
+	Banned             bool   `json:"banned"`
+	BanReason          string `json:"ban_reason"`
 }
 
 type sessionHandle struct {
@@ -103,6 +209,121 @@ type sessionHandle struct {
 	AwayMessage   string  `json:"away_message"`
 	IdleSeconds   float64 `json:"idle_seconds"`
 	IsICQ         bool    `json:"is_icq"`
+	BytesIn       uint64  `json:"bytes_in"`
+	BytesOut      uint64  `json:"bytes_out"`
+	KeepAlivesIn  uint64  `json:"keepalives_in"`
+}
+
+type serverMetrics struct {
+	OnlineUsers int    `json:"online_users"`
+	BytesIn     uint64 `json:"bytes_in"`
+	BytesOut    uint64 `json:"bytes_out"`
+}
+
+type userProfile struct {
+	Profile string `json:"profile"`
+}
+
+type userWatch struct {
+	Watched bool `json:"watched"`
+}
+
+type userChatRoomCreator struct {
+	CanCreateChatRooms bool `json:"can_create_chat_rooms"`
+}
+
+type userOfficial struct {
+	Official bool `json:"official"`
+}
+
+type userConcurrentLogin struct {
+	Policy string `json:"policy"`
+}
+
+type userBan struct {
+	Reason string `json:"reason"`
+}
+
+type userAliases struct {
+	Aliases []string `json:"aliases"`
+}
+
+type userAllowedNetworks struct {
+	AllowedNetworks []string `json:"allowed_networks"`
+}
+
+type userStorageHandle struct {
+	OfflineMessageBytes int64 `json:"offline_message_bytes"`
+	ProfileBytes        int64 `json:"profile_bytes"`
+	FeedbagBytes        int64 `json:"feedbag_bytes"`
+	UsedBytes           int64 `json:"used_bytes"`
+	QuotaBytes          int64 `json:"quota_bytes"`
+}
+
+type userStorageQuota struct {
+	QuotaBytes *int64 `json:"quota_bytes"`
+}
+
+type userAwayMessage struct {
+	AwayMessage string `json:"away_message"`
+}
+
+type virtualUserPresence struct {
+	Online      bool   `json:"online"`
+	AwayMessage string `json:"away_message"`
+	Mobile      bool   `json:"mobile"`
+}
+
+type passwordResetToken struct {
+	ScreenName string `json:"screen_name"`
+	Token      string `json:"token"`
+}
+
+type passwordResetCompletion struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type awayTemplate struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+type awayTemplateUpdate struct {
+	Message string `json:"message"`
+}
+
+type sharedGroup struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type awayTemplateApply struct {
+	Name string `json:"name"`
+}
+
+type buddyCreate struct {
+	Group string `json:"group"`
+}
+
+// feedbag is a user's server-side buddy list as exported and imported by the
+// management API.
+type feedbag struct {
+	Items []feedbagItem `json:"items"`
+}
+
+type feedbagItem struct {
+	GroupID    uint16             `json:"group_id"`
+	ItemID     uint16             `json:"item_id"`
+	ClassID    uint16             `json:"class_id"`
+	Name       string             `json:"name"`
+	Attributes []feedbagAttribute `json:"attributes"`
+}
+
+// feedbagAttribute is a feedbag item TLV. The value is base64-encoded in JSON.
+type feedbagAttribute struct {
+	Tag   uint16 `json:"tag"`
+	Value []byte `json:"value"`
 }
 
 type chatRoomCreate struct {
@@ -117,12 +338,65 @@ type chatRoom struct {
 	Participants []aimChatUserHandle `json:"participants"`
 }
 
+type chatRoomSlowMode struct {
+	Seconds int `json:"seconds"`
+}
+
+type chatRoomSpectator struct {
+	ScreenName string `json:"screen_name"`
+}
+
+type chatRoomModerator struct {
+	ScreenName string `json:"screen_name"`
+}
+
+type chatRoomBan struct {
+	ScreenName string `json:"screen_name"`
+}
+
+type chatRoomTopic struct {
+	Topic string `json:"topic"`
+}
+
+type chatTranscriptEntry struct {
+	ScreenName string    `json:"screen_name"`
+	Text       string    `json:"text"`
+	Sent       time.Time `json:"sent"`
+}
+
 type instantMessage struct {
 	From string `json:"from"`
 	To   string `json:"to"`
 	Text string `json:"text"`
 }
 
+type icqAlert struct {
+	Message  string `json:"message"`
+	Critical bool   `json:"critical"`
+}
+
+type icqBroadcastResult struct {
+	Sent    int `json:"sent"`
+	Stored  int `json:"stored"`
+	Skipped int `json:"skipped"`
+}
+
+type broadcast struct {
+	Message  string `json:"message"`
+	Critical bool   `json:"critical"`
+}
+
+type broadcastResult struct {
+	Sent int `json:"sent"`
+}
+
+type debugSNAC struct {
+	ScreenName string `json:"screen_name"`
+	FoodGroup  uint16 `json:"food_group"`
+	SubGroup   uint16 `json:"sub_group"`
+	HexBody    string `json:"hex_body"`
+}
+
 type directoryKeyword struct {
 	ID   uint8  `json:"id"`
 	Name string `json:"name"`
@@ -141,7 +415,3 @@ type directoryKeywordCreate struct {
 	CategoryID uint8  `json:"category_id"`
 	Name       string `json:"name"`
 }
-
-type messageBody struct {
-	Message string `json:"message"`
-}
@@ -3,6 +3,7 @@ package oscar
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -21,9 +22,15 @@ import (
 type AdminServer struct {
 	AuthService
 	Handler
+	// Capture, if set, records client traffic to a capture file.
+	Capture    *FrameCapture
 	ListenAddr string
 	Logger     *slog.Logger
 	OnlineNotifier
+	// TLSConfig, if set, makes the server accept TLS connections.
+	TLSConfig *tls.Config
+	// Traffic accumulates bytes sent and received across all connections
+	Traffic *state.TrafficCounter
 	config.Config
 }
 
@@ -35,6 +42,9 @@ func (rt AdminServer) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to start admin server: %w", err)
 	}
+	if rt.TLSConfig != nil {
+		listener = tls.NewListener(listener, rt.TLSConfig)
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -75,6 +85,14 @@ func (rt AdminServer) Start(ctx context.Context) error {
 }
 
 func (rt AdminServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser) error {
+	var captured *capturedConn
+	if rt.Capture != nil {
+		captured = rt.Capture.newConn(rwc)
+		rwc = captured
+	}
+	conn := newMeteredConn(rwc, rt.Traffic)
+	rwc = conn
+
 	flapc := wire.NewFlapClient(100, rwc, rwc)
 
 	if err := flapc.SendSignonFrame(nil); err != nil {
@@ -94,6 +112,8 @@ func (rt AdminServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteC
 	if sess == nil {
 		return errors.New("session not found")
 	}
+	conn.attach(sess.Traffic())
+	captured.attach(sess.IdentScreenName())
 
 	defer func() {
 		rwc.Close()
@@ -167,7 +187,8 @@ func dispatchIncomingMessagesSimple(ctx context.Context, sess *state.Session, fl
 				logger.InfoContext(ctx, "got FLAPFrameSignoff", "flap", flap)
 				return nil
 			case wire.FLAPFrameKeepAlive:
-				logger.DebugContext(ctx, "keepalive heartbeat")
+				// count the keepalive without dispatching or logging it
+				sess.Traffic().AddKeepAlive()
 			default:
 				return fmt.Errorf("got unknown FLAP frame type. flap: %v", flap)
 			}
@@ -2,11 +2,13 @@ package oscar
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"net/netip"
 	"sync"
 
 	"github.com/mk6i/retro-aim-server/config"
@@ -18,8 +20,8 @@ import (
 
 type AuthService interface {
 	BUCPChallenge(bodyIn wire.SNAC_0x17_0x06_BUCPChallengeRequest, newUUID func() uuid.UUID) (wire.SNACMessage, error)
-	BUCPLogin(bodyIn wire.SNAC_0x17_0x02_BUCPLoginRequest, newUserFn func(screenName state.DisplayScreenName) (state.User, error)) (wire.SNACMessage, error)
-	FLAPLogin(frame wire.FLAPSignonFrame, newUserFn func(screenName state.DisplayScreenName) (state.User, error)) (wire.TLVRestBlock, error)
+	BUCPLogin(bodyIn wire.SNAC_0x17_0x02_BUCPLoginRequest, newUserFn func(screenName state.DisplayScreenName) (state.User, error), remoteAddr netip.Addr) (wire.SNACMessage, error)
+	FLAPLogin(frame wire.FLAPSignonFrame, newUserFn func(screenName state.DisplayScreenName) (state.User, error), remoteAddr netip.Addr) (wire.TLVRestBlock, error)
 	RegisterBOSSession(ctx context.Context, authCookie []byte) (*state.Session, error)
 	RetrieveBOSSession(authCookie []byte) (*state.Session, error)
 	RegisterChatSession(authCookie []byte) (*state.Session, error)
@@ -33,6 +35,8 @@ type AuthServer struct {
 	AuthService
 	config.Config
 	Logger *slog.Logger
+	// TLSConfig, if set, makes the server accept TLS connections.
+	TLSConfig *tls.Config
 }
 
 // Start starts the authentication server and listens for new connections.
@@ -42,6 +46,9 @@ func (rt AuthServer) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to start auth server: %w", err)
 	}
+	if rt.TLSConfig != nil {
+		listener = tls.NewListener(listener, rt.TLSConfig)
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -66,7 +73,7 @@ func (rt AuthServer) Start(ctx context.Context) error {
 			defer wg.Done()
 			connCtx := context.WithValue(ctx, "ip", conn.RemoteAddr().String())
 			rt.Logger.DebugContext(connCtx, "accepted connection")
-			if err := rt.handleNewConnection(conn); err != nil {
+			if err := rt.handleNewConnection(conn, remoteIP(conn)); err != nil {
 				rt.Logger.Info("user session failed", "err", err.Error())
 			}
 		}()
@@ -81,7 +88,16 @@ func (rt AuthServer) Start(ctx context.Context) error {
 	return nil
 }
 
-func (rt AuthServer) handleNewConnection(rwc io.ReadWriteCloser) error {
+// remoteIP returns the IP address of the client at the other end of conn.
+func remoteIP(conn net.Conn) netip.Addr {
+	addrPort, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addrPort.Addr().Unmap()
+}
+
+func (rt AuthServer) handleNewConnection(rwc io.ReadWriteCloser, remoteAddr netip.Addr) error {
 	defer rwc.Close()
 
 	flapc := wire.NewFlapClient(100, rwc, rwc)
@@ -99,21 +115,21 @@ func (rt AuthServer) handleNewConnection(rwc io.ReadWriteCloser) error {
 	// indicator of FLAP-auth because older ICQ clients appear to omit the
 	// roasted password TLV when the password is not stored client-side.
 	if _, hasScreenName := signonFrame.Uint16BE(wire.LoginTLVTagsScreenName); hasScreenName {
-		return rt.processFLAPAuth(signonFrame, flapc)
+		return rt.processFLAPAuth(signonFrame, flapc, remoteAddr)
 	}
 
-	return rt.processBUCPAuth(flapc, err)
+	return rt.processBUCPAuth(flapc, remoteAddr)
 }
 
-func (rt AuthServer) processFLAPAuth(signonFrame wire.FLAPSignonFrame, flapc *wire.FlapClient) error {
-	tlv, err := rt.AuthService.FLAPLogin(signonFrame, state.NewStubUser)
+func (rt AuthServer) processFLAPAuth(signonFrame wire.FLAPSignonFrame, flapc *wire.FlapClient, remoteAddr netip.Addr) error {
+	tlv, err := rt.AuthService.FLAPLogin(signonFrame, state.NewStubUser, remoteAddr)
 	if err != nil {
 		return err
 	}
 	return flapc.SendSignoffFrame(tlv)
 }
 
-func (rt AuthServer) processBUCPAuth(flapc *wire.FlapClient, err error) error {
+func (rt AuthServer) processBUCPAuth(flapc *wire.FlapClient, remoteAddr netip.Addr) error {
 	challengeRequest := wire.SNAC_0x17_0x06_BUCPChallengeRequest{}
 	if err := flapc.ReceiveSNAC(&wire.SNACFrame{}, &challengeRequest); err != nil {
 		return err
@@ -138,7 +154,7 @@ func (rt AuthServer) processBUCPAuth(flapc *wire.FlapClient, err error) error {
 		return err
 	}
 
-	outSNAC, err = rt.BUCPLogin(loginRequest, state.NewStubUser)
+	outSNAC, err = rt.BUCPLogin(loginRequest, state.NewStubUser, remoteAddr)
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"log/slog"
+	"net/netip"
 	"testing"
 
 	"github.com/mk6i/retro-aim-server/wire"
@@ -76,7 +77,7 @@ func TestBUCPAuthService_handleNewConnection(t *testing.T) {
 			Body: wire.SNAC_0x17_0x07_BUCPChallengeResponse{},
 		}, nil)
 	authService.EXPECT().
-		BUCPLogin(mock.Anything, mock.Anything).
+		BUCPLogin(mock.Anything, mock.Anything, netip.MustParseAddr("203.0.113.7")).
 		Return(wire.SNACMessage{
 			Frame: wire.SNACFrame{
 				FoodGroup: wire.BUCP,
@@ -93,5 +94,5 @@ func TestBUCPAuthService_handleNewConnection(t *testing.T) {
 		PipeReader: clientReader,
 		PipeWriter: clientWriter,
 	}
-	assert.NoError(t, rt.handleNewConnection(rwc))
+	assert.NoError(t, rt.handleNewConnection(rwc, netip.MustParseAddr("203.0.113.7")))
 }
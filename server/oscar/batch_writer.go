@@ -0,0 +1,78 @@
+package oscar
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// newBatchWriter creates a new instance of batchWriter that flushes buffered
+// writes to w no later than interval after the first buffered write.
+func newBatchWriter(w io.Writer, interval time.Duration) *batchWriter {
+	return &batchWriter{
+		interval: interval,
+		w:        w,
+	}
+}
+
+// batchWriter coalesces consecutive writes, such as a burst of FLAP frames
+// during chat fan-out, into fewer writes to the underlying connection. Data
+// is written in the same order it was received. Buffered data is flushed by a
+// timer, so that a connection never sits idle with unsent data. It is safe to
+// use with multiple goroutines.
+type batchWriter struct {
+	buf      bytes.Buffer
+	err      error
+	interval time.Duration
+	mutex    sync.Mutex
+	timer    *time.Timer
+	w        io.Writer
+}
+
+// Write buffers p and schedules a flush. It returns the error from a previous
+// failed flush, if any.
+func (b *batchWriter) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, func() {
+			_ = b.Flush()
+		})
+	}
+	return b.buf.Write(p)
+}
+
+// Flush writes all buffered data to the underlying writer.
+func (b *batchWriter) Flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if b.err != nil || b.buf.Len() == 0 {
+		return b.err
+	}
+	if _, err := b.w.Write(b.buf.Bytes()); err != nil {
+		b.err = err
+	}
+	b.buf.Reset()
+	return b.err
+}
+
+// outboundWriter returns a writer for sending FLAP frames to the client. If
+// batching is enabled, writes are coalesced by a batchWriter and the returned
+// flush function must be called before the connection closes.
+func outboundWriter(w io.Writer, batchMs int) (io.Writer, func() error) {
+	if batchMs <= 0 {
+		return w, func() error { return nil }
+	}
+	bw := newBatchWriter(w, time.Duration(batchMs)*time.Millisecond)
+	return bw, bw.Flush
+}
@@ -0,0 +1,122 @@
+package oscar
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// countingWriter counts the number of writes made to the underlying writer.
+type countingWriter struct {
+	buf    bytes.Buffer
+	mutex  sync.Mutex
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *countingWriter) String() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.buf.String()
+}
+
+func TestBatchWriter_CoalescesWritesInOrder(t *testing.T) {
+	cw := &countingWriter{}
+	bw := newBatchWriter(cw, time.Hour)
+
+	for _, s := range []string{"a", "b", "c"} {
+		_, err := bw.Write([]byte(s))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 0, cw.writes)
+
+	assert.NoError(t, bw.Flush())
+	assert.Equal(t, 1, cw.writes)
+	assert.Equal(t, "abc", cw.String())
+}
+
+func TestBatchWriter_FlushesOnTimer(t *testing.T) {
+	cw := &countingWriter{}
+	bw := newBatchWriter(cw, time.Millisecond)
+
+	_, err := bw.Write([]byte("abc"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return cw.String() == "abc"
+	}, time.Second, time.Millisecond)
+}
+
+func TestBatchWriter_FlushError(t *testing.T) {
+	r, w := io.Pipe()
+	assert.NoError(t, r.Close())
+
+	bw := newBatchWriter(w, time.Hour)
+	_, err := bw.Write([]byte("abc"))
+	assert.NoError(t, err)
+
+	assert.True(t, errors.Is(bw.Flush(), io.ErrClosedPipe))
+	_, err = bw.Write([]byte("def"))
+	assert.True(t, errors.Is(err, io.ErrClosedPipe))
+}
+
+func TestOutboundWriter(t *testing.T) {
+	cw := &countingWriter{}
+
+	w, flush := outboundWriter(cw, 0)
+	assert.Equal(t, cw, w)
+	assert.NoError(t, flush())
+
+	w, flush = outboundWriter(cw, 10)
+	assert.IsType(t, &batchWriter{}, w)
+	assert.NoError(t, flush())
+}
+
+// benchmarkChatFanOut simulates a client receiving a burst of chat messages
+// and reports the number of writes made to the connection per message.
+func benchmarkChatFanOut(b *testing.B, batchMs int) {
+	cw := &countingWriter{}
+	w, flush := outboundWriter(cw, batchMs)
+	flapc := wire.NewFlapClient(0, nil, w)
+
+	frame := wire.SNACFrame{
+		FoodGroup: wire.Chat,
+		SubGroup:  wire.ChatChannelMsgToClient,
+	}
+	body := wire.SNAC_0x0E_0x06_ChatChannelMsgToClient{
+		Cookie:  1234,
+		Channel: 3,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := flapc.SendSNAC(frame, body); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := flush(); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(cw.writes)/float64(b.N), "writes/op")
+}
+
+func BenchmarkChatFanOut_Unbatched(b *testing.B) {
+	benchmarkChatFanOut(b, 0)
+}
+
+func BenchmarkChatFanOut_Batched(b *testing.B) {
+	benchmarkChatFanOut(b, 5)
+}
@@ -2,11 +2,13 @@ package oscar
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"net/netip"
 	"sync"
 	"time"
 
@@ -37,16 +39,56 @@ type DepartureNotifier interface {
 	BroadcastBuddyDeparted(ctx context.Context, sess *state.Session) error
 }
 
+// ChatSessionCloser is the interface for closing a user's chat room sessions
+// when their BOS connection ends.
+type ChatSessionCloser interface {
+	CloseUserSessions(screenName state.IdentScreenName)
+}
+
+// FileTransferReleaser is the interface for releasing the file transfer slots
+// held by a user when their BOS connection ends.
+type FileTransferReleaser interface {
+	EndUserTransfers(screenName state.IdentScreenName)
+}
+
+// AddrBanList is the interface for checking whether clients are barred from
+// connecting from an IP address.
+type AddrBanList interface {
+	BannedAddr(addr netip.Addr) bool
+}
+
+// SessionDetacher is the interface for signing off one of the sessions of a
+// user who is signed on more than once.
+type SessionDetacher interface {
+	// DetachSession removes sess if the user has other sessions, and reports
+	// whether it did.
+	DetachSession(sess *state.Session) bool
+}
+
 // BOSServer provides client connection lifecycle management for the BOS
 // service.
 type BOSServer struct {
+	AddrBanList
 	AuthService
 	BuddyListRegistry
+	ChatSessionCloser
 	DepartureNotifier
+	FileTransferReleaser
 	Handler
+	SessionDetacher
+	// Capture, if set, records client traffic to a capture file.
+	Capture    *FrameCapture
 	ListenAddr string
 	Logger     *slog.Logger
 	OnlineNotifier
+	// SignonTimeout is how long a client may take to complete the sign-on
+	// sequence by sending OServiceClientOnline before its connection is
+	// closed. 0 disables the timeout.
+	SignonTimeout time.Duration
+	// TLSConfig, if set, makes the server accept TLS connections.
+	TLSConfig *tls.Config
+	// Traffic accumulates bytes sent and received across all connections
+	Traffic *state.TrafficCounter
 	config.Config
 }
 
@@ -58,6 +100,9 @@ func (rt BOSServer) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to start BOS server: %w", err)
 	}
+	if rt.TLSConfig != nil {
+		listener = tls.NewListener(listener, rt.TLSConfig)
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -66,7 +111,9 @@ func (rt BOSServer) Start(ctx context.Context) error {
 
 	rt.Logger.Info("starting server", "listen_host", rt.ListenAddr, "oscar_host", rt.Config.OSCARHost)
 
-	if rt.BuddyListRegistry != nil { // nil check is a hack until server refactor
+	// the registry is shared with the TLS listener, so only the plaintext
+	// listener clears it
+	if rt.BuddyListRegistry != nil && rt.TLSConfig == nil { // nil check is a hack until server refactor
 		if err = rt.BuddyListRegistry.ClearBuddyListRegistry(); err != nil {
 			return fmt.Errorf("unable to clear client-side buddy list: %s", err.Error())
 		}
@@ -88,7 +135,7 @@ func (rt BOSServer) Start(ctx context.Context) error {
 			defer wg.Done()
 			connCtx := context.WithValue(ctx, "ip", conn.RemoteAddr().String())
 			rt.Logger.DebugContext(connCtx, "accepted connection")
-			if err := rt.handleNewConnection(connCtx, conn); err != nil {
+			if err := rt.handleNewConnection(connCtx, conn, remoteIP(conn)); err != nil {
 				rt.Logger.Info("user session failed", "err", err.Error())
 			}
 		}()
@@ -123,10 +170,25 @@ func waitForShutdown(wg *sync.WaitGroup) bool {
 	}
 }
 
-func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser) error {
-	flapc := wire.NewFlapClient(100, rwc, rwc)
+func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser, remoteAddr netip.Addr) error {
+	if rt.AddrBanList != nil && rt.BannedAddr(remoteAddr) {
+		// the address may have been banned after the client logged in
+		rwc.Close()
+		return fmt.Errorf("connection refused from banned address %s", remoteAddr)
+	}
+
+	var captured *capturedConn
+	if rt.Capture != nil {
+		captured = rt.Capture.newConn(rwc)
+		rwc = captured
+	}
+	conn := newMeteredConn(rwc, rt.Traffic)
+	rwc = conn
+
+	w, flush := outboundWriter(rwc, rt.Config.OutboundBatchMs)
+	flapc := wire.NewFlapClient(100, rwc, w)
 
-	if err := flapc.SendSignonFrame(nil); err != nil {
+	if err := flapc.SendSignonFrame(signonTLVs(rt.Config.FLAPCompression)); err != nil {
 		return err
 	}
 	flap, err := flapc.ReceiveSignonFrame()
@@ -146,6 +208,13 @@ func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteClo
 	if sess == nil {
 		return errors.New("session not found")
 	}
+	sess.SetRemoteAddr(remoteAddr)
+	conn.attach(sess.Traffic())
+	captured.attach(sess.IdentScreenName())
+
+	if negotiateCompression(rt.Config.FLAPCompression, flapc, flap) {
+		rt.Logger.DebugContext(ctx, "FLAP compression enabled")
+	}
 
 	if rt.BuddyListRegistry != nil { // nil check is a hack until server refactor
 		if err := rt.BuddyListRegistry.RegisterBuddyList(sess.IdentScreenName()); err != nil {
@@ -155,7 +224,15 @@ func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteClo
 
 	defer func() {
 		sess.Close()
+		if err := flush(); err != nil {
+			rt.Logger.DebugContext(ctx, "unable to flush outbound messages", "err", err.Error())
+		}
 		rwc.Close()
+		if rt.SessionDetacher != nil && rt.SessionDetacher.DetachSession(sess) {
+			// the user is still signed on from another client, so they
+			// haven't departed
+			return
+		}
 		if rt.DepartureNotifier != nil {
 			if err := rt.DepartureNotifier.BroadcastBuddyDeparted(ctx, sess); err != nil {
 				rt.Logger.ErrorContext(ctx, "error sending buddy departure notifications", "err", err.Error())
@@ -170,14 +247,36 @@ func (rt BOSServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteClo
 			}
 		}
 		rt.Signout(ctx, sess)
+		if rt.ChatSessionCloser != nil {
+			// chat connections outlive the BOS connection unless they're
+			// explicitly closed, which would leave the user lingering in
+			// chat rooms they can no longer talk in.
+			rt.ChatSessionCloser.CloseUserSessions(sess.IdentScreenName())
+		}
+		if rt.FileTransferReleaser != nil {
+			// the user's transfers can't go on once they sign off
+			rt.FileTransferReleaser.EndUserTransfers(sess.IdentScreenName())
+		}
 	}()
 
 	ctx = context.WithValue(ctx, "screenName", sess.IdentScreenName())
 
+	if rt.SignonTimeout > 0 {
+		// a client that never completes sign-on holds a session that buddies
+		// can't see, so reap it
+		timer := time.AfterFunc(rt.SignonTimeout, func() {
+			if !sess.SignonComplete() {
+				rt.Logger.InfoContext(ctx, "client didn't complete sign-on in time, closing connection")
+				sess.Close()
+			}
+		})
+		defer timer.Stop()
+	}
+
 	msg := rt.OnlineNotifier.HostOnline()
 	if err := flapc.SendSNAC(msg.Frame, msg.Body); err != nil {
 		return err
 	}
 
-	return dispatchIncomingMessages(ctx, sess, flapc, rwc, rt.Logger, rt.Handler)
+	return dispatchIncomingMessages(ctx, sess, flapc, rwc, rt.Logger, rt.Handler, time.Duration(rt.Config.ServerKeepaliveSec)*time.Second, newRateLimiter(rt.Config))
 }
@@ -5,11 +5,16 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -28,48 +33,52 @@ func (m pipeRWC) Close() error {
 	return m.PipeWriter.Close()
 }
 
+// signonBOSClient plays the client side of a BOS sign-on that sends
+// OServiceClientOnline and then disconnects.
+func signonBOSClient(t *testing.T, serverReader io.Reader, serverWriter io.WriteCloser) {
+	// < receive FLAPSignonFrame
+	flap := wire.FLAPFrame{}
+	assert.NoError(t, wire.UnmarshalBE(&flap, serverReader))
+	flapSignonFrame := wire.FLAPSignonFrame{}
+	assert.NoError(t, wire.UnmarshalBE(&flapSignonFrame, bytes.NewBuffer(flap.Payload)))
+
+	// > send FLAPSignonFrame
+	flapSignonFrame = wire.FLAPSignonFrame{
+		FLAPVersion: 1,
+	}
+	flapSignonFrame.Append(wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("the-cookie")))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(flapSignonFrame, buf))
+	flap = wire.FLAPFrame{
+		StartMarker: 42,
+		FrameType:   wire.FLAPFrameSignon,
+		Payload:     buf.Bytes(),
+	}
+	assert.NoError(t, wire.MarshalBE(flap, serverWriter))
+
+	flapc := wire.NewFlapClient(0, serverReader, serverWriter)
+
+	// < receive SNAC_0x01_0x03_OServiceHostOnline
+	frame := wire.SNACFrame{}
+	body := wire.SNAC_0x01_0x03_OServiceHostOnline{}
+	assert.NoError(t, flapc.ReceiveSNAC(&frame, &body))
+
+	// send the first request that should get relayed to BOSRouter.Handle
+	frame = wire.SNACFrame{
+		FoodGroup: wire.OService,
+		SubGroup:  wire.OServiceClientOnline,
+	}
+	assert.NoError(t, flapc.SendSNAC(frame, struct{}{}))
+	assert.NoError(t, serverWriter.Close())
+}
+
 func TestBOSService_handleNewConnection(t *testing.T) {
 	sess := state.NewSession()
 
 	clientReader, serverWriter := io.Pipe()
 	serverReader, clientWriter := io.Pipe()
 
-	go func() {
-		// < receive FLAPSignonFrame
-		flap := wire.FLAPFrame{}
-		assert.NoError(t, wire.UnmarshalBE(&flap, serverReader))
-		flapSignonFrame := wire.FLAPSignonFrame{}
-		assert.NoError(t, wire.UnmarshalBE(&flapSignonFrame, bytes.NewBuffer(flap.Payload)))
-
-		// > send FLAPSignonFrame
-		flapSignonFrame = wire.FLAPSignonFrame{
-			FLAPVersion: 1,
-		}
-		flapSignonFrame.Append(wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("the-cookie")))
-		buf := &bytes.Buffer{}
-		assert.NoError(t, wire.MarshalBE(flapSignonFrame, buf))
-		flap = wire.FLAPFrame{
-			StartMarker: 42,
-			FrameType:   wire.FLAPFrameSignon,
-			Payload:     buf.Bytes(),
-		}
-		assert.NoError(t, wire.MarshalBE(flap, serverWriter))
-
-		flapc := wire.NewFlapClient(0, serverReader, serverWriter)
-
-		// < receive SNAC_0x01_0x03_OServiceHostOnline
-		frame := wire.SNACFrame{}
-		body := wire.SNAC_0x01_0x03_OServiceHostOnline{}
-		assert.NoError(t, flapc.ReceiveSNAC(&frame, &body))
-
-		// send the first request that should get relayed to BOSRouter.Handle
-		frame = wire.SNACFrame{
-			FoodGroup: wire.OService,
-			SubGroup:  wire.OServiceClientOnline,
-		}
-		assert.NoError(t, flapc.SendSNAC(frame, struct{}{}))
-		assert.NoError(t, serverWriter.Close())
-	}()
+	go signonBOSClient(t, serverReader, serverWriter)
 
 	authService := newMockAuthService(t)
 	authService.EXPECT().
@@ -78,6 +87,10 @@ func TestBOSService_handleNewConnection(t *testing.T) {
 	authService.EXPECT().
 		Signout(mock.Anything, sess)
 
+	chatSessionCloser := newMockChatSessionCloser(t)
+	chatSessionCloser.EXPECT().
+		CloseUserSessions(sess.IdentScreenName())
+
 	onlineNotifier := newMockOnlineNotifier(t)
 	onlineNotifier.EXPECT().
 		HostOnline().
@@ -99,15 +112,374 @@ func TestBOSService_handleNewConnection(t *testing.T) {
 			}, inFrame)
 		}).Return(nil)
 
+	traffic := &state.TrafficCounter{}
 	rt := BOSServer{
-		AuthService:    authService,
-		Handler:        router,
-		Logger:         slog.Default(),
-		OnlineNotifier: onlineNotifier,
+		AuthService:       authService,
+		ChatSessionCloser: chatSessionCloser,
+		Handler:           router,
+		Logger:            slog.Default(),
+		OnlineNotifier:    onlineNotifier,
+		Traffic:           traffic,
 	}
 	rwc := pipeRWC{
 		PipeReader: clientReader,
 		PipeWriter: clientWriter,
 	}
-	assert.NoError(t, rt.handleNewConnection(context.Background(), rwc))
+	assert.NoError(t, rt.handleNewConnection(context.Background(), rwc, netip.MustParseAddr("203.0.113.7")))
+
+	// the session counts traffic after sign-on, the totals count all traffic
+	assert.NotZero(t, sess.Traffic().BytesIn())
+	assert.NotZero(t, sess.Traffic().BytesOut())
+	assert.Greater(t, traffic.BytesIn(), sess.Traffic().BytesIn())
+	assert.Greater(t, traffic.BytesOut(), sess.Traffic().BytesOut())
+}
+
+func TestBOSService_handleNewConnection_BannedAddr(t *testing.T) {
+	banFile := filepath.Join(t.TempDir(), "bans.txt")
+	assert.NoError(t, os.WriteFile(banFile, []byte("203.0.113.0/24\n"), 0644))
+	banList := state.NewBanList(banFile)
+	assert.NoError(t, banList.Load())
+
+	// the connection is refused before sign-on, so the auth service is
+	// never consulted
+	rt := BOSServer{
+		AddrBanList: banList,
+		AuthService: newMockAuthService(t),
+		Logger:      slog.Default(),
+	}
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, _ := io.Pipe()
+	rwc := pipeRWC{
+		PipeReader: serverReader,
+		PipeWriter: serverWriter,
+	}
+
+	err := rt.handleNewConnection(context.Background(), rwc, netip.MustParseAddr("203.0.113.7"))
+	assert.ErrorContains(t, err, "banned address")
+
+	// the client sees the connection close without a signon frame
+	_, err = clientReader.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestBOSService_handleNewConnection_OtherSessionRemains(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	other, err := sessionManager.AddSessionInstance(context.Background(), "me")
+	assert.NoError(t, err)
+	sess, err := sessionManager.AddSessionInstance(context.Background(), "me")
+	assert.NoError(t, err)
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	go signonBOSClient(t, serverReader, serverWriter)
+
+	// the user is still signed on from the other session, so there's no
+	// sign-out, departure notice, or chat session cleanup
+	authService := newMockAuthService(t)
+	authService.EXPECT().
+		RegisterBOSSession(mock.Anything, []byte("the-cookie")).
+		Return(sess, nil)
+
+	onlineNotifier := newMockOnlineNotifier(t)
+	onlineNotifier.EXPECT().
+		HostOnline().
+		Return(wire.SNACMessage{
+			Frame: wire.SNACFrame{
+				FoodGroup: wire.OService,
+				SubGroup:  wire.OServiceHostOnline,
+			},
+			Body: wire.SNAC_0x01_0x03_OServiceHostOnline{},
+		})
+
+	router := newMockHandler(t)
+	router.EXPECT().
+		Handle(mock.Anything, sess, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	rt := BOSServer{
+		AuthService:       authService,
+		ChatSessionCloser: newMockChatSessionCloser(t),
+		Handler:           router,
+		Logger:            slog.Default(),
+		OnlineNotifier:    onlineNotifier,
+		SessionDetacher:   sessionManager,
+		Traffic:           &state.TrafficCounter{},
+	}
+	rwc := pipeRWC{
+		PipeReader: clientReader,
+		PipeWriter: clientWriter,
+	}
+	assert.NoError(t, rt.handleNewConnection(context.Background(), rwc, netip.MustParseAddr("203.0.113.7")))
+
+	assert.Same(t, other, sessionManager.RetrieveSession(state.NewIdentScreenName("me")))
+}
+
+func TestBOSService_handleNewConnection_SignonTimeout(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// sendClientOnline indicates whether the client completes sign-on
+		sendClientOnline bool
+	}{
+		{
+			name:             "client that doesn't complete sign-on is disconnected",
+			sendClientOnline: false,
+		},
+		{
+			name:             "client that completes sign-on stays connected",
+			sendClientOnline: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sess := state.NewSession()
+			timeout := 50 * time.Millisecond
+
+			clientReader, serverWriter := io.Pipe()
+			serverReader, clientWriter := io.Pipe()
+
+			go func() {
+				// < receive FLAPSignonFrame
+				flap := wire.FLAPFrame{}
+				assert.NoError(t, wire.UnmarshalBE(&flap, serverReader))
+
+				// > send FLAPSignonFrame
+				flapSignonFrame := wire.FLAPSignonFrame{
+					FLAPVersion: 1,
+				}
+				flapSignonFrame.Append(wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("the-cookie")))
+				buf := &bytes.Buffer{}
+				assert.NoError(t, wire.MarshalBE(flapSignonFrame, buf))
+				flap = wire.FLAPFrame{
+					StartMarker: 42,
+					FrameType:   wire.FLAPFrameSignon,
+					Payload:     buf.Bytes(),
+				}
+				assert.NoError(t, wire.MarshalBE(flap, serverWriter))
+
+				flapc := wire.NewFlapClient(0, serverReader, serverWriter)
+
+				// < receive SNAC_0x01_0x03_OServiceHostOnline
+				frame := wire.SNACFrame{}
+				body := wire.SNAC_0x01_0x03_OServiceHostOnline{}
+				assert.NoError(t, flapc.ReceiveSNAC(&frame, &body))
+
+				if !tc.sendClientOnline {
+					// < receive the signoff frame once the timeout passes
+					flap, err := flapc.ReceiveFLAP()
+					assert.NoError(t, err)
+					assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+					assert.NoError(t, serverWriter.Close())
+					return
+				}
+
+				// > send SNAC_0x01_0x02_OServiceClientOnline
+				frame = wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceClientOnline,
+				}
+				assert.NoError(t, flapc.SendSNAC(frame, wire.SNAC_0x01_0x02_OServiceClientOnline{}))
+
+				// stay connected past the timeout
+				time.Sleep(4 * timeout)
+				select {
+				case <-sess.Closed():
+					assert.Fail(t, "session closed after sign-on completed")
+					// unblock the server's signoff frame
+					go io.Copy(io.Discard, serverReader)
+				default:
+				}
+				assert.NoError(t, serverWriter.Close())
+			}()
+
+			authService := newMockAuthService(t)
+			authService.EXPECT().
+				RegisterBOSSession(mock.Anything, []byte("the-cookie")).
+				Return(sess, nil)
+			authService.EXPECT().
+				Signout(mock.Anything, sess)
+
+			onlineNotifier := newMockOnlineNotifier(t)
+			onlineNotifier.EXPECT().
+				HostOnline().
+				Return(wire.SNACMessage{
+					Frame: wire.SNACFrame{
+						FoodGroup: wire.OService,
+						SubGroup:  wire.OServiceHostOnline,
+					},
+					Body: wire.SNAC_0x01_0x03_OServiceHostOnline{},
+				})
+
+			router := newMockHandler(t)
+			if tc.sendClientOnline {
+				router.EXPECT().
+					Handle(mock.Anything, sess, mock.Anything, mock.Anything, mock.Anything).
+					Run(func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw ResponseWriter) {
+						sess.SetSignonComplete()
+					}).Return(nil)
+			}
+
+			rt := BOSServer{
+				AuthService:    authService,
+				Handler:        router,
+				Logger:         slog.Default(),
+				OnlineNotifier: onlineNotifier,
+				SignonTimeout:  timeout,
+			}
+			rwc := pipeRWC{
+				PipeReader: clientReader,
+				PipeWriter: clientWriter,
+			}
+			assert.NoError(t, rt.handleNewConnection(context.Background(), rwc, netip.MustParseAddr("203.0.113.7")))
+		})
+	}
+}
+
+func TestBOSService_handleNewConnection_Compression(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// serverCompression indicates whether the server offers compression
+		serverCompression bool
+		// clientAccepts indicates whether the client accepts compression in its
+		// signon frame
+		clientAccepts bool
+		// wantCompressed indicates whether data frames are expected to be
+		// compressed
+		wantCompressed bool
+	}{
+		{
+			name:              "client accepts compression offer",
+			serverCompression: true,
+			clientAccepts:     true,
+			wantCompressed:    true,
+		},
+		{
+			name:              "client doesn't support compression",
+			serverCompression: true,
+			clientAccepts:     false,
+			wantCompressed:    false,
+		},
+		{
+			name:              "server doesn't offer compression",
+			serverCompression: false,
+			clientAccepts:     true,
+			wantCompressed:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sess := state.NewSession()
+
+			clientReader, serverWriter := io.Pipe()
+			serverReader, clientWriter := io.Pipe()
+
+			go func() {
+				// < receive FLAPSignonFrame
+				flap := wire.FLAPFrame{}
+				assert.NoError(t, wire.UnmarshalBE(&flap, serverReader))
+				flapSignonFrame := wire.FLAPSignonFrame{}
+				assert.NoError(t, wire.UnmarshalBE(&flapSignonFrame, bytes.NewBuffer(flap.Payload)))
+				method, offered := flapSignonFrame.Uint16BE(wire.FLAPSignonTLVCompression)
+				assert.Equal(t, tc.serverCompression, offered)
+
+				// > send FLAPSignonFrame
+				flapSignonFrame = wire.FLAPSignonFrame{
+					FLAPVersion: 1,
+				}
+				flapSignonFrame.Append(wire.NewTLVBE(wire.OServiceTLVTagsLoginCookie, []byte("the-cookie")))
+				if tc.clientAccepts {
+					flapSignonFrame.Append(wire.NewTLVBE(wire.FLAPSignonTLVCompression, wire.FLAPCompressionDeflate))
+				}
+				buf := &bytes.Buffer{}
+				assert.NoError(t, wire.MarshalBE(flapSignonFrame, buf))
+				flap = wire.FLAPFrame{
+					StartMarker: 42,
+					FrameType:   wire.FLAPFrameSignon,
+					Payload:     buf.Bytes(),
+				}
+				assert.NoError(t, wire.MarshalBE(flap, serverWriter))
+
+				flapc := wire.NewFlapClient(0, serverReader, serverWriter)
+				if tc.wantCompressed {
+					flapc.SetCompression(method)
+				}
+
+				// < receive SNAC_0x01_0x03_OServiceHostOnline
+				flap, err := flapc.ReceiveFLAP()
+				assert.NoError(t, err)
+				hostOnline := &bytes.Buffer{}
+				assert.NoError(t, wire.MarshalBE(wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceHostOnline,
+				}, hostOnline))
+				assert.NoError(t, wire.MarshalBE(wire.SNAC_0x01_0x03_OServiceHostOnline{
+					FoodGroups: []uint16{wire.OService, wire.ICBM},
+				}, hostOnline))
+				if tc.wantCompressed {
+					assert.NotEqual(t, hostOnline.Bytes(), flap.Payload)
+				} else {
+					assert.Equal(t, hostOnline.Bytes(), flap.Payload)
+				}
+				payload, err := flapc.DataPayload(flap)
+				assert.NoError(t, err)
+				assert.Equal(t, hostOnline.Bytes(), payload)
+
+				// send the first request that should get relayed to BOSRouter.Handle
+				frame := wire.SNACFrame{
+					FoodGroup: wire.OService,
+					SubGroup:  wire.OServiceClientOnline,
+				}
+				assert.NoError(t, flapc.SendSNAC(frame, wire.SNAC_0x01_0x02_OServiceClientOnline{}))
+				assert.NoError(t, serverWriter.Close())
+			}()
+
+			authService := newMockAuthService(t)
+			authService.EXPECT().
+				RegisterBOSSession(mock.Anything, []byte("the-cookie")).
+				Return(sess, nil)
+			authService.EXPECT().
+				Signout(mock.Anything, sess)
+
+			onlineNotifier := newMockOnlineNotifier(t)
+			onlineNotifier.EXPECT().
+				HostOnline().
+				Return(wire.SNACMessage{
+					Frame: wire.SNACFrame{
+						FoodGroup: wire.OService,
+						SubGroup:  wire.OServiceHostOnline,
+					},
+					Body: wire.SNAC_0x01_0x03_OServiceHostOnline{
+						FoodGroups: []uint16{wire.OService, wire.ICBM},
+					},
+				})
+
+			router := newMockHandler(t)
+			router.EXPECT().
+				Handle(mock.Anything, sess, mock.Anything, mock.Anything, mock.Anything).
+				Run(func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw ResponseWriter) {
+					assert.Equal(t, wire.SNACFrame{
+						FoodGroup: wire.OService,
+						SubGroup:  wire.OServiceClientOnline,
+					}, inFrame)
+				}).Return(nil)
+
+			rt := BOSServer{
+				AuthService:    authService,
+				Config:         config.Config{FLAPCompression: tc.serverCompression},
+				Handler:        router,
+				Logger:         slog.Default(),
+				OnlineNotifier: onlineNotifier,
+				Traffic:        &state.TrafficCounter{},
+			}
+			rwc := pipeRWC{
+				PipeReader: clientReader,
+				PipeWriter: clientWriter,
+			}
+			assert.NoError(t, rt.handleNewConnection(context.Background(), rwc, netip.MustParseAddr("203.0.113.7")))
+		})
+	}
 }
@@ -0,0 +1,106 @@
+package oscar
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// NewFrameCapture creates a new instance of FrameCapture that writes capture
+// records to w. Each record is written with a single call to w.Write, so w
+// must be safe to use with multiple goroutines.
+func NewFrameCapture(w io.Writer) *FrameCapture {
+	return &FrameCapture{
+		nowFn: time.Now,
+		w:     w,
+	}
+}
+
+// FrameCapture records the FLAP frames exchanged with clients to a capture
+// file that can be inspected with cmd/capture_reader. Capturing is best
+// effort: a failed write drops the record without affecting the connection.
+type FrameCapture struct {
+	lastConnID atomic.Uint64
+	nowFn      func() time.Time
+	w          io.Writer
+}
+
+// newConn wraps a client connection so that its traffic is captured.
+func (c *FrameCapture) newConn(rwc io.ReadWriteCloser) *capturedConn {
+	return &capturedConn{
+		ReadWriteCloser: rwc,
+		capture:         c,
+		connID:          c.lastConnID.Add(1),
+	}
+}
+
+// record writes data to the capture file, split into as many records as it
+// takes to fit the record size limit.
+func (c *FrameCapture) record(connID uint64, direction uint8, screenName string, data []byte) {
+	buf := &bytes.Buffer{}
+	for len(data) > 0 {
+		chunk := data[:min(len(data), math.MaxUint16)]
+		data = data[len(chunk):]
+		rec := wire.CaptureRecord{
+			Time:       uint64(c.nowFn().UnixNano()),
+			ConnID:     connID,
+			Direction:  direction,
+			ScreenName: screenName,
+			Data:       chunk,
+		}
+		if err := wire.MarshalBE(rec, buf); err != nil {
+			return
+		}
+	}
+	_, _ = c.w.Write(buf.Bytes())
+}
+
+// capturedConn records the bytes read from and written to a client
+// connection. Records are tagged with the session's screen name once a
+// session is attached.
+type capturedConn struct {
+	io.ReadWriteCloser
+	capture    *FrameCapture
+	connID     uint64
+	screenName atomic.Pointer[string]
+}
+
+// attach tags subsequent records with the session's screen name. It does
+// nothing if c is nil, which is the case when capturing is disabled.
+func (c *capturedConn) attach(screenName state.IdentScreenName) {
+	if c == nil {
+		return
+	}
+	name := screenName.String()
+	c.screenName.Store(&name)
+}
+
+func (c *capturedConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if n > 0 {
+		c.capture.record(c.connID, wire.CaptureDirectionIn, c.currentScreenName(), p[:n])
+	}
+	return n, err
+}
+
+func (c *capturedConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	if n > 0 {
+		c.capture.record(c.connID, wire.CaptureDirectionOut, c.currentScreenName(), p[:n])
+	}
+	return n, err
+}
+
+// currentScreenName returns the attached screen name, or an empty string if
+// no session is attached.
+func (c *capturedConn) currentScreenName() string {
+	if name := c.screenName.Load(); name != nil {
+		return *name
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package oscar
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+func TestFrameCapture(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	captureFile := &bytes.Buffer{}
+	capture := NewFrameCapture(captureFile)
+	capture.nowFn = func() time.Time { return now }
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	conn := capture.newConn(pipeRWC{PipeReader: serverReader, PipeWriter: serverWriter})
+
+	// traffic before the session is attached isn't tagged with a screen name
+	go func() {
+		_, _ = clientWriter.Write([]byte{1, 2, 3, 4})
+	}()
+	_, err := io.ReadFull(conn, make([]byte, 4))
+	require.NoError(t, err)
+
+	conn.attach(state.NewIdentScreenName("ChattingChuck"))
+
+	// server sends a FLAP frame to the client
+	go func() {
+		_, _ = io.Copy(io.Discard, clientReader)
+	}()
+	require.NoError(t, wire.NewFlapClient(0, nil, conn).SendKeepAlive())
+	require.NoError(t, conn.Close())
+
+	// a nil conn, as used when capturing is disabled, ignores attach
+	var disabled *capturedConn
+	disabled.attach(state.NewIdentScreenName("ChattingChuck"))
+
+	var records []wire.CaptureRecord
+	for {
+		rec, err := wire.ReadCaptureRecord(captureFile)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+
+	require.NotEmpty(t, records)
+	assert.Equal(t, uint64(1), records[0].ConnID)
+	assert.Equal(t, wire.CaptureDirectionIn, records[0].Direction)
+	assert.Empty(t, records[0].ScreenName)
+	assert.Equal(t, []byte{1, 2, 3, 4}, records[0].Data)
+	assert.Equal(t, now, records[0].CapturedAt().UTC())
+
+	// the frame may be written in several chunks, each with its own record
+	var out []byte
+	for _, rec := range records[1:] {
+		assert.Equal(t, uint64(1), rec.ConnID)
+		assert.Equal(t, wire.CaptureDirectionOut, rec.Direction)
+		assert.Equal(t, "chattingchuck", rec.ScreenName)
+		out = append(out, rec.Data...)
+	}
+	// a FLAP keepalive frame with no payload is 6 bytes long
+	assert.Len(t, out, 6)
+	assert.Equal(t, byte(0x2A), out[0])
+
+	// each connection gets its own ID
+	assert.Equal(t, uint64(2), capture.newConn(nil).connID)
+}
@@ -2,14 +2,17 @@ package oscar
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/mk6i/retro-aim-server/config"
+	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
@@ -19,8 +22,14 @@ import (
 type ChatServer struct {
 	AuthService
 	Handler
-	Logger *slog.Logger
+	// Capture, if set, records client traffic to a capture file.
+	Capture *FrameCapture
+	Logger  *slog.Logger
 	OnlineNotifier
+	// TLSConfig, if set, makes the server accept TLS connections.
+	TLSConfig *tls.Config
+	// Traffic accumulates bytes sent and received across all connections
+	Traffic *state.TrafficCounter
 	config.Config
 }
 
@@ -31,6 +40,9 @@ func (rt ChatServer) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to start chat sever: %w", err)
 	}
+	if rt.TLSConfig != nil {
+		listener = tls.NewListener(listener, rt.TLSConfig)
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -71,8 +83,17 @@ func (rt ChatServer) Start(ctx context.Context) error {
 }
 
 func (rt ChatServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser) error {
-	flapc := wire.NewFlapClient(100, rwc, rwc)
-	if err := flapc.SendSignonFrame(nil); err != nil {
+	var captured *capturedConn
+	if rt.Capture != nil {
+		captured = rt.Capture.newConn(rwc)
+		rwc = captured
+	}
+	conn := newMeteredConn(rwc, rt.Traffic)
+	rwc = conn
+
+	w, flush := outboundWriter(rwc, rt.Config.OutboundBatchMs)
+	flapc := wire.NewFlapClient(100, rwc, w)
+	if err := flapc.SendSignonFrame(signonTLVs(rt.Config.FLAPCompression)); err != nil {
 		return err
 	}
 	flap, err := flapc.ReceiveSignonFrame()
@@ -92,9 +113,18 @@ func (rt ChatServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteCl
 	if chatSess == nil {
 		return errors.New("session not found")
 	}
+	conn.attach(chatSess.Traffic())
+	captured.attach(chatSess.IdentScreenName())
+
+	if negotiateCompression(rt.Config.FLAPCompression, flapc, flap) {
+		rt.Logger.DebugContext(ctx, "FLAP compression enabled")
+	}
 
 	defer func() {
 		chatSess.Close()
+		if err := flush(); err != nil {
+			rt.Logger.DebugContext(ctx, "unable to flush outbound messages", "err", err.Error())
+		}
 		rwc.Close()
 		rt.SignoutChat(ctx, chatSess)
 	}()
@@ -105,5 +135,5 @@ func (rt ChatServer) handleNewConnection(ctx context.Context, rwc io.ReadWriteCl
 	}
 
 	ctx = context.WithValue(ctx, "screenName", chatSess.IdentScreenName())
-	return dispatchIncomingMessages(ctx, chatSess, flapc, rwc, rt.Logger, rt.Handler)
+	return dispatchIncomingMessages(ctx, chatSess, flapc, rwc, rt.Logger, rt.Handler, time.Duration(rt.Config.ServerKeepaliveSec)*time.Second, newRateLimiter(rt.Config))
 }
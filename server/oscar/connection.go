@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"time"
 
 	"github.com/mk6i/retro-aim-server/server/oscar/middleware"
 	"github.com/mk6i/retro-aim-server/state"
@@ -25,6 +26,46 @@ func sendInvalidSNACErr(frameIn wire.SNACFrame, rw ResponseWriter) error {
 	return rw.SendSNAC(frameOut, bodyOut)
 }
 
+// sendRateLimitedErr tells the client that its SNAC was dropped because the
+// client is rate limited.
+func sendRateLimitedErr(frameIn wire.SNACFrame, rw ResponseWriter) error {
+	frameOut := wire.SNACFrame{
+		FoodGroup: frameIn.FoodGroup,
+		SubGroup:  0x01, // error subgroup for all SNACs
+		RequestID: frameIn.RequestID,
+	}
+	bodyOut := wire.SNACError{
+		Code: wire.ErrorCodeRateToHost,
+	}
+	return rw.SendSNAC(frameOut, bodyOut)
+}
+
+// signonTLVs returns the TLVs that the server sends in its signon frame. If
+// compression is true, the server offers FLAP payload compression.
+func signonTLVs(compression bool) []wire.TLV {
+	if !compression {
+		return nil
+	}
+	return []wire.TLV{
+		wire.NewTLVBE(wire.FLAPSignonTLVCompression, wire.FLAPCompressionDeflate),
+	}
+}
+
+// negotiateCompression enables FLAP payload compression on flapc if the
+// server offered it and the client accepted it in its signon frame. Clients
+// that don't know about compression ignore the offer and stay uncompressed.
+func negotiateCompression(offered bool, flapc *wire.FlapClient, signon wire.FLAPSignonFrame) bool {
+	if !offered {
+		return false
+	}
+	method, ok := signon.Uint16BE(wire.FLAPSignonTLVCompression)
+	if !ok || method != wire.FLAPCompressionDeflate {
+		return false
+	}
+	flapc.SetCompression(method)
+	return true
+}
+
 // dispatchIncomingMessages receives incoming messages and sends them to the
 // appropriate message handler. Messages from the client are sent to the
 // router. Messages relayed from the user session are forwarded to the client.
@@ -32,8 +73,17 @@ func sendInvalidSNACErr(frameIn wire.SNACFrame, rw ResponseWriter) error {
 // types of messages. The function terminates upon receiving a connection error
 // or when the session closes.
 //
+// If keepalive is greater than 0, the client is sent a keepalive probe after
+// it has been silent for the keepalive interval. The connection is closed if
+// the client remains silent for another interval after the probe.
+//
+// If limiter is not nil, every SNAC from the client counts against the
+// client's rate class. SNACs sent while the client is rate limited are
+// dropped with a rate limit error, and the connection is closed if the client
+// exceeds the disconnect level.
+//
 // todo: this method has too many params and should be folded into a new type
-func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *wire.FlapClient, r io.Reader, logger *slog.Logger, router Handler) error {
+func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *wire.FlapClient, r io.Reader, logger *slog.Logger, router Handler, keepalive time.Duration, limiter *rateLimiter) error {
 	defer func() {
 		logger.InfoContext(ctx, "user disconnected")
 	}()
@@ -57,20 +107,63 @@ func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *w
 		}
 	}()
 
+	// keepaliveCh stays nil, and therefore never fires, when probes are
+	// disabled
+	var keepaliveCh <-chan time.Time
+	if keepalive > 0 {
+		ticker := time.NewTicker(keepalive)
+		defer ticker.Stop()
+		keepaliveCh = ticker.C
+	}
+	// heard indicates whether the client sent anything during the current
+	// keepalive interval
+	heard := false
+	// probed indicates whether a keepalive probe is awaiting a response
+	probed := false
+
 	for {
 		select {
 		case flap, ok := <-msgCh:
 			if !ok {
 				return nil
 			}
+			heard = true
 			switch flap.FrameType {
 			case wire.FLAPFrameData:
-				flapBuf := bytes.NewBuffer(flap.Payload)
+				payload, err := flapc.DataPayload(flap)
+				if err != nil {
+					return err
+				}
+				flapBuf := bytes.NewBuffer(payload)
 
 				inFrame := wire.SNACFrame{}
 				if err := wire.UnmarshalBE(&inFrame, flapBuf); err != nil {
 					return err
 				}
+				if limiter != nil {
+					action, notice := limiter.track()
+					if notice != nil {
+						if err := flapc.SendSNAC(notice.Frame, notice.Body); err != nil {
+							return err
+						}
+						middleware.LogRequest(ctx, logger, notice.Frame, notice.Body)
+					}
+					switch action {
+					case rateLimitActionDisconnect:
+						logger.WarnContext(ctx, "client exceeded rate limit, closing connection")
+						if err := flapc.Disconnect(); err != nil {
+							return fmt.Errorf("unable to gracefully disconnect user. %w", err)
+						}
+						return nil
+					case rateLimitActionDrop:
+						logger.DebugContext(ctx, "client is rate limited, dropping SNAC", "snac", inFrame)
+						// let the client know the request won't be answered
+						if err := sendRateLimitedErr(inFrame, flapc); err != nil {
+							return err
+						}
+						continue
+					}
+				}
 				// route a client request to the appropriate service handler. the
 				// handler may write a response to the client connection.
 				if err := router.Handle(ctx, sess, inFrame, flapBuf, flapc); err != nil {
@@ -91,10 +184,27 @@ func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *w
 				logger.InfoContext(ctx, "got FLAPFrameSignoff", "flap", flap)
 				return nil
 			case wire.FLAPFrameKeepAlive:
-				logger.DebugContext(ctx, "keepalive heartbeat")
+				// a keepalive proves the client is alive, which was
+				// recorded above. it carries no SNAC, so count it without
+				// dispatching or logging it.
+				sess.Traffic().AddKeepAlive()
 			default:
 				return fmt.Errorf("got unknown FLAP frame type. flap: %v", flap)
 			}
+		case <-keepaliveCh:
+			switch {
+			case heard:
+				heard = false
+				probed = false
+			case probed:
+				logger.InfoContext(ctx, "client didn't respond to keepalive probe, closing connection")
+				return nil
+			default:
+				if err := flapc.SendKeepAlive(); err != nil {
+					return err
+				}
+				probed = true
+			}
 		case m := <-sess.ReceiveMessage():
 			// forward a notification sent from another client to this client
 			if err := flapc.SendSNAC(m.Frame, m.Body); err != nil {
@@ -104,8 +214,12 @@ func dispatchIncomingMessages(ctx context.Context, sess *state.Session, flapc *w
 			middleware.LogRequest(ctx, logger, m.Frame, m.Body)
 		case <-sess.Closed():
 			block := wire.TLVRestBlock{}
-			// error code indicating user signed in a different location
-			block.Append(wire.NewTLVBE(0x0009, uint8(0x01)))
+			// error code indicating why the user was signed off
+			errCode := wire.SignoffErrCodeMultipleLogins
+			if sess.Kicked() {
+				errCode = wire.SignoffErrCodeKicked
+			}
+			block.Append(wire.NewTLVBE(0x0009, errCode))
 			// "more info" button
 			block.Append(wire.NewTLVBE(0x000b, "https://github.com/mk6i/retro-aim-server"))
 			if err := flapc.SendSignoffFrame(block); err != nil {
@@ -7,10 +7,12 @@ import (
 	"log/slog"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/mk6i/retro-aim-server/config"
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
 )
@@ -25,7 +27,7 @@ func TestHandleChatConnection_MessageRelay(t *testing.T) {
 	clientReader, serverWriter := io.Pipe()
 	go func() {
 		flapc := wire.NewFlapClient(0, nil, serverWriter)
-		err := dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil)
+		err := dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil, 0, nil)
 		assert.NoError(t, err)
 	}()
 
@@ -144,7 +146,7 @@ func TestHandleChatConnection_ClientRequest(t *testing.T) {
 	clientReader, serverWriter := io.Pipe()
 	go func() {
 		flapc := wire.NewFlapClient(0, nil, serverWriter)
-		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), router))
+		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), router, 0, nil))
 	}()
 
 	// send client messages
@@ -165,3 +167,289 @@ func TestHandleChatConnection_ClientRequest(t *testing.T) {
 	assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
 	assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
 }
+
+func TestHandleChatConnection_IgnoredSNAC(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	// the vendor-specific SNAC has no route, but is allowlisted
+	vendorSNAC := wire.SNACFrame{FoodGroup: wire.OService, SubGroup: 0x0050}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	router := NewRouter()
+	router.Register(wire.OService, wire.OServiceNoop, func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw ResponseWriter) error {
+		wg.Done()
+		return nil
+	})
+	h := IgnoreSNACs(router, []wire.SNACFrame{vendorSNAC}, slog.Default())
+
+	// start the server connection handler in the background
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	go func() {
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), h, 0, nil))
+	}()
+
+	// collect everything the server sends to the client
+	flaps := make(chan wire.FLAPFrame, 2)
+	go func() {
+		defer close(flaps)
+		for {
+			flap := wire.FLAPFrame{}
+			if err := wire.UnmarshalBE(&flap, clientReader); err != nil {
+				return
+			}
+			flaps <- flap
+		}
+	}()
+
+	// send the vendor-specific SNAC followed by a SNAC the server handles
+	flapc := wire.NewFlapClient(0, nil, clientWriter)
+	assert.NoError(t, flapc.SendSNAC(vendorSNAC, struct{}{}))
+	assert.NoError(t, flapc.SendSNAC(wire.SNACFrame{FoodGroup: wire.OService, SubGroup: wire.OServiceNoop}, struct{}{}))
+
+	// the connection survived the vendor-specific SNAC
+	wg.Wait()
+
+	sess.Close()
+	<-sess.Closed()
+
+	// the client is not sent an error for the vendor-specific SNAC, so the
+	// first thing it receives is the disconnection message
+	flap := <-flaps
+	assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+}
+
+func TestHandleChatConnection_SignoffErrCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		close       func(sess *state.Session)
+		wantErrCode uint8
+	}{
+		{
+			name:        "signed on from another location",
+			close:       (*state.Session).Close,
+			wantErrCode: wire.SignoffErrCodeMultipleLogins,
+		},
+		{
+			name:        "kicked by an operator",
+			close:       (*state.Session).Kick,
+			wantErrCode: wire.SignoffErrCodeKicked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := state.NewSession()
+
+			serverReader, _ := io.Pipe()
+			clientReader, serverWriter := io.Pipe()
+			go func() {
+				flapc := wire.NewFlapClient(0, nil, serverWriter)
+				assert.NoError(t, dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), NewRouter(), 0, nil))
+			}()
+
+			tt.close(sess)
+
+			flap := wire.FLAPFrame{}
+			assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+			assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+
+			block := wire.TLVRestBlock{}
+			assert.NoError(t, wire.UnmarshalBE(&block, bytes.NewBuffer(flap.Payload)))
+			errCode, ok := block.Uint8(0x0009)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantErrCode, errCode)
+		})
+	}
+}
+
+func TestHandleChatConnection_KeepaliveProbe(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	keepalive := 20 * time.Millisecond
+
+	// start the server connection handler in the background. the client never
+	// sends anything, so it should be probed and then disconnected.
+	serverReader, _ := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		done <- dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil, keepalive, nil)
+	}()
+
+	// verify the connection handler sends a keepalive probe
+	flap := wire.FLAPFrame{}
+	assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+	assert.Equal(t, wire.FLAPFrameKeepAlive, flap.FrameType)
+
+	// verify the connection handler gives up on the unresponsive client
+	// within the keepalive window
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), 3*keepalive+100*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("connection handler didn't disconnect unresponsive client")
+	}
+}
+
+func TestHandleChatConnection_KeepaliveResponsiveClient(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	keepalive := 20 * time.Millisecond
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		done <- dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), nil, keepalive, nil)
+	}()
+
+	// answer each keepalive probe for several keepalive intervals
+	clientFlapc := wire.NewFlapClient(0, nil, clientWriter)
+	for i := 0; i < 3; i++ {
+		flap := wire.FLAPFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+		assert.Equal(t, wire.FLAPFrameKeepAlive, flap.FrameType)
+		assert.NoError(t, clientFlapc.SendKeepAlive())
+	}
+
+	// verify the connection is still up
+	select {
+	case <-done:
+		t.Fatal("connection handler disconnected responsive client")
+	default:
+	}
+
+	// stop the session, which terminates the connection handler goroutine
+	sess.Close()
+	<-sess.Closed()
+
+	// drain frames until the signoff frame arrives
+	for {
+		flap := wire.FLAPFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+		if flap.FrameType == wire.FLAPFrameSignoff {
+			break
+		}
+	}
+	assert.NoError(t, <-done)
+}
+
+func TestHandleChatConnection_KeepaliveCounted(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	// keepalives carry no SNAC, so the router must never be called
+	router := newMockHandler(t)
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		done <- dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), router, 0, nil)
+	}()
+
+	// send several unsolicited keepalives
+	clientFlapc := wire.NewFlapClient(0, nil, clientWriter)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, clientFlapc.SendKeepAlive())
+	}
+
+	// verify each keepalive is counted
+	assert.Eventually(t, func() bool {
+		return sess.Traffic().KeepAlives() == 3
+	}, time.Second, 5*time.Millisecond)
+
+	// stop the session, which terminates the connection handler goroutine
+	sess.Close()
+	<-sess.Closed()
+
+	flap := wire.FLAPFrame{}
+	assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+	assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+	assert.NoError(t, <-done)
+}
+
+func TestHandleChatConnection_RateLimit(t *testing.T) {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	sess, _ := sessionManager.AddSession(nil, "bob")
+
+	limiter := newRateLimiter(config.Config{
+		RateLimitEnforce:         true,
+		RateLimitWindowSize:      2,
+		RateLimitClearLevel:      40,
+		RateLimitAlertLevel:      30,
+		RateLimitLimitLevel:      20,
+		RateLimitDisconnectLevel: 10,
+		RateLimitMaxLevel:        100,
+	})
+	// freeze time so that every SNAC arrives with no time in between
+	now := time.Unix(1000, 0)
+	limiter.lastTime = now
+	limiter.timeNow = func() time.Time {
+		return now
+	}
+
+	// only the SNACs sent before the client is rate limited are routed
+	noop := wire.SNACFrame{FoodGroup: wire.OService, SubGroup: wire.OServiceNoop}
+	router := newMockHandler(t)
+	router.EXPECT().
+		Handle(mock.Anything, sess, noop, mock.Anything, mock.Anything).
+		Return(nil).
+		Times(2)
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		flapc := wire.NewFlapClient(0, nil, serverWriter)
+		done <- dispatchIncomingMessages(context.Background(), sess, flapc, serverReader, slog.Default(), router, 0, limiter)
+	}()
+
+	go func() {
+		clientFlapc := wire.NewFlapClient(0, nil, clientWriter)
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, clientFlapc.SendSNAC(noop, struct{}{}))
+		}
+	}()
+
+	// the client is warned, then limited, then disconnected
+	for _, wantCode := range []uint16{wire.OServiceRateChangeCodeWarning, wire.OServiceRateChangeCodeLimit} {
+		flap := wire.FLAPFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+		assert.Equal(t, wire.FLAPFrameData, flap.FrameType)
+
+		buf := bytes.NewBuffer(flap.Payload)
+		frame := wire.SNACFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&frame, buf))
+		assert.Equal(t, wire.OServiceRateParamChange, frame.SubGroup)
+		body := wire.SNAC_0x01_0x0A_OServiceRateParamChange{}
+		assert.NoError(t, wire.UnmarshalBE(&body, buf))
+		assert.Equal(t, wantCode, body.Code)
+	}
+
+	// the dropped SNAC is answered with a rate limit error
+	errFlap := wire.FLAPFrame{}
+	assert.NoError(t, wire.UnmarshalBE(&errFlap, clientReader))
+	buf := bytes.NewBuffer(errFlap.Payload)
+	frame := wire.SNACFrame{}
+	assert.NoError(t, wire.UnmarshalBE(&frame, buf))
+	assert.Equal(t, wire.SNACFrame{FoodGroup: wire.OService, SubGroup: 0x01}, frame)
+	snacErr := wire.SNACError{}
+	assert.NoError(t, wire.UnmarshalBE(&snacErr, buf))
+	assert.Equal(t, wire.ErrorCodeRateToHost, snacErr.Code)
+
+	flap := wire.FLAPFrameDisconnect{}
+	assert.NoError(t, wire.UnmarshalBE(&flap, clientReader))
+	assert.Equal(t, wire.FLAPFrameSignoff, flap.FrameType)
+	assert.NoError(t, <-done)
+}
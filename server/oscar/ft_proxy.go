@@ -0,0 +1,338 @@
+package oscar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+const (
+	// proxyInitTimeout is how long a client has to send its first proxy
+	// packet after connecting.
+	proxyInitTimeout = 30 * time.Second
+	// proxyMaxPayload is the largest init packet payload the proxy accepts.
+	proxyMaxPayload = 1024
+)
+
+// SessionRetriever is the interface for looking up the sessions of signed-on
+// users.
+type SessionRetriever interface {
+	RetrieveSession(screenName state.IdentScreenName) *state.Session
+}
+
+// FileTransferEnder is the interface for releasing the file transfer slot of
+// a transfer once its proxy session ends.
+type FileTransferEnder interface {
+	EndTransfer(cookie [8]byte)
+}
+
+// FileTransferProxyServer relays rendezvous data, such as file transfers,
+// between two clients that can't connect to each other directly. It speaks
+// the rendezvous proxy protocol that AIM clients use with ars.oscar.aol.com.
+// Only signed-on users may open proxy sessions, and only from the IP address
+// of their BOS connection, since the proxy protocol carries no credentials.
+type FileTransferProxyServer struct {
+	FileTransferEnder
+	// IP is the IPv4 address of the proxy that senders pass along to
+	// recipients.
+	IP         net.IP
+	ListenAddr string
+	Logger     *slog.Logger
+	// PairTimeout is how long a sender waits for the recipient to join its
+	// proxy session.
+	PairTimeout time.Duration
+	SessionRetriever
+}
+
+// Start starts a TCP server and relays rendezvous data between the clients
+// that connect to it.
+func (rt FileTransferProxyServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", rt.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("unable to start file transfer proxy server: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	rt.Logger.Info("starting server", "listen_host", rt.ListenAddr, "proxy_ip", rt.IP.String())
+
+	sessions := newProxySessions()
+	wg := sync.WaitGroup{}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			rt.Logger.Error("accept failed", "err", err.Error())
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connCtx := context.WithValue(ctx, "ip", conn.RemoteAddr().String())
+			rt.Logger.DebugContext(connCtx, "accepted connection")
+			if err := rt.handleNewConnection(connCtx, conn, sessions); err != nil {
+				rt.Logger.InfoContext(connCtx, "proxy session failed", "err", err.Error())
+			}
+		}()
+	}
+
+	if !waitForShutdown(&wg) {
+		rt.Logger.Error("shutdown complete, but connections didn't close cleanly")
+	} else {
+		rt.Logger.Info("shutdown complete")
+	}
+
+	return nil
+}
+
+// handleNewConnection reads the client's init packet and either opens a
+// proxy session for a sender or joins a recipient to an open one.
+func (rt FileTransferProxyServer) handleNewConnection(ctx context.Context, conn net.Conn, sessions *proxySessions) error {
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			conn.Close()
+		}
+	}()
+
+	if err := conn.SetReadDeadline(time.Now().Add(proxyInitTimeout)); err != nil {
+		return err
+	}
+	pkt, err := wire.ReadProxyPacket(conn, proxyMaxPayload)
+	if err != nil {
+		return fmt.Errorf("unable to read init packet: %w", err)
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	switch pkt.Command {
+	case wire.ProxyCommandInitSend:
+		return rt.initSend(ctx, conn, pkt, sessions)
+	case wire.ProxyCommandInitRecv:
+		handedOff, err = rt.initRecv(ctx, conn, pkt, sessions)
+		return err
+	default:
+		return sendProxyErr(conn, wire.ProxyErrCodeBadRequest,
+			fmt.Errorf("unexpected init command %#04x", pkt.Command))
+	}
+}
+
+// initSend opens a proxy session for the sender, waits for the recipient to
+// join, and then relays data between them until either side disconnects.
+func (rt FileTransferProxyServer) initSend(ctx context.Context, conn net.Conn, pkt wire.ProxyPacket, sessions *proxySessions) error {
+	body := wire.ProxyInitSend{}
+	if err := wire.UnmarshalBE(&body, bytes.NewBuffer(pkt.Payload)); err != nil {
+		return sendProxyErr(conn, wire.ProxyErrCodeBadRequest, err)
+	}
+	if err := rt.authenticate(conn, body.ScreenName); err != nil {
+		return sendProxyErr(conn, wire.ProxyErrCodeBadRequest, err)
+	}
+
+	key, peerCh, err := sessions.open(body.Cookie)
+	if err != nil {
+		return err
+	}
+	if rt.FileTransferEnder != nil {
+		// the transfer is over once the proxy session ends, whether it
+		// completed or failed
+		defer func() {
+			cookie := [8]byte{}
+			binary.BigEndian.PutUint64(cookie[:], body.Cookie)
+			rt.EndTransfer(cookie)
+		}()
+	}
+	defer func() {
+		// close a recipient that joined after the sender gave up
+		if peer := sessions.close(key, peerCh); peer != nil {
+			peer.Close()
+		}
+	}()
+
+	ack := wire.ProxyAck{
+		Port: key.port,
+		IP:   binary.BigEndian.Uint32(rt.IP.To4()),
+	}
+	if err := wire.WriteProxyPacket(conn, wire.ProxyCommandAck, ack); err != nil {
+		return err
+	}
+
+	var peer net.Conn
+	select {
+	case peer = <-peerCh:
+	case <-time.After(rt.PairTimeout):
+		return sendProxyErr(conn, wire.ProxyErrCodeInitTimeout,
+			errors.New("recipient didn't join proxy session in time"))
+	case <-ctx.Done():
+		return nil
+	}
+	defer peer.Close()
+
+	for _, c := range []net.Conn{conn, peer} {
+		if err := wire.WriteProxyPacket(c, wire.ProxyCommandReady, nil); err != nil {
+			return err
+		}
+	}
+
+	rt.Logger.DebugContext(ctx, "relaying proxy session", "screen_name", body.ScreenName)
+	relay(ctx, conn, peer)
+	return nil
+}
+
+// initRecv joins the recipient to the sender's proxy session. It reports
+// whether the connection was handed off to the sender's goroutine, which
+// then owns it.
+func (rt FileTransferProxyServer) initRecv(_ context.Context, conn net.Conn, pkt wire.ProxyPacket, sessions *proxySessions) (bool, error) {
+	body := wire.ProxyInitRecv{}
+	if err := wire.UnmarshalBE(&body, bytes.NewBuffer(pkt.Payload)); err != nil {
+		return false, sendProxyErr(conn, wire.ProxyErrCodeBadRequest, err)
+	}
+	if err := rt.authenticate(conn, body.ScreenName); err != nil {
+		return false, sendProxyErr(conn, wire.ProxyErrCodeBadRequest, err)
+	}
+
+	if !sessions.join(proxySessionKey{cookie: body.Cookie, port: body.Port}, conn) {
+		return false, sendProxyErr(conn, wire.ProxyErrCodeBadRequest,
+			errors.New("proxy session not found"))
+	}
+	return true, nil
+}
+
+// authenticate checks that screenName is signed on and that conn comes from
+// the same IP address as the user's BOS connection. This keeps users who know
+// an online screen name from using the proxy on that user's behalf.
+func (rt FileTransferProxyServer) authenticate(conn net.Conn, screenName string) error {
+	sess := rt.RetrieveSession(state.NewIdentScreenName(screenName))
+	if sess == nil {
+		return fmt.Errorf("%s is not signed on", screenName)
+	}
+	if addr := remoteIP(conn); !addr.IsValid() || addr != sess.RemoteAddr() {
+		return fmt.Errorf("%s connected from %s, which doesn't match their BOS connection", screenName, addr)
+	}
+	return nil
+}
+
+// relay copies data in both directions between a and b until either side
+// disconnects or the server shuts down.
+func relay(ctx context.Context, a net.Conn, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	// unblock the other copy
+	a.Close()
+	b.Close()
+}
+
+// sendProxyErr sends a proxy error packet to the client and returns err.
+func sendProxyErr(w io.Writer, code uint16, err error) error {
+	if err1 := wire.WriteProxyPacket(w, wire.ProxyCommandError, wire.ProxyError{Code: code}); err1 != nil {
+		return errors.Join(err1, err)
+	}
+	return err
+}
+
+// proxySessionKey identifies a proxy session by the rendezvous cookie and the
+// port number that the proxy assigned to it.
+type proxySessionKey struct {
+	cookie uint64
+	port   uint16
+}
+
+// newProxySessions creates a new instance of proxySessions.
+func newProxySessions() *proxySessions {
+	return &proxySessions{
+		pending: make(map[proxySessionKey]chan net.Conn),
+	}
+}
+
+// proxySessions tracks the proxy sessions whose sender is waiting for the
+// recipient to join. It is safe to use with multiple goroutines.
+type proxySessions struct {
+	mutex   sync.Mutex
+	pending map[proxySessionKey]chan net.Conn
+}
+
+// open creates a proxy session for cookie with a random port number. It
+// returns the session key and the channel that receives the recipient's
+// connection.
+func (p *proxySessions) open(cookie uint64) (proxySessionKey, chan net.Conn, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for {
+		var b [2]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return proxySessionKey{}, nil, fmt.Errorf("unable to generate proxy port: %w", err)
+		}
+		key := proxySessionKey{
+			cookie: cookie,
+			port:   binary.BigEndian.Uint16(b[:]),
+		}
+		if _, taken := p.pending[key]; key.port == 0 || taken {
+			continue
+		}
+		// buffered so that the recipient never waits on the sender
+		ch := make(chan net.Conn, 1)
+		p.pending[key] = ch
+		return key, ch, nil
+	}
+}
+
+// join removes the proxy session identified by key and hands conn to its
+// sender. It returns false if the session doesn't exist.
+func (p *proxySessions) join(key proxySessionKey, conn net.Conn) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ch, ok := p.pending[key]
+	if !ok {
+		return false
+	}
+	delete(p.pending, key)
+	ch <- conn
+	return true
+}
+
+// close removes the proxy session identified by key. It returns the
+// connection of a recipient that joined without being picked up by the
+// sender, if any.
+func (p *proxySessions) close(key proxySessionKey, ch chan net.Conn) net.Conn {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.pending, key)
+	select {
+	case conn := <-ch:
+		return conn
+	default:
+		return nil
+	}
+}
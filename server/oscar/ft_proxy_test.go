@@ -0,0 +1,254 @@
+package oscar
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// clientAddr is the IP address that test users connect to BOS and the proxy
+// from.
+var clientAddr = netip.MustParseAddr("203.0.113.7")
+
+// newTestProxyServer creates a proxy server that knows about the signed-on
+// users in screenNames, who are connected to BOS from clientAddr.
+func newTestProxyServer(t *testing.T, pairTimeout time.Duration, screenNames ...state.DisplayScreenName) FileTransferProxyServer {
+	sessionManager := state.NewInMemorySessionManager(slog.Default())
+	for _, screenName := range screenNames {
+		sess, err := sessionManager.AddSession(context.Background(), screenName)
+		require.NoError(t, err)
+		sess.SetRemoteAddr(clientAddr)
+	}
+	return FileTransferProxyServer{
+		IP:               net.ParseIP("10.0.0.1"),
+		Logger:           slog.Default(),
+		PairTimeout:      pairTimeout,
+		SessionRetriever: sessionManager,
+	}
+}
+
+// addrConn is a net.Conn with a fixed remote address.
+type addrConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// connectToProxy starts handling a new proxy connection from clientAddr in
+// the background and returns the client end of the connection.
+func connectToProxy(rt FileTransferProxyServer, sessions *proxySessions) net.Conn {
+	return connectToProxyFrom(rt, sessions, clientAddr)
+}
+
+// connectToProxyFrom starts handling a new proxy connection from addr in the
+// background and returns the client end of the connection.
+func connectToProxyFrom(rt FileTransferProxyServer, sessions *proxySessions, addr netip.Addr) net.Conn {
+	serverConn, clientConn := net.Pipe()
+	conn := addrConn{
+		Conn:       serverConn,
+		remoteAddr: net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, 1234)),
+	}
+	go func() {
+		_ = rt.handleNewConnection(context.Background(), conn, sessions)
+	}()
+	return clientConn
+}
+
+// readProxyPayload reads a proxy packet, checks its command, and unmarshalls
+// its payload into body.
+func readProxyPayload(t *testing.T, r io.Reader, wantCommand uint16, body any) {
+	pkt, err := wire.ReadProxyPacket(r, proxyMaxPayload)
+	require.NoError(t, err)
+	require.Equal(t, wantCommand, pkt.Command)
+	if body != nil {
+		require.NoError(t, wire.UnmarshalBE(body, bytes.NewBuffer(pkt.Payload)))
+	}
+}
+
+func TestFileTransferProxyServer_Relay(t *testing.T) {
+	rt := newTestProxyServer(t, time.Minute, "sender", "recipient")
+	sessions := newProxySessions()
+
+	// sender opens a proxy session
+	sender := connectToProxy(rt, sessions)
+	defer sender.Close()
+	require.NoError(t, wire.WriteProxyPacket(sender, wire.ProxyCommandInitSend, wire.ProxyInitSend{
+		ScreenName: "sender",
+		Cookie:     1234,
+	}))
+	ack := wire.ProxyAck{}
+	readProxyPayload(t, sender, wire.ProxyCommandAck, &ack)
+	assert.Equal(t, uint32(0x0A000001), ack.IP)
+	assert.NotZero(t, ack.Port)
+
+	// recipient joins the proxy session
+	recipient := connectToProxy(rt, sessions)
+	defer recipient.Close()
+	require.NoError(t, wire.WriteProxyPacket(recipient, wire.ProxyCommandInitRecv, wire.ProxyInitRecv{
+		ScreenName: "recipient",
+		Port:       ack.Port,
+		Cookie:     1234,
+	}))
+
+	readProxyPayload(t, sender, wire.ProxyCommandReady, nil)
+	readProxyPayload(t, recipient, wire.ProxyCommandReady, nil)
+
+	// data is relayed in both directions
+	go func() {
+		_, _ = sender.Write([]byte("file data"))
+	}()
+	buf := make([]byte, len("file data"))
+	_, err := io.ReadFull(recipient, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "file data", string(buf))
+
+	go func() {
+		_, _ = recipient.Write([]byte("ack"))
+	}()
+	buf = make([]byte, len("ack"))
+	_, err = io.ReadFull(sender, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ack", string(buf))
+
+	// closing one side closes the other
+	require.NoError(t, sender.Close())
+	_, err = recipient.Read(buf)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestFileTransferProxyServer_EndsTransfer(t *testing.T) {
+	limiter := state.NewFileTransferLimiter(1, 0)
+	sender := state.NewIdentScreenName("sender")
+	recipient := state.NewIdentScreenName("recipient")
+	require.True(t, limiter.StartTransfer([8]byte{0, 0, 0, 0, 0, 0, 0x04, 0xD2}, sender, recipient, true))
+
+	rt := newTestProxyServer(t, 10*time.Millisecond, "sender", "recipient")
+	rt.FileTransferEnder = limiter
+	sessions := newProxySessions()
+
+	// sender opens a proxy session for the transfer, but the recipient never
+	// joins
+	conn := connectToProxy(rt, sessions)
+	defer conn.Close()
+	require.NoError(t, wire.WriteProxyPacket(conn, wire.ProxyCommandInitSend, wire.ProxyInitSend{
+		ScreenName: "sender",
+		Cookie:     1234,
+	}))
+	readProxyPayload(t, conn, wire.ProxyCommandAck, &wire.ProxyAck{})
+	readProxyPayload(t, conn, wire.ProxyCommandError, nil)
+
+	// the sender's slot is released once the proxy session ends
+	assert.Eventually(t, func() bool {
+		return limiter.StartTransfer([8]byte{2}, sender, recipient, true)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileTransferProxyServer_Errors(t *testing.T) {
+	t.Run("sender isn't signed on", func(t *testing.T) {
+		rt := newTestProxyServer(t, time.Minute)
+		sender := connectToProxy(rt, newProxySessions())
+		defer sender.Close()
+
+		require.NoError(t, wire.WriteProxyPacket(sender, wire.ProxyCommandInitSend, wire.ProxyInitSend{
+			ScreenName: "sender",
+			Cookie:     1234,
+		}))
+		body := wire.ProxyError{}
+		readProxyPayload(t, sender, wire.ProxyCommandError, &body)
+		assert.Equal(t, wire.ProxyErrCodeBadRequest, body.Code)
+	})
+
+	t.Run("sender connects from a different IP than their BOS connection", func(t *testing.T) {
+		rt := newTestProxyServer(t, time.Minute, "sender")
+		sender := connectToProxyFrom(rt, newProxySessions(), netip.MustParseAddr("198.51.100.1"))
+		defer sender.Close()
+
+		require.NoError(t, wire.WriteProxyPacket(sender, wire.ProxyCommandInitSend, wire.ProxyInitSend{
+			ScreenName: "sender",
+			Cookie:     1234,
+		}))
+		body := wire.ProxyError{}
+		readProxyPayload(t, sender, wire.ProxyCommandError, &body)
+		assert.Equal(t, wire.ProxyErrCodeBadRequest, body.Code)
+	})
+
+	t.Run("recipient connects from a different IP than their BOS connection", func(t *testing.T) {
+		rt := newTestProxyServer(t, time.Minute, "recipient")
+		recipient := connectToProxyFrom(rt, newProxySessions(), netip.MustParseAddr("198.51.100.1"))
+		defer recipient.Close()
+
+		require.NoError(t, wire.WriteProxyPacket(recipient, wire.ProxyCommandInitRecv, wire.ProxyInitRecv{
+			ScreenName: "recipient",
+			Port:       1,
+			Cookie:     1234,
+		}))
+		body := wire.ProxyError{}
+		readProxyPayload(t, recipient, wire.ProxyCommandError, &body)
+		assert.Equal(t, wire.ProxyErrCodeBadRequest, body.Code)
+	})
+
+	t.Run("recipient joins unknown proxy session", func(t *testing.T) {
+		rt := newTestProxyServer(t, time.Minute, "recipient")
+		recipient := connectToProxy(rt, newProxySessions())
+		defer recipient.Close()
+
+		require.NoError(t, wire.WriteProxyPacket(recipient, wire.ProxyCommandInitRecv, wire.ProxyInitRecv{
+			ScreenName: "recipient",
+			Port:       1,
+			Cookie:     1234,
+		}))
+		body := wire.ProxyError{}
+		readProxyPayload(t, recipient, wire.ProxyCommandError, &body)
+		assert.Equal(t, wire.ProxyErrCodeBadRequest, body.Code)
+	})
+
+	t.Run("recipient doesn't join in time", func(t *testing.T) {
+		rt := newTestProxyServer(t, 10*time.Millisecond, "sender")
+		sessions := newProxySessions()
+		sender := connectToProxy(rt, sessions)
+		defer sender.Close()
+
+		require.NoError(t, wire.WriteProxyPacket(sender, wire.ProxyCommandInitSend, wire.ProxyInitSend{
+			ScreenName: "sender",
+			Cookie:     1234,
+		}))
+		ack := wire.ProxyAck{}
+		readProxyPayload(t, sender, wire.ProxyCommandAck, &ack)
+
+		body := wire.ProxyError{}
+		readProxyPayload(t, sender, wire.ProxyCommandError, &body)
+		assert.Equal(t, wire.ProxyErrCodeInitTimeout, body.Code)
+
+		// the expired proxy session can no longer be joined
+		assert.Eventually(t, func() bool {
+			sessions.mutex.Lock()
+			defer sessions.mutex.Unlock()
+			return len(sessions.pending) == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("unexpected init command", func(t *testing.T) {
+		rt := newTestProxyServer(t, time.Minute)
+		client := connectToProxy(rt, newProxySessions())
+		defer client.Close()
+
+		require.NoError(t, wire.WriteProxyPacket(client, wire.ProxyCommandReady, nil))
+		body := wire.ProxyError{}
+		readProxyPayload(t, client, wire.ProxyCommandError, &body)
+		assert.Equal(t, wire.ProxyErrCodeBadRequest, body.Code)
+	})
+}
@@ -14,11 +14,13 @@ import (
 
 type FeedbagService interface {
 	DeleteItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x0A_FeedbagDeleteItem) (wire.SNACMessage, error)
-	Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) (wire.SNACMessage, error)
-	QueryIfModified(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x05_FeedbagQueryIfModified) (wire.SNACMessage, error)
+	EndCluster(ctx context.Context, sess *state.Session)
+	Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) ([]wire.SNACMessage, error)
+	QueryIfModified(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x05_FeedbagQueryIfModified) ([]wire.SNACMessage, error)
+	RequestAuthorizeToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost) error
 	RespondAuthorizeToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost) error
 	RightsQuery(ctx context.Context, inFrame wire.SNACFrame) wire.SNACMessage
-	StartCluster(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x11_FeedbagStartCluster)
+	StartCluster(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x11_FeedbagStartCluster) *wire.SNACMessage
 	UpsertItem(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, items []wire.FeedbagItem) (wire.SNACMessage, error)
 	Use(ctx context.Context, sess *state.Session) error
 }
@@ -48,12 +50,17 @@ func (h FeedbagHandler) RightsQuery(ctx context.Context, _ *state.Session, inFra
 }
 
 func (h FeedbagHandler) Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, _ io.Reader, rw oscar.ResponseWriter) error {
-	outSNAC, err := h.FeedbagService.Query(ctx, sess, inFrame)
+	outSNACs, err := h.FeedbagService.Query(ctx, sess, inFrame)
 	if err != nil {
 		return err
 	}
-	h.LogRequest(ctx, inFrame, outSNAC)
-	return rw.SendSNAC(outSNAC.Frame, outSNAC.Body)
+	for _, outSNAC := range outSNACs {
+		h.LogRequest(ctx, inFrame, outSNAC)
+		if err := rw.SendSNAC(outSNAC.Frame, outSNAC.Body); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (h FeedbagHandler) QueryIfModified(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw oscar.ResponseWriter) error {
@@ -61,12 +68,17 @@ func (h FeedbagHandler) QueryIfModified(ctx context.Context, sess *state.Session
 	if err := wire.UnmarshalBE(&inBody, r); err != nil {
 		return err
 	}
-	outSNAC, err := h.FeedbagService.QueryIfModified(ctx, sess, inFrame, inBody)
+	outSNACs, err := h.FeedbagService.QueryIfModified(ctx, sess, inFrame, inBody)
 	if err != nil {
 		return err
 	}
-	h.LogRequestAndResponse(ctx, inFrame, inBody, outSNAC.Frame, outSNAC.Body)
-	return rw.SendSNAC(outSNAC.Frame, outSNAC.Body)
+	for _, outSNAC := range outSNACs {
+		h.LogRequestAndResponse(ctx, inFrame, inBody, outSNAC.Frame, outSNAC.Body)
+		if err := rw.SendSNAC(outSNAC.Frame, outSNAC.Body); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (h FeedbagHandler) Use(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, _ io.Reader, _ oscar.ResponseWriter) error {
@@ -113,21 +125,38 @@ func (h FeedbagHandler) DeleteItem(ctx context.Context, sess *state.Session, inF
 	return rw.SendSNAC(outSNAC.Frame, outSNAC.Body)
 }
 
-func (h FeedbagHandler) StartCluster(ctx context.Context, _ *state.Session, inFrame wire.SNACFrame, r io.Reader, _ oscar.ResponseWriter) error {
+func (h FeedbagHandler) StartCluster(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw oscar.ResponseWriter) error {
 	inBody := wire.SNAC_0x13_0x11_FeedbagStartCluster{}
 	if err := wire.UnmarshalBE(&inBody, r); err != nil {
 		return err
 	}
-	h.FeedbagService.StartCluster(ctx, inFrame, inBody)
-	h.LogRequest(ctx, inFrame, inBody)
-	return nil
+	outSNAC := h.FeedbagService.StartCluster(ctx, sess, inFrame, inBody)
+	if outSNAC == nil {
+		h.LogRequest(ctx, inFrame, inBody)
+		return nil
+	}
+	h.LogRequestAndResponse(ctx, inFrame, inBody, outSNAC.Frame, outSNAC.Body)
+	return rw.SendSNAC(outSNAC.Frame, outSNAC.Body)
 }
 
-func (h FeedbagHandler) EndCluster(ctx context.Context, _ *state.Session, inFrame wire.SNACFrame, _ io.Reader, _ oscar.ResponseWriter) error {
+func (h FeedbagHandler) EndCluster(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, _ io.Reader, _ oscar.ResponseWriter) error {
+	h.FeedbagService.EndCluster(ctx, sess)
 	h.LogRequest(ctx, inFrame, nil)
 	return nil
 }
 
+func (h FeedbagHandler) RequestAuthorizeToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw oscar.ResponseWriter) error {
+	inBody := wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{}
+	if err := wire.UnmarshalBE(&inBody, r); err != nil {
+		return err
+	}
+	if err := h.FeedbagService.RequestAuthorizeToHost(ctx, sess, inFrame, inBody); err != nil {
+		return err
+	}
+	h.LogRequest(ctx, inFrame, inBody)
+	return nil
+}
+
 func (h FeedbagHandler) RespondAuthorizeToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw oscar.ResponseWriter) error {
 	inBody := wire.SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost{}
 	if err := wire.UnmarshalBE(&inBody, r); err != nil {
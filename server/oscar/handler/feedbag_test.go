@@ -63,6 +63,8 @@ func TestFeedbagHandler_EndCluster(t *testing.T) {
 	}
 
 	svc := newMockFeedbagService(t)
+	svc.EXPECT().
+		EndCluster(mock.Anything, mock.Anything)
 	h := NewFeedbagHandler(slog.Default(), svc)
 	responseWriter := newMockResponseWriter(t)
 
@@ -144,7 +146,7 @@ func TestFeedbagHandler_Query(t *testing.T) {
 	svc := newMockFeedbagService(t)
 	svc.EXPECT().
 		Query(mock.Anything, mock.Anything, input.Frame).
-		Return(output, nil)
+		Return([]wire.SNACMessage{output}, nil)
 
 	h := NewFeedbagHandler(slog.Default(), svc)
 
@@ -182,7 +184,7 @@ func TestFeedbagHandler_QueryIfModified(t *testing.T) {
 	svc := newMockFeedbagService(t)
 	svc.EXPECT().
 		QueryIfModified(mock.Anything, mock.Anything, input.Frame, input.Body).
-		Return(output, nil)
+		Return([]wire.SNACMessage{output}, nil)
 
 	h := NewFeedbagHandler(slog.Default(), svc)
 
@@ -269,11 +271,48 @@ func TestFeedbagHandler_StartCluster(t *testing.T) {
 
 	svc := newMockFeedbagService(t)
 	svc.EXPECT().
-		StartCluster(mock.Anything, input.Frame, input.Body)
+		StartCluster(mock.Anything, mock.Anything, input.Frame, input.Body).
+		Return(nil)
+
+	h := NewFeedbagHandler(slog.Default(), svc)
+
+	responseWriter := newMockResponseWriter(t)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(input.Body, buf))
+
+	assert.NoError(t, h.StartCluster(nil, nil, input.Frame, buf, responseWriter))
+}
+
+func TestFeedbagHandler_StartCluster_Rejected(t *testing.T) {
+	input := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagStartCluster,
+		},
+		Body: wire.SNAC_0x13_0x11_FeedbagStartCluster{},
+	}
+	output := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagErr,
+		},
+		Body: wire.SNACError{
+			Code: wire.ErrorCodeRequestDenied,
+		},
+	}
+
+	svc := newMockFeedbagService(t)
+	svc.EXPECT().
+		StartCluster(mock.Anything, mock.Anything, input.Frame, input.Body).
+		Return(&output)
 
 	h := NewFeedbagHandler(slog.Default(), svc)
 
 	responseWriter := newMockResponseWriter(t)
+	responseWriter.EXPECT().
+		SendSNAC(output.Frame, output.Body).
+		Return(nil)
 
 	buf := &bytes.Buffer{}
 	assert.NoError(t, wire.MarshalBE(input.Body, buf))
@@ -346,6 +385,32 @@ func TestFeedbagHandler_Use(t *testing.T) {
 	assert.NoError(t, h.Use(nil, nil, input.Frame, buf, responseWriter))
 }
 
+func TestFeedbagHandler_RequestAuthorizeToHost(t *testing.T) {
+	input := wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.Feedbag,
+			SubGroup:  wire.FeedbagRequestAuthorizeToHost,
+		},
+		Body: wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost{
+			ScreenName: "theScreenName",
+			Reason:     "please add me",
+		},
+	}
+
+	svc := newMockFeedbagService(t)
+	svc.EXPECT().
+		RequestAuthorizeToHost(mock.Anything, mock.Anything, input.Frame, input.Body).
+		Return(nil)
+
+	h := NewFeedbagHandler(slog.Default(), svc)
+	responseWriter := newMockResponseWriter(t)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(input.Body, buf))
+
+	assert.NoError(t, h.RequestAuthorizeToHost(nil, nil, input.Frame, buf, responseWriter))
+}
+
 func TestFeedbagHandler_RespondAuthorizeToHost(t *testing.T) {
 	input := wire.SNACMessage{
 		Frame: wire.SNACFrame{
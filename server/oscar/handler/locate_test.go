@@ -33,7 +33,7 @@ func TestLocateHandler_GetDirInfo(t *testing.T) {
 
 	svc := newMockLocateService(t)
 	svc.EXPECT().
-		DirInfo(mock.Anything, input.Frame, input.Body).
+		DirInfo(mock.Anything, mock.Anything, input.Frame, input.Body).
 		Return(output, nil)
 
 	h := NewLocateHandler(svc, slog.Default())
@@ -83,23 +83,59 @@ func (_c *mockFeedbagService_DeleteItem_Call) RunAndReturn(run func(context.Cont
 	return _c
 }
 
+// EndCluster provides a mock function with given fields: ctx, sess
+func (_m *mockFeedbagService) EndCluster(ctx context.Context, sess *state.Session) {
+	_m.Called(ctx, sess)
+}
+
+// mockFeedbagService_EndCluster_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EndCluster'
+type mockFeedbagService_EndCluster_Call struct {
+	*mock.Call
+}
+
+// EndCluster is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sess *state.Session
+func (_e *mockFeedbagService_Expecter) EndCluster(ctx interface{}, sess interface{}) *mockFeedbagService_EndCluster_Call {
+	return &mockFeedbagService_EndCluster_Call{Call: _e.mock.On("EndCluster", ctx, sess)}
+}
+
+func (_c *mockFeedbagService_EndCluster_Call) Run(run func(ctx context.Context, sess *state.Session)) *mockFeedbagService_EndCluster_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*state.Session))
+	})
+	return _c
+}
+
+func (_c *mockFeedbagService_EndCluster_Call) Return() *mockFeedbagService_EndCluster_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *mockFeedbagService_EndCluster_Call) RunAndReturn(run func(context.Context, *state.Session)) *mockFeedbagService_EndCluster_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Query provides a mock function with given fields: ctx, sess, inFrame
-func (_m *mockFeedbagService) Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) (wire.SNACMessage, error) {
+func (_m *mockFeedbagService) Query(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame) ([]wire.SNACMessage, error) {
 	ret := _m.Called(ctx, sess, inFrame)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Query")
 	}
 
-	var r0 wire.SNACMessage
+	var r0 []wire.SNACMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame) (wire.SNACMessage, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame) ([]wire.SNACMessage, error)); ok {
 		return rf(ctx, sess, inFrame)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame) wire.SNACMessage); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame) []wire.SNACMessage); ok {
 		r0 = rf(ctx, sess, inFrame)
 	} else {
-		r0 = ret.Get(0).(wire.SNACMessage)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]wire.SNACMessage)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, *state.Session, wire.SNACFrame) error); ok {
@@ -131,33 +167,35 @@ func (_c *mockFeedbagService_Query_Call) Run(run func(ctx context.Context, sess
 	return _c
 }
 
-func (_c *mockFeedbagService_Query_Call) Return(_a0 wire.SNACMessage, _a1 error) *mockFeedbagService_Query_Call {
+func (_c *mockFeedbagService_Query_Call) Return(_a0 []wire.SNACMessage, _a1 error) *mockFeedbagService_Query_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *mockFeedbagService_Query_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame) (wire.SNACMessage, error)) *mockFeedbagService_Query_Call {
+func (_c *mockFeedbagService_Query_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame) ([]wire.SNACMessage, error)) *mockFeedbagService_Query_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // QueryIfModified provides a mock function with given fields: ctx, sess, inFrame, inBody
-func (_m *mockFeedbagService) QueryIfModified(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x05_FeedbagQueryIfModified) (wire.SNACMessage, error) {
+func (_m *mockFeedbagService) QueryIfModified(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x05_FeedbagQueryIfModified) ([]wire.SNACMessage, error) {
 	ret := _m.Called(ctx, sess, inFrame, inBody)
 
 	if len(ret) == 0 {
 		panic("no return value specified for QueryIfModified")
 	}
 
-	var r0 wire.SNACMessage
+	var r0 []wire.SNACMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) (wire.SNACMessage, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) ([]wire.SNACMessage, error)); ok {
 		return rf(ctx, sess, inFrame, inBody)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) wire.SNACMessage); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) []wire.SNACMessage); ok {
 		r0 = rf(ctx, sess, inFrame, inBody)
 	} else {
-		r0 = ret.Get(0).(wire.SNACMessage)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]wire.SNACMessage)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) error); ok {
@@ -190,12 +228,61 @@ func (_c *mockFeedbagService_QueryIfModified_Call) Run(run func(ctx context.Cont
 	return _c
 }
 
-func (_c *mockFeedbagService_QueryIfModified_Call) Return(_a0 wire.SNACMessage, _a1 error) *mockFeedbagService_QueryIfModified_Call {
+func (_c *mockFeedbagService_QueryIfModified_Call) Return(_a0 []wire.SNACMessage, _a1 error) *mockFeedbagService_QueryIfModified_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *mockFeedbagService_QueryIfModified_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) (wire.SNACMessage, error)) *mockFeedbagService_QueryIfModified_Call {
+func (_c *mockFeedbagService_QueryIfModified_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x05_FeedbagQueryIfModified) ([]wire.SNACMessage, error)) *mockFeedbagService_QueryIfModified_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RequestAuthorizeToHost provides a mock function with given fields: ctx, sess, inFrame, inBody
+func (_m *mockFeedbagService) RequestAuthorizeToHost(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost) error {
+	ret := _m.Called(ctx, sess, inFrame, inBody)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestAuthorizeToHost")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost) error); ok {
+		r0 = rf(ctx, sess, inFrame, inBody)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockFeedbagService_RequestAuthorizeToHost_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequestAuthorizeToHost'
+type mockFeedbagService_RequestAuthorizeToHost_Call struct {
+	*mock.Call
+}
+
+// RequestAuthorizeToHost is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sess *state.Session
+//   - inFrame wire.SNACFrame
+//   - inBody wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost
+func (_e *mockFeedbagService_Expecter) RequestAuthorizeToHost(ctx interface{}, sess interface{}, inFrame interface{}, inBody interface{}) *mockFeedbagService_RequestAuthorizeToHost_Call {
+	return &mockFeedbagService_RequestAuthorizeToHost_Call{Call: _e.mock.On("RequestAuthorizeToHost", ctx, sess, inFrame, inBody)}
+}
+
+func (_c *mockFeedbagService_RequestAuthorizeToHost_Call) Run(run func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost)) *mockFeedbagService_RequestAuthorizeToHost_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*state.Session), args[2].(wire.SNACFrame), args[3].(wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost))
+	})
+	return _c
+}
+
+func (_c *mockFeedbagService_RequestAuthorizeToHost_Call) Return(_a0 error) *mockFeedbagService_RequestAuthorizeToHost_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockFeedbagService_RequestAuthorizeToHost_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost) error) *mockFeedbagService_RequestAuthorizeToHost_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -296,9 +383,24 @@ func (_c *mockFeedbagService_RightsQuery_Call) RunAndReturn(run func(context.Con
 	return _c
 }
 
-// StartCluster provides a mock function with given fields: ctx, inFrame, inBody
-func (_m *mockFeedbagService) StartCluster(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x11_FeedbagStartCluster) {
-	_m.Called(ctx, inFrame, inBody)
+// StartCluster provides a mock function with given fields: ctx, sess, inFrame, inBody
+func (_m *mockFeedbagService) StartCluster(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x11_FeedbagStartCluster) *wire.SNACMessage {
+	ret := _m.Called(ctx, sess, inFrame, inBody)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartCluster")
+	}
+
+	var r0 *wire.SNACMessage
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x11_FeedbagStartCluster) *wire.SNACMessage); ok {
+		r0 = rf(ctx, sess, inFrame, inBody)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wire.SNACMessage)
+		}
+	}
+
+	return r0
 }
 
 // mockFeedbagService_StartCluster_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StartCluster'
@@ -308,25 +410,26 @@ type mockFeedbagService_StartCluster_Call struct {
 
 // StartCluster is a helper method to define mock.On call
 //   - ctx context.Context
+//   - sess *state.Session
 //   - inFrame wire.SNACFrame
 //   - inBody wire.SNAC_0x13_0x11_FeedbagStartCluster
-func (_e *mockFeedbagService_Expecter) StartCluster(ctx interface{}, inFrame interface{}, inBody interface{}) *mockFeedbagService_StartCluster_Call {
-	return &mockFeedbagService_StartCluster_Call{Call: _e.mock.On("StartCluster", ctx, inFrame, inBody)}
+func (_e *mockFeedbagService_Expecter) StartCluster(ctx interface{}, sess interface{}, inFrame interface{}, inBody interface{}) *mockFeedbagService_StartCluster_Call {
+	return &mockFeedbagService_StartCluster_Call{Call: _e.mock.On("StartCluster", ctx, sess, inFrame, inBody)}
 }
 
-func (_c *mockFeedbagService_StartCluster_Call) Run(run func(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x11_FeedbagStartCluster)) *mockFeedbagService_StartCluster_Call {
+func (_c *mockFeedbagService_StartCluster_Call) Run(run func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x13_0x11_FeedbagStartCluster)) *mockFeedbagService_StartCluster_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(wire.SNACFrame), args[2].(wire.SNAC_0x13_0x11_FeedbagStartCluster))
+		run(args[0].(context.Context), args[1].(*state.Session), args[2].(wire.SNACFrame), args[3].(wire.SNAC_0x13_0x11_FeedbagStartCluster))
 	})
 	return _c
 }
 
-func (_c *mockFeedbagService_StartCluster_Call) Return() *mockFeedbagService_StartCluster_Call {
-	_c.Call.Return()
+func (_c *mockFeedbagService_StartCluster_Call) Return(_a0 *wire.SNACMessage) *mockFeedbagService_StartCluster_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *mockFeedbagService_StartCluster_Call) RunAndReturn(run func(context.Context, wire.SNACFrame, wire.SNAC_0x13_0x11_FeedbagStartCluster)) *mockFeedbagService_StartCluster_Call {
+func (_c *mockFeedbagService_StartCluster_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x13_0x11_FeedbagStartCluster) *wire.SNACMessage) *mockFeedbagService_StartCluster_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -24,9 +24,9 @@ func (_m *mockLocateService) EXPECT() *mockLocateService_Expecter {
 	return &mockLocateService_Expecter{mock: &_m.Mock}
 }
 
-// DirInfo provides a mock function with given fields: ctx, frame, body
-func (_m *mockLocateService) DirInfo(ctx context.Context, frame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error) {
-	ret := _m.Called(ctx, frame, body)
+// DirInfo provides a mock function with given fields: ctx, sess, frame, body
+func (_m *mockLocateService) DirInfo(ctx context.Context, sess *state.Session, frame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error) {
+	ret := _m.Called(ctx, sess, frame, body)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DirInfo")
@@ -34,17 +34,17 @@ func (_m *mockLocateService) DirInfo(ctx context.Context, frame wire.SNACFrame,
 
 	var r0 wire.SNACMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error)); ok {
-		return rf(ctx, frame, body)
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error)); ok {
+		return rf(ctx, sess, frame, body)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) wire.SNACMessage); ok {
-		r0 = rf(ctx, frame, body)
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) wire.SNACMessage); ok {
+		r0 = rf(ctx, sess, frame, body)
 	} else {
 		r0 = ret.Get(0).(wire.SNACMessage)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) error); ok {
-		r1 = rf(ctx, frame, body)
+	if rf, ok := ret.Get(1).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) error); ok {
+		r1 = rf(ctx, sess, frame, body)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -59,15 +59,16 @@ type mockLocateService_DirInfo_Call struct {
 
 // DirInfo is a helper method to define mock.On call
 //   - ctx context.Context
+//   - sess *state.Session
 //   - frame wire.SNACFrame
 //   - body wire.SNAC_0x02_0x0B_LocateGetDirInfo
-func (_e *mockLocateService_Expecter) DirInfo(ctx interface{}, frame interface{}, body interface{}) *mockLocateService_DirInfo_Call {
-	return &mockLocateService_DirInfo_Call{Call: _e.mock.On("DirInfo", ctx, frame, body)}
+func (_e *mockLocateService_Expecter) DirInfo(ctx interface{}, sess interface{}, frame interface{}, body interface{}) *mockLocateService_DirInfo_Call {
+	return &mockLocateService_DirInfo_Call{Call: _e.mock.On("DirInfo", ctx, sess, frame, body)}
 }
 
-func (_c *mockLocateService_DirInfo_Call) Run(run func(ctx context.Context, frame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo)) *mockLocateService_DirInfo_Call {
+func (_c *mockLocateService_DirInfo_Call) Run(run func(ctx context.Context, sess *state.Session, frame wire.SNACFrame, body wire.SNAC_0x02_0x0B_LocateGetDirInfo)) *mockLocateService_DirInfo_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(wire.SNACFrame), args[2].(wire.SNAC_0x02_0x0B_LocateGetDirInfo))
+		run(args[0].(context.Context), args[1].(*state.Session), args[2].(wire.SNACFrame), args[3].(wire.SNAC_0x02_0x0B_LocateGetDirInfo))
 	})
 	return _c
 }
@@ -77,7 +78,7 @@ func (_c *mockLocateService_DirInfo_Call) Return(_a0 wire.SNACMessage, _a1 error
 	return _c
 }
 
-func (_c *mockLocateService_DirInfo_Call) RunAndReturn(run func(context.Context, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error)) *mockLocateService_DirInfo_Call {
+func (_c *mockLocateService_DirInfo_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x02_0x0B_LocateGetDirInfo) (wire.SNACMessage, error)) *mockLocateService_DirInfo_Call {
 	_c.Call.Return(run)
 	return _c
 }
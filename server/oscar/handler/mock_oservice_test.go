@@ -72,17 +72,17 @@ func (_c *mockOServiceService_ClientOnline_Call) RunAndReturn(run func(context.C
 	return _c
 }
 
-// ClientVersions provides a mock function with given fields: ctx, frame, bodyIn
-func (_m *mockOServiceService) ClientVersions(ctx context.Context, frame wire.SNACFrame, bodyIn wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage {
-	ret := _m.Called(ctx, frame, bodyIn)
+// ClientVersions provides a mock function with given fields: ctx, sess, frame, bodyIn
+func (_m *mockOServiceService) ClientVersions(ctx context.Context, sess *state.Session, frame wire.SNACFrame, bodyIn wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage {
+	ret := _m.Called(ctx, sess, frame, bodyIn)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ClientVersions")
 	}
 
 	var r0 wire.SNACMessage
-	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame, wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage); ok {
-		r0 = rf(ctx, frame, bodyIn)
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage); ok {
+		r0 = rf(ctx, sess, frame, bodyIn)
 	} else {
 		r0 = ret.Get(0).(wire.SNACMessage)
 	}
@@ -97,15 +97,16 @@ type mockOServiceService_ClientVersions_Call struct {
 
 // ClientVersions is a helper method to define mock.On call
 //   - ctx context.Context
+//   - sess *state.Session
 //   - frame wire.SNACFrame
 //   - bodyIn wire.SNAC_0x01_0x17_OServiceClientVersions
-func (_e *mockOServiceService_Expecter) ClientVersions(ctx interface{}, frame interface{}, bodyIn interface{}) *mockOServiceService_ClientVersions_Call {
-	return &mockOServiceService_ClientVersions_Call{Call: _e.mock.On("ClientVersions", ctx, frame, bodyIn)}
+func (_e *mockOServiceService_Expecter) ClientVersions(ctx interface{}, sess interface{}, frame interface{}, bodyIn interface{}) *mockOServiceService_ClientVersions_Call {
+	return &mockOServiceService_ClientVersions_Call{Call: _e.mock.On("ClientVersions", ctx, sess, frame, bodyIn)}
 }
 
-func (_c *mockOServiceService_ClientVersions_Call) Run(run func(ctx context.Context, frame wire.SNACFrame, bodyIn wire.SNAC_0x01_0x17_OServiceClientVersions)) *mockOServiceService_ClientVersions_Call {
+func (_c *mockOServiceService_ClientVersions_Call) Run(run func(ctx context.Context, sess *state.Session, frame wire.SNACFrame, bodyIn wire.SNAC_0x01_0x17_OServiceClientVersions)) *mockOServiceService_ClientVersions_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(wire.SNACFrame), args[2].(wire.SNAC_0x01_0x17_OServiceClientVersions))
+		run(args[0].(context.Context), args[1].(*state.Session), args[2].(wire.SNACFrame), args[3].(wire.SNAC_0x01_0x17_OServiceClientVersions))
 	})
 	return _c
 }
@@ -115,7 +116,7 @@ func (_c *mockOServiceService_ClientVersions_Call) Return(_a0 wire.SNACMessage)
 	return _c
 }
 
-func (_c *mockOServiceService_ClientVersions_Call) RunAndReturn(run func(context.Context, wire.SNACFrame, wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage) *mockOServiceService_ClientVersions_Call {
+func (_c *mockOServiceService_ClientVersions_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x01_0x17_OServiceClientVersions) wire.SNACMessage) *mockOServiceService_ClientVersions_Call {
 	_c.Call.Return(run)
 	return _c
 }
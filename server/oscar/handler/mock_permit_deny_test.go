@@ -263,6 +263,54 @@ func (_c *mockPermitDenyService_RightsQuery_Call) RunAndReturn(run func(context.
 	return _c
 }
 
+// SetGroupPermitMask provides a mock function with given fields: ctx, sess, body
+func (_m *mockPermitDenyService) SetGroupPermitMask(ctx context.Context, sess *state.Session, body wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask) error {
+	ret := _m.Called(ctx, sess, body)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetGroupPermitMask")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask) error); ok {
+		r0 = rf(ctx, sess, body)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockPermitDenyService_SetGroupPermitMask_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetGroupPermitMask'
+type mockPermitDenyService_SetGroupPermitMask_Call struct {
+	*mock.Call
+}
+
+// SetGroupPermitMask is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sess *state.Session
+//   - body wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask
+func (_e *mockPermitDenyService_Expecter) SetGroupPermitMask(ctx interface{}, sess interface{}, body interface{}) *mockPermitDenyService_SetGroupPermitMask_Call {
+	return &mockPermitDenyService_SetGroupPermitMask_Call{Call: _e.mock.On("SetGroupPermitMask", ctx, sess, body)}
+}
+
+func (_c *mockPermitDenyService_SetGroupPermitMask_Call) Run(run func(ctx context.Context, sess *state.Session, body wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask)) *mockPermitDenyService_SetGroupPermitMask_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*state.Session), args[2].(wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask))
+	})
+	return _c
+}
+
+func (_c *mockPermitDenyService_SetGroupPermitMask_Call) Return(_a0 error) *mockPermitDenyService_SetGroupPermitMask_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockPermitDenyService_SetGroupPermitMask_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask) error) *mockPermitDenyService_SetGroupPermitMask_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // newMockPermitDenyService creates a new instance of mockPermitDenyService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func newMockPermitDenyService(t interface {
@@ -5,8 +5,10 @@ package handler
 import (
 	context "context"
 
-	wire "github.com/mk6i/retro-aim-server/wire"
+	state "github.com/mk6i/retro-aim-server/state"
 	mock "github.com/stretchr/testify/mock"
+
+	wire "github.com/mk6i/retro-aim-server/wire"
 )
 
 // mockUserLookupService is an autogenerated mock type for the UserLookupService type
@@ -22,9 +24,9 @@ func (_m *mockUserLookupService) EXPECT() *mockUserLookupService_Expecter {
 	return &mockUserLookupService_Expecter{mock: &_m.Mock}
 }
 
-// FindByEmail provides a mock function with given fields: ctx, inFrame, inBody
-func (_m *mockUserLookupService) FindByEmail(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error) {
-	ret := _m.Called(ctx, inFrame, inBody)
+// FindByEmail provides a mock function with given fields: ctx, sess, inFrame, inBody
+func (_m *mockUserLookupService) FindByEmail(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error) {
+	ret := _m.Called(ctx, sess, inFrame, inBody)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FindByEmail")
@@ -32,17 +34,17 @@ func (_m *mockUserLookupService) FindByEmail(ctx context.Context, inFrame wire.S
 
 	var r0 wire.SNACMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error)); ok {
-		return rf(ctx, inFrame, inBody)
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error)); ok {
+		return rf(ctx, sess, inFrame, inBody)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) wire.SNACMessage); ok {
-		r0 = rf(ctx, inFrame, inBody)
+	if rf, ok := ret.Get(0).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) wire.SNACMessage); ok {
+		r0 = rf(ctx, sess, inFrame, inBody)
 	} else {
 		r0 = ret.Get(0).(wire.SNACMessage)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) error); ok {
-		r1 = rf(ctx, inFrame, inBody)
+	if rf, ok := ret.Get(1).(func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) error); ok {
+		r1 = rf(ctx, sess, inFrame, inBody)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -57,15 +59,16 @@ type mockUserLookupService_FindByEmail_Call struct {
 
 // FindByEmail is a helper method to define mock.On call
 //   - ctx context.Context
+//   - sess *state.Session
 //   - inFrame wire.SNACFrame
 //   - inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail
-func (_e *mockUserLookupService_Expecter) FindByEmail(ctx interface{}, inFrame interface{}, inBody interface{}) *mockUserLookupService_FindByEmail_Call {
-	return &mockUserLookupService_FindByEmail_Call{Call: _e.mock.On("FindByEmail", ctx, inFrame, inBody)}
+func (_e *mockUserLookupService_Expecter) FindByEmail(ctx interface{}, sess interface{}, inFrame interface{}, inBody interface{}) *mockUserLookupService_FindByEmail_Call {
+	return &mockUserLookupService_FindByEmail_Call{Call: _e.mock.On("FindByEmail", ctx, sess, inFrame, inBody)}
 }
 
-func (_c *mockUserLookupService_FindByEmail_Call) Run(run func(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail)) *mockUserLookupService_FindByEmail_Call {
+func (_c *mockUserLookupService_FindByEmail_Call) Run(run func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail)) *mockUserLookupService_FindByEmail_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(wire.SNACFrame), args[2].(wire.SNAC_0x0A_0x02_UserLookupFindByEmail))
+		run(args[0].(context.Context), args[1].(*state.Session), args[2].(wire.SNACFrame), args[3].(wire.SNAC_0x0A_0x02_UserLookupFindByEmail))
 	})
 	return _c
 }
@@ -75,7 +78,7 @@ func (_c *mockUserLookupService_FindByEmail_Call) Return(_a0 wire.SNACMessage, _
 	return _c
 }
 
-func (_c *mockUserLookupService_FindByEmail_Call) RunAndReturn(run func(context.Context, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error)) *mockUserLookupService_FindByEmail_Call {
+func (_c *mockUserLookupService_FindByEmail_Call) RunAndReturn(run func(context.Context, *state.Session, wire.SNACFrame, wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error)) *mockUserLookupService_FindByEmail_Call {
 	_c.Call.Return(run)
 	return _c
 }
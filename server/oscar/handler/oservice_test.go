@@ -181,7 +181,7 @@ func TestOServiceHandler_ClientVersions(t *testing.T) {
 
 	svc := newMockOServiceService(t)
 	svc.EXPECT().
-		ClientVersions(mock.Anything, input.Frame, input.Body).
+		ClientVersions(mock.Anything, mock.Anything, input.Frame, input.Body).
 		Return(output)
 
 	h := OServiceHandler{
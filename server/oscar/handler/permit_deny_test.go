@@ -185,13 +185,16 @@ func TestPermitDenyHandler_SetGroupPermitMask(t *testing.T) {
 	input := wire.SNACMessage{
 		Frame: wire.SNACFrame{
 			FoodGroup: wire.PermitDeny,
-			SubGroup:  wire.PermitDenyDelPermListEntries,
+			SubGroup:  wire.PermitDenySetGroupPermitMask,
 		},
 		Body: wire.SNAC_0x09_0x04_PermitDenySetGroupPermitMask{
 			PermMask: 1234,
 		},
 	}
 	svc := newMockPermitDenyService(t)
+	svc.EXPECT().
+		SetGroupPermitMask(mock.Anything, sess, input.Body).
+		Return(nil)
 
 	buf := &bytes.Buffer{}
 	assert.NoError(t, wire.MarshalBE(input.Body, buf))
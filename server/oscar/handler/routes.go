@@ -51,6 +51,7 @@ func NewBOSRouter(h Handlers) oscar.Router {
 	router.Register(wire.Feedbag, wire.FeedbagInsertItem, h.FeedbagHandler.InsertItem)
 	router.Register(wire.Feedbag, wire.FeedbagQuery, h.FeedbagHandler.Query)
 	router.Register(wire.Feedbag, wire.FeedbagQueryIfModified, h.FeedbagHandler.QueryIfModified)
+	router.Register(wire.Feedbag, wire.FeedbagRequestAuthorizeToHost, h.FeedbagHandler.RequestAuthorizeToHost)
 	router.Register(wire.Feedbag, wire.FeedbagRespondAuthorizeToHost, h.FeedbagHandler.RespondAuthorizeToHost)
 	router.Register(wire.Feedbag, wire.FeedbagRightsQuery, h.FeedbagHandler.RightsQuery)
 	router.Register(wire.Feedbag, wire.FeedbagStartCluster, h.FeedbagHandler.StartCluster)
@@ -12,7 +12,7 @@ import (
 )
 
 type UserLookupService interface {
-	FindByEmail(ctx context.Context, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error)
+	FindByEmail(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, inBody wire.SNAC_0x0A_0x02_UserLookupFindByEmail) (wire.SNACMessage, error)
 }
 
 func NewUserLookupHandler(logger *slog.Logger, userLookupService UserLookupService) UserLookupHandler {
@@ -29,12 +29,12 @@ type UserLookupHandler struct {
 	middleware.RouteLogger
 }
 
-func (h UserLookupHandler) FindByEmail(ctx context.Context, _ *state.Session, inFrame wire.SNACFrame, r io.Reader, rw oscar.ResponseWriter) error {
+func (h UserLookupHandler) FindByEmail(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw oscar.ResponseWriter) error {
 	inBody := wire.SNAC_0x0A_0x02_UserLookupFindByEmail{}
 	if err := wire.UnmarshalBE(&inBody, r); err != nil {
 		return err
 	}
-	outSNAC, err := h.UserLookupService.FindByEmail(ctx, inFrame, inBody)
+	outSNAC, err := h.UserLookupService.FindByEmail(ctx, sess, inFrame, inBody)
 	if err != nil {
 		return err
 	}
@@ -37,7 +37,7 @@ func TestUserLookupHandler_FindByEmail(t *testing.T) {
 
 	svc := newMockUserLookupService(t)
 	svc.EXPECT().
-		FindByEmail(mock.Anything, input.Frame, input.Body).
+		FindByEmail(mock.Anything, mock.Anything, input.Frame, input.Body).
 		Return(output, nil)
 
 	h := NewUserLookupHandler(slog.Default(), svc)
@@ -0,0 +1,54 @@
+package oscar
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/mk6i/retro-aim-server/state"
+)
+
+// newMeteredConn creates a new instance of meteredConn that counts traffic
+// toward total. If total is nil, traffic is only counted toward the attached
+// session.
+func newMeteredConn(rwc io.ReadWriteCloser, total *state.TrafficCounter) *meteredConn {
+	return &meteredConn{
+		ReadWriteCloser: rwc,
+		total:           total,
+	}
+}
+
+// meteredConn counts the bytes read from and written to a client connection.
+// Bytes are counted toward the server totals and, once a session is attached,
+// toward the session's own counters.
+type meteredConn struct {
+	io.ReadWriteCloser
+	sess  atomic.Pointer[state.TrafficCounter]
+	total *state.TrafficCounter
+}
+
+// attach starts counting traffic toward the session counter.
+func (c *meteredConn) attach(counter *state.TrafficCounter) {
+	c.sess.Store(counter)
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if c.total != nil {
+		c.total.AddBytesIn(n)
+	}
+	if sess := c.sess.Load(); sess != nil {
+		sess.AddBytesIn(n)
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	if c.total != nil {
+		c.total.AddBytesOut(n)
+	}
+	if sess := c.sess.Load(); sess != nil {
+		sess.AddBytesOut(n)
+	}
+	return n, err
+}
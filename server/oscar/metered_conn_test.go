@@ -0,0 +1,62 @@
+package oscar
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/state"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+func TestMeteredConn(t *testing.T) {
+	total := &state.TrafficCounter{}
+
+	// simulate two client connections, each with their own session
+	sessA := state.NewSession()
+	sessB := state.NewSession()
+
+	send := func(sess *state.Session) {
+		serverReader, clientWriter := io.Pipe()
+		clientReader, serverWriter := io.Pipe()
+		conn := newMeteredConn(pipeRWC{PipeReader: serverReader, PipeWriter: serverWriter}, total)
+
+		// traffic before the session is attached only counts toward the total
+		go func() {
+			_, _ = clientWriter.Write([]byte{1, 2, 3, 4})
+		}()
+		_, err := io.ReadFull(conn, make([]byte, 4))
+		assert.NoError(t, err)
+
+		conn.attach(sess.Traffic())
+
+		// server sends a FLAP frame to the client
+		go func() {
+			_, _ = io.Copy(io.Discard, clientReader)
+		}()
+		flapc := wire.NewFlapClient(0, nil, conn)
+		assert.NoError(t, flapc.SendKeepAlive())
+
+		// server receives a FLAP frame from the client
+		go func() {
+			_ = wire.NewFlapClient(0, nil, clientWriter).SendKeepAlive()
+		}()
+		flap := wire.FLAPFrame{}
+		assert.NoError(t, wire.UnmarshalBE(&flap, conn))
+
+		assert.NoError(t, conn.Close())
+	}
+
+	send(sessA)
+	send(sessB)
+
+	// a FLAP keepalive frame with no payload is 6 bytes long
+	assert.Equal(t, uint64(6), sessA.Traffic().BytesIn())
+	assert.Equal(t, uint64(6), sessA.Traffic().BytesOut())
+	assert.Equal(t, uint64(6), sessB.Traffic().BytesIn())
+	assert.Equal(t, uint64(6), sessB.Traffic().BytesOut())
+
+	assert.Equal(t, uint64(2*(4+6)), total.BytesIn())
+	assert.Equal(t, uint64(2*6), total.BytesOut())
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedLogFileLayout is the time layout of the suffix appended to the name
+// of a rotated log file.
+const rotatedLogFileLayout = "20060102T150405.000000000"
+
+// NewRotatingFile opens the log file at path for appending. Once the file
+// grows past maxSize bytes, it is renamed with a timestamp suffix and a new
+// file is started in its place. Rotated files older than retention are
+// removed by Prune. A maxSize of 0 disables rotation and a retention of 0
+// keeps rotated files forever.
+func NewRotatingFile(path string, maxSize int64, retention time.Duration) (*RotatingFile, error) {
+	r := &RotatingFile{
+		maxSize:   maxSize,
+		nowFn:     time.Now,
+		path:      path,
+		retention: retention,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RotatingFile is an io.Writer that writes to a log file with size-based
+// rotation and age-based retention. It is safe to use with multiple
+// goroutines.
+type RotatingFile struct {
+	file      *os.File
+	maxSize   int64
+	mutex     sync.Mutex
+	nowFn     func() time.Time
+	path      string
+	retention time.Duration
+	size      int64
+}
+
+// Write appends p to the log file, first rotating the file if p would push it
+// past the size limit.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file.
+func (r *RotatingFile) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
+
+// Prune removes rotated log files that are older than the retention period.
+// It does nothing if rotated files are kept forever.
+func (r *RotatingFile) Prune() error {
+	if r.retention <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	cutoff := r.nowFn().Add(-r.retention)
+	for _, match := range matches {
+		// ignore files that weren't created by rotation
+		rotatedAt, err := time.Parse(rotatedLogFileLayout, strings.TrimPrefix(match, r.path+"."))
+		if err != nil {
+			continue
+		}
+		if rotatedAt.Before(cutoff) {
+			if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to remove rotated log file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// open opens the log file and records its current size.
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to stat log file: %w", err)
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate renames the current log file with a timestamp suffix and opens a
+// new one in its place.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("unable to close log file: %w", err)
+	}
+	rotatedPath := r.path + "." + r.nowFn().UTC().Format(rotatedLogFileLayout)
+	if err := os.Rename(r.path, rotatedPath); err != nil {
+		// keep logging to the current file
+		return errors.Join(fmt.Errorf("unable to rotate log file: %w", err), r.open())
+	}
+	return r.open()
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ras.log")
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r, err := NewRotatingFile(path, 10, 0)
+	require.NoError(t, err)
+	defer r.Close()
+	r.nowFn = func() time.Time { return now }
+
+	// fits within the size limit
+	_, err = r.Write([]byte("12345"))
+	require.NoError(t, err)
+	_, err = r.Write([]byte("6789"))
+	require.NoError(t, err)
+
+	// pushes the file past the size limit
+	_, err = r.Write([]byte("abc"))
+	require.NoError(t, err)
+
+	rotated, err := os.ReadFile(path + "." + now.Format(rotatedLogFileLayout))
+	require.NoError(t, err)
+	assert.Equal(t, "123456789", string(rotated))
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", string(current))
+}
+
+func TestRotatingFile_Write_NoRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ras.log")
+
+	r, err := NewRotatingFile(path, 0, 0)
+	require.NoError(t, err)
+	defer r.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err = r.Write([]byte("0123456789"))
+		require.NoError(t, err)
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestRotatingFile_Prune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ras.log")
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	expired := path + "." + now.Add(-8*24*time.Hour).Format(rotatedLogFileLayout)
+	retained := path + "." + now.Add(-6*24*time.Hour).Format(rotatedLogFileLayout)
+	unrelated := path + ".bak"
+	for _, name := range []string{expired, retained, unrelated} {
+		require.NoError(t, os.WriteFile(name, []byte("log"), 0644))
+	}
+
+	r, err := NewRotatingFile(path, 10, 7*24*time.Hour)
+	require.NoError(t, err)
+	defer r.Close()
+	r.nowFn = func() time.Time { return now }
+
+	assert.NoError(t, r.Prune())
+
+	assert.NoFileExists(t, expired)
+	assert.FileExists(t, retained)
+	assert.FileExists(t, unrelated)
+	assert.FileExists(t, path)
+}
@@ -3,8 +3,8 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
-	"os"
 	"strings"
 
 	"github.com/mk6i/retro-aim-server/config"
@@ -19,25 +19,26 @@ var levelNames = map[slog.Leveler]string{
 	LevelTrace: "TRACE",
 }
 
-func NewLogger(cfg config.Config) *slog.Logger {
-	var level slog.Level
-	switch strings.ToLower(cfg.LogLevel) {
-	case "trace":
-		level = LevelTrace
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	case "info":
-		fallthrough
-	default:
-		level = slog.LevelInfo
+// NewLogger creates a logger that writes text records to w at the log level
+// set in cfg. Client request logs for the food groups listed in
+// cfg.FoodGroupLogLevels are written at the level set for their food group
+// instead.
+func NewLogger(cfg config.Config, w io.Writer) (*slog.Logger, error) {
+	level := parseLevel(cfg.LogLevel)
+	foodGroupLevels, err := ParseFoodGroupLogLevels(cfg.FoodGroupLogLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	// the underlying handler must let through records for the most verbose
+	// food group; handler filters the rest
+	minLevel := level
+	for _, l := range foodGroupLevels {
+		minLevel = min(minLevel, l)
 	}
 
 	opts := &slog.HandlerOptions{
-		Level: level,
+		Level: minLevel,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			if a.Key == slog.LevelKey {
 				level := a.Value.Any().(slog.Level)
@@ -51,11 +52,79 @@ func NewLogger(cfg config.Config) *slog.Logger {
 			return a
 		},
 	}
-	return slog.New(handler{slog.NewTextHandler(os.Stdout, opts)})
+	return slog.New(handler{
+		Handler:         slog.NewTextHandler(w, opts),
+		level:           level,
+		foodGroupLevels: foodGroupLevels,
+	}), nil
 }
 
+// parseLevel returns the log level named by s. It defaults to info for
+// unrecognized names.
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return LevelTrace
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ParseFoodGroupLogLevels parses a comma-separated list of foodgroup:level
+// pairs, such as "ICBM:debug,ChatNav:trace". Food group names are matched
+// case-insensitively. An empty string yields an empty map.
+func ParseFoodGroupLogLevels(s string) (map[uint16]slog.Level, error) {
+	levels := make(map[uint16]slog.Level)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, level, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("log level %q must be in foodgroup:level format", entry)
+		}
+		foodGroup, ok := wire.FoodGroupByName(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown food group in log level %q", entry)
+		}
+		switch strings.ToLower(strings.TrimSpace(level)) {
+		case "trace", "debug", "info", "warn", "error":
+		default:
+			return nil, fmt.Errorf("unknown level in log level %q", entry)
+		}
+		levels[foodGroup] = parseLevel(level)
+	}
+	return levels, nil
+}
+
+// foodGroupKey is the context key for the food group of the client request
+// being logged.
+type foodGroupKey struct{}
+
 type handler struct {
 	slog.Handler
+	// level is the minimum level of records that are written
+	level slog.Level
+	// foodGroupLevels overrides level for records logged for client requests
+	// of specific food groups
+	foodGroupLevels map[uint16]slog.Level
+}
+
+func (h handler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := h.level
+	if foodGroup, ok := ctx.Value(foodGroupKey{}).(uint16); ok {
+		if l, ok := h.foodGroupLevels[foodGroup]; ok {
+			minLevel = l
+		}
+	}
+	return level >= minLevel && h.Handler.Enabled(ctx, level)
 }
 
 func (h handler) Handle(ctx context.Context, r slog.Record) error {
@@ -69,11 +138,22 @@ func (h handler) Handle(ctx context.Context, r slog.Record) error {
 }
 
 func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return handler{h.Handler.WithAttrs(attrs)}
+	h.Handler = h.Handler.WithAttrs(attrs)
+	return h
 }
 
 func (h handler) WithGroup(name string) slog.Handler {
-	return h.Handler.WithGroup(name)
+	h.Handler = h.Handler.WithGroup(name)
+	return h
+}
+
+// withFoodGroup returns a copy of ctx that logs at the level set for
+// foodGroup.
+func withFoodGroup(ctx context.Context, foodGroup uint16) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, foodGroupKey{}, foodGroup)
 }
 
 type RouteLogger struct {
@@ -81,6 +161,7 @@ type RouteLogger struct {
 }
 
 func (rt RouteLogger) LogRequestAndResponse(ctx context.Context, inFrame wire.SNACFrame, inSNAC any, outFrame wire.SNACFrame, outSNAC any) {
+	ctx = withFoodGroup(ctx, inFrame.FoodGroup)
 	msg := "client request -> server response"
 	switch {
 	case rt.Logger.Enabled(ctx, LevelTrace):
@@ -111,6 +192,7 @@ func (rt RouteLogger) LogRequest(ctx context.Context, inFrame wire.SNACFrame, in
 }
 
 func LogRequest(ctx context.Context, logger *slog.Logger, inFrame wire.SNACFrame, inSNAC any) {
+	ctx = withFoodGroup(ctx, inFrame.FoodGroup)
 	const msg = "client request"
 	switch {
 	case logger.Enabled(ctx, LevelTrace):
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/mk6i/retro-aim-server/config"
+	"github.com/mk6i/retro-aim-server/wire"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFoodGroupLogLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    map[uint16]slog.Level
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  map[uint16]slog.Level{},
+		},
+		{
+			name:  "mixed case names and levels",
+			given: "ICBM:debug, chatnav:TRACE,",
+			want: map[uint16]slog.Level{
+				wire.ICBM:    slog.LevelDebug,
+				wire.ChatNav: LevelTrace,
+			},
+		},
+		{
+			name:    "missing level",
+			given:   "ICBM",
+			wantErr: true,
+		},
+		{
+			name:    "unknown food group",
+			given:   "Nope:debug",
+			wantErr: true,
+		},
+		{
+			name:    "unknown level",
+			given:   "ICBM:loud",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseFoodGroupLogLevels(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestRouteLogger_FoodGroupLogLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := NewLogger(config.Config{
+		LogLevel:           "info",
+		FoodGroupLogLevels: "ICBM:debug",
+	}, buf)
+	require.NoError(t, err)
+	rt := RouteLogger{Logger: logger}
+	ctx := context.Background()
+
+	// ICBM requests are logged at debug
+	rt.LogRequest(ctx, wire.SNACFrame{FoodGroup: wire.ICBM, SubGroup: wire.ICBMChannelMsgToHost}, nil)
+	assert.Contains(t, buf.String(), "food_group=ICBM")
+	assert.Contains(t, buf.String(), "sub_group=ICBMChannelMsgToHost")
+
+	// Feedbag requests stay at info
+	buf.Reset()
+	rt.LogRequest(ctx, wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagQuery}, nil)
+	rt.LogRequestAndResponse(ctx, wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagQuery}, nil,
+		wire.SNACFrame{FoodGroup: wire.Feedbag, SubGroup: wire.FeedbagReply}, nil)
+	assert.Empty(t, buf.String())
+
+	// logs outside of client requests stay at info
+	logger.DebugContext(ctx, "debug message")
+	assert.Empty(t, buf.String())
+	logger.InfoContext(ctx, "info message")
+	assert.Contains(t, buf.String(), "info message")
+}
+
+func TestNewLogger_InvalidFoodGroupLogLevels(t *testing.T) {
+	_, err := NewLogger(config.Config{FoodGroupLogLevels: "ICBM"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package oscar
+
+import (
+	"time"
+
+	"github.com/mk6i/retro-aim-server/config"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// rateLimitState is the state of a client's rate class.
+type rateLimitState uint8
+
+const (
+	rateLimitStateClear   rateLimitState = iota // client is within its limits
+	rateLimitStateAlert                         // client was warned that it's close to the limit
+	rateLimitStateLimited                       // client's SNACs are being dropped
+)
+
+// rateLimitAction tells the dispatcher what to do with an incoming SNAC.
+type rateLimitAction uint8
+
+const (
+	rateLimitActionAllow      rateLimitAction = iota // route the SNAC
+	rateLimitActionDrop                              // discard the SNAC
+	rateLimitActionDisconnect                        // close the connection
+)
+
+// rateLimiter enforces the rate class that RateParamsQuery advertises to the
+// client. It tracks the rolling average time in milliseconds between the
+// client's SNACs, as described in SNAC_0x01_0x07_OServiceRateParamsReply. All
+// SNACs belong to the same rate class. rateLimiter is not safe for concurrent
+// use; each connection has its own.
+type rateLimiter struct {
+	cfg      config.Config
+	level    uint32
+	lastTime time.Time
+	state    rateLimitState
+	timeNow  func() time.Time
+}
+
+// newRateLimiter creates a rateLimiter whose rolling average starts at the
+// max level. It returns nil if rate limit enforcement is disabled.
+func newRateLimiter(cfg config.Config) *rateLimiter {
+	if !cfg.RateLimitEnforce {
+		return nil
+	}
+	return &rateLimiter{
+		cfg:      cfg,
+		level:    cfg.RateLimitMaxLevel,
+		lastTime: time.Now(),
+		timeNow:  time.Now,
+	}
+}
+
+// track records an incoming SNAC and returns what to do with it. If the rate
+// class changed state, it also returns the rate change notification to send
+// to the client.
+func (r *rateLimiter) track() (rateLimitAction, *wire.SNACMessage) {
+	now := r.timeNow()
+	elapsed := now.Sub(r.lastTime).Milliseconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	r.lastTime = now
+
+	window := uint64(r.cfg.RateLimitWindowSize)
+	level := (uint64(r.level)*(window-1) + uint64(elapsed)) / window
+	r.level = uint32(min(level, uint64(r.cfg.RateLimitMaxLevel)))
+
+	switch {
+	case r.level < r.cfg.RateLimitDisconnectLevel:
+		return rateLimitActionDisconnect, nil
+	case r.state == rateLimitStateLimited:
+		if r.level < r.cfg.RateLimitClearLevel {
+			return rateLimitActionDrop, nil
+		}
+		r.state = rateLimitStateClear
+		return rateLimitActionAllow, r.notification(wire.OServiceRateChangeCodeClear, elapsed)
+	case r.level < r.cfg.RateLimitLimitLevel:
+		r.state = rateLimitStateLimited
+		return rateLimitActionDrop, r.notification(wire.OServiceRateChangeCodeLimit, elapsed)
+	case r.level < r.cfg.RateLimitAlertLevel:
+		if r.state == rateLimitStateAlert {
+			return rateLimitActionAllow, nil
+		}
+		r.state = rateLimitStateAlert
+		return rateLimitActionAllow, r.notification(wire.OServiceRateChangeCodeWarning, elapsed)
+	case r.state == rateLimitStateAlert && r.level >= r.cfg.RateLimitClearLevel:
+		r.state = rateLimitStateClear
+		return rateLimitActionAllow, r.notification(wire.OServiceRateChangeCodeClear, elapsed)
+	}
+
+	return rateLimitActionAllow, nil
+}
+
+// notification builds the SNAC that tells the client about a change to its
+// rate class.
+func (r *rateLimiter) notification(code uint16, elapsed int64) *wire.SNACMessage {
+	var currentState uint8
+	if r.state == rateLimitStateLimited {
+		currentState = 1
+	}
+	return &wire.SNACMessage{
+		Frame: wire.SNACFrame{
+			FoodGroup: wire.OService,
+			SubGroup:  wire.OServiceRateParamChange,
+		},
+		Body: wire.SNAC_0x01_0x0A_OServiceRateParamChange{
+			Code:            code,
+			ID:              0x01,
+			WindowSize:      r.cfg.RateLimitWindowSize,
+			ClearLevel:      r.cfg.RateLimitClearLevel,
+			AlertLevel:      r.cfg.RateLimitAlertLevel,
+			LimitLevel:      r.cfg.RateLimitLimitLevel,
+			DisconnectLevel: r.cfg.RateLimitDisconnectLevel,
+			CurrentLevel:    r.level,
+			MaxLevel:        r.cfg.RateLimitMaxLevel,
+			LastTime:        uint32(elapsed),
+			CurrentState:    currentState,
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package oscar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/config"
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+func TestRateLimiter_track(t *testing.T) {
+	cfg := config.Config{
+		RateLimitEnforce:         true,
+		RateLimitWindowSize:      2,
+		RateLimitClearLevel:      50,
+		RateLimitAlertLevel:      40,
+		RateLimitLimitLevel:      30,
+		RateLimitDisconnectLevel: 10,
+		RateLimitMaxLevel:        100,
+	}
+
+	now := time.Unix(1000, 0)
+	limiter := newRateLimiter(cfg)
+	limiter.lastTime = now
+	limiter.timeNow = func() time.Time {
+		return now
+	}
+
+	steps := []struct {
+		// name describes the step
+		name string
+		// elapsed is the time since the previous SNAC
+		elapsed time.Duration
+		// wantLevel is the expected rolling average after the SNAC
+		wantLevel uint32
+		// wantAction is the expected action for the SNAC
+		wantAction rateLimitAction
+		// wantCode is the expected rate change code, or 0 if no notification
+		// is expected
+		wantCode uint16
+	}{
+		{
+			name:       "slow SNAC stays at the max level",
+			elapsed:    100 * time.Millisecond,
+			wantLevel:  100,
+			wantAction: rateLimitActionAllow,
+		},
+		{
+			name:       "fast SNAC above the alert level",
+			elapsed:    0,
+			wantLevel:  50,
+			wantAction: rateLimitActionAllow,
+		},
+		{
+			name:       "drop below the alert level, warn client",
+			elapsed:    20 * time.Millisecond,
+			wantLevel:  35,
+			wantAction: rateLimitActionAllow,
+			wantCode:   wire.OServiceRateChangeCodeWarning,
+		},
+		{
+			name:       "stay below the alert level, don't warn again",
+			elapsed:    40 * time.Millisecond,
+			wantLevel:  37,
+			wantAction: rateLimitActionAllow,
+		},
+		{
+			name:       "drop below the limit level, limit client",
+			elapsed:    10 * time.Millisecond,
+			wantLevel:  23,
+			wantAction: rateLimitActionDrop,
+			wantCode:   wire.OServiceRateChangeCodeLimit,
+		},
+		{
+			name:       "stay limited until the clear level",
+			elapsed:    60 * time.Millisecond,
+			wantLevel:  41,
+			wantAction: rateLimitActionDrop,
+		},
+		{
+			name:       "reach the clear level, clear limit",
+			elapsed:    100 * time.Millisecond,
+			wantLevel:  70,
+			wantAction: rateLimitActionAllow,
+			wantCode:   wire.OServiceRateChangeCodeClear,
+		},
+		{
+			name:       "drop below the alert level again, warn client",
+			elapsed:    0,
+			wantLevel:  35,
+			wantAction: rateLimitActionAllow,
+			wantCode:   wire.OServiceRateChangeCodeWarning,
+		},
+		{
+			name:       "reach the clear level, clear warning",
+			elapsed:    100 * time.Millisecond,
+			wantLevel:  67,
+			wantAction: rateLimitActionAllow,
+			wantCode:   wire.OServiceRateChangeCodeClear,
+		},
+		{
+			name:       "drop below the alert level",
+			elapsed:    0,
+			wantLevel:  33,
+			wantAction: rateLimitActionAllow,
+			wantCode:   wire.OServiceRateChangeCodeWarning,
+		},
+		{
+			name:       "drop below the limit level",
+			elapsed:    0,
+			wantLevel:  16,
+			wantAction: rateLimitActionDrop,
+			wantCode:   wire.OServiceRateChangeCodeLimit,
+		},
+		{
+			name:       "drop below the disconnect level, disconnect client",
+			elapsed:    0,
+			wantLevel:  8,
+			wantAction: rateLimitActionDisconnect,
+		},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.elapsed)
+		action, notice := limiter.track()
+		assert.Equal(t, step.wantAction, action, step.name)
+		assert.Equal(t, step.wantLevel, limiter.level, step.name)
+		if step.wantCode == 0 {
+			assert.Nil(t, notice, step.name)
+			continue
+		}
+		if assert.NotNil(t, notice, step.name) {
+			assert.Equal(t, wire.SNACFrame{
+				FoodGroup: wire.OService,
+				SubGroup:  wire.OServiceRateParamChange,
+			}, notice.Frame, step.name)
+			body := notice.Body.(wire.SNAC_0x01_0x0A_OServiceRateParamChange)
+			assert.Equal(t, step.wantCode, body.Code, step.name)
+			assert.Equal(t, step.wantLevel, body.CurrentLevel, step.name)
+			assert.Equal(t, uint32(step.elapsed.Milliseconds()), body.LastTime, step.name)
+		}
+	}
+}
+
+func TestNewRateLimiter_Disabled(t *testing.T) {
+	assert.Nil(t, newRateLimiter(config.Config{RateLimitEnforce: false}))
+}
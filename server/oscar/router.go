@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"strconv"
+	"strings"
 
 	"github.com/mk6i/retro-aim-server/state"
 	"github.com/mk6i/retro-aim-server/wire"
@@ -71,3 +74,59 @@ func (rt Router) Handle(ctx context.Context, sess *state.Session, inFrame wire.S
 	}
 	return h.Handle(ctx, sess, inFrame, r, rw)
 }
+
+// ParseSNACList parses a comma-separated list of group:subGroup pairs, such as
+// "0x0001:0x0022,0x0001:0x0023". Numbers may be decimal or 0x-prefixed hex.
+// An empty string yields an empty list.
+func ParseSNACList(s string) ([]wire.SNACFrame, error) {
+	var frames []wire.SNACFrame
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, subGroup, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("SNAC %q must be in group:subgroup format", entry)
+		}
+		g, err := strconv.ParseUint(strings.TrimSpace(group), 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid food group in SNAC %q: %w", entry, err)
+		}
+		sg, err := strconv.ParseUint(strings.TrimSpace(subGroup), 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subgroup in SNAC %q: %w", entry, err)
+		}
+		frames = append(frames, wire.SNACFrame{
+			FoodGroup: uint16(g),
+			SubGroup:  uint16(sg),
+		})
+	}
+	return frames, nil
+}
+
+// IgnoreSNACs wraps a Handler so that the group:subGroup pairs in snacs are
+// accepted without a response when h has no route for them. Some clients send
+// vendor-specific SNACs and drop the connection if the server replies with an
+// error. SNACs that h routes are handled as usual.
+func IgnoreSNACs(h Handler, snacs []wire.SNACFrame, logger *slog.Logger) Handler {
+	if len(snacs) == 0 {
+		return h
+	}
+	ignored := make(map[uint16]map[uint16]bool)
+	for _, snac := range snacs {
+		if _, ok := ignored[snac.FoodGroup]; !ok {
+			ignored[snac.FoodGroup] = make(map[uint16]bool)
+		}
+		ignored[snac.FoodGroup][snac.SubGroup] = true
+	}
+	return HandlerFunc(func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw ResponseWriter) error {
+		err := h.Handle(ctx, sess, inFrame, r, rw)
+		if errors.Is(err, ErrRouteNotFound) && ignored[inFrame.FoodGroup][inFrame.SubGroup] {
+			logger.DebugContext(ctx, "ignoring allowlisted SNAC",
+				"food_group", wire.FoodGroupName(inFrame.FoodGroup), "sub_group", inFrame.SubGroup)
+			return nil
+		}
+		return err
+	})
+}
@@ -3,6 +3,7 @@ package oscar
 import (
 	"context"
 	"io"
+	"log/slog"
 	"testing"
 
 	"github.com/mk6i/retro-aim-server/state"
@@ -55,3 +56,81 @@ func TestRouter_ErrRouteNotFound(t *testing.T) {
 	}
 	assert.ErrorIs(t, r.Handle(nil, nil, frame, nil, nil), ErrRouteNotFound)
 }
+
+func TestParseSNACList(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    []wire.SNACFrame
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  nil,
+		},
+		{
+			name:  "hex and decimal pairs",
+			given: "0x0001:0x0050, 19:36,",
+			want: []wire.SNACFrame{
+				{FoodGroup: wire.OService, SubGroup: 0x0050},
+				{FoodGroup: wire.Feedbag, SubGroup: 0x0024},
+			},
+		},
+		{
+			name:    "missing subgroup",
+			given:   "0x0001",
+			wantErr: true,
+		},
+		{
+			name:    "invalid number",
+			given:   "0x0001:abc",
+			wantErr: true,
+		},
+		{
+			name:    "out of range",
+			given:   "0x10000:0x0001",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseSNACList(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestIgnoreSNACs(t *testing.T) {
+	r := NewRouter()
+
+	var called bool
+	r.Register(wire.OService, wire.OServiceNoop, func(ctx context.Context, sess *state.Session, inFrame wire.SNACFrame, r io.Reader, rw ResponseWriter) error {
+		called = true
+		return nil
+	})
+
+	h := IgnoreSNACs(r, []wire.SNACFrame{
+		{FoodGroup: wire.OService, SubGroup: 0x0050},
+		{FoodGroup: wire.OService, SubGroup: wire.OServiceNoop},
+	}, slog.Default())
+
+	// allowlisted SNAC without a route is accepted
+	frame := wire.SNACFrame{FoodGroup: wire.OService, SubGroup: 0x0050}
+	assert.NoError(t, h.Handle(nil, nil, frame, nil, nil))
+
+	// allowlisted SNAC with a route is still handled
+	frame = wire.SNACFrame{FoodGroup: wire.OService, SubGroup: wire.OServiceNoop}
+	assert.NoError(t, h.Handle(nil, nil, frame, nil, nil))
+	assert.True(t, called)
+
+	// SNAC that's not allowlisted is still unknown
+	frame = wire.SNACFrame{FoodGroup: wire.OService, SubGroup: 0x0051}
+	assert.ErrorIs(t, h.Handle(nil, nil, frame, nil, nil), ErrRouteNotFound)
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// NewAutoSuspender creates a new instance of AutoSuspender that suspends
+// users via banList once they receive threshold warnings within window. The
+// suspension lasts for cooldown. A threshold of 0 disables suspensions.
+func NewAutoSuspender(banList *BanList, threshold int, window time.Duration, cooldown time.Duration) *AutoSuspender {
+	return &AutoSuspender{
+		banList:   banList,
+		cooldown:  cooldown,
+		nowFn:     time.Now,
+		threshold: threshold,
+		warnings:  make(map[IdentScreenName][]time.Time),
+		window:    window,
+	}
+}
+
+// AutoSuspender tracks the warnings that users receive and suspends users
+// who are warned too often. It is safe to use with multiple goroutines.
+type AutoSuspender struct {
+	banList   *BanList
+	cooldown  time.Duration
+	mutex     sync.Mutex
+	nowFn     func() time.Time
+	threshold int
+	warnings  map[IdentScreenName][]time.Time
+	window    time.Duration
+}
+
+// RecordWarning records a warning received by screenName. It returns true if
+// the warning pushed the user to the threshold, in which case the user is
+// now suspended.
+func (a *AutoSuspender) RecordWarning(screenName IdentScreenName) bool {
+	if a.threshold <= 0 {
+		return false
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.nowFn()
+	a.expire(now)
+
+	a.warnings[screenName] = append(a.warnings[screenName], now)
+	if len(a.warnings[screenName]) < a.threshold {
+		return false
+	}
+
+	delete(a.warnings, screenName)
+	a.banList.Suspend(screenName, now.Add(a.cooldown))
+	return true
+}
+
+// expire drops warnings that fall outside the window.
+func (a *AutoSuspender) expire(now time.Time) {
+	cutoff := now.Add(-a.window)
+	for screenName, times := range a.warnings {
+		i := 0
+		for i < len(times) && !times[i].After(cutoff) {
+			i++
+		}
+		if i == len(times) {
+			delete(a.warnings, screenName)
+		} else {
+			a.warnings[screenName] = times[i:]
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoSuspender_RecordWarning(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFn := func() time.Time { return now }
+
+	banList := NewBanList("")
+	banList.nowFn = nowFn
+	suspender := NewAutoSuspender(banList, 3, time.Hour, 30*time.Minute)
+	suspender.nowFn = nowFn
+
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+
+	assert.False(t, suspender.RecordWarning(userA))
+	assert.False(t, suspender.RecordWarning(userA))
+	assert.False(t, suspender.RecordWarning(userB))
+
+	// warnings that fall outside the window don't count
+	now = now.Add(time.Hour)
+	assert.False(t, suspender.RecordWarning(userA))
+	assert.False(t, suspender.RecordWarning(userA))
+	assert.False(t, banList.Banned(userA))
+
+	// the third warning within the window suspends the user
+	assert.True(t, suspender.RecordWarning(userA))
+	assert.True(t, banList.Banned(userA))
+	assert.False(t, banList.Banned(userB))
+
+	// the suspension lifts after the cooldown
+	now = now.Add(30 * time.Minute)
+	assert.False(t, banList.Banned(userA))
+
+	// the count starts over after a suspension
+	assert.False(t, suspender.RecordWarning(userA))
+}
+
+func TestAutoSuspender_Disabled(t *testing.T) {
+	banList := NewBanList("")
+	suspender := NewAutoSuspender(banList, 0, time.Hour, time.Hour)
+
+	for i := 0; i < 10; i++ {
+		assert.False(t, suspender.RecordWarning(NewIdentScreenName("userA")))
+	}
+	assert.False(t, banList.Banned(NewIdentScreenName("userA")))
+}
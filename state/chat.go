@@ -39,6 +39,7 @@ type ChatRoom struct {
 	creator    IdentScreenName
 	exchange   uint16
 	name       string
+	topic      string
 }
 
 // Creator returns the screen name of the user who created the chat room.
@@ -51,6 +52,18 @@ func (c ChatRoom) Exchange() uint16 {
 	return c.exchange
 }
 
+// Topic returns the topic set by the room's moderators, or an empty string if
+// no topic is set.
+func (c ChatRoom) Topic() string {
+	return c.topic
+}
+
+// WithTopic returns a copy of the chat room with its topic set to topic.
+func (c ChatRoom) WithTopic(topic string) ChatRoom {
+	c.topic = topic
+	return c
+}
+
 // Name returns the chat room name.
 func (c ChatRoom) Name() string {
 	return c.name
@@ -120,3 +133,14 @@ func (c ChatRoom) TLVList() []wire.TLV {
 		wire.NewTLVBE(wire.ChatRoomTLVMaxMsgVisLen, uint16(1024)),
 	}
 }
+
+// ChatTranscriptEntry is a chat room message recorded in the room's
+// transcript.
+type ChatTranscriptEntry struct {
+	// ScreenName is the screen name of the user who sent the message.
+	ScreenName DisplayScreenName
+	// Text is the plaintext message text.
+	Text string
+	// Sent is when the message was sent.
+	Sent time.Time
+}
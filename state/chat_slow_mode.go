@@ -0,0 +1,84 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// NewChatSlowMode creates a new instance of ChatSlowMode with slow mode
+// disabled for all chat rooms.
+func NewChatSlowMode() *ChatSlowMode {
+	return &ChatSlowMode{
+		intervals: make(map[string]time.Duration),
+		lastSent:  make(map[string]map[IdentScreenName]time.Time),
+		nowFn:     time.Now,
+	}
+}
+
+// ChatSlowMode enforces a per-room minimum interval between consecutive chat
+// messages sent by the same user. It is safe to use with multiple goroutines.
+type ChatSlowMode struct {
+	intervals map[string]time.Duration
+	lastSent  map[string]map[IdentScreenName]time.Time
+	mutex     sync.Mutex
+	nowFn     func() time.Time
+}
+
+// SetSlowMode sets the minimum interval between a user's messages in the chat
+// room identified by cookie. An interval of 0 disables slow mode.
+func (c *ChatSlowMode) SetSlowMode(cookie string, interval time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// start every user with a clean slate when the interval changes
+	delete(c.lastSent, cookie)
+
+	if interval <= 0 {
+		delete(c.intervals, cookie)
+		return
+	}
+	c.intervals[cookie] = interval
+}
+
+// AllowMessage indicates whether the user may send a message to the chat
+// room identified by cookie. If the message is allowed, the send time is
+// recorded. Otherwise, it returns the room's slow mode interval.
+func (c *ChatSlowMode) AllowMessage(cookie string, screenName IdentScreenName) (bool, time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	interval, ok := c.intervals[cookie]
+	if !ok {
+		return true, 0
+	}
+
+	now := c.nowFn()
+	c.expire(now)
+
+	if last, ok := c.lastSent[cookie][screenName]; ok && now.Sub(last) < interval {
+		return false, interval
+	}
+
+	if _, ok := c.lastSent[cookie]; !ok {
+		c.lastSent[cookie] = make(map[IdentScreenName]time.Time)
+	}
+	c.lastSent[cookie][screenName] = now
+
+	return true, interval
+}
+
+// expire forgets send times that are older than their room's interval, which
+// no longer hold back the next message. The caller must hold the mutex.
+func (c *ChatSlowMode) expire(now time.Time) {
+	for cookie, users := range c.lastSent {
+		interval := c.intervals[cookie]
+		for screenName, last := range users {
+			if now.Sub(last) >= interval {
+				delete(users, screenName)
+			}
+		}
+		if len(users) == 0 {
+			delete(c.lastSent, cookie)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatSlowMode_AllowMessage(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	slowMode := NewChatSlowMode()
+	slowMode.nowFn = func() time.Time { return now }
+
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+
+	// slow mode is disabled by default
+	allowed, _ := slowMode.AllowMessage("room-1", userA)
+	assert.True(t, allowed)
+	allowed, _ = slowMode.AllowMessage("room-1", userA)
+	assert.True(t, allowed)
+
+	slowMode.SetSlowMode("room-1", 10*time.Second)
+
+	allowed, interval := slowMode.AllowMessage("room-1", userA)
+	assert.True(t, allowed)
+	assert.Equal(t, 10*time.Second, interval)
+
+	// rapid second message is rejected
+	now = now.Add(5 * time.Second)
+	allowed, interval = slowMode.AllowMessage("room-1", userA)
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, interval)
+
+	// other users and rooms are unaffected
+	allowed, _ = slowMode.AllowMessage("room-1", userB)
+	assert.True(t, allowed)
+	allowed, _ = slowMode.AllowMessage("room-2", userA)
+	assert.True(t, allowed)
+
+	// message is allowed after the interval elapses
+	now = now.Add(5 * time.Second)
+	allowed, _ = slowMode.AllowMessage("room-1", userA)
+	assert.True(t, allowed)
+
+	// zero disables slow mode
+	slowMode.SetSlowMode("room-1", 0)
+	allowed, _ = slowMode.AllowMessage("room-1", userA)
+	assert.True(t, allowed)
+}
+
+func TestChatSlowMode_ExpiresSendTimes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	slowMode := NewChatSlowMode()
+	slowMode.nowFn = func() time.Time { return now }
+
+	slowMode.SetSlowMode("room-1", 10*time.Second)
+	slowMode.SetSlowMode("room-2", 30*time.Second)
+
+	allowed, _ := slowMode.AllowMessage("room-1", NewIdentScreenName("userA"))
+	assert.True(t, allowed)
+	allowed, _ = slowMode.AllowMessage("room-2", NewIdentScreenName("userB"))
+	assert.True(t, allowed)
+
+	// userA's send time is older than room-1's interval and is forgotten
+	now = now.Add(15 * time.Second)
+	allowed, _ = slowMode.AllowMessage("room-2", NewIdentScreenName("userC"))
+	assert.True(t, allowed)
+	assert.Equal(t, map[string]map[IdentScreenName]time.Time{
+		"room-2": {
+			NewIdentScreenName("userB"): now.Add(-15 * time.Second),
+			NewIdentScreenName("userC"): now,
+		},
+	}, slowMode.lastSent)
+}
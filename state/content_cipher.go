@@ -0,0 +1,79 @@
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// contentCipherPrefix marks a stored value as ciphertext produced by
+// ContentCipher. Values without the prefix are treated as plaintext, which
+// allows content stored before encryption was enabled to be read.
+var contentCipherPrefix = []byte("enc:v1:")
+
+// NewContentCipher creates a new instance of ContentCipher that encrypts with
+// AES-GCM. The key must be 16, 24, or 32 bytes long to select AES-128,
+// AES-192, or AES-256.
+func NewContentCipher(key []byte) (*ContentCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create AES cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCM cipher: %w", err)
+	}
+	return &ContentCipher{aead: aead}, nil
+}
+
+// ContentCipher encrypts private content, such as profiles and offline
+// messages, before it's written to the database. A nil *ContentCipher passes
+// content through unchanged.
+type ContentCipher struct {
+	aead cipher.AEAD
+}
+
+// Encrypt encrypts plaintext with a random nonce. The result is the
+// base64-encoded nonce and ciphertext, preceded by contentCipherPrefix.
+func (c *ContentCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+
+	out := make([]byte, len(contentCipherPrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, contentCipherPrefix)
+	base64.StdEncoding.Encode(out[len(contentCipherPrefix):], sealed)
+	return out, nil
+}
+
+// Decrypt reverses Encrypt. Data that lacks contentCipherPrefix is returned
+// as-is.
+func (c *ContentCipher) Decrypt(data []byte) ([]byte, error) {
+	if c == nil || !bytes.HasPrefix(data, contentCipherPrefix) {
+		return data, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(data[len(contentCipherPrefix):]))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode ciphertext: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt content: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentCipher_RoundTrip(t *testing.T) {
+	c, err := NewContentCipher(bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+
+	ciphertext, err := c.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(ciphertext, contentCipherPrefix))
+	assert.NotContains(t, string(ciphertext), "hello")
+
+	// each encryption uses a fresh nonce
+	ciphertext2, err := c.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, ciphertext, ciphertext2)
+
+	plaintext, err := c.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+}
+
+func TestContentCipher_DecryptPlaintext(t *testing.T) {
+	c, err := NewContentCipher(bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+
+	plaintext, err := c.Decrypt([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+}
+
+func TestContentCipher_DecryptWrongKey(t *testing.T) {
+	c1, err := NewContentCipher(bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+	c2, err := NewContentCipher(bytes.Repeat([]byte{0x02}, 32))
+	assert.NoError(t, err)
+
+	ciphertext, err := c1.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+
+	_, err = c2.Decrypt(ciphertext)
+	assert.Error(t, err)
+}
+
+func TestContentCipher_Nil(t *testing.T) {
+	var c *ContentCipher
+
+	ciphertext, err := c.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), ciphertext)
+
+	plaintext, err := c.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+}
+
+func TestNewContentCipher_InvalidKey(t *testing.T) {
+	_, err := NewContentCipher([]byte("short"))
+	assert.Error(t, err)
+}
@@ -5,9 +5,11 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/mk6i/retro-aim-server/wire"
@@ -16,8 +18,25 @@ import (
 // authCookieLen is the fixed auth cookie length.
 const authCookieLen = 256
 
-func NewHMACCookieBaker() (HMACCookieBaker, error) {
-	cb := HMACCookieBaker{}
+// ErrCookieExpired indicates that a cookie is past its expiry time.
+var ErrCookieExpired = errors.New("HMAC cookie expired")
+
+// ErrCookieRedeemed indicates that a single-use cookie was already redeemed.
+var ErrCookieRedeemed = errors.New("HMAC cookie already redeemed")
+
+// NewHMACCookieBaker creates a new HMACCookieBaker that issues cookies that
+// are valid for the duration of ttl. If singleUse is true, each cookie can be
+// cracked only once, so that an intercepted cookie can't be replayed.
+func NewHMACCookieBaker(ttl time.Duration, singleUse bool) (HMACCookieBaker, error) {
+	cb := HMACCookieBaker{
+		nowFn: time.Now,
+		ttl:   ttl,
+	}
+	if singleUse {
+		cb.redeemed = &redeemedCookies{
+			nonces: make(map[uint64]time.Time),
+		}
+	}
 	cb.key = make([]byte, 32)
 	if _, err := io.ReadFull(rand.Reader, cb.key); err != nil {
 		return cb, fmt.Errorf("cannot generate random HMAC key: %w", err)
@@ -26,12 +45,20 @@ func NewHMACCookieBaker() (HMACCookieBaker, error) {
 }
 
 type HMACCookieBaker struct {
-	key []byte
+	key      []byte
+	nowFn    func() time.Time
+	redeemed *redeemedCookies
+	ttl      time.Duration
 }
 
 func (c HMACCookieBaker) Issue(data []byte) ([]byte, error) {
+	nonce := make([]byte, 8)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("cannot generate cookie nonce: %w", err)
+	}
 	payload := hmacTokenPayload{
-		Expiry: uint32(time.Now().Add(1 * time.Minute).Unix()),
+		Expiry: uint32(c.nowFn().Add(c.ttl).Unix()),
+		Nonce:  binary.BigEndian.Uint64(nonce),
 		Data:   data,
 	}
 	buf := &bytes.Buffer{}
@@ -78,8 +105,13 @@ func (c HMACCookieBaker) Crack(data []byte) ([]byte, error) {
 	}
 
 	expiry := time.Unix(int64(payload.Expiry), 0)
-	if expiry.Before(time.Now()) {
-		return nil, errors.New("HMAC cookie expired")
+	now := c.nowFn()
+	if expiry.Before(now) {
+		return nil, ErrCookieExpired
+	}
+
+	if c.redeemed != nil && !c.redeemed.redeem(payload.Nonce, expiry, now) {
+		return nil, ErrCookieRedeemed
 	}
 
 	return payload.Data, nil
@@ -87,7 +119,37 @@ func (c HMACCookieBaker) Crack(data []byte) ([]byte, error) {
 
 type hmacTokenPayload struct {
 	Expiry uint32
-	Data   []byte `oscar:"len_prefix=uint16"`
+	// Nonce makes each cookie unique, even when the same data is issued
+	// twice within the same second.
+	Nonce uint64
+	Data  []byte `oscar:"len_prefix=uint16"`
+}
+
+// redeemedCookies tracks the nonces of single-use cookies that have been
+// cracked. A nonce is forgotten once its cookie expires, since an expired
+// cookie is refused anyway. It is safe to use with multiple goroutines.
+type redeemedCookies struct {
+	mutex  sync.Mutex
+	nonces map[uint64]time.Time
+}
+
+// redeem marks the cookie identified by nonce as redeemed. It returns false if
+// the cookie was already redeemed.
+func (r *redeemedCookies) redeem(nonce uint64, expiry time.Time, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for n, exp := range r.nonces {
+		if exp.Before(now) {
+			delete(r.nonces, n)
+		}
+	}
+
+	if _, ok := r.nonces[nonce]; ok {
+		return false
+	}
+	r.nonces[nonce] = expiry
+	return true
 }
 
 type hmacToken struct {
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACCookieBaker_IssueAndCrack(t *testing.T) {
+	baker, err := NewHMACCookieBaker(time.Minute, false)
+	assert.NoError(t, err)
+
+	cookie, err := baker.Issue([]byte("the-data"))
+	assert.NoError(t, err)
+	assert.Len(t, cookie, authCookieLen)
+
+	data, err := baker.Crack(cookie)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("the-data"), data)
+}
+
+func TestHMACCookieBaker_CrackExpiredCookie(t *testing.T) {
+	baker, err := NewHMACCookieBaker(10*time.Second, false)
+	assert.NoError(t, err)
+
+	issueTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	baker.nowFn = func() time.Time {
+		return issueTime
+	}
+	cookie, err := baker.Issue([]byte("the-data"))
+	assert.NoError(t, err)
+
+	// cookie is still valid right before the TTL elapses
+	baker.nowFn = func() time.Time {
+		return issueTime.Add(9 * time.Second)
+	}
+	_, err = baker.Crack(cookie)
+	assert.NoError(t, err)
+
+	// cookie is refused after the TTL elapses
+	baker.nowFn = func() time.Time {
+		return issueTime.Add(11 * time.Second)
+	}
+	_, err = baker.Crack(cookie)
+	assert.ErrorIs(t, err, ErrCookieExpired)
+}
+
+func TestHMACCookieBaker_CrackInvalidSignature(t *testing.T) {
+	baker1, err := NewHMACCookieBaker(time.Minute, false)
+	assert.NoError(t, err)
+	baker2, err := NewHMACCookieBaker(time.Minute, false)
+	assert.NoError(t, err)
+
+	cookie, err := baker1.Issue([]byte("the-data"))
+	assert.NoError(t, err)
+
+	_, err = baker2.Crack(cookie)
+	assert.Error(t, err)
+}
+
+func TestHMACCookieBaker_CrackSingleUse(t *testing.T) {
+	baker, err := NewHMACCookieBaker(10*time.Second, true)
+	assert.NoError(t, err)
+
+	issueTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	baker.nowFn = func() time.Time {
+		return issueTime
+	}
+
+	// cookies issued for the same data within the same second are distinct
+	cookie1, err := baker.Issue([]byte("the-data"))
+	assert.NoError(t, err)
+	cookie2, err := baker.Issue([]byte("the-data"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, cookie1, cookie2)
+
+	// the first redemption succeeds
+	data, err := baker.Crack(cookie1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("the-data"), data)
+
+	// a replay is refused
+	_, err = baker.Crack(cookie1)
+	assert.ErrorIs(t, err, ErrCookieRedeemed)
+
+	// other cookies are unaffected
+	_, err = baker.Crack(cookie2)
+	assert.NoError(t, err)
+
+	// a replay after expiry is refused as expired
+	baker.nowFn = func() time.Time {
+		return issueTime.Add(11 * time.Second)
+	}
+	_, err = baker.Crack(cookie1)
+	assert.ErrorIs(t, err, ErrCookieExpired)
+}
@@ -0,0 +1,464 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+// DefaultBuddyGroup is the group that buddies are added to when no group is
+// specified and no default group is configured.
+const DefaultBuddyGroup = "Buddies"
+
+// AddBuddyToFeedbag returns the feedbag items that must be inserted and
+// updated in order to add buddy to group. If the group does not exist, it's
+// created and added to the root group. If group is empty, the buddy is added
+// to DefaultBuddyGroup, since clients may mishandle buddies in an unnamed
+// group. Shared groups are never used, even if one has the same name. Return
+// ErrFeedbagFull if the feedbag has used up the group or item IDs.
+func AddBuddyToFeedbag(items []wire.FeedbagItem, buddy DisplayScreenName, group string) (inserted []wire.FeedbagItem, updated []wire.FeedbagItem, err error) {
+	if group == "" {
+		group = DefaultBuddyGroup
+	}
+	return addBuddyToFeedbag(items, buddy, group, false)
+}
+
+// addBuddyToFeedbag adds buddy to the group named group that is a shared
+// group if shared is true, or a user group otherwise. A new group is marked
+// as shared if shared is true.
+func addBuddyToFeedbag(items []wire.FeedbagItem, buddy DisplayScreenName, group string, shared bool) (inserted []wire.FeedbagItem, updated []wire.FeedbagItem, err error) {
+	var root, grp *wire.FeedbagItem
+	var maxGroupID, maxItemID uint16
+	for i, item := range items {
+		if item.ClassID == wire.FeedbagClassIdGroup {
+			switch {
+			case item.GroupID == 0:
+				root = &items[i]
+			case item.Name == group && IsSharedGroup(item) == shared:
+				grp = &items[i]
+			}
+		}
+		maxGroupID = max(maxGroupID, item.GroupID)
+		maxItemID = max(maxItemID, item.ItemID)
+	}
+
+	// new IDs are allocated past the highest ID in use, so none are left
+	// once the highest possible ID is taken
+	if maxItemID == math.MaxUint16 {
+		return nil, nil, ErrFeedbagFull
+	}
+
+	newGroup := grp == nil
+	if newGroup {
+		if maxGroupID == math.MaxUint16 {
+			return nil, nil, ErrFeedbagFull
+		}
+		grp = &wire.FeedbagItem{
+			Name:    group,
+			GroupID: maxGroupID + 1,
+			ClassID: wire.FeedbagClassIdGroup,
+		}
+		if shared {
+			grp.Append(wire.NewTLVBE(wire.FeedbagAttributesShared, []byte{}))
+		}
+		newRoot := root == nil
+		if newRoot {
+			root = &wire.FeedbagItem{
+				ClassID: wire.FeedbagClassIdGroup,
+			}
+		}
+		rootCopy := *root
+		if err := appendFeedbagOrder(&rootCopy, grp.GroupID); err != nil {
+			return nil, nil, err
+		}
+		if newRoot {
+			inserted = append(inserted, rootCopy)
+		} else {
+			updated = append(updated, rootCopy)
+		}
+	}
+
+	buddyItem := wire.FeedbagItem{
+		Name:    buddy.String(),
+		GroupID: grp.GroupID,
+		ItemID:  maxItemID + 1,
+		ClassID: wire.FeedbagClassIdBuddy,
+	}
+
+	grpCopy := *grp
+	if err := appendFeedbagOrder(&grpCopy, buddyItem.ItemID); err != nil {
+		return nil, nil, err
+	}
+	if newGroup {
+		inserted = append(inserted, grpCopy)
+	} else {
+		updated = append(updated, grpCopy)
+	}
+	inserted = append(inserted, buddyItem)
+
+	return inserted, updated, nil
+}
+
+// RemoveBuddyFromFeedbag returns the buddy items that must be deleted and
+// the group items that must be updated in order to remove buddy from all
+// groups. Shared groups are left alone.
+func RemoveBuddyFromFeedbag(items []wire.FeedbagItem, buddy IdentScreenName) (deleted []wire.FeedbagItem, updated []wire.FeedbagItem, err error) {
+	shared := SharedGroupIDs(items)
+	for _, item := range items {
+		if item.ClassID == wire.FeedbagClassIdBuddy && NewIdentScreenName(item.Name) == buddy && !shared[item.GroupID] {
+			deleted = append(deleted, item)
+		}
+	}
+
+	for _, item := range items {
+		if item.ClassID != wire.FeedbagClassIdGroup || item.GroupID == 0 {
+			continue
+		}
+		order, err := feedbagOrder(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		newOrder := make([]uint16, 0, len(order))
+		for _, itemID := range order {
+			if !slices.ContainsFunc(deleted, func(d wire.FeedbagItem) bool {
+				return d.GroupID == item.GroupID && d.ItemID == itemID
+			}) {
+				newOrder = append(newOrder, itemID)
+			}
+		}
+		if len(newOrder) != len(order) {
+			setFeedbagOrder(&item, newOrder)
+			updated = append(updated, item)
+		}
+	}
+
+	return deleted, updated, nil
+}
+
+// IsSharedGroup indicates whether item is a shared group. Shared groups are
+// marked with wire.FeedbagAttributesShared so that they can't be confused
+// with a user group of the same name.
+func IsSharedGroup(item wire.FeedbagItem) bool {
+	return item.ClassID == wire.FeedbagClassIdGroup && item.GroupID != 0 &&
+		item.HasTag(wire.FeedbagAttributesShared)
+}
+
+// SharedGroupIDs returns the IDs of the shared groups in items.
+func SharedGroupIDs(items []wire.FeedbagItem) map[uint16]bool {
+	ids := make(map[uint16]bool)
+	for _, item := range items {
+		if IsSharedGroup(item) {
+			ids[item.GroupID] = true
+		}
+	}
+	return ids
+}
+
+// SyncSharedGroupInFeedbag returns the feedbag items that must be inserted,
+// updated, and deleted so that group contains exactly members. The group is
+// created if it doesn't exist, and it's removed if members is empty. A user
+// group with the same name is never taken over.
+func SyncSharedGroupInFeedbag(items []wire.FeedbagItem, group string, members []DisplayScreenName) (inserted []wire.FeedbagItem, updated []wire.FeedbagItem, deleted []wire.FeedbagItem, err error) {
+	type itemKey struct {
+		groupID uint16
+		itemID  uint16
+	}
+	keyOf := func(item wire.FeedbagItem) itemKey {
+		return itemKey{groupID: item.GroupID, itemID: item.ItemID}
+	}
+
+	want := make(map[IdentScreenName]bool, len(members))
+	for _, member := range members {
+		want[member.IdentScreenName()] = true
+	}
+
+	var grp *wire.FeedbagItem
+	for i, item := range items {
+		if IsSharedGroup(item) && item.Name == group {
+			grp = &items[i]
+			break
+		}
+	}
+
+	// work is the feedbag as it looks after each change. changed holds the
+	// new version of each modified item in the order they were modified.
+	work := slices.Clone(items)
+	var changed []wire.FeedbagItem
+	apply := func(item wire.FeedbagItem) {
+		if i := slices.IndexFunc(work, func(w wire.FeedbagItem) bool { return keyOf(w) == keyOf(item) }); i >= 0 {
+			work[i] = item
+		} else {
+			work = append(work, item)
+		}
+		if i := slices.IndexFunc(changed, func(c wire.FeedbagItem) bool { return keyOf(c) == keyOf(item) }); i >= 0 {
+			changed[i] = item
+		} else {
+			changed = append(changed, item)
+		}
+	}
+
+	// remove buddies that are no longer members, along with duplicates
+	have := make(map[IdentScreenName]bool)
+	if grp != nil {
+		for _, item := range items {
+			if item.ClassID != wire.FeedbagClassIdBuddy || item.GroupID != grp.GroupID {
+				continue
+			}
+			screenName := NewIdentScreenName(item.Name)
+			if want[screenName] && !have[screenName] {
+				have[screenName] = true
+				continue
+			}
+			deleted = append(deleted, item)
+		}
+
+		if len(deleted) > 0 {
+			order, err := feedbagOrder(*grp)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			order = slices.DeleteFunc(order, func(itemID uint16) bool {
+				return slices.ContainsFunc(deleted, func(d wire.FeedbagItem) bool { return d.ItemID == itemID })
+			})
+			grpCopy := *grp
+			setFeedbagOrder(&grpCopy, order)
+			apply(grpCopy)
+		}
+		work = slices.DeleteFunc(work, func(w wire.FeedbagItem) bool {
+			return slices.ContainsFunc(deleted, func(d wire.FeedbagItem) bool { return keyOf(d) == keyOf(w) })
+		})
+	}
+
+	if len(members) == 0 {
+		if grp != nil {
+			// remove the empty group and take it out of the root group's order
+			deleted = append(deleted, *grp)
+			changed = slices.DeleteFunc(changed, func(c wire.FeedbagItem) bool { return keyOf(c) == keyOf(*grp) })
+			for _, item := range work {
+				if item.ClassID != wire.FeedbagClassIdGroup || item.GroupID != 0 {
+					continue
+				}
+				order, err := feedbagOrder(item)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				setFeedbagOrder(&item, slices.DeleteFunc(order, func(groupID uint16) bool { return groupID == grp.GroupID }))
+				apply(item)
+			}
+		}
+	} else {
+		for _, member := range members {
+			if have[member.IdentScreenName()] {
+				continue
+			}
+			have[member.IdentScreenName()] = true
+			ins, upd, err := addBuddyToFeedbag(work, member, group, true)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			for _, item := range append(ins, upd...) {
+				apply(item)
+			}
+		}
+	}
+
+	for _, item := range changed {
+		if slices.ContainsFunc(items, func(i wire.FeedbagItem) bool { return keyOf(i) == keyOf(item) }) {
+			updated = append(updated, item)
+		} else {
+			inserted = append(inserted, item)
+		}
+	}
+
+	return inserted, updated, deleted, nil
+}
+
+// ValidateFeedbag checks that items form a well-formed feedbag before it's
+// stored wholesale, such as when a buddy list is imported. It returns an
+// error describing the first problem found.
+func ValidateFeedbag(items []wire.FeedbagItem) error {
+	type itemKey struct {
+		groupID uint16
+		itemID  uint16
+	}
+	seen := make(map[itemKey]bool, len(items))
+	groups := make(map[uint16]bool)
+
+	for _, item := range items {
+		key := itemKey{groupID: item.GroupID, itemID: item.ItemID}
+		if seen[key] {
+			return fmt.Errorf("duplicate item ID %d in group ID %d", item.ItemID, item.GroupID)
+		}
+		seen[key] = true
+
+		if item.ClassID == wire.FeedbagClassIdGroup {
+			if item.ItemID != 0 {
+				return fmt.Errorf("group %q must have item ID 0", item.Name)
+			}
+			groups[item.GroupID] = true
+			continue
+		}
+		if item.ItemID == 0 {
+			return fmt.Errorf("item %q of class %d must have a non-zero item ID", item.Name, item.ClassID)
+		}
+
+		switch item.ClassID {
+		case wire.FeedbagClassIdBuddy, wire.FeedbagClassIDPermit, wire.FeedbagClassIDDeny:
+			if NewIdentScreenName(item.Name).String() == "" {
+				return fmt.Errorf("item ID %d of class %d must have a screen name", item.ItemID, item.ClassID)
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.ClassID == wire.FeedbagClassIdBuddy && (item.GroupID == 0 || !groups[item.GroupID]) {
+			return fmt.Errorf("buddy %q belongs to nonexistent group ID %d", item.Name, item.GroupID)
+		}
+	}
+
+	return nil
+}
+
+// feedbagOrder returns the list of IDs in a group item's order attribute.
+func feedbagOrder(item wire.FeedbagItem) ([]uint16, error) {
+	var order []uint16
+	if b, hasOrder := item.Bytes(wire.FeedbagAttributesOrder); hasOrder {
+		if err := wire.UnmarshalBE(&order, bytes.NewBuffer(b)); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal feedbag order: %w", err)
+		}
+	}
+	return order, nil
+}
+
+// appendFeedbagOrder appends id to a group item's order attribute.
+func appendFeedbagOrder(item *wire.FeedbagItem, id uint16) error {
+	order, err := feedbagOrder(*item)
+	if err != nil {
+		return err
+	}
+	setFeedbagOrder(item, append(order, id))
+	return nil
+}
+
+// setFeedbagOrder replaces a group item's order attribute. The TLV list is
+// copied so that the caller's original item is not modified.
+func setFeedbagOrder(item *wire.FeedbagItem, order []uint16) {
+	tlvs := wire.TLVList{}
+	for _, tlv := range item.TLVList {
+		if tlv.Tag != wire.FeedbagAttributesOrder {
+			tlvs.Append(tlv)
+		}
+	}
+	tlvs.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, order))
+	item.TLVList = tlvs
+}
+
+// OrderFeedbag returns the feedbag items in the order arranged by the user.
+// The root group comes first, followed by the other items in the root group
+// by item ID. Each group follows in the root group's order attribute, with
+// its members in the group's order attribute. Groups and members missing
+// from an order attribute follow those that are present, by ID. This ensures
+// that the list is displayed in the same arrangement regardless of the order
+// in which items were stored.
+func OrderFeedbag(items []wire.FeedbagItem) []wire.FeedbagItem {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b wire.FeedbagItem) int {
+		if a.GroupID != b.GroupID {
+			return int(a.GroupID) - int(b.GroupID)
+		}
+		return int(a.ItemID) - int(b.ItemID)
+	})
+
+	groups := make(map[uint16]wire.FeedbagItem)
+	members := make(map[uint16][]wire.FeedbagItem)
+	for _, item := range sorted {
+		if item.ClassID == wire.FeedbagClassIdGroup && item.ItemID == 0 {
+			groups[item.GroupID] = item
+		} else {
+			members[item.GroupID] = append(members[item.GroupID], item)
+		}
+	}
+
+	out := make([]wire.FeedbagItem, 0, len(items))
+	visited := make(map[uint16]bool)
+	visit := func(groupID uint16) {
+		if visited[groupID] {
+			return
+		}
+		visited[groupID] = true
+		group, hasGroup := groups[groupID]
+		if hasGroup {
+			out = append(out, group)
+		}
+		if groupID == 0 {
+			// the root group's order attribute refers to groups, not items
+			out = append(out, members[groupID]...)
+			return
+		}
+		out = append(out, byFeedbagOrder(members[groupID], group, func(item wire.FeedbagItem) uint16 {
+			return item.ItemID
+		})...)
+	}
+
+	visit(0)
+	var subGroups []wire.FeedbagItem
+	for groupID, group := range groups {
+		if groupID != 0 {
+			subGroups = append(subGroups, group)
+		}
+	}
+	slices.SortFunc(subGroups, func(a, b wire.FeedbagItem) int {
+		return int(a.GroupID) - int(b.GroupID)
+	})
+	for _, group := range byFeedbagOrder(subGroups, groups[0], func(item wire.FeedbagItem) uint16 {
+		return item.GroupID
+	}) {
+		visit(group.GroupID)
+	}
+	// items that belong to a group that doesn't exist
+	for _, item := range sorted {
+		if !visited[item.GroupID] {
+			visit(item.GroupID)
+		}
+	}
+
+	return out
+}
+
+// byFeedbagOrder returns items arranged according to parent's order
+// attribute, where id returns the ID that the order attribute refers to.
+// Items missing from the order attribute keep their relative order and follow
+// the ordered items. An order attribute that can't be read is ignored.
+func byFeedbagOrder(items []wire.FeedbagItem, parent wire.FeedbagItem, id func(wire.FeedbagItem) uint16) []wire.FeedbagItem {
+	order, err := feedbagOrder(parent)
+	if err != nil || len(order) == 0 {
+		return items
+	}
+
+	rank := make(map[uint16]int, len(order))
+	for i, itemID := range order {
+		if _, ok := rank[itemID]; !ok {
+			rank[itemID] = i
+		}
+	}
+
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b wire.FeedbagItem) int {
+		rankA, okA := rank[id(a)]
+		rankB, okB := rank[id(b)]
+		switch {
+		case okA && okB:
+			return rankA - rankB
+		case okA:
+			return -1
+		case okB:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return sorted
+}
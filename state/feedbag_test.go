@@ -0,0 +1,335 @@
+package state
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mk6i/retro-aim-server/wire"
+)
+
+func TestOrderFeedbag(t *testing.T) {
+	newGroup := func(name string, groupID uint16, order ...uint16) wire.FeedbagItem {
+		item := wire.FeedbagItem{
+			Name:    name,
+			GroupID: groupID,
+			ClassID: wire.FeedbagClassIdGroup,
+		}
+		if order != nil {
+			item.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, order))
+		}
+		return item
+	}
+	newBuddy := func(name string, groupID uint16, itemID uint16) wire.FeedbagItem {
+		return wire.FeedbagItem{
+			Name:    name,
+			GroupID: groupID,
+			ItemID:  itemID,
+			ClassID: wire.FeedbagClassIdBuddy,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		given []wire.FeedbagItem
+		want  []wire.FeedbagItem
+	}{
+		{
+			name:  "empty feedbag",
+			given: []wire.FeedbagItem{},
+			want:  []wire.FeedbagItem{},
+		},
+		{
+			name: "groups and buddies follow order attributes",
+			given: []wire.FeedbagItem{
+				newBuddy("buddy1", 1, 10),
+				newGroup("Family", 2, 21, 20),
+				newBuddy("buddy2", 2, 20),
+				newGroup("", 0, 2, 1),
+				newBuddy("buddy3", 2, 21),
+				newGroup("Friends", 1, 10),
+				{ItemID: 5, ClassID: wire.FeedbagClassIdPdinfo},
+			},
+			want: []wire.FeedbagItem{
+				newGroup("", 0, 2, 1),
+				{ItemID: 5, ClassID: wire.FeedbagClassIdPdinfo},
+				newGroup("Family", 2, 21, 20),
+				newBuddy("buddy3", 2, 21),
+				newBuddy("buddy2", 2, 20),
+				newGroup("Friends", 1, 10),
+				newBuddy("buddy1", 1, 10),
+			},
+		},
+		{
+			name: "items missing from order attributes follow ordered items by ID",
+			given: []wire.FeedbagItem{
+				newGroup("Friends", 1, 11),
+				newBuddy("buddy3", 1, 12),
+				newGroup("Coworkers", 3),
+				newBuddy("buddy2", 1, 11),
+				newBuddy("buddy1", 1, 10),
+				newGroup("", 0, 1),
+			},
+			want: []wire.FeedbagItem{
+				newGroup("", 0, 1),
+				newGroup("Friends", 1, 11),
+				newBuddy("buddy2", 1, 11),
+				newBuddy("buddy1", 1, 10),
+				newBuddy("buddy3", 1, 12),
+				newGroup("Coworkers", 3),
+			},
+		},
+		{
+			name: "items without a group come last",
+			given: []wire.FeedbagItem{
+				newBuddy("buddy2", 7, 20),
+				newGroup("Friends", 1, 10),
+				newBuddy("buddy1", 1, 10),
+			},
+			want: []wire.FeedbagItem{
+				newGroup("Friends", 1, 10),
+				newBuddy("buddy1", 1, 10),
+				newBuddy("buddy2", 7, 20),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, OrderFeedbag(tt.given))
+		})
+	}
+}
+
+func TestOrderFeedbag_PreservedAcrossStore(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+	arranged := []wire.FeedbagItem{
+		{ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{3, 1, 2})},
+		}},
+		{Name: "Work", GroupID: 3, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{30})},
+		}},
+		{Name: "buddy30", GroupID: 3, ItemID: 30, ClassID: wire.FeedbagClassIdBuddy},
+		{Name: "Friends", GroupID: 1, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{12, 10, 11})},
+		}},
+		{Name: "buddy12", GroupID: 1, ItemID: 12, ClassID: wire.FeedbagClassIdBuddy},
+		{Name: "buddy10", GroupID: 1, ItemID: 10, ClassID: wire.FeedbagClassIdBuddy},
+		{Name: "buddy11", GroupID: 1, ItemID: 11, ClassID: wire.FeedbagClassIdBuddy},
+		{Name: "Family", GroupID: 2, ClassID: wire.FeedbagClassIdGroup, TLVLBlock: wire.TLVLBlock{
+			TLVList: wire.TLVList{wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{})},
+		}},
+	}
+
+	// insert the items in an order unrelated to the user's arrangement
+	for _, i := range []int{6, 2, 0, 7, 4, 1, 5, 3} {
+		assert.NoError(t, f.FeedbagUpsert(me, []wire.FeedbagItem{arranged[i]}))
+	}
+
+	// simulate a reconnect by reading the feedbag back from the store
+	items, err := f.Feedbag(me)
+	assert.NoError(t, err)
+	assert.Equal(t, arranged, OrderFeedbag(items))
+}
+
+func TestSyncSharedGroupInFeedbag(t *testing.T) {
+	root := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	root.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}))
+	friends := wire.FeedbagItem{Name: "Friends", GroupID: 1, ClassID: wire.FeedbagClassIdGroup}
+	friends.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}))
+	buddy := wire.FeedbagItem{Name: "buddy", GroupID: 1, ItemID: 1, ClassID: wire.FeedbagClassIdBuddy}
+
+	// the shared group is created with its members
+	items := []wire.FeedbagItem{root, friends, buddy}
+	inserted, updated, deleted, err := SyncSharedGroupInFeedbag(items, "Staff", []DisplayScreenName{"StaffAnn", "StaffBob"})
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	wantStaff := wire.FeedbagItem{Name: "Staff", GroupID: 2, ClassID: wire.FeedbagClassIdGroup}
+	wantStaff.Append(wire.NewTLVBE(wire.FeedbagAttributesShared, []byte{}))
+	wantStaff.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2, 3}))
+	assert.Equal(t, []wire.FeedbagItem{
+		wantStaff,
+		{Name: "StaffAnn", GroupID: 2, ItemID: 2, ClassID: wire.FeedbagClassIdBuddy},
+		{Name: "StaffBob", GroupID: 2, ItemID: 3, ClassID: wire.FeedbagClassIdBuddy},
+	}, inserted)
+	wantRoot := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	wantRoot.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}))
+	assert.Equal(t, []wire.FeedbagItem{wantRoot}, updated)
+
+	// syncing again changes nothing
+	items = append([]wire.FeedbagItem{wantRoot, friends, buddy}, inserted...)
+	inserted, updated, deleted, err = SyncSharedGroupInFeedbag(items, "Staff", []DisplayScreenName{"StaffAnn", "StaffBob"})
+	assert.NoError(t, err)
+	assert.Empty(t, inserted)
+	assert.Empty(t, updated)
+	assert.Empty(t, deleted)
+
+	// former members are removed
+	inserted, updated, deleted, err = SyncSharedGroupInFeedbag(items, "Staff", []DisplayScreenName{"StaffBob"})
+	assert.NoError(t, err)
+	assert.Empty(t, inserted)
+	wantStaff = wire.FeedbagItem{Name: "Staff", GroupID: 2, ClassID: wire.FeedbagClassIdGroup}
+	wantStaff.Append(wire.NewTLVBE(wire.FeedbagAttributesShared, []byte{}))
+	wantStaff.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{3}))
+	assert.Equal(t, []wire.FeedbagItem{wantStaff}, updated)
+	assert.Equal(t, []wire.FeedbagItem{
+		{Name: "StaffAnn", GroupID: 2, ItemID: 2, ClassID: wire.FeedbagClassIdBuddy},
+	}, deleted)
+
+	// the group is removed along with its last member
+	inserted, updated, deleted, err = SyncSharedGroupInFeedbag(items, "Staff", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, inserted)
+	assert.Equal(t, []wire.FeedbagItem{root}, updated)
+	assert.Len(t, deleted, 3)
+}
+
+func TestValidateFeedbag(t *testing.T) {
+	root := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	friends := wire.FeedbagItem{Name: "Friends", GroupID: 1, ClassID: wire.FeedbagClassIdGroup}
+	buddy := wire.FeedbagItem{Name: "buddy", GroupID: 1, ItemID: 1, ClassID: wire.FeedbagClassIdBuddy}
+	deny := wire.FeedbagItem{Name: "spammer", ItemID: 2, ClassID: wire.FeedbagClassIDDeny}
+
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// items is the feedbag to validate
+		items []wire.FeedbagItem
+		// wantErr indicates whether the feedbag is invalid
+		wantErr bool
+	}{
+		{
+			name:  "well-formed feedbag",
+			items: []wire.FeedbagItem{root, friends, buddy, deny},
+		},
+		{
+			name:  "empty feedbag",
+			items: nil,
+		},
+		{
+			name:    "duplicate item ID",
+			items:   []wire.FeedbagItem{root, friends, buddy, buddy},
+			wantErr: true,
+		},
+		{
+			name: "group with non-zero item ID",
+			items: []wire.FeedbagItem{root,
+				{Name: "Friends", GroupID: 1, ItemID: 1, ClassID: wire.FeedbagClassIdGroup}},
+			wantErr: true,
+		},
+		{
+			name:    "buddy with zero item ID",
+			items:   []wire.FeedbagItem{root, friends, {Name: "buddy", GroupID: 1, ClassID: wire.FeedbagClassIdBuddy}},
+			wantErr: true,
+		},
+		{
+			name:    "buddy in nonexistent group",
+			items:   []wire.FeedbagItem{root, buddy},
+			wantErr: true,
+		},
+		{
+			name:    "deny item without screen name",
+			items:   []wire.FeedbagItem{root, {ItemID: 2, ClassID: wire.FeedbagClassIDDeny}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFeedbag(tt.items)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSyncSharedGroupInFeedbag_UserGroupWithSameName(t *testing.T) {
+	root := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	root.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}))
+	staff := wire.FeedbagItem{Name: "Staff", GroupID: 1, ClassID: wire.FeedbagClassIdGroup}
+	staff.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1}))
+	buddy := wire.FeedbagItem{Name: "buddy", GroupID: 1, ItemID: 1, ClassID: wire.FeedbagClassIdBuddy}
+	items := []wire.FeedbagItem{root, staff, buddy}
+
+	// the user's own group is left alone and a separate shared group is
+	// created
+	inserted, updated, deleted, err := SyncSharedGroupInFeedbag(items, "Staff", []DisplayScreenName{"StaffAnn"})
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+	wantStaff := wire.FeedbagItem{Name: "Staff", GroupID: 2, ClassID: wire.FeedbagClassIdGroup}
+	wantStaff.Append(wire.NewTLVBE(wire.FeedbagAttributesShared, []byte{}))
+	wantStaff.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{2}))
+	assert.Equal(t, []wire.FeedbagItem{
+		wantStaff,
+		{Name: "StaffAnn", GroupID: 2, ItemID: 2, ClassID: wire.FeedbagClassIdBuddy},
+	}, inserted)
+	wantRoot := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	wantRoot.Append(wire.NewTLVBE(wire.FeedbagAttributesOrder, []uint16{1, 2}))
+	assert.Equal(t, []wire.FeedbagItem{wantRoot}, updated)
+
+	// removing the shared group leaves the user's group alone
+	items = append([]wire.FeedbagItem{wantRoot, staff, buddy}, inserted...)
+	_, _, deleted, err = SyncSharedGroupInFeedbag(items, "Staff", nil)
+	assert.NoError(t, err)
+	for _, item := range deleted {
+		assert.Equal(t, uint16(2), item.GroupID)
+	}
+
+	// buddies added by users never land in the shared group, and removing
+	// a buddy leaves the shared group alone
+	ins, _, err := AddBuddyToFeedbag(items, "userD", "Staff")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1), ins[0].GroupID)
+	del, _, err := RemoveBuddyFromFeedbag(items, NewIdentScreenName("StaffAnn"))
+	assert.NoError(t, err)
+	assert.Empty(t, del)
+}
+
+func TestAddBuddyToFeedbag_NoFreeIDs(t *testing.T) {
+	root := wire.FeedbagItem{ClassID: wire.FeedbagClassIdGroup}
+	friends := wire.FeedbagItem{Name: "Friends", GroupID: 1, ClassID: wire.FeedbagClassIdGroup}
+
+	tests := []struct {
+		// name is the unit test name
+		name string
+		// items is the feedbag the buddy is added to
+		items []wire.FeedbagItem
+		// group is the group the buddy is added to
+		group string
+	}{
+		{
+			name: "item IDs are used up",
+			items: []wire.FeedbagItem{root, friends, {
+				Name: "buddy", GroupID: 1, ItemID: math.MaxUint16, ClassID: wire.FeedbagClassIdBuddy,
+			}},
+			group: "Friends",
+		},
+		{
+			name: "group IDs are used up",
+			items: []wire.FeedbagItem{root, friends, {
+				Name: "Family", GroupID: math.MaxUint16, ClassID: wire.FeedbagClassIdGroup,
+			}},
+			group: "Coworkers",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := AddBuddyToFeedbag(tt.items, "newbuddy", tt.group)
+			assert.ErrorIs(t, err, ErrFeedbagFull)
+		})
+	}
+}
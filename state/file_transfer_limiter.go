@@ -0,0 +1,136 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// fileTransferTTL is how long a file transfer counts toward the caps after it
+// was proposed. It frees the slots of transfers that the server never sees
+// end, such as a proposal that is never answered.
+const fileTransferTTL = time.Hour
+
+// NewFileTransferLimiter creates a new instance of FileTransferLimiter that
+// allows at most maxPerUser simultaneous file transfers per user and at most
+// maxTotal simultaneous file transfers server-wide. A cap of 0 disables that
+// cap.
+func NewFileTransferLimiter(maxPerUser int, maxTotal int) *FileTransferLimiter {
+	return &FileTransferLimiter{
+		maxPerUser: maxPerUser,
+		maxTotal:   maxTotal,
+		nowFn:      time.Now,
+		transfers:  make(map[[8]byte]fileTransfer),
+	}
+}
+
+// fileTransfer is an active file transfer between two users.
+type fileTransfer struct {
+	parties [2]IdentScreenName
+	// proxied indicates whether the transfer is relayed through the file
+	// transfer proxy.
+	proxied   bool
+	startedAt time.Time
+}
+
+// FileTransferLimiter tracks active file transfers and caps how many may run
+// at once. Transfers are identified by their rendezvous cookie. It is safe to
+// use with multiple goroutines.
+type FileTransferLimiter struct {
+	maxPerUser int
+	maxTotal   int
+	mutex      sync.Mutex
+	nowFn      func() time.Time
+	transfers  map[[8]byte]fileTransfer
+}
+
+// StartTransfer records a file transfer proposed by sender to recipient,
+// which is relayed through the file transfer proxy if proxied is true. It
+// returns false if the transfer would exceed the per-user cap of either party
+// or the server-wide cap. Repeat proposals for a transfer that is already
+// active, such as a proxy redirect, are always allowed.
+func (f *FileTransferLimiter) StartTransfer(cookie [8]byte, sender IdentScreenName, recipient IdentScreenName, proxied bool) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := f.nowFn()
+	f.expire(now)
+
+	if transfer, ok := f.transfers[cookie]; ok {
+		if proxied {
+			transfer.proxied = true
+			f.transfers[cookie] = transfer
+		}
+		return true
+	}
+
+	if f.maxTotal > 0 && len(f.transfers) >= f.maxTotal {
+		return false
+	}
+
+	if f.maxPerUser > 0 {
+		senderCount, recipientCount := 0, 0
+		for _, transfer := range f.transfers {
+			for _, party := range transfer.parties {
+				switch party {
+				case sender:
+					senderCount++
+				case recipient:
+					recipientCount++
+				}
+			}
+		}
+		if senderCount >= f.maxPerUser || recipientCount >= f.maxPerUser {
+			return false
+		}
+	}
+
+	f.transfers[cookie] = fileTransfer{
+		parties:   [2]IdentScreenName{sender, recipient},
+		proxied:   proxied,
+		startedAt: now,
+	}
+	return true
+}
+
+// EndTransfer stops tracking the file transfer identified by cookie. It does
+// nothing if the transfer isn't tracked.
+func (f *FileTransferLimiter) EndTransfer(cookie [8]byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.transfers, cookie)
+}
+
+// AcceptTransfer stops tracking the direct file transfer identified by cookie
+// once the recipient accepts it. From then on the file is sent over a
+// connection between the clients, so the server can't tell when it finishes.
+// Transfers relayed through the file transfer proxy are tracked until the
+// proxy session ends.
+func (f *FileTransferLimiter) AcceptTransfer(cookie [8]byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if transfer, ok := f.transfers[cookie]; ok && !transfer.proxied {
+		delete(f.transfers, cookie)
+	}
+}
+
+// EndUserTransfers stops tracking every file transfer that screenName sends
+// or receives, such as when the user signs off.
+func (f *FileTransferLimiter) EndUserTransfers(screenName IdentScreenName) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for cookie, transfer := range f.transfers {
+		if transfer.parties[0] == screenName || transfer.parties[1] == screenName {
+			delete(f.transfers, cookie)
+		}
+	}
+}
+
+// expire stops tracking transfers that were proposed more than
+// fileTransferTTL ago.
+func (f *FileTransferLimiter) expire(now time.Time) {
+	for cookie, transfer := range f.transfers {
+		if now.Sub(transfer.startedAt) >= fileTransferTTL {
+			delete(f.transfers, cookie)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTransferLimiter_StartTransfer(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewFileTransferLimiter(2, 3)
+	limiter.nowFn = func() time.Time { return now }
+
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+	userC := NewIdentScreenName("userC")
+	userD := NewIdentScreenName("userD")
+
+	assert.True(t, limiter.StartTransfer([8]byte{1}, userA, userB, false))
+	assert.True(t, limiter.StartTransfer([8]byte{2}, userA, userC, false))
+
+	// repeat proposals for an active transfer are allowed
+	assert.True(t, limiter.StartTransfer([8]byte{1}, userA, userB, false))
+
+	// userA is at the per-user cap, whether sending or receiving
+	assert.False(t, limiter.StartTransfer([8]byte{3}, userA, userD, false))
+	assert.False(t, limiter.StartTransfer([8]byte{3}, userD, userA, false))
+
+	// server-wide cap
+	assert.True(t, limiter.StartTransfer([8]byte{3}, userC, userD, false))
+	assert.False(t, limiter.StartTransfer([8]byte{4}, userB, userD, false))
+
+	// ending a transfer frees up a slot
+	limiter.EndTransfer([8]byte{1})
+	assert.True(t, limiter.StartTransfer([8]byte{4}, userA, userB, false))
+
+	// transfers expire
+	now = now.Add(fileTransferTTL)
+	assert.True(t, limiter.StartTransfer([8]byte{5}, userA, userD, false))
+	assert.True(t, limiter.StartTransfer([8]byte{6}, userA, userB, false))
+}
+
+func TestFileTransferLimiter_EndUserTransfers(t *testing.T) {
+	limiter := NewFileTransferLimiter(1, 0)
+
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+	userC := NewIdentScreenName("userC")
+	userD := NewIdentScreenName("userD")
+
+	assert.True(t, limiter.StartTransfer([8]byte{1}, userA, userB, false))
+	assert.True(t, limiter.StartTransfer([8]byte{2}, userC, userD, false))
+
+	// userB signs off, which frees the slots of both parties
+	limiter.EndUserTransfers(userB)
+	assert.True(t, limiter.StartTransfer([8]byte{3}, userA, userB, false))
+
+	// transfers between other users continue
+	assert.False(t, limiter.StartTransfer([8]byte{4}, userC, userA, false))
+}
+
+func TestFileTransferLimiter_AcceptTransfer(t *testing.T) {
+	limiter := NewFileTransferLimiter(1, 0)
+
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+
+	// a direct transfer stops counting once it's accepted, so a completed
+	// transfer doesn't block the next one
+	assert.True(t, limiter.StartTransfer([8]byte{1}, userA, userB, false))
+	assert.False(t, limiter.StartTransfer([8]byte{2}, userA, userB, false))
+	limiter.AcceptTransfer([8]byte{1})
+	assert.True(t, limiter.StartTransfer([8]byte{2}, userA, userB, false))
+
+	// a proxy redirect keeps the transfer counting until the proxy session
+	// ends
+	assert.True(t, limiter.StartTransfer([8]byte{2}, userA, userB, true))
+	limiter.AcceptTransfer([8]byte{2})
+	assert.False(t, limiter.StartTransfer([8]byte{3}, userA, userB, false))
+	limiter.EndTransfer([8]byte{2})
+	assert.True(t, limiter.StartTransfer([8]byte{3}, userA, userB, false))
+}
+
+func TestFileTransferLimiter_NoCaps(t *testing.T) {
+	limiter := NewFileTransferLimiter(0, 0)
+	for i := byte(0); i < 100; i++ {
+		assert.True(t, limiter.StartTransfer([8]byte{i}, NewIdentScreenName("userA"), NewIdentScreenName("userB"), false))
+	}
+}
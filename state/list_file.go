@@ -0,0 +1,199 @@
+package state
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readListFile reads a list file that contains one entry per line. Leading
+// and trailing whitespace is trimmed from each entry. Blank lines and lines
+// that start with '#' are ignored.
+func readListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
+
+// NewBanList creates a new instance of BanList that loads banned screen names
+// and IP addresses from the file at path. An empty path yields a list that
+// bans no one.
+func NewBanList(path string) *BanList {
+	return &BanList{
+		path:        path,
+		entries:     make(map[IdentScreenName]bool),
+		nowFn:       time.Now,
+		suspensions: make(map[IdentScreenName]time.Time),
+	}
+}
+
+// BanList is a list of screen names and IP addresses that are barred from
+// signing on. The list is read from a file so that it can be kept under
+// version control. Entries that parse as an IP address or CIDR, such as
+// 203.0.113.7 or 198.51.100.0/24, ban addresses. All other entries ban screen
+// names. Users may also be suspended for a limited time, which isn't
+// persisted. It is safe to use with multiple goroutines.
+type BanList struct {
+	addrs       []netip.Prefix
+	entries     map[IdentScreenName]bool
+	mutex       sync.RWMutex
+	nowFn       func() time.Time
+	path        string
+	suspensions map[IdentScreenName]time.Time
+}
+
+// Load reads the ban list file, replacing the current list. The current list
+// is kept if the file can't be read.
+func (b *BanList) Load() error {
+	if b.path == "" {
+		return nil
+	}
+
+	lines, err := readListFile(b.path)
+	if err != nil {
+		return fmt.Errorf("unable to read ban list: %w", err)
+	}
+
+	var addrs []netip.Prefix
+	entries := make(map[IdentScreenName]bool, len(lines))
+	for _, line := range lines {
+		if prefix, ok := parseAddrBan(line); ok {
+			addrs = append(addrs, prefix)
+			continue
+		}
+		entries[NewIdentScreenName(line)] = true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.addrs = addrs
+	b.entries = entries
+	return nil
+}
+
+// parseAddrBan parses a ban list entry as an IP address or CIDR. A bare IP
+// address is treated as a single-host network.
+func parseAddrBan(entry string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return prefix.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// Banned indicates whether screenName is on the ban list or suspended.
+func (b *BanList) Banned(screenName IdentScreenName) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.entries[screenName] {
+		return true
+	}
+	until, ok := b.suspensions[screenName]
+	return ok && b.nowFn().Before(until)
+}
+
+// BannedAddr indicates whether addr is on the ban list.
+func (b *BanList) BannedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, prefix := range b.addrs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Suspend bars screenName from signing on until the given time. Reloading
+// the ban list doesn't lift suspensions.
+func (b *BanList) Suspend(screenName IdentScreenName, until time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// drop lapsed suspensions so that they don't pile up
+	now := b.nowFn()
+	for sn, t := range b.suspensions {
+		if !now.Before(t) {
+			delete(b.suspensions, sn)
+		}
+	}
+	b.suspensions[screenName] = until
+}
+
+// NewMessageFilter creates a new instance of MessageFilter that loads
+// prohibited words and phrases from the file at path. An empty path yields a
+// filter that matches nothing.
+func NewMessageFilter(path string) *MessageFilter {
+	return &MessageFilter{
+		path: path,
+	}
+}
+
+// MessageFilter is a list of words and phrases that are prohibited in
+// messages. The list is read from a file so that it can be kept under version
+// control. It is safe to use with multiple goroutines.
+type MessageFilter struct {
+	entries []string
+	mutex   sync.RWMutex
+	path    string
+}
+
+// Load reads the message filter file, replacing the current list. The current
+// list is kept if the file can't be read.
+func (m *MessageFilter) Load() error {
+	if m.path == "" {
+		return nil
+	}
+
+	lines, err := readListFile(m.path)
+	if err != nil {
+		return fmt.Errorf("unable to read message filter list: %w", err)
+	}
+
+	entries := make([]string, 0, len(lines))
+	for _, line := range lines {
+		entries = append(entries, strings.ToLower(line))
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries = entries
+	return nil
+}
+
+// Match indicates whether text contains any prohibited word or phrase. The
+// comparison is case-insensitive.
+func (m *MessageFilter) Match(text string) bool {
+	text = strings.ToLower(text)
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, entry := range m.entries {
+		if strings.Contains(text, entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# spammers\n\n  Spam Bot  \nuserB\n"), 0644))
+
+	banList := NewBanList(path)
+
+	// nothing is banned until the list is loaded
+	assert.False(t, banList.Banned(NewIdentScreenName("spambot")))
+
+	assert.NoError(t, banList.Load())
+	assert.True(t, banList.Banned(NewIdentScreenName("spambot")))
+	assert.True(t, banList.Banned(NewIdentScreenName("UserB")))
+	assert.False(t, banList.Banned(NewIdentScreenName("userC")))
+	assert.False(t, banList.Banned(NewIdentScreenName("# spammers")))
+
+	// reload picks up changes
+	assert.NoError(t, os.WriteFile(path, []byte("userC\n"), 0644))
+	assert.NoError(t, banList.Load())
+	assert.False(t, banList.Banned(NewIdentScreenName("spambot")))
+	assert.True(t, banList.Banned(NewIdentScreenName("userC")))
+
+	// a failed reload keeps the current list
+	assert.NoError(t, os.Remove(path))
+	assert.Error(t, banList.Load())
+	assert.True(t, banList.Banned(NewIdentScreenName("userC")))
+}
+
+func TestBanList_Addrs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("203.0.113.7\n198.51.100.0/24\n2001:db8::/32\nuserA\n"), 0644))
+
+	banList := NewBanList(path)
+	assert.NoError(t, banList.Load())
+
+	assert.True(t, banList.BannedAddr(netip.MustParseAddr("203.0.113.7")))
+	assert.True(t, banList.BannedAddr(netip.MustParseAddr("::ffff:203.0.113.7")))
+	assert.True(t, banList.BannedAddr(netip.MustParseAddr("198.51.100.42")))
+	assert.True(t, banList.BannedAddr(netip.MustParseAddr("2001:db8::1")))
+	assert.False(t, banList.BannedAddr(netip.MustParseAddr("203.0.113.8")))
+	assert.False(t, banList.BannedAddr(netip.Addr{}))
+
+	// addresses don't ban screen names, and vice versa
+	assert.True(t, banList.Banned(NewIdentScreenName("userA")))
+	assert.False(t, banList.Banned(NewIdentScreenName("203.0.113.7")))
+}
+
+func TestBanList_NoFile(t *testing.T) {
+	banList := NewBanList("")
+	assert.NoError(t, banList.Load())
+	assert.False(t, banList.Banned(NewIdentScreenName("userA")))
+}
+
+func TestBanList_Suspend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("userB\n"), 0644))
+
+	banList := NewBanList(path)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	banList.nowFn = func() time.Time { return now }
+
+	banList.Suspend(NewIdentScreenName("userA"), now.Add(time.Hour))
+	assert.True(t, banList.Banned(NewIdentScreenName("userA")))
+
+	// reloading the list doesn't lift the suspension
+	assert.NoError(t, banList.Load())
+	assert.True(t, banList.Banned(NewIdentScreenName("userA")))
+	assert.True(t, banList.Banned(NewIdentScreenName("userB")))
+
+	// the suspension lapses
+	now = now.Add(time.Hour)
+	assert.False(t, banList.Banned(NewIdentScreenName("userA")))
+	assert.True(t, banList.Banned(NewIdentScreenName("userB")))
+}
+
+func TestMessageFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# spam\nCheap Pills\nwarez\n"), 0644))
+
+	filter := NewMessageFilter(path)
+	assert.NoError(t, filter.Load())
+
+	assert.True(t, filter.Match("buy cheap pills now"))
+	assert.True(t, filter.Match("<HTML>get WAREZ here</HTML>"))
+	assert.False(t, filter.Match("hello there"))
+
+	// reload picks up changes
+	assert.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+	assert.NoError(t, filter.Load())
+	assert.False(t, filter.Match("buy cheap pills now"))
+	assert.True(t, filter.Match("hello there"))
+}
+
+func TestMessageFilter_NoFile(t *testing.T) {
+	filter := NewMessageFilter("")
+	assert.NoError(t, filter.Load())
+	assert.False(t, filter.Match("anything"))
+}
@@ -0,0 +1,5 @@
+CREATE TABLE uinCounter
+(
+	id      INTEGER PRIMARY KEY CHECK (id = 1),
+	nextUIN INTEGER NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE chatRoomModerator
+(
+	cookie     TEXT,
+	screenName VARCHAR(16),
+	PRIMARY KEY (cookie, screenName),
+	FOREIGN KEY (cookie) REFERENCES chatRoom (cookie) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    DROP COLUMN icq_permissions_webAware;
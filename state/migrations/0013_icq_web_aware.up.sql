@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN icq_permissions_webAware BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,10 @@
+CREATE TABLE chatTranscript
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    cookie     TEXT        NOT NULL,
+    screenName VARCHAR(16) NOT NULL,
+    text       TEXT        NOT NULL,
+    sent       INTEGER     NOT NULL
+);
+CREATE INDEX chatTranscript_cookie ON chatTranscript (cookie);
+CREATE INDEX chatTranscript_sent ON chatTranscript (sent);
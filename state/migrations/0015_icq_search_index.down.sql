@@ -0,0 +1,8 @@
+DROP INDEX users_icq_firstName;
+DROP INDEX users_icq_lastName;
+DROP INDEX users_icq_nickName;
+DROP INDEX users_icq_emailAddress;
+DROP INDEX users_icq_interests_code1;
+DROP INDEX users_icq_interests_code2;
+DROP INDEX users_icq_interests_code3;
+DROP INDEX users_icq_interests_code4;
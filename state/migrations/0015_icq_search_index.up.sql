@@ -0,0 +1,8 @@
+CREATE INDEX users_icq_firstName ON users (LOWER(icq_basicInfo_firstName));
+CREATE INDEX users_icq_lastName ON users (LOWER(icq_basicInfo_lastName));
+CREATE INDEX users_icq_nickName ON users (LOWER(icq_basicInfo_nickName));
+CREATE INDEX users_icq_emailAddress ON users (icq_basicInfo_emailAddress);
+CREATE INDEX users_icq_interests_code1 ON users (icq_interests_code1);
+CREATE INDEX users_icq_interests_code2 ON users (icq_interests_code2);
+CREATE INDEX users_icq_interests_code3 ON users (icq_interests_code3);
+CREATE INDEX users_icq_interests_code4 ON users (icq_interests_code4);
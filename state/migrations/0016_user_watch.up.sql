@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN isWatched BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+CREATE TABLE awayTemplate
+(
+    name    TEXT PRIMARY KEY,
+    message TEXT NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP INDEX users_confirmToken;
+ALTER TABLE users
+    DROP COLUMN confirmTokenExpiry;
+ALTER TABLE users
+    DROP COLUMN confirmToken;
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN confirmToken TEXT NOT NULL DEFAULT '';
+ALTER TABLE users
+    ADD COLUMN confirmTokenExpiry INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX users_confirmToken ON users (confirmToken);
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN canCreateChatRooms BOOLEAN NOT NULL DEFAULT false;
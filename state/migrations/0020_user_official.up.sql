@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN isOfficial BOOLEAN NOT NULL DEFAULT false;
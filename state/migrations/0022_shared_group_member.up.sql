@@ -0,0 +1,6 @@
+CREATE TABLE sharedGroupMember
+(
+    groupName  TEXT NOT NULL,
+    screenName VARCHAR(16) NOT NULL,
+    PRIMARY KEY (groupName, screenName)
+);
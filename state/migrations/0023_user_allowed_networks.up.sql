@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN allowedNetworks TEXT NOT NULL DEFAULT '';
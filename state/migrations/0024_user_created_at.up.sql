@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN createdAt INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+CREATE TABLE screenNameAlias
+(
+    alias      VARCHAR(16) PRIMARY KEY,
+    screenName VARCHAR(16) NOT NULL,
+    FOREIGN KEY (screenName) REFERENCES users (identScreenName) ON DELETE CASCADE
+);
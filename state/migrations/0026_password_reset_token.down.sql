@@ -0,0 +1,5 @@
+DROP INDEX users_passwordResetToken;
+ALTER TABLE users
+    DROP COLUMN passwordResetTokenExpiry;
+ALTER TABLE users
+    DROP COLUMN passwordResetToken;
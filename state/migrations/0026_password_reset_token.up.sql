@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN passwordResetToken TEXT NOT NULL DEFAULT '';
+ALTER TABLE users
+    ADD COLUMN passwordResetTokenExpiry INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX users_passwordResetToken ON users (passwordResetToken);
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN concurrentLoginPolicy TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    DROP COLUMN bannedAt;
+ALTER TABLE users
+    DROP COLUMN banReason;
+ALTER TABLE users
+    DROP COLUMN isBanned;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN isBanned BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users
+    ADD COLUMN banReason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users
+    ADD COLUMN bannedAt INTEGER NOT NULL DEFAULT 0;
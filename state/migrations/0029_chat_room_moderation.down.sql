@@ -0,0 +1,3 @@
+ALTER TABLE chatRoom
+    DROP COLUMN topic;
+DROP TABLE chatRoomBan;
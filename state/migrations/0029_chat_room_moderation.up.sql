@@ -0,0 +1,9 @@
+CREATE TABLE chatRoomBan
+(
+	cookie     TEXT,
+	screenName VARCHAR(16),
+	PRIMARY KEY (cookie, screenName),
+	FOREIGN KEY (cookie) REFERENCES chatRoom (cookie) ON DELETE CASCADE
+);
+ALTER TABLE chatRoom
+    ADD COLUMN topic TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2id parameters for new password hashes. Hashes record the parameters
+// they were made with, so changing them doesn't invalidate stored hashes.
+const (
+	argon2Memory  uint32 = 19 * 1024 // KiB
+	argon2Time    uint32 = 2
+	argon2Threads uint8  = 1
+	argon2KeyLen  uint32 = 32
+	argon2SaltLen        = 16
+)
+
+// argon2Hash hashes passwd with argon2id and returns the hash in PHC string
+// format, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>.
+func argon2Hash(passwd string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(passwd), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// argon2Verify indicates whether passwd matches encodedHash, an argon2id hash
+// in PHC string format. It returns false if encodedHash is malformed.
+func argon2Verify(encodedHash string, passwd string) bool {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil ||
+		time == 0 || threads == 0 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+
+	have := argon2.IDKey([]byte(passwd), salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(have, want) == 1
+}
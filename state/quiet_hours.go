@@ -0,0 +1,75 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NewQuietHours creates a new instance of QuietHours for the daily period
+// between start and end, which are times of day in 24-hour HH:MM format. The
+// period may span midnight, such as 22:00 to 07:00. It returns nil, which
+// never reports quiet hours, if both start and end are empty.
+func NewQuietHours(start, end string) (*QuietHours, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	if start == "" || end == "" {
+		return nil, errors.New("quiet hours start and end must both be set")
+	}
+
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours start %q: %w", start, err)
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours end %q: %w", end, err)
+	}
+
+	return &QuietHours{
+		start: sinceMidnight(startTime),
+		end:   sinceMidnight(endTime),
+	}, nil
+}
+
+// QuietHours is a daily period during which non-critical server broadcasts
+// are held back.
+type QuietHours struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// Remaining returns how long until quiet hours end if now falls within quiet
+// hours, else 0. Times are evaluated in the location of now.
+func (q *QuietHours) Remaining(now time.Time) time.Duration {
+	if q == nil || q.start == q.end {
+		return 0
+	}
+
+	t := sinceMidnight(now)
+	if q.start < q.end {
+		if t >= q.start && t < q.end {
+			return q.end - t
+		}
+		return 0
+	}
+
+	// quiet hours span midnight
+	switch {
+	case t >= q.start:
+		return 24*time.Hour - t + q.end
+	case t < q.end:
+		return q.end - t
+	default:
+		return 0
+	}
+}
+
+// sinceMidnight returns the time of day of t as a duration since midnight.
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQuietHours(t *testing.T) {
+	q, err := NewQuietHours("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+
+	_, err = NewQuietHours("22:00", "")
+	assert.Error(t, err)
+
+	_, err = NewQuietHours("10pm", "07:00")
+	assert.Error(t, err)
+
+	_, err = NewQuietHours("22:00", "25:00")
+	assert.Error(t, err)
+}
+
+func TestQuietHours_Remaining(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 2, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		now   time.Time
+		want  time.Duration
+	}{
+		{
+			name:  "within same-day quiet hours",
+			start: "01:00",
+			end:   "05:00",
+			now:   at(3, 30),
+			want:  90 * time.Minute,
+		},
+		{
+			name:  "before same-day quiet hours",
+			start: "01:00",
+			end:   "05:00",
+			now:   at(0, 59),
+			want:  0,
+		},
+		{
+			name:  "at end of same-day quiet hours",
+			start: "01:00",
+			end:   "05:00",
+			now:   at(5, 0),
+			want:  0,
+		},
+		{
+			name:  "before midnight in quiet hours that span midnight",
+			start: "22:00",
+			end:   "07:00",
+			now:   at(23, 0),
+			want:  8 * time.Hour,
+		},
+		{
+			name:  "after midnight in quiet hours that span midnight",
+			start: "22:00",
+			end:   "07:00",
+			now:   at(6, 15),
+			want:  45 * time.Minute,
+		},
+		{
+			name:  "outside quiet hours that span midnight",
+			start: "22:00",
+			end:   "07:00",
+			now:   at(12, 0),
+			want:  0,
+		},
+		{
+			name:  "start equals end",
+			start: "07:00",
+			end:   "07:00",
+			now:   at(7, 0),
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewQuietHours(tt.start, tt.end)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, q.Remaining(tt.now))
+		})
+	}
+}
+
+func TestQuietHours_Nil(t *testing.T) {
+	var q *QuietHours
+	assert.Equal(t, time.Duration(0), q.Remaining(time.Now()))
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// NewRegistrationLimiter creates a new instance of RegistrationLimiter that
+// allows at most limit registrations per IP address within window. A limit
+// of 0 disables the limit.
+func NewRegistrationLimiter(limit int, window time.Duration) *RegistrationLimiter {
+	return &RegistrationLimiter{
+		attempts: make(map[netip.Addr][]time.Time),
+		limit:    limit,
+		nowFn:    time.Now,
+		window:   window,
+	}
+}
+
+// RegistrationLimiter caps how many accounts may be registered from a single
+// IP address over a sliding time window. It is safe to use with multiple
+// goroutines.
+type RegistrationLimiter struct {
+	attempts map[netip.Addr][]time.Time
+	limit    int
+	mutex    sync.Mutex
+	nowFn    func() time.Time
+	window   time.Duration
+}
+
+// Allow records a registration attempt from addr. It returns false, without
+// recording the attempt, if addr has already reached the limit within the
+// window.
+func (r *RegistrationLimiter) Allow(addr netip.Addr) bool {
+	if r.limit <= 0 {
+		return true
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.nowFn()
+	r.expire(now)
+
+	if len(r.attempts[addr]) >= r.limit {
+		return false
+	}
+	r.attempts[addr] = append(r.attempts[addr], now)
+	return true
+}
+
+// expire forgets attempts that fell out of the window. The caller must hold
+// the mutex.
+func (r *RegistrationLimiter) expire(now time.Time) {
+	cutoff := now.Add(-r.window)
+	for addr, attempts := range r.attempts {
+		i := 0
+		for i < len(attempts) && !attempts[i].After(cutoff) {
+			i++
+		}
+		if i == len(attempts) {
+			delete(r.attempts, addr)
+		} else {
+			r.attempts[addr] = attempts[i:]
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRegistrationLimiter(2, time.Hour)
+	limiter.nowFn = func() time.Time { return now }
+
+	addrA := netip.MustParseAddr("203.0.113.1")
+	addrB := netip.MustParseAddr("203.0.113.2")
+
+	assert.True(t, limiter.Allow(addrA))
+	now = now.Add(10 * time.Minute)
+	assert.True(t, limiter.Allow(addrA))
+
+	// addrA is at the limit, but addrB has its own allowance
+	assert.False(t, limiter.Allow(addrA))
+	assert.True(t, limiter.Allow(addrB))
+
+	// the first attempt falls out of the window
+	now = now.Add(50 * time.Minute)
+	assert.True(t, limiter.Allow(addrA))
+	assert.False(t, limiter.Allow(addrA))
+}
+
+func TestRegistrationLimiter_Allow_NoLimit(t *testing.T) {
+	limiter := NewRegistrationLimiter(0, time.Hour)
+	addr := netip.MustParseAddr("203.0.113.1")
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Allow(addr))
+	}
+}
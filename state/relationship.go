@@ -17,7 +17,10 @@ import (
 // filtered on a specific list of users.
 //
 // The query creates a unified view of both server-side buddy lists and
-// client-side buddy lists.
+// client-side buddy lists. Users with server-side buddy lists may also watch
+// users who aren't on their list via client-side buddy list entries. These
+// transient watches count as buddies unless the user already appears on the
+// server-side list.
 const relationshipSQLTpl = `
 WITH myScreenName AS (SELECT ?),
      {{ if .DoFilter }}filter AS (SELECT * FROM (VALUES%s) as t),{{ end }}
@@ -45,7 +48,25 @@ WITH myScreenName AS (SELECT ?),
                            AND EXISTS(SELECT 1
                                       FROM buddyListMode
                                       WHERE buddyListMode.screenName = clientSideBuddyList.me
-                                        AND useFeedbag IS FALSE)),
+                                        AND useFeedbag IS FALSE)
+                         UNION
+                         SELECT me AS screenName,
+                                1  AS isBuddy,
+                                0  AS isPermit,
+                                0  AS isDeny
+                         FROM clientSideBuddyList
+                         WHERE them = (SELECT * FROM myScreenName)
+                           {{ if .DoFilter }}AND me IN (SELECT * FROM filter){{ end }}
+                           AND isBuddy IS TRUE
+                           AND EXISTS(SELECT 1
+                                      FROM buddyListMode
+                                      WHERE buddyListMode.screenName = clientSideBuddyList.me
+                                        AND useFeedbag IS TRUE)
+                           AND NOT EXISTS(SELECT 1
+                                          FROM feedbag
+                                          WHERE feedbag.screenName = clientSideBuddyList.me
+                                            AND feedbag.name = clientSideBuddyList.them
+                                            AND feedbag.classId IN (0, 2, 3))),
      yourBuddyList AS (SELECT feedbag.name                                         AS screenName,
                               MAX(CASE WHEN feedbag.classId = 0 THEN 1 ELSE 0 END) AS isBuddy,
                               MAX(CASE WHEN feedbag.classId = 2 THEN 1 ELSE 0 END) AS isPermit,
@@ -70,7 +91,25 @@ WITH myScreenName AS (SELECT ?),
                          AND EXISTS(SELECT 1
                                     FROM buddyListMode
                                     WHERE buddyListMode.screenName = clientSideBuddyList.me
-                                      AND useFeedbag IS FALSE)),
+                                      AND useFeedbag IS FALSE)
+                       UNION
+                       SELECT them AS screenName,
+                              1    AS isBuddy,
+                              0    AS isPermit,
+                              0    AS isDeny
+                       FROM clientSideBuddyList
+                       WHERE me = (SELECT * FROM myScreenName)
+                       {{ if .DoFilter }}AND them IN (SELECT * FROM filter){{ end }}
+                         AND isBuddy IS TRUE
+                         AND EXISTS(SELECT 1
+                                    FROM buddyListMode
+                                    WHERE buddyListMode.screenName = clientSideBuddyList.me
+                                      AND useFeedbag IS TRUE)
+                         AND NOT EXISTS(SELECT 1
+                                        FROM feedbag
+                                        WHERE feedbag.screenName = clientSideBuddyList.me
+                                          AND feedbag.name = clientSideBuddyList.them
+                                          AND feedbag.classId IN (0, 2, 3))),
      theirPrivacyPrefs AS (SELECT buddyListMode.screenName,
                                   CASE
                                       WHEN buddyListMode.useFeedbag IS TRUE THEN IFNULL(feedbagPrefs.pdMode, 1)
@@ -3040,3 +3040,58 @@ func TestSQLiteUserStore_AllRelationships(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLiteUserStore_TransientWatches(t *testing.T) {
+	defer func() {
+		_ = os.Remove(testFile)
+	}()
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	me := NewIdentScreenName("me")
+	buddy := NewIdentScreenName("buddy")
+	them := NewIdentScreenName("them")
+
+	// me has a server-side buddy list with one buddy
+	assert.NoError(t, feedbagStore.UseFeedbag(me))
+	assert.NoError(t, feedbagStore.FeedbagUpsert(me, []wire.FeedbagItem{
+		pdInfoItem(1, wire.FeedbagPDModePermitAll),
+		newFeedbagItem(wire.FeedbagClassIdBuddy, 2, buddy.String()),
+	}))
+	assert.NoError(t, feedbagStore.RegisterBuddyList(buddy))
+	assert.NoError(t, feedbagStore.RegisterBuddyList(them))
+
+	// a transient watch on a user already on the server-side list doesn't
+	// produce a duplicate relationship
+	assert.NoError(t, feedbagStore.AddBuddy(me, buddy))
+	have, err := feedbagStore.AllRelationships(me, nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Relationship{
+		{User: buddy, IsOnYourList: true},
+	}, have)
+
+	// a transient watch on a user who isn't on the list delivers their
+	// presence
+	assert.NoError(t, feedbagStore.AddBuddy(me, them))
+	have, err = feedbagStore.AllRelationships(them, nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Relationship{
+		{User: me, IsOnTheirList: true},
+	}, have)
+
+	// removing the watch stops presence updates
+	assert.NoError(t, feedbagStore.RemoveBuddy(me, them))
+	have, err = feedbagStore.AllRelationships(them, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, have)
+
+	// signing off removes the watch, so it's gone when the user signs on
+	// again
+	assert.NoError(t, feedbagStore.AddBuddy(me, them))
+	assert.NoError(t, feedbagStore.UnregisterBuddyList(me))
+	assert.NoError(t, feedbagStore.UseFeedbag(me))
+	have, err = feedbagStore.AllRelationships(them, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, have)
+}
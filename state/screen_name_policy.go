@@ -0,0 +1,138 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// defaultScreenNameMinLetters is the minimum number of letters in an AIM
+	// screen name.
+	defaultScreenNameMinLetters = 3
+	// defaultScreenNameMaxLength is the maximum length of an AIM screen name,
+	// including spaces.
+	defaultScreenNameMaxLength = 16
+	// maxScreenNameLength is the largest screen name that fits in the
+	// single-byte length prefix used by the OSCAR protocol.
+	maxScreenNameLength = 255
+)
+
+// NewScreenNamePolicy creates a ScreenNamePolicy and checks that its settings
+// are usable. allowedSymbols is the set of punctuation characters, besides
+// letters, digits, and spaces, that screen names may contain. minLetters and
+// maxLength fall back to the AIM defaults of 3 and 16 when set to 0.
+func NewScreenNamePolicy(allowedSymbols string, asciiOnly bool, minLetters int, maxLength int) (ScreenNamePolicy, error) {
+	policy := ScreenNamePolicy{
+		AllowedSymbols: allowedSymbols,
+		ASCIIOnly:      asciiOnly,
+		MinLetters:     minLetters,
+		MaxLength:      maxLength,
+	}
+
+	if minLetters < 0 {
+		return policy, fmt.Errorf("minimum letter count %d must not be negative", minLetters)
+	}
+	if maxLength < 0 || maxLength > maxScreenNameLength {
+		return policy, fmt.Errorf("maximum length %d must be between 0 and %d", maxLength, maxScreenNameLength)
+	}
+	if policy.minLetters() > policy.maxLength() {
+		return policy, fmt.Errorf("minimum letter count %d exceeds maximum length %d", policy.minLetters(), policy.maxLength())
+	}
+	for _, ch := range allowedSymbols {
+		if !unicode.IsPunct(ch) && !unicode.IsSymbol(ch) {
+			return policy, fmt.Errorf("allowed symbol %q is not a punctuation or symbol character", ch)
+		}
+	}
+
+	return policy, nil
+}
+
+// ScreenNamePolicy defines the characters and length permitted in AIM screen
+// names. The zero value enforces the classic AIM rules: 3 to 16 characters
+// made up of letters, digits, and spaces.
+type ScreenNamePolicy struct {
+	// AllowedSymbols is the set of punctuation characters, besides letters,
+	// digits, and spaces, that a screen name may contain.
+	AllowedSymbols string
+	// ASCIIOnly restricts letters and digits to the ASCII range.
+	ASCIIOnly bool
+	// MinLetters is the minimum number of letters in a screen name. Defaults
+	// to 3 if 0.
+	MinLetters int
+	// MaxLength is the maximum length of a screen name in bytes, including
+	// spaces. Defaults to 16 if 0.
+	MaxLength int
+}
+
+func (p ScreenNamePolicy) minLetters() int {
+	if p.MinLetters == 0 {
+		return defaultScreenNameMinLetters
+	}
+	return p.MinLetters
+}
+
+func (p ScreenNamePolicy) maxLength() int {
+	if p.MaxLength == 0 {
+		return defaultScreenNameMaxLength
+	}
+	return p.MaxLength
+}
+
+// isLetter indicates whether ch counts as a letter under the policy.
+func (p ScreenNamePolicy) isLetter(ch rune) bool {
+	if p.ASCIIOnly && ch > unicode.MaxASCII {
+		return false
+	}
+	return unicode.IsLetter(ch)
+}
+
+// isAllowed indicates whether ch may appear in a screen name under the
+// policy.
+func (p ScreenNamePolicy) isAllowed(ch rune) bool {
+	switch {
+	case ch == ' ':
+		return true
+	case p.ASCIIOnly && ch > unicode.MaxASCII:
+		return false
+	case unicode.IsLetter(ch) || unicode.IsDigit(ch):
+		return true
+	default:
+		return strings.ContainsRune(p.AllowedSymbols, ch)
+	}
+}
+
+// ValidateScreenName returns an error if name is not a valid AIM screen name
+// under policy.
+// Possible errors:
+//   - ErrAIMHandleLength: if the screen name has fewer letters than the policy
+//     minimum or is longer than the policy maximum (including spaces).
+//   - ErrAIMHandleInvalidFormat: if the screen name does not start with a
+//     letter, ends with a space, or contains a character that the policy
+//     doesn't allow, such as a control character or emoji.
+func ValidateScreenName(policy ScreenNamePolicy, name DisplayScreenName) error {
+	letters := 0
+	for _, ch := range name {
+		if policy.isLetter(ch) {
+			letters++
+		}
+	}
+	if letters < policy.minLetters() || len(name) > policy.maxLength() {
+		return fmt.Errorf("%w: screen name must contain at least %d letters and be no longer than %d characters",
+			ErrAIMHandleLength, policy.minLetters(), policy.maxLength())
+	}
+
+	for i, ch := range name {
+		if i == 0 && !policy.isLetter(ch) {
+			return fmt.Errorf("%w: screen name must start with a letter", ErrAIMHandleInvalidFormat)
+		}
+		if !policy.isAllowed(ch) {
+			return fmt.Errorf("%w: screen name must not contain %q", ErrAIMHandleInvalidFormat, ch)
+		}
+	}
+	if name[len(name)-1] == ' ' {
+		return fmt.Errorf("%w: screen name must not end with a space", ErrAIMHandleInvalidFormat)
+	}
+
+	return nil
+}
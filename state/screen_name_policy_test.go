@@ -0,0 +1,171 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateScreenName(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ScreenNamePolicy
+		input   DisplayScreenName
+		wantErr error
+	}{
+		{
+			name:  "default policy allows letters, digits, and spaces",
+			input: "Chatty Chuck 99",
+		},
+		{
+			name:  "default policy allows unicode letters",
+			input: "Chätting Chuck",
+		},
+		{
+			name:    "default policy rejects symbols",
+			input:   "chatting_chuck",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "default policy rejects emoji",
+			input:   "chat😀chuck",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "default policy rejects control characters",
+			input:   "chatting\tchuck",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "default policy rejects name longer than 16 characters",
+			input:   "chatting chuck 12",
+			wantErr: ErrAIMHandleLength,
+		},
+		{
+			name:   "allowed symbols are accepted",
+			policy: ScreenNamePolicy{AllowedSymbols: "_-."},
+			input:  "chatting_chuck.9",
+		},
+		{
+			name:    "symbols outside the allowed set are rejected",
+			policy:  ScreenNamePolicy{AllowedSymbols: "_-."},
+			input:   "chatting@chuck",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "allowed symbol can't start a name",
+			policy:  ScreenNamePolicy{AllowedSymbols: "_"},
+			input:   "_chattingchuck",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "ascii-only policy rejects unicode letters",
+			policy:  ScreenNamePolicy{ASCIIOnly: true},
+			input:   "Chätting Chuck",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "ascii-only policy rejects non-ascii first letter",
+			policy:  ScreenNamePolicy{ASCIIOnly: true},
+			input:   "Ächting",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+		{
+			name:    "ascii-only policy doesn't count unicode letters toward minimum",
+			policy:  ScreenNamePolicy{ASCIIOnly: true},
+			input:   "Cää",
+			wantErr: ErrAIMHandleLength,
+		},
+		{
+			name:   "custom length allows longer names",
+			policy: ScreenNamePolicy{MaxLength: 24},
+			input:  "Chatting Chuck The Third",
+		},
+		{
+			name:    "custom length rejects names over the limit",
+			policy:  ScreenNamePolicy{MaxLength: 8},
+			input:   "Chatting Chuck",
+			wantErr: ErrAIMHandleLength,
+		},
+		{
+			name:    "custom minimum letters rejects short names",
+			policy:  ScreenNamePolicy{MinLetters: 5},
+			input:   "Chuc",
+			wantErr: ErrAIMHandleLength,
+		},
+		{
+			name:    "spaces still can't end a name",
+			policy:  ScreenNamePolicy{AllowedSymbols: "_"},
+			input:   "chatting_chuck ",
+			wantErr: ErrAIMHandleInvalidFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateScreenName(tt.policy, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewScreenNamePolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedSymbols string
+		minLetters     int
+		maxLength      int
+		wantErr        bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:           "custom settings",
+			allowedSymbols: "_-.",
+			minLetters:     2,
+			maxLength:      32,
+		},
+		{
+			name:           "allowed symbols must be punctuation",
+			allowedSymbols: "_a",
+			wantErr:        true,
+		},
+		{
+			name:           "allowed symbols can't include spaces",
+			allowedSymbols: "_ ",
+			wantErr:        true,
+		},
+		{
+			name:      "max length exceeds protocol limit",
+			maxLength: 256,
+			wantErr:   true,
+		},
+		{
+			name:       "min letters exceeds max length",
+			minLetters: 10,
+			maxLength:  8,
+			wantErr:    true,
+		},
+		{
+			name:       "negative min letters",
+			minLetters: -1,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewScreenNamePolicy(tt.allowedSymbols, false, tt.minLetters, tt.maxLength)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
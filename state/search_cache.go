@@ -0,0 +1,80 @@
+package state
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewSearchCache creates a new instance of SearchCache that serves cached
+// search results for up to ttl.
+func NewSearchCache(ttl time.Duration) *SearchCache {
+	return &SearchCache{
+		entries: make(map[string]searchCacheEntry),
+		nowFn:   time.Now,
+		ttl:     ttl,
+	}
+}
+
+// searchCacheEntry is the cached result of a directory search.
+type searchCacheEntry struct {
+	users   []User
+	total   int
+	expires time.Time
+}
+
+// SearchCache caches the results of user directory searches, keyed by the
+// normalized search query, so that bursts of identical searches don't
+// re-query the database. It is safe to use with multiple goroutines.
+type SearchCache struct {
+	entries map[string]searchCacheEntry
+	mutex   sync.Mutex
+	nowFn   func() time.Time
+	ttl     time.Duration
+}
+
+// Get returns the cached results for the search identified by key. It returns
+// false if the results aren't cached or have expired.
+func (c *SearchCache) Get(key string) ([]User, int, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	if !c.nowFn().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, 0, false
+	}
+	return slices.Clone(entry.users), entry.total, true
+}
+
+// Put caches the results of the search identified by key.
+func (c *SearchCache) Put(key string, users []User, total int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.nowFn()
+
+	// drop expired entries so that one-off searches don't pile up
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = searchCacheEntry{
+		users:   slices.Clone(users),
+		total:   total,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// Clear removes all cached results. It is called whenever user directory
+// info changes, since the change may affect the results of any search.
+func (c *SearchCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewSearchCache(10 * time.Second)
+	cache.nowFn = func() time.Time { return now }
+
+	_, _, ok := cache.Get("query")
+	assert.False(t, ok)
+
+	users := []User{{IdentScreenName: NewIdentScreenName("user1")}}
+	cache.Put("query", users, 5)
+
+	// mutating the results doesn't change the cached copy
+	users[0].IdentScreenName = NewIdentScreenName("user2")
+
+	have, total, ok := cache.Get("query")
+	assert.True(t, ok)
+	assert.Equal(t, []User{{IdentScreenName: NewIdentScreenName("user1")}}, have)
+	assert.Equal(t, 5, total)
+
+	// results expire after the TTL
+	now = now.Add(10 * time.Second)
+	_, _, ok = cache.Get("query")
+	assert.False(t, ok)
+
+	// clearing drops all results
+	cache.Put("query", users, 1)
+	cache.Clear()
+	_, _, ok = cache.Get("query")
+	assert.False(t, ok)
+}
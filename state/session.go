@@ -1,7 +1,10 @@
 package state
 
 import (
+	"net/netip"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mk6i/retro-aim-server/wire"
@@ -23,21 +26,38 @@ const (
 // Session represents a user's current session. Unless stated otherwise, all
 // methods may be safely accessed by multiple goroutines.
 type Session struct {
+	accountCreatedAt  time.Time
+	autoResponseTo    map[IdentScreenName]bool
 	awayMessage       string
+	buddiesOnline     map[IdentScreenName]bool
 	caps              [][16]byte
 	chatRoomCookie    string
 	closed            bool
 	displayScreenName DisplayScreenName
+	feedbagCluster    time.Time
+	feedbagInUse      bool
+	feedbagQueried    bool
+	foodGroupVersions map[uint16]uint16
 	identScreenName   IdentScreenName
 	idle              bool
 	idleTime          time.Time
+	kicked            bool
+	lastIMTime        time.Time
+	lastLookupTime    time.Time
+	lookupCount       int
 	msgCh             chan wire.SNACMessage
 	mutex             sync.RWMutex
 	nowFn             func() time.Time
+	overflowed        atomic.Bool
+	permitMask        uint16
+	remoteAddr        netip.Addr
 	signonComplete    bool
 	signonTime        time.Time
+	spectator         bool
 	stopCh            chan struct{}
+	traffic           *TrafficCounter
 	uin               uint32
+	undelivered       atomic.Uint32
 	warning           uint16
 	userInfoBitmask   uint16
 	userStatusBitmask uint32
@@ -50,8 +70,10 @@ func NewSession() *Session {
 	return &Session{
 		msgCh:             make(chan wire.SNACMessage, 1000),
 		nowFn:             time.Now,
+		permitMask:        permitMaskAll,
 		stopCh:            make(chan struct{}),
 		signonTime:        time.Now(),
+		traffic:           &TrafficCounter{},
 		caps:              make([][16]byte, 0),
 		userInfoBitmask:   wire.OServiceUserFlagOSCARFree,
 		userStatusBitmask: wire.OServiceUserStatusAvailable,
@@ -81,6 +103,48 @@ func (s *Session) UserInfoBitmask() (flags uint16) {
 	return s.userInfoBitmask
 }
 
+// permitMaskAll is the default group permit mask, which allows users of every
+// class.
+const permitMaskAll uint16 = 0xFFFF
+
+// permitMaskClasses are the user info flags that identify a user's class for
+// the purposes of the group permit mask. Transient flags, such as away status,
+// are not considered.
+const permitMaskClasses = wire.OServiceUserFlagUnconfirmed |
+	wire.OServiceUserFlagAdministrator |
+	wire.OServiceUserFlagAOL |
+	wire.OServiceUserFlagOSCARPay |
+	wire.OServiceUserFlagOSCARFree |
+	wire.OServiceUserFlagICQ |
+	wire.OServiceUserFlagWireless |
+	wire.OServiceUserFlagInternal |
+	wire.OServiceUserFlagBot |
+	wire.OServiceUserFlagOneWayWireless |
+	wire.OServiceUserFlagOfficial
+
+// SetPermitMask sets the group permit mask, a set of wire.OServiceUserFlag*
+// user classes that are allowed to interact with the user.
+func (s *Session) SetPermitMask(mask uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.permitMask = mask
+}
+
+// PermitMask returns the group permit mask.
+func (s *Session) PermitMask() uint16 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.permitMask
+}
+
+// PermitsClassOf indicates whether the group permit mask allows the user of
+// other to interact with the user. A user is allowed if any of their user
+// classes are in the mask.
+func (s *Session) PermitsClassOf(other *Session) bool {
+	theirClasses := other.UserInfoBitmask() & permitMaskClasses
+	return s.PermitMask()&theirClasses != 0
+}
+
 // SetUserStatusBitmask sets the user status bitmask from the client.
 func (s *Session) SetUserStatusBitmask(bitmask uint32) {
 	s.mutex.Lock()
@@ -96,6 +160,15 @@ func (s *Session) IncrementWarning(incr uint16) {
 	s.warning += incr
 }
 
+// DecayWarning decreases the user's warning level by decr, stopping at zero.
+// It returns the new warning level.
+func (s *Session) DecayWarning(decr uint16) uint16 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.warning -= min(decr, s.warning)
+	return s.warning
+}
+
 // Invisible returns true if the user is idle.
 func (s *Session) Invisible() bool {
 	s.mutex.RLock()
@@ -103,6 +176,21 @@ func (s *Session) Invisible() bool {
 	return s.userStatusBitmask&wire.OServiceUserStatusInvisible == wire.OServiceUserStatusInvisible
 }
 
+// DND returns true if the user has set "do not disturb" status.
+func (s *Session) DND() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.userStatusBitmask&wire.OServiceUserStatusDND == wire.OServiceUserStatusDND
+}
+
+// Occupied returns true if the user has set ICQ "occupied" status. Users in
+// "do not disturb" mode are also occupied.
+func (s *Session) Occupied() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.userStatusBitmask&wire.OServiceUserStatusBusy == wire.OServiceUserStatusBusy
+}
+
 // SetIdentScreenName sets the user's screen name.
 func (s *Session) SetIdentScreenName(screenName IdentScreenName) {
 	s.mutex.Lock()
@@ -131,6 +219,21 @@ func (s *Session) DisplayScreenName() DisplayScreenName {
 	return s.displayScreenName
 }
 
+// SetRemoteAddr sets the IP address of the user's BOS connection.
+func (s *Session) SetRemoteAddr(addr netip.Addr) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.remoteAddr = addr
+}
+
+// RemoteAddr returns the IP address of the user's BOS connection. It's the
+// zero value if the address is unknown.
+func (s *Session) RemoteAddr() netip.Addr {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.remoteAddr
+}
+
 // SetSignonTime sets the user's sign-ontime.
 func (s *Session) SetSignonTime(t time.Time) {
 	s.mutex.Lock()
@@ -138,6 +241,21 @@ func (s *Session) SetSignonTime(t time.Time) {
 	s.signonTime = t
 }
 
+// SetAccountCreatedAt sets when the user's account was created.
+func (s *Session) SetAccountCreatedAt(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accountCreatedAt = t
+}
+
+// AccountCreatedAt reports when the user's account was created. It's zero if
+// the creation time is unknown.
+func (s *Session) AccountCreatedAt() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.accountCreatedAt
+}
+
 // SignonTime reports when the user signed on
 func (s *Session) SignonTime() time.Time {
 	s.mutex.RLock()
@@ -189,6 +307,88 @@ func (s *Session) AwayMessage() string {
 	return s.awayMessage
 }
 
+// SetLastIMTime records when the user last sent an instant message.
+func (s *Session) SetLastIMTime(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastIMTime = t
+}
+
+// LastIMTime returns when the user last sent an instant message.
+func (s *Session) LastIMTime() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.lastIMTime
+}
+
+// RecordUserLookup records that the user looked up another user at time t and
+// returns the number of lookups the user has made during the session,
+// including this one.
+func (s *Session) RecordUserLookup(t time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastLookupTime = t
+	s.lookupCount++
+	return s.lookupCount
+}
+
+// LastUserLookupTime returns when the user last looked up another user.
+func (s *Session) LastUserLookupTime() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.lastLookupTime
+}
+
+// StartFeedbagCluster records that the client opened a group of feedbag edits
+// at time t.
+func (s *Session) StartFeedbagCluster(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.feedbagCluster = t
+}
+
+// EndFeedbagCluster records that the client closed its group of feedbag
+// edits.
+func (s *Session) EndFeedbagCluster() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.feedbagCluster = time.Time{}
+}
+
+// FeedbagClusterStart returns when the client opened its current group of
+// feedbag edits, or the zero time if no group is open.
+func (s *Session) FeedbagClusterStart() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.feedbagCluster
+}
+
+// ExpectAutoResponse records that the user received an instant message from
+// sender that the user's client may answer with an auto-response, such as an
+// away message.
+func (s *Session) ExpectAutoResponse(sender IdentScreenName) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.autoResponseTo == nil {
+		s.autoResponseTo = make(map[IdentScreenName]bool)
+	}
+	s.autoResponseTo[sender] = true
+}
+
+// TakeAutoResponse reports whether the user may send an auto-response to
+// recipient, which is the case if recipient sent the user an instant message
+// since the user's last auto-response to them. Each message allows one
+// auto-response.
+func (s *Session) TakeAutoResponse(recipient IdentScreenName) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.autoResponseTo[recipient] {
+		return false
+	}
+	delete(s.autoResponseTo, recipient)
+	return true
+}
+
 // SetChatRoomCookie sets the chatRoomCookie for the chat room the user is currently in.
 func (s *Session) SetChatRoomCookie(cookie string) {
 	s.mutex.Lock()
@@ -203,6 +403,60 @@ func (s *Session) ChatRoomCookie() string {
 	return s.chatRoomCookie
 }
 
+// SetSpectator marks the chat session as a read-only spectator. Spectators
+// receive chat room messages, but they can't post and are hidden from the
+// room's occupant list.
+func (s *Session) SetSpectator(spectator bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.spectator = spectator
+}
+
+// Spectator indicates whether the chat session is a read-only spectator.
+func (s *Session) Spectator() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.spectator
+}
+
+// Traffic returns the counters for bytes sent and received over the session's
+// client connection.
+func (s *Session) Traffic() *TrafficCounter {
+	return s.traffic
+}
+
+// SetFeedbagInUse indicates that the server-side buddy list has been
+// activated for the session.
+func (s *Session) SetFeedbagInUse() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.feedbagInUse = true
+}
+
+// FeedbagInUse indicates whether the server-side buddy list has been
+// activated for the session.
+func (s *Session) FeedbagInUse() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.feedbagInUse
+}
+
+// SetFeedbagQueried indicates that the client has retrieved its server-side
+// buddy list.
+func (s *Session) SetFeedbagQueried() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.feedbagQueried = true
+}
+
+// FeedbagQueried indicates whether the client has retrieved its server-side
+// buddy list.
+func (s *Session) FeedbagQueried() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.feedbagQueried
+}
+
 // SignonComplete indicates whether the client has completed the sign-on sequence.
 func (s *Session) SignonComplete() bool {
 	s.mutex.RLock()
@@ -273,8 +527,13 @@ func (s *Session) userInfo() wire.TLVList {
 	}
 
 	// capabilities (buddy icon, chat, etc...)
-	if len(s.caps) > 0 {
-		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoOscarCaps, s.caps))
+	caps := s.caps
+	if s.userInfoBitmask&wire.OServiceUserFlagOfficial == wire.OServiceUserFlagOfficial {
+		// badge official accounts for clients that recognize the capability
+		caps = append(slices.Clone(caps), wire.CapOfficialBadge)
+	}
+	if len(caps) > 0 {
+		tlvs.Append(wire.NewTLVBE(wire.OServiceUserInfoOscarCaps, caps))
 	}
 
 	return tlvs
@@ -315,21 +574,95 @@ func (s *Session) ReceiveMessage() chan wire.SNACMessage {
 // SessSendStatus to indicate whether the message was successfully sent or
 // not. This method is non-blocking.
 func (s *Session) RelayMessage(msg wire.SNACMessage) SessSendStatus {
+	status := s.relayMessage(msg)
+	if status == SessSendOK {
+		s.recordBuddyPresence(msg)
+	}
+	return status
+}
+
+func (s *Session) relayMessage(msg wire.SNACMessage) SessSendStatus {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 	if s.closed {
+		s.countUndelivered()
 		return SessSendClosed
 	}
 	select {
 	case s.msgCh <- msg:
 		return SessSendOK
 	case <-s.stopCh:
+		s.countUndelivered()
 		return SessSendClosed
 	default:
+		s.overflowed.Store(true)
+		s.undelivered.Add(1)
 		return SessQueueFull
 	}
 }
 
+// recordBuddyPresence keeps track of the buddies that the user's client was
+// told are online by buddy arrival and departure messages relayed to the user.
+func (s *Session) recordBuddyPresence(msg wire.SNACMessage) {
+	var buddy IdentScreenName
+	var online bool
+	switch body := msg.Body.(type) {
+	case wire.SNAC_0x03_0x0B_BuddyArrived:
+		buddy, online = NewIdentScreenName(body.ScreenName), true
+	case wire.SNAC_0x03_0x0C_BuddyDeparted:
+		buddy, online = NewIdentScreenName(body.ScreenName), false
+	default:
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if online {
+		if s.buddiesOnline == nil {
+			s.buddiesOnline = make(map[IdentScreenName]bool)
+		}
+		s.buddiesOnline[buddy] = true
+	} else {
+		delete(s.buddiesOnline, buddy)
+	}
+}
+
+// BuddyToldOnline reports whether the last buddy arrival or departure message
+// about buddy that was relayed to the user said buddy is online.
+func (s *Session) BuddyToldOnline(buddy IdentScreenName) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.buddiesOnline[buddy]
+}
+
+// BuddiesToldOnline returns the buddies that the user was last told are
+// online via buddy arrival messages.
+func (s *Session) BuddiesToldOnline() []IdentScreenName {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	buddies := make([]IdentScreenName, 0, len(s.buddiesOnline))
+	for buddy := range s.buddiesOnline {
+		buddies = append(buddies, buddy)
+	}
+	return buddies
+}
+
+// countUndelivered counts a message that couldn't be relayed after the
+// session's queue overflowed. Messages relayed to a session that closed for
+// any other reason, such as signing off, aren't counted.
+func (s *Session) countUndelivered() {
+	if s.overflowed.Load() {
+		s.undelivered.Add(1)
+	}
+}
+
+// UndeliveredCount returns the number of messages that were not relayed to
+// the session because its queue overflowed, including messages relayed
+// after the overflow closed the session.
+func (s *Session) UndeliveredCount() uint32 {
+	return s.undelivered.Load()
+}
+
 // Close shuts down the session's ability to relay messages. Once invoked,
 // RelayMessage returns SessQueueFull and Closed returns a closed channel.
 // It is not possible to re-open message relaying once closed. It is safe to
@@ -344,6 +677,24 @@ func (s *Session) Close() {
 	s.closed = true
 }
 
+// Kick closes the session on behalf of an operator, as opposed to the user
+// signing on from another location.
+func (s *Session) Kick() {
+	s.mutex.Lock()
+	if !s.closed {
+		s.kicked = true
+	}
+	s.mutex.Unlock()
+	s.Close()
+}
+
+// Kicked indicates whether the session was closed by Kick.
+func (s *Session) Kicked() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.kicked
+}
+
 // Closed blocks until the session is closed.
 func (s *Session) Closed() <-chan struct{} {
 	return s.stopCh
@@ -362,3 +713,19 @@ func (s *Session) ClientID() string {
 	defer s.mutex.RUnlock()
 	return s.clientID
 }
+
+// SetFoodGroupVersions sets the food group versions negotiated with the
+// client, keyed by food group.
+func (s *Session) SetFoodGroupVersions(versions map[uint16]uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.foodGroupVersions = versions
+}
+
+// FoodGroupVersion returns the version negotiated with the client for
+// foodGroup. It returns 0 if no version was negotiated.
+func (s *Session) FoodGroupVersion(foodGroup uint16) uint16 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.foodGroupVersions[foodGroup]
+}
@@ -10,13 +10,55 @@ import (
 	"github.com/mk6i/retro-aim-server/wire"
 )
 
+// sessionSlot holds the sessions of a signed-on user. sess is the session
+// that represents the user to everyone else. others holds the additional
+// sessions of a user who signed on more than once under the allow-multiple
+// concurrent login policy.
 type sessionSlot struct {
 	sess    *Session
+	others  []*Session
 	removed chan bool
+	// departing is set once the last session has started signing off, after
+	// which no more sessions may join the slot.
+	departing bool
+}
+
+// all returns every session in the slot.
+func (rec *sessionSlot) all() []*Session {
+	return append([]*Session{rec.sess}, rec.others...)
+}
+
+// remove takes sess out of a slot that has more than one session. If sess
+// represents the user, the session that signed on after it takes over. It
+// reports whether sess was in the slot.
+func (rec *sessionSlot) remove(sess *Session) bool {
+	if rec.sess == sess {
+		rec.sess, rec.others = rec.others[0], rec.others[1:]
+		return true
+	}
+	for i, other := range rec.others {
+		if other == sess {
+			rec.others = append(rec.others[:i], rec.others[i+1:]...)
+			return true
+		}
+	}
+	return false
 }
 
 var errSessConflict = errors.New("session conflict: another session was created concurrently for this user")
 
+var (
+	// ErrVirtualUserConflict indicates that a screen name can't be used for a
+	// virtual user because it belongs to a registered account or a signed-on
+	// user.
+	ErrVirtualUserConflict = errors.New("screen name belongs to a registered or signed-on user")
+	// ErrVirtualUserNotFound indicates that a virtual user is not signed on.
+	ErrVirtualUserNotFound = errors.New("virtual user is not signed on")
+	// ErrSessionActive indicates that a session can't be added because the
+	// user already has an active session.
+	ErrSessionActive = errors.New("user already has an active session")
+)
+
 // InMemorySessionManager handles the lifecycle of a user session and provides
 // synchronized message relay between sessions in the session pool. An
 // InMemorySessionManager is safe for concurrent use by multiple goroutines.
@@ -39,18 +81,23 @@ func (s *InMemorySessionManager) RelayToAll(ctx context.Context, msg wire.SNACMe
 	s.mapMutex.RLock()
 	defer s.mapMutex.RUnlock()
 	for _, rec := range s.store {
-		s.maybeRelayMessage(ctx, msg, rec.sess)
+		for _, sess := range rec.all() {
+			s.maybeRelayMessage(ctx, msg, sess)
+		}
 	}
 }
 
-// RelayToScreenName relays a message to a session with a matching screen name.
+// RelayToScreenName relays a message to the sessions with a matching screen
+// name.
 func (s *InMemorySessionManager) RelayToScreenName(ctx context.Context, screenName IdentScreenName, msg wire.SNACMessage) {
-	sess := s.RetrieveSession(screenName)
-	if sess == nil {
+	sessions := s.retrieveByScreenNames([]IdentScreenName{screenName})
+	if len(sessions) == 0 {
 		s.logger.WarnContext(ctx, "can't send notification because user is not online", "recipient", screenName, "message", msg)
 		return
 	}
-	s.maybeRelayMessage(ctx, msg, sess)
+	for _, sess := range sessions {
+		s.maybeRelayMessage(ctx, msg, sess)
+	}
 }
 
 // RelayToScreenNames relays a message to sessions with matching screenNames.
@@ -85,17 +132,63 @@ func (s *InMemorySessionManager) AddSession(ctx context.Context, screenName Disp
 		// lock while we wait.
 		s.mapMutex.Unlock()
 
-		// signal to callers that this session has to go
-		active.sess.Close()
+		// signal to callers that these sessions have to go
+		for _, sess := range active.all() {
+			sess.Close()
+		}
 
-		select {
-		case <-active.removed: // wait for RemoveSession to be called
-		case <-ctx.Done():
-			return nil, fmt.Errorf("waiting for previous session to terminate: %w", ctx.Err())
+		if err := s.waitForRemoval(ctx, active); err != nil {
+			return nil, err
 		}
+	}
+
+	defer s.mapMutex.Unlock()
 
-		// the session has been removed, let's try to replace it
-		s.mapMutex.Lock()
+	// make sure a concurrent call didn't already add a session
+	if active != nil && s.findRec(screenName.IdentScreenName()) != nil {
+		return nil, errSessConflict
+	}
+
+	return s.addSlot(screenName), nil
+}
+
+// AddSessionIfAbsent adds a new session to the pool unless a session with
+// the same screen name is already active, in which case it returns
+// ErrSessionActive and leaves the active session alone.
+func (s *InMemorySessionManager) AddSessionIfAbsent(screenName DisplayScreenName) (*Session, error) {
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	if s.findRec(screenName.IdentScreenName()) != nil {
+		return nil, ErrSessionActive
+	}
+
+	return s.addSlot(screenName), nil
+}
+
+// AddSessionInstance adds a new session to the pool alongside the user's
+// active sessions, if any. Messages sent to the user are delivered to every
+// session, while [InMemorySessionManager.RetrieveSession] returns the session
+// that signed on first, so the user has a single presence. If the user's last
+// session is signing off, the call blocks until it's removed or the context
+// is canceled.
+func (s *InMemorySessionManager) AddSessionInstance(ctx context.Context, screenName DisplayScreenName) (*Session, error) {
+	s.mapMutex.Lock()
+
+	active := s.findRec(screenName.IdentScreenName())
+	if active != nil && !active.departing {
+		defer s.mapMutex.Unlock()
+		sess := newSessionFor(screenName)
+		active.others = append(active.others, sess)
+		return sess, nil
+	}
+
+	if active != nil {
+		// the user is signing off, so this session can't join theirs
+		s.mapMutex.Unlock()
+		if err := s.waitForRemoval(ctx, active); err != nil {
+			return nil, err
+		}
 	}
 
 	defer s.mapMutex.Unlock()
@@ -105,16 +198,62 @@ func (s *InMemorySessionManager) AddSession(ctx context.Context, screenName Disp
 		return nil, errSessConflict
 	}
 
-	sess := NewSession()
-	sess.SetIdentScreenName(screenName.IdentScreenName())
-	sess.SetDisplayScreenName(screenName)
+	return s.addSlot(screenName), nil
+}
+
+// DetachSession removes sess from the pool if the user has other sessions,
+// in which case the user stays signed on. It reports whether sess was
+// removed. Otherwise, sess is the user's last session, and no more sessions
+// may join it until it's removed by [InMemorySessionManager.RemoveSession].
+func (s *InMemorySessionManager) DetachSession(sess *Session) bool {
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	rec, ok := s.store[sess.IdentScreenName()]
+	if !ok {
+		return false
+	}
+	if len(rec.others) == 0 {
+		if rec.sess == sess {
+			rec.departing = true
+		}
+		return false
+	}
+	return rec.remove(sess)
+}
 
+// waitForRemoval waits for the last session in rec to be removed by
+// [InMemorySessionManager.RemoveSession]. It must be called without holding
+// mapMutex, and it returns with mapMutex locked unless the context is
+// canceled.
+func (s *InMemorySessionManager) waitForRemoval(ctx context.Context, rec *sessionSlot) error {
+	select {
+	case <-rec.removed: // wait for RemoveSession to be called
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for previous session to terminate: %w", ctx.Err())
+	}
+	// the session has been removed, let's try to replace it
+	s.mapMutex.Lock()
+	return nil
+}
+
+// addSlot creates a session for screenName and adds it to the pool. The
+// caller must hold mapMutex.
+func (s *InMemorySessionManager) addSlot(screenName DisplayScreenName) *Session {
+	sess := newSessionFor(screenName)
 	s.store[sess.IdentScreenName()] = &sessionSlot{
 		sess:    sess,
 		removed: make(chan bool),
 	}
+	return sess
+}
 
-	return sess, nil
+// newSessionFor creates a session for screenName.
+func newSessionFor(screenName DisplayScreenName) *Session {
+	sess := NewSession()
+	sess.SetIdentScreenName(screenName.IdentScreenName())
+	sess.SetDisplayScreenName(screenName)
+	return sess
 }
 
 func (s *InMemorySessionManager) findRec(identScreenName IdentScreenName) *sessionSlot {
@@ -130,7 +269,15 @@ func (s *InMemorySessionManager) findRec(identScreenName IdentScreenName) *sessi
 func (s *InMemorySessionManager) RemoveSession(sess *Session) {
 	s.mapMutex.Lock()
 	defer s.mapMutex.Unlock()
-	if rec, ok := s.store[sess.IdentScreenName()]; ok && rec.sess == sess {
+	rec, ok := s.store[sess.IdentScreenName()]
+	if !ok {
+		return
+	}
+	if len(rec.others) > 0 {
+		rec.remove(sess)
+		return
+	}
+	if rec.sess == sess {
 		delete(s.store, sess.IdentScreenName())
 		close(rec.removed)
 	}
@@ -154,7 +301,7 @@ func (s *InMemorySessionManager) retrieveByScreenNames(screenNames []IdentScreen
 	for _, sn := range screenNames {
 		for _, rec := range s.store {
 			if sn == rec.sess.IdentScreenName() {
-				ret = append(ret, rec.sess)
+				ret = append(ret, rec.all()...)
 			}
 		}
 	}
@@ -168,6 +315,13 @@ func (s *InMemorySessionManager) Empty() bool {
 	return len(s.store) == 0
 }
 
+// SessionCount returns the number of sessions in the session pool.
+func (s *InMemorySessionManager) SessionCount() int {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+	return len(s.store)
+}
+
 // AllSessions returns all sessions in the session pool.
 func (s *InMemorySessionManager) AllSessions() []*Session {
 	s.mapMutex.RLock()
@@ -183,8 +337,9 @@ func (s *InMemorySessionManager) AllSessions() []*Session {
 // InMemoryChatSessionManager.
 func NewInMemoryChatSessionManager(logger *slog.Logger) *InMemoryChatSessionManager {
 	return &InMemoryChatSessionManager{
-		store:  make(map[string]*InMemorySessionManager),
-		logger: logger,
+		store:      make(map[string]*InMemorySessionManager),
+		spectators: make(map[string]map[IdentScreenName]bool),
+		logger:     logger,
 	}
 }
 
@@ -192,13 +347,48 @@ func NewInMemoryChatSessionManager(logger *slog.Logger) *InMemoryChatSessionMana
 // stored in memory. It provides thread-safe operations to add, remove, and
 // manipulate sessions as well as relay messages to participants.
 type InMemoryChatSessionManager struct {
-	logger   *slog.Logger
-	mapMutex sync.RWMutex
-	store    map[string]*InMemorySessionManager
+	logger     *slog.Logger
+	mapMutex   sync.RWMutex
+	spectators map[string]map[IdentScreenName]bool
+	store      map[string]*InMemorySessionManager
+}
+
+// AddSpectator allows a user to join a chat room as a read-only spectator.
+// It takes effect the next time the user joins the room.
+func (s *InMemoryChatSessionManager) AddSpectator(chatCookie string, screenName IdentScreenName) {
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	if _, ok := s.spectators[chatCookie]; !ok {
+		s.spectators[chatCookie] = make(map[IdentScreenName]bool)
+	}
+	s.spectators[chatCookie][screenName] = true
+}
+
+// RemoveSpectator revokes a user's spectator status for a chat room. It takes
+// effect the next time the user joins the room.
+func (s *InMemoryChatSessionManager) RemoveSpectator(chatCookie string, screenName IdentScreenName) {
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	delete(s.spectators[chatCookie], screenName)
+	if len(s.spectators[chatCookie]) == 0 {
+		delete(s.spectators, chatCookie)
+	}
+}
+
+// RemoveAllSpectators revokes the spectator status of every user in a chat
+// room. It's called when the room is deleted.
+func (s *InMemoryChatSessionManager) RemoveAllSpectators(chatCookie string) {
+	s.mapMutex.Lock()
+	defer s.mapMutex.Unlock()
+
+	delete(s.spectators, chatCookie)
 }
 
 // AddSession adds a user to a chat room. If screenName already exists, the old
-// session is replaced by a new one.
+// session is replaced by a new one. If the user was added as a spectator for
+// the room, the session is marked as a spectator.
 func (s *InMemoryChatSessionManager) AddSession(ctx context.Context, chatCookie string, screenName DisplayScreenName) (*Session, error) {
 	s.mapMutex.Lock()
 	defer s.mapMutex.Unlock()
@@ -215,11 +405,13 @@ func (s *InMemoryChatSessionManager) AddSession(ctx context.Context, chatCookie
 	}
 
 	sess.SetChatRoomCookie(chatCookie)
+	sess.SetSpectator(s.spectators[chatCookie][screenName.IdentScreenName()])
 
 	return sess, nil
 }
 
-// RemoveSession removes a user session from a chat room. It panics if you
+// RemoveSession removes a user session from a chat room. The room, along with
+// its spectators, is torn down when its last session leaves. It panics if you
 // attempt to remove the session twice.
 func (s *InMemoryChatSessionManager) RemoveSession(sess *Session) {
 	s.mapMutex.Lock()
@@ -233,9 +425,32 @@ func (s *InMemoryChatSessionManager) RemoveSession(sess *Session) {
 
 	if sessionManager.Empty() {
 		delete(s.store, sess.ChatRoomCookie())
+		delete(s.spectators, sess.ChatRoomCookie())
+	}
+}
+
+// CloseUserSessions closes the user's session in every chat room they
+// occupy. Closing a session ends its chat connection, which signs the user out
+// of the room and announces their departure to the remaining participants.
+func (s *InMemoryChatSessionManager) CloseUserSessions(screenName IdentScreenName) {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+
+	for _, sessionManager := range s.store {
+		if sess := sessionManager.RetrieveSession(screenName); sess != nil {
+			sess.Close()
+		}
 	}
 }
 
+// LiveChatRoomCount returns the number of chat rooms that have at least one
+// participant.
+func (s *InMemoryChatSessionManager) LiveChatRoomCount() int {
+	s.mapMutex.RLock()
+	defer s.mapMutex.RUnlock()
+	return len(s.store)
+}
+
 // AllSessions returns all chat room participants. Returns
 // ErrChatRoomNotFound if the room does not exist.
 func (s *InMemoryChatSessionManager) AllSessions(cookie string) []*Session {
@@ -67,6 +67,102 @@ func TestInMemorySessionManager_AddSession_SessionConflict(t *testing.T) {
 	assert.ErrorIs(t, err, errSessConflict)
 }
 
+func TestInMemorySessionManager_AddSessionIfAbsent(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+
+	sess1, err := sm.AddSessionIfAbsent("user-screen-name")
+	assert.NoError(t, err)
+	assert.Equal(t, sess1, sm.RetrieveSession(NewIdentScreenName("user-screen-name")))
+
+	// the active session is left alone
+	sess2, err := sm.AddSessionIfAbsent("user-screen-name")
+	assert.Nil(t, sess2)
+	assert.ErrorIs(t, err, ErrSessionActive)
+	select {
+	case <-sess1.Closed():
+		t.Fatal("active session should not be closed")
+	default:
+	}
+
+	// a new session can be added once the active one is removed
+	sm.RemoveSession(sess1)
+	sess3, err := sm.AddSessionIfAbsent("user-screen-name")
+	assert.NoError(t, err)
+	assert.Equal(t, sess3, sm.RetrieveSession(NewIdentScreenName("user-screen-name")))
+}
+
+func TestInMemorySessionManager_AddSessionInstance(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	ctx := context.Background()
+	screenName := NewIdentScreenName("user-screen-name")
+
+	sess1, err := sm.AddSessionInstance(ctx, "user-screen-name")
+	assert.NoError(t, err)
+	sess2, err := sm.AddSessionInstance(ctx, "user-screen-name")
+	assert.NoError(t, err)
+	assert.NotSame(t, sess1, sess2)
+
+	// the user has a single presence, represented by the first session
+	assert.Same(t, sess1, sm.RetrieveSession(screenName))
+	assert.Equal(t, 1, sm.SessionCount())
+
+	// messages are delivered to every session
+	msg := wire.SNACMessage{Frame: wire.SNACFrame{FoodGroup: wire.ICBM}}
+	sm.RelayToScreenName(ctx, screenName, msg)
+	assert.Equal(t, msg, <-sess1.ReceiveMessage())
+	assert.Equal(t, msg, <-sess2.ReceiveMessage())
+
+	// the first session signs off, and the second takes over
+	assert.True(t, sm.DetachSession(sess1))
+	assert.Same(t, sess2, sm.RetrieveSession(screenName))
+
+	// the last session signs off like a single session
+	assert.False(t, sm.DetachSession(sess2))
+	sm.RemoveSession(sess2)
+	assert.Nil(t, sm.RetrieveSession(screenName))
+}
+
+func TestInMemorySessionManager_AddSessionInstance_Departing(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	ctx := context.Background()
+
+	sess1, err := sm.AddSessionInstance(ctx, "user-screen-name")
+	assert.NoError(t, err)
+
+	// the last session starts signing off, so a new session has to wait for
+	// it to be removed rather than join it
+	assert.False(t, sm.DetachSession(sess1))
+	go sm.RemoveSession(sess1)
+
+	sess2, err := sm.AddSessionInstance(ctx, "user-screen-name")
+	assert.NoError(t, err)
+	assert.Same(t, sess2, sm.RetrieveSession(NewIdentScreenName("user-screen-name")))
+}
+
+func TestInMemorySessionManager_AddSession_ClosesInstances(t *testing.T) {
+	sm := NewInMemorySessionManager(slog.Default())
+	ctx := context.Background()
+
+	sess1, err := sm.AddSessionInstance(ctx, "user-screen-name")
+	assert.NoError(t, err)
+	sess2, err := sm.AddSessionInstance(ctx, "user-screen-name")
+	assert.NoError(t, err)
+
+	for _, sess := range []*Session{sess1, sess2} {
+		go func() {
+			<-sess.Closed()
+			if !sm.DetachSession(sess) {
+				sm.RemoveSession(sess)
+			}
+		}()
+	}
+
+	// every session is signed off in favor of the new one
+	sess3, err := sm.AddSession(ctx, "user-screen-name")
+	assert.NoError(t, err)
+	assert.Same(t, sess3, sm.RetrieveSession(NewIdentScreenName("user-screen-name")))
+}
+
 func TestInMemorySessionManager_Remove_Existing(t *testing.T) {
 	sm := NewInMemorySessionManager(slog.Default())
 
@@ -377,6 +473,112 @@ func TestInMemoryChatSessionManager_RelayToAllExcept_HappyPath(t *testing.T) {
 	}
 }
 
+func TestInMemoryChatSessionManager_RelayToAllExcept_QueueFull(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	cookie := "the-cookie"
+	sender, err := sm.AddSession(context.Background(), cookie, "sender")
+	assert.NoError(t, err)
+	slow, err := sm.AddSession(context.Background(), cookie, "slow-reader")
+	assert.NoError(t, err)
+
+	// fill the slow reader's queue
+	for slow.RelayMessage(wire.SNACMessage{}) == SessSendOK {
+	}
+	assert.Equal(t, uint32(1), slow.UndeliveredCount())
+
+	// the room keeps talking until the slow reader is removed from the room
+	msg := wire.SNACMessage{Frame: wire.SNACFrame{FoodGroup: wire.Chat}}
+	sm.RelayToAllExcept(context.Background(), cookie, sender.IdentScreenName(), msg)
+	sm.RelayToAllExcept(context.Background(), cookie, sender.IdentScreenName(), msg)
+
+	select {
+	case <-slow.Closed():
+	default:
+		assert.Fail(t, "slow reader should be disconnected")
+	}
+	assert.Equal(t, uint32(3), slow.UndeliveredCount())
+}
+
+func TestInMemoryChatSessionManager_AddSession_Spectator(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	cookie := "the-cookie"
+	sm.AddSpectator(cookie, NewIdentScreenName("spectator"))
+
+	chatter, err := sm.AddSession(context.Background(), cookie, "chatter")
+	assert.NoError(t, err)
+	assert.False(t, chatter.Spectator())
+
+	spectator, err := sm.AddSession(context.Background(), cookie, "Spectator")
+	assert.NoError(t, err)
+	assert.True(t, spectator.Spectator())
+
+	// spectator status is scoped to the room
+	otherRoom, err := sm.AddSession(context.Background(), "other-cookie", "Spectator")
+	assert.NoError(t, err)
+	assert.False(t, otherRoom.Spectator())
+
+	// spectators receive messages sent to the room
+	want := wire.SNACMessage{Frame: wire.SNACFrame{FoodGroup: wire.Chat}}
+	sm.RelayToAllExcept(context.Background(), cookie, chatter.IdentScreenName(), want)
+
+	select {
+	case have := <-spectator.ReceiveMessage():
+		assert.Equal(t, want, have)
+	default:
+		assert.Fail(t, "spectator should receive a message")
+	}
+
+	// revoked spectator status applies to the next join
+	sm.RemoveSpectator(cookie, NewIdentScreenName("spectator"))
+	sm.RemoveSession(spectator)
+	rejoined, err := sm.AddSession(context.Background(), cookie, "Spectator")
+	assert.NoError(t, err)
+	assert.False(t, rejoined.Spectator())
+}
+
+func TestInMemoryChatSessionManager_RemoveSession_SpectatorsTornDown(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	cookie := "the-cookie"
+	sm.AddSpectator(cookie, NewIdentScreenName("spectator"))
+
+	chatter, err := sm.AddSession(context.Background(), cookie, "chatter")
+	assert.NoError(t, err)
+	spectator, err := sm.AddSession(context.Background(), cookie, "spectator")
+	assert.NoError(t, err)
+
+	// spectators outlive sessions that leave while the room is occupied
+	sm.RemoveSession(spectator)
+	spectator, err = sm.AddSession(context.Background(), cookie, "spectator")
+	assert.NoError(t, err)
+	assert.True(t, spectator.Spectator())
+
+	// spectators go away with the room when its last session leaves
+	sm.RemoveSession(spectator)
+	sm.RemoveSession(chatter)
+	assert.Empty(t, sm.spectators)
+
+	rejoined, err := sm.AddSession(context.Background(), cookie, "spectator")
+	assert.NoError(t, err)
+	assert.False(t, rejoined.Spectator())
+}
+
+func TestInMemoryChatSessionManager_RemoveAllSpectators(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	sm.AddSpectator("the-cookie", NewIdentScreenName("spectator1"))
+	sm.AddSpectator("the-cookie", NewIdentScreenName("spectator2"))
+	sm.AddSpectator("other-cookie", NewIdentScreenName("spectator1"))
+
+	sm.RemoveAllSpectators("the-cookie")
+
+	assert.Equal(t, map[string]map[IdentScreenName]bool{
+		"other-cookie": {NewIdentScreenName("spectator1"): true},
+	}, sm.spectators)
+}
+
 func TestInMemoryChatSessionManager_AllSessions_RoomExists(t *testing.T) {
 	sm := NewInMemoryChatSessionManager(slog.Default())
 
@@ -397,6 +599,27 @@ func TestInMemoryChatSessionManager_AllSessions_RoomExists(t *testing.T) {
 	assert.True(t, lookup[user2])
 }
 
+func TestInMemoryChatSessionManager_LiveChatRoomCount(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+	assert.Equal(t, 0, sm.LiveChatRoomCount())
+
+	user1, err := sm.AddSession(context.Background(), "chat-room-1", "user-screen-name-1")
+	assert.NoError(t, err)
+	user2, err := sm.AddSession(context.Background(), "chat-room-1", "user-screen-name-2")
+	assert.NoError(t, err)
+	user3, err := sm.AddSession(context.Background(), "chat-room-2", "user-screen-name-3")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sm.LiveChatRoomCount())
+
+	// a room stops counting once everyone leaves
+	sm.RemoveSession(user1)
+	sm.RemoveSession(user2)
+	assert.Equal(t, 1, sm.LiveChatRoomCount())
+
+	sm.RemoveSession(user3)
+	assert.Equal(t, 0, sm.LiveChatRoomCount())
+}
+
 func TestInMemoryChatSessionManager_RelayToScreenName_SessionAndChatRoomExist(t *testing.T) {
 	sm := NewInMemoryChatSessionManager(slog.Default())
 
@@ -458,3 +681,32 @@ func TestInMemoryChatSessionManager_RemoveSession_DoubleLogin(t *testing.T) {
 
 	assert.Len(t, sm.AllSessions("chat-room-1"), 1)
 }
+
+func TestInMemoryChatSessionManager_CloseUserSessions(t *testing.T) {
+	sm := NewInMemoryChatSessionManager(slog.Default())
+
+	user1Room1, err := sm.AddSession(context.Background(), "chat-room-1", "user-screen-name-1")
+	assert.NoError(t, err)
+	user1Room2, err := sm.AddSession(context.Background(), "chat-room-2", "user-screen-name-1")
+	assert.NoError(t, err)
+	user2Room1, err := sm.AddSession(context.Background(), "chat-room-1", "user-screen-name-2")
+	assert.NoError(t, err)
+
+	sm.CloseUserSessions(NewIdentScreenName("user-screen-name-1"))
+
+	select {
+	case <-user1Room1.Closed():
+	default:
+		t.Fatal("expected session in chat-room-1 to be closed")
+	}
+	select {
+	case <-user1Room2.Closed():
+	default:
+		t.Fatal("expected session in chat-room-2 to be closed")
+	}
+	select {
+	case <-user2Room1.Closed():
+		t.Fatal("expected other user's session to remain open")
+	default:
+	}
+}
@@ -27,6 +27,14 @@ func TestSession_IncrementAndGetWarning(t *testing.T) {
 	assert.Equal(t, uint16(3), s.Warning())
 }
 
+func TestSession_DecayWarning(t *testing.T) {
+	s := NewSession()
+	s.IncrementWarning(30)
+	assert.Equal(t, uint16(20), s.DecayWarning(10))
+	assert.Equal(t, uint16(0), s.DecayWarning(50))
+	assert.Equal(t, uint16(0), s.Warning())
+}
+
 func TestSession_SetAndGetInvisible(t *testing.T) {
 	s := NewSession()
 	assert.False(t, s.Invisible())
@@ -34,6 +42,48 @@ func TestSession_SetAndGetInvisible(t *testing.T) {
 	assert.True(t, s.Invisible())
 }
 
+func TestSession_SetAndGetDND(t *testing.T) {
+	s := NewSession()
+	assert.False(t, s.DND())
+	s.SetUserStatusBitmask(wire.OServiceUserStatusDND)
+	assert.True(t, s.DND())
+}
+
+func TestSession_ICQStatusModes(t *testing.T) {
+	cases := []struct {
+		// name is the unit test name
+		name string
+		// status is the ICQ status mode set by the client
+		status uint32
+		// wantDND indicates whether the user is in "do not disturb" mode
+		wantDND bool
+		// wantOccupied indicates whether the user is occupied
+		wantOccupied bool
+	}{
+		{name: "away", status: wire.ICQStatusModeAway},
+		{name: "N/A", status: wire.ICQStatusModeNA},
+		{name: "occupied", status: wire.ICQStatusModeOccupied, wantOccupied: true},
+		{name: "DND", status: wire.ICQStatusModeDND, wantDND: true, wantOccupied: true},
+		{name: "free for chat", status: wire.ICQStatusModeFreeForChat},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSession()
+			s.SetUserStatusBitmask(tc.status | wire.OServiceUserStatusWebAware)
+			assert.Equal(t, tc.wantDND, s.DND())
+			assert.Equal(t, tc.wantOccupied, s.Occupied())
+			assert.False(t, s.Invisible())
+
+			// buddies see the exact status mode
+			userInfo := s.TLVUserInfo()
+			status, ok := userInfo.Uint32BE(wire.OServiceUserInfoStatus)
+			assert.True(t, ok)
+			assert.Equal(t, tc.status|wire.OServiceUserStatusWebAware, status)
+		})
+	}
+}
+
 func TestSession_SetAndGetScreenName(t *testing.T) {
 	s := NewSession()
 	assert.Empty(t, s.IdentScreenName())
@@ -237,6 +287,26 @@ func TestSession_TLVUserInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "user is official",
+			givenSessionFn: func() *Session {
+				s := NewSession()
+				s.SetSignonTime(time.Unix(1, 0))
+				s.SetUserInfoFlag(wire.OServiceUserFlagOfficial)
+				s.SetCaps([][16]byte{wire.CapChat})
+				return s
+			},
+			want: wire.TLVUserInfo{
+				TLVBlock: wire.TLVBlock{
+					TLVList: wire.TLVList{
+						wire.NewTLVBE(wire.OServiceUserInfoSignonTOD, uint32(1)),
+						wire.NewTLVBE(wire.OServiceUserInfoUserFlags, wire.OServiceUserFlagOSCARFree|wire.OServiceUserFlagOfficial),
+						wire.NewTLVBE(wire.OServiceUserInfoStatus, uint32(0x0000)),
+						wire.NewTLVBE(wire.OServiceUserInfoOscarCaps, [][16]byte{wire.CapChat, wire.CapOfficialBadge}),
+					},
+				},
+			},
+		},
 		{
 			name: "user has buddy icon",
 			givenSessionFn: func() *Session {
@@ -264,6 +334,38 @@ func TestSession_TLVUserInfo(t *testing.T) {
 	}
 }
 
+func TestSession_BuddiesToldOnline(t *testing.T) {
+	s := NewSession()
+
+	arrived := func(sn string) wire.SNACMessage {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyArrived},
+			Body:  wire.SNAC_0x03_0x0B_BuddyArrived{TLVUserInfo: wire.TLVUserInfo{ScreenName: sn}},
+		}
+	}
+	departed := func(sn string) wire.SNACMessage {
+		return wire.SNACMessage{
+			Frame: wire.SNACFrame{FoodGroup: wire.Buddy, SubGroup: wire.BuddyDeparted},
+			Body:  wire.SNAC_0x03_0x0C_BuddyDeparted{TLVUserInfo: wire.TLVUserInfo{ScreenName: sn}},
+		}
+	}
+
+	assert.Equal(t, SessSendOK, s.RelayMessage(arrived("Alice")))
+	assert.Equal(t, SessSendOK, s.RelayMessage(arrived("Bob")))
+	// an arrival also signals user info changes, such as going idle
+	assert.Equal(t, SessSendOK, s.RelayMessage(arrived("Bob")))
+	assert.Equal(t, SessSendOK, s.RelayMessage(departed("alice")))
+
+	assert.False(t, s.BuddyToldOnline(NewIdentScreenName("alice")))
+	assert.True(t, s.BuddyToldOnline(NewIdentScreenName("bob")))
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("bob")}, s.BuddiesToldOnline())
+
+	// messages that aren't delivered don't change what the user was told
+	s.Close()
+	assert.Equal(t, SessSendClosed, s.RelayMessage(departed("bob")))
+	assert.True(t, s.BuddyToldOnline(NewIdentScreenName("bob")))
+}
+
 func TestSession_SendAndRecvMessage_ExpectSessSendOK(t *testing.T) {
 	s := NewSession()
 
@@ -304,6 +406,8 @@ func TestSession_SendMessage_SessSendClosed(t *testing.T) {
 	if res := s.RelayMessage(wire.SNACMessage{}); res != SessSendClosed {
 		t.Fatalf("expected SessSendClosed, got %+v", res)
 	}
+	// messages missed after an ordinary close aren't delivery failures
+	assert.Zero(t, s.UndeliveredCount())
 }
 
 func TestSession_SendMessage_SessQueueFull(t *testing.T) {
@@ -318,6 +422,23 @@ func TestSession_SendMessage_SessQueueFull(t *testing.T) {
 	assert.Equal(t, SessQueueFull, s.RelayMessage(wire.SNACMessage{}))
 }
 
+func TestSession_UndeliveredCount(t *testing.T) {
+	s := Session{
+		msgCh:  make(chan wire.SNACMessage, 1),
+		stopCh: make(chan struct{}),
+	}
+	assert.Equal(t, SessSendOK, s.RelayMessage(wire.SNACMessage{}))
+	assert.Zero(t, s.UndeliveredCount())
+
+	// the queue overflows and the session is dropped
+	assert.Equal(t, SessQueueFull, s.RelayMessage(wire.SNACMessage{}))
+	s.Close()
+
+	// messages relayed before the session is removed are missed too
+	assert.Equal(t, SessSendClosed, s.RelayMessage(wire.SNACMessage{}))
+	assert.Equal(t, uint32(2), s.UndeliveredCount())
+}
+
 func TestSession_Close_Twice(t *testing.T) {
 	s := Session{
 		stopCh: make(chan struct{}),
@@ -345,3 +466,65 @@ func TestSession_Close(t *testing.T) {
 	s.Close()
 	<-s.Closed()
 }
+
+func TestSession_TakeAutoResponse(t *testing.T) {
+	s := NewSession()
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+
+	// no message received yet, so no auto-response is allowed
+	assert.False(t, s.TakeAutoResponse(userA))
+
+	s.ExpectAutoResponse(userA)
+	assert.False(t, s.TakeAutoResponse(userB))
+	assert.True(t, s.TakeAutoResponse(userA))
+
+	// each message allows only one auto-response
+	assert.False(t, s.TakeAutoResponse(userA))
+}
+
+func TestSession_PermitsClassOf(t *testing.T) {
+	me := NewSession()
+	aimUser := NewSession()
+	icqUser := NewSession()
+	icqUser.SetUserInfoFlag(wire.OServiceUserFlagICQ)
+	icqUser.ClearUserInfoFlag(wire.OServiceUserFlagOSCARFree)
+	awayUser := NewSession()
+	awayUser.ClearUserInfoFlag(wire.OServiceUserFlagOSCARFree)
+	awayUser.SetUserInfoFlag(wire.OServiceUserFlagUnavailable)
+
+	// all classes are permitted by default
+	assert.True(t, me.PermitsClassOf(aimUser))
+	assert.True(t, me.PermitsClassOf(icqUser))
+
+	me.SetPermitMask(wire.OServiceUserFlagICQ)
+	assert.Equal(t, wire.OServiceUserFlagICQ, me.PermitMask())
+	assert.False(t, me.PermitsClassOf(aimUser))
+	assert.True(t, me.PermitsClassOf(icqUser))
+
+	// transient flags don't count as a user class
+	me.SetPermitMask(wire.OServiceUserFlagUnavailable)
+	assert.False(t, me.PermitsClassOf(awayUser))
+
+	me.SetPermitMask(0)
+	assert.False(t, me.PermitsClassOf(aimUser))
+	assert.False(t, me.PermitsClassOf(icqUser))
+}
+
+func TestSession_Kick(t *testing.T) {
+	s := NewSession()
+	assert.False(t, s.Kicked())
+	s.Kick()
+	assert.True(t, s.Kicked())
+	select {
+	case <-s.Closed():
+	default:
+		t.Fatal("kicked session should be closed")
+	}
+
+	// a session that was already closed wasn't kicked
+	s = NewSession()
+	s.Close()
+	s.Kick()
+	assert.False(t, s.Kicked())
+}
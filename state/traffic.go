@@ -0,0 +1,42 @@
+package state
+
+import "sync/atomic"
+
+// TrafficCounter tracks the number of bytes received from and sent to
+// clients, as well as the number of keepalive frames received. The zero value
+// is ready to use. It is safe to use with multiple goroutines.
+type TrafficCounter struct {
+	bytesIn    atomic.Uint64
+	bytesOut   atomic.Uint64
+	keepAlives atomic.Uint64
+}
+
+// AddBytesIn adds n to the number of bytes received.
+func (t *TrafficCounter) AddBytesIn(n int) {
+	t.bytesIn.Add(uint64(n))
+}
+
+// AddBytesOut adds n to the number of bytes sent.
+func (t *TrafficCounter) AddBytesOut(n int) {
+	t.bytesOut.Add(uint64(n))
+}
+
+// BytesIn returns the number of bytes received.
+func (t *TrafficCounter) BytesIn() uint64 {
+	return t.bytesIn.Load()
+}
+
+// BytesOut returns the number of bytes sent.
+func (t *TrafficCounter) BytesOut() uint64 {
+	return t.bytesOut.Load()
+}
+
+// AddKeepAlive increments the number of keepalive frames received.
+func (t *TrafficCounter) AddKeepAlive() {
+	t.keepAlives.Add(1)
+}
+
+// KeepAlives returns the number of keepalive frames received.
+func (t *TrafficCounter) KeepAlives() uint64 {
+	return t.keepAlives.Load()
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +22,9 @@ var (
 	ErrNoUser = errors.New("user does not exist")
 	// ErrNoEmail indicates that a user has not set an email address.
 	ErrNoEmailAddress = errors.New("user has no email address")
+	// ErrUINRangeExhausted indicates that there are no UINs left to allocate
+	// in the configured range.
+	ErrUINRangeExhausted = errors.New("no UINs left in allocation range")
 )
 
 // IdentScreenName struct stores the normalized version of a user's screen name.
@@ -57,46 +61,17 @@ func NewIdentScreenName(screenName string) IdentScreenName {
 type DisplayScreenName string
 
 var (
-	ErrAIMHandleInvalidFormat = errors.New("screen name must start with a letter, cannot end with a space, and must contain only letters, numbers, and spaces")
-	ErrAIMHandleLength        = errors.New("screen name must be between 3 and 16 characters")
+	ErrAIMHandleInvalidFormat = errors.New("invalid screen name format")
+	ErrAIMHandleLength        = errors.New("invalid screen name length")
 	ErrPasswordInvalid        = errors.New("invalid password length")
 	ErrICQUINInvalidFormat    = errors.New("uin must be a number in the range 10000-2147483646")
 )
 
-// ValidateAIMHandle returns an error if the instance is not a valid AIM screen name.
-// Possible errors:
-//   - ErrAIMHandleLength: if the screen name has less than 3 non-space
-//     characters or more than 16 characters (including spaces).
-//   - ErrAIMHandleInvalidFormat: if the screen name does not start with a
-//     letter, ends with a space, or contains invalid characters
+// ValidateAIMHandle returns an error if the instance is not a valid AIM
+// screen name under the default ScreenNamePolicy. See ValidateScreenName for
+// possible errors.
 func (s DisplayScreenName) ValidateAIMHandle() error {
-	// Must contain min 3 letters, max 16 letters and spaces.
-	c := 0
-	for _, r := range s {
-		if unicode.IsLetter(r) {
-			c++
-		}
-		if c == 3 {
-			break
-		}
-	}
-	if c < 3 || len(s) > 16 {
-		return ErrAIMHandleLength
-	}
-
-	// Must start with a letter, cannot end with a space, and must contain only
-	// letters, numbers, and spaces.
-	if !unicode.IsLetter(rune(s[0])) || s[len(s)-1] == ' ' {
-		return ErrAIMHandleInvalidFormat
-	}
-
-	for _, ch := range s {
-		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != ' ' {
-			return ErrAIMHandleInvalidFormat
-		}
-	}
-
-	return nil
+	return ValidateScreenName(ScreenNamePolicy{}, s)
 }
 
 // IsUIN indicates whether the screen name is an ICQ UIN.
@@ -161,10 +136,17 @@ type User struct {
 	DisplayScreenName DisplayScreenName
 	// AuthKey is the salt for the MD5 password hash.
 	AuthKey string
+	// PasswordHash is the argon2id hash of the password in PHC string format.
+	// It's used to authenticate clients that send the password itself, such
+	// as AIM v1.0-v3.0 with roasted passwords. It's empty for accounts whose
+	// password was set before argon2id hashes were stored, until the user
+	// signs on with such a client or changes their password.
+	PasswordHash string
 	// StrongMD5Pass is the MD5 password hash format used by AIM v4.8-v5.9.
 	StrongMD5Pass []byte
-	// WeakMD5Pass is the MD5 password hash format used by AIM v3.5-v4.7. This
-	// hash is used to authenticate roasted passwords for AIM v1.0-v3.0.
+	// WeakMD5Pass is the MD5 password hash format used by AIM v3.5-v4.7. It's
+	// also used to authenticate roasted passwords for accounts that don't
+	// have a PasswordHash yet. It's nil if weak MD5 hashes are disabled.
 	WeakMD5Pass []byte
 	// IsICQ indicates whether the user is an ICQ account (true) or an AIM
 	// account (false).
@@ -199,6 +181,77 @@ type User struct {
 	ICQWorkInfo ICQWorkInfo
 
 	AIMDirectoryInfo AIMNameAndAddr
+
+	// IsWatched indicates whether operators are notified when the user signs
+	// on.
+	IsWatched bool
+	// CanCreateChatRooms indicates whether the user may create chat rooms
+	// when chat room creation is restricted to flagged accounts.
+	CanCreateChatRooms bool
+	// IsOfficial indicates whether the user is a staff or bot account whose
+	// user info carries the official badge.
+	IsOfficial bool
+	// StorageQuota is the user's storage quota in bytes, which overrides the
+	// server default. 0 means unlimited storage and nil means the user has the
+	// default quota.
+	StorageQuota *int64
+	// AllowedNetworks restricts the networks that the user may log in from.
+	// The user may log in from anywhere if the list is empty.
+	AllowedNetworks []netip.Prefix
+	// CreatedAt is when the account was created. It's zero for accounts
+	// created before creation times were recorded.
+	CreatedAt time.Time
+	// PasswordResetPending indicates whether a password reset has been
+	// requested for the account and not yet completed.
+	PasswordResetPending bool
+	// ConcurrentLoginPolicy is the user's concurrent login policy, which
+	// overrides the server default. Empty means the user has the default
+	// policy.
+	ConcurrentLoginPolicy string
+	// IsBanned indicates whether operators have barred the user from signing
+	// on.
+	IsBanned bool
+	// BanReason is the operator's reason for banning the user.
+	BanReason string
+	// BannedAt is when the user was banned. It's zero if the user isn't
+	// banned.
+	BannedAt time.Time
+}
+
+// StorageUsage reports how much data is stored on behalf of a user.
+type StorageUsage struct {
+	// OfflineMessageBytes is the size of the offline messages waiting for
+	// the user.
+	OfflineMessageBytes int64
+	// ProfileBytes is the size of the user's profile.
+	ProfileBytes int64
+	// FeedbagBytes is the size of the user's server-side buddy list.
+	FeedbagBytes int64
+	// QuotaBytes is the user's storage quota. 0 means unlimited storage.
+	QuotaBytes int64
+}
+
+// UsedBytes returns the total size of the data stored for the user.
+func (s StorageUsage) UsedBytes() int64 {
+	return s.OfflineMessageBytes + s.ProfileBytes + s.FeedbagBytes
+}
+
+// AwayTemplate is a named away message defined by the operator that can be
+// applied to a user's session.
+type AwayTemplate struct {
+	// Name uniquely identifies the template.
+	Name string
+	// Message is the away message HTML.
+	Message string
+}
+
+// SharedGroupMember is a user that the operator has placed in a shared group.
+// Shared groups appear in every user's feedbag and can't be changed by users.
+type SharedGroupMember struct {
+	// Group is the name of the shared group.
+	Group string
+	// ScreenName is the screen name of the member.
+	ScreenName DisplayScreenName
 }
 
 // AIMNameAndAddr holds name and address AIM directory information.
@@ -369,6 +422,9 @@ type ICQPermissions struct {
 	// AuthRequired indicates where users must ask this permission to add them
 	// to their contact list.
 	AuthRequired bool
+	// WebAware indicates whether the user's online status may be shown
+	// outside of ICQ, such as on the web.
+	WebAware bool
 }
 
 // Age returns the user's age relative to their birthday and timeNow.
@@ -392,20 +448,108 @@ func (u *User) Age(timeNow func() time.Time) uint16 {
 
 // ValidateHash checks if md5Hash is identical to one of the password hashes.
 func (u *User) ValidateHash(md5Hash []byte) bool {
-	return bytes.Equal(u.StrongMD5Pass, md5Hash) || bytes.Equal(u.WeakMD5Pass, md5Hash)
+	return u.ValidateStrongHash(md5Hash) ||
+		(len(md5Hash) > 0 && bytes.Equal(u.WeakMD5Pass, md5Hash))
+}
+
+// ValidateStrongHash checks if md5Hash is identical to the strong MD5
+// password hash.
+func (u *User) ValidateStrongHash(md5Hash []byte) bool {
+	return len(md5Hash) > 0 && bytes.Equal(u.StrongMD5Pass, md5Hash)
+}
+
+// ValidatePassword checks if passwd is the user's password. The password is
+// checked against PasswordHash, or against the MD5 password hashes if the
+// user doesn't have a PasswordHash yet.
+func (u *User) ValidatePassword(passwd string) bool {
+	if u.PasswordHash != "" {
+		return argon2Verify(u.PasswordHash, passwd)
+	}
+	return u.ValidateHash(wire.StrongMD5PasswordHash(passwd, u.AuthKey)) ||
+		u.ValidateHash(wire.WeakMD5PasswordHash(passwd, u.AuthKey))
+}
+
+// LoginAllowedFrom indicates whether the user may log in from addr according
+// to the user's allowed networks.
+func (u *User) LoginAllowedFrom(addr netip.Addr) bool {
+	if len(u.AllowedNetworks) == 0 {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, network := range u.AllowedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConcurrentLogin returns the user's concurrent login policy, or
+// defaultPolicy if the user doesn't have one.
+func (u *User) ConcurrentLogin(defaultPolicy string) string {
+	if u.ConcurrentLoginPolicy == "" {
+		return defaultPolicy
+	}
+	return u.ConcurrentLoginPolicy
+}
+
+// ParseAllowedNetworks parses a comma-separated list of CIDRs, such as
+// "10.0.0.0/8, 192.168.1.0/24". Bare IP addresses are treated as single-host
+// networks.
+func ParseAllowedNetworks(s string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		network, err := parseNetwork(field)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// parseNetwork parses a CIDR or a bare IP address.
+func parseNetwork(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid network %q: %w", s, err)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	network, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid network %q: %w", s, err)
+	}
+	return network.Masked(), nil
 }
 
 // ValidateRoastedPass checks if the provided roasted password matches the MD5
 // hash of the user's actual password. A roasted password is a XOR-obfuscated
 // form of the real password, intended to add a simple layer of security.
 func (u *User) ValidateRoastedPass(roastedPass []byte) bool {
-	clearPass := wire.RoastPassword(roastedPass)
-	md5Hash := wire.WeakMD5PasswordHash(string(clearPass), u.AuthKey) // todo remove string conversion
-	return bytes.Equal(u.WeakMD5Pass, md5Hash)
+	return u.ValidatePassword(string(wire.RoastPassword(roastedPass)))
 }
 
-// HashPassword computes MD5 hashes of the user's password. It computes both
-// weak and strong variants and stores them in the struct.
+// SetPasswordHash computes the argon2id hash of the user's password and
+// stores it in the struct. Unlike HashPassword, it doesn't validate the
+// password, which lets accounts with legacy passwords get a PasswordHash.
+func (u *User) SetPasswordHash(passwd string) error {
+	hash, err := argon2Hash(passwd)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+// HashPassword computes the argon2id hash and the weak and strong MD5 hashes
+// of the user's password and stores them in the struct.
 func (u *User) HashPassword(passwd string) error {
 	if u.IsICQ {
 		if err := validateICQPassword(passwd); err != nil {
@@ -416,6 +560,9 @@ func (u *User) HashPassword(passwd string) error {
 			return err
 		}
 	}
+	if err := u.SetPasswordHash(passwd); err != nil {
+		return err
+	}
 	u.WeakMD5Pass = wire.WeakMD5PasswordHash(passwd, u.AuthKey)
 	u.StrongMD5Pass = wire.StrongMD5PasswordHash(passwd, u.AuthKey)
 	return nil
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// unsafeUserInfoElements are the HTML elements that are removed from profiles
+// and away messages. Clients that render user info with an embedded browser
+// would otherwise run scripts or load content set by another user.
+var unsafeUserInfoElements = map[string]bool{
+	"applet":   true,
+	"base":     true,
+	"embed":    true,
+	"form":     true,
+	"frame":    true,
+	"frameset": true,
+	"iframe":   true,
+	"link":     true,
+	"meta":     true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+}
+
+// SanitizeUserInfo removes unsafe markup from the HTML of a profile or away
+// message. Unsafe elements are dropped, along with the content of script and
+// style elements, as are event handler attributes and links to script URLs.
+// Everything else, including the AIM-specific tags that clients use for
+// formatting, is kept verbatim.
+func SanitizeUserInfo(body string) string {
+	var sb strings.Builder
+	tok := html.NewTokenizer(strings.NewReader(body))
+	var skipText string
+	for {
+		tt := tok.Next()
+		if tt == html.ErrorToken {
+			return sb.String()
+		}
+
+		// copy the token text, which parsing the token may modify
+		raw := slices.Clone(tok.Raw())
+		switch tt {
+		case html.TextToken:
+			if skipText == "" {
+				sb.Write(raw)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := tok.Token()
+			if unsafeUserInfoElements[t.Data] {
+				if tt == html.StartTagToken && (t.Data == "script" || t.Data == "style") {
+					skipText = t.Data
+				}
+				continue
+			}
+			if skipText != "" {
+				continue
+			}
+			attrs := t.Attr[:0]
+			for _, attr := range t.Attr {
+				if isUnsafeAttr(attr) {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			if len(attrs) == len(t.Attr) {
+				sb.Write(raw)
+			} else {
+				t.Attr = attrs
+				sb.WriteString(t.String())
+			}
+		case html.EndTagToken:
+			name, _ := tok.TagName()
+			if skipText == string(name) {
+				skipText = ""
+				continue
+			}
+			if skipText == "" && !unsafeUserInfoElements[string(name)] {
+				sb.Write(raw)
+			}
+		default:
+			// comments and doctypes
+			if skipText == "" && !bytes.HasPrefix(raw, []byte("<!--")) {
+				sb.Write(raw)
+			}
+		}
+	}
+}
+
+// isUnsafeAttr reports whether attr is an event handler or a link to a
+// script URL.
+func isUnsafeAttr(attr html.Attribute) bool {
+	if strings.HasPrefix(attr.Key, "on") {
+		return true
+	}
+	switch attr.Key {
+	case "href", "src", "action", "background", "dynsrc", "lowsrc":
+		scheme := strings.ToLower(strings.TrimSpace(attr.Val))
+		scheme = strings.Map(func(r rune) rune {
+			// browsers ignore whitespace and control characters in the scheme
+			if r <= ' ' {
+				return -1
+			}
+			return r
+		}, scheme)
+		return strings.HasPrefix(scheme, "javascript:") || strings.HasPrefix(scheme, "vbscript:")
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeUserInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "AIM formatting is kept verbatim",
+			body: `<HTML><BODY BGCOLOR="#ffffff"><FONT FACE="Arial" LANG="0">hello&nbsp;<A HREF="http://example.com">there</A></FONT></BODY></HTML>`,
+			want: `<HTML><BODY BGCOLOR="#ffffff"><FONT FACE="Arial" LANG="0">hello&nbsp;<A HREF="http://example.com">there</A></FONT></BODY></HTML>`,
+		},
+		{
+			name: "plain text is kept verbatim",
+			body: "gone fishing :-)",
+			want: "gone fishing :-)",
+		},
+		{
+			name: "script is removed with its content",
+			body: `hi<SCRIPT>alert("x")</SCRIPT> there`,
+			want: `hi there`,
+		},
+		{
+			name: "embedded content is removed",
+			body: `<iframe src="http://example.com"></iframe><object data="x"></object>hi`,
+			want: `hi`,
+		},
+		{
+			name: "event handlers are removed",
+			body: `<B onmouseover="alert(1)">bold</B>`,
+			want: `<b>bold</B>`,
+		},
+		{
+			name: "script links are removed",
+			body: `<A HREF=" JavaScript:alert(1)">click</A>`,
+			want: `<a>click</A>`,
+		},
+		{
+			name: "comments are removed",
+			body: `a<!--[if IE]><script>alert(1)</script><![endif]-->b`,
+			want: `ab`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeUserInfo(tt.body))
+		})
+	}
+}
@@ -2,16 +2,24 @@ package state
 
 import (
 	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
 	"database/sql"
 	"embed"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"math"
 	"net/http"
 	"net/mail"
+	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -24,13 +32,22 @@ import (
 )
 
 var (
-	ErrKeywordCategoryExists   = errors.New("keyword category already exists")
-	ErrKeywordCategoryNotFound = errors.New("keyword category not found")
-	ErrKeywordExists           = errors.New("keyword already exists")
-	ErrKeywordInUse            = errors.New("can't delete keyword that is associated with a user")
-	ErrKeywordNotFound         = errors.New("keyword not found")
-	errTooManyCategories       = errors.New("there are too many keyword categories")
-	errTooManyKeywords         = errors.New("there are too many keywords")
+	ErrAwayTemplateNotFound       = errors.New("away template not found")
+	ErrConfirmTokenNotFound       = errors.New("confirmation token not found")
+	ErrFeedbagFull                = errors.New("feedbag has no free group or item IDs")
+	ErrKeywordCategoryExists      = errors.New("keyword category already exists")
+	ErrKeywordCategoryNotFound    = errors.New("keyword category not found")
+	ErrKeywordExists              = errors.New("keyword already exists")
+	ErrKeywordInUse               = errors.New("can't delete keyword that is associated with a user")
+	ErrKeywordNotFound            = errors.New("keyword not found")
+	ErrOfflineMessageLimit        = errors.New("offline message limit reached")
+	ErrPasswordResetTokenNotFound = errors.New("password reset token not found")
+	ErrScreenNameAliasNotFound    = errors.New("screen name alias not found")
+	ErrSharedGroupMemberNotFound  = errors.New("shared group member not found")
+	ErrSharedGroupReadOnly        = errors.New("shared groups can't be changed by users")
+	ErrStorageQuotaExceeded       = errors.New("storage quota exceeded")
+	errTooManyCategories          = errors.New("there are too many keyword categories")
+	errTooManyKeywords            = errors.New("there are too many keywords")
 )
 
 //go:embed migrations/*
@@ -39,7 +56,22 @@ var migrations embed.FS
 // SQLiteUserStore stores user feedbag (buddy list), profile, and
 // authentication credentials information in a SQLite database.
 type SQLiteUserStore struct {
-	db *sql.DB
+	contentCipher       *ContentCipher
+	db                  *sql.DB
+	defaultBuddyIcon    *wire.BARTID
+	defaultStorageQuota int64
+	noMD5Pass           bool
+	offlineMessageLimit int
+	reservedNames       *reservedScreenNames
+	searchCache         *SearchCache
+}
+
+// reservedScreenNames holds the screen names of virtual users, which can't be
+// registered while the virtual user is signed on. The mutex also serializes
+// account creation with reservations.
+type reservedScreenNames struct {
+	mutex sync.Mutex
+	names map[IdentScreenName]struct{}
 }
 
 // NewSQLiteUserStore creates a new instance of SQLiteUserStore. If the
@@ -58,7 +90,12 @@ func NewSQLiteUserStore(dbFilePath string) (*SQLiteUserStore, error) {
 	// any potential locking issues.
 	db.SetMaxOpenConns(1)
 
-	store := &SQLiteUserStore{db: db}
+	store := &SQLiteUserStore{
+		db: db,
+		reservedNames: &reservedScreenNames{
+			names: make(map[IdentScreenName]struct{}),
+		},
+	}
 
 	if err := store.runMigrations(); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
@@ -67,6 +104,91 @@ func NewSQLiteUserStore(dbFilePath string) (*SQLiteUserStore, error) {
 	return store, nil
 }
 
+// SetContentCipher sets the cipher used to encrypt profiles and offline
+// messages at rest. Content is stored in plaintext if no cipher is set.
+func (f *SQLiteUserStore) SetContentCipher(contentCipher *ContentCipher) {
+	f.contentCipher = contentCipher
+}
+
+// SetSearchCache sets the cache that serves repeated user directory searches.
+// Every search queries the database if no cache is set.
+func (f *SQLiteUserStore) SetSearchCache(searchCache *SearchCache) {
+	f.searchCache = searchCache
+}
+
+// SetDefaultStorageQuota sets the storage quota in bytes of users who don't
+// have their own quota. A quota of 0 means unlimited storage.
+func (f *SQLiteUserStore) SetDefaultStorageQuota(quota int64) {
+	f.defaultStorageQuota = quota
+}
+
+// SetOfflineMessageLimit sets the maximum number of offline messages stored
+// for each recipient. A limit of 0 means no limit.
+func (f *SQLiteUserStore) SetOfflineMessageLimit(limit int) {
+	f.offlineMessageLimit = limit
+}
+
+// DisableWeakMD5Pass stops the store from keeping MD5 password hashes and
+// deletes the existing ones of accounts that have an argon2id password hash.
+// Both the weak and the strong MD5 hash are deleted, since either one is
+// enough to sign on with. Accounts without an argon2id hash keep their MD5
+// hashes, which are the only way to verify their passwords, until they get
+// one.
+func (f *SQLiteUserStore) DisableWeakMD5Pass() error {
+	f.noMD5Pass = true
+	q := `
+		UPDATE users
+		SET weakMD5Pass   = NULL,
+		    strongMD5Pass = NULL
+		WHERE passwordHash != ''
+	`
+	_, err := f.db.Exec(q)
+	return err
+}
+
+// weakMD5Pass returns the weak MD5 password hash of u to store, which is nil
+// if MD5 hashes are disabled and u has an argon2id password hash.
+func (f SQLiteUserStore) weakMD5Pass(u User) []byte {
+	if f.noMD5Pass && u.PasswordHash != "" {
+		return nil
+	}
+	return u.WeakMD5Pass
+}
+
+// strongMD5Pass returns the strong MD5 password hash of u to store, which is
+// nil if MD5 hashes are disabled and u has an argon2id password hash.
+func (f SQLiteUserStore) strongMD5Pass(u User) []byte {
+	if f.noMD5Pass && u.PasswordHash != "" {
+		return nil
+	}
+	return u.StrongMD5Pass
+}
+
+// cachedSearch returns the cached results of the search identified by key, or
+// runs search and caches its results if they aren't cached.
+func (f SQLiteUserStore) cachedSearch(key string, search func() ([]User, int, error)) ([]User, int, error) {
+	if f.searchCache == nil {
+		return search()
+	}
+	if users, total, ok := f.searchCache.Get(key); ok {
+		return users, total, nil
+	}
+	users, total, err := search()
+	if err != nil {
+		return nil, 0, err
+	}
+	f.searchCache.Put(key, users, total)
+	return users, total, nil
+}
+
+// invalidateSearches discards cached search results after a change to user
+// directory info.
+func (f SQLiteUserStore) invalidateSearches() {
+	if f.searchCache != nil {
+		f.searchCache.Clear()
+	}
+}
+
 func (f SQLiteUserStore) runMigrations() error {
 	migrationFS, err := fs.Sub(migrations, "migrations")
 	if err != nil {
@@ -172,6 +294,14 @@ func (f SQLiteUserStore) FindByAIMEmail(email string) (User, error) {
 
 // FindByAIMKeyword returns users who have a matching keyword.
 func (f SQLiteUserStore) FindByAIMKeyword(keyword string) ([]User, error) {
+	users, _, err := f.cachedSearch("aim_keyword\x00"+keyword, func() ([]User, int, error) {
+		users, err := f.findByAIMKeyword(keyword)
+		return users, len(users), err
+	})
+	return users, err
+}
+
+func (f SQLiteUserStore) findByAIMKeyword(keyword string) ([]User, error) {
 	where := `
 		(SELECT id FROM aimKeyword WHERE name = ?) IN
 		(aim_keyword1, aim_keyword2, aim_keyword3, aim_keyword4, aim_keyword5)
@@ -184,9 +314,18 @@ func (f SQLiteUserStore) FindByAIMKeyword(keyword string) ([]User, error) {
 	return users, nil
 }
 
-// FindByICQName returns users with matching first name, last name, and
-// nickname. Empty values are not included in the search parameters.
-func (f SQLiteUserStore) FindByICQName(firstName, lastName, nickName string) ([]User, error) {
+// FindByICQName returns up to limit users with matching first name, last
+// name, and nickname, ordered by screen name, along with the total number of
+// matching users. Empty values are not included in the search parameters. No
+// users are returned if all values are empty.
+func (f SQLiteUserStore) FindByICQName(firstName, lastName, nickName string, limit int) ([]User, int, error) {
+	key := strings.ToLower(fmt.Sprintf("icq_name\x00%d\x00%s\x00%s\x00%s", limit, firstName, lastName, nickName))
+	return f.cachedSearch(key, func() ([]User, int, error) {
+		return f.findByICQName(firstName, lastName, nickName, limit)
+	})
+}
+
+func (f SQLiteUserStore) findByICQName(firstName, lastName, nickName string, limit int) ([]User, int, error) {
 	var args []any
 	var clauses []string
 
@@ -205,19 +344,32 @@ func (f SQLiteUserStore) FindByICQName(firstName, lastName, nickName string) ([]
 		clauses = append(clauses, `LOWER(icq_basicInfo_nickName) = LOWER(?)`)
 	}
 
+	if len(clauses) == 0 {
+		return nil, 0, nil
+	}
+
 	whereClause := strings.Join(clauses, " AND ")
 
-	users, err := f.queryUsers(whereClause, args)
+	users, total, err := f.queryUserPage(whereClause, args, limit)
 	if err != nil {
-		err = fmt.Errorf("FindByICQName: %w", err)
+		return nil, 0, fmt.Errorf("FindByICQName: %w", err)
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
 // FindByAIMNameAndAddr returns users with all matching non-empty directory info
 // fields. Empty values are not included in the search parameters.
 func (f SQLiteUserStore) FindByAIMNameAndAddr(info AIMNameAndAddr) ([]User, error) {
+	key := strings.ToLower(fmt.Sprintf("aim_name_and_addr\x00%q", info))
+	users, _, err := f.cachedSearch(key, func() ([]User, int, error) {
+		users, err := f.findByAIMNameAndAddr(info)
+		return users, len(users), err
+	})
+	return users, err
+}
+
+func (f SQLiteUserStore) findByAIMNameAndAddr(info AIMNameAndAddr) ([]User, error) {
 	var args []any
 	var clauses []string
 
@@ -281,8 +433,17 @@ func (f SQLiteUserStore) FindByAIMNameAndAddr(info AIMNameAndAddr) ([]User, erro
 	return users, nil
 }
 
-// FindByICQInterests returns users who have at least one matching interest.
-func (f SQLiteUserStore) FindByICQInterests(code uint16, keywords []string) ([]User, error) {
+// FindByICQInterests returns up to limit users who have at least one
+// matching interest, ordered by screen name, along with the total number of
+// matching users.
+func (f SQLiteUserStore) FindByICQInterests(code uint16, keywords []string, limit int) ([]User, int, error) {
+	key := strings.ToLower(fmt.Sprintf("icq_interests\x00%d\x00%d\x00%s", limit, code, strings.Join(keywords, "\x00")))
+	return f.cachedSearch(key, func() ([]User, int, error) {
+		return f.findByICQInterests(code, keywords, limit)
+	})
+}
+
+func (f SQLiteUserStore) findByICQInterests(code uint16, keywords []string, limit int) ([]User, int, error) {
 	var args []any
 	var clauses []string
 
@@ -298,17 +459,25 @@ func (f SQLiteUserStore) FindByICQInterests(code uint16, keywords []string) ([]U
 
 	cond := strings.Join(clauses, " OR ")
 
-	users, err := f.queryUsers(cond, args)
+	users, total, err := f.queryUserPage(cond, args, limit)
 	if err != nil {
-		err = fmt.Errorf("FindByICQInterests: %w", err)
+		return nil, 0, fmt.Errorf("FindByICQInterests: %w", err)
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
-// FindByICQKeyword returns users with matching interest keyword across all
-// interest categories.
-func (f SQLiteUserStore) FindByICQKeyword(keyword string) ([]User, error) {
+// FindByICQKeyword returns up to limit users with matching interest keyword
+// across all interest categories, ordered by screen name, along with the total
+// number of matching users.
+func (f SQLiteUserStore) FindByICQKeyword(keyword string, limit int) ([]User, int, error) {
+	key := strings.ToLower(fmt.Sprintf("icq_keyword\x00%d\x00%s", limit, keyword))
+	return f.cachedSearch(key, func() ([]User, int, error) {
+		return f.findByICQKeyword(keyword, limit)
+	})
+}
+
+func (f SQLiteUserStore) findByICQKeyword(keyword string, limit int) ([]User, int, error) {
 	var args []any
 	var clauses []string
 
@@ -319,18 +488,20 @@ func (f SQLiteUserStore) FindByICQKeyword(keyword string) ([]User, error) {
 
 	whereClause := strings.Join(clauses, " OR ")
 
-	users, err := f.queryUsers(whereClause, args)
+	users, total, err := f.queryUserPage(whereClause, args, limit)
 	if err != nil {
-		err = fmt.Errorf("FindByICQKeyword: %w", err)
+		return nil, 0, fmt.Errorf("FindByICQKeyword: %w", err)
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
 // User looks up a user by screen name. It populates the User record with
-// credentials that can be used to validate the user's password.
+// credentials that can be used to validate the user's password. If screenName
+// is an alias, the canonical account it belongs to is returned.
 func (f SQLiteUserStore) User(screenName IdentScreenName) (*User, error) {
-	users, err := f.queryUsers(`identScreenName = ?`, []any{screenName.String()})
+	where := `identScreenName = COALESCE((SELECT screenName FROM screenNameAlias WHERE alias = ?), ?)`
+	users, err := f.queryUsers(where, []any{screenName.String(), screenName.String()})
 	if err != nil {
 		return nil, fmt.Errorf("User: %w", err)
 	}
@@ -342,6 +513,27 @@ func (f SQLiteUserStore) User(screenName IdentScreenName) (*User, error) {
 	return &users[0], nil
 }
 
+// queryUserPage retrieves up to limit users that match the specified WHERE
+// clause, ordered by screen name. It also returns the total number of
+// matching users so that callers can tell how many users were left out.
+func (f SQLiteUserStore) queryUserPage(whereClause string, queryParams []any, limit int) ([]User, int, error) {
+	var total int
+	q := fmt.Sprintf(`SELECT COUNT(*) FROM users WHERE %s`, whereClause)
+	if err := f.db.QueryRow(q, queryParams...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	pageClause := fmt.Sprintf(`(%s) ORDER BY identScreenName LIMIT %d`, whereClause, limit)
+	users, err := f.queryUsers(pageClause, queryParams)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
 // queryUsers retrieves a list of users from the database based on the
 // specified WHERE clause and query parameters. Returns a slice of User objects
 // or an error if the query fails.
@@ -352,6 +544,7 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			displayScreenName,
 			emailAddress,
 			authKey,
+			passwordHash,
 			strongMD5Pass,
 			weakMD5Pass,
 			confirmStatus,
@@ -401,6 +594,7 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			icq_moreInfo_lang3,
 			icq_notes,
 			icq_permissions_authRequired,
+			icq_permissions_webAware,
 			icq_workInfo_address,
 			icq_workInfo_city,
 			icq_workInfo_company,
@@ -422,7 +616,18 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			aim_city,
 			aim_nickName,
 			aim_zipCode,
-			aim_address
+			aim_address,
+			isWatched,
+			canCreateChatRooms,
+			isOfficial,
+			storageQuota,
+			allowedNetworks,
+			createdAt,
+			passwordResetToken != '',
+			concurrentLoginPolicy,
+			isBanned,
+			banReason,
+			bannedAt
 		FROM users
 		WHERE %s
 	`
@@ -437,11 +642,15 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 	for rows.Next() {
 		var u User
 		var sn string
+		var allowedNetworks string
+		var createdAt int64
+		var bannedAt int64
 		err := rows.Scan(
 			&sn,
 			&u.DisplayScreenName,
 			&u.EmailAddress,
 			&u.AuthKey,
+			&u.PasswordHash,
 			&u.StrongMD5Pass,
 			&u.WeakMD5Pass,
 			&u.ConfirmStatus,
@@ -491,6 +700,7 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			&u.ICQMoreInfo.Lang3,
 			&u.ICQNotes.Notes,
 			&u.ICQPermissions.AuthRequired,
+			&u.ICQPermissions.WebAware,
 			&u.ICQWorkInfo.Address,
 			&u.ICQWorkInfo.City,
 			&u.ICQWorkInfo.Company,
@@ -513,11 +723,31 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 			&u.AIMDirectoryInfo.NickName,
 			&u.AIMDirectoryInfo.ZIPCode,
 			&u.AIMDirectoryInfo.Address,
+			&u.IsWatched,
+			&u.CanCreateChatRooms,
+			&u.IsOfficial,
+			&u.StorageQuota,
+			&allowedNetworks,
+			&createdAt,
+			&u.PasswordResetPending,
+			&u.ConcurrentLoginPolicy,
+			&u.IsBanned,
+			&u.BanReason,
+			&bannedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 		u.IdentScreenName = NewIdentScreenName(sn)
+		if u.AllowedNetworks, err = ParseAllowedNetworks(allowedNetworks); err != nil {
+			return nil, fmt.Errorf("parse allowed networks for %s: %w", sn, err)
+		}
+		if createdAt > 0 {
+			u.CreatedAt = time.Unix(createdAt, 0).UTC()
+		}
+		if bannedAt > 0 {
+			u.BannedAt = time.Unix(bannedAt, 0).UTC()
+		}
 		users = append(users, u)
 	}
 	if err = rows.Err(); err != nil {
@@ -527,24 +757,43 @@ func (f SQLiteUserStore) queryUsers(whereClause string, queryParams []any) ([]Us
 	return users, nil
 }
 
-// InsertUser inserts a user to the store. Return ErrDupUser if a user with the
-// same screen name already exists.
+// InsertUser inserts a user to the store. Return ErrDupUser if a user, alias,
+// or virtual user with the same screen name already exists. The account
+// creation time is recorded as u.CreatedAt, or the current time if unset. The
+// user's ICQ privacy settings are stored along with the account.
 func (f SQLiteUserStore) InsertUser(u User) error {
 	if u.DisplayScreenName.IsUIN() && !u.IsICQ {
 		return errors.New("inserting user with UIN and isICQ=false")
 	}
+	f.reservedNames.mutex.Lock()
+	defer f.reservedNames.mutex.Unlock()
+	if _, ok := f.reservedNames.names[u.IdentScreenName]; ok {
+		return ErrDupUser
+	}
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	// the screen name can't be taken by another account's alias
 	q := `
-		INSERT INTO users (identScreenName, displayScreenName, authKey, weakMD5Pass, strongMD5Pass, isICQ)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO users (identScreenName, displayScreenName, authKey, passwordHash, weakMD5Pass, strongMD5Pass, isICQ, createdAt,
+		                   icq_permissions_authRequired, icq_permissions_webAware)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM screenNameAlias WHERE alias = ?)
 		ON CONFLICT (identScreenName) DO NOTHING
 	`
 	result, err := f.db.Exec(q,
 		u.IdentScreenName.String(),
 		u.DisplayScreenName,
 		u.AuthKey,
-		u.WeakMD5Pass,
-		u.StrongMD5Pass,
+		u.PasswordHash,
+		f.weakMD5Pass(u),
+		f.strongMD5Pass(u),
 		u.IsICQ,
+		createdAt.Unix(),
+		u.ICQPermissions.AuthRequired,
+		u.ICQPermissions.WebAware,
+		u.IdentScreenName.String(),
 	)
 	if err != nil {
 		return err
@@ -558,6 +807,258 @@ func (f SQLiteUserStore) InsertUser(u User) error {
 		return ErrDupUser
 	}
 
+	f.invalidateSearches()
+	return nil
+}
+
+// ReserveScreenName reserves screenName for a virtual user so that it can't
+// be registered until ReleaseScreenName is called. It returns
+// ErrVirtualUserConflict if screenName belongs to a registered account or
+// alias, or is already reserved.
+func (f SQLiteUserStore) ReserveScreenName(screenName IdentScreenName) error {
+	f.reservedNames.mutex.Lock()
+	defer f.reservedNames.mutex.Unlock()
+
+	if _, ok := f.reservedNames.names[screenName]; ok {
+		return ErrVirtualUserConflict
+	}
+
+	q := `
+		SELECT EXISTS (SELECT 1 FROM users WHERE identScreenName = ?)
+		    OR EXISTS (SELECT 1 FROM screenNameAlias WHERE alias = ?)
+	`
+	var taken bool
+	if err := f.db.QueryRow(q, screenName.String(), screenName.String()).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrVirtualUserConflict
+	}
+
+	f.reservedNames.names[screenName] = struct{}{}
+	return nil
+}
+
+// ReleaseScreenName releases a screen name reserved by ReserveScreenName.
+func (f SQLiteUserStore) ReleaseScreenName(screenName IdentScreenName) {
+	f.reservedNames.mutex.Lock()
+	defer f.reservedNames.mutex.Unlock()
+	delete(f.reservedNames.names, screenName)
+}
+
+// NextUIN allocates the next unused ICQ UIN in the range [first, last]. The
+// next-UIN counter is persisted, so UINs are never handed out twice, even
+// across restarts. UINs that already belong to a user are skipped. It returns
+// ErrUINRangeExhausted if no UINs are left in the range.
+func (f SQLiteUserStore) NextUIN(first, last uint32) (uint32, error) {
+	tx, err := f.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	next := int64(first)
+	q := `SELECT nextUIN FROM uinCounter WHERE id = 1`
+	var stored int64
+	err = tx.QueryRow(q).Scan(&stored)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return 0, err
+	case stored > next:
+		next = stored
+	}
+
+	for ; next <= int64(last); next++ {
+		var exists bool
+		q = `SELECT EXISTS(SELECT 1 FROM users WHERE identScreenName = ?)`
+		if err := tx.QueryRow(q, strconv.FormatInt(next, 10)).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if !exists {
+			break
+		}
+	}
+	if next > int64(last) {
+		return 0, ErrUINRangeExhausted
+	}
+
+	q = `
+		INSERT INTO uinCounter (id, nextUIN)
+		VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET nextUIN = excluded.nextUIN
+	`
+	if _, err := tx.Exec(q, next+1); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return uint32(next), nil
+}
+
+// SetWatched sets whether operators are notified when the user signs on.
+// Return ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) SetWatched(screenName IdentScreenName, watched bool) error {
+	q := `
+		UPDATE users SET isWatched = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, watched, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// SetAllowedNetworks sets the networks that the user may log in from. An
+// empty list lets the user log in from anywhere. Return ErrNoUser if the user
+// does not exist.
+func (f SQLiteUserStore) SetAllowedNetworks(screenName IdentScreenName, networks []netip.Prefix) error {
+	strs := make([]string, 0, len(networks))
+	for _, network := range networks {
+		strs = append(strs, network.String())
+	}
+	q := `
+		UPDATE users SET allowedNetworks = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, strings.Join(strs, ","), screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// SetCanCreateChatRooms sets whether the user may create chat rooms when chat
+// room creation is restricted to flagged accounts. Return ErrNoUser if the
+// user does not exist.
+func (f SQLiteUserStore) SetCanCreateChatRooms(screenName IdentScreenName, allowed bool) error {
+	q := `
+		UPDATE users SET canCreateChatRooms = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, allowed, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// SetOfficial sets whether the user is a staff or bot account whose user info
+// carries the official badge. Return ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) SetOfficial(screenName IdentScreenName, official bool) error {
+	q := `
+		UPDATE users SET isOfficial = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, official, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// SetConcurrentLoginPolicy sets the user's concurrent login policy, which
+// overrides the server default. An empty policy restores the default. Return
+// ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) SetConcurrentLoginPolicy(screenName IdentScreenName, policy string) error {
+	q := `
+		UPDATE users SET concurrentLoginPolicy = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, policy, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// BanUser bars the user from signing on and records why and when. Return
+// ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) BanUser(screenName IdentScreenName, reason string) error {
+	q := `
+		UPDATE users SET isBanned = true, banReason = ?, bannedAt = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, reason, time.Now().Unix(), screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// UnbanUser lets a banned user sign on again and clears the ban record.
+// Return ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) UnbanUser(screenName IdentScreenName) error {
+	q := `
+		UPDATE users SET isBanned = false, banReason = '', bannedAt = 0 WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
 	return nil
 }
 
@@ -571,6 +1072,7 @@ func (f SQLiteUserStore) DeleteUser(screenName IdentScreenName) error {
 	if err != nil {
 		return err
 	}
+	f.invalidateSearches()
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -583,8 +1085,8 @@ func (f SQLiteUserStore) DeleteUser(screenName IdentScreenName) error {
 	return nil
 }
 
-// SetUserPassword sets the user's password hashes and auth key. The following
-// fields must be set on u:
+// SetUserPassword sets the user's password hashes and auth key and cancels
+// any pending password reset. The following fields must be set on u:
 // - AuthKey
 // - WeakMD5Pass
 // - StrongMD5Pass
@@ -626,10 +1128,10 @@ func (f SQLiteUserStore) SetUserPassword(screenName IdentScreenName, newPassword
 
 	q = `
 		UPDATE users
-		SET authKey = ?, weakMD5Pass = ?, strongMD5Pass = ?
+		SET authKey = ?, passwordHash = ?, weakMD5Pass = ?, strongMD5Pass = ?, passwordResetToken = ''
 		WHERE identScreenName = ?
 	`
-	result, err := tx.Exec(q, u.AuthKey, u.WeakMD5Pass, u.StrongMD5Pass, screenName.String())
+	result, err := tx.Exec(q, u.AuthKey, u.PasswordHash, f.weakMD5Pass(u), f.strongMD5Pass(u), screenName.String())
 	if err != nil {
 		return err
 	}
@@ -656,6 +1158,34 @@ func (f SQLiteUserStore) SetUserPassword(screenName IdentScreenName, newPassword
 	return tx.Commit()
 }
 
+// SetPasswordHash sets the argon2id password hash of an account whose
+// password was set before argon2id hashes were stored. If MD5 hashes are
+// disabled, the account's weak and strong MD5 hashes are deleted. Return
+// ErrNoUser if the account doesn't exist.
+func (f SQLiteUserStore) SetPasswordHash(screenName IdentScreenName, passwordHash string) error {
+	q := `
+		UPDATE users
+		SET passwordHash  = ?,
+		    weakMD5Pass   = CASE WHEN ? THEN NULL ELSE weakMD5Pass END,
+		    strongMD5Pass = CASE WHEN ? THEN NULL ELSE strongMD5Pass END
+		WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, passwordHash, f.noMD5Pass, f.noMD5Pass, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
 // Feedbag fetches the contents of a user's feedbag (buddy list).
 func (f SQLiteUserStore) Feedbag(screenName IdentScreenName) ([]wire.FeedbagItem, error) {
 	q := `
@@ -700,8 +1230,13 @@ func (f SQLiteUserStore) FeedbagLastModified(screenName IdentScreenName) (time.T
 	return time.Unix(lastModified.Int64, 0), err
 }
 
-// FeedbagDelete deletes an entry from a user's feedbag (buddy list).
+// FeedbagDelete deletes an entry from a user's feedbag (buddy list). Return
+// ErrSharedGroupReadOnly if any of the items belong to a shared group.
 func (f SQLiteUserStore) FeedbagDelete(screenName IdentScreenName, items []wire.FeedbagItem) error {
+	if err := f.checkSharedGroups(screenName, items); err != nil {
+		return err
+	}
+
 	// todo add transaction
 	q := `DELETE FROM feedbag WHERE screenName = ? AND itemID = ?`
 
@@ -714,12 +1249,66 @@ func (f SQLiteUserStore) FeedbagDelete(screenName IdentScreenName, items []wire.
 	return nil
 }
 
-// FeedbagUpsert upserts an entry to a user's feedbag (buddy list). An entry is
-// created if it doesn't already exist, or modified if it already exists.
-func (f SQLiteUserStore) FeedbagUpsert(screenName IdentScreenName, items []wire.FeedbagItem) error {
-	q := `
-		INSERT INTO feedbag (screenName, groupID, itemID, classID, name, attributes, pdMode, lastModified)
-		VALUES (?, ?, ?, ?, ?, ?, ?, UNIXEPOCH())
+// checkSharedGroups returns ErrSharedGroupReadOnly if any of items is a
+// shared group or belongs to one in the user's feedbag, since shared groups
+// are managed by the operator.
+func (f SQLiteUserStore) checkSharedGroups(screenName IdentScreenName, items []wire.FeedbagItem) error {
+	current, err := f.Feedbag(screenName)
+	if err != nil {
+		return err
+	}
+	shared := SharedGroupIDs(current)
+	for _, item := range items {
+		if shared[item.GroupID] || IsSharedGroup(item) {
+			return ErrSharedGroupReadOnly
+		}
+	}
+	return nil
+}
+
+// FeedbagUpsert upserts an entry to a user's feedbag (buddy list). An entry is
+// created if it doesn't already exist, or modified if it already exists.
+// Return ErrSharedGroupReadOnly if any of the items belong to a shared group.
+func (f SQLiteUserStore) FeedbagUpsert(screenName IdentScreenName, items []wire.FeedbagItem) error {
+	if err := f.checkSharedGroups(screenName, items); err != nil {
+		return err
+	}
+	return feedbagUpsert(f.db, screenName, items)
+}
+
+// FeedbagReplace replaces the entire contents of a user's feedbag (buddy list)
+// with items. Every item is stamped with the current time so that clients pick
+// up the new list the next time they ask whether it was modified.
+func (f SQLiteUserStore) FeedbagReplace(screenName IdentScreenName, items []wire.FeedbagItem) (err error) {
+	tx, err := f.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM feedbag WHERE screenName = ?`, screenName.String()); err != nil {
+		return err
+	}
+	if err = feedbagUpsert(tx, screenName, items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// feedbagUpsert upserts feedbag entries using db, which is either the
+// database or a transaction.
+func feedbagUpsert(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, screenName IdentScreenName, items []wire.FeedbagItem) error {
+	q := `
+		INSERT INTO feedbag (screenName, groupID, itemID, classID, name, attributes, pdMode, lastModified)
+		VALUES (?, ?, ?, ?, ?, ?, ?, UNIXEPOCH())
 		ON CONFLICT (screenName, groupID, itemID)
 			DO UPDATE SET classID      = excluded.classID,
 						  name         = excluded.name,
@@ -749,7 +1338,7 @@ func (f SQLiteUserStore) FeedbagUpsert(screenName IdentScreenName, items []wire.
 				pdMode = uint8(wire.FeedbagPDModePermitAll)
 			}
 		}
-		_, err := f.db.Exec(q,
+		_, err := db.Exec(q,
 			screenName.String(),
 			item.GroupID,
 			item.ItemID,
@@ -798,7 +1387,8 @@ func (f SQLiteUserStore) UnregisterBuddyList(user IdentScreenName) error {
 }
 
 // UseFeedbag sets the current session to use the server-side buddy list
-// instead of the default client-side buddy list.
+// instead of the default client-side buddy list. It also brings the shared
+// groups in the user's feedbag up to date.
 func (f SQLiteUserStore) UseFeedbag(user IdentScreenName) error {
 	q := `
 		INSERT INTO buddyListMode (screenName, useFeedbag)
@@ -807,8 +1397,28 @@ func (f SQLiteUserStore) UseFeedbag(user IdentScreenName) error {
 			DO UPDATE SET clientSidePDMode = 0,
 						  useFeedbag       = true
 	`
-	_, err := f.db.Exec(q, user.String(), true)
-	return err
+	if _, err := f.db.Exec(q, user.String(), true); err != nil {
+		return err
+	}
+
+	// bring the user's shared groups up to date before the client fetches
+	// the feedbag
+	members, err := f.SharedGroupMembers()
+	if err != nil {
+		return err
+	}
+	var synced []string
+	for _, member := range members {
+		if slices.Contains(synced, member.Group) {
+			continue
+		}
+		synced = append(synced, member.Group)
+		if _, _, _, err := f.SyncSharedGroup(user, member.Group); err != nil {
+			return fmt.Errorf("SyncSharedGroup: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // SetPDMode sets my current client-side permit/deny mode. It clears any
@@ -916,7 +1526,8 @@ func (f SQLiteUserStore) AddBuddy(me IdentScreenName, them IdentScreenName) erro
 	return err
 }
 
-// RemoveBuddy removes a buddy from my client-side buddy list.
+// RemoveBuddy removes a buddy from my client-side buddy list. The entry is
+// deleted if them is not also on my permit or deny list.
 func (f SQLiteUserStore) RemoveBuddy(me IdentScreenName, them IdentScreenName) error {
 	q := `
 		UPDATE clientSideBuddyList
@@ -924,6 +1535,17 @@ func (f SQLiteUserStore) RemoveBuddy(me IdentScreenName, them IdentScreenName) e
 		WHERE me = ?
 		  AND them = ?
 	`
+	if _, err := f.db.Exec(q, me.String(), them.String()); err != nil {
+		return err
+	}
+	q = `
+		DELETE FROM clientSideBuddyList
+		WHERE me = ?
+		  AND them = ?
+		  AND isBuddy IS FALSE
+		  AND isPermit IS FALSE
+		  AND isDeny IS FALSE
+	`
 	_, err := f.db.Exec(q, me.String(), them.String())
 	return err
 }
@@ -982,12 +1604,16 @@ func (f SQLiteUserStore) Profile(screenName IdentScreenName) (string, error) {
 		FROM profile
 		WHERE screenName = ?
 	`
-	var profile string
+	var profile []byte
 	err := f.db.QueryRow(q, screenName.String()).Scan(&profile)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return "", err
 	}
-	return profile, nil
+	profile, err = f.contentCipher.Decrypt(profile)
+	if err != nil {
+		return "", err
+	}
+	return string(profile), nil
 }
 
 // SetProfile sets the text contents of a user's profile.
@@ -998,7 +1624,11 @@ func (f SQLiteUserStore) SetProfile(screenName IdentScreenName, body string) err
 		ON CONFLICT (screenName)
 			DO UPDATE SET body = excluded.body
 	`
-	_, err := f.db.Exec(q, screenName.String(), body)
+	stored, err := f.contentCipher.Encrypt([]byte(body))
+	if err != nil {
+		return err
+	}
+	_, err = f.db.Exec(q, screenName.String(), string(stored))
 	return err
 }
 
@@ -1034,6 +1664,7 @@ func (f SQLiteUserStore) SetDirectoryInfo(screenName IdentScreenName, info AIMNa
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
+	f.invalidateSearches()
 
 	c, err := res.RowsAffected()
 	if err != nil {
@@ -1070,13 +1701,74 @@ func (f SQLiteUserStore) BARTRetrieve(hash []byte) ([]byte, error) {
 	return body, err
 }
 
+// bartExportItem is the serialized form of a BART item written by
+// ExportBARTItems.
+type bartExportItem struct {
+	Hash []byte `json:"hash"`
+	Body []byte `json:"body"`
+}
+
+// ExportBARTItems writes every BART item to w as a stream of JSON objects,
+// one per line, that ImportBARTItems reads back. The hash and body of each
+// item are base64-encoded. It returns the number of items written.
+func (f SQLiteUserStore) ExportBARTItems(w io.Writer) (int, error) {
+	q := `
+		SELECT hash, body
+		FROM bartItem
+		ORDER BY hash
+	`
+	rows, err := f.db.Query(q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for rows.Next() {
+		var item bartExportItem
+		if err := rows.Scan(&item.Hash, &item.Body); err != nil {
+			return count, err
+		}
+		if err := enc.Encode(item); err != nil {
+			return count, fmt.Errorf("unable to write BART item: %w", err)
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// ImportBARTItems reads BART items written by ExportBARTItems from r and
+// stores them. Items whose hash already exists are left unchanged. It returns
+// the number of items read.
+func (f SQLiteUserStore) ImportBARTItems(r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	count := 0
+	for {
+		var item bartExportItem
+		if err := dec.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				return count, nil
+			}
+			return count, fmt.Errorf("unable to read BART item %d: %w", count+1, err)
+		}
+		if len(item.Hash) == 0 {
+			return count, fmt.Errorf("BART item %d has no hash", count+1)
+		}
+		if err := f.BARTUpsert(item.Hash, item.Body); err != nil {
+			return count, fmt.Errorf("BARTUpsert: %w", err)
+		}
+		count++
+	}
+}
+
 // ChatRoomByCookie looks up a chat room by cookie. Returns
 // ErrChatRoomNotFound if the room does not exist for cookie.
 func (f SQLiteUserStore) ChatRoomByCookie(cookie string) (ChatRoom, error) {
 	chatRoom := ChatRoom{}
 
 	q := `
-		SELECT exchange, name, created, creator
+		SELECT exchange, name, created, creator, topic
 		FROM chatRoom
 		WHERE lower(cookie) = lower(?)
 	`
@@ -1086,6 +1778,7 @@ func (f SQLiteUserStore) ChatRoomByCookie(cookie string) (ChatRoom, error) {
 		&chatRoom.name,
 		&chatRoom.createTime,
 		&creator,
+		&chatRoom.topic,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		err = fmt.Errorf("%w: %s", ErrChatRoomNotFound, cookie)
@@ -1103,7 +1796,7 @@ func (f SQLiteUserStore) ChatRoomByName(exchange uint16, name string) (ChatRoom,
 	}
 
 	q := `
-		SELECT name, created, creator
+		SELECT name, created, creator, topic
 		FROM chatRoom
 		WHERE exchange = ? AND lower(name) = lower(?)
 	`
@@ -1112,6 +1805,7 @@ func (f SQLiteUserStore) ChatRoomByName(exchange uint16, name string) (ChatRoom,
 		&chatRoom.name,
 		&chatRoom.createTime,
 		&creator,
+		&chatRoom.topic,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		err = ErrChatRoomNotFound
@@ -1147,9 +1841,34 @@ func (f SQLiteUserStore) CreateChatRoom(chatRoom *ChatRoom) error {
 	return err
 }
 
+// DeleteChatRoom deletes the chat room identified by cookie along with its
+// moderators and bans. Returns ErrChatRoomNotFound if the room does not
+// exist for cookie.
+func (f SQLiteUserStore) DeleteChatRoom(cookie string) error {
+	// moderators and bans are deleted by cascade
+	q := `
+		DELETE FROM chatRoom
+		WHERE lower(cookie) = lower(?)
+	`
+	result, err := f.db.Exec(q, cookie)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrChatRoomNotFound, cookie)
+	}
+
+	return nil
+}
+
 func (f SQLiteUserStore) AllChatRooms(exchange uint16) ([]ChatRoom, error) {
 	q := `
-		SELECT created, creator, name
+		SELECT created, creator, name, topic
 		FROM chatRoom
 		WHERE exchange = ?
 		ORDER BY created ASC
@@ -1166,7 +1885,7 @@ func (f SQLiteUserStore) AllChatRooms(exchange uint16) ([]ChatRoom, error) {
 			exchange: exchange,
 		}
 		var creator string
-		if err := rows.Scan(&cr.createTime, &creator, &cr.name); err != nil {
+		if err := rows.Scan(&cr.createTime, &creator, &cr.name, &cr.topic); err != nil {
 			return nil, err
 		}
 		cr.creator = NewIdentScreenName(creator)
@@ -1180,6 +1899,219 @@ func (f SQLiteUserStore) AllChatRooms(exchange uint16) ([]ChatRoom, error) {
 	return users, nil
 }
 
+// AddChatRoomModerator makes a user a moderator of the chat room identified
+// by cookie. Moderators can remove other users from the room. Adding an
+// existing moderator has no effect.
+func (f SQLiteUserStore) AddChatRoomModerator(cookie string, screenName IdentScreenName) error {
+	q := `
+		INSERT INTO chatRoomModerator (cookie, screenName)
+		VALUES (?, ?)
+		ON CONFLICT (cookie, screenName) DO NOTHING
+	`
+	_, err := f.db.Exec(q, cookie, screenName.String())
+	return err
+}
+
+// RemoveChatRoomModerator revokes a user's moderator status for the chat room
+// identified by cookie.
+func (f SQLiteUserStore) RemoveChatRoomModerator(cookie string, screenName IdentScreenName) error {
+	q := `
+		DELETE FROM chatRoomModerator
+		WHERE cookie = ? AND screenName = ?
+	`
+	_, err := f.db.Exec(q, cookie, screenName.String())
+	return err
+}
+
+// ChatRoomModerators returns the moderators of the chat room identified by
+// cookie.
+func (f SQLiteUserStore) ChatRoomModerators(cookie string) ([]IdentScreenName, error) {
+	q := `
+		SELECT screenName
+		FROM chatRoomModerator
+		WHERE cookie = ?
+		ORDER BY screenName ASC
+	`
+	rows, err := f.db.Query(q, cookie)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moderators []IdentScreenName
+	for rows.Next() {
+		var screenName string
+		if err := rows.Scan(&screenName); err != nil {
+			return nil, err
+		}
+		moderators = append(moderators, NewIdentScreenName(screenName))
+	}
+
+	return moderators, rows.Err()
+}
+
+// IsChatRoomModerator indicates whether a user is a moderator of the chat
+// room identified by cookie. The user who created a room is always one of its
+// moderators.
+func (f SQLiteUserStore) IsChatRoomModerator(cookie string, screenName IdentScreenName) (bool, error) {
+	q := `
+		SELECT EXISTS(SELECT 1 FROM chatRoomModerator WHERE cookie = ? AND screenName = ?)
+			OR EXISTS(SELECT 1 FROM chatRoom WHERE lower(cookie) = lower(?) AND creator = ?)
+	`
+	var exists bool
+	err := f.db.QueryRow(q, cookie, screenName.String(), cookie, screenName.String()).Scan(&exists)
+	return exists, err
+}
+
+// AddChatRoomBan bans a user from the chat room identified by cookie. Banned
+// users can't join the room. Banning a user who is already banned has no
+// effect.
+func (f SQLiteUserStore) AddChatRoomBan(cookie string, screenName IdentScreenName) error {
+	q := `
+		INSERT INTO chatRoomBan (cookie, screenName)
+		VALUES (?, ?)
+		ON CONFLICT (cookie, screenName) DO NOTHING
+	`
+	_, err := f.db.Exec(q, cookie, screenName.String())
+	return err
+}
+
+// RemoveChatRoomBan lifts a user's ban from the chat room identified by
+// cookie.
+func (f SQLiteUserStore) RemoveChatRoomBan(cookie string, screenName IdentScreenName) error {
+	q := `
+		DELETE FROM chatRoomBan
+		WHERE cookie = ? AND screenName = ?
+	`
+	_, err := f.db.Exec(q, cookie, screenName.String())
+	return err
+}
+
+// ChatRoomBans returns the users banned from the chat room identified by
+// cookie.
+func (f SQLiteUserStore) ChatRoomBans(cookie string) ([]IdentScreenName, error) {
+	q := `
+		SELECT screenName
+		FROM chatRoomBan
+		WHERE cookie = ?
+		ORDER BY screenName ASC
+	`
+	rows, err := f.db.Query(q, cookie)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var banned []IdentScreenName
+	for rows.Next() {
+		var screenName string
+		if err := rows.Scan(&screenName); err != nil {
+			return nil, err
+		}
+		banned = append(banned, NewIdentScreenName(screenName))
+	}
+
+	return banned, rows.Err()
+}
+
+// IsChatRoomBanned indicates whether a user is banned from the chat room
+// identified by cookie.
+func (f SQLiteUserStore) IsChatRoomBanned(cookie string, screenName IdentScreenName) (bool, error) {
+	q := `
+		SELECT EXISTS(SELECT 1 FROM chatRoomBan WHERE cookie = ? AND screenName = ?)
+	`
+	var exists bool
+	err := f.db.QueryRow(q, cookie, screenName.String()).Scan(&exists)
+	return exists, err
+}
+
+// SetChatRoomTopic sets the topic of the chat room identified by cookie.
+// Returns ErrChatRoomNotFound if the room does not exist.
+func (f SQLiteUserStore) SetChatRoomTopic(cookie string, topic string) error {
+	q := `
+		UPDATE chatRoom
+		SET topic = ?
+		WHERE lower(cookie) = lower(?)
+	`
+	res, err := f.db.Exec(q, topic, cookie)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrChatRoomNotFound, cookie)
+	}
+	return nil
+}
+
+// AppendChatTranscript records a message in the transcript of the chat room
+// identified by cookie. The message text is encrypted if content encryption is
+// enabled.
+func (f SQLiteUserStore) AppendChatTranscript(cookie string, entry ChatTranscriptEntry) error {
+	q := `
+		INSERT INTO chatTranscript (cookie, screenName, text, sent)
+		VALUES (?, ?, ?, ?)
+	`
+	text, err := f.contentCipher.Encrypt([]byte(entry.Text))
+	if err != nil {
+		return err
+	}
+	_, err = f.db.Exec(q, cookie, entry.ScreenName.String(), string(text), entry.Sent.Unix())
+	return err
+}
+
+// ChatTranscript returns the messages recorded for the chat room identified
+// by cookie that were sent at or after since, in the order they were
+// recorded.
+func (f SQLiteUserStore) ChatTranscript(cookie string, since time.Time) ([]ChatTranscriptEntry, error) {
+	q := `
+		SELECT screenName, text, sent
+		FROM chatTranscript
+		WHERE cookie = ? AND sent >= ?
+		ORDER BY id ASC
+	`
+	rows, err := f.db.Query(q, cookie, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ChatTranscriptEntry
+	for rows.Next() {
+		var screenName string
+		var text []byte
+		var entry ChatTranscriptEntry
+		var sent int64
+		if err := rows.Scan(&screenName, &text, &sent); err != nil {
+			return nil, err
+		}
+		text, err = f.contentCipher.Decrypt(text)
+		if err != nil {
+			return nil, err
+		}
+		entry.ScreenName = DisplayScreenName(screenName)
+		entry.Text = string(text)
+		entry.Sent = time.Unix(sent, 0).UTC()
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// DeleteChatTranscriptsBefore removes transcript messages sent before
+// before from all chat rooms.
+func (f SQLiteUserStore) DeleteChatTranscriptsBefore(before time.Time) error {
+	q := `
+		DELETE FROM chatTranscript
+		WHERE sent < ?
+	`
+	_, err := f.db.Exec(q, before.Unix())
+	return err
+}
+
 // UpdateDisplayScreenName updates the user's DisplayScreenName
 func (f SQLiteUserStore) UpdateDisplayScreenName(displayScreenName DisplayScreenName) error {
 	q := `
@@ -1188,7 +2120,12 @@ func (f SQLiteUserStore) UpdateDisplayScreenName(displayScreenName DisplayScreen
 		WHERE identScreenName = ?
 	`
 	_, err := f.db.Exec(q, displayScreenName.String(), displayScreenName.IdentScreenName().String())
-	return err
+	if err != nil {
+		return err
+	}
+
+	f.invalidateSearches()
+	return nil
 }
 
 // UpdateEmailAddress updates the user's EmailAddress
@@ -1260,6 +2197,126 @@ func (f SQLiteUserStore) UpdateConfirmStatus(confirmStatus bool, screenName Iden
 	return err
 }
 
+// SetConfirmToken sets the token that confirms the user's account when
+// passed to ConfirmAccountByToken before expiresAt. It replaces any previous
+// token. Only a hash of the token is stored.
+func (f SQLiteUserStore) SetConfirmToken(token string, expiresAt time.Time, screenName IdentScreenName) error {
+	q := `
+		UPDATE users
+		SET confirmToken = ?, confirmTokenExpiry = ?
+		WHERE identScreenName = ?
+	`
+	_, err := f.db.Exec(q, hashToken(token), expiresAt.Unix(), screenName.String())
+	return err
+}
+
+// ConfirmAccountByToken marks the account that owns the confirmation token as
+// confirmed and clears the token so that it can't be reused. It returns the
+// screen name of the confirmed account. Return ErrConfirmTokenNotFound if no
+// account has the token or the token has expired.
+func (f SQLiteUserStore) ConfirmAccountByToken(token string) (IdentScreenName, error) {
+	if token == "" {
+		return IdentScreenName{}, ErrConfirmTokenNotFound
+	}
+	q := `
+		UPDATE users
+		SET confirmStatus = true, confirmToken = '', confirmTokenExpiry = 0
+		WHERE confirmToken = ? AND confirmTokenExpiry > ?
+		RETURNING identScreenName
+	`
+	var screenName string
+	err := f.db.QueryRow(q, hashToken(token), time.Now().Unix()).Scan(&screenName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return IdentScreenName{}, ErrConfirmTokenNotFound
+	}
+	if err != nil {
+		return IdentScreenName{}, err
+	}
+	return NewIdentScreenName(screenName), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of a single-use token, which
+// is stored in place of the token so that a copy of the database can't be
+// used to take over accounts.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetPasswordResetToken flags the account as pending a password reset, which
+// is completed by passing token to ResetPasswordByToken before expiresAt. It
+// replaces any previous token. Only a hash of the token is stored. Return
+// ErrNoUser if the user doesn't exist.
+func (f SQLiteUserStore) SetPasswordResetToken(screenName IdentScreenName, token string, expiresAt time.Time) error {
+	q := `
+		UPDATE users
+		SET passwordResetToken = ?, passwordResetTokenExpiry = ?
+		WHERE identScreenName = ?
+	`
+	res, err := f.db.Exec(q, hashToken(token), expiresAt.Unix(), screenName.String())
+	if err != nil {
+		return err
+	}
+	c, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if c == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+// ResetPasswordByToken sets a new password on the account that owns the
+// password reset token and clears the token so that it can't be reused. It
+// returns the screen name of the account. Return ErrPasswordResetTokenNotFound
+// if no account has the token or the token has expired, or ErrPasswordInvalid
+// if the new password doesn't meet the password rules.
+func (f SQLiteUserStore) ResetPasswordByToken(token string, newPassword string) (IdentScreenName, error) {
+	if token == "" {
+		return IdentScreenName{}, ErrPasswordResetTokenNotFound
+	}
+
+	tx, err := f.db.Begin()
+	if err != nil {
+		return IdentScreenName{}, err
+	}
+	defer tx.Rollback()
+
+	q := `
+		SELECT identScreenName, authKey, isICQ
+		FROM users
+		WHERE passwordResetToken = ? AND passwordResetTokenExpiry > ?
+	`
+	var sn string
+	u := User{}
+	err = tx.QueryRow(q, hashToken(token), time.Now().Unix()).Scan(&sn, &u.AuthKey, &u.IsICQ)
+	if errors.Is(err, sql.ErrNoRows) {
+		return IdentScreenName{}, ErrPasswordResetTokenNotFound
+	}
+	if err != nil {
+		return IdentScreenName{}, err
+	}
+
+	if err := u.HashPassword(newPassword); err != nil {
+		return IdentScreenName{}, err
+	}
+
+	q = `
+		UPDATE users
+		SET passwordHash = ?, weakMD5Pass = ?, strongMD5Pass = ?, passwordResetToken = ''
+		WHERE identScreenName = ?
+	`
+	if _, err := tx.Exec(q, u.PasswordHash, f.weakMD5Pass(u), f.strongMD5Pass(u), sn); err != nil {
+		return IdentScreenName{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return IdentScreenName{}, err
+	}
+	return NewIdentScreenName(sn), nil
+}
+
 // ConfirmStatusByName retrieves the user's confirmation status
 func (f SQLiteUserStore) ConfirmStatusByName(screenName IdentScreenName) (bool, error) {
 	q := `
@@ -1312,6 +2369,8 @@ func (f SQLiteUserStore) SetWorkInfo(name IdentScreenName, data ICQWorkInfo) err
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
+	f.invalidateSearches()
+
 	c, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("rows affected: %w", err)
@@ -1336,14 +2395,39 @@ func (f SQLiteUserStore) SetMoreInfo(name IdentScreenName, data ICQMoreInfo) err
 		WHERE identScreenName = ?
 	`
 	res, err := f.db.Exec(q,
-		data.BirthDay,
-		data.BirthMonth,
-		data.BirthYear,
-		data.Gender,
-		data.HomePageAddr,
-		data.Lang1,
-		data.Lang2,
-		data.Lang3,
+		data.BirthDay,
+		data.BirthMonth,
+		data.BirthYear,
+		data.Gender,
+		data.HomePageAddr,
+		data.Lang1,
+		data.Lang2,
+		data.Lang3,
+		name.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	f.invalidateSearches()
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if c == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+func (f SQLiteUserStore) SetUserNotes(name IdentScreenName, data ICQUserNotes) error {
+	q := `
+		UPDATE users
+		SET icq_notes = ?
+		WHERE identScreenName = ?
+	`
+	res, err := f.db.Exec(q,
+		data.Notes,
 		name.String(),
 	)
 	if err != nil {
@@ -1359,14 +2443,17 @@ func (f SQLiteUserStore) SetMoreInfo(name IdentScreenName, data ICQMoreInfo) err
 	return nil
 }
 
-func (f SQLiteUserStore) SetUserNotes(name IdentScreenName, data ICQUserNotes) error {
+// SetPermissions updates the privacy settings for an ICQ user.
+func (f SQLiteUserStore) SetPermissions(name IdentScreenName, data ICQPermissions) error {
 	q := `
-		UPDATE users
-		SET icq_notes = ?
+		UPDATE users SET
+			icq_permissions_authRequired = ?,
+			icq_permissions_webAware = ?
 		WHERE identScreenName = ?
 	`
 	res, err := f.db.Exec(q,
-		data.Notes,
+		data.AuthRequired,
+		data.WebAware,
 		name.String(),
 	)
 	if err != nil {
@@ -1409,6 +2496,8 @@ func (f SQLiteUserStore) SetInterests(name IdentScreenName, data ICQInterests) e
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
+	f.invalidateSearches()
+
 	c, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("rows affected: %w", err)
@@ -1454,6 +2543,8 @@ func (f SQLiteUserStore) SetAffiliations(name IdentScreenName, data ICQAffiliati
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
+	f.invalidateSearches()
+
 	c, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("rows affected: %w", err)
@@ -1503,6 +2594,8 @@ func (f SQLiteUserStore) SetBasicInfo(name IdentScreenName, data ICQBasicInfo) e
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
+	f.invalidateSearches()
+
 	c, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("rows affected: %w", err)
@@ -1513,25 +2606,67 @@ func (f SQLiteUserStore) SetBasicInfo(name IdentScreenName, data ICQBasicInfo) e
 	return nil
 }
 
-// SaveMessage saves an offline message for later retrieval.
+// SaveMessage saves an offline message for later retrieval. Return
+// ErrStorageQuotaExceeded if the message would take the recipient past their
+// storage quota, or ErrOfflineMessageLimit if the recipient already has the
+// maximum number of offline messages waiting. The message limit applies to
+// AIM users only, since ICQ offline messages predate it.
 func (f SQLiteUserStore) SaveMessage(offlineMessage OfflineMessage) error {
 	buf := &bytes.Buffer{}
 	if err := wire.MarshalBE(offlineMessage.Message, buf); err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
 
+	stored, err := f.contentCipher.Encrypt(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	usage, err := f.StorageUsage(offlineMessage.Recipient)
+	switch {
+	case errors.Is(err, ErrNoUser):
+		// no account to charge the message to
+	case err != nil:
+		return fmt.Errorf("StorageUsage: %w", err)
+	case usage.QuotaBytes > 0 && usage.UsedBytes()+int64(len(stored)) > usage.QuotaBytes:
+		return ErrStorageQuotaExceeded
+	}
+
+	// the limit only applies to AIM users
+	limit := f.offlineMessageLimit
+	if DisplayScreenName(offlineMessage.Recipient.String()).IsUIN() {
+		limit = 0
+	}
+
+	// count and insert in one statement so that concurrent senders can't
+	// take the recipient past the limit
 	q := `
 		INSERT INTO offlineMessage (sender, recipient, message, sent)
-		VALUES (?, ?, ?, ?)
+		SELECT ?, ?, ?, ?
+		WHERE ? <= 0
+		   OR (SELECT COUNT(*) FROM offlineMessage WHERE recipient = ?) < ?
 	`
-	_, err := f.db.Exec(
+	res, err := f.db.Exec(
 		q,
 		offlineMessage.Sender.String(),
 		offlineMessage.Recipient.String(),
-		buf.Bytes(),
+		stored,
 		offlineMessage.Sent,
+		limit,
+		offlineMessage.Recipient.String(),
+		limit,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrOfflineMessageLimit
+	}
+	return nil
 }
 
 // RetrieveMessages retrieves all offline messages sent to recipient.
@@ -1543,6 +2678,7 @@ func (f SQLiteUserStore) RetrieveMessages(recip IdentScreenName) ([]OfflineMessa
 		    sent
 		FROM offlineMessage
 		WHERE recipient = ?
+		ORDER BY sent, rowid
 	`
 	rows, err := f.db.Query(q, recip.String())
 	if err != nil {
@@ -1560,6 +2696,11 @@ func (f SQLiteUserStore) RetrieveMessages(recip IdentScreenName) ([]OfflineMessa
 			return nil, err
 		}
 
+		buf, err = f.contentCipher.Decrypt(buf)
+		if err != nil {
+			return nil, err
+		}
+
 		var msg wire.SNAC_0x04_0x06_ICBMChannelMsgToHost
 		if err := wire.UnmarshalBE(&msg, bytes.NewBuffer(buf)); err != nil {
 			return nil, fmt.Errorf("unmarshal: %w", err)
@@ -1589,7 +2730,93 @@ func (f SQLiteUserStore) DeleteMessages(recip IdentScreenName) error {
 	return err
 }
 
-// BuddyIconRefByName retrieves the buddy icon reference for a given user
+// StorageUsage reports how much data is stored on behalf of a user and the
+// user's storage quota. Return ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) StorageUsage(screenName IdentScreenName) (StorageUsage, error) {
+	q := `
+		SELECT
+			(SELECT COALESCE(SUM(LENGTH(CAST(message AS BLOB))), 0)
+			 FROM offlineMessage
+			 WHERE recipient = identScreenName),
+			(SELECT COALESCE(SUM(LENGTH(CAST(body AS BLOB))), 0)
+			 FROM profile
+			 WHERE screenName = identScreenName),
+			(SELECT COALESCE(SUM(LENGTH(CAST(name AS BLOB)) + LENGTH(CAST(attributes AS BLOB))), 0)
+			 FROM feedbag
+			 WHERE screenName = identScreenName),
+			storageQuota
+		FROM users
+		WHERE identScreenName = ?
+	`
+	usage := StorageUsage{}
+	var quota *int64
+	err := f.db.QueryRow(q, screenName.String()).Scan(
+		&usage.OfflineMessageBytes,
+		&usage.ProfileBytes,
+		&usage.FeedbagBytes,
+		&quota,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return usage, ErrNoUser
+	}
+	if err != nil {
+		return usage, err
+	}
+
+	usage.QuotaBytes = f.defaultStorageQuota
+	if quota != nil {
+		usage.QuotaBytes = *quota
+	}
+	return usage, nil
+}
+
+// SetStorageQuota sets the user's storage quota in bytes, overriding the
+// default quota. A quota of 0 means unlimited storage and a nil quota reverts
+// the user to the default quota. Return ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) SetStorageQuota(screenName IdentScreenName, quota *int64) error {
+	q := `
+		UPDATE users SET storageQuota = ? WHERE identScreenName = ?
+	`
+	result, err := f.db.Exec(q, quota, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
+
+// SetDefaultBuddyIcon stores icon as the buddy icon of users who haven't set
+// their own. The icon is saved as a BART item keyed by its MD5 hash so that
+// clients can download it like any other buddy icon.
+func (f *SQLiteUserStore) SetDefaultBuddyIcon(icon []byte) error {
+	if len(icon) == 0 {
+		return errors.New("default buddy icon is empty")
+	}
+	hash := md5.Sum(icon)
+	if err := f.BARTUpsert(hash[:], icon); err != nil {
+		return fmt.Errorf("BARTUpsert: %w", err)
+	}
+	f.defaultBuddyIcon = &wire.BARTID{
+		Type: wire.BARTTypesBuddyIcon,
+		BARTInfo: wire.BARTInfo{
+			Flags: wire.BARTFlagsKnown,
+			Hash:  hash[:],
+		},
+	}
+	return nil
+}
+
+// BuddyIconRefByName retrieves the buddy icon reference for a given user. If
+// the user hasn't set a buddy icon, the default buddy icon is returned, if
+// one is set.
 func (f SQLiteUserStore) BuddyIconRefByName(screenName IdentScreenName) (*wire.BARTID, error) {
 	q := `
 		SELECT
@@ -1605,7 +2832,11 @@ func (f SQLiteUserStore) BuddyIconRefByName(screenName IdentScreenName) (*wire.B
 	var attrs []byte
 	err := f.db.QueryRow(q, screenName.String(), wire.BARTTypesBuddyIcon, wire.FeedbagClassIdBart).Scan(&item.GroupID, &item.ItemID, &item.ClassID, &item.Name, &attrs)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+		if f.defaultBuddyIcon == nil {
+			return nil, nil
+		}
+		icon := *f.defaultBuddyIcon
+		return &icon, nil
 	}
 	if err != nil {
 		return nil, err
@@ -1653,7 +2884,12 @@ func (f SQLiteUserStore) SetKeywords(name IdentScreenName, keywords [5]string) e
 		keywords[0], keywords[1], keywords[2], keywords[3], keywords[4],
 		keywords[0], keywords[1], keywords[2], keywords[3], keywords[4],
 		name.String())
-	return err
+	if err != nil {
+		return err
+	}
+
+	f.invalidateSearches()
+	return nil
 }
 
 // Categories returns a list of keyword categories.
@@ -1739,6 +2975,7 @@ func (f SQLiteUserStore) DeleteCategory(categoryID uint8) error {
 		return ErrKeywordCategoryNotFound
 	}
 
+	f.invalidateSearches()
 	return nil
 }
 
@@ -1820,6 +3057,8 @@ func (f SQLiteUserStore) CreateKeyword(name string, categoryID uint8) (Keyword,
 		return Keyword{}, err
 	}
 
+	f.invalidateSearches()
+
 	return Keyword{
 		ID:   uint8(id),
 		Name: name,
@@ -1846,6 +3085,7 @@ func (f SQLiteUserStore) DeleteKeyword(id uint8) error {
 		return ErrKeywordNotFound
 	}
 
+	f.invalidateSearches()
 	return nil
 }
 
@@ -1944,3 +3184,297 @@ func (f SQLiteUserStore) InterestList() ([]wire.ODirKeywordListItem, error) {
 
 	return list, nil
 }
+
+// AwayTemplates returns all away message templates ordered by name.
+func (f SQLiteUserStore) AwayTemplates() ([]AwayTemplate, error) {
+	q := `
+		SELECT name, message
+		FROM awayTemplate
+		ORDER BY name ASC
+	`
+	rows, err := f.db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []AwayTemplate
+	for rows.Next() {
+		var template AwayTemplate
+		if err := rows.Scan(&template.Name, &template.Message); err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// AwayTemplate returns the away message template with the given name.
+// Return ErrAwayTemplateNotFound if the template does not exist.
+func (f SQLiteUserStore) AwayTemplate(name string) (AwayTemplate, error) {
+	q := `
+		SELECT name, message
+		FROM awayTemplate
+		WHERE name = ?
+	`
+	var template AwayTemplate
+	err := f.db.QueryRow(q, name).Scan(&template.Name, &template.Message)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AwayTemplate{}, ErrAwayTemplateNotFound
+	}
+	return template, err
+}
+
+// SetAwayTemplate creates an away message template, or replaces the message
+// of the template if one with the same name already exists.
+func (f SQLiteUserStore) SetAwayTemplate(template AwayTemplate) error {
+	q := `
+		INSERT INTO awayTemplate (name, message)
+		VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET message = excluded.message
+	`
+	_, err := f.db.Exec(q, template.Name, template.Message)
+	return err
+}
+
+// DeleteAwayTemplate deletes the away message template with the given name.
+// Return ErrAwayTemplateNotFound if the template does not exist.
+func (f SQLiteUserStore) DeleteAwayTemplate(name string) error {
+	q := `
+		DELETE FROM awayTemplate WHERE name = ?
+	`
+	result, err := f.db.Exec(q, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAwayTemplateNotFound
+	}
+
+	return nil
+}
+
+// SharedGroupMembers returns the members of all shared groups ordered by
+// group name and screen name.
+func (f SQLiteUserStore) SharedGroupMembers() ([]SharedGroupMember, error) {
+	q := `
+		SELECT sharedGroupMember.groupName, users.displayScreenName
+		FROM sharedGroupMember
+		JOIN users ON users.identScreenName = sharedGroupMember.screenName
+		ORDER BY sharedGroupMember.groupName ASC, users.identScreenName ASC
+	`
+	rows, err := f.db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []SharedGroupMember
+	for rows.Next() {
+		var member SharedGroupMember
+		if err := rows.Scan(&member.Group, &member.ScreenName); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// AddSharedGroupMember adds a user to a shared group, creating the group if
+// it doesn't exist. Adding a user who is already a member has no effect.
+// Return ErrNoUser if the user does not exist.
+func (f SQLiteUserStore) AddSharedGroupMember(group string, screenName IdentScreenName) error {
+	user, err := f.User(screenName)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNoUser
+	}
+
+	q := `
+		INSERT INTO sharedGroupMember (groupName, screenName)
+		VALUES (?, ?)
+		ON CONFLICT DO NOTHING
+	`
+	_, err = f.db.Exec(q, group, user.IdentScreenName.String())
+	return err
+}
+
+// RemoveSharedGroupMember removes a user from a shared group. The group
+// ceases to exist once its last member is removed. Return
+// ErrSharedGroupMemberNotFound if the user is not a member of the group.
+func (f SQLiteUserStore) RemoveSharedGroupMember(group string, screenName IdentScreenName) error {
+	q := `
+		DELETE FROM sharedGroupMember WHERE groupName = ? AND screenName = ?
+	`
+	result, err := f.db.Exec(q, group, screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSharedGroupMemberNotFound
+	}
+
+	return nil
+}
+
+// SyncSharedGroup brings a shared group in a user's feedbag up to date with
+// the group's members, creating the group if it's missing and removing it if
+// the group has no members left. The user is never listed in their own
+// feedbag. It returns the feedbag items that were inserted, updated, and
+// deleted so that the changes can be sent to the user's client.
+func (f SQLiteUserStore) SyncSharedGroup(owner IdentScreenName, group string) (inserted []wire.FeedbagItem, updated []wire.FeedbagItem, deleted []wire.FeedbagItem, err error) {
+	all, err := f.SharedGroupMembers()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var members []DisplayScreenName
+	for _, member := range all {
+		if member.Group == group && member.ScreenName.IdentScreenName() != owner {
+			members = append(members, member.ScreenName)
+		}
+	}
+
+	items, err := f.Feedbag(owner)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	inserted, updated, deleted, err = SyncSharedGroupInFeedbag(items, group, members)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the shared group can't be changed through FeedbagDelete and
+	// FeedbagUpsert. Items are deleted by group ID as well as item ID, since
+	// every group has an item ID of 0.
+	q := `DELETE FROM feedbag WHERE screenName = ? AND groupID = ? AND itemID = ?`
+	for _, item := range deleted {
+		if _, err := f.db.Exec(q, owner.String(), item.GroupID, item.ItemID); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if err := feedbagUpsert(f.db, owner, append(slices.Clone(inserted), updated...)); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return inserted, updated, deleted, nil
+}
+
+// CanonicalScreenName returns the screen name of the account that screenName
+// is an alias of. If screenName is not an alias, it's returned as-is.
+func (f SQLiteUserStore) CanonicalScreenName(screenName IdentScreenName) (IdentScreenName, error) {
+	q := `
+		SELECT screenName FROM screenNameAlias WHERE alias = ?
+	`
+	var canonical string
+	err := f.db.QueryRow(q, screenName.String()).Scan(&canonical)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return screenName, nil
+	case err != nil:
+		return IdentScreenName{}, err
+	}
+	return NewIdentScreenName(canonical), nil
+}
+
+// ScreenNameAliases returns a user's aliases ordered by name.
+func (f SQLiteUserStore) ScreenNameAliases(screenName IdentScreenName) ([]IdentScreenName, error) {
+	q := `
+		SELECT alias FROM screenNameAlias WHERE screenName = ? ORDER BY alias ASC
+	`
+	rows, err := f.db.Query(q, screenName.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []IdentScreenName
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, NewIdentScreenName(alias))
+	}
+
+	return aliases, rows.Err()
+}
+
+// AddScreenNameAlias makes alias another name for the user's account. Return
+// ErrNoUser if the user does not exist, or ErrDupUser if alias is already
+// taken by an account, another alias, or a virtual user.
+func (f SQLiteUserStore) AddScreenNameAlias(screenName IdentScreenName, alias IdentScreenName) error {
+	f.reservedNames.mutex.Lock()
+	defer f.reservedNames.mutex.Unlock()
+	if _, ok := f.reservedNames.names[alias]; ok {
+		return ErrDupUser
+	}
+
+	q := `
+		SELECT COUNT(*) FROM users WHERE identScreenName = ?
+	`
+	var count int
+	if err := f.db.QueryRow(q, screenName.String()).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNoUser
+	}
+
+	q = `
+		INSERT INTO screenNameAlias (alias, screenName)
+		SELECT ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE identScreenName = ?)
+		ON CONFLICT (alias) DO NOTHING
+	`
+	result, err := f.db.Exec(q, alias.String(), screenName.String(), alias.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrDupUser
+	}
+
+	return nil
+}
+
+// DeleteScreenNameAlias removes one of the user's aliases. Return
+// ErrScreenNameAliasNotFound if alias is not an alias of the user.
+func (f SQLiteUserStore) DeleteScreenNameAlias(screenName IdentScreenName, alias IdentScreenName) error {
+	q := `
+		DELETE FROM screenNameAlias WHERE alias = ? AND screenName = ?
+	`
+	result, err := f.db.Exec(q, alias.String(), screenName.String())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrScreenNameAliasNotFound
+	}
+
+	return nil
+}
@@ -1,11 +1,18 @@
 package state
 
 import (
+	"bytes"
+	"crypto/md5"
 	"fmt"
 	"math"
 	"net/mail"
+	"net/netip"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -182,6 +189,39 @@ func TestFeedbagDelete(t *testing.T) {
 	}
 }
 
+func TestSQLiteUserStore_FeedbagReplace(t *testing.T) {
+	screenName := NewIdentScreenName("sn2day")
+
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	oldItems := []wire.FeedbagItem{
+		{GroupID: 0, ItemID: 0, ClassID: wire.FeedbagClassIdGroup},
+		{GroupID: 1, ItemID: 0, ClassID: wire.FeedbagClassIdGroup, Name: "Friends"},
+		{GroupID: 1, ItemID: 1, ClassID: wire.FeedbagClassIdBuddy, Name: "oldbuddy"},
+	}
+	assert.NoError(t, f.FeedbagUpsert(screenName, oldItems))
+
+	newItems := []wire.FeedbagItem{
+		{GroupID: 0, ItemID: 0, ClassID: wire.FeedbagClassIdGroup},
+		{GroupID: 2, ItemID: 0, ClassID: wire.FeedbagClassIdGroup, Name: "Co-Workers"},
+		{GroupID: 2, ItemID: 5, ClassID: wire.FeedbagClassIdBuddy, Name: "newbuddy"},
+	}
+	assert.NoError(t, f.FeedbagReplace(screenName, newItems))
+
+	have, err := f.Feedbag(screenName)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, newItems, have)
+
+	lastModified, err := f.FeedbagLastModified(screenName)
+	assert.NoError(t, err)
+	assert.True(t, lastModified.After(time.Unix(0, 0)))
+}
+
 func TestLastModifiedEmpty(t *testing.T) {
 
 	screenName := NewIdentScreenName("sn2day")
@@ -318,6 +358,7 @@ func TestGetUser(t *testing.T) {
 		AuthKey:           "theauthkey",
 		StrongMD5Pass:     []byte("thepasshash"),
 		RegStatus:         3,
+		CreatedAt:         time.Unix(1700000000, 0).UTC(),
 	}
 	err = f.InsertUser(*insertedUser)
 	assert.NoError(t, err)
@@ -450,6 +491,400 @@ func TestSQLiteUserStore_DeleteUser_DeleteNonExistentUser(t *testing.T) {
 	assert.ErrorIs(t, ErrNoUser, err)
 }
 
+func TestSQLiteUserStore_SetWatched(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.IsWatched)
+
+	assert.NoError(t, f.SetWatched(screenName, true))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.True(t, u.IsWatched)
+
+	assert.NoError(t, f.SetWatched(screenName, false))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.IsWatched)
+
+	err = f.SetWatched(NewIdentScreenName("userB"), true)
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_SetCanCreateChatRooms(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.CanCreateChatRooms)
+
+	assert.NoError(t, f.SetCanCreateChatRooms(screenName, true))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.True(t, u.CanCreateChatRooms)
+
+	assert.NoError(t, f.SetCanCreateChatRooms(screenName, false))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.CanCreateChatRooms)
+
+	err = f.SetCanCreateChatRooms(NewIdentScreenName("userB"), true)
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_SetOfficial(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.IsOfficial)
+
+	assert.NoError(t, f.SetOfficial(screenName, true))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.True(t, u.IsOfficial)
+
+	assert.NoError(t, f.SetOfficial(screenName, false))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.IsOfficial)
+
+	err = f.SetOfficial(NewIdentScreenName("userB"), true)
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_SetConcurrentLoginPolicy(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.Empty(t, u.ConcurrentLoginPolicy)
+	assert.Equal(t, "kick-old", u.ConcurrentLogin("kick-old"))
+
+	assert.NoError(t, f.SetConcurrentLoginPolicy(screenName, "reject-new"))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.Equal(t, "reject-new", u.ConcurrentLogin("kick-old"))
+
+	assert.NoError(t, f.SetConcurrentLoginPolicy(screenName, ""))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.Empty(t, u.ConcurrentLoginPolicy)
+
+	err = f.SetConcurrentLoginPolicy(NewIdentScreenName("userB"), "reject-new")
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_BanUser(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.IsBanned)
+
+	before := time.Now().Add(-time.Second)
+	assert.NoError(t, f.BanUser(screenName, "spamming"))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.True(t, u.IsBanned)
+	assert.Equal(t, "spamming", u.BanReason)
+	assert.True(t, u.BannedAt.After(before))
+
+	assert.NoError(t, f.UnbanUser(screenName))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, u.IsBanned)
+	assert.Empty(t, u.BanReason)
+	assert.True(t, u.BannedAt.IsZero())
+
+	err = f.BanUser(NewIdentScreenName("userB"), "spamming")
+	assert.ErrorIs(t, err, ErrNoUser)
+	err = f.UnbanUser(NewIdentScreenName("userB"))
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_SetAllowedNetworks(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.Empty(t, u.AllowedNetworks)
+
+	networks := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+	assert.NoError(t, f.SetAllowedNetworks(screenName, networks))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.Equal(t, networks, u.AllowedNetworks)
+
+	assert.NoError(t, f.SetAllowedNetworks(screenName, nil))
+	u, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.Empty(t, u.AllowedNetworks)
+
+	err = f.SetAllowedNetworks(NewIdentScreenName("userB"), networks)
+	assert.ErrorIs(t, err, ErrNoUser)
+}
+
+func TestSQLiteUserStore_ConfirmAccountByToken(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+
+	// an empty token never matches accounts that have no token
+	_, err = f.ConfirmAccountByToken("")
+	assert.ErrorIs(t, err, ErrConfirmTokenNotFound)
+
+	expiresAt := time.Now().Add(time.Hour)
+	assert.NoError(t, f.SetConfirmToken("old-token", expiresAt, screenName))
+	// a new token replaces the old one
+	assert.NoError(t, f.SetConfirmToken("the-token", expiresAt, screenName))
+
+	_, err = f.ConfirmAccountByToken("old-token")
+	assert.ErrorIs(t, err, ErrConfirmTokenNotFound)
+
+	confirmed, err := f.ConfirmStatusByName(screenName)
+	assert.NoError(t, err)
+	assert.False(t, confirmed)
+
+	have, err := f.ConfirmAccountByToken("the-token")
+	assert.NoError(t, err)
+	assert.Equal(t, screenName, have)
+
+	confirmed, err = f.ConfirmStatusByName(screenName)
+	assert.NoError(t, err)
+	assert.True(t, confirmed)
+
+	// the token can't be reused
+	_, err = f.ConfirmAccountByToken("the-token")
+	assert.ErrorIs(t, err, ErrConfirmTokenNotFound)
+
+	// an expired token is rejected
+	assert.NoError(t, f.UpdateConfirmStatus(false, screenName))
+	assert.NoError(t, f.SetConfirmToken("expired-token", time.Now().Add(-time.Minute), screenName))
+	_, err = f.ConfirmAccountByToken("expired-token")
+	assert.ErrorIs(t, err, ErrConfirmTokenNotFound)
+
+	confirmed, err = f.ConfirmStatusByName(screenName)
+	assert.NoError(t, err)
+	assert.False(t, confirmed)
+}
+
+func TestSQLiteUserStore_ResetPasswordByToken(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	screenName := NewIdentScreenName("userA")
+	u := User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+		AuthKey:           "theauthkey",
+	}
+	assert.NoError(t, u.HashPassword("oldpass"))
+	assert.NoError(t, f.InsertUser(u))
+
+	// an empty token never matches accounts that have no token
+	_, err = f.ResetPasswordByToken("", "newpass")
+	assert.ErrorIs(t, err, ErrPasswordResetTokenNotFound)
+
+	expiresAt := time.Now().Add(time.Hour)
+	assert.ErrorIs(t, f.SetPasswordResetToken(NewIdentScreenName("nobody"), "the-token", expiresAt), ErrNoUser)
+	assert.NoError(t, f.SetPasswordResetToken(screenName, "old-token", expiresAt))
+	// a new token replaces the old one
+	assert.NoError(t, f.SetPasswordResetToken(screenName, "the-token", expiresAt))
+
+	_, err = f.ResetPasswordByToken("old-token", "newpass")
+	assert.ErrorIs(t, err, ErrPasswordResetTokenNotFound)
+
+	have, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.True(t, have.PasswordResetPending)
+
+	// an invalid password leaves the reset pending
+	_, err = f.ResetPasswordByToken("the-token", "no")
+	assert.ErrorIs(t, err, ErrPasswordInvalid)
+
+	sn, err := f.ResetPasswordByToken("the-token", "newpass")
+	assert.NoError(t, err)
+	assert.Equal(t, screenName, sn)
+
+	have, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, have.PasswordResetPending)
+	assert.True(t, have.ValidateHash(wire.WeakMD5PasswordHash("newpass", "theauthkey")))
+	assert.False(t, have.ValidateHash(wire.WeakMD5PasswordHash("oldpass", "theauthkey")))
+
+	// the token can't be reused
+	_, err = f.ResetPasswordByToken("the-token", "newpass2")
+	assert.ErrorIs(t, err, ErrPasswordResetTokenNotFound)
+
+	// setting the password directly cancels a pending reset
+	assert.NoError(t, f.SetPasswordResetToken(screenName, "another-token", expiresAt))
+	assert.NoError(t, f.SetUserPassword(screenName, "newpass3"))
+	have, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.False(t, have.PasswordResetPending)
+	_, err = f.ResetPasswordByToken("another-token", "newpass4")
+	assert.ErrorIs(t, err, ErrPasswordResetTokenNotFound)
+
+	// an expired token is rejected
+	assert.NoError(t, f.SetPasswordResetToken(screenName, "expired-token", time.Now().Add(-time.Minute)))
+	_, err = f.ResetPasswordByToken("expired-token", "newpass5")
+	assert.ErrorIs(t, err, ErrPasswordResetTokenNotFound)
+	have, err = f.User(screenName)
+	assert.NoError(t, err)
+	assert.True(t, have.ValidateHash(wire.WeakMD5PasswordHash("newpass3", "theauthkey")))
+}
+
+func TestSQLiteUserStore_PasswordHash(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	strongUser := User{
+		IdentScreenName:   NewIdentScreenName("strongUser"),
+		DisplayScreenName: "strongUser",
+		AuthKey:           "theauthkey",
+	}
+	assert.NoError(t, strongUser.HashPassword("thepass"))
+	assert.NoError(t, f.InsertUser(strongUser))
+
+	// legacyUser's password was set before argon2id hashes were stored
+	legacyUser := strongUser
+	legacyUser.IdentScreenName = NewIdentScreenName("legacyUser")
+	legacyUser.DisplayScreenName = "legacyUser"
+	legacyUser.PasswordHash = ""
+	assert.NoError(t, f.InsertUser(legacyUser))
+
+	have, err := f.User(strongUser.IdentScreenName)
+	assert.NoError(t, err)
+	assert.True(t, have.ValidatePassword("thepass"))
+	assert.Equal(t, strongUser.PasswordHash, have.PasswordHash)
+
+	// MD5 hashes are deleted only for accounts with an argon2id hash
+	assert.NoError(t, f.DisableWeakMD5Pass())
+	have, err = f.User(strongUser.IdentScreenName)
+	assert.NoError(t, err)
+	assert.Nil(t, have.WeakMD5Pass)
+	assert.Nil(t, have.StrongMD5Pass)
+	have, err = f.User(legacyUser.IdentScreenName)
+	assert.NoError(t, err)
+	assert.Equal(t, legacyUser.WeakMD5Pass, have.WeakMD5Pass)
+	assert.Equal(t, legacyUser.StrongMD5Pass, have.StrongMD5Pass)
+
+	// the legacy account gets an argon2id hash and loses its MD5 hashes
+	assert.NoError(t, legacyUser.SetPasswordHash("thepass"))
+	assert.NoError(t, f.SetPasswordHash(legacyUser.IdentScreenName, legacyUser.PasswordHash))
+	have, err = f.User(legacyUser.IdentScreenName)
+	assert.NoError(t, err)
+	assert.Equal(t, legacyUser.PasswordHash, have.PasswordHash)
+	assert.Nil(t, have.WeakMD5Pass)
+	assert.Nil(t, have.StrongMD5Pass)
+
+	// new passwords are stored without MD5 hashes
+	assert.NoError(t, f.SetUserPassword(strongUser.IdentScreenName, "newpass"))
+	have, err = f.User(strongUser.IdentScreenName)
+	assert.NoError(t, err)
+	assert.True(t, have.ValidatePassword("newpass"))
+	assert.False(t, have.ValidatePassword("thepass"))
+	assert.Nil(t, have.WeakMD5Pass)
+	assert.Nil(t, have.StrongMD5Pass)
+
+	assert.ErrorIs(t, f.SetPasswordHash(NewIdentScreenName("nobody"), legacyUser.PasswordHash), ErrNoUser)
+}
+
 func TestNewStubUser(t *testing.T) {
 	have, err := NewStubUser("userA")
 	assert.NoError(t, err)
@@ -461,6 +896,10 @@ func TestNewStubUser(t *testing.T) {
 	}
 	assert.NoError(t, want.HashPassword("welcome1"))
 
+	// the argon2id hash is salted, so compare it by verifying the password
+	assert.True(t, have.ValidatePassword("welcome1"))
+	want.PasswordHash = have.PasswordHash
+
 	assert.Equal(t, want, have)
 }
 
@@ -507,6 +946,48 @@ func TestSQLiteUserStore_BARTUpsertAndRetrieve(t *testing.T) {
 	assert.Equal(t, item, b)
 }
 
+func TestSQLiteUserStore_ExportImportBARTItems(t *testing.T) {
+	importFile := filepath.Join(t.TempDir(), "import.db")
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	src, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	icon1 := []byte("icon-1")
+	hash1 := md5.Sum(icon1)
+	icon2 := []byte("icon-2")
+	hash2 := md5.Sum(icon2)
+	assert.NoError(t, src.BARTUpsert(hash1[:], icon1))
+	assert.NoError(t, src.BARTUpsert(hash2[:], icon2))
+
+	buf := &bytes.Buffer{}
+	count, err := src.ExportBARTItems(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	dst, err := NewSQLiteUserStore(importFile)
+	assert.NoError(t, err)
+
+	// an item that's already present is left as-is
+	assert.NoError(t, dst.BARTUpsert(hash1[:], icon1))
+
+	count, err = dst.ImportBARTItems(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	b, err := dst.BARTRetrieve(hash1[:])
+	assert.NoError(t, err)
+	assert.Equal(t, icon1, b)
+	b, err = dst.BARTRetrieve(hash2[:])
+	assert.NoError(t, err)
+	assert.Equal(t, icon2, b)
+
+	_, err = dst.ImportBARTItems(strings.NewReader(`{"body":"aWNvbg=="}`))
+	assert.Error(t, err)
+}
+
 func TestSQLiteUserStore_SetUserPassword_UserExists(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -680,6 +1161,326 @@ func TestSQLiteUserStore_AllChatRooms(t *testing.T) {
 	assert.Equal(t, chatRooms[0:2], gotRooms)
 }
 
+func TestSQLiteUserStore_ChatRoomModerators(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	room := NewChatRoom("chat room", NewIdentScreenName("creator"), PublicExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&room))
+
+	assert.NoError(t, userStore.AddChatRoomModerator(room.Cookie(), NewIdentScreenName("mod2")))
+	assert.NoError(t, userStore.AddChatRoomModerator(room.Cookie(), NewIdentScreenName("mod1")))
+	// adding an existing moderator is a no-op
+	assert.NoError(t, userStore.AddChatRoomModerator(room.Cookie(), NewIdentScreenName("mod1")))
+
+	mods, err := userStore.ChatRoomModerators(room.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("mod1"), NewIdentScreenName("mod2")}, mods)
+
+	isMod, err := userStore.IsChatRoomModerator(room.Cookie(), NewIdentScreenName("mod1"))
+	assert.NoError(t, err)
+	assert.True(t, isMod)
+
+	isMod, err = userStore.IsChatRoomModerator("4-0-another room", NewIdentScreenName("mod1"))
+	assert.NoError(t, err)
+	assert.False(t, isMod)
+
+	assert.NoError(t, userStore.RemoveChatRoomModerator(room.Cookie(), NewIdentScreenName("mod1")))
+
+	isMod, err = userStore.IsChatRoomModerator(room.Cookie(), NewIdentScreenName("mod1"))
+	assert.NoError(t, err)
+	assert.False(t, isMod)
+
+	mods, err = userStore.ChatRoomModerators(room.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("mod2")}, mods)
+}
+
+func TestSQLiteUserStore_IsChatRoomModerator_Creator(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	room := NewChatRoom("chat room", NewIdentScreenName("creator"), PrivateExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&room))
+
+	// the room creator is a moderator without being added as one
+	isMod, err := userStore.IsChatRoomModerator(room.Cookie(), NewIdentScreenName("creator"))
+	assert.NoError(t, err)
+	assert.True(t, isMod)
+
+	isMod, err = userStore.IsChatRoomModerator(room.Cookie(), NewIdentScreenName("someone else"))
+	assert.NoError(t, err)
+	assert.False(t, isMod)
+}
+
+func TestSQLiteUserStore_ChatRoomBans(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	room := NewChatRoom("chat room", NewIdentScreenName("creator"), PublicExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&room))
+
+	assert.NoError(t, userStore.AddChatRoomBan(room.Cookie(), NewIdentScreenName("troll2")))
+	assert.NoError(t, userStore.AddChatRoomBan(room.Cookie(), NewIdentScreenName("troll1")))
+	// banning a banned user is a no-op
+	assert.NoError(t, userStore.AddChatRoomBan(room.Cookie(), NewIdentScreenName("troll1")))
+
+	banned, err := userStore.ChatRoomBans(room.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("troll1"), NewIdentScreenName("troll2")}, banned)
+
+	isBanned, err := userStore.IsChatRoomBanned(room.Cookie(), NewIdentScreenName("troll1"))
+	assert.NoError(t, err)
+	assert.True(t, isBanned)
+
+	isBanned, err = userStore.IsChatRoomBanned("4-0-another room", NewIdentScreenName("troll1"))
+	assert.NoError(t, err)
+	assert.False(t, isBanned)
+
+	assert.NoError(t, userStore.RemoveChatRoomBan(room.Cookie(), NewIdentScreenName("troll1")))
+
+	isBanned, err = userStore.IsChatRoomBanned(room.Cookie(), NewIdentScreenName("troll1"))
+	assert.NoError(t, err)
+	assert.False(t, isBanned)
+}
+
+func TestSQLiteUserStore_DeleteChatRoom(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	room := NewChatRoom("chat room", NewIdentScreenName("creator"), PublicExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&room))
+	assert.NoError(t, userStore.AddChatRoomModerator(room.Cookie(), NewIdentScreenName("mod")))
+	assert.NoError(t, userStore.AddChatRoomBan(room.Cookie(), NewIdentScreenName("troll")))
+
+	otherRoom := NewChatRoom("other room", NewIdentScreenName("creator"), PublicExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&otherRoom))
+	assert.NoError(t, userStore.AddChatRoomModerator(otherRoom.Cookie(), NewIdentScreenName("mod")))
+	assert.NoError(t, userStore.AddChatRoomBan(otherRoom.Cookie(), NewIdentScreenName("troll")))
+
+	assert.NoError(t, userStore.DeleteChatRoom(room.Cookie()))
+
+	_, err = userStore.ChatRoomByCookie(room.Cookie())
+	assert.ErrorIs(t, err, ErrChatRoomNotFound)
+
+	moderators, err := userStore.ChatRoomModerators(room.Cookie())
+	assert.NoError(t, err)
+	assert.Empty(t, moderators)
+
+	banned, err := userStore.ChatRoomBans(room.Cookie())
+	assert.NoError(t, err)
+	assert.Empty(t, banned)
+
+	// the other room is untouched
+	moderators, err = userStore.ChatRoomModerators(otherRoom.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("mod")}, moderators)
+
+	banned, err = userStore.ChatRoomBans(otherRoom.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{NewIdentScreenName("troll")}, banned)
+
+	err = userStore.DeleteChatRoom(room.Cookie())
+	assert.ErrorIs(t, err, ErrChatRoomNotFound)
+}
+
+func TestSQLiteUserStore_SetChatRoomTopic(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	room := NewChatRoom("chat room", NewIdentScreenName("creator"), PublicExchange)
+	assert.NoError(t, userStore.CreateChatRoom(&room))
+
+	assert.NoError(t, userStore.SetChatRoomTopic(room.Cookie(), "the topic"))
+
+	gotRoom, err := userStore.ChatRoomByCookie(room.Cookie())
+	assert.NoError(t, err)
+	assert.Equal(t, "the topic", gotRoom.Topic())
+
+	err = userStore.SetChatRoomTopic("5-0-no such room", "the topic")
+	assert.ErrorIs(t, err, ErrChatRoomNotFound)
+}
+
+func TestSQLiteUserStore_AwayTemplates(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, userStore.SetAwayTemplate(AwayTemplate{Name: "lunch", Message: "Out to lunch"}))
+	assert.NoError(t, userStore.SetAwayTemplate(AwayTemplate{Name: "brb", Message: "Be right back"}))
+	// setting an existing template replaces its message
+	assert.NoError(t, userStore.SetAwayTemplate(AwayTemplate{Name: "lunch", Message: "Out to <b>lunch</b>"}))
+
+	templates, err := userStore.AwayTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []AwayTemplate{
+		{Name: "brb", Message: "Be right back"},
+		{Name: "lunch", Message: "Out to <b>lunch</b>"},
+	}, templates)
+
+	template, err := userStore.AwayTemplate("brb")
+	assert.NoError(t, err)
+	assert.Equal(t, AwayTemplate{Name: "brb", Message: "Be right back"}, template)
+
+	assert.NoError(t, userStore.DeleteAwayTemplate("brb"))
+
+	_, err = userStore.AwayTemplate("brb")
+	assert.ErrorIs(t, err, ErrAwayTemplateNotFound)
+	assert.ErrorIs(t, userStore.DeleteAwayTemplate("brb"), ErrAwayTemplateNotFound)
+
+	templates, err = userStore.AwayTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []AwayTemplate{{Name: "lunch", Message: "Out to <b>lunch</b>"}}, templates)
+}
+
+func TestSQLiteUserStore_SharedGroups(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	for _, sn := range []DisplayScreenName{"StaffAnn", "StaffBob", "userC"} {
+		assert.NoError(t, f.InsertUser(User{
+			IdentScreenName:   sn.IdentScreenName(),
+			DisplayScreenName: sn,
+		}))
+	}
+
+	assert.NoError(t, f.AddSharedGroupMember("Staff", NewIdentScreenName("staffann")))
+	assert.NoError(t, f.AddSharedGroupMember("Staff", NewIdentScreenName("staffbob")))
+	// adding an existing member has no effect
+	assert.NoError(t, f.AddSharedGroupMember("Staff", NewIdentScreenName("staffbob")))
+	assert.ErrorIs(t, f.AddSharedGroupMember("Staff", NewIdentScreenName("nobody")), ErrNoUser)
+
+	members, err := f.SharedGroupMembers()
+	assert.NoError(t, err)
+	assert.Equal(t, []SharedGroupMember{
+		{Group: "Staff", ScreenName: "StaffAnn"},
+		{Group: "Staff", ScreenName: "StaffBob"},
+	}, members)
+
+	// using the feedbag adds the shared group to the user's feedbag
+	userC := NewIdentScreenName("userC")
+	assert.NoError(t, f.UseFeedbag(userC))
+	items, err := f.Feedbag(userC)
+	assert.NoError(t, err)
+	var buddies []string
+	for _, item := range items {
+		if item.ClassID == wire.FeedbagClassIdBuddy {
+			buddies = append(buddies, item.Name)
+		}
+	}
+	assert.ElementsMatch(t, []string{"staffann", "staffbob"}, buddies)
+
+	// users can't delete the shared group or its members
+	var shared []wire.FeedbagItem
+	for _, item := range items {
+		if item.GroupID != 0 {
+			shared = append(shared, item)
+		}
+	}
+	assert.Len(t, shared, 3)
+	assert.ErrorIs(t, f.FeedbagDelete(userC, shared), ErrSharedGroupReadOnly)
+	assert.ErrorIs(t, f.FeedbagUpsert(userC, shared), ErrSharedGroupReadOnly)
+	assert.ErrorIs(t, f.FeedbagUpsert(userC, []wire.FeedbagItem{
+		{Name: "userD", GroupID: shared[0].GroupID, ItemID: 100, ClassID: wire.FeedbagClassIdBuddy},
+	}), ErrSharedGroupReadOnly)
+	items, err = f.Feedbag(userC)
+	assert.NoError(t, err)
+	assert.Len(t, items, 4)
+
+	// members aren't listed in their own feedbag
+	inserted, _, _, err := f.SyncSharedGroup(NewIdentScreenName("staffann"), "Staff")
+	assert.NoError(t, err)
+	for _, item := range inserted {
+		assert.NotEqual(t, "staffann", NewIdentScreenName(item.Name).String())
+	}
+
+	// removing a member removes them from the shared group
+	assert.NoError(t, f.RemoveSharedGroupMember("Staff", NewIdentScreenName("staffann")))
+	assert.ErrorIs(t, f.RemoveSharedGroupMember("Staff", NewIdentScreenName("staffann")), ErrSharedGroupMemberNotFound)
+	_, _, deleted, err := f.SyncSharedGroup(userC, "Staff")
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.Equal(t, "staffann", deleted[0].Name)
+
+	// removing the last member removes the group
+	assert.NoError(t, f.RemoveSharedGroupMember("Staff", NewIdentScreenName("staffbob")))
+	_, _, _, err = f.SyncSharedGroup(userC, "Staff")
+	assert.NoError(t, err)
+	items, err = f.Feedbag(userC)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, uint16(0), items[0].GroupID)
+}
+
+func TestSQLiteUserStore_ChatTranscript(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []ChatTranscriptEntry{
+		{ScreenName: "UserB", Text: "old news", Sent: base},
+		{ScreenName: "UserA", Text: "hello", Sent: base.Add(time.Hour)},
+		{ScreenName: "UserB", Text: "hi there", Sent: base.Add(time.Hour)},
+		{ScreenName: "UserA", Text: "bye", Sent: base.Add(2 * time.Hour)},
+	}
+	for _, entry := range entries {
+		assert.NoError(t, userStore.AppendChatTranscript("4-0-room", entry))
+	}
+	assert.NoError(t, userStore.AppendChatTranscript("4-0-another room", ChatTranscriptEntry{
+		ScreenName: "UserC",
+		Text:       "elsewhere",
+		Sent:       base.Add(time.Hour),
+	}))
+
+	// messages are returned in the order they were recorded, even when they
+	// share a timestamp
+	have, err := userStore.ChatTranscript("4-0-room", base.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, entries[1:], have)
+
+	// expired messages are removed from all rooms
+	assert.NoError(t, userStore.DeleteChatTranscriptsBefore(base.Add(2*time.Hour)))
+
+	have, err = userStore.ChatTranscript("4-0-room", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, entries[3:], have)
+
+	have, err = userStore.ChatTranscript("4-0-another room", time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, have)
+}
+
 func TestSQLiteUserStore_CreateChatRoom_ErrChatRoomExists(t *testing.T) {
 
 	tt := []struct {
@@ -916,6 +1717,45 @@ func TestSQLiteUserStore_SetMoreInfo(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_SetPermissions(t *testing.T) {
+	// Cleanup after test
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	// Initialize the SQLiteUserStore with a test database file
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	// Create a test user
+	screenName := NewIdentScreenName("100003")
+	user := User{
+		IdentScreenName: screenName,
+		IsICQ:           true,
+	}
+	err = f.InsertUser(user)
+	assert.NoError(t, err)
+
+	t.Run("Successful Update", func(t *testing.T) {
+		permissions := ICQPermissions{
+			AuthRequired: true,
+			WebAware:     true,
+		}
+		err := f.SetPermissions(screenName, permissions)
+		assert.NoError(t, err)
+
+		// Retrieve the user and verify the permissions were set correctly
+		updatedUser, err := f.User(screenName)
+		assert.NoError(t, err)
+		assert.Equal(t, permissions, updatedUser.ICQPermissions)
+	})
+
+	t.Run("Update Non-Existing User", func(t *testing.T) {
+		err := f.SetPermissions(NewIdentScreenName("100004"), ICQPermissions{})
+		assert.ErrorIs(t, err, ErrNoUser)
+	})
+}
+
 func TestSQLiteUserStore_SetUserNotes(t *testing.T) {
 	// Cleanup after test
 	defer func() {
@@ -1298,7 +2138,7 @@ func TestSQLiteUserStore_FindByICQInterests(t *testing.T) {
 
 	t.Run("Find Users by Single Keyword", func(t *testing.T) {
 		// Search for users interested in "Music"
-		users, err := f.FindByICQInterests(2, []string{"Music"})
+		users, _, err := f.FindByICQInterests(2, []string{"Music"}, 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 
@@ -1309,7 +2149,7 @@ func TestSQLiteUserStore_FindByICQInterests(t *testing.T) {
 
 	t.Run("Find Users by Multiple Keywords", func(t *testing.T) {
 		// Search for users interested in "Coding" or "Gaming"
-		users, err := f.FindByICQInterests(1, []string{"Coding", "Gaming"})
+		users, _, err := f.FindByICQInterests(1, []string{"Coding", "Gaming"}, 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 
@@ -1320,14 +2160,14 @@ func TestSQLiteUserStore_FindByICQInterests(t *testing.T) {
 
 	t.Run("Find Users by Multiple Codes and Keywords", func(t *testing.T) {
 		// Search for users interested in "Coding"
-		users, err := f.FindByICQInterests(1, []string{"Coding"})
+		users, _, err := f.FindByICQInterests(1, []string{"Coding"}, 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 		assert.True(t, containsUserWithScreenName(users, user1.IdentScreenName))
 		assert.True(t, containsUserWithScreenName(users, user2.IdentScreenName))
 
 		// Search for users interested in "Travel"
-		users, err = f.FindByICQInterests(4, []string{"Travel"})
+		users, _, err = f.FindByICQInterests(4, []string{"Travel"}, 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 1)
 		assert.True(t, containsUserWithScreenName(users, user3.IdentScreenName))
@@ -1335,12 +2175,108 @@ func TestSQLiteUserStore_FindByICQInterests(t *testing.T) {
 
 	t.Run("No Users Found", func(t *testing.T) {
 		// Search for users interested in a keyword that no user has
-		users, err := f.FindByICQInterests(1, []string{"Status"})
+		users, _, err := f.FindByICQInterests(1, []string{"Status"}, 10)
 		assert.NoError(t, err)
 		assert.Empty(t, users)
 	})
 }
 
+func TestSQLiteUserStore_SearchCache(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+	f.SetSearchCache(NewSearchCache(time.Minute))
+
+	user1 := User{IdentScreenName: NewIdentScreenName("user1")}
+	assert.NoError(t, f.InsertUser(user1))
+	assert.NoError(t, f.SetInterests(user1.IdentScreenName, ICQInterests{Keyword1: "Coding"}))
+
+	users, total, err := f.FindByICQKeyword("coding", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, users, 1)
+
+	// change the database behind the cache's back. an identical search
+	// within the TTL is served from the cache.
+	_, err = f.db.Exec(`UPDATE users SET icq_interests_keyword1 = '' WHERE identScreenName = 'user1'`)
+	assert.NoError(t, err)
+	users, total, err = f.FindByICQKeyword("Coding", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, users, 1)
+
+	// a profile update invalidates the cache
+	user2 := User{IdentScreenName: NewIdentScreenName("user2")}
+	assert.NoError(t, f.InsertUser(user2))
+	assert.NoError(t, f.SetInterests(user2.IdentScreenName, ICQInterests{Keyword1: "Coding"}))
+	users, total, err = f.FindByICQKeyword("coding", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, user2.IdentScreenName, users[0].IdentScreenName)
+	}
+}
+
+func TestSQLiteUserStore_SearchCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, f *SQLiteUserStore)
+	}{
+		{
+			name: "insert user",
+			mutate: func(t *testing.T, f *SQLiteUserStore) {
+				assert.NoError(t, f.InsertUser(User{IdentScreenName: NewIdentScreenName("user3")}))
+			},
+		},
+		{
+			name: "create keyword",
+			mutate: func(t *testing.T, f *SQLiteUserStore) {
+				_, err := f.CreateKeyword("Knitting", 0)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "delete keyword",
+			mutate: func(t *testing.T, f *SQLiteUserStore) {
+				kw, err := f.CreateKeyword("Knitting", 0)
+				assert.NoError(t, err)
+				f.searchCache.Put("sentinel", nil, 0)
+				assert.NoError(t, f.DeleteKeyword(kw.ID))
+			},
+		},
+		{
+			name: "delete category",
+			mutate: func(t *testing.T, f *SQLiteUserStore) {
+				category, err := f.CreateCategory("Crafts")
+				assert.NoError(t, err)
+				f.searchCache.Put("sentinel", nil, 0)
+				assert.NoError(t, f.DeleteCategory(category.ID))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				assert.NoError(t, os.Remove(testFile))
+			}()
+
+			f, err := NewSQLiteUserStore(testFile)
+			assert.NoError(t, err)
+			f.SetSearchCache(NewSearchCache(time.Minute))
+
+			f.searchCache.Put("sentinel", nil, 0)
+			tt.mutate(t, f)
+
+			_, _, ok := f.searchCache.Get("sentinel")
+			assert.False(t, ok)
+		})
+	}
+}
+
 func TestSQLiteUserStore_FindByICQKeyword(t *testing.T) {
 	// Cleanup after test
 	defer func() {
@@ -1400,7 +2336,7 @@ func TestSQLiteUserStore_FindByICQKeyword(t *testing.T) {
 
 	t.Run("Find Users by Keyword", func(t *testing.T) {
 		// Search for users interested in "Music"
-		users, err := f.FindByICQKeyword("Music")
+		users, _, err := f.FindByICQKeyword("Music", 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 
@@ -1411,7 +2347,7 @@ func TestSQLiteUserStore_FindByICQKeyword(t *testing.T) {
 
 	t.Run("No Users Found", func(t *testing.T) {
 		// Search for users interested in a keyword that no user has
-		users, err := f.FindByICQKeyword("Knitting")
+		users, _, err := f.FindByICQKeyword("Knitting", 10)
 		assert.NoError(t, err)
 		assert.Empty(t, users)
 	})
@@ -1479,7 +2415,7 @@ func TestSQLiteUserStore_FindByICQName(t *testing.T) {
 
 	t.Run("Find Users by First Name", func(t *testing.T) {
 		// Search for users with the first name "John"
-		users, err := f.FindByICQName("John", "", "")
+		users, _, err := f.FindByICQName("John", "", "", 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 
@@ -1490,7 +2426,7 @@ func TestSQLiteUserStore_FindByICQName(t *testing.T) {
 
 	t.Run("Find Users by Last Name", func(t *testing.T) {
 		// Search for users with the last name "Smith"
-		users, err := f.FindByICQName("", "Smith", "")
+		users, _, err := f.FindByICQName("", "Smith", "", 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 
@@ -1501,7 +2437,7 @@ func TestSQLiteUserStore_FindByICQName(t *testing.T) {
 
 	t.Run("Find Users by Nickname", func(t *testing.T) {
 		// Search for users with the nickname "Johnny"
-		users, err := f.FindByICQName("", "", "Johnny")
+		users, _, err := f.FindByICQName("", "", "Johnny", 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 1)
 
@@ -1511,7 +2447,7 @@ func TestSQLiteUserStore_FindByICQName(t *testing.T) {
 
 	t.Run("Find Users by Multiple Fields", func(t *testing.T) {
 		// Search for users with the first name "Jane" and last name "Smith"
-		users, err := f.FindByICQName("Jane", "Smith", "")
+		users, _, err := f.FindByICQName("Jane", "Smith", "", 10)
 		assert.NoError(t, err)
 		assert.Len(t, users, 1)
 
@@ -1519,10 +2455,74 @@ func TestSQLiteUserStore_FindByICQName(t *testing.T) {
 		assert.True(t, containsUserWithScreenName(users, user2.IdentScreenName))
 	})
 
-	t.Run("No Users Found", func(t *testing.T) {
-		// Search for users with a first name that no user has
-		users, err := f.FindByICQName("NonExistent", "", "")
+	t.Run("No Users Found", func(t *testing.T) {
+		// Search for users with a first name that no user has
+		users, _, err := f.FindByICQName("NonExistent", "", "", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+	})
+}
+
+func TestSQLiteUserStore_FindByICQName_Paginated(t *testing.T) {
+	// Cleanup after test
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	// populate a large directory where every 10th user is named John
+	const userCount = 1000
+	for i := 0; i < userCount; i++ {
+		sn := NewIdentScreenName(fmt.Sprintf("%d", 100000+i))
+		assert.NoError(t, f.InsertUser(User{IdentScreenName: sn}))
+		info := ICQBasicInfo{
+			FirstName: "Jane",
+			LastName:  "Doe",
+		}
+		if i%10 == 0 {
+			info.FirstName = "John"
+		}
+		assert.NoError(t, f.SetBasicInfo(sn, info))
+	}
+
+	t.Run("first page is ordered and reports total matches", func(t *testing.T) {
+		users, total, err := f.FindByICQName("john", "", "", 40)
+		assert.NoError(t, err)
+
+		assert.Equal(t, userCount/10, total)
+		assert.Len(t, users, 40)
+		assert.Equal(t, NewIdentScreenName("100000"), users[0].IdentScreenName)
+		assert.Equal(t, NewIdentScreenName("100390"), users[39].IdentScreenName)
+	})
+
+	t.Run("limit larger than result set returns all matches", func(t *testing.T) {
+		users, total, err := f.FindByICQName("John", "", "", userCount)
+		assert.NoError(t, err)
+		assert.Equal(t, userCount/10, total)
+		assert.Len(t, users, userCount/10)
+	})
+
+	t.Run("search uses the name index", func(t *testing.T) {
+		rows, err := f.db.Query(`EXPLAIN QUERY PLAN SELECT COUNT(*) FROM users WHERE LOWER(icq_basicInfo_firstName) = LOWER(?)`, "John")
+		assert.NoError(t, err)
+		defer rows.Close()
+
+		var plan []string
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			assert.NoError(t, rows.Scan(&id, &parent, &notUsed, &detail))
+			plan = append(plan, detail)
+		}
+		assert.Contains(t, strings.Join(plan, "\n"), "users_icq_firstName")
+	})
+
+	t.Run("all empty values returns no users", func(t *testing.T) {
+		users, total, err := f.FindByICQName("", "", "", 40)
 		assert.NoError(t, err)
+		assert.Zero(t, total)
 		assert.Empty(t, users)
 	})
 }
@@ -1887,6 +2887,117 @@ func TestSQLiteUserStore_RetrieveMessages(t *testing.T) {
 	})
 }
 
+func TestSQLiteUserStore_StorageQuota(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+	f.SetDefaultStorageQuota(1000)
+
+	screenName := NewIdentScreenName("userA")
+	err = f.InsertUser(User{
+		IdentScreenName:   screenName,
+		DisplayScreenName: "userA",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, f.SetProfile(screenName, "hello"))
+
+	msg := OfflineMessage{
+		Sender:    NewIdentScreenName("userB"),
+		Recipient: screenName,
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:     1,
+			ScreenName: "userA",
+		},
+		Sent: time.Now().UTC(),
+	}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, wire.MarshalBE(msg.Message, buf))
+	msgSize := int64(buf.Len())
+
+	usage, err := f.StorageUsage(screenName)
+	assert.NoError(t, err)
+	assert.Equal(t, StorageUsage{ProfileBytes: 5, QuotaBytes: 1000}, usage)
+
+	// leave room for exactly one message
+	quota := 5 + msgSize
+	assert.NoError(t, f.SetStorageQuota(screenName, &quota))
+	assert.NoError(t, f.SaveMessage(msg))
+
+	usage, err = f.StorageUsage(screenName)
+	assert.NoError(t, err)
+	assert.Equal(t, StorageUsage{OfflineMessageBytes: msgSize, ProfileBytes: 5, QuotaBytes: quota}, usage)
+	assert.Equal(t, quota, usage.UsedBytes())
+
+	// the next message is bounced
+	assert.ErrorIs(t, f.SaveMessage(msg), ErrStorageQuotaExceeded)
+	messages, err := f.RetrieveMessages(screenName)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// a quota of 0 means unlimited storage
+	unlimited := int64(0)
+	assert.NoError(t, f.SetStorageQuota(screenName, &unlimited))
+	assert.NoError(t, f.SaveMessage(msg))
+
+	// revert to the default quota
+	assert.NoError(t, f.SetStorageQuota(screenName, nil))
+	u, err := f.User(screenName)
+	assert.NoError(t, err)
+	assert.Nil(t, u.StorageQuota)
+	usage, err = f.StorageUsage(screenName)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), usage.QuotaBytes)
+
+	_, err = f.StorageUsage(NewIdentScreenName("userC"))
+	assert.ErrorIs(t, err, ErrNoUser)
+	assert.ErrorIs(t, f.SetStorageQuota(NewIdentScreenName("userC"), nil), ErrNoUser)
+}
+
+func TestSQLiteUserStore_OfflineMessageLimit(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+	f.SetOfflineMessageLimit(2)
+
+	msg := func(recip string) OfflineMessage {
+		return OfflineMessage{
+			Sender:    NewIdentScreenName("userB"),
+			Recipient: NewIdentScreenName(recip),
+			Sent:      time.Now().UTC(),
+		}
+	}
+
+	assert.NoError(t, f.SaveMessage(msg("userA")))
+	assert.NoError(t, f.SaveMessage(msg("userA")))
+	// the third message for userA is bounced
+	assert.ErrorIs(t, f.SaveMessage(msg("userA")), ErrOfflineMessageLimit)
+	// the limit is counted per recipient
+	assert.NoError(t, f.SaveMessage(msg("userC")))
+	// ICQ users aren't limited
+	for range 3 {
+		assert.NoError(t, f.SaveMessage(msg("100003")))
+	}
+
+	messages, err := f.RetrieveMessages(NewIdentScreenName("userA"))
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	// delivering the messages frees up room
+	assert.NoError(t, f.DeleteMessages(NewIdentScreenName("userA")))
+	assert.NoError(t, f.SaveMessage(msg("userA")))
+
+	// a limit of 0 means no limit
+	f.SetOfflineMessageLimit(0)
+	assert.NoError(t, f.SaveMessage(msg("userA")))
+	assert.NoError(t, f.SaveMessage(msg("userA")))
+}
+
 func TestSQLiteUserStore_DeleteMessages(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -2024,6 +3135,61 @@ func TestSQLiteUserStore_BuddyIconRefByNameMissingRef(t *testing.T) {
 	}
 }
 
+func TestSQLiteUserStore_DefaultBuddyIcon(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	userWithIcon := NewIdentScreenName("TalkingTyler")
+	userWithoutIcon := NewIdentScreenName("SingingSuzy")
+	ownHash := []byte{'t', 'h', 'e', 'h', 'a', 's', 'h'}
+	defaultIcon := []byte("the-default-icon")
+	defaultHash := md5.Sum(defaultIcon)
+
+	feedbagStore, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, feedbagStore.SetDefaultBuddyIcon(defaultIcon))
+
+	itemsIn := []wire.FeedbagItem{
+		{
+			Name:    "1",
+			ClassID: wire.FeedbagClassIdBart,
+			TLVLBlock: wire.TLVLBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.FeedbagAttributesBartInfo, wire.BARTInfo{
+						Hash: ownHash,
+					}),
+				},
+			},
+		},
+	}
+	assert.NoError(t, feedbagStore.FeedbagUpsert(userWithIcon, itemsIn))
+
+	// a user without an icon gets the default icon, which can be downloaded
+	// by its hash
+	b, err := feedbagStore.BuddyIconRefByName(userWithoutIcon)
+	assert.NoError(t, err)
+	assert.Equal(t, &wire.BARTID{
+		Type: wire.BARTTypesBuddyIcon,
+		BARTInfo: wire.BARTInfo{
+			Flags: wire.BARTFlagsKnown,
+			Hash:  defaultHash[:],
+		},
+	}, b)
+
+	body, err := feedbagStore.BARTRetrieve(b.Hash)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultIcon, body)
+
+	// a user's own icon overrides the default icon
+	b, err = feedbagStore.BuddyIconRefByName(userWithIcon)
+	assert.NoError(t, err)
+	assert.Equal(t, ownHash, b.Hash)
+
+	assert.Error(t, feedbagStore.SetDefaultBuddyIcon(nil))
+}
+
 func TestSQLiteUserStore_SetDirectoryInfo(t *testing.T) {
 	defer func() {
 		assert.NoError(t, os.Remove(testFile))
@@ -2808,17 +3974,10 @@ func TestSQLiteUserStore_RemoveBuddy(t *testing.T) {
 	err = f.RemoveBuddy(me, them)
 	assert.NoError(t, err)
 
+	// the entry is deleted, so no relationship remains
 	relationships, err = f.AllRelationships(me, nil)
 	assert.NoError(t, err)
-
-	expect = []Relationship{
-		{
-			User:          them,
-			IsOnTheirList: false,
-			IsOnYourList:  false,
-		},
-	}
-	assert.ElementsMatch(t, relationships, expect)
+	assert.Empty(t, relationships)
 }
 
 func TestSQLiteUserStore_RemoveDenyBuddy(t *testing.T) {
@@ -3208,3 +4367,324 @@ func TestSQLiteUserStore_PermitDenyTransitionIntegration(t *testing.T) {
 	}
 	assert.ElementsMatch(t, relationships, expect)
 }
+
+func TestSQLiteUserStore_NextUIN(t *testing.T) {
+	t.Run("concurrent allocations get distinct sequential UINs", func(t *testing.T) {
+		defer func() {
+			assert.NoError(t, os.Remove(testFile))
+		}()
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		const count = 20
+		uins := make([]uint32, count)
+		wg := sync.WaitGroup{}
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				uin, err := f.NextUIN(200000, 299999)
+				assert.NoError(t, err)
+				uins[i] = uin
+			}(i)
+		}
+		wg.Wait()
+
+		slices.Sort(uins)
+		for i, uin := range uins {
+			assert.Equal(t, uint32(200000+i), uin)
+		}
+	})
+
+	t.Run("counter persists across store instances", func(t *testing.T) {
+		defer func() {
+			assert.NoError(t, os.Remove(testFile))
+		}()
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		uin, err := f.NextUIN(200000, 299999)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(200000), uin)
+
+		f, err = NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		uin, err = f.NextUIN(200000, 299999)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(200001), uin)
+	})
+
+	t.Run("raising the start of the range skips ahead", func(t *testing.T) {
+		defer func() {
+			assert.NoError(t, os.Remove(testFile))
+		}()
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		uin, err := f.NextUIN(200000, 299999)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(200000), uin)
+
+		uin, err = f.NextUIN(500000, 599999)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(500000), uin)
+	})
+
+	t.Run("UINs that belong to existing users are skipped", func(t *testing.T) {
+		defer func() {
+			assert.NoError(t, os.Remove(testFile))
+		}()
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		for _, sn := range []DisplayScreenName{"200000", "200001"} {
+			assert.NoError(t, f.InsertUser(User{
+				DisplayScreenName: sn,
+				IdentScreenName:   sn.IdentScreenName(),
+				IsICQ:             true,
+			}))
+		}
+
+		uin, err := f.NextUIN(200000, 299999)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(200002), uin)
+	})
+
+	t.Run("range exhausted", func(t *testing.T) {
+		defer func() {
+			assert.NoError(t, os.Remove(testFile))
+		}()
+
+		f, err := NewSQLiteUserStore(testFile)
+		assert.NoError(t, err)
+
+		uin, err := f.NextUIN(200000, 200000)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(200000), uin)
+
+		_, err = f.NextUIN(200000, 200000)
+		assert.ErrorIs(t, err, ErrUINRangeExhausted)
+	})
+}
+
+func TestSQLiteUserStore_ContentEncryption(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	// store plaintext content before encryption is enabled
+	assert.NoError(t, f.SetProfile(NewIdentScreenName("legacy"), "plaintext profile"))
+
+	contentCipher, err := NewContentCipher(bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+	f.SetContentCipher(contentCipher)
+
+	screenName := NewIdentScreenName("me")
+	assert.NoError(t, f.SetProfile(screenName, "my private profile"))
+
+	msg := OfflineMessage{
+		Sender:    NewIdentScreenName("me"),
+		Recipient: NewIdentScreenName("them"),
+		Message: wire.SNAC_0x04_0x06_ICBMChannelMsgToHost{
+			Cookie:    1234,
+			ChannelID: wire.ICBMChannelIM,
+			TLVRestBlock: wire.TLVRestBlock{
+				TLVList: wire.TLVList{
+					wire.NewTLVBE(wire.ICBMTLVAOLIMData, []byte("my private message")),
+				},
+			},
+		},
+		Sent: time.Now().UTC(),
+	}
+	assert.NoError(t, f.SaveMessage(msg))
+
+	entry := ChatTranscriptEntry{
+		ScreenName: "me",
+		Text:       "my private chat message",
+		Sent:       time.Unix(1000, 0).UTC(),
+	}
+	assert.NoError(t, f.AppendChatTranscript("the-cookie", entry))
+
+	t.Run("stored rows are ciphertext", func(t *testing.T) {
+		var profile []byte
+		err := f.db.QueryRow(`SELECT body FROM profile WHERE screenName = ?`, screenName.String()).Scan(&profile)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(profile, contentCipherPrefix))
+		assert.NotContains(t, string(profile), "my private profile")
+
+		var message []byte
+		err = f.db.QueryRow(`SELECT message FROM offlineMessage WHERE recipient = ?`, "them").Scan(&message)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(message, contentCipherPrefix))
+		assert.NotContains(t, string(message), "my private message")
+
+		var text []byte
+		err = f.db.QueryRow(`SELECT text FROM chatTranscript WHERE cookie = ?`, "the-cookie").Scan(&text)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(text, contentCipherPrefix))
+		assert.NotContains(t, string(text), "my private chat message")
+	})
+
+	t.Run("content round-trips through decryption", func(t *testing.T) {
+		profile, err := f.Profile(screenName)
+		assert.NoError(t, err)
+		assert.Equal(t, "my private profile", profile)
+
+		messages, err := f.RetrieveMessages(NewIdentScreenName("them"))
+		assert.NoError(t, err)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, msg.Message, messages[0].Message)
+		}
+
+		entries, err := f.ChatTranscript("the-cookie", time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, []ChatTranscriptEntry{entry}, entries)
+	})
+
+	t.Run("plaintext content stored before encryption is readable", func(t *testing.T) {
+		profile, err := f.Profile(NewIdentScreenName("legacy"))
+		assert.NoError(t, err)
+		assert.Equal(t, "plaintext profile", profile)
+	})
+}
+
+func TestSQLiteUserStore_InsertUser_RecordsCreationTime(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	before := time.Now().Truncate(time.Second)
+	assert.NoError(t, f.InsertUser(User{
+		IdentScreenName:   NewIdentScreenName("userA"),
+		DisplayScreenName: "userA",
+	}))
+
+	u, err := f.User(NewIdentScreenName("userA"))
+	assert.NoError(t, err)
+	assert.False(t, u.CreatedAt.Before(before))
+	assert.False(t, u.CreatedAt.After(time.Now()))
+}
+
+func TestSQLiteUserStore_InsertUser_StoresPermissions(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.InsertUser(User{
+		IdentScreenName:   NewIdentScreenName("100003"),
+		DisplayScreenName: "100003",
+		IsICQ:             true,
+		ICQPermissions: ICQPermissions{
+			AuthRequired: true,
+			WebAware:     true,
+		},
+	}))
+
+	u, err := f.User(NewIdentScreenName("100003"))
+	assert.NoError(t, err)
+	assert.Equal(t, ICQPermissions{AuthRequired: true, WebAware: true}, u.ICQPermissions)
+}
+
+func TestSQLiteUserStore_ReserveScreenName(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.InsertUser(User{
+		IdentScreenName:   NewIdentScreenName("userA"),
+		DisplayScreenName: "userA",
+	}))
+
+	t.Run("registered screen name can't be reserved", func(t *testing.T) {
+		assert.ErrorIs(t, f.ReserveScreenName(NewIdentScreenName("userA")), ErrVirtualUserConflict)
+	})
+
+	t.Run("reserved screen name can't be registered or reserved twice", func(t *testing.T) {
+		assert.NoError(t, f.ReserveScreenName(NewIdentScreenName("virtualA")))
+		assert.ErrorIs(t, f.ReserveScreenName(NewIdentScreenName("virtualA")), ErrVirtualUserConflict)
+		assert.ErrorIs(t, f.InsertUser(User{
+			IdentScreenName:   NewIdentScreenName("virtualA"),
+			DisplayScreenName: "virtualA",
+		}), ErrDupUser)
+	})
+
+	t.Run("released screen name can be registered", func(t *testing.T) {
+		f.ReleaseScreenName(NewIdentScreenName("virtualA"))
+		assert.NoError(t, f.InsertUser(User{
+			IdentScreenName:   NewIdentScreenName("virtualA"),
+			DisplayScreenName: "virtualA",
+		}))
+	})
+}
+
+func TestSQLiteUserStore_ScreenNameAlias(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Remove(testFile))
+	}()
+
+	f, err := NewSQLiteUserStore(testFile)
+	assert.NoError(t, err)
+
+	userA := NewIdentScreenName("userA")
+	userB := NewIdentScreenName("userB")
+	alias := NewIdentScreenName("userA alt")
+	assert.NoError(t, f.InsertUser(User{IdentScreenName: userA, DisplayScreenName: "userA"}))
+	assert.NoError(t, f.InsertUser(User{IdentScreenName: userB, DisplayScreenName: "userB"}))
+
+	assert.NoError(t, f.AddScreenNameAlias(userA, alias))
+
+	// the alias can't be taken twice, and can't name an existing account
+	assert.ErrorIs(t, f.AddScreenNameAlias(userB, alias), ErrDupUser)
+	assert.ErrorIs(t, f.AddScreenNameAlias(userA, userB), ErrDupUser)
+	assert.ErrorIs(t, f.AddScreenNameAlias(NewIdentScreenName("nobody"), NewIdentScreenName("other")), ErrNoUser)
+	// an account can't be created under an alias
+	assert.ErrorIs(t, f.InsertUser(User{IdentScreenName: alias, DisplayScreenName: "userA alt"}), ErrDupUser)
+
+	aliases, err := f.ScreenNameAliases(userA)
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentScreenName{alias}, aliases)
+
+	canonical, err := f.CanonicalScreenName(alias)
+	assert.NoError(t, err)
+	assert.Equal(t, userA, canonical)
+	canonical, err = f.CanonicalScreenName(userB)
+	assert.NoError(t, err)
+	assert.Equal(t, userB, canonical)
+
+	// looking up the alias returns the canonical account
+	u, err := f.User(alias)
+	assert.NoError(t, err)
+	if assert.NotNil(t, u) {
+		assert.Equal(t, userA, u.IdentScreenName)
+	}
+
+	assert.ErrorIs(t, f.DeleteScreenNameAlias(userB, alias), ErrScreenNameAliasNotFound)
+	assert.NoError(t, f.DeleteScreenNameAlias(userA, alias))
+	u, err = f.User(alias)
+	assert.NoError(t, err)
+	assert.Nil(t, u)
+
+	// aliases are removed along with the account
+	assert.NoError(t, f.AddScreenNameAlias(userA, alias))
+	assert.NoError(t, f.DeleteUser(userA))
+	canonical, err = f.CanonicalScreenName(alias)
+	assert.NoError(t, err)
+	assert.Equal(t, alias, canonical)
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"net/netip"
 	"testing"
 	"time"
 
@@ -87,11 +88,102 @@ func TestUser_HashPassword(t *testing.T) {
 				require.NoError(t, err)
 				assert.Equal(t, tt.expectedWeakMD5, tt.user.WeakMD5Pass)
 				assert.Equal(t, tt.expectedStrongMD5, tt.user.StrongMD5Pass)
+				assert.True(t, tt.user.ValidatePassword(tt.password))
 			}
 		})
 	}
 }
 
+func TestUser_ValidatePassword(t *testing.T) {
+	strongUser := User{AuthKey: "someAuthKey"}
+	require.NoError(t, strongUser.HashPassword("thePassword"))
+
+	// legacyUser's password was set before argon2id hashes were stored
+	legacyUser := User{
+		AuthKey:       "someAuthKey",
+		WeakMD5Pass:   wire.WeakMD5PasswordHash("thePassword", "someAuthKey"),
+		StrongMD5Pass: wire.StrongMD5PasswordHash("thePassword", "someAuthKey"),
+	}
+
+	tests := []struct {
+		name     string
+		user     User
+		password string
+		want     bool
+	}{
+		{
+			name:     "correct password checked against argon2id hash",
+			user:     strongUser,
+			password: "thePassword",
+			want:     true,
+		},
+		{
+			name:     "wrong password checked against argon2id hash",
+			user:     strongUser,
+			password: "wrongPassword",
+			want:     false,
+		},
+		{
+			name: "argon2id hash takes precedence over MD5 hashes",
+			user: User{
+				AuthKey:       strongUser.AuthKey,
+				PasswordHash:  strongUser.PasswordHash,
+				StrongMD5Pass: wire.StrongMD5PasswordHash("oldPassword", "someAuthKey"),
+			},
+			password: "oldPassword",
+			want:     false,
+		},
+		{
+			name:     "correct password checked against MD5 hashes",
+			user:     legacyUser,
+			password: "thePassword",
+			want:     true,
+		},
+		{
+			name:     "wrong password checked against MD5 hashes",
+			user:     legacyUser,
+			password: "wrongPassword",
+			want:     false,
+		},
+		{
+			name: "correct password checked against strong MD5 hash only",
+			user: User{
+				AuthKey:       "someAuthKey",
+				StrongMD5Pass: wire.StrongMD5PasswordHash("thePassword", "someAuthKey"),
+			},
+			password: "thePassword",
+			want:     true,
+		},
+		{
+			name:     "malformed argon2id hash",
+			user:     User{PasswordHash: "$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$aGFzaA"},
+			password: "thePassword",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.user.ValidatePassword(tt.password))
+		})
+	}
+}
+
+func TestUser_ValidateHash(t *testing.T) {
+	user := User{AuthKey: "someAuthKey"}
+	require.NoError(t, user.HashPassword("thePassword"))
+
+	assert.True(t, user.ValidateHash(user.StrongMD5Pass))
+	assert.True(t, user.ValidateHash(user.WeakMD5Pass))
+	assert.True(t, user.ValidateStrongHash(user.StrongMD5Pass))
+	assert.False(t, user.ValidateStrongHash(user.WeakMD5Pass))
+
+	// an empty hash doesn't match a missing weak MD5 hash
+	user.WeakMD5Pass = nil
+	assert.False(t, user.ValidateHash(nil))
+	assert.False(t, user.ValidateHash([]byte{}))
+}
+
 func TestAge(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -260,3 +352,63 @@ func TestDisplayScreenName_ValidateICQHandle(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAllowedNetworks(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{
+			name:  "empty list",
+			given: "",
+			want:  nil,
+		},
+		{
+			name:  "CIDRs and bare addresses",
+			given: "10.1.2.3/8, 192.168.1.5,2001:db8::/32,",
+			want: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.168.1.5/32"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			},
+		},
+		{
+			name:    "invalid CIDR",
+			given:   "10.0.0.0/33",
+			wantErr: true,
+		},
+		{
+			name:    "invalid address",
+			given:   "not-an-ip",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseAllowedNetworks(tt.given)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestUser_LoginAllowedFrom(t *testing.T) {
+	u := User{}
+	assert.True(t, u.LoginAllowedFrom(netip.MustParseAddr("203.0.113.7")))
+
+	u.AllowedNetworks = []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+	assert.True(t, u.LoginAllowedFrom(netip.MustParseAddr("10.20.30.40")))
+	assert.True(t, u.LoginAllowedFrom(netip.MustParseAddr("::ffff:10.20.30.40")))
+	assert.True(t, u.LoginAllowedFrom(netip.MustParseAddr("2001:db8::1")))
+	assert.False(t, u.LoginAllowedFrom(netip.MustParseAddr("203.0.113.7")))
+	assert.False(t, u.LoginAllowedFrom(netip.Addr{}))
+}
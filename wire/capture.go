@@ -0,0 +1,51 @@
+package wire
+
+import (
+	"io"
+	"time"
+)
+
+// A traffic capture file records the raw bytes exchanged with OSCAR clients
+// so that protocol bugs can be replayed and inspected offline. The file is a
+// sequence of CaptureRecord structs with no header. Each record holds one
+// chunk of bytes as it was read from or written to a client connection, so a
+// FLAP frame may span several records and a record may hold several FLAP
+// frames. The frames of a connection are reassembled by concatenating the
+// data of its records in each direction. Data frames are recorded as sent,
+// so when the connection's signon frames negotiate FLAP compression, the data
+// frames that follow must be inflated to read their SNACs.
+const (
+	CaptureDirectionIn  uint8 = 0x01 // sent by the client
+	CaptureDirectionOut uint8 = 0x02 // sent by the server
+)
+
+// CaptureRecord is a chunk of client connection traffic in a capture file.
+type CaptureRecord struct {
+	// Time is when the chunk was captured, in nanoseconds since the Unix
+	// epoch.
+	Time uint64
+	// ConnID identifies the client connection. It's unique within a run of
+	// the server.
+	ConnID uint64
+	// Direction is CaptureDirectionIn or CaptureDirectionOut.
+	Direction uint8
+	// ScreenName is the screen name of the connection's session, or empty if
+	// the client hasn't signed on yet.
+	ScreenName string `oscar:"len_prefix=uint8"`
+	Data       []byte `oscar:"len_prefix=uint16"`
+}
+
+// CapturedAt returns the time the chunk was captured.
+func (r CaptureRecord) CapturedAt() time.Time {
+	return time.Unix(0, int64(r.Time))
+}
+
+// ReadCaptureRecord reads the next record from a capture file. It returns
+// io.EOF once there are no more records.
+func ReadCaptureRecord(r io.Reader) (CaptureRecord, error) {
+	rec := CaptureRecord{}
+	if err := UnmarshalBE(&rec, r); err != nil {
+		return CaptureRecord{}, err
+	}
+	return rec, nil
+}
@@ -2,8 +2,11 @@ package wire
 
 import (
 	"bytes"
+	"compress/flate"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 )
 
 type SNACError struct {
@@ -19,6 +22,32 @@ const (
 	FLAPFrameKeepAlive uint8 = 0x05
 )
 
+// Error codes sent in TLV 0x0009 of the sign-off frame that ends a BOS
+// connection. Clients explain SignoffErrCodeMultipleLogins to the user and
+// show a generic disconnection message for other codes.
+const (
+	// SignoffErrCodeMultipleLogins indicates that the user signed on from
+	// another location.
+	SignoffErrCodeMultipleLogins uint8 = 0x01
+	// SignoffErrCodeKicked indicates that an operator signed the user off.
+	SignoffErrCodeKicked uint8 = 0x02
+)
+
+const (
+	// FLAPSignonTLVCompression is a signon frame TLV, not found in the
+	// original protocol, that negotiates FLAP payload compression. The server
+	// offers a compression method in its signon frame, and the client accepts
+	// it by echoing the same value in its own signon frame.
+	FLAPSignonTLVCompression uint16 = 0xFF01
+	// FLAPCompressionDeflate compresses each data frame payload on its own
+	// using DEFLATE (RFC 1951).
+	FLAPCompressionDeflate uint16 = 0x0001
+)
+
+// ErrFLAPPayloadTooLarge indicates that a compressed FLAP payload inflates to
+// more than the largest payload that fits in an uncompressed frame.
+var ErrFLAPPayloadTooLarge = errors.New("decompressed FLAP payload is too large")
+
 type FLAPFrame struct {
 	StartMarker uint8
 	FrameType   uint8
@@ -43,6 +72,10 @@ type SNACFrame struct {
 	RequestID uint32
 }
 
+// SNACFlagMoreReplies is set in SNACFrame.Flags on every SNAC of a reply that
+// spans multiple SNACs, except for the last one.
+const SNACFlagMoreReplies uint16 = 0x0001
+
 type FLAPSignonFrame struct {
 	FLAPVersion uint32
 	TLVRestBlock
@@ -69,9 +102,55 @@ func NewFlapClient(startSeq uint32, r io.Reader, w io.Writer) *FlapClient {
 // each successive message. It is not safe to use with multiple goroutines
 // without synchronization.
 type FlapClient struct {
-	sequence uint32
-	r        io.Reader
-	w        io.Writer
+	compression uint16
+	sequence    uint32
+	r           io.Reader
+	w           io.Writer
+}
+
+// SetCompression sets the compression method applied to data frame payloads
+// sent and received from now on. Pass 0 to disable compression.
+func (f *FlapClient) SetCompression(method uint16) {
+	f.compression = method
+}
+
+// DataPayload returns the payload of a data frame received from the peer,
+// decompressing it if compression is enabled.
+func (f *FlapClient) DataPayload(flap FLAPFrame) ([]byte, error) {
+	if f.compression != FLAPCompressionDeflate {
+		return flap.Payload, nil
+	}
+	r := flate.NewReader(bytes.NewReader(flap.Payload))
+	defer r.Close()
+	// read one byte past the limit to detect oversized payloads
+	b, err := io.ReadAll(io.LimitReader(r, math.MaxUint16+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress FLAP payload: %w", err)
+	}
+	if len(b) > math.MaxUint16 {
+		return nil, ErrFLAPPayloadTooLarge
+	}
+	return b, nil
+}
+
+// compressPayload compresses an outgoing data frame payload if compression is
+// enabled.
+func (f *FlapClient) compressPayload(payload []byte) ([]byte, error) {
+	if f.compression != FLAPCompressionDeflate {
+		return payload, nil
+	}
+	buf := &bytes.Buffer{}
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // Fixes a race condition caused by testify. Yup...
@@ -160,7 +239,8 @@ func (f *FlapClient) SendSignoffFrame(tlvs TLVRestBlock) error {
 	return nil
 }
 
-// SendSNAC sends a SNAC message wrapped in a FLAP frame.
+// SendSNAC sends a SNAC message wrapped in a FLAP frame. The payload is
+// compressed if compression is enabled.
 func (f *FlapClient) SendSNAC(frame SNACFrame, body any) error {
 	snacBuf := &bytes.Buffer{}
 	if err := MarshalBE(frame, snacBuf); err != nil {
@@ -169,12 +249,16 @@ func (f *FlapClient) SendSNAC(frame SNACFrame, body any) error {
 	if err := MarshalBE(body, snacBuf); err != nil {
 		return err
 	}
+	payload, err := f.compressPayload(snacBuf.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to compress FLAP payload: %w", err)
+	}
 
 	flap := FLAPFrame{
 		StartMarker: 42,
 		FrameType:   FLAPFrameData,
 		Sequence:    uint16(f.sequence),
-		Payload:     snacBuf.Bytes(),
+		Payload:     payload,
 	}
 	if err := MarshalBE(flap, f.w); err != nil {
 		return err
@@ -184,19 +268,39 @@ func (f *FlapClient) SendSNAC(frame SNACFrame, body any) error {
 	return nil
 }
 
-// ReceiveSNAC receives a SNAC message wrapped in a FLAP frame.
+// ReceiveSNAC receives a SNAC message wrapped in a FLAP frame, decompressing
+// the payload if compression is enabled.
 func (f *FlapClient) ReceiveSNAC(frame *SNACFrame, body any) error {
 	flap := FLAPFrame{}
 	if err := UnmarshalBE(&flap, f.r); err != nil {
 		return err
 	}
-	buf := bytes.NewBuffer(flap.Payload)
+	payload, err := f.DataPayload(flap)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(payload)
 	if err := UnmarshalBE(frame, buf); err != nil {
 		return err
 	}
 	return UnmarshalBE(body, buf)
 }
 
+// SendKeepAlive sends a keepalive FLAP frame.
+func (f *FlapClient) SendKeepAlive() error {
+	flap := FLAPFrame{
+		StartMarker: 42,
+		FrameType:   FLAPFrameKeepAlive,
+		Sequence:    uint16(f.sequence),
+	}
+	if err := MarshalBE(flap, f.w); err != nil {
+		return err
+	}
+
+	f.sequence++
+	return nil
+}
+
 // Disconnect sends a signoff FLAP frame.
 func (f *FlapClient) Disconnect() error {
 	flap := FLAPFrameDisconnect{
@@ -0,0 +1,148 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The rendezvous proxy protocol lets two clients that can't connect to each
+// other directly, such as when both are behind NAT, exchange rendezvous data,
+// such as a file transfer, through the server. AIM clients know the proxy as
+// ars.oscar.aol.com on port 5190.
+//
+// The sender opens a proxy connection with a ProxyCommandInitSend packet and
+// gets a ProxyCommandAck packet containing the proxy IP address and a port
+// number that identifies the session. The sender passes both to the
+// recipient in a rendezvous proposal, and the recipient opens its own proxy
+// connection with a ProxyCommandInitRecv packet. Once both are connected, the
+// proxy sends each side a ProxyCommandReady packet and relays everything that
+// follows unchanged.
+const (
+	ProxyVersion uint16 = 0x044A
+
+	ProxyCommandError    uint16 = 0x0001
+	ProxyCommandInitSend uint16 = 0x0002
+	ProxyCommandAck      uint16 = 0x0003
+	ProxyCommandInitRecv uint16 = 0x0004
+	ProxyCommandReady    uint16 = 0x0005
+
+	ProxyErrCodeBadRequest  uint16 = 0x000D // malformed or unknown request
+	ProxyErrCodeInitTimeout uint16 = 0x0010 // peer didn't connect in time
+
+	ProxyTLVCapability uint16 = 0x0001 // rendezvous capability UUID
+
+	// proxyHeaderLen is the length of the proxy packet header that follows
+	// the packet length.
+	proxyHeaderLen = 10
+)
+
+// ErrProxyVersion indicates that a proxy packet has an unsupported version.
+var ErrProxyVersion = errors.New("unsupported proxy packet version")
+
+// ProxyPacket is a rendezvous proxy protocol packet. The Payload is one of
+// the Proxy* structs that matches Command.
+type ProxyPacket struct {
+	Command uint16
+	Flags   uint16
+	Payload []byte
+}
+
+// ProxyInitSend is the payload of a ProxyCommandInitSend packet, sent by the
+// client that proposes the rendezvous.
+type ProxyInitSend struct {
+	ScreenName string `oscar:"len_prefix=uint8"`
+	Cookie     uint64
+	TLVRestBlock
+}
+
+// ProxyInitRecv is the payload of a ProxyCommandInitRecv packet, sent by the
+// client that accepts the rendezvous. Port is the port number from the
+// ProxyAck that the sender received.
+type ProxyInitRecv struct {
+	ScreenName string `oscar:"len_prefix=uint8"`
+	Port       uint16
+	Cookie     uint64
+	TLVRestBlock
+}
+
+// ProxyAck is the payload of a ProxyCommandAck packet. Port identifies the
+// proxy session rather than a TCP port, and IP is the IPv4 address of the
+// proxy.
+type ProxyAck struct {
+	Port uint16
+	IP   uint32
+}
+
+// ProxyError is the payload of a ProxyCommandError packet.
+type ProxyError struct {
+	Code uint16
+}
+
+// ReadProxyPacket reads a rendezvous proxy packet from r. Payloads longer
+// than maxPayload are rejected.
+func ReadProxyPacket(r io.Reader, maxPayload int) (ProxyPacket, error) {
+	var header struct {
+		Length  uint16
+		Version uint16
+		Command uint16
+		Unknown uint32
+		Flags   uint16
+	}
+	if err := UnmarshalBE(&header, r); err != nil {
+		return ProxyPacket{}, err
+	}
+	if header.Version != ProxyVersion {
+		return ProxyPacket{}, fmt.Errorf("%w: %#04x", ErrProxyVersion, header.Version)
+	}
+	if header.Length < proxyHeaderLen {
+		return ProxyPacket{}, fmt.Errorf("proxy packet length %d is shorter than its header", header.Length)
+	}
+	payloadLen := int(header.Length) - proxyHeaderLen
+	if payloadLen > maxPayload {
+		return ProxyPacket{}, fmt.Errorf("proxy packet payload of %d bytes is too large", payloadLen)
+	}
+
+	pkt := ProxyPacket{
+		Command: header.Command,
+		Flags:   header.Flags,
+		Payload: make([]byte, payloadLen),
+	}
+	if _, err := io.ReadFull(r, pkt.Payload); err != nil {
+		return ProxyPacket{}, err
+	}
+	return pkt, nil
+}
+
+// WriteProxyPacket writes a rendezvous proxy packet with the given command
+// to w. body is marshalled as the packet payload, and may be nil for packets
+// without one.
+func WriteProxyPacket(w io.Writer, command uint16, body any) error {
+	payload := &bytes.Buffer{}
+	if body != nil {
+		if err := MarshalBE(body, payload); err != nil {
+			return err
+		}
+	}
+
+	pkt := struct {
+		Length  uint16
+		Version uint16
+		Command uint16
+		Unknown uint32
+		Flags   uint16
+	}{
+		Length:  uint16(proxyHeaderLen + payload.Len()),
+		Version: ProxyVersion,
+		Command: command,
+	}
+	buf := &bytes.Buffer{}
+	if err := MarshalBE(pkt, buf); err != nil {
+		return err
+	}
+	buf.Write(payload.Bytes())
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,78 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteProxyPacket(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteProxyPacket(buf, ProxyCommandAck, ProxyAck{Port: 0x1234, IP: 0x7F000001}))
+
+	want := []byte{
+		0x00, 0x10, // length
+		0x04, 0x4A, // version
+		0x00, 0x03, // command
+		0x00, 0x00, 0x00, 0x00, // unknown
+		0x00, 0x00, // flags
+		0x12, 0x34, // port
+		0x7F, 0x00, 0x00, 0x01, // IP
+	}
+	assert.Equal(t, want, buf.Bytes())
+}
+
+func TestReadProxyPacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   []byte
+		want    ProxyPacket
+		wantErr error
+	}{
+		{
+			name: "read packet with payload",
+			given: []byte{
+				0x00, 0x0C, // length
+				0x04, 0x4A, // version
+				0x00, 0x01, // command
+				0x00, 0x00, 0x00, 0x00, // unknown
+				0x02, 0x20, // flags
+				0x00, 0x0D, // error code
+			},
+			want: ProxyPacket{
+				Command: ProxyCommandError,
+				Flags:   0x0220,
+				Payload: []byte{0x00, 0x0D},
+			},
+		},
+		{
+			name: "reject unsupported version",
+			given: []byte{
+				0x00, 0x0A, // length
+				0x04, 0x4B, // version
+				0x00, 0x05, // command
+				0x00, 0x00, 0x00, 0x00, // unknown
+				0x00, 0x00, // flags
+			},
+			wantErr: ErrProxyVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ReadProxyPacket(bytes.NewReader(tt.given), 1024)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, have)
+			}
+		})
+	}
+}
+
+func TestReadProxyPacket_PayloadTooLarge(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteProxyPacket(buf, ProxyCommandInitSend, ProxyInitSend{ScreenName: "sender"}))
+
+	_, err := ReadProxyPacket(buf, 4)
+	assert.Error(t, err)
+}
@@ -94,6 +94,8 @@ const (
 	LoginErrInvalidUsernameOrPassword uint16 = 0x0001
 	LoginErrInvalidPassword           uint16 = 0x0005 // invalid password
 	LoginErrICQUserErr                uint16 = 0x0008 // ICQ user doesn't exist
+	LoginErrSuspendedAccount          uint16 = 0x0011 // account suspended
+	LoginErrRateLimitExceeded         uint16 = 0x0018 // rate limit exceeded, reconnect in a few minutes
 )
 
 //
@@ -137,6 +139,14 @@ const (
 	OServiceBartQuery2        uint16 = 0x0022
 	OServiceBartReply2        uint16 = 0x0023
 
+	OServiceMOTDTypeNormal uint16 = 0x0004 // informational message
+
+	OServiceRateChangeCodeWarning uint16 = 0x0002 // client is close to the limit
+	OServiceRateChangeCodeLimit   uint16 = 0x0003 // client's SNACs are being dropped
+	OServiceRateChangeCodeClear   uint16 = 0x0004 // limit or warning cleared
+
+	OServiceMOTDTLVMessage uint16 = 0x0B
+
 	OServiceUserInfoUserFlags  uint16 = 0x01
 	OServiceUserInfoSignonTOD  uint16 = 0x03
 	OServiceUserInfoIdleTime   uint16 = 0x04
@@ -159,6 +169,13 @@ const (
 	OServiceUserStatusICQHomePage       uint32 = 0x00200000
 	OServiceUserStatusDirectRequireAuth uint32 = 0x10000000
 
+	// ICQ clients combine the status flags above into these status modes.
+	ICQStatusModeAway        = OServiceUserStatusAway
+	ICQStatusModeNA          = OServiceUserStatusAway | OServiceUserStatusOut
+	ICQStatusModeOccupied    = OServiceUserStatusAway | OServiceUserStatusBusy
+	ICQStatusModeDND         = OServiceUserStatusAway | OServiceUserStatusDND | OServiceUserStatusBusy
+	ICQStatusModeFreeForChat = OServiceUserStatusChat
+
 	OServiceUserFlagUnconfirmed    uint16 = 0x0001 // Unconfirmed account
 	OServiceUserFlagAdministrator  uint16 = 0x0002 // Server Administrator
 	OServiceUserFlagAOL            uint16 = 0x0004 // AOL (staff?) account
@@ -268,6 +285,24 @@ type SNAC_0x01_0x08_OServiceRateParamsSubAdd struct {
 	TLVRestBlock
 }
 
+// SNAC_0x01_0x0A_OServiceRateParamChange notifies the client that the state
+// of one of its rate classes changed. Code is one of the
+// OServiceRateChangeCode* values, and the remaining fields carry the rate
+// class params as described in SNAC_0x01_0x07_OServiceRateParamsReply.
+type SNAC_0x01_0x0A_OServiceRateParamChange struct {
+	Code            uint16
+	ID              uint16
+	WindowSize      uint32
+	ClearLevel      uint32
+	AlertLevel      uint32
+	LimitLevel      uint32
+	DisconnectLevel uint32
+	CurrentLevel    uint32
+	MaxLevel        uint32
+	LastTime        uint32
+	CurrentState    uint8
+}
+
 type SNAC_0x01_0x0F_OServiceUserInfoUpdate struct {
 	TLVUserInfo
 }
@@ -285,6 +320,14 @@ type SNAC_0x01_0x11_OServiceIdleNotification struct {
 	IdleTime uint32
 }
 
+// SNAC_0x01_0x13_OServiceMOTD is a message of the day pushed to the client.
+// MessageType indicates the kind of message, such as an upgrade notice or
+// normal information.
+type SNAC_0x01_0x13_OServiceMOTD struct {
+	MessageType uint16
+	TLVRestBlock
+}
+
 type SNAC_0x01_0x14_OServiceSetPrivacyFlags struct {
 	PrivacyFlags uint32
 }
@@ -599,6 +642,70 @@ type ICBMCh1Message struct {
 	Text     []byte
 }
 
+const (
+	ICBMRdvMessagePropose uint16 = 0x00
+	ICBMRdvMessageCancel  uint16 = 0x01
+	ICBMRdvMessageAccept  uint16 = 0x02
+
+	ICBMRdvTLVTagsCancelReason uint16 = 0x000B
+	// ICBMRdvTLVTagsRequestUseARS is set on proposals whose rendezvous is
+	// relayed through the file transfer proxy.
+	ICBMRdvTLVTagsRequestUseARS uint16 = 0x0010
+	ICBMRdvTLVTagsSvcData       uint16 = 0x2711
+
+	ICBMRdvCancelReasonsUnknown         uint16 = 0x00
+	ICBMRdvCancelReasonsUserCancel      uint16 = 0x01
+	ICBMRdvCancelReasonsTimeout         uint16 = 0x02
+	ICBMRdvCancelReasonsAcceptElsewhere uint16 = 0x03
+	// ICBMRdvCancelReasonsSizeExceeded is a server-issued cancel reason
+	// indicating that the proposal exceeds the server's file size limit.
+	ICBMRdvCancelReasonsSizeExceeded uint16 = 0x04
+	// ICBMRdvCancelReasonsNotAllowed is a server-issued cancel reason
+	// indicating that the server doesn't allow the proposal's capability.
+	ICBMRdvCancelReasonsNotAllowed uint16 = 0x05
+	// ICBMRdvCancelReasonsTooManyTransfers is a server-issued cancel reason
+	// indicating that the server's file transfer concurrency cap is reached.
+	ICBMRdvCancelReasonsTooManyTransfers uint16 = 0x06
+)
+
+// CapFileTransfer is the capability UUID for file transfer (send file)
+// rendezvous sessions.
+var CapFileTransfer = [16]byte{0x09, 0x46, 0x13, 0x43, 0x4C, 0x7F, 0x11, 0xD1, 0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00}
+
+// CapChat is the capability UUID for chat room invitation rendezvous
+// sessions.
+var CapChat = [16]byte{0x74, 0x8F, 0x24, 0x20, 0x62, 0x87, 0x11, 0xD1, 0x82, 0x22, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00}
+
+// CapOfficialBadge is a capability UUID that the server adds to the user info
+// of official accounts, such as staff and bots, so that clients can badge
+// them. It is specific to this server and not an AOL capability.
+var CapOfficialBadge = [16]byte{0x9A, 0x2C, 0x6E, 0x1F, 0x3B, 0x7D, 0x4F, 0x0A, 0x8E, 0x5C, 0x0D, 0x4B, 0x7A, 0x1F, 0x6C, 0x23}
+
+// ICBMCh2Fragment represents an ICBM channel 2 (rendezvous) message, which is
+// carried in TLV ICBMTLVData.
+type ICBMCh2Fragment struct {
+	Type       uint16
+	Cookie     [8]byte
+	Capability [16]byte
+	TLVRestBlock
+}
+
+// ICBMRdvFileTransferSvcData is the service data block (TLV
+// ICBMRdvTLVTagsSvcData) of a file transfer rendezvous proposal.
+type ICBMRdvFileTransferSvcData struct {
+	MultipleFilesFlag uint16
+	FileCount         uint16
+	TotalBytes        uint32
+}
+
+// ICBMRoomInfo is the service data block (TLV ICBMRdvTLVTagsSvcData) of a
+// chat room invitation rendezvous proposal.
+type ICBMRoomInfo struct {
+	Exchange uint16
+	Cookie   string `oscar:"len_prefix=uint8"`
+	Instance uint16
+}
+
 // ICBMCh4Message represents an ICBM channel 4 (ICQ) message component.
 type ICBMCh4Message struct {
 	UIN         uint32
@@ -993,6 +1100,7 @@ const (
 	ChatRoomInfoOwner      uint16 = 0x0030
 
 	ChatTLVPublicWhisperFlag    uint16 = 0x01
+	ChatTLVWhisperToUser        uint16 = 0x02
 	ChatTLVSenderInformation    uint16 = 0x03
 	ChatTLVMessageInfo          uint16 = 0x05
 	ChatTLVEnableReflectionFlag uint16 = 0x06
@@ -1450,6 +1558,12 @@ type SNAC_0x13_0x18_FeedbagRequestAuthorizationToHost struct {
 	Unknown    uint16
 }
 
+type SNAC_0x13_0x19_FeedbagRequestAuthorizeToClient struct {
+	ScreenName string `oscar:"len_prefix=uint8"`
+	Reason     string `oscar:"len_prefix=uint16"`
+	Unknown    uint16
+}
+
 type SNAC_0x13_0x1A_FeedbagRespondAuthorizeToHost struct {
 	ScreenName string `oscar:"len_prefix=uint8"`
 	Accepted   uint8
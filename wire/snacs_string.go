@@ -1,5 +1,7 @@
 package wire
 
+import "strings"
+
 var foodGroupName = map[uint16]string{
 	OService:    "OService",
 	Locate:      "Locate",
@@ -37,6 +39,17 @@ func FoodGroupName(foodGroup uint16) string {
 	return name
 }
 
+// FoodGroupByName gets the food group with the given string name, ignoring
+// case. It returns false if no food group has that name.
+func FoodGroupByName(name string) (uint16, bool) {
+	for foodGroup, n := range foodGroupName {
+		if strings.EqualFold(n, name) {
+			return foodGroup, true
+		}
+	}
+	return 0, false
+}
+
 var subGroupName = map[uint16]map[uint16]string{
 	OService: {
 		OServiceErr:               "OServiceErr",
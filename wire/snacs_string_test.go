@@ -13,6 +13,17 @@ func TestFoodGroupName_InvalidFoodGroup(t *testing.T) {
 	assert.Equal(t, "unknown", FoodGroupName(2142))
 }
 
+func TestFoodGroupByName_HappyPath(t *testing.T) {
+	foodGroup, ok := FoodGroupByName("icbm")
+	assert.True(t, ok)
+	assert.Equal(t, ICBM, foodGroup)
+}
+
+func TestFoodGroupByName_InvalidFoodGroup(t *testing.T) {
+	_, ok := FoodGroupByName("nope")
+	assert.False(t, ok)
+}
+
 func TestSubGroupName_HappyPath(t *testing.T) {
 	assert.Equal(t, "OServiceServiceRequest", SubGroupName(OService, OServiceServiceRequest))
 }